@@ -23,6 +23,9 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	if cfg.Profile != "" {
+		log.Printf("Loaded configuration profile %q", cfg.Profile)
+	}
 
 	// Set Gin mode
 	gin.SetMode(cfg.Server.Mode)
@@ -64,6 +67,11 @@ func main() {
 
 	// Setup router
 	r := gin.Default()
+	if cfg.Server.TrustedProxies != nil {
+		if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+			log.Fatalf("Failed to set trusted proxies: %v", err)
+		}
+	}
 	api.SetupRoutes(r, cfg, cacheService)
 
 	// Initialize services for scheduler
@@ -73,14 +81,32 @@ func main() {
 	queryService := services.NewQueryService(cacheService)
 	dashboardService := services.NewDashboardService()
 	notificationService := services.NewNotificationService(pool, &cfg.Notification)
+	if notificationService.EncryptsConfigs() {
+		// Encrypt channel configs saved before the key was configured
+		n, err := notificationService.EncryptPlaintextConfigs(context.Background())
+		if err != nil {
+			log.Fatalf("Failed to encrypt notification channel configs: %v", err)
+		}
+		if n > 0 {
+			log.Printf("Encrypted %d plaintext notification channel configs", n)
+		}
+	} else {
+		log.Println("[WARN] NOTIFICATION_ENCRYPTION_KEY is not set; notification channel secrets are stored in plaintext")
+	}
 	alertService := services.NewAlertService(pool, cachedTrinoService, notificationService, queryService)
 	subscriptionService := services.NewSubscriptionService(pool, notificationService, dashboardService)
 
 	// Start scheduler
-	scheduler, err := services.NewScheduler(alertService, subscriptionService, notificationService)
+	// Advisory locks ensure each batch runs on only one replica
+	schedulerLocker := services.NewPostgresAdvisoryLocker(pool)
+	scheduler, err := services.NewScheduler(alertService, subscriptionService, notificationService, schedulerLocker)
 	if err != nil {
 		log.Fatalf("Failed to create scheduler: %v", err)
 	}
+	scheduler.SetTrashPurge(dashboardService, time.Duration(cfg.Dashboard.TrashRetentionDays)*24*time.Hour)
+	scheduler.SetTrashPurgeWarning(time.Duration(cfg.Dashboard.TrashWarningDays) * 24 * time.Hour)
+	scheduler.SetQueryHistoryCap(queryService, cfg.Limits.MaxQueryHistoryPerUser)
+	scheduler.SetMaintenance(services.NewMaintenanceService(pool))
 	if err := scheduler.Start(); err != nil {
 		log.Fatalf("Failed to start scheduler: %v", err)
 	}
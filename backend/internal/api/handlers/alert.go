@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -14,13 +17,27 @@ import (
 type AlertHandler struct {
 	alertService        *services.AlertService
 	notificationService *services.NotificationService
+	savedQueries        savedQueryReader // queryService; separate so previews can be tested without a database
+	roleService         *services.RoleService
+	defaultCatalog      string
+	defaultSchema       string
 }
 
 // NewAlertHandler creates a new alert handler
-func NewAlertHandler(alertService *services.AlertService, notificationService *services.NotificationService) *AlertHandler {
+func NewAlertHandler(
+	alertService *services.AlertService,
+	notificationService *services.NotificationService,
+	queryService *services.QueryService,
+	roleService *services.RoleService,
+	defaultCatalog, defaultSchema string,
+) *AlertHandler {
 	return &AlertHandler{
 		alertService:        alertService,
 		notificationService: notificationService,
+		savedQueries:        queryService,
+		roleService:         roleService,
+		defaultCatalog:      defaultCatalog,
+		defaultSchema:       defaultSchema,
 	}
 }
 
@@ -89,6 +106,12 @@ func (h *AlertHandler) CreateAlert(c *gin.Context) {
 
 	alert, err := h.alertService.CreateAlert(c.Request.Context(), userID.(uuid.UUID), &req)
 	if err != nil {
+		if respondActiveLimitError(c, err) {
+			return
+		}
+		if respondReadOnlyError(c, err) || respondRowFilterRejection(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -118,6 +141,12 @@ func (h *AlertHandler) UpdateAlert(c *gin.Context) {
 
 	alert, err := h.alertService.UpdateAlert(c.Request.Context(), alertID, userID.(uuid.UUID), &req)
 	if err != nil {
+		if respondActiveLimitError(c, err) {
+			return
+		}
+		if respondReadOnlyError(c, err) || respondRowFilterRejection(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -147,6 +176,69 @@ func (h *AlertHandler) DeleteAlert(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Alert deleted"})
 }
 
+// PreviewAlert evaluates an alert definition that has not been saved yet, reporting whether it
+// would trigger now and the value it checked. The body is shaped like a create request; the
+// saved query must belong to the requester, who must be allowed to read the catalogs and schemas
+// it references. Nothing is saved and no notification is sent.
+// POST /alerts/preview
+func (h *AlertHandler) PreviewAlert(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.PreviewAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preview, err := h.previewAlert(c.Request.Context(), userID.(uuid.UUID), &req)
+	if err != nil {
+		if respondReadOnlyError(c, err) || respondRowFilterRejection(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Query not found"})
+		case errors.Is(err, services.ErrPermissionDenied):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized"})
+		case errors.Is(err, ErrCatalogAccessDenied), errors.Is(err, ErrShowCatalogsForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
+// previewAlert checks that the user may read every catalog and schema the alert's saved query
+// references, as the query handlers do before running SQL, then evaluates the alert. A saved
+// query that cannot be loaded is left to PreviewAlert to report.
+func (h *AlertHandler) previewAlert(ctx context.Context, userID uuid.UUID, req *models.PreviewAlertRequest) (*models.AlertPreviewResponse, error) {
+	if h.roleService != nil {
+		if savedQuery, err := h.savedQueries.GetSavedQueryByID(ctx, req.QueryID); err == nil {
+			if savedQuery.UserID != userID {
+				return nil, services.ErrPermissionDenied
+			}
+			catalog, schema := h.defaultCatalog, h.defaultSchema
+			if savedQuery.Catalog != nil && *savedQuery.Catalog != "" {
+				catalog = *savedQuery.Catalog
+			}
+			if savedQuery.SchemaName != nil && *savedQuery.SchemaName != "" {
+				schema = *savedQuery.SchemaName
+			}
+			if err := enforceCatalogAccess(ctx, h.roleService, userID, savedQuery.QueryText, catalog, schema); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return h.alertService.PreviewAlert(ctx, userID, req)
+}
+
 // TestAlert manually triggers an alert evaluation
 func (h *AlertHandler) TestAlert(c *gin.Context) {
 	userID, exists := c.Get("userID")
@@ -174,7 +266,7 @@ func (h *AlertHandler) TestAlert(c *gin.Context) {
 
 	triggered, value, err := h.alertService.EvaluateAlert(c.Request.Context(), alert)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondRowFilterError(c, err)
 		return
 	}
 
@@ -206,6 +298,115 @@ func (h *AlertHandler) TestAlert(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// SnoozeAlert suppresses an alert's notifications for the requested duration.
+// The alert is still checked while snoozed so its history stays complete.
+func (h *AlertHandler) SnoozeAlert(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	alertID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert ID"})
+		return
+	}
+
+	var req models.SnoozeAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !h.authorizeAlertOwner(c, alertID, userID.(uuid.UUID)) {
+		return
+	}
+
+	until := time.Now().Add(time.Duration(req.DurationMinutes) * time.Minute)
+	alert, err := h.alertService.SnoozeAlert(c.Request.Context(), alertID, userID.(uuid.UUID), until)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
+
+// UnsnoozeAlert clears an alert's snooze
+func (h *AlertHandler) UnsnoozeAlert(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	alertID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert ID"})
+		return
+	}
+
+	if !h.authorizeAlertOwner(c, alertID, userID.(uuid.UUID)) {
+		return
+	}
+
+	alert, err := h.alertService.UnsnoozeAlert(c.Request.Context(), alertID, userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, alert)
+}
+
+// AcknowledgeAlert records the current user's acknowledgement on the latest history entry
+func (h *AlertHandler) AcknowledgeAlert(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	alertID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert ID"})
+		return
+	}
+
+	if !h.authorizeAlertOwner(c, alertID, userID.(uuid.UUID)) {
+		return
+	}
+
+	entry, err := h.alertService.AcknowledgeAlert(c.Request.Context(), alertID, userID.(uuid.UUID))
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No alert history to acknowledge"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// authorizeAlertOwner writes a 404/403 response and returns false unless the user owns the alert
+func (h *AlertHandler) authorizeAlertOwner(c *gin.Context, alertID, userID uuid.UUID) bool {
+	alert, err := h.alertService.GetAlertByID(c.Request.Context(), alertID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+		return false
+	}
+
+	if alert.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized"})
+		return false
+	}
+
+	return true
+}
+
 // GetAlertHistory returns the history of alert triggers
 func (h *AlertHandler) GetAlertHistory(c *gin.Context) {
 	userID, exists := c.Get("userID")
@@ -252,3 +453,59 @@ func (h *AlertHandler) GetAlertHistory(c *gin.Context) {
 
 	c.JSON(http.StatusOK, history)
 }
+
+// GetDigestSettings returns the authenticated user's daily alert digest settings
+func (h *AlertHandler) GetDigestSettings(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	settings, err := h.alertService.GetDigestSettings(c.Request.Context(), userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// UpdateDigestSettings turns digest mode on or off and sets when the digest is sent.
+// While enabled, non-critical alert triggers are queued instead of notified immediately.
+func (h *AlertHandler) UpdateDigestSettings(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.UpdateAlertDigestSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := h.alertService.UpdateDigestSettings(c.Request.Context(), userID.(uuid.UUID), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// respondActiveLimitError responds 409 with the current count and limit when err is an
+// *services.ActiveLimitError, and reports whether it did
+func respondActiveLimitError(c *gin.Context, err error) bool {
+	var limitErr *services.ActiveLimitError
+	if !errors.As(err, &limitErr) {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{
+		"error": limitErr.Error(),
+		"count": limitErr.Count,
+		"limit": limitErr.Limit,
+	})
+	return true
+}
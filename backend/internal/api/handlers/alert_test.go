@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/repository"
+	"github.com/mitsume/backend/internal/services"
+)
+
+func TestPreviewAlert_RejectsQueryOutsideAllowedCatalogs(t *testing.T) {
+	userID := uuid.New()
+	roles := repository.NewMockRoleRepository()
+	roles.AllowedCatalogs[userID] = []string{"hive"}
+
+	queryID := uuid.New()
+	postgres := "postgres"
+	queries := fakeSavedQueries{
+		queryID: {ID: queryID, UserID: userID, QueryText: "SELECT amount FROM orders", Catalog: &postgres},
+	}
+	// No alert service: the preview must be rejected before the query runs
+	h := &AlertHandler{savedQueries: queries, roleService: services.NewRoleService(roles), defaultCatalog: "hive"}
+
+	c, w := createTestContext(http.MethodPost, "/api/alerts/preview", models.PreviewAlertRequest{
+		QueryID:           queryID,
+		ConditionColumn:   "amount",
+		ConditionOperator: models.OperatorGreaterThan,
+		ConditionValue:    "50",
+	})
+	c.Set("userID", userID)
+	h.PreviewAlert(c)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("PreviewAlert() status = %d, want %d: %s", w.Code, http.StatusForbidden, w.Body.String())
+	}
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/services"
+)
+
+// AnnotationHandler handles chart annotation API requests
+type AnnotationHandler struct {
+	annotationService *services.AnnotationService
+}
+
+// NewAnnotationHandler creates a new annotation handler
+func NewAnnotationHandler(annotationService *services.AnnotationService) *AnnotationHandler {
+	return &AnnotationHandler{
+		annotationService: annotationService,
+	}
+}
+
+// GetAnnotations returns the user's personal annotations and, with ?dashboard_id=, the dashboard's.
+// ?from= and ?to= (RFC 3339) bound the time range.
+// GET /annotations
+func (h *AnnotationHandler) GetAnnotations(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	var dashboardID *uuid.UUID
+	if raw := c.Query("dashboard_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dashboard_id"})
+			return
+		}
+		dashboardID = &id
+	}
+
+	from, err := parseOptionalTimeQuery(c, "from")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from: must be RFC 3339"})
+		return
+	}
+	to, err := parseOptionalTimeQuery(c, "to")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to: must be RFC 3339"})
+		return
+	}
+
+	annotations, err := h.annotationService.ListAnnotations(c.Request.Context(), userID, dashboardID, from, to)
+	if err != nil {
+		respondAnnotationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, annotations)
+}
+
+// CreateAnnotation creates a personal or dashboard annotation
+// POST /annotations
+func (h *AnnotationHandler) CreateAnnotation(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	var req models.CreateAnnotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	annotation, err := h.annotationService.CreateAnnotation(c.Request.Context(), userID, &req)
+	if err != nil {
+		respondAnnotationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, annotation)
+}
+
+// UpdateAnnotation updates an annotation
+// PUT /annotations/:id
+func (h *AnnotationHandler) UpdateAnnotation(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid annotation id"})
+		return
+	}
+
+	var req models.UpdateAnnotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	annotation, err := h.annotationService.UpdateAnnotation(c.Request.Context(), id, userID, &req)
+	if err != nil {
+		respondAnnotationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, annotation)
+}
+
+// DeleteAnnotation deletes an annotation
+// DELETE /annotations/:id
+func (h *AnnotationHandler) DeleteAnnotation(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid annotation id"})
+		return
+	}
+
+	if err := h.annotationService.DeleteAnnotation(c.Request.Context(), id, userID); err != nil {
+		respondAnnotationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Annotation deleted"})
+}
+
+func respondAnnotationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "annotation not found"})
+	case errors.Is(err, services.ErrPermissionDenied):
+		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func parseOptionalTimeQuery(c *gin.Context, key string) (*time.Time, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
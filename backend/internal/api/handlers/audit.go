@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/services"
+)
+
+// AuditHandler serves the admin audit log
+type AuditHandler struct {
+	auditService *services.AuditService
+}
+
+// NewAuditHandler creates a new audit log handler
+func NewAuditHandler(auditService *services.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// GetAuditLog returns audit log entries, newest first
+// GET /admin/audit-log?actor_id=&action=&from=&to=&limit=&offset=
+// from (inclusive) and to (exclusive) are RFC 3339.
+func (h *AuditHandler) GetAuditLog(c *gin.Context) {
+	filter := models.AuditLogFilter{Action: c.Query("action")}
+
+	if raw := c.Query("actor_id"); raw != "" {
+		actorID, err := uuid.Parse(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid actor_id"})
+			return
+		}
+		filter.ActorID = &actorID
+	}
+	from, err := parseOptionalTimeQuery(c, "from")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from: must be RFC 3339"})
+		return
+	}
+	to, err := parseOptionalTimeQuery(c, "to")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to: must be RFC 3339"})
+		return
+	}
+	filter.From, filter.To = from, to
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			filter.Limit = parsed
+		}
+	}
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil {
+			filter.Offset = parsed
+		}
+	}
+
+	resp, err := h.auditService.List(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
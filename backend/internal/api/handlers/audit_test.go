@@ -0,0 +1,19 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestGetAuditLog_InvalidDateRange(t *testing.T) {
+	handler := NewAuditHandler(nil)
+
+	for _, query := range []string{"?from=yesterday", "?to=2024-13-01"} {
+		c, w := createTestContext("GET", "/api/admin/audit-log"+query, nil)
+		handler.GetAuditLog(c)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("GetAuditLog(%s) status = %d, want %d", query, w.Code, http.StatusBadRequest)
+		}
+	}
+}
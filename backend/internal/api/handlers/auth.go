@@ -3,10 +3,14 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/api/middleware"
 	"github.com/mitsume/backend/internal/config"
 	"github.com/mitsume/backend/internal/models"
 	"github.com/mitsume/backend/internal/services"
@@ -15,12 +19,17 @@ import (
 )
 
 type AuthHandler struct {
-	authService *services.AuthService
-	oauthConfig *oauth2.Config
-	cfg         *config.Config
+	authService  *services.AuthService
+	oauthConfig  *oauth2.Config
+	oidc         *oidcProvider // nil when OIDC sign-in is not configured
+	cfg          *config.Config
+	auditService *services.AuditService // nil disables audit logging
+
+	loginLimiter services.RateLimiter // nil disables the per-account login limit
+	loginLimit   services.RateLimit
 }
 
-func NewAuthHandler(authService *services.AuthService, cfg *config.Config) *AuthHandler {
+func NewAuthHandler(authService *services.AuthService, cfg *config.Config, auditService *services.AuditService) *AuthHandler {
 	var oauthConfig *oauth2.Config
 	if cfg.Google.ClientID != "" && cfg.Google.ClientSecret != "" {
 		oauthConfig = &oauth2.Config{
@@ -33,12 +42,21 @@ func NewAuthHandler(authService *services.AuthService, cfg *config.Config) *Auth
 	}
 
 	return &AuthHandler{
-		authService: authService,
-		oauthConfig: oauthConfig,
-		cfg:         cfg,
+		authService:  authService,
+		oauthConfig:  oauthConfig,
+		oidc:         newOIDCProvider(cfg.OIDC),
+		cfg:          cfg,
+		auditService: auditService,
 	}
 }
 
+// SetLoginLimiter limits login attempts per account, whichever client IP they come from.
+// This complements the per-IP limit on /auth/login against distributed password guessing.
+func (h *AuthHandler) SetLoginLimiter(limiter services.RateLimiter, limit services.RateLimit) {
+	h.loginLimiter = limiter
+	h.loginLimit = limit
+}
+
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req models.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -62,8 +80,23 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	if h.loginLimiter != nil {
+		key := "login-account:" + strings.ToLower(strings.TrimSpace(req.Email))
+		allowed, retryAfter, err := h.loginLimiter.Allow(c.Request.Context(), key, h.loginLimit)
+		if err != nil {
+			log.Printf("Login rate limiter error: %v", err)
+		} else if !allowed {
+			middleware.AbortRateLimited(c, retryAfter)
+			return
+		}
+	}
+
 	resp, err := h.authService.Login(c.Request.Context(), &req)
 	if err != nil {
+		if errors.Is(err, services.ErrUserNotActive) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
@@ -71,6 +104,178 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authService.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrSessionExpired) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrUserNotActive) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.Logout(c.Request.Context(), req.RefreshToken); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// ChangePassword replaces the current user's password. Other sessions are signed out;
+// the response carries fresh tokens for the caller.
+// POST /auth/change-password
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authService.ChangePassword(c.Request.Context(), userID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidCurrentPassword):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrNoLocalPassword), errors.Is(err, services.ErrPasswordTooShort):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// UpdateUserStatus enables or disables a user account (admin only).
+// Disabled users are rejected on their next request, even with an unexpired token.
+// PUT /admin/users/:userId/status
+func (h *AuthHandler) UpdateUserStatus(c *gin.Context) {
+	adminUserID := c.MustGet("userID").(uuid.UUID)
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req models.UpdateUserStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.SetUserStatus(c.Request.Context(), adminUserID, userID, req.Status); err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		case errors.Is(err, services.ErrInvalidRequest):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot change your own status"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), adminUserID, models.AuditActionUserSetStatus, models.AuditTargetUser, userID,
+		map[string]interface{}{"status": req.Status})
+
+	c.JSON(http.StatusOK, gin.H{"status": req.Status})
+}
+
+// ApproveUsers activates several pending users at once (admin only), optionally assigning them
+// a role. Users that cannot be approved are reported per ID instead of failing the request.
+// POST /admin/users/approve-bulk
+func (h *AuthHandler) ApproveUsers(c *gin.Context) {
+	adminUserID := c.MustGet("userID").(uuid.UUID)
+
+	var req models.ApproveUsersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.authService.ApproveUsers(c.Request.Context(), adminUserID, req.UserIDs, req.RoleID)
+	if err != nil {
+		if errors.Is(err, services.ErrRoleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "role not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, result := range resp.Results {
+		if result.Outcome != models.UserApprovalApproved {
+			continue
+		}
+		metadata := map[string]interface{}{"bulk": true}
+		if req.RoleID != nil {
+			metadata["role_id"] = *req.RoleID
+		}
+		h.auditService.Record(c.Request.Context(), adminUserID, models.AuditActionUserApprove, models.AuditTargetUser, result.UserID, metadata)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ResetPassword sets a generated temporary password for a local user (admin only) and returns it.
+// The user's existing sessions are signed out.
+// POST /admin/users/:userId/reset-password
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	adminUserID := c.MustGet("userID").(uuid.UUID)
+	userID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	password, err := h.authService.ResetPassword(c.Request.Context(), adminUserID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		case errors.Is(err, services.ErrInvalidRequest):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot reset your own password; use change-password instead"})
+		case errors.Is(err, services.ErrNoLocalPassword):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// The password itself is never recorded
+	h.auditService.Record(c.Request.Context(), adminUserID, models.AuditActionUserResetPassword, models.AuditTargetUser, userID, nil)
+
+	c.JSON(http.StatusOK, models.ResetPasswordResponse{TemporaryPassword: password})
+}
+
 func (h *AuthHandler) Me(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
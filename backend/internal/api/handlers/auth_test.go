@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mitsume/backend/internal/config"
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/repository"
+	"github.com/mitsume/backend/internal/services"
+)
+
+func setupAuthHandlerTest(limit services.RateLimit) *AuthHandler {
+	cfg := &config.Config{JWT: config.JWTConfig{Secret: "test-secret", ExpireHour: 1}}
+	authService := services.NewAuthService(cfg, repository.NewMockUserRepository(), nil, nil)
+	handler := NewAuthHandler(authService, cfg, nil)
+	handler.SetLoginLimiter(services.NewMemoryRateLimiter(), limit)
+	return handler
+}
+
+func login(handler *AuthHandler, email, remoteAddr string) int {
+	c, w := createTestContext("POST", "/api/auth/login", models.LoginRequest{Email: email, Password: "wrong"})
+	c.Request.RemoteAddr = remoteAddr
+	handler.Login(c)
+	return w.Code
+}
+
+func TestLogin_PerAccountLimitAcrossIPs(t *testing.T) {
+	handler := setupAuthHandlerTest(services.RateLimit{PerMinute: 1, Burst: 2})
+
+	if code := login(handler, "alice@example.com", "10.0.0.1:1234"); code != http.StatusUnauthorized {
+		t.Fatalf("first login status = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := login(handler, "Alice@Example.com ", "10.0.0.2:1234"); code != http.StatusUnauthorized {
+		t.Fatalf("second login status = %d, want %d", code, http.StatusUnauthorized)
+	}
+
+	c, w := createTestContext("POST", "/api/auth/login", models.LoginRequest{Email: "alice@example.com", Password: "wrong"})
+	c.Request.RemoteAddr = "10.0.0.3:1234"
+	handler.Login(c)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("third login status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Fatal("expected Retry-After header")
+	}
+
+	// Other accounts are unaffected
+	if code := login(handler, "bob@example.com", "10.0.0.3:1234"); code != http.StatusUnauthorized {
+		t.Fatalf("other account login status = %d, want %d", code, http.StatusUnauthorized)
+	}
+}
+
+func TestLogin_NoLimiter(t *testing.T) {
+	cfg := &config.Config{JWT: config.JWTConfig{Secret: "test-secret", ExpireHour: 1}}
+	handler := NewAuthHandler(services.NewAuthService(cfg, repository.NewMockUserRepository(), nil, nil), cfg, nil)
+
+	for i := 0; i < 5; i++ {
+		if code := login(handler, "alice@example.com", "10.0.0.1:1234"); code != http.StatusUnauthorized {
+			t.Fatalf("login %d status = %d, want %d", i, code, http.StatusUnauthorized)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/repository"
+	"github.com/mitsume/backend/internal/services"
+)
+
+type ChartThemeHandler struct {
+	repo        repository.ChartThemeRepository
+	roleService *services.RoleService
+}
+
+func NewChartThemeHandler(repo repository.ChartThemeRepository, roleService *services.RoleService) *ChartThemeHandler {
+	return &ChartThemeHandler{repo: repo, roleService: roleService}
+}
+
+// GetChartThemes returns the system themes and the user's own themes
+// GET /chart-themes
+func (h *ChartThemeHandler) GetChartThemes(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	themes, err := h.repo.GetAll(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch themes"})
+		return
+	}
+
+	if themes == nil {
+		themes = []models.ChartTheme{}
+	}
+
+	c.JSON(http.StatusOK, themes)
+}
+
+// CreateChartTheme creates a theme for the user, or a system theme when is_system is set (admins only)
+// POST /chart-themes
+func (h *ChartThemeHandler) CreateChartTheme(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	var req models.CreateChartThemeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	if len(req.Name) > models.MaxChartThemeNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name too long"})
+		return
+	}
+
+	if err := models.ValidateChartThemeConfig(req.Config); err != nil {
+		var validationErr *models.ValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Message, "field": validationErr.Field})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.IsSystem {
+		isAdmin, err := h.roleService.IsAdmin(c.Request.Context(), userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check permissions"})
+			return
+		}
+		if !isAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only admins can create system themes"})
+			return
+		}
+	}
+
+	theme, err := h.repo.Create(c.Request.Context(), userID, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create theme"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, theme)
+}
+
+// DeleteChartTheme deletes one of the user's themes, or a system theme for admins
+// DELETE /chart-themes/:id
+func (h *ChartThemeHandler) DeleteChartTheme(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	themeID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid theme id"})
+		return
+	}
+
+	isAdmin, err := h.roleService.IsAdmin(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check permissions"})
+		return
+	}
+
+	if err := h.repo.Delete(c.Request.Context(), themeID, userID, isAdmin); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "theme not found or cannot be deleted"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete theme"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
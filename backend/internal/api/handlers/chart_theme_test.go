@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/repository"
+	"github.com/mitsume/backend/internal/services"
+)
+
+func setupChartThemeHandlerTest() (*ChartThemeHandler, *repository.MockChartThemeRepository, *repository.MockRoleRepository) {
+	themeRepo := repository.NewMockChartThemeRepository()
+	roleRepo := repository.NewMockRoleRepository()
+	return NewChartThemeHandler(themeRepo, services.NewRoleService(roleRepo)), themeRepo, roleRepo
+}
+
+func createChartTheme(handler *ChartThemeHandler, userID uuid.UUID, body interface{}) int {
+	c, w := createTestContext("POST", "/api/chart-themes", body)
+	c.Set("userID", userID)
+	handler.CreateChartTheme(c)
+	return w.Code
+}
+
+func TestCreateChartTheme_SystemThemeRequiresAdmin(t *testing.T) {
+	handler, themeRepo, roleRepo := setupChartThemeHandlerTest()
+	user, admin := uuid.New(), uuid.New()
+	roleRepo.AdminUsers[admin] = true
+	req := map[string]interface{}{"name": "Brand", "config": map[string]interface{}{"colorScheme": "vivid"}, "is_system": true}
+
+	if code := createChartTheme(handler, user, req); code != http.StatusForbidden {
+		t.Fatalf("non-admin status = %d, want %d", code, http.StatusForbidden)
+	}
+	if code := createChartTheme(handler, admin, req); code != http.StatusCreated {
+		t.Fatalf("admin status = %d, want %d", code, http.StatusCreated)
+	}
+	if len(themeRepo.Themes) != 1 || !themeRepo.Themes[0].IsSystem || themeRepo.Themes[0].UserID != nil {
+		t.Fatalf("themes = %+v, want one system theme without an owner", themeRepo.Themes)
+	}
+}
+
+func TestCreateChartTheme_RejectsInvalidConfig(t *testing.T) {
+	handler, themeRepo, _ := setupChartThemeHandlerTest()
+
+	code := createChartTheme(handler, uuid.New(), map[string]interface{}{
+		"name":   "Mine",
+		"config": map[string]interface{}{"customColors": []string{"javascript:alert(1)"}},
+	})
+
+	if code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", code, http.StatusBadRequest)
+	}
+	if len(themeRepo.Themes) != 0 {
+		t.Fatal("no theme should be created")
+	}
+}
+
+func TestGetChartThemes_ReturnsSystemAndOwnThemes(t *testing.T) {
+	handler, themeRepo, _ := setupChartThemeHandlerTest()
+	user := uuid.New()
+	ctx := context.Background()
+	themeRepo.Create(ctx, uuid.New(), &models.CreateChartThemeRequest{Name: "System", Config: json.RawMessage(`{}`), IsSystem: true})
+	themeRepo.Create(ctx, user, &models.CreateChartThemeRequest{Name: "Mine", Config: json.RawMessage(`{}`)})
+	themeRepo.Create(ctx, uuid.New(), &models.CreateChartThemeRequest{Name: "Someone else's", Config: json.RawMessage(`{}`)})
+
+	c, w := createTestContext("GET", "/api/chart-themes", nil)
+	c.Set("userID", user)
+	handler.GetChartThemes(c)
+
+	var themes []models.ChartTheme
+	if err := json.Unmarshal(w.Body.Bytes(), &themes); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(themes) != 2 || themes[0].Name != "System" || themes[1].Name != "Mine" {
+		t.Fatalf("themes = %+v, want the system theme and the user's own", themes)
+	}
+}
+
+func TestDeleteChartTheme_SystemThemeRequiresAdmin(t *testing.T) {
+	handler, themeRepo, roleRepo := setupChartThemeHandlerTest()
+	user, admin := uuid.New(), uuid.New()
+	roleRepo.AdminUsers[admin] = true
+	theme, _ := themeRepo.Create(context.Background(), admin, &models.CreateChartThemeRequest{Name: "System", Config: json.RawMessage(`{}`), IsSystem: true})
+
+	deleteTheme := func(userID uuid.UUID) int {
+		c, _ := createTestContext("DELETE", "/api/chart-themes/"+theme.ID.String(), nil)
+		c.Set("userID", userID)
+		c.Params = gin.Params{{Key: "id", Value: theme.ID.String()}}
+		handler.DeleteChartTheme(c)
+		return c.Writer.Status() // 204 has no body, so the recorder's code is never written
+	}
+
+	if code := deleteTheme(user); code != http.StatusNotFound {
+		t.Fatalf("non-admin status = %d, want %d", code, http.StatusNotFound)
+	}
+	if code := deleteTheme(admin); code != http.StatusNoContent {
+		t.Fatalf("admin status = %d, want %d", code, http.StatusNoContent)
+	}
+}
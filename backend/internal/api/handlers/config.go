@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mitsume/backend/internal/config"
+	"github.com/mitsume/backend/internal/models"
+)
+
+type ConfigHandler struct {
+	cfg *config.Config
+}
+
+func NewConfigHandler(cfg *config.Config) *ConfigHandler {
+	return &ConfigHandler{cfg: cfg}
+}
+
+// PublicConfig is the subset of server configuration the frontend needs.
+// Nothing secret may be added here: the endpoint is unauthenticated.
+type PublicConfig struct {
+	AllowedChartTypes []string `json:"allowed_chart_types"`
+}
+
+// GetPublicConfig returns client-facing configuration
+// GET /config
+func (h *ConfigHandler) GetPublicConfig(c *gin.Context) {
+	allowed := h.cfg.Dashboard.AllowedChartTypes
+	if len(allowed) == 0 {
+		allowed = models.ChartTypes
+	}
+
+	c.JSON(http.StatusOK, PublicConfig{
+		AllowedChartTypes: allowed,
+	})
+}
@@ -1,29 +1,86 @@
 package handlers
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/mitsume/backend/internal/models"
 	"github.com/mitsume/backend/internal/repository"
 	"github.com/mitsume/backend/internal/services"
+	"golang.org/x/sync/errgroup"
 )
 
 var safeRawTokenPattern = regexp.MustCompile(`^[a-zA-Z0-9_.,:@/-]*$`)
 
+// dashboardViewer is the part of DashboardService used to check view access and read widgets
+type dashboardViewer interface {
+	GetUserPermissionLevel(ctx context.Context, dashboardID, userID uuid.UUID) (models.PermissionLevel, error)
+	IsDraft(ctx context.Context, dashboardID uuid.UUID) (bool, error)
+	GetWidget(ctx context.Context, dashboardID, widgetID uuid.UUID) (*models.Widget, error)
+	GetDashboardActivity(ctx context.Context, dashboardID uuid.UUID, limit, offset int) (*models.DashboardActivityResponse, error)
+	GetWidgets(ctx context.Context, dashboardID uuid.UUID) ([]models.Widget, error)
+	GetDashboardParameters(ctx context.Context, dashboardID uuid.UUID) (json.RawMessage, error)
+	GetDashboardTitle(ctx context.Context, dashboardID uuid.UUID) (string, *string, error)
+	GetDashboardOwner(ctx context.Context, dashboardID uuid.UUID) (uuid.UUID, error)
+	GetDashboardExecutionDefaults(ctx context.Context, dashboardID uuid.UUID) (*string, *string, error)
+	GetDashboardLocation(ctx context.Context, dashboardID uuid.UUID) (*time.Location, error)
+}
+
+// dashboardArchiver is the part of DashboardService used to archive and restore dashboards
+type dashboardArchiver interface {
+	SetArchived(ctx context.Context, id, userID uuid.UUID, archived bool) error
+}
+
+// dashboardTrash is the part of DashboardService used to list and restore soft-deleted dashboards
+type dashboardTrash interface {
+	GetTrash(ctx context.Context, userID uuid.UUID) ([]models.Dashboard, error)
+	RestoreDashboard(ctx context.Context, id, userID uuid.UUID) error
+}
+
+// dashboardVersions is the part of DashboardService used to list and restore dashboard versions
+type dashboardVersions interface {
+	GetDashboardVersions(ctx context.Context, dashboardID, userID uuid.UUID) ([]models.DashboardVersion, error)
+	RestoreDashboardVersion(ctx context.Context, dashboardID uuid.UUID, version int, userID uuid.UUID) (*models.Dashboard, error)
+}
+
+// savedQueryReader is the part of QueryService used to load a widget's query
+type savedQueryReader interface {
+	GetSavedQueryByID(ctx context.Context, id uuid.UUID) (*models.SavedQuery, error)
+}
+
 type DashboardHandler struct {
-	dashboardService *services.DashboardService
-	trinoService     repository.CachedTrinoExecutor
-	queryService     *services.QueryService
-	roleService      *services.RoleService
-	defaultCatalog   string
-	defaultSchema    string
+	dashboardService  *services.DashboardService
+	viewer            dashboardViewer   // dashboardService; separate so read paths can be tested without a database
+	archiver          dashboardArchiver // dashboardService; separate so archiving can be tested without a database
+	trash             dashboardTrash    // dashboardService; separate so the trash can be tested without a database
+	versions          dashboardVersions // dashboardService; separate so version history can be tested without a database
+	trinoService      repository.CachedTrinoExecutor
+	queryService      *services.QueryService
+	savedQueries      savedQueryReader          // queryService; separate so dashboard rendering can be tested without a database
+	statements        services.StatementChecker // queryService; nil accepts any statement
+	roleService       *services.RoleService
+	defaultCatalog    string
+	defaultSchema     string
+	allowedChartTypes []string
+	maxParameters     int                           // 0 disables the per-dashboard parameter limit
+	widgetHealth      *services.WidgetHealthService // nil disables widget error tracking
+	annotations       *services.AnnotationService   // nil disables annotations in widget data
+	auditService      *services.AuditService        // nil disables audit logging
+	renderConcurrency int                           // widget queries a render runs at once; 0 uses defaultRenderConcurrency
+	parameterTerms    parameterTermFilter           // zero value allows every raw and identifier value
 }
 
 func NewDashboardHandler(
@@ -33,29 +90,203 @@ func NewDashboardHandler(
 	roleService *services.RoleService,
 	defaultCatalog string,
 	defaultSchema string,
+	allowedChartTypes []string,
+	maxParameters int,
+	widgetHealth *services.WidgetHealthService,
+	annotations *services.AnnotationService,
+	auditService *services.AuditService,
 ) *DashboardHandler {
 	return &DashboardHandler{
-		dashboardService: dashboardService,
-		trinoService:     trinoService,
-		queryService:     queryService,
-		roleService:      roleService,
-		defaultCatalog:   defaultCatalog,
-		defaultSchema:    defaultSchema,
+		dashboardService:  dashboardService,
+		viewer:            dashboardService,
+		archiver:          dashboardService,
+		trash:             dashboardService,
+		versions:          dashboardService,
+		trinoService:      trinoService,
+		queryService:      queryService,
+		savedQueries:      queryService,
+		statements:        queryService,
+		roleService:       roleService,
+		defaultCatalog:    defaultCatalog,
+		defaultSchema:     defaultSchema,
+		allowedChartTypes: allowedChartTypes,
+		maxParameters:     maxParameters,
+		widgetHealth:      widgetHealth,
+		annotations:       annotations,
+		auditService:      auditService,
+	}
+}
+
+// recordWidgetOutcome persists the widget's error state after a data fetch.
+// Failures here are logged only; they must not affect the widget response.
+func (h *DashboardHandler) recordWidgetOutcome(ctx context.Context, widget *models.Widget, queryErr error) {
+	if h.widgetHealth == nil {
+		return
+	}
+	if queryErr != nil {
+		if err := h.widgetHealth.RecordFailure(ctx, widget, queryErr.Error()); err != nil {
+			log.Printf("Failed to record error state for widget %s: %v", widget.ID, err)
+		}
+		return
+	}
+	if widget.LastError != nil {
+		if err := h.widgetHealth.RecordSuccess(ctx, widget.ID); err != nil {
+			log.Printf("Failed to clear error state for widget %s: %v", widget.ID, err)
+		}
+	}
+}
+
+// recordQueryHistory adds a query_history entry for an execution on behalf of the viewing user,
+// so dashboard data access shows up in history and audit like ad-hoc queries do
+func (h *DashboardHandler) recordQueryHistory(ctx context.Context, userID uuid.UUID, queryText string, source models.QueryHistorySource, sourceID uuid.UUID, result *models.QueryResult, queryErr error) {
+	if h.queryService == nil {
+		return
+	}
+	services.RecordQueryExecution(ctx, h.queryService, userID, queryText, source, &sourceID, result, queryErr)
+}
+
+// widgetAnnotations returns the annotations within the time range of a time-series widget's result.
+// Failures are logged only; the widget is still served without annotations.
+func (h *DashboardHandler) widgetAnnotations(ctx context.Context, dashboardID, userID uuid.UUID, widget *models.Widget, result *models.QueryResult) []models.Annotation {
+	if h.annotations == nil {
+		return nil
+	}
+	from, to, ok := services.WidgetTimeWindow(widget, result)
+	if !ok {
+		return nil
+	}
+	annotations, err := h.annotations.GetAnnotationsForWidget(ctx, dashboardID, userID, from, to)
+	if err != nil {
+		log.Printf("Failed to load annotations for widget %s: %v", widget.ID, err)
+		return nil
+	}
+	return annotations
+}
+
+// widgetMaxStaleness returns how old a cached result may be when served for the widget (0 means no limit)
+func widgetMaxStaleness(widget *models.Widget) time.Duration {
+	if widget.MaxStalenessSeconds == nil {
+		return 0
+	}
+	return time.Duration(*widget.MaxStalenessSeconds) * time.Second
+}
+
+// executeWidgetQuery runs a widget's resolved query through the cache, or bypasses the cache and
+// repopulates it when refresh is set. The widget's column aliases are applied to the result.
+func (h *DashboardHandler) executeWidgetQuery(ctx context.Context, widget *models.Widget, query, catalog, schema string, refresh bool) (*models.QueryResult, error) {
+	savedQueryID := widget.QueryID
+	if widget.HasInlineQuery() {
+		savedQueryID = nil
+	}
+
+	var result *models.QueryResult
+	var err error
+	if refresh {
+		result, err = h.trinoService.RefreshQuery(ctx, query, catalog, schema, int(services.CachePriorityNormal), savedQueryID)
+	} else {
+		result, err = h.trinoService.ExecuteQueryWithMaxStaleness(ctx, query, catalog, schema, int(services.CachePriorityNormal), savedQueryID, widgetMaxStaleness(widget))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return models.ApplyColumnAliases(nonEmptyWidgetResult(result), models.ColumnAliases(widget.ChartConfig))
+}
+
+// widgetDataETag identifies the widget data served from one cache entry. The resolved query text
+// carries the parameter values, so each parameter set gets its own tag; the widget's last edit
+// covers its chart config, and the cache timestamp changes whenever the result is re-computed.
+func widgetDataETag(widget *models.Widget, query, catalog, schema string, cachedAt time.Time) string {
+	data := fmt.Sprintf("%s|%d|%d:%s|%d:%s|%d:%s|%d", widget.ID, widget.UpdatedAt.UnixNano(),
+		len(query), query, len(catalog), catalog, len(schema), schema, cachedAt.UnixNano())
+	hash := sha256.Sum256([]byte(data))
+	return `"` + hex.EncodeToString(hash[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header names etag. Weak tags compare equal to
+// their strong form, as RFC 9110 requires for If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// setWidgetCacheHeaders sets the ETag and a private Cache-Control lasting until the cache entry
+// expires
+func setWidgetCacheHeaders(c *gin.Context, etag string, expiresAt, now time.Time) {
+	maxAge := int(expiresAt.Sub(now).Seconds())
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", maxAge))
+}
+
+// widgetDataNotModified answers 304 Not Modified, without running the query, when If-None-Match
+// names the result currently cached for the widget's resolved query
+func (h *DashboardHandler) widgetDataNotModified(c *gin.Context, widget *models.Widget, query, catalog, schema string) bool {
+	ifNoneMatch := c.GetHeader("If-None-Match")
+	if ifNoneMatch == "" {
+		return false
+	}
+	cachedAt, expiresAt, ok := h.trinoService.CachedResultTime(c.Request.Context(), query, catalog, schema, int(services.CachePriorityNormal), widgetMaxStaleness(widget))
+	if !ok {
+		return false
+	}
+	etag := widgetDataETag(widget, query, catalog, schema, cachedAt)
+	if !etagMatches(ifNoneMatch, etag) {
+		return false
+	}
+	setWidgetCacheHeaders(c, etag, expiresAt, time.Now())
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// setWidgetDataCacheHeaders tags widget data that was just served with its cache entry. Results
+// that were not cached (caching disabled) carry no ETag and must be revalidated.
+func (h *DashboardHandler) setWidgetDataCacheHeaders(c *gin.Context, widget *models.Widget, query, catalog, schema string) {
+	cachedAt, expiresAt, ok := h.trinoService.CachedResultTime(c.Request.Context(), query, catalog, schema, int(services.CachePriorityNormal), widgetMaxStaleness(widget))
+	if !ok {
+		c.Header("Cache-Control", "private, no-cache")
+		return
+	}
+	setWidgetCacheHeaders(c, widgetDataETag(widget, query, catalog, schema, cachedAt), expiresAt, time.Now())
+}
+
+// nonEmptyWidgetResult makes a query that matched nothing answer with its columns, "rows": []
+// and row_count 0, so clients can tell it apart from a failed query. Results cached before
+// empty rows were normalized may still hold nil slices. The result may be shared with the
+// cache, so it is copied rather than modified.
+func nonEmptyWidgetResult(result *models.QueryResult) *models.QueryResult {
+	if result.Rows != nil && result.Columns != nil && result.RowCount == len(result.Rows) {
+		return result
 	}
+	normalized := *result
+	if normalized.Columns == nil {
+		normalized.Columns = []string{}
+	}
+	if normalized.Rows == nil {
+		normalized.Rows = [][]interface{}{}
+	}
+	normalized.RowCount = len(normalized.Rows)
+	return &normalized
 }
 
 // checkDashboardViewPermission checks if user has appropriate permission to view dashboard content.
 // For drafts (is_draft=true): requires edit permission (only editors/owners can access)
 // For published dashboards: requires view permission
 // Returns the permission level and any error. If permission is denied, returns ErrPermissionDenied.
-func (h *DashboardHandler) checkDashboardViewPermission(ctx gin.Context, dashboardID, userID uuid.UUID) (models.PermissionLevel, error) {
-	permLevel, err := h.dashboardService.GetUserPermissionLevel(ctx.Request.Context(), dashboardID, userID)
+func (h *DashboardHandler) checkDashboardViewPermission(ctx *gin.Context, dashboardID, userID uuid.UUID) (models.PermissionLevel, error) {
+	permLevel, err := h.viewer.GetUserPermissionLevel(ctx.Request.Context(), dashboardID, userID)
 	if err != nil {
 		return models.PermissionNone, err
 	}
 
 	// Check if this is a draft
-	isDraft, err := h.dashboardService.IsDraft(ctx.Request.Context(), dashboardID)
+	isDraft, err := h.viewer.IsDraft(ctx.Request.Context(), dashboardID)
 	if err != nil {
 		return models.PermissionNone, err
 	}
@@ -78,7 +309,17 @@ func (h *DashboardHandler) checkDashboardViewPermission(ctx gin.Context, dashboa
 func (h *DashboardHandler) GetDashboards(c *gin.Context) {
 	userID := c.MustGet("userID").(uuid.UUID)
 
-	dashboards, err := h.dashboardService.GetDashboards(c.Request.Context(), userID)
+	var archived bool
+	switch filter := c.Query("filter"); filter {
+	case "":
+	case models.DashboardFilterArchived:
+		archived = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown filter: " + filter})
+		return
+	}
+
+	dashboards, err := h.dashboardService.GetDashboards(c.Request.Context(), userID, archived)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -101,6 +342,10 @@ func (h *DashboardHandler) GetDashboard(c *gin.Context) {
 
 	dashboard, err := h.dashboardService.GetDashboard(c.Request.Context(), dashboardID, userID)
 	if err != nil {
+		if errors.Is(err, services.ErrPermissionDenied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": "dashboard not found"})
 		return
 	}
@@ -119,6 +364,10 @@ func (h *DashboardHandler) CreateDashboard(c *gin.Context) {
 
 	dashboard, err := h.dashboardService.CreateDashboard(c.Request.Context(), userID, &req)
 	if err != nil {
+		if errors.Is(err, services.ErrDuplicateDashboardName) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "field": "name"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -140,6 +389,11 @@ func (h *DashboardHandler) UpdateDashboard(c *gin.Context) {
 		return
 	}
 
+	if err := models.ValidateDashboardParameters(req.Parameters, h.maxParameters); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
 	dashboard, err := h.dashboardService.UpdateDashboard(c.Request.Context(), dashboardID, userID, &req)
 	if err != nil {
 		if errors.Is(err, services.ErrNotFound) {
@@ -150,6 +404,15 @@ func (h *DashboardHandler) UpdateDashboard(c *gin.Context) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
 			return
 		}
+		if errors.Is(err, services.ErrDuplicateDashboardName) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "field": "name"})
+			return
+		}
+		var validationErr *models.ValidationError
+		if errors.As(err, &validationErr) {
+			respondValidationError(c, err)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -157,6 +420,46 @@ func (h *DashboardHandler) UpdateDashboard(c *gin.Context) {
 	c.JSON(http.StatusOK, dashboard)
 }
 
+// ArchiveDashboard hides a dashboard from the default list (owner only)
+// POST /dashboards/:id/archive
+func (h *DashboardHandler) ArchiveDashboard(c *gin.Context) {
+	h.setArchived(c, true)
+}
+
+// UnarchiveDashboard returns an archived dashboard to the default list (owner only)
+// POST /dashboards/:id/unarchive
+func (h *DashboardHandler) UnarchiveDashboard(c *gin.Context) {
+	h.setArchived(c, false)
+}
+
+func (h *DashboardHandler) setArchived(c *gin.Context, archived bool) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	dashboardID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dashboard id"})
+		return
+	}
+
+	if err := h.archiver.SetArchived(c.Request.Context(), dashboardID, userID, archived); err != nil {
+		if errors.Is(err, services.ErrNotFound) || errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "dashboard not found"})
+			return
+		}
+		if errors.Is(err, services.ErrPermissionDenied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only the owner can archive a dashboard"})
+			return
+		}
+		if errors.Is(err, services.ErrInvalidRequest) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": dashboardID, "is_archived": archived})
+}
+
 func (h *DashboardHandler) DeleteDashboard(c *gin.Context) {
 	userID := c.MustGet("userID").(uuid.UUID)
 	dashboardID, err := uuid.Parse(c.Param("id"))
@@ -166,7 +469,7 @@ func (h *DashboardHandler) DeleteDashboard(c *gin.Context) {
 	}
 
 	if err := h.dashboardService.DeleteDashboard(c.Request.Context(), dashboardID, userID); err != nil {
-		if errors.Is(err, services.ErrNotFound) {
+		if errors.Is(err, services.ErrNotFound) || errors.Is(err, repository.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "dashboard not found"})
 			return
 		}
@@ -181,6 +484,54 @@ func (h *DashboardHandler) DeleteDashboard(c *gin.Context) {
 	c.JSON(http.StatusNoContent, nil)
 }
 
+// GetTrash lists the user's soft-deleted dashboards
+// GET /dashboards/trash
+func (h *DashboardHandler) GetTrash(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	dashboards, err := h.trash.GetTrash(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if dashboards == nil {
+		dashboards = []models.Dashboard{}
+	}
+
+	c.JSON(http.StatusOK, dashboards)
+}
+
+// RestoreDashboard takes a dashboard out of the trash (owner only)
+// POST /dashboards/:id/restore
+func (h *DashboardHandler) RestoreDashboard(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	dashboardID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dashboard id"})
+		return
+	}
+
+	if err := h.trash.RestoreDashboard(c.Request.Context(), dashboardID, userID); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "dashboard not found in trash"})
+			return
+		}
+		if errors.Is(err, services.ErrPermissionDenied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "permission denied: only owner can restore"})
+			return
+		}
+		if errors.Is(err, services.ErrDuplicateDashboardName) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "field": "name"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": dashboardID, "restored": true})
+}
+
 // GetDraft returns the existing draft for a published dashboard (if any)
 // GET /dashboards/:id/draft
 func (h *DashboardHandler) GetDraft(c *gin.Context) {
@@ -240,6 +591,176 @@ func (h *DashboardHandler) CreateDraft(c *gin.Context) {
 	c.JSON(http.StatusCreated, draft)
 }
 
+// GetDashboardActivity returns recent changes to a dashboard and its widgets, newest first
+// GET /dashboards/:id/activity?limit=&offset=
+func (h *DashboardHandler) GetDashboardActivity(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	dashboardID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dashboard id"})
+		return
+	}
+
+	if _, err := h.checkDashboardViewPermission(c, dashboardID, userID); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "dashboard not found"})
+			return
+		}
+		if errors.Is(err, services.ErrPermissionDenied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var limit, offset int
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil {
+			limit = parsed
+		}
+	}
+	if o := c.Query("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil {
+			offset = parsed
+		}
+	}
+
+	resp, err := h.viewer.GetDashboardActivity(c.Request.Context(), dashboardID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// CloneDashboard creates a private copy of a dashboard owned by the caller
+// POST /dashboards/:id/clone
+func (h *DashboardHandler) CloneDashboard(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	dashboardID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dashboard id"})
+		return
+	}
+
+	clone, err := h.dashboardService.CloneDashboard(c.Request.Context(), dashboardID, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) || errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "dashboard not found"})
+			return
+		}
+		if errors.Is(err, services.ErrPermissionDenied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to clone dashboard"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, clone)
+}
+
+// ExportDashboard returns the dashboard as a portable JSON document (owner only)
+// GET /dashboards/:id/export
+func (h *DashboardHandler) ExportDashboard(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	dashboardID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dashboard id"})
+		return
+	}
+
+	export, err := h.dashboardService.ExportDashboard(c.Request.Context(), dashboardID, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) || errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "dashboard not found"})
+			return
+		}
+		if errors.Is(err, services.ErrPermissionDenied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export dashboard"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="dashboard-%s.json"`, dashboardID))
+	c.JSON(http.StatusOK, export)
+}
+
+// ImportDashboard recreates an exported dashboard, and the saved queries it uses, for the caller
+// POST /dashboards/import
+func (h *DashboardHandler) ImportDashboard(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	var doc models.DashboardExport
+	if err := c.ShouldBindJSON(&doc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.ValidateDashboardImport(&doc); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+	if err := models.ValidateDashboardParameters(doc.Dashboard.Parameters, h.maxParameters); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+	for i, w := range doc.Widgets {
+		if err := models.ValidateChartType(w.ChartType, h.allowedChartTypes); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s in widgets[%d]", err.Error(), i)})
+			return
+		}
+		if _, err := models.ValidateWidgetPosition(w.Position); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid position in widgets[%d]", i)})
+			return
+		}
+		if len(w.ResponsivePositions) > 0 {
+			if _, err := models.ValidateResponsivePositions(w.ResponsivePositions); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid responsive_positions in widgets[%d]", i)})
+				return
+			}
+		}
+		if err := models.ValidateChartConfig(w.ChartConfig); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid chart_config in widgets[%d]", i)})
+			return
+		}
+	}
+	// Imported SQL is held to read-only mode like SQL saved through the editor
+	if h.statements != nil {
+		if err := services.CheckDashboardImportStatements(&doc, h.statements); err != nil {
+			respondValidationError(c, err)
+			return
+		}
+	}
+
+	dashboard, err := h.dashboardService.ImportDashboard(c.Request.Context(), userID, &doc)
+	if err != nil {
+		var validationErr *models.ValidationError
+		if errors.As(err, &validationErr) {
+			respondValidationError(c, err)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import dashboard"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, dashboard)
+}
+
+// respondValidationError responds 400 with the field of a *models.ValidationError when available
+func respondValidationError(c *gin.Context, err error) {
+	var validationErr *models.ValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Message, "field": validationErr.Field})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
 // SaveAsDraft saves changes to an existing draft dashboard
 // POST /dashboards/:id/save-draft (where :id is the draft ID)
 func (h *DashboardHandler) SaveAsDraft(c *gin.Context) {
@@ -334,8 +855,71 @@ func (h *DashboardHandler) DiscardDraft(c *gin.Context) {
 	c.JSON(http.StatusNoContent, nil)
 }
 
-// Widget handlers
-
+// GetDashboardVersions lists the versions kept each time a draft was published, newest first
+// GET /dashboards/:id/versions
+func (h *DashboardHandler) GetDashboardVersions(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	dashboardID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dashboard id"})
+		return
+	}
+
+	versions, err := h.versions.GetDashboardVersions(c.Request.Context(), dashboardID, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) || errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "dashboard not found"})
+			return
+		}
+		if errors.Is(err, services.ErrPermissionDenied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+// RestoreDashboardVersion creates a draft from a previous version (edit permission)
+// POST /dashboards/:id/versions/:version/restore
+func (h *DashboardHandler) RestoreDashboardVersion(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	dashboardID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dashboard id"})
+		return
+	}
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid version"})
+		return
+	}
+
+	draft, err := h.versions.RestoreDashboardVersion(c.Request.Context(), dashboardID, version, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) || errors.Is(err, repository.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "version not found"})
+			return
+		}
+		if errors.Is(err, services.ErrPermissionDenied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+			return
+		}
+		if errors.Is(err, services.ErrDraftExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore version"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, draft)
+}
+
+// Widget handlers
+
 func (h *DashboardHandler) CreateWidget(c *gin.Context) {
 	userID := c.MustGet("userID").(uuid.UUID)
 	dashboardID, err := uuid.Parse(c.Param("id"))
@@ -350,6 +934,16 @@ func (h *DashboardHandler) CreateWidget(c *gin.Context) {
 		return
 	}
 
+	// Validate chart type against the configured allowlist
+	if err := models.ValidateChartType(req.ChartType, h.allowedChartTypes); err != nil {
+		if validationErr, ok := err.(*models.ValidationError); ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Message, "field": validationErr.Field})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Validate widget position
 	if _, err := models.ValidateWidgetPosition(req.Position); err != nil {
 		if validationErr, ok := err.(*models.ValidationError); ok {
@@ -382,8 +976,17 @@ func (h *DashboardHandler) CreateWidget(c *gin.Context) {
 		return
 	}
 
+	if err := h.checkWidgetQueryText(req.QueryText); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
 	widget, err := h.dashboardService.CreateWidget(c.Request.Context(), dashboardID, userID, &req)
 	if err != nil {
+		if errors.Is(err, services.ErrChartThemeNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "field": "theme_id"})
+			return
+		}
 		if errors.Is(err, services.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "dashboard or query not found"})
 			return
@@ -418,6 +1021,18 @@ func (h *DashboardHandler) UpdateWidget(c *gin.Context) {
 		return
 	}
 
+	// Validate chart type if provided
+	if req.ChartType != "" {
+		if err := models.ValidateChartType(req.ChartType, h.allowedChartTypes); err != nil {
+			if validationErr, ok := err.(*models.ValidationError); ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Message, "field": validationErr.Field})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	// Validate widget position if provided
 	if len(req.Position) > 0 {
 		if _, err := models.ValidateWidgetPosition(req.Position); err != nil {
@@ -454,8 +1069,17 @@ func (h *DashboardHandler) UpdateWidget(c *gin.Context) {
 		}
 	}
 
+	if err := h.checkWidgetQueryText(req.QueryText); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
 	widget, err := h.dashboardService.UpdateWidget(c.Request.Context(), widgetID, dashboardID, userID, &req)
 	if err != nil {
+		if errors.Is(err, services.ErrChartThemeNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "field": "theme_id"})
+			return
+		}
 		if errors.Is(err, services.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "dashboard or widget not found"})
 			return
@@ -471,6 +1095,47 @@ func (h *DashboardHandler) UpdateWidget(c *gin.Context) {
 	c.JSON(http.StatusOK, widget)
 }
 
+// GetWidget returns a single widget's configuration (view permission; edit for drafts)
+// GET /dashboards/:id/widgets/:widgetId
+func (h *DashboardHandler) GetWidget(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	dashboardID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dashboard id"})
+		return
+	}
+	widgetID, err := uuid.Parse(c.Param("widgetId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid widget id"})
+		return
+	}
+
+	if _, err := h.checkDashboardViewPermission(c, dashboardID, userID); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "dashboard not found"})
+			return
+		}
+		if errors.Is(err, services.ErrPermissionDenied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	widget, err := h.viewer.GetWidget(c.Request.Context(), dashboardID, widgetID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "widget not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, widget)
+}
+
 func (h *DashboardHandler) DeleteWidget(c *gin.Context) {
 	userID := c.MustGet("userID").(uuid.UUID)
 	dashboardID, err := uuid.Parse(c.Param("id"))
@@ -547,6 +1212,10 @@ func (h *DashboardHandler) BatchUpdateWidgets(c *gin.Context) {
 
 	// Validate create requests
 	for i, createReq := range req.Create {
+		if err := models.ValidateChartType(createReq.ChartType, h.allowedChartTypes); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s in create[%d]", err.Error(), i)})
+			return
+		}
 		if len(createReq.Position) > 0 {
 			if _, err := models.ValidateWidgetPosition(createReq.Position); err != nil {
 				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid position in create[%d]", i)})
@@ -563,10 +1232,20 @@ func (h *DashboardHandler) BatchUpdateWidgets(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid chart_config in create[%d]", i)})
 			return
 		}
+		if err := h.checkWidgetQueryText(createReq.QueryText); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid query_text in create[%d]: %s", i, err.Error())})
+			return
+		}
 	}
 
 	// Validate update requests
 	for widgetID, updateReq := range req.Update {
+		if updateReq.ChartType != "" {
+			if err := models.ValidateChartType(updateReq.ChartType, h.allowedChartTypes); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s in update[%s]", err.Error(), widgetID)})
+				return
+			}
+		}
 		if len(updateReq.Position) > 0 {
 			if _, err := models.ValidateWidgetPosition(updateReq.Position); err != nil {
 				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid position in update[%s]", widgetID)})
@@ -583,10 +1262,18 @@ func (h *DashboardHandler) BatchUpdateWidgets(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid chart_config in update[%s]", widgetID)})
 			return
 		}
+		if err := h.checkWidgetQueryText(updateReq.QueryText); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid query_text in update[%s]: %s", widgetID, err.Error())})
+			return
+		}
 	}
 
 	response, err := h.dashboardService.BatchUpdateWidgets(c.Request.Context(), dashboardID, userID, &req)
 	if err != nil {
+		if errors.Is(err, services.ErrChartThemeNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "field": "theme_id"})
+			return
+		}
 		if errors.Is(err, services.ErrPermissionDenied) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
 			return
@@ -665,6 +1352,14 @@ func (h *DashboardHandler) GrantPermission(c *gin.Context) {
 		return
 	}
 
+	h.auditService.Record(c.Request.Context(), userID, models.AuditActionDashboardGrant, models.AuditTargetDashboard, dashboardID,
+		map[string]interface{}{
+			"permission_id":    permission.ID,
+			"user_id":          req.UserID,
+			"role_id":          req.RoleID,
+			"permission_level": req.PermissionLevel,
+		})
+
 	c.JSON(http.StatusCreated, permission)
 }
 
@@ -694,6 +1389,9 @@ func (h *DashboardHandler) RevokePermission(c *gin.Context) {
 		return
 	}
 
+	h.auditService.Record(c.Request.Context(), userID, models.AuditActionDashboardRevoke, models.AuditTargetDashboard, dashboardID,
+		map[string]interface{}{"permission_id": permissionID})
+
 	c.JSON(http.StatusNoContent, nil)
 }
 
@@ -724,9 +1422,49 @@ func (h *DashboardHandler) UpdateVisibility(c *gin.Context) {
 		return
 	}
 
+	h.auditService.Record(c.Request.Context(), userID, models.AuditActionDashboardVisibility, models.AuditTargetDashboard, dashboardID,
+		map[string]interface{}{"is_public": req.IsPublic})
+
 	c.JSON(http.StatusOK, gin.H{"message": "visibility updated"})
 }
 
+// UpdateErrorNotification sets the channel notified when a widget on the dashboard starts failing (owner only)
+// PUT /dashboards/:id/error-notification
+func (h *DashboardHandler) UpdateErrorNotification(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	dashboardID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dashboard id"})
+		return
+	}
+
+	var req models.UpdateErrorNotificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if h.widgetHealth == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "widget error tracking is not enabled"})
+		return
+	}
+
+	if err := h.widgetHealth.SetErrorNotificationChannel(c.Request.Context(), dashboardID, userID, req.ChannelID); err != nil {
+		if errors.Is(err, services.ErrPermissionDenied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only the owner can change error notifications"})
+			return
+		}
+		if errors.Is(err, services.ErrInvalidRequest) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"error_notification_channel_id": req.ChannelID})
+}
+
 // GetWidgetData executes the widget's query and returns the result.
 // This endpoint allows dashboard viewers to get widget data without having
 // direct access to the data source - the query is executed using the
@@ -747,7 +1485,7 @@ func (h *DashboardHandler) GetWidgetData(c *gin.Context) {
 	}
 
 	// Check if user has appropriate permission (view for published, edit for drafts)
-	_, err = h.checkDashboardViewPermission(*c, dashboardID, userID)
+	permLevel, err := h.checkDashboardViewPermission(c, dashboardID, userID)
 	if err != nil {
 		if errors.Is(err, services.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "dashboard not found"})
@@ -762,7 +1500,7 @@ func (h *DashboardHandler) GetWidgetData(c *gin.Context) {
 	}
 
 	// Get widget (single query instead of fetching all widgets)
-	widget, err := h.dashboardService.GetWidget(ctx, dashboardID, widgetID)
+	widget, err := h.viewer.GetWidget(ctx, dashboardID, widgetID)
 	if err != nil {
 		if errors.Is(err, services.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "widget not found"})
@@ -772,20 +1510,16 @@ func (h *DashboardHandler) GetWidgetData(c *gin.Context) {
 		return
 	}
 
-	if widget.QueryID == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "widget has no associated query"})
-		return
-	}
-
-	// Get the saved query
-	savedQuery, err := h.queryService.GetSavedQueryByID(ctx, *widget.QueryID)
+	// Get the widget's inline SQL or saved query
+	savedQuery, err := h.widgetQuery(ctx, widget)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "query not found"})
+		respondWidgetQueryError(c, err)
 		return
 	}
 
-	// If the query contains parameters, return required/missing without executing (GET has no body)
-	paramsJSON, err := h.dashboardService.GetDashboardParameters(ctx, dashboardID)
+	// GET has no body, so parameters can only take the dashboard's default values. If any has
+	// none, return required/missing without executing.
+	paramsJSON, err := h.viewer.GetDashboardParameters(ctx, dashboardID)
 	if err != nil && !errors.Is(err, services.ErrNotFound) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -798,55 +1532,78 @@ func (h *DashboardHandler) GetWidgetData(c *gin.Context) {
 		}
 	}
 
+	queryText := savedQuery.QueryText
 	requiredParams := extractRequiredParameterNames(savedQuery.QueryText, paramDefs)
 	if len(requiredParams) > 0 {
-		c.JSON(http.StatusOK, models.WidgetDataResponse{
-			WidgetID:           widgetID,
-			RequiredParameters: requiredParams,
-			MissingParameters:  requiredParams,
-		})
-		return
+		defaults, err := h.resolveParameterDates(ctx, dashboardID, nil, paramDefs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		resolvedQuery, missingParams := replaceParametersWithDefs(savedQuery.QueryText, defaults, paramDefs, permLevel.CanEdit())
+		if len(missingParams) > 0 {
+			c.JSON(http.StatusOK, models.WidgetDataResponse{
+				WidgetID:           widgetID,
+				ChartConfig:        models.MergeChartTheme(widget.ThemeConfig, widget.ChartConfig),
+				RequiredParameters: requiredParams,
+				MissingParameters:  missingParams,
+			})
+			return
+		}
+		queryText = resolvedQuery
 	}
 
 	// Get dashboard owner for permission check
-	ownerID, err := h.dashboardService.GetDashboardOwner(ctx, dashboardID)
+	ownerID, err := h.viewer.GetDashboardOwner(ctx, dashboardID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Determine effective catalog/schema (explicit or defaults)
-	catalog := h.defaultCatalog
-	schema := h.defaultSchema
-	if savedQuery.Catalog != nil && *savedQuery.Catalog != "" {
-		catalog = *savedQuery.Catalog
+	// Determine effective catalog/schema (query, then dashboard, then server defaults)
+	dashboardCatalog, dashboardSchema, err := h.viewer.GetDashboardExecutionDefaults(ctx, dashboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
-	if savedQuery.SchemaName != nil && *savedQuery.SchemaName != "" {
-		schema = *savedQuery.SchemaName
+	catalog, schema := h.queryExecutionContext(savedQuery, dashboardCatalog, dashboardSchema)
+
+	if err := h.authorizeWidgetQuery(ctx, ownerID, widget, queryText, catalog, schema); err != nil {
+		respondWidgetAccessError(c, err)
+		return
 	}
 
-	if err := enforceCatalogAccess(ctx, h.roleService, ownerID, savedQuery.QueryText, catalog); err != nil {
-		if errors.Is(err, ErrCatalogAccessDenied) || errors.Is(err, ErrShowCatalogsForbidden) {
-			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// Row filters are those of the viewer, not the owner
+	filtered, err := applyRowFilters(ctx, h.roleService, userID, queryText, catalog, schema)
+	if err != nil {
+		respondRowFilterError(c, err)
+		return
+	}
+
+	if h.widgetDataNotModified(c, widget, filtered, catalog, schema) {
 		return
 	}
 
-	// Execute the query with caching (NORMAL priority for widget data)
-	result, err := h.trinoService.ExecuteQueryWithCache(ctx, savedQuery.QueryText, catalog, schema, int(services.CachePriorityNormal), widget.QueryID)
+	// Execute the query with caching (NORMAL priority for widget data), honoring the widget's freshness requirement
+	result, err := h.executeWidgetQuery(ctx, widget, filtered, catalog, schema, false)
+	h.recordWidgetOutcome(ctx, widget, err)
+	h.recordQueryHistory(ctx, userID, queryText, models.QueryHistorySourceWidget, widget.ID, result, err)
 	if err != nil {
 		c.JSON(http.StatusOK, models.WidgetDataResponse{
-			WidgetID: widgetID,
-			Error:    err.Error(),
+			WidgetID:    widgetID,
+			Error:       err.Error(),
+			ChartConfig: models.MergeChartTheme(widget.ThemeConfig, widget.ChartConfig),
 		})
 		return
 	}
 
+	h.setWidgetDataCacheHeaders(c, widget, filtered, catalog, schema)
 	c.JSON(http.StatusOK, models.WidgetDataResponse{
-		WidgetID:    widgetID,
-		QueryResult: result,
+		WidgetID:           widgetID,
+		QueryResult:        result,
+		ChartConfig:        models.MergeChartTheme(widget.ThemeConfig, widget.ChartConfig),
+		RequiredParameters: requiredParams,
+		Annotations:        h.widgetAnnotations(ctx, dashboardID, userID, widget, result),
 	})
 }
 
@@ -904,9 +1661,28 @@ func isValidIdentifier(s string) bool {
 	return matched
 }
 
+// quoteIdentifier validates s as an identifier and quotes it. With allowQualified, dot-qualified
+// names such as schema.table are accepted and each part is validated and quoted separately.
+// Embedded double quotes are doubled, although validation already rejects them.
+func quoteIdentifier(s string, allowQualified bool) (string, error) {
+	parts := []string{s}
+	if allowQualified {
+		parts = strings.Split(s, ".")
+	}
+
+	quoted := make([]string, len(parts))
+	for i, part := range parts {
+		if !isValidIdentifier(part) {
+			return "", fmt.Errorf("invalid identifier: %s", s)
+		}
+		quoted[i] = `"` + strings.ReplaceAll(strings.TrimSpace(part), `"`, `""`) + `"`
+	}
+	return strings.Join(quoted, "."), nil
+}
+
 // formatParameterValue formats a parameter value according to its SqlFormat
-// Returns the formatted value and any validation error
-func formatParameterValue(value interface{}, sqlFormat models.SqlFormat, allowRaw bool) (string, error) {
+// Returns the formatted value and any validation error, including numbers and dates outside bounds
+func formatParameterValue(value interface{}, sqlFormat models.SqlFormat, allowRaw, allowQualified bool, bounds parameterBounds) (string, error) {
 	if value == nil {
 		return "", fmt.Errorf("null value")
 	}
@@ -924,23 +1700,37 @@ func formatParameterValue(value interface{}, sqlFormat models.SqlFormat, allowRa
 		}
 	case bool:
 		strValue = fmt.Sprintf("%t", v)
+	case []string:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = item
+		}
+		return formatParameterValue(items, sqlFormat, allowRaw, allowQualified, bounds)
 	case []interface{}:
+		if len(v) == 0 && isListFormat(sqlFormat) {
+			// An empty list would leave IN () behind, which is not valid SQL
+			return "", fmt.Errorf("empty list")
+		}
 		// Handle arrays based on format
 		switch sqlFormat {
 		case models.SqlFormatStringList:
+			// Each item is quoted on its own, so commas and quotes inside an item stay part of it
 			var parts []string
 			for _, item := range v {
-				s := fmt.Sprintf("%v", item)
+				s := formatParameterItem(item)
 				parts = append(parts, fmt.Sprintf("'%s'", escapeString(s)))
 			}
 			return strings.Join(parts, ","), nil
 		case models.SqlFormatNumberList:
 			var parts []string
 			for _, item := range v {
-				s := fmt.Sprintf("%v", item)
+				s := formatParameterItem(item)
 				if !isValidNumber(s) {
 					return "", fmt.Errorf("invalid number in list: %s", s)
 				}
+				if err := bounds.checkNumber(s); err != nil {
+					return "", err
+				}
 				parts = append(parts, s)
 			}
 			return strings.Join(parts, ","), nil
@@ -967,6 +1757,9 @@ func formatParameterValue(value interface{}, sqlFormat models.SqlFormat, allowRa
 		if !isValidNumber(strValue) {
 			return "", fmt.Errorf("invalid number: %s", strValue)
 		}
+		if err := bounds.checkNumber(strValue); err != nil {
+			return "", err
+		}
 		return strValue, nil
 
 	case models.SqlFormatDate:
@@ -974,14 +1767,14 @@ func formatParameterValue(value interface{}, sqlFormat models.SqlFormat, allowRa
 		if !isValidDate(strValue) {
 			return "", fmt.Errorf("invalid date format (expected YYYY-MM-DD): %s", strValue)
 		}
+		if err := bounds.checkDate(strValue); err != nil {
+			return "", err
+		}
 		return fmt.Sprintf("DATE '%s'", strValue), nil
 
 	case models.SqlFormatIdentifier:
 		// Validate and quote as identifier
-		if !isValidIdentifier(strValue) {
-			return "", fmt.Errorf("invalid identifier: %s", strValue)
-		}
-		return fmt.Sprintf("\"%s\"", strValue), nil
+		return quoteIdentifier(strValue, allowQualified)
 
 	case models.SqlFormatStringList:
 		// Format as comma-separated quoted strings
@@ -1001,6 +1794,9 @@ func formatParameterValue(value interface{}, sqlFormat models.SqlFormat, allowRa
 			if !isValidNumber(part) {
 				return "", fmt.Errorf("invalid number in list: %s", part)
 			}
+			if err := bounds.checkNumber(part); err != nil {
+				return "", err
+			}
 			numbers = append(numbers, part)
 		}
 		return strings.Join(numbers, ","), nil
@@ -1021,6 +1817,28 @@ func formatParameterValue(value interface{}, sqlFormat models.SqlFormat, allowRa
 	}
 }
 
+// isListFormat reports whether a format expands to the items of an IN (...) list
+func isListFormat(sqlFormat models.SqlFormat) bool {
+	return sqlFormat == models.SqlFormatStringList || sqlFormat == models.SqlFormatNumberList
+}
+
+// isEmptyParameterValue reports whether a value counts as not given: null, an empty string or
+// an empty selection
+func isEmptyParameterValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case []string:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
+
 // getParameterDefinition finds a parameter definition by name
 func getParameterDefinition(defs []models.ParameterDefinition, name string) *models.ParameterDefinition {
 	for i := range defs {
@@ -1037,7 +1855,7 @@ func getParameterDefinitionForPlaceholder(defs []models.ParameterDefinition, pla
 		if def.Name == placeholder {
 			return def, ""
 		}
-		if def.Type != models.ParameterTypeDateRange {
+		if !isDateRangeType(def.Type) {
 			continue
 		}
 		if def.Targets != nil {
@@ -1123,10 +1941,15 @@ func replaceParametersWithDefs(queryText string, params map[string]interface{},
 				value = v
 				exists = true
 			}
+			// Fall back to the definition's default when the caller left the parameter out
+			if (!exists || value == nil) && def.DefaultValue != nil {
+				value = parameterDefaultValue(def)
+				exists = true
+			}
 		}
 
 		// Check for empty/missing value
-		if !exists || value == nil || value == "" {
+		if !exists || isEmptyParameterValue(value) {
 			// Check empty behavior from definition
 			if def != nil {
 				switch def.EmptyBehavior {
@@ -1134,7 +1957,12 @@ func replaceParametersWithDefs(queryText string, params map[string]interface{},
 					result = strings.ReplaceAll(result, placeholder, "NULL")
 					continue
 				case models.EmptyBehaviorMatchNone:
-					result = strings.ReplaceAll(result, placeholder, "1=0")
+					// List placeholders sit inside IN (...), where IN (NULL) matches nothing
+					if isListFormat(def.SqlFormat) {
+						result = strings.ReplaceAll(result, placeholder, "NULL")
+					} else {
+						result = strings.ReplaceAll(result, placeholder, "1=0")
+					}
 					continue
 				}
 			}
@@ -1146,7 +1974,7 @@ func replaceParametersWithDefs(queryText string, params map[string]interface{},
 		}
 
 		// Handle daterange mapping (single UI param -> start/end placeholders)
-		if def != nil && def.Type == models.ParameterTypeDateRange {
+		if def != nil && isDateRangeType(def.Type) {
 			start, end, err := parseDateRangeValue(value)
 			if err != nil {
 				if _, ok := seenMissing[logicalName]; !ok {
@@ -1169,7 +1997,7 @@ func replaceParametersWithDefs(queryText string, params map[string]interface{},
 					}
 					continue
 				}
-				formatted, err := formatParameterValue(start, sqlFormat, allowRaw)
+				formatted, err := formatParameterValue(start, sqlFormat, allowRaw, def.AllowQualified, boundsOf(def))
 				if err != nil {
 					if _, ok := seenMissing[logicalName]; !ok {
 						seenMissing[logicalName] = struct{}{}
@@ -1189,7 +2017,7 @@ func replaceParametersWithDefs(queryText string, params map[string]interface{},
 					}
 					continue
 				}
-				formatted, err := formatParameterValue(end, sqlFormat, allowRaw)
+				formatted, err := formatParameterValue(end, sqlFormat, allowRaw, def.AllowQualified, boundsOf(def))
 				if err != nil {
 					if _, ok := seenMissing[logicalName]; !ok {
 						seenMissing[logicalName] = struct{}{}
@@ -1209,8 +2037,8 @@ func replaceParametersWithDefs(queryText string, params map[string]interface{},
 				}
 				continue
 			}
-			formattedStart, errStart := formatParameterValue(start, sqlFormat, allowRaw)
-			formattedEnd, errEnd := formatParameterValue(end, sqlFormat, allowRaw)
+			formattedStart, errStart := formatParameterValue(start, sqlFormat, allowRaw, def.AllowQualified, boundsOf(def))
+			formattedEnd, errEnd := formatParameterValue(end, sqlFormat, allowRaw, def.AllowQualified, boundsOf(def))
 			if errStart != nil || errEnd != nil {
 				if _, ok := seenMissing[logicalName]; !ok {
 					seenMissing[logicalName] = struct{}{}
@@ -1224,12 +2052,16 @@ func replaceParametersWithDefs(queryText string, params map[string]interface{},
 
 		// Determine SQL format
 		sqlFormat := models.SqlFormatRaw
-		if def != nil && def.SqlFormat != "" {
-			sqlFormat = def.SqlFormat
+		allowQualified := false
+		if def != nil {
+			if def.SqlFormat != "" {
+				sqlFormat = def.SqlFormat
+			}
+			allowQualified = def.AllowQualified
 		}
 
 		// Format the value
-		formattedValue, err := formatParameterValue(value, sqlFormat, allowRaw)
+		formattedValue, err := formatParameterValue(value, sqlFormat, allowRaw, allowQualified, boundsOf(def))
 		if err != nil {
 			// Validation failed - treat as missing to prevent SQL injection
 			if _, ok := seenMissing[logicalName]; !ok {
@@ -1245,6 +2077,23 @@ func replaceParametersWithDefs(queryText string, params map[string]interface{},
 	return result, missing
 }
 
+// parameterDefaultValue returns def's default value in the form request values are decoded to,
+// so that defaults set from Go (string lists, string maps) format like JSON input
+func parameterDefaultValue(def *models.ParameterDefinition) interface{} {
+	switch v := def.DefaultValue.(type) {
+	case []string:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = item
+		}
+		return items
+	case map[string]string:
+		return map[string]interface{}{"start": v["start"], "end": v["end"]}
+	default:
+		return v
+	}
+}
+
 // GetWidgetDataWithParams executes the widget's query with parameter substitution.
 // POST /dashboards/:id/widgets/:widgetId/data
 func (h *DashboardHandler) GetWidgetDataWithParams(c *gin.Context) {
@@ -1270,7 +2119,7 @@ func (h *DashboardHandler) GetWidgetDataWithParams(c *gin.Context) {
 	}
 
 	// Check if user has appropriate permission (view for published, edit for drafts)
-	permLevel, err := h.checkDashboardViewPermission(*c, dashboardID, userID)
+	permLevel, err := h.checkDashboardViewPermission(c, dashboardID, userID)
 	if err != nil {
 		if errors.Is(err, services.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "dashboard not found"})
@@ -1284,6 +2133,12 @@ func (h *DashboardHandler) GetWidgetDataWithParams(c *gin.Context) {
 		return
 	}
 
+	// Viewers always go through the cache so they cannot hammer Trino
+	if req.BypassCache && !permLevel.CanEdit() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "edit permission required to bypass the cache"})
+		return
+	}
+
 	// Get widget (single query instead of fetching all widgets)
 	widget, err := h.dashboardService.GetWidget(ctx, dashboardID, widgetID)
 	if err != nil {
@@ -1295,15 +2150,10 @@ func (h *DashboardHandler) GetWidgetDataWithParams(c *gin.Context) {
 		return
 	}
 
-	if widget.QueryID == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "widget has no associated query"})
-		return
-	}
-
-	// Get the saved query
-	savedQuery, err := h.queryService.GetSavedQueryByID(ctx, *widget.QueryID)
+	// Get the widget's inline SQL or saved query
+	savedQuery, err := h.widgetQuery(ctx, widget)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "query not found"})
+		respondWidgetQueryError(c, err)
 		return
 	}
 
@@ -1327,6 +2177,14 @@ func (h *DashboardHandler) GetWidgetDataWithParams(c *gin.Context) {
 	if req.Parameters == nil {
 		req.Parameters = map[string]interface{}{}
 	}
+	if req.Parameters, err = h.resolveParameterDates(ctx, dashboardID, req.Parameters, paramDefs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.validateParameters(req.Parameters, paramDefs, permLevel); err != nil {
+		respondValidationError(c, err)
+		return
+	}
 
 	// Replace parameters with provided values using definitions for secure formatting
 	resolvedQuery, missingParams := replaceParametersWithDefs(savedQuery.QueryText, req.Parameters, paramDefs, permLevel.CanEdit())
@@ -1348,28 +2206,35 @@ func (h *DashboardHandler) GetWidgetDataWithParams(c *gin.Context) {
 		return
 	}
 
-	// Determine effective catalog/schema (explicit or defaults)
-	catalog := h.defaultCatalog
-	schema := h.defaultSchema
-	if savedQuery.Catalog != nil && *savedQuery.Catalog != "" {
-		catalog = *savedQuery.Catalog
+	// Determine effective catalog/schema (query, then dashboard, then server defaults)
+	dashboardCatalog, dashboardSchema, err := h.dashboardService.GetDashboardExecutionDefaults(ctx, dashboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
-	if savedQuery.SchemaName != nil && *savedQuery.SchemaName != "" {
-		schema = *savedQuery.SchemaName
+	catalog, schema := h.queryExecutionContext(savedQuery, dashboardCatalog, dashboardSchema)
+
+	if err := h.authorizeWidgetQuery(ctx, ownerID, widget, resolvedQuery, catalog, schema); err != nil {
+		respondWidgetAccessError(c, err)
+		return
 	}
 
-	if err := enforceCatalogAccess(ctx, h.roleService, ownerID, resolvedQuery, catalog); err != nil {
-		if errors.Is(err, ErrCatalogAccessDenied) || errors.Is(err, ErrShowCatalogsForbidden) {
-			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// Row filters are those of the viewer, not the owner
+	filtered, err := applyRowFilters(ctx, h.roleService, userID, resolvedQuery, catalog, schema)
+	if err != nil {
+		respondRowFilterError(c, err)
+		return
+	}
+
+	if !req.BypassCache && h.widgetDataNotModified(c, widget, filtered, catalog, schema) {
 		return
 	}
 
-	// Execute the resolved query with caching
-	// Note: Cache key should include parameters for uniqueness
-	result, err := h.trinoService.ExecuteQueryWithCache(ctx, resolvedQuery, catalog, schema, int(services.CachePriorityNormal), widget.QueryID)
+	// Execute the resolved query with caching; the cache key is derived from the resolved
+	// query text, so each set of parameter values is cached separately
+	result, err := h.executeWidgetQuery(ctx, widget, filtered, catalog, schema, req.BypassCache)
+	h.recordWidgetOutcome(ctx, widget, err)
+	h.recordQueryHistory(ctx, userID, resolvedQuery, models.QueryHistorySourceWidget, widget.ID, result, err)
 	if err != nil {
 		c.JSON(http.StatusOK, models.WidgetDataResponse{
 			WidgetID:           widgetID,
@@ -1379,16 +2244,497 @@ func (h *DashboardHandler) GetWidgetDataWithParams(c *gin.Context) {
 		return
 	}
 
+	h.setWidgetDataCacheHeaders(c, widget, filtered, catalog, schema)
 	c.JSON(http.StatusOK, models.WidgetDataResponse{
 		WidgetID:           widgetID,
 		QueryResult:        result,
+		ChartConfig:        models.MergeChartTheme(widget.ThemeConfig, widget.ChartConfig),
 		RequiredParameters: requiredParams,
+		Annotations:        h.widgetAnnotations(ctx, dashboardID, userID, widget, result),
 	})
 }
 
-// GetParameterOptions executes the options query for a parameter with dynamic options.
-// POST /dashboards/:id/parameters/:name/options
-func (h *DashboardHandler) GetParameterOptions(c *gin.Context) {
+// defaultRenderConcurrency bounds the widget queries a dashboard render runs at once when
+// DASHBOARD_RENDER_CONCURRENCY is not positive
+const defaultRenderConcurrency = 4
+
+// SetRenderConcurrency sets how many widget queries a dashboard render runs at once
+func (h *DashboardHandler) SetRenderConcurrency(n int) {
+	h.renderConcurrency = n
+}
+
+// SetParameterTerms rejects raw and identifier parameter values of users without edit
+// permission that contain any of the denied SQL keywords or function names. Words in allowed
+// are accepted even when they start with a denied term. An empty denied list disables the check.
+func (h *DashboardHandler) SetParameterTerms(denied, allowed []string) {
+	h.parameterTerms = newParameterTermFilter(denied, allowed)
+}
+
+// validateParameters checks parameter values against their definitions and, for users who
+// cannot edit the dashboard, against the denied terms
+func (h *DashboardHandler) validateParameters(params map[string]interface{}, defs []models.ParameterDefinition, permLevel models.PermissionLevel) error {
+	if err := validateParameterValues(params, defs); err != nil {
+		return err
+	}
+	if permLevel.CanEdit() {
+		return nil // editors may insert raw values anyway
+	}
+	return h.parameterTerms.check(params, defs)
+}
+
+// RenderDashboard resolves every widget's data with one set of parameter values.
+// Widgets run concurrently through the query cache; a failing widget reports its
+// error in its own entry instead of failing the whole render.
+// POST /dashboards/:id/render
+func (h *DashboardHandler) RenderDashboard(c *gin.Context) {
+	rendered, ok := h.renderWidgets(c)
+	if !ok {
+		return
+	}
+
+	title, description, err := h.viewer.GetDashboardTitle(c.Request.Context(), rendered.dashboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := models.DashboardRenderResponse{
+		DashboardID: rendered.dashboardID,
+		Title:       models.RenderParameterText(title, rendered.params, rendered.paramDefs),
+		Widgets:     rendered.widgets,
+	}
+	if description != nil {
+		text := models.RenderParameterText(*description, rendered.params, rendered.paramDefs)
+		resp.Description = &text
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetDashboardData resolves every widget's data with one set of parameter values, like
+// RenderDashboard, and returns it keyed by widget ID. Parameters missing for any widget
+// are also listed once for the whole dashboard.
+// POST /dashboards/:id/data
+func (h *DashboardHandler) GetDashboardData(c *gin.Context) {
+	rendered, ok := h.renderWidgets(c)
+	if !ok {
+		return
+	}
+
+	resp := models.DashboardDataResponse{
+		DashboardID:       rendered.dashboardID,
+		Widgets:           make(map[uuid.UUID]models.WidgetDataResponse, len(rendered.widgets)),
+		MissingParameters: []string{},
+	}
+	missing := map[string]bool{}
+	for _, result := range rendered.widgets {
+		resp.Widgets[result.WidgetID] = result
+		for _, name := range result.MissingParameters {
+			if !missing[name] {
+				missing[name] = true
+				resp.MissingParameters = append(resp.MissingParameters, name)
+			}
+		}
+	}
+	sort.Strings(resp.MissingParameters)
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// dashboardParameterDefs returns the dashboard's parameter definitions (nil if it has none)
+func (h *DashboardHandler) dashboardParameterDefs(ctx context.Context, dashboardID uuid.UUID) ([]models.ParameterDefinition, error) {
+	paramsJSON, err := h.viewer.GetDashboardParameters(ctx, dashboardID)
+	if err != nil && !errors.Is(err, services.ErrNotFound) {
+		return nil, err
+	}
+	var paramDefs []models.ParameterDefinition
+	if len(paramsJSON) > 0 {
+		if err := json.Unmarshal(paramsJSON, &paramDefs); err != nil {
+			return nil, errors.New("failed to parse dashboard parameters")
+		}
+	}
+	return paramDefs, nil
+}
+
+// renderedDashboard is the outcome of renderWidgets
+type renderedDashboard struct {
+	dashboardID uuid.UUID
+	params      map[string]interface{}
+	paramDefs   []models.ParameterDefinition
+	widgets     []models.WidgetDataResponse // one entry per widget with a query
+}
+
+// renderWidgets checks the caller's view permission and resolves the data of every widget
+// with a query, running them concurrently with the dashboard owner's catalog permissions.
+// It responds and returns ok=false when the dashboard cannot be rendered at all.
+func (h *DashboardHandler) renderWidgets(c *gin.Context) (*renderedDashboard, bool) {
+	ctx := c.Request.Context()
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	dashboardID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dashboard id"})
+		return nil, false
+	}
+
+	var req models.WidgetDataRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return nil, false
+	}
+	if req.Parameters == nil {
+		req.Parameters = map[string]interface{}{}
+	}
+
+	permLevel, err := h.checkDashboardViewPermission(c, dashboardID, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "dashboard not found"})
+			return nil, false
+		}
+		if errors.Is(err, services.ErrPermissionDenied) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
+			return nil, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	// Viewers always go through the cache so they cannot hammer Trino
+	if req.BypassCache && !permLevel.CanEdit() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "edit permission required to bypass the cache"})
+		return nil, false
+	}
+
+	widgets, err := h.viewer.GetWidgets(ctx, dashboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	paramDefs, err := h.dashboardParameterDefs(ctx, dashboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if req.Parameters, err = h.resolveParameterDates(ctx, dashboardID, req.Parameters, paramDefs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if err := h.validateParameters(req.Parameters, paramDefs, permLevel); err != nil {
+		respondValidationError(c, err)
+		return nil, false
+	}
+
+	// Queries run with the dashboard owner's catalog permissions, as for single widgets
+	ownerID, err := h.viewer.GetDashboardOwner(ctx, dashboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	dashboardCatalog, dashboardSchema, err := h.viewer.GetDashboardExecutionDefaults(ctx, dashboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	// Widgets without a query (e.g. text widgets) have no data to render
+	var queryWidgets []*models.Widget
+	for i := range widgets {
+		if widgets[i].HasQuery() {
+			queryWidgets = append(queryWidgets, &widgets[i])
+		}
+	}
+
+	limit := h.renderConcurrency
+	if limit <= 0 {
+		limit = defaultRenderConcurrency
+	}
+	results := make([]models.WidgetDataResponse, len(queryWidgets))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(limit)
+
+	for i, widget := range queryWidgets {
+		g.Go(func() error {
+			// Once the client has gone away, widgets still waiting for a slot are not started;
+			// those in flight see the cancelled context
+			if err := gctx.Err(); err != nil {
+				results[i] = models.WidgetDataResponse{WidgetID: widget.ID, Error: err.Error()}
+				return nil
+			}
+			// A failing widget reports its error in its own entry, so other widgets keep running
+			results[i] = h.renderWidget(gctx, dashboardID, userID, ownerID, dashboardCatalog, dashboardSchema, widget, req.Parameters, paramDefs, permLevel.CanEdit(), req.BypassCache)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	return &renderedDashboard{
+		dashboardID: dashboardID,
+		params:      req.Parameters,
+		paramDefs:   paramDefs,
+		widgets:     results,
+	}, true
+}
+
+// errWidgetHasNoQuery is returned by widgetQuery for widgets without SQL (e.g. text widgets)
+var errWidgetHasNoQuery = errors.New("widget has no associated query")
+
+// widgetQuery returns the query a widget runs. Inline SQL takes precedence over query_id and is
+// returned as an unsaved SavedQuery carrying the widget's catalog and schema, so both kinds of
+// widget go through the same parameter substitution, execution context and access checks.
+func (h *DashboardHandler) widgetQuery(ctx context.Context, widget *models.Widget) (*models.SavedQuery, error) {
+	if widget.HasInlineQuery() {
+		return &models.SavedQuery{QueryText: *widget.QueryText, Catalog: widget.Catalog, SchemaName: widget.Schema}, nil
+	}
+	if widget.QueryID == nil {
+		return nil, errWidgetHasNoQuery
+	}
+	return h.savedQueries.GetSavedQueryByID(ctx, *widget.QueryID)
+}
+
+// checkWidgetQueryText rejects inline widget SQL that read-only mode would reject in a saved query.
+// Catalog access is checked when the widget runs, by authorizeWidgetQuery.
+func (h *DashboardHandler) checkWidgetQueryText(queryText *string) error {
+	if queryText == nil || *queryText == "" || h.statements == nil {
+		return nil
+	}
+	if err := h.statements.CheckStatement(*queryText); err != nil {
+		return &models.ValidationError{Field: "query_text", Message: err.Error()}
+	}
+	return nil
+}
+
+// authorizeWidgetQuery checks a resolved widget query before it runs. As alerts do at run time,
+// read-only mode is applied again here: saved queries and inline SQL may predate it or have been
+// imported. The dashboard owner must have access to every catalog the query reads. Inline SQL
+// was written by the widget's last editor rather than checked as a saved query of the owner, so
+// that editor must have access too; otherwise an editor could read catalogs only the owner was
+// granted. widget is nil for queries that do not belong to a widget, e.g. parameter options.
+func (h *DashboardHandler) authorizeWidgetQuery(ctx context.Context, ownerID uuid.UUID, widget *models.Widget, query, catalog, schema string) error {
+	if h.statements != nil {
+		if err := h.statements.CheckStatement(query); err != nil {
+			return err
+		}
+	}
+	if err := enforceCatalogAccess(ctx, h.roleService, ownerID, query, catalog, schema); err != nil {
+		return err
+	}
+	if widget != nil && widget.HasInlineQuery() && widget.UpdatedBy != nil && *widget.UpdatedBy != ownerID {
+		return enforceCatalogAccess(ctx, h.roleService, *widget.UpdatedBy, query, catalog, schema)
+	}
+	return nil
+}
+
+// respondWidgetAccessError reports an authorizeWidgetQuery failure
+func respondWidgetAccessError(c *gin.Context, err error) {
+	if respondReadOnlyError(c, err) {
+		return
+	}
+	if errors.Is(err, ErrCatalogAccessDenied) || errors.Is(err, ErrShowCatalogsForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// respondWidgetQueryError reports a widgetQuery failure
+func respondWidgetQueryError(c *gin.Context, err error) {
+	if errors.Is(err, errWidgetHasNoQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "query not found"})
+}
+
+// queryExecutionContext resolves the catalog and schema a saved query runs in on a dashboard:
+// the query's own values, then the dashboard's defaults, then the server-wide defaults
+func (h *DashboardHandler) queryExecutionContext(savedQuery *models.SavedQuery, dashboardCatalog, dashboardSchema *string) (string, string) {
+	catalog := h.defaultCatalog
+	schema := h.defaultSchema
+	if dashboardCatalog != nil && *dashboardCatalog != "" {
+		catalog = *dashboardCatalog
+	}
+	if dashboardSchema != nil && *dashboardSchema != "" {
+		schema = *dashboardSchema
+	}
+	if savedQuery.Catalog != nil && *savedQuery.Catalog != "" {
+		catalog = *savedQuery.Catalog
+	}
+	if savedQuery.SchemaName != nil && *savedQuery.SchemaName != "" {
+		schema = *savedQuery.SchemaName
+	}
+	return catalog, schema
+}
+
+// renderWidget resolves one widget's data for renderWidgets. Every failure is
+// reported in the response's Error so the other widgets are still served.
+func (h *DashboardHandler) renderWidget(
+	ctx context.Context,
+	dashboardID, userID, ownerID uuid.UUID,
+	dashboardCatalog, dashboardSchema *string,
+	widget *models.Widget,
+	params map[string]interface{},
+	paramDefs []models.ParameterDefinition,
+	allowRaw, refresh bool,
+) models.WidgetDataResponse {
+	resp := models.WidgetDataResponse{WidgetID: widget.ID}
+
+	savedQuery, err := h.widgetQuery(ctx, widget)
+	if err != nil {
+		resp.Error = "query not found"
+		return resp
+	}
+
+	resp.RequiredParameters = extractRequiredParameterNames(savedQuery.QueryText, paramDefs)
+	resolvedQuery, missingParams := replaceParametersWithDefs(savedQuery.QueryText, params, paramDefs, allowRaw)
+	if len(missingParams) > 0 {
+		resp.MissingParameters = missingParams
+		return resp
+	}
+
+	catalog, schema := h.queryExecutionContext(savedQuery, dashboardCatalog, dashboardSchema)
+
+	if err := h.authorizeWidgetQuery(ctx, ownerID, widget, resolvedQuery, catalog, schema); err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+	filtered, err := applyRowFilters(ctx, h.roleService, userID, resolvedQuery, catalog, schema)
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+
+	result, err := h.executeWidgetQuery(ctx, widget, filtered, catalog, schema, refresh)
+	if err != nil && ctx.Err() != nil {
+		// Cancelled because the client went away; that says nothing about the widget
+		resp.Error = ctx.Err().Error()
+		return resp
+	}
+	h.recordWidgetOutcome(ctx, widget, err)
+	h.recordQueryHistory(ctx, userID, resolvedQuery, models.QueryHistorySourceWidget, widget.ID, result, err)
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+
+	resp.QueryResult = result
+	resp.ChartConfig = models.MergeChartTheme(widget.ThemeConfig, widget.ChartConfig)
+	resp.Annotations = h.widgetAnnotations(ctx, dashboardID, userID, widget, result)
+	return resp
+}
+
+// DebugWidgetQuery reports how a widget's query would run: the SQL after parameter substitution,
+// the catalog and schema, the dashboard owner whose permissions are enforced and whether the
+// access check passes. Nothing is executed. Parameters are given as a JSON object in the params
+// query string and resolved as for a viewer, so raw-formatted values are not inserted.
+// GET /admin/dashboards/:id/widgets/:widgetId/debug
+func (h *DashboardHandler) DebugWidgetQuery(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	dashboardID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dashboard id"})
+		return
+	}
+	widgetID, err := uuid.Parse(c.Param("widgetId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid widget id"})
+		return
+	}
+
+	var params map[string]interface{}
+	if raw := c.Query("params"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &params); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "params must be a JSON object"})
+			return
+		}
+	}
+
+	widget, err := h.viewer.GetWidget(ctx, dashboardID, widgetID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "widget not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	savedQuery, err := h.widgetQuery(ctx, widget)
+	if err != nil {
+		respondWidgetQueryError(c, err)
+		return
+	}
+
+	paramsJSON, err := h.viewer.GetDashboardParameters(ctx, dashboardID)
+	if err != nil && !errors.Is(err, services.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	var paramDefs []models.ParameterDefinition
+	if len(paramsJSON) > 0 {
+		if err := json.Unmarshal(paramsJSON, &paramDefs); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to parse dashboard parameters"})
+			return
+		}
+	}
+
+	ownerID, err := h.viewer.GetDashboardOwner(ctx, dashboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	dashboardCatalog, dashboardSchema, err := h.viewer.GetDashboardExecutionDefaults(ctx, dashboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Same resolution steps as renderWidget, stopping before execution
+	resp := models.WidgetQueryDebug{
+		WidgetID:           widget.ID,
+		QueryText:          savedQuery.QueryText,
+		RequiredParameters: extractRequiredParameterNames(savedQuery.QueryText, paramDefs),
+		OwnerID:            ownerID,
+	}
+	if !widget.HasInlineQuery() {
+		resp.QueryID = widget.QueryID
+	}
+	resp.Catalog, resp.Schema = h.queryExecutionContext(savedQuery, dashboardCatalog, dashboardSchema)
+
+	if params, err = h.resolveParameterDates(ctx, dashboardID, params, paramDefs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resolvedQuery, missingParams := replaceParametersWithDefs(savedQuery.QueryText, params, paramDefs, false)
+	if len(missingParams) > 0 {
+		resp.MissingParameters = missingParams
+	} else {
+		resp.ResolvedQuery = resolvedQuery
+		if err := h.authorizeWidgetQuery(ctx, ownerID, widget, resolvedQuery, resp.Catalog, resp.Schema); err != nil {
+			resp.AccessError = err.Error()
+		} else {
+			resp.AccessAllowed = true
+		}
+	}
+
+	h.auditService.Record(ctx, userID, models.AuditActionWidgetDebug, models.AuditTargetDashboard, dashboardID,
+		map[string]interface{}{"widget_id": widgetID, "parameters": params})
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// maxParameterOptions caps the options returned for a parameter with dynamic options
+const maxParameterOptions = 200
+
+// GetParameterOptions executes the options query for a parameter with dynamic options.
+// The options query may be any query returning rows, including WITH (CTE) queries: the first
+// column is the option value, the second (optional) column its label, and further columns
+// are ignored. Rows with a NULL value are skipped.
+// POST /dashboards/:id/parameters/:name/options
+func (h *DashboardHandler) GetParameterOptions(c *gin.Context) {
 	ctx := c.Request.Context()
 	userID := c.MustGet("userID").(uuid.UUID)
 
@@ -1412,7 +2758,7 @@ func (h *DashboardHandler) GetParameterOptions(c *gin.Context) {
 	}
 
 	// Check if user has appropriate permission (view for published, edit for drafts)
-	permLevel, err := h.checkDashboardViewPermission(*c, dashboardID, userID)
+	permLevel, err := h.checkDashboardViewPermission(c, dashboardID, userID)
 	if err != nil {
 		if errors.Is(err, services.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "dashboard not found"})
@@ -1427,7 +2773,7 @@ func (h *DashboardHandler) GetParameterOptions(c *gin.Context) {
 	}
 
 	// Load dashboard parameter definitions (without loading widgets)
-	paramsJSON, err := h.dashboardService.GetDashboardParameters(ctx, dashboardID)
+	paramsJSON, err := h.viewer.GetDashboardParameters(ctx, dashboardID)
 	if err != nil && !errors.Is(err, services.ErrNotFound) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -1466,73 +2812,89 @@ func (h *DashboardHandler) GetParameterOptions(c *gin.Context) {
 	}
 
 	// Get the options query
-	savedQuery, err := h.queryService.GetSavedQueryByID(ctx, *paramDef.OptionsQueryID)
+	savedQuery, err := h.savedQueries.GetSavedQueryByID(ctx, *paramDef.OptionsQueryID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "options query not found"})
 		return
 	}
 
 	// Get dashboard owner for permission check
-	ownerID, err := h.dashboardService.GetDashboardOwner(ctx, dashboardID)
+	ownerID, err := h.viewer.GetDashboardOwner(ctx, dashboardID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Determine effective catalog/schema (explicit or defaults)
-	catalog := h.defaultCatalog
-	schema := h.defaultSchema
-	if savedQuery.Catalog != nil && *savedQuery.Catalog != "" {
-		catalog = *savedQuery.Catalog
-	}
-	if savedQuery.SchemaName != nil && *savedQuery.SchemaName != "" {
-		schema = *savedQuery.SchemaName
+	// Determine effective catalog/schema (query, then dashboard, then server defaults)
+	dashboardCatalog, dashboardSchema, err := h.viewer.GetDashboardExecutionDefaults(ctx, dashboardID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
 	}
+	catalog, schema := h.queryExecutionContext(savedQuery, dashboardCatalog, dashboardSchema)
 
 	// Replace parameters in the options query (for cascade/dependsOn)
 	// Use secure formatting based on parameter definitions
 	if req.Parameters == nil {
 		req.Parameters = map[string]interface{}{}
 	}
+	if req.Parameters, err = h.resolveParameterDates(ctx, dashboardID, req.Parameters, paramDefs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.validateParameters(req.Parameters, paramDefs, permLevel); err != nil {
+		respondValidationError(c, err)
+		return
+	}
 	resolvedQuery, missingParams := replaceParametersWithDefs(savedQuery.QueryText, req.Parameters, paramDefs, permLevel.CanEdit())
 	if len(missingParams) > 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing parameters"})
 		return
 	}
 
-	if err := enforceCatalogAccess(ctx, h.roleService, ownerID, resolvedQuery, catalog); err != nil {
-		if errors.Is(err, ErrCatalogAccessDenied) || errors.Is(err, ErrShowCatalogsForbidden) {
-			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.authorizeWidgetQuery(ctx, ownerID, nil, resolvedQuery, catalog, schema); err != nil {
+		respondWidgetAccessError(c, err)
+		return
+	}
+
+	filtered, err := applyRowFilters(ctx, h.roleService, userID, resolvedQuery, catalog, schema)
+	if err != nil {
+		respondRowFilterError(c, err)
 		return
 	}
 
 	// Execute the query
-	result, err := h.trinoService.ExecuteQueryWithCache(ctx, resolvedQuery, catalog, schema, int(services.CachePriorityNormal), paramDef.OptionsQueryID)
+	result, err := h.trinoService.ExecuteQueryWithCache(ctx, filtered, catalog, schema, int(services.CachePriorityNormal), paramDef.OptionsQueryID)
+	h.recordQueryHistory(ctx, userID, resolvedQuery, models.QueryHistorySourceParameterOptions, dashboardID, result, err)
 	if err != nil {
+		if respondQueryTimeout(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Convert query result to options
-	// First column = value, second column = label (optional)
-	const maxOptions = 200
+	c.JSON(http.StatusOK, parameterOptionsFromResult(result, maxParameterOptions))
+}
+
+// parameterOptionsFromResult converts up to maxOptions rows of an options query result to
+// options: first column = value, second column = label (defaults to the value), extra columns
+// are ignored. Rows without a value (no columns or NULL) are skipped.
+func parameterOptionsFromResult(result *models.QueryResult, maxOptions int) []models.ParameterOption {
 	options := make([]models.ParameterOption, 0, min(len(result.Rows), maxOptions))
 
-	for i, row := range result.Rows {
-		if i >= maxOptions {
+	for _, row := range result.Rows {
+		if len(options) >= maxOptions {
 			break
 		}
-		if len(row) == 0 {
+		if len(row) == 0 || row[0] == nil {
 			continue
 		}
 
-		value := fmt.Sprintf("%v", row[0])
+		value := formatOptionValue(row[0])
 		label := value
 		if len(row) > 1 && row[1] != nil {
-			label = fmt.Sprintf("%v", row[1])
+			label = formatOptionValue(row[1])
 		}
 
 		options = append(options, models.ParameterOption{
@@ -1541,5 +2903,29 @@ func (h *DashboardHandler) GetParameterOptions(c *gin.Context) {
 		})
 	}
 
-	c.JSON(http.StatusOK, options)
+	return options
+}
+
+// formatOptionValue renders an option column value as text. Numbers are written in plain
+// decimal notation (results decoded from the cache hold float64, which %v would print as
+// 1e+06) and times as RFC 3339, as the Trino service formats them in query results.
+func formatOptionValue(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case []byte:
+		return string(val)
+	case bool:
+		return strconv.FormatBool(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(val), 'f', -1, 32)
+	case json.Number:
+		return val.String()
+	case time.Time:
+		return val.Format(time.RFC3339)
+	default:
+		return fmt.Sprintf("%v", val)
+	}
 }
@@ -0,0 +1,1839 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/repository"
+	"github.com/mitsume/backend/internal/services"
+)
+
+// fakeDashboardViewer serves permission levels, draft flags, widgets, activity, parameters, owners
+// and catalog defaults from maps
+type fakeDashboardViewer struct {
+	levels   map[uuid.UUID]models.PermissionLevel
+	drafts   map[uuid.UUID]bool
+	widgets  map[uuid.UUID]*models.Widget
+	events   map[uuid.UUID][]models.DashboardEvent
+	params   map[uuid.UUID]json.RawMessage
+	owners   map[uuid.UUID]uuid.UUID
+	titles   map[uuid.UUID]string
+	catalogs map[uuid.UUID]string
+	schemas  map[uuid.UUID]string
+}
+
+func (f *fakeDashboardViewer) GetUserPermissionLevel(ctx context.Context, dashboardID, userID uuid.UUID) (models.PermissionLevel, error) {
+	return f.levels[dashboardID], nil
+}
+
+func (f *fakeDashboardViewer) IsDraft(ctx context.Context, dashboardID uuid.UUID) (bool, error) {
+	return f.drafts[dashboardID], nil
+}
+
+func (f *fakeDashboardViewer) GetWidget(ctx context.Context, dashboardID, widgetID uuid.UUID) (*models.Widget, error) {
+	if w, ok := f.widgets[widgetID]; ok && w.DashboardID == dashboardID {
+		return w, nil
+	}
+	return nil, services.ErrNotFound
+}
+
+func (f *fakeDashboardViewer) GetDashboardActivity(ctx context.Context, dashboardID uuid.UUID, limit, offset int) (*models.DashboardActivityResponse, error) {
+	all := f.events[dashboardID]
+	page := []models.DashboardEvent{}
+	for i := offset; i < len(all) && len(page) < limit; i++ {
+		page = append(page, all[i])
+	}
+	return &models.DashboardActivityResponse{Events: page, Total: len(all)}, nil
+}
+
+func (f *fakeDashboardViewer) GetWidgets(ctx context.Context, dashboardID uuid.UUID) ([]models.Widget, error) {
+	widgets := []models.Widget{}
+	for _, w := range f.widgets {
+		if w.DashboardID == dashboardID {
+			widgets = append(widgets, *w)
+		}
+	}
+	return widgets, nil
+}
+
+func (f *fakeDashboardViewer) GetDashboardParameters(ctx context.Context, dashboardID uuid.UUID) (json.RawMessage, error) {
+	return f.params[dashboardID], nil
+}
+
+func (f *fakeDashboardViewer) GetDashboardTitle(ctx context.Context, dashboardID uuid.UUID) (string, *string, error) {
+	return f.titles[dashboardID], nil, nil
+}
+
+func (f *fakeDashboardViewer) GetDashboardOwner(ctx context.Context, dashboardID uuid.UUID) (uuid.UUID, error) {
+	if owner, ok := f.owners[dashboardID]; ok {
+		return owner, nil
+	}
+	return uuid.Nil, services.ErrNotFound
+}
+
+func (f *fakeDashboardViewer) GetDashboardExecutionDefaults(ctx context.Context, dashboardID uuid.UUID) (*string, *string, error) {
+	var catalog, schema *string
+	if v, ok := f.catalogs[dashboardID]; ok {
+		catalog = &v
+	}
+	if v, ok := f.schemas[dashboardID]; ok {
+		schema = &v
+	}
+	return catalog, schema, nil
+}
+
+func (f *fakeDashboardViewer) GetDashboardLocation(ctx context.Context, dashboardID uuid.UUID) (*time.Location, error) {
+	return time.UTC, nil
+}
+
+// fakeSavedQueries serves saved queries from a map
+type fakeSavedQueries map[uuid.UUID]*models.SavedQuery
+
+func (f fakeSavedQueries) GetSavedQueryByID(ctx context.Context, id uuid.UUID) (*models.SavedQuery, error) {
+	if q, ok := f[id]; ok {
+		return q, nil
+	}
+	return nil, services.ErrNotFound
+}
+
+func setupGetWidgetTest() (*DashboardHandler, *fakeDashboardViewer, *models.Widget) {
+	widget := &models.Widget{ID: uuid.New(), DashboardID: uuid.New(), Name: "Revenue", ChartType: "line"}
+	viewer := &fakeDashboardViewer{
+		levels:  map[uuid.UUID]models.PermissionLevel{widget.DashboardID: models.PermissionView},
+		drafts:  map[uuid.UUID]bool{},
+		widgets: map[uuid.UUID]*models.Widget{widget.ID: widget},
+	}
+	return &DashboardHandler{viewer: viewer}, viewer, widget
+}
+
+func getWidget(handler *DashboardHandler, dashboardID, widgetID string) (int, []byte) {
+	c, w := createTestContext("GET", "/api/dashboards/"+dashboardID+"/widgets/"+widgetID, nil)
+	c.Params = gin.Params{
+		{Key: "id", Value: dashboardID},
+		{Key: "widgetId", Value: widgetID},
+	}
+	handler.GetWidget(c)
+	return w.Code, w.Body.Bytes()
+}
+
+func TestGetWidget_Success(t *testing.T) {
+	handler, _, widget := setupGetWidgetTest()
+
+	code, body := getWidget(handler, widget.DashboardID.String(), widget.ID.String())
+	if code != http.StatusOK {
+		t.Fatalf("GetWidget() status = %d, want %d", code, http.StatusOK)
+	}
+
+	var got models.Widget
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if got.ID != widget.ID || got.Name != widget.Name {
+		t.Fatalf("GetWidget() = %+v, want widget %s", got, widget.ID)
+	}
+}
+
+func TestGetWidget_PermissionDenied(t *testing.T) {
+	handler, viewer, widget := setupGetWidgetTest()
+	viewer.levels[widget.DashboardID] = models.PermissionNone
+
+	if code, _ := getWidget(handler, widget.DashboardID.String(), widget.ID.String()); code != http.StatusForbidden {
+		t.Fatalf("GetWidget() status = %d, want %d", code, http.StatusForbidden)
+	}
+}
+
+func TestGetWidget_DraftRequiresEdit(t *testing.T) {
+	handler, viewer, widget := setupGetWidgetTest()
+	viewer.drafts[widget.DashboardID] = true
+
+	if code, _ := getWidget(handler, widget.DashboardID.String(), widget.ID.String()); code != http.StatusForbidden {
+		t.Fatalf("GetWidget() status = %d, want %d", code, http.StatusForbidden)
+	}
+
+	viewer.levels[widget.DashboardID] = models.PermissionEdit
+	if code, _ := getWidget(handler, widget.DashboardID.String(), widget.ID.String()); code != http.StatusOK {
+		t.Fatalf("GetWidget() status = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestGetWidget_NotFound(t *testing.T) {
+	handler, viewer, widget := setupGetWidgetTest()
+
+	if code, _ := getWidget(handler, widget.DashboardID.String(), uuid.New().String()); code != http.StatusNotFound {
+		t.Fatalf("GetWidget() unknown widget status = %d, want %d", code, http.StatusNotFound)
+	}
+
+	// A widget is not reachable through another dashboard the user can view
+	otherDashboard := uuid.New()
+	viewer.levels[otherDashboard] = models.PermissionView
+	if code, _ := getWidget(handler, otherDashboard.String(), widget.ID.String()); code != http.StatusNotFound {
+		t.Fatalf("GetWidget() other dashboard status = %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestGetWidget_InvalidID(t *testing.T) {
+	handler, _, widget := setupGetWidgetTest()
+
+	if code, _ := getWidget(handler, "not-a-uuid", widget.ID.String()); code != http.StatusBadRequest {
+		t.Fatalf("GetWidget() status = %d, want %d", code, http.StatusBadRequest)
+	}
+}
+
+func TestImportDashboard_RejectsIncompatibleVersion(t *testing.T) {
+	handler := &DashboardHandler{}
+	doc := models.DashboardExport{
+		Version:   models.DashboardExportVersion + 1,
+		Dashboard: models.ExportedDashboard{Name: "Sales"},
+	}
+
+	c, w := createTestContext("POST", "/api/dashboards/import", doc)
+	handler.ImportDashboard(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("ImportDashboard() status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if body["field"] != "version" {
+		t.Fatalf("ImportDashboard() field = %v, want version", body["field"])
+	}
+}
+
+func TestImportDashboard_RejectsWritesInReadOnlyMode(t *testing.T) {
+	queryService := services.NewQueryService(nil)
+	queryService.SetReadOnlyMode(true)
+	handler := &DashboardHandler{statements: queryService}
+	queryText := "DELETE FROM hive.sales.orders"
+	doc := models.DashboardExport{
+		Version:   models.DashboardExportVersion,
+		Dashboard: models.ExportedDashboard{Name: "Sales"},
+		Widgets: []models.ExportedWidget{{
+			Name: "Orders", QueryText: &queryText, ChartType: "table",
+			Position: json.RawMessage(`{"x":0,"y":0,"w":6,"h":4}`),
+		}},
+	}
+
+	c, w := createTestContext("POST", "/api/dashboards/import", doc)
+	handler.ImportDashboard(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("ImportDashboard() status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if body["field"] != "widgets[0].query_text" {
+		t.Fatalf("ImportDashboard() field = %v, want widgets[0].query_text", body["field"])
+	}
+}
+
+func TestUpdateDashboard_RejectsTooManyParameters(t *testing.T) {
+	handler := &DashboardHandler{maxParameters: 2}
+	req := models.UpdateDashboardRequest{
+		Parameters: json.RawMessage(`[{"name":"a"},{"name":"b"},{"name":"c"}]`),
+	}
+
+	dashboardID := uuid.New()
+	c, w := createTestContext("PUT", "/api/dashboards/"+dashboardID.String(), req)
+	c.Params = gin.Params{{Key: "id", Value: dashboardID.String()}}
+	handler.UpdateDashboard(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("UpdateDashboard() status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if body["field"] != "parameters" {
+		t.Fatalf("UpdateDashboard() field = %v, want parameters", body["field"])
+	}
+}
+
+// fakeDashboardArchiver mirrors DashboardService.SetArchived against maps of owners and drafts
+type fakeDashboardArchiver struct {
+	owners   map[uuid.UUID]uuid.UUID
+	drafts   map[uuid.UUID]bool
+	archived map[uuid.UUID]bool
+}
+
+func (f *fakeDashboardArchiver) SetArchived(ctx context.Context, id, userID uuid.UUID, archived bool) error {
+	owner, ok := f.owners[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if owner != userID {
+		return services.ErrPermissionDenied
+	}
+	if f.drafts[id] {
+		return services.ErrInvalidRequest
+	}
+	f.archived[id] = archived
+	return nil
+}
+
+func postArchive(handler *DashboardHandler, dashboardID, userID uuid.UUID, archive bool) int {
+	c, w := createTestContext("POST", "/api/dashboards/"+dashboardID.String()+"/archive", nil)
+	c.Params = gin.Params{{Key: "id", Value: dashboardID.String()}}
+	c.Set("userID", userID)
+	if archive {
+		handler.ArchiveDashboard(c)
+	} else {
+		handler.UnarchiveDashboard(c)
+	}
+	return w.Code
+}
+
+func TestArchiveDashboard(t *testing.T) {
+	owner := uuid.New()
+	dashboardID, draftID := uuid.New(), uuid.New()
+	archiver := &fakeDashboardArchiver{
+		owners:   map[uuid.UUID]uuid.UUID{dashboardID: owner, draftID: owner},
+		drafts:   map[uuid.UUID]bool{draftID: true},
+		archived: map[uuid.UUID]bool{},
+	}
+	handler := &DashboardHandler{archiver: archiver}
+
+	if code := postArchive(handler, dashboardID, uuid.New(), true); code != http.StatusForbidden {
+		t.Fatalf("non-owner ArchiveDashboard() status = %d, want %d", code, http.StatusForbidden)
+	}
+	if code := postArchive(handler, draftID, owner, true); code != http.StatusBadRequest {
+		t.Fatalf("draft ArchiveDashboard() status = %d, want %d", code, http.StatusBadRequest)
+	}
+	if code := postArchive(handler, uuid.New(), owner, true); code != http.StatusNotFound {
+		t.Fatalf("missing ArchiveDashboard() status = %d, want %d", code, http.StatusNotFound)
+	}
+
+	if code := postArchive(handler, dashboardID, owner, true); code != http.StatusOK || !archiver.archived[dashboardID] {
+		t.Fatalf("owner ArchiveDashboard() status = %d, archived = %v", code, archiver.archived[dashboardID])
+	}
+	if code := postArchive(handler, dashboardID, owner, false); code != http.StatusOK || archiver.archived[dashboardID] {
+		t.Fatalf("owner UnarchiveDashboard() status = %d, archived = %v", code, archiver.archived[dashboardID])
+	}
+}
+
+func TestGetDashboards_RejectsUnknownFilter(t *testing.T) {
+	handler := &DashboardHandler{}
+	c, w := createTestContext("GET", "/api/dashboards?filter=deleted", nil)
+	handler.GetDashboards(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("GetDashboards() status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+// fakeDashboardTrash mirrors DashboardService.GetTrash and RestoreDashboard against maps of
+// trashed dashboards and their owners
+type fakeDashboardTrash struct {
+	owners  map[uuid.UUID]uuid.UUID
+	trashed map[uuid.UUID]bool
+	names   map[uuid.UUID]string
+	taken   map[string]bool // names already used by live dashboards
+}
+
+func (f *fakeDashboardTrash) GetTrash(ctx context.Context, userID uuid.UUID) ([]models.Dashboard, error) {
+	var dashboards []models.Dashboard
+	for id, owner := range f.owners {
+		if owner == userID && f.trashed[id] {
+			dashboards = append(dashboards, models.Dashboard{ID: id, Name: f.names[id], UserID: owner})
+		}
+	}
+	return dashboards, nil
+}
+
+func (f *fakeDashboardTrash) RestoreDashboard(ctx context.Context, id, userID uuid.UUID) error {
+	if !f.trashed[id] {
+		return services.ErrNotFound
+	}
+	if f.owners[id] != userID {
+		return services.ErrPermissionDenied
+	}
+	if f.taken[f.names[id]] {
+		return services.ErrDuplicateDashboardName
+	}
+	f.trashed[id] = false
+	return nil
+}
+
+func postRestore(handler *DashboardHandler, dashboardID, userID uuid.UUID) int {
+	c, w := createTestContext("POST", "/api/dashboards/"+dashboardID.String()+"/restore", nil)
+	c.Params = gin.Params{{Key: "id", Value: dashboardID.String()}}
+	c.Set("userID", userID)
+	handler.RestoreDashboard(c)
+	return w.Code
+}
+
+func TestRestoreDashboard(t *testing.T) {
+	owner := uuid.New()
+	dashboardID, clashingID := uuid.New(), uuid.New()
+	trash := &fakeDashboardTrash{
+		owners:  map[uuid.UUID]uuid.UUID{dashboardID: owner, clashingID: owner},
+		trashed: map[uuid.UUID]bool{dashboardID: true, clashingID: true},
+		names:   map[uuid.UUID]string{dashboardID: "Sales", clashingID: "Revenue"},
+		taken:   map[string]bool{"Revenue": true},
+	}
+	handler := &DashboardHandler{trash: trash}
+
+	if code := postRestore(handler, dashboardID, uuid.New()); code != http.StatusForbidden {
+		t.Fatalf("non-owner RestoreDashboard() status = %d, want %d", code, http.StatusForbidden)
+	}
+	if code := postRestore(handler, uuid.New(), owner); code != http.StatusNotFound {
+		t.Fatalf("missing RestoreDashboard() status = %d, want %d", code, http.StatusNotFound)
+	}
+	if code := postRestore(handler, clashingID, owner); code != http.StatusConflict {
+		t.Fatalf("duplicate name RestoreDashboard() status = %d, want %d", code, http.StatusConflict)
+	}
+
+	if code := postRestore(handler, dashboardID, owner); code != http.StatusOK || trash.trashed[dashboardID] {
+		t.Fatalf("owner RestoreDashboard() status = %d, trashed = %v", code, trash.trashed[dashboardID])
+	}
+	if code := postRestore(handler, dashboardID, owner); code != http.StatusNotFound {
+		t.Fatalf("second RestoreDashboard() status = %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestGetTrash_ReturnsEmptyArray(t *testing.T) {
+	handler := &DashboardHandler{trash: &fakeDashboardTrash{}}
+	c, w := createTestContext("GET", "/api/dashboards/trash", nil)
+	handler.GetTrash(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("GetTrash() status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if body := strings.TrimSpace(w.Body.String()); body != "[]" {
+		t.Fatalf("GetTrash() body = %s, want []", body)
+	}
+}
+
+// fakeDashboardVersions serves versions from a map and tracks which dashboards have a draft
+type fakeDashboardVersions struct {
+	versions map[uuid.UUID][]models.DashboardVersion
+	drafts   map[uuid.UUID]bool
+}
+
+func (f *fakeDashboardVersions) GetDashboardVersions(ctx context.Context, dashboardID, userID uuid.UUID) ([]models.DashboardVersion, error) {
+	return f.versions[dashboardID], nil
+}
+
+func (f *fakeDashboardVersions) RestoreDashboardVersion(ctx context.Context, dashboardID uuid.UUID, version int, userID uuid.UUID) (*models.Dashboard, error) {
+	if f.drafts[dashboardID] {
+		return nil, services.ErrDraftExists
+	}
+	for _, v := range f.versions[dashboardID] {
+		if v.Version == version {
+			f.drafts[dashboardID] = true
+			return &models.Dashboard{ID: uuid.New(), Name: v.Name, IsDraft: true, DraftOf: &dashboardID}, nil
+		}
+	}
+	return nil, services.ErrNotFound
+}
+
+func postRestoreVersion(handler *DashboardHandler, dashboardID uuid.UUID, version string) int {
+	c, w := createTestContext("POST", "/api/dashboards/"+dashboardID.String()+"/versions/"+version+"/restore", nil)
+	c.Params = gin.Params{{Key: "id", Value: dashboardID.String()}, {Key: "version", Value: version}}
+	handler.RestoreDashboardVersion(c)
+	return w.Code
+}
+
+func TestRestoreDashboardVersion(t *testing.T) {
+	dashboardID := uuid.New()
+	versions := &fakeDashboardVersions{
+		versions: map[uuid.UUID][]models.DashboardVersion{
+			dashboardID: {{DashboardID: dashboardID, Version: 2, Name: "Sales"}, {DashboardID: dashboardID, Version: 1, Name: "Sales"}},
+		},
+		drafts: map[uuid.UUID]bool{},
+	}
+	handler := &DashboardHandler{versions: versions}
+
+	for _, bad := range []string{"latest", "0", "-1"} {
+		if code := postRestoreVersion(handler, dashboardID, bad); code != http.StatusBadRequest {
+			t.Fatalf("RestoreDashboardVersion(%q) status = %d, want %d", bad, code, http.StatusBadRequest)
+		}
+	}
+	if code := postRestoreVersion(handler, dashboardID, "3"); code != http.StatusNotFound {
+		t.Fatalf("missing RestoreDashboardVersion() status = %d, want %d", code, http.StatusNotFound)
+	}
+	if code := postRestoreVersion(handler, dashboardID, "1"); code != http.StatusCreated {
+		t.Fatalf("RestoreDashboardVersion() status = %d, want %d", code, http.StatusCreated)
+	}
+	if code := postRestoreVersion(handler, dashboardID, "2"); code != http.StatusConflict {
+		t.Fatalf("RestoreDashboardVersion() with a draft status = %d, want %d", code, http.StatusConflict)
+	}
+}
+
+func TestGetDashboardVersions(t *testing.T) {
+	dashboardID := uuid.New()
+	handler := &DashboardHandler{versions: &fakeDashboardVersions{
+		versions: map[uuid.UUID][]models.DashboardVersion{
+			dashboardID: {{DashboardID: dashboardID, Version: 1, Name: "Sales", WidgetCount: 3}},
+		},
+	}}
+
+	c, w := createTestContext("GET", "/api/dashboards/"+dashboardID.String()+"/versions", nil)
+	c.Params = gin.Params{{Key: "id", Value: dashboardID.String()}}
+	handler.GetDashboardVersions(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("GetDashboardVersions() status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var got []models.DashboardVersion
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(got) != 1 || got[0].Version != 1 || got[0].WidgetCount != 3 {
+		t.Fatalf("GetDashboardVersions() = %+v", got)
+	}
+}
+
+func setupActivityTest() (*DashboardHandler, *fakeDashboardViewer, uuid.UUID) {
+	dashboardID := uuid.New()
+	actor := uuid.New()
+	events := make([]models.DashboardEvent, 3)
+	for i := range events {
+		events[i] = models.DashboardEvent{
+			ID:          uuid.New(),
+			DashboardID: dashboardID,
+			ActorID:     &actor,
+			Action:      models.DashboardEventUpdate,
+			TargetType:  models.DashboardEventTargetWidget,
+			TargetID:    uuid.New(),
+			TargetName:  "Revenue",
+		}
+	}
+	viewer := &fakeDashboardViewer{
+		levels: map[uuid.UUID]models.PermissionLevel{dashboardID: models.PermissionView},
+		drafts: map[uuid.UUID]bool{},
+		events: map[uuid.UUID][]models.DashboardEvent{dashboardID: events},
+	}
+	return &DashboardHandler{viewer: viewer}, viewer, dashboardID
+}
+
+func getActivity(handler *DashboardHandler, dashboardID, query string) (int, []byte) {
+	c, w := createTestContext("GET", "/api/dashboards/"+dashboardID+"/activity"+query, nil)
+	c.Params = gin.Params{{Key: "id", Value: dashboardID}}
+	handler.GetDashboardActivity(c)
+	return w.Code, w.Body.Bytes()
+}
+
+func TestGetDashboardActivity_Paginates(t *testing.T) {
+	handler, viewer, dashboardID := setupActivityTest()
+
+	code, body := getActivity(handler, dashboardID.String(), "?limit=2&offset=1")
+	if code != http.StatusOK {
+		t.Fatalf("GetDashboardActivity() status = %d, want %d", code, http.StatusOK)
+	}
+
+	var got models.DashboardActivityResponse
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if got.Total != 3 || len(got.Events) != 2 {
+		t.Fatalf("GetDashboardActivity() total = %d, events = %d, want 3 and 2", got.Total, len(got.Events))
+	}
+	if got.Events[0].ID != viewer.events[dashboardID][1].ID {
+		t.Fatalf("GetDashboardActivity() first event = %s, want the one at offset 1", got.Events[0].ID)
+	}
+}
+
+func TestGetDashboardActivity_RequiresViewPermission(t *testing.T) {
+	handler, viewer, dashboardID := setupActivityTest()
+	viewer.levels[dashboardID] = models.PermissionNone
+
+	if code, _ := getActivity(handler, dashboardID.String(), ""); code != http.StatusForbidden {
+		t.Fatalf("GetDashboardActivity() status = %d, want %d", code, http.StatusForbidden)
+	}
+}
+
+func TestGetDashboardActivity_DraftRequiresEdit(t *testing.T) {
+	handler, viewer, dashboardID := setupActivityTest()
+	viewer.drafts[dashboardID] = true
+
+	if code, _ := getActivity(handler, dashboardID.String(), ""); code != http.StatusForbidden {
+		t.Fatalf("GetDashboardActivity() status = %d, want %d", code, http.StatusForbidden)
+	}
+
+	viewer.levels[dashboardID] = models.PermissionEdit
+	if code, _ := getActivity(handler, dashboardID.String(), ""); code != http.StatusOK {
+		t.Fatalf("GetDashboardActivity() status = %d, want %d", code, http.StatusOK)
+	}
+}
+
+// renderFixture is a dashboard whose owner may only read the hive catalog, with one widget per outcome
+type renderFixture struct {
+	handler     *DashboardHandler
+	viewer      *fakeDashboardViewer
+	roles       *repository.MockRoleRepository // the owner may read the hive catalog only
+	dashboardID uuid.UUID
+	ok          uuid.UUID // renders data
+	failing     uuid.UUID // query fails in Trino
+	denied      uuid.UUID // reads a catalog the owner cannot access
+	needsParam  uuid.UUID // uses a parameter without a default
+}
+
+func setupRenderTest() *renderFixture {
+	f := &renderFixture{dashboardID: uuid.New()}
+	ownerID := uuid.New()
+	queries := fakeSavedQueries{}
+	widgets := map[uuid.UUID]*models.Widget{}
+	addWidget := func(queryText string) uuid.UUID {
+		queryID := uuid.New()
+		queries[queryID] = &models.SavedQuery{ID: queryID, QueryText: queryText}
+		w := &models.Widget{ID: uuid.New(), DashboardID: f.dashboardID, Name: queryText, ChartType: "table", QueryID: &queryID}
+		widgets[w.ID] = w
+		return w.ID
+	}
+	f.ok = addWidget("SELECT * FROM hive.sales.orders WHERE region = '{{region}}'")
+	f.failing = addWidget("SELECT * FROM hive.sales.broken")
+	f.denied = addWidget("SELECT * FROM postgres.public.users")
+	f.needsParam = addWidget("SELECT * FROM hive.sales.orders WHERE day = '{{day}}'")
+	text := &models.Widget{ID: uuid.New(), DashboardID: f.dashboardID, Name: "Notes", ChartType: "text"}
+	widgets[text.ID] = text
+
+	f.viewer = &fakeDashboardViewer{
+		levels:  map[uuid.UUID]models.PermissionLevel{f.dashboardID: models.PermissionView},
+		drafts:  map[uuid.UUID]bool{},
+		widgets: widgets,
+		params:  map[uuid.UUID]json.RawMessage{},
+		owners:  map[uuid.UUID]uuid.UUID{f.dashboardID: ownerID},
+		titles:  map[uuid.UUID]string{f.dashboardID: "Orders in {{region}}"},
+	}
+
+	trino := repository.NewMockTrinoExecutor()
+	trino.ExecuteQueryFunc = func(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error) {
+		if strings.Contains(query, "broken") {
+			return nil, errors.New("table hive.sales.broken does not exist")
+		}
+		return &models.QueryResult{Columns: []string{"region"}, Rows: [][]interface{}{{"emea"}}, RowCount: 1}, nil
+	}
+
+	f.roles = repository.NewMockRoleRepository()
+	f.roles.AllowedCatalogs[ownerID] = []string{"hive"}
+
+	f.handler = &DashboardHandler{
+		viewer:       f.viewer,
+		savedQueries: queries,
+		trinoService: trino,
+		roleService:  services.NewRoleService(f.roles),
+	}
+	return f
+}
+
+func renderDashboard(handler *DashboardHandler, dashboardID string, body interface{}) (int, []byte) {
+	c, w := createTestContext("POST", "/api/dashboards/"+dashboardID+"/render", body)
+	c.Params = gin.Params{{Key: "id", Value: dashboardID}}
+	handler.RenderDashboard(c)
+	return w.Code, w.Body.Bytes()
+}
+
+func TestRenderDashboard_ReportsPartialFailuresPerWidget(t *testing.T) {
+	f := setupRenderTest()
+
+	code, body := renderDashboard(f.handler, f.dashboardID.String(), models.WidgetDataRequest{
+		Parameters: map[string]interface{}{"region": "emea"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("RenderDashboard() status = %d, want %d: %s", code, http.StatusOK, body)
+	}
+
+	var got models.DashboardRenderResponse
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(got.Widgets) != 4 {
+		t.Fatalf("RenderDashboard() widgets = %d, want 4 (text widgets are skipped)", len(got.Widgets))
+	}
+	byID := map[uuid.UUID]models.WidgetDataResponse{}
+	for _, w := range got.Widgets {
+		byID[w.WidgetID] = w
+	}
+
+	if w := byID[f.ok]; w.Error != "" || w.QueryResult == nil || w.QueryResult.RowCount != 1 {
+		t.Errorf("ok widget = %+v, want one row and no error", w)
+	}
+	if w := byID[f.failing]; w.QueryResult != nil || !strings.Contains(w.Error, "does not exist") {
+		t.Errorf("failing widget = %+v, want the Trino error", w)
+	}
+	if w := byID[f.denied]; w.QueryResult != nil || w.Error != "access denied to catalog: postgres" {
+		t.Errorf("denied widget = %+v, want the owner's catalog to be enforced", w)
+	}
+	if w := byID[f.needsParam]; w.QueryResult != nil || len(w.MissingParameters) != 1 || w.MissingParameters[0] != "day" {
+		t.Errorf("needsParam widget = %+v, want missing parameter day", w)
+	}
+}
+
+func TestRenderDashboard_MissingSavedQueryDoesNotFailRender(t *testing.T) {
+	f := setupRenderTest()
+	delete(f.handler.savedQueries.(fakeSavedQueries), *f.viewer.widgets[f.failing].QueryID)
+
+	code, body := renderDashboard(f.handler, f.dashboardID.String(), models.WidgetDataRequest{
+		Parameters: map[string]interface{}{"region": "emea"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("RenderDashboard() status = %d, want %d", code, http.StatusOK)
+	}
+
+	var got models.DashboardRenderResponse
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	for _, w := range got.Widgets {
+		if w.WidgetID == f.failing && w.Error != "query not found" {
+			t.Errorf("widget with deleted query error = %q, want %q", w.Error, "query not found")
+		}
+		if w.WidgetID == f.ok && w.QueryResult == nil {
+			t.Errorf("ok widget has no data, want it rendered despite the other failure")
+		}
+	}
+}
+
+func TestRenderDashboard_MergesThemeUnderChartConfig(t *testing.T) {
+	f := setupRenderTest()
+	widget := f.viewer.widgets[f.ok]
+	widget.ChartConfig = json.RawMessage(`{"xAxis":"region","customColors":["#000000"]}`)
+	widget.ThemeConfig = json.RawMessage(`{"customColors":["#ffffff"],"fontFamily":"Inter"}`)
+
+	code, body := renderDashboard(f.handler, f.dashboardID.String(), models.WidgetDataRequest{
+		Parameters: map[string]interface{}{"region": "emea"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("RenderDashboard() status = %d, want %d", code, http.StatusOK)
+	}
+
+	var got models.DashboardRenderResponse
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	for _, w := range got.Widgets {
+		if w.WidgetID != f.ok {
+			continue
+		}
+		var cfg map[string]interface{}
+		if err := json.Unmarshal(w.ChartConfig, &cfg); err != nil {
+			t.Fatalf("chart_config = %s, want an object", w.ChartConfig)
+		}
+		if cfg["xAxis"] != "region" || cfg["fontFamily"] != "Inter" {
+			t.Errorf("chart_config = %v, want the widget's keys and the theme's font", cfg)
+		}
+		if colors, _ := cfg["customColors"].([]interface{}); len(colors) != 1 || colors[0] != "#000000" {
+			t.Errorf("customColors = %v, want the widget's own colors to win over the theme", cfg["customColors"])
+		}
+		return
+	}
+	t.Fatal("ok widget missing from render response")
+}
+
+func TestGetWidgetData_MergesThemeUnderChartConfig(t *testing.T) {
+	f := setupRenderTest()
+	widget := f.viewer.widgets[f.failing]
+	widget.ChartConfig = json.RawMessage(`{"xAxis":"region","customColors":["#000000"]}`)
+	widget.ThemeConfig = json.RawMessage(`{"customColors":["#ffffff"],"fontFamily":"Inter"}`)
+	queryText := "SELECT region FROM hive.sales.orders"
+	widget.QueryID, widget.QueryText = nil, &queryText
+
+	c, w := createTestContext("GET", "/api/dashboards/"+f.dashboardID.String()+"/widgets/"+widget.ID.String()+"/data", nil)
+	c.Params = gin.Params{{Key: "id", Value: f.dashboardID.String()}, {Key: "widgetId", Value: widget.ID.String()}}
+	f.handler.GetWidgetData(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GetWidgetData() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var got models.WidgetDataResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if got.QueryResult == nil {
+		t.Fatalf("GetWidgetData() returned no data: %s", w.Body.String())
+	}
+	var cfg map[string]interface{}
+	if err := json.Unmarshal(got.ChartConfig, &cfg); err != nil {
+		t.Fatalf("chart_config = %s, want an object", got.ChartConfig)
+	}
+	if cfg["xAxis"] != "region" || cfg["fontFamily"] != "Inter" {
+		t.Errorf("chart_config = %v, want the widget's keys and the theme's font", cfg)
+	}
+	if colors, _ := cfg["customColors"].([]interface{}); len(colors) != 1 || colors[0] != "#000000" {
+		t.Errorf("customColors = %v, want the widget's own colors to win over the theme", cfg["customColors"])
+	}
+}
+
+func TestRenderDashboard_AppliesColumnAliases(t *testing.T) {
+	f := setupRenderTest()
+	f.viewer.widgets[f.ok].ChartConfig = json.RawMessage(`{"columnAliases":{"region":"Region"}}`)
+	f.viewer.widgets[f.failing].ChartConfig = json.RawMessage(`{"columnAliases":{"revenue":"Revenue"}}`)
+	f.handler.trinoService.(*repository.MockTrinoExecutor).ExecuteQueryFunc = func(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error) {
+		return &models.QueryResult{Columns: []string{"region"}, Rows: [][]interface{}{{"emea"}}, RowCount: 1}, nil
+	}
+
+	code, body := renderDashboard(f.handler, f.dashboardID.String(), models.WidgetDataRequest{
+		Parameters: map[string]interface{}{"region": "emea"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("RenderDashboard() status = %d, want %d", code, http.StatusOK)
+	}
+
+	var got models.DashboardRenderResponse
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	for _, w := range got.Widgets {
+		switch w.WidgetID {
+		case f.ok:
+			if w.QueryResult == nil || len(w.QueryResult.Columns) != 1 || w.QueryResult.Columns[0] != "Region" {
+				t.Errorf("aliased widget = %+v, want column region returned as Region", w)
+			}
+		case f.failing:
+			if w.QueryResult != nil || !strings.Contains(w.Error, "unknown column: revenue") {
+				t.Errorf("widget aliasing a missing column = %+v, want an unknown column error", w)
+			}
+		}
+	}
+}
+
+func TestRenderDashboard_BypassCacheRequiresEditPermission(t *testing.T) {
+	f := setupRenderTest()
+	trino := f.handler.trinoService.(*repository.MockTrinoExecutor)
+	req := models.WidgetDataRequest{Parameters: map[string]interface{}{"region": "emea"}, BypassCache: true}
+
+	if code, _ := renderDashboard(f.handler, f.dashboardID.String(), req); code != http.StatusForbidden {
+		t.Fatalf("viewer RenderDashboard() status = %d, want %d", code, http.StatusForbidden)
+	}
+	if len(trino.ExecuteQueryCalls) != 0 {
+		t.Fatalf("viewer bypass ran %d queries, want none", len(trino.ExecuteQueryCalls))
+	}
+
+	f.viewer.levels[f.dashboardID] = models.PermissionEdit
+	if code, body := renderDashboard(f.handler, f.dashboardID.String(), req); code != http.StatusOK {
+		t.Fatalf("editor RenderDashboard() status = %d, want %d: %s", code, http.StatusOK, body)
+	}
+	// ok and failing reach Trino; denied and needsParam stop before executing
+	if len(trino.RefreshQueryCalls) != 2 {
+		t.Fatalf("editor bypass refreshed %d queries, want 2", len(trino.RefreshQueryCalls))
+	}
+}
+
+func debugWidgetQuery(handler *DashboardHandler, dashboardID, widgetID uuid.UUID, params string) (int, models.WidgetQueryDebug) {
+	path := "/api/admin/dashboards/" + dashboardID.String() + "/widgets/" + widgetID.String() + "/debug"
+	c, w := createTestContext("GET", path, nil)
+	c.Params = gin.Params{{Key: "id", Value: dashboardID.String()}, {Key: "widgetId", Value: widgetID.String()}}
+	if params != "" {
+		c.Request.URL.RawQuery = url.Values{"params": {params}}.Encode()
+	}
+	handler.DebugWidgetQuery(c)
+
+	var got models.WidgetQueryDebug
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	return w.Code, got
+}
+
+func TestDebugWidgetQuery_ResolvesWithoutExecuting(t *testing.T) {
+	f := setupRenderTest()
+	f.viewer.catalogs = map[uuid.UUID]string{f.dashboardID: "hive"}
+	f.viewer.schemas = map[uuid.UUID]string{f.dashboardID: "sales"}
+	trino := f.handler.trinoService.(*repository.MockTrinoExecutor)
+
+	code, got := debugWidgetQuery(f.handler, f.dashboardID, f.ok, `{"region":"emea"}`)
+	if code != http.StatusOK {
+		t.Fatalf("DebugWidgetQuery() status = %d, want %d", code, http.StatusOK)
+	}
+	if got.ResolvedQuery != "SELECT * FROM hive.sales.orders WHERE region = 'emea'" {
+		t.Errorf("resolved_query = %q, want the region substituted", got.ResolvedQuery)
+	}
+	if got.Catalog != "hive" || got.Schema != "sales" {
+		t.Errorf("catalog/schema = %s/%s, want the dashboard defaults hive/sales", got.Catalog, got.Schema)
+	}
+	if got.OwnerID != f.viewer.owners[f.dashboardID] || !got.AccessAllowed || got.AccessError != "" {
+		t.Errorf("debug = %+v, want access allowed for the dashboard owner", got)
+	}
+	if len(trino.ExecuteQueryCalls) != 0 {
+		t.Errorf("DebugWidgetQuery() ran %d queries, want none", len(trino.ExecuteQueryCalls))
+	}
+}
+
+func TestDebugWidgetQuery_ReportsAccessDenied(t *testing.T) {
+	f := setupRenderTest()
+
+	code, got := debugWidgetQuery(f.handler, f.dashboardID, f.denied, "")
+	if code != http.StatusOK {
+		t.Fatalf("DebugWidgetQuery() status = %d, want %d", code, http.StatusOK)
+	}
+	if got.AccessAllowed || got.AccessError != "access denied to catalog: postgres" {
+		t.Errorf("debug = %+v, want the owner's catalog check to fail", got)
+	}
+	if got.ResolvedQuery != "SELECT * FROM postgres.public.users" {
+		t.Errorf("resolved_query = %q, want the query that would have run", got.ResolvedQuery)
+	}
+}
+
+func TestDebugWidgetQuery_MissingParameters(t *testing.T) {
+	f := setupRenderTest()
+
+	code, got := debugWidgetQuery(f.handler, f.dashboardID, f.needsParam, `{"region":"emea"}`)
+	if code != http.StatusOK {
+		t.Fatalf("DebugWidgetQuery() status = %d, want %d", code, http.StatusOK)
+	}
+	if len(got.MissingParameters) != 1 || got.MissingParameters[0] != "day" || got.ResolvedQuery != "" || got.AccessAllowed {
+		t.Errorf("debug = %+v, want missing parameter day and no access check", got)
+	}
+}
+
+func TestDebugWidgetQuery_RejectsInvalidParams(t *testing.T) {
+	f := setupRenderTest()
+
+	if code, _ := debugWidgetQuery(f.handler, f.dashboardID, f.ok, `["emea"]`); code != http.StatusBadRequest {
+		t.Errorf("DebugWidgetQuery() with a JSON array status = %d, want %d", code, http.StatusBadRequest)
+	}
+	if code, _ := debugWidgetQuery(f.handler, f.dashboardID, uuid.New(), ""); code != http.StatusNotFound {
+		t.Errorf("DebugWidgetQuery() for an unknown widget status = %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestRenderDashboard_RendersParameterPlaceholdersInTitle(t *testing.T) {
+	f := setupRenderTest()
+
+	code, body := renderDashboard(f.handler, f.dashboardID.String(), models.WidgetDataRequest{
+		Parameters: map[string]interface{}{"region": "emea"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("RenderDashboard() status = %d, want %d: %s", code, http.StatusOK, body)
+	}
+	var got models.DashboardRenderResponse
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if got.Title != "Orders in emea" {
+		t.Errorf("RenderDashboard() title = %q, want %q", got.Title, "Orders in emea")
+	}
+
+	// Without a value or default the raw title is shown
+	_, body = renderDashboard(f.handler, f.dashboardID.String(), models.WidgetDataRequest{})
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if got.Title != "Orders in {{region}}" {
+		t.Errorf("RenderDashboard() title = %q, want the raw title", got.Title)
+	}
+}
+
+func TestRenderDashboard_ZeroRowResultIsNotAnError(t *testing.T) {
+	f := setupRenderTest()
+	// A result cached with nil slices, as before empty rows were normalized
+	cached := &models.QueryResult{Columns: []string{"region"}, RowCount: 0}
+	f.handler.trinoService.(*repository.MockTrinoExecutor).ExecuteQueryFunc = func(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error) {
+		return cached, nil
+	}
+
+	code, body := renderDashboard(f.handler, f.dashboardID.String(), models.WidgetDataRequest{
+		Parameters: map[string]interface{}{"region": "emea"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("RenderDashboard() status = %d, want %d: %s", code, http.StatusOK, body)
+	}
+
+	var got struct {
+		Widgets []struct {
+			WidgetID    uuid.UUID                  `json:"widget_id"`
+			Error       string                     `json:"error"`
+			QueryResult map[string]json.RawMessage `json:"query_result"`
+		} `json:"widgets"`
+	}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	for _, w := range got.Widgets {
+		if w.WidgetID != f.ok {
+			continue
+		}
+		if w.Error != "" || w.QueryResult == nil {
+			t.Fatalf("zero-row widget = %+v, want a result and no error", w)
+		}
+		if string(w.QueryResult["rows"]) != "[]" || string(w.QueryResult["row_count"]) != "0" || string(w.QueryResult["columns"]) != `["region"]` {
+			t.Errorf("zero-row result = rows %s, row_count %s, columns %s; want [], 0, [\"region\"]",
+				w.QueryResult["rows"], w.QueryResult["row_count"], w.QueryResult["columns"])
+		}
+		if cached.Rows != nil {
+			t.Error("RenderDashboard() modified the cached result")
+		}
+		return
+	}
+	t.Fatal("RenderDashboard() did not return the ok widget")
+}
+
+func TestRenderDashboard_RequiresViewPermission(t *testing.T) {
+	f := setupRenderTest()
+	f.viewer.levels[f.dashboardID] = models.PermissionNone
+
+	if code, _ := renderDashboard(f.handler, f.dashboardID.String(), models.WidgetDataRequest{}); code != http.StatusForbidden {
+		t.Fatalf("RenderDashboard() status = %d, want %d", code, http.StatusForbidden)
+	}
+}
+
+func TestRenderDashboard_LimitsConcurrentWidgetQueries(t *testing.T) {
+	f := setupRenderTest()
+	f.handler.SetRenderConcurrency(2)
+	for i := 0; i < 6; i++ {
+		queryID := uuid.New()
+		f.handler.savedQueries.(fakeSavedQueries)[queryID] = &models.SavedQuery{ID: queryID, QueryText: "SELECT * FROM hive.sales.orders"}
+		w := &models.Widget{ID: uuid.New(), DashboardID: f.dashboardID, Name: "extra", ChartType: "table", QueryID: &queryID}
+		f.viewer.widgets[w.ID] = w
+	}
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	f.handler.trinoService.(*repository.MockTrinoExecutor).ExecuteQueryFunc = func(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error) {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return &models.QueryResult{Columns: []string{"region"}, Rows: [][]interface{}{{"emea"}}, RowCount: 1}, nil
+	}
+
+	code, body := renderDashboard(f.handler, f.dashboardID.String(), models.WidgetDataRequest{
+		Parameters: map[string]interface{}{"region": "emea"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("RenderDashboard() status = %d, want %d: %s", code, http.StatusOK, body)
+	}
+	var got models.DashboardRenderResponse
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(got.Widgets) != 10 {
+		t.Fatalf("RenderDashboard() widgets = %d, want 10", len(got.Widgets))
+	}
+	if maxInFlight > 2 {
+		t.Errorf("widget queries in flight = %d, want at most 2", maxInFlight)
+	}
+}
+
+func TestRenderDashboard_ClientDisconnectStopsWidgetQueries(t *testing.T) {
+	f := setupRenderTest()
+	f.handler.SetRenderConcurrency(1)
+
+	started := make(chan struct{}, 4)
+	var mu sync.Mutex
+	calls := 0
+	f.handler.trinoService.(*repository.MockTrinoExecutor).ExecuteQueryFunc = func(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		started <- struct{}{}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, w := createTestContext("POST", "/api/dashboards/"+f.dashboardID.String()+"/render", models.WidgetDataRequest{
+		Parameters: map[string]interface{}{"region": "emea"},
+	})
+	c.Request = c.Request.WithContext(ctx)
+	c.Params = gin.Params{{Key: "id", Value: f.dashboardID.String()}}
+	go func() {
+		<-started
+		cancel()
+	}()
+	f.handler.RenderDashboard(c)
+
+	var got models.DashboardRenderResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("widget queries executed = %d, want 1 (the rest are not started after the disconnect)", calls)
+	}
+	for _, widget := range got.Widgets {
+		if (widget.WidgetID == f.ok || widget.WidgetID == f.failing) && widget.Error != context.Canceled.Error() {
+			t.Errorf("widget %s error = %q, want %q", widget.WidgetID, widget.Error, context.Canceled.Error())
+		}
+	}
+}
+
+func getDashboardData(handler *DashboardHandler, dashboardID string, body interface{}) (int, []byte) {
+	c, w := createTestContext("POST", "/api/dashboards/"+dashboardID+"/data", body)
+	c.Params = gin.Params{{Key: "id", Value: dashboardID}}
+	handler.GetDashboardData(c)
+	return w.Code, w.Body.Bytes()
+}
+
+func TestGetDashboardData_KeysWidgetsByIDAndAggregatesMissingParameters(t *testing.T) {
+	f := setupRenderTest()
+
+	// Without parameters, the ok widget misses region and needsParam misses day
+	code, body := getDashboardData(f.handler, f.dashboardID.String(), models.WidgetDataRequest{})
+	if code != http.StatusOK {
+		t.Fatalf("GetDashboardData() status = %d, want %d: %s", code, http.StatusOK, body)
+	}
+
+	var got models.DashboardDataResponse
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if got.DashboardID != f.dashboardID {
+		t.Errorf("GetDashboardData() dashboard_id = %s, want %s", got.DashboardID, f.dashboardID)
+	}
+	if len(got.Widgets) != 4 {
+		t.Fatalf("GetDashboardData() widgets = %d, want 4 (text widgets are skipped)", len(got.Widgets))
+	}
+	for id, w := range got.Widgets {
+		if w.WidgetID != id {
+			t.Errorf("widget keyed %s has widget_id %s", id, w.WidgetID)
+		}
+	}
+	if want := []string{"day", "region"}; !slices.Equal(got.MissingParameters, want) {
+		t.Errorf("GetDashboardData() missing_parameters = %v, want %v", got.MissingParameters, want)
+	}
+	if w := got.Widgets[f.denied]; w.Error != "access denied to catalog: postgres" {
+		t.Errorf("denied widget = %+v, want the owner's catalog to be enforced", w)
+	}
+
+	code, body = getDashboardData(f.handler, f.dashboardID.String(), models.WidgetDataRequest{
+		Parameters: map[string]interface{}{"region": "emea", "day": "2024-01-01"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("GetDashboardData() status = %d, want %d", code, http.StatusOK)
+	}
+	got = models.DashboardDataResponse{}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(got.MissingParameters) != 0 {
+		t.Errorf("GetDashboardData() missing_parameters = %v, want none", got.MissingParameters)
+	}
+	if w := got.Widgets[f.ok]; w.QueryResult == nil || w.QueryResult.RowCount != 1 {
+		t.Errorf("ok widget = %+v, want one row", w)
+	}
+}
+
+func TestGetDashboardData_RequiresViewPermission(t *testing.T) {
+	f := setupRenderTest()
+	f.viewer.levels[f.dashboardID] = models.PermissionNone
+
+	if code, _ := getDashboardData(f.handler, f.dashboardID.String(), models.WidgetDataRequest{}); code != http.StatusForbidden {
+		t.Fatalf("GetDashboardData() status = %d, want %d", code, http.StatusForbidden)
+	}
+}
+
+func TestFormatParameterValue_Identifier(t *testing.T) {
+	tests := []struct {
+		name           string
+		value          string
+		allowQualified bool
+		want           string
+		wantErr        bool
+	}{
+		{"plain", "revenue", false, `"revenue"`, false},
+		{"trimmed", " revenue ", false, `"revenue"`, false},
+		{"qualified rejected by default", "sales.orders", false, "", true},
+		{"schema qualified", "sales.orders", true, `"sales"."orders"`, false},
+		{"catalog qualified", "hive.sales.orders", true, `"hive"."sales"."orders"`, false},
+		{"empty part", "sales..orders", true, "", true},
+		{"leading dot", ".orders", true, "", true},
+		{"trailing dot", "sales.", true, "", true},
+		{"embedded quote", `orders" OR 1=1 --`, false, "", true},
+		{"embedded quote in part", `sales.orders"; DROP TABLE t; --`, true, "", true},
+		{"quoted part", `"sales".orders`, true, "", true},
+		{"statement separator", "orders;DELETE", true, "", true},
+		{"whitespace inside", "sales orders", true, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatParameterValue(tt.value, models.SqlFormatIdentifier, false, tt.allowQualified, parameterBounds{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("formatParameterValue(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("formatParameterValue(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplaceParametersWithDefs_QualifiedIdentifier(t *testing.T) {
+	defs := []models.ParameterDefinition{
+		{Name: "table", Type: models.ParameterTypeText, SqlFormat: models.SqlFormatIdentifier, AllowQualified: true},
+		{Name: "column", Type: models.ParameterTypeText, SqlFormat: models.SqlFormatIdentifier},
+	}
+
+	got, missing := replaceParametersWithDefs("SELECT {{column}} FROM {{table}}",
+		map[string]interface{}{"table": "sales.orders", "column": "amount"}, defs, false)
+	if len(missing) != 0 {
+		t.Fatalf("replaceParametersWithDefs() missing = %v, want none", missing)
+	}
+	if want := `SELECT "amount" FROM "sales"."orders"`; got != want {
+		t.Fatalf("replaceParametersWithDefs() = %q, want %q", got, want)
+	}
+
+	_, missing = replaceParametersWithDefs("SELECT {{column}} FROM {{table}}",
+		map[string]interface{}{"table": "sales.orders", "column": "o.amount"}, defs, false)
+	if len(missing) != 1 || missing[0] != "column" {
+		t.Fatalf("replaceParametersWithDefs() missing = %v, want [column] for a qualified value without allow_qualified", missing)
+	}
+}
+
+func TestReplaceParametersWithDefs_FallsBackToDefaults(t *testing.T) {
+	defs := []models.ParameterDefinition{
+		{Name: "region", Type: models.ParameterTypeSelect, SqlFormat: models.SqlFormatString, DefaultValue: "tokyo"},
+		{Name: "ids", Type: models.ParameterTypeMultiSelect, SqlFormat: models.SqlFormatNumberList, DefaultValue: []interface{}{float64(1), float64(2)}},
+		{Name: "tags", Type: models.ParameterTypeMultiSelect, SqlFormat: models.SqlFormatStringList, DefaultValue: []string{"a", "b"}},
+		{
+			Name: "period", Type: models.ParameterTypeDateRange,
+			Targets:      &models.DateRangeTargets{Start: "from", End: "to"},
+			DefaultValue: map[string]interface{}{"start": "2024-01-01", "end": "2024-01-31"},
+		},
+	}
+	query := "SELECT * FROM t WHERE region = {{region}} AND id IN ({{ids}}) AND tag IN ({{tags}}) AND d BETWEEN {{from}} AND {{to}}"
+
+	got, missing := replaceParametersWithDefs(query, nil, defs, false)
+	if len(missing) != 0 {
+		t.Fatalf("replaceParametersWithDefs() missing = %v, want none", missing)
+	}
+	want := "SELECT * FROM t WHERE region = 'tokyo' AND id IN (1,2) AND tag IN ('a','b') AND d BETWEEN DATE '2024-01-01' AND DATE '2024-01-31'"
+	if got != want {
+		t.Fatalf("replaceParametersWithDefs() = %q, want %q", got, want)
+	}
+
+	// A value given by the caller wins over the default
+	got, _ = replaceParametersWithDefs("SELECT {{region}}", map[string]interface{}{"region": "osaka"}, defs, false)
+	if want := "SELECT 'osaka'"; got != want {
+		t.Fatalf("replaceParametersWithDefs() = %q, want %q", got, want)
+	}
+
+	// Without a default the parameter is still missing
+	_, missing = replaceParametersWithDefs("SELECT {{city}}", nil,
+		[]models.ParameterDefinition{{Name: "city", SqlFormat: models.SqlFormatString}}, false)
+	if len(missing) != 1 || missing[0] != "city" {
+		t.Fatalf("replaceParametersWithDefs() missing = %v, want [city]", missing)
+	}
+}
+
+func TestFormatParameterValue_Bounds(t *testing.T) {
+	min, max := 1.0, 100.0
+	numbers := parameterBounds{Min: &min, Max: &max}
+	dates := parameterBounds{MinDate: "2024-01-01", MaxDate: "2024-12-31"}
+
+	tests := []struct {
+		name    string
+		value   interface{}
+		format  models.SqlFormat
+		bounds  parameterBounds
+		want    string
+		wantErr bool
+	}{
+		{"number within bounds", float64(50), models.SqlFormatNumber, numbers, "50", false},
+		{"number at the maximum", "100", models.SqlFormatNumber, numbers, "100", false},
+		{"number below the minimum", "0.5", models.SqlFormatNumber, numbers, "", true},
+		{"number above the maximum", float64(101), models.SqlFormatNumber, numbers, "", true},
+		{"number list item out of bounds", []interface{}{float64(5), float64(500)}, models.SqlFormatNumberList, numbers, "", true},
+		{"comma-separated list out of bounds", "5, 500", models.SqlFormatNumberList, numbers, "", true},
+		{"date within bounds", "2024-06-01", models.SqlFormatDate, dates, "DATE '2024-06-01'", false},
+		{"date before the earliest", "2023-12-31", models.SqlFormatDate, dates, "", true},
+		{"date after the latest", "2025-01-01", models.SqlFormatDate, dates, "", true},
+		{"no bounds", "-5", models.SqlFormatNumber, parameterBounds{}, "-5", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatParameterValue(tt.value, tt.format, false, false, tt.bounds)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("formatParameterValue(%v) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("formatParameterValue(%v) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateParameterValues(t *testing.T) {
+	limit := 10.0
+	queryID := uuid.New()
+	defs := []models.ParameterDefinition{
+		{Name: "region", Type: models.ParameterTypeSelect, SqlFormat: models.SqlFormatString,
+			Options: []models.ParameterOption{{Value: "emea"}, {Value: "apac"}}},
+		{Name: "regions", Type: models.ParameterTypeMultiSelect, SqlFormat: models.SqlFormatStringList,
+			Options: []models.ParameterOption{{Value: "emea"}, {Value: "apac"}}},
+		{Name: "country", Type: models.ParameterTypeSelect, SqlFormat: models.SqlFormatString, OptionsQueryID: &queryID},
+		{Name: "top", Type: models.ParameterTypeNumber, SqlFormat: models.SqlFormatNumber, Max: &limit},
+		{Name: "period", Type: models.ParameterTypeDateRange, MinDate: "2024-01-01"},
+	}
+
+	tests := []struct {
+		name      string
+		params    map[string]interface{}
+		wantField string // empty when the values are valid
+	}{
+		{"valid values", map[string]interface{}{
+			"region": "emea", "regions": []interface{}{"emea", "apac"}, "top": float64(10),
+			"period": map[string]interface{}{"start": "2024-01-01", "end": "2024-01-31"},
+		}, ""},
+		{"omitted values", map[string]interface{}{}, ""},
+		{"dynamic options are not checked", map[string]interface{}{"country": "jp"}, ""},
+		{"select value outside options", map[string]interface{}{"region": "us"}, "parameters.region"},
+		{"multiselect item outside options", map[string]interface{}{"regions": []interface{}{"emea", "us"}}, "parameters.regions"},
+		{"comma-separated multiselect", map[string]interface{}{"regions": "emea,us"}, "parameters.regions"},
+		{"number above maximum", map[string]interface{}{"top": float64(11)}, "parameters.top"},
+		{"date range starting too early", map[string]interface{}{"period": "2023-12-01,2024-01-31"}, "parameters.period"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateParameterValues(tt.params, defs)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("validateParameterValues() error = %v", err)
+				}
+				return
+			}
+			var validationErr *models.ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Field != tt.wantField {
+				t.Fatalf("validateParameterValues() error = %v, want a validation error on %s", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestRenderDashboard_RejectsValueOutsideOptions(t *testing.T) {
+	f := setupRenderTest()
+	f.viewer.params[f.dashboardID], _ = json.Marshal([]models.ParameterDefinition{
+		{Name: "region", Type: models.ParameterTypeSelect, Options: []models.ParameterOption{{Value: "emea"}}},
+	})
+
+	code, body := renderDashboard(f.handler, f.dashboardID.String(), models.WidgetDataRequest{
+		Parameters: map[string]interface{}{"region": "' OR 1=1 --"},
+	})
+	if code != http.StatusBadRequest {
+		t.Fatalf("RenderDashboard() status = %d, want %d: %s", code, http.StatusBadRequest, body)
+	}
+	var got map[string]string
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if got["field"] != "parameters.region" {
+		t.Errorf("RenderDashboard() error field = %q, want parameters.region", got["field"])
+	}
+}
+
+// setupParameterOptionsTest returns a dashboard whose "region" parameter takes its options from queryText,
+// run as an owner who may only read the hive catalog
+func setupParameterOptionsTest(queryText string, result *models.QueryResult) (*DashboardHandler, uuid.UUID) {
+	dashboardID := uuid.New()
+	ownerID := uuid.New()
+	queryID := uuid.New()
+
+	params, _ := json.Marshal([]models.ParameterDefinition{
+		{Name: "region", Type: models.ParameterTypeSelect, SqlFormat: models.SqlFormatString, OptionsQueryID: &queryID},
+	})
+	viewer := &fakeDashboardViewer{
+		levels: map[uuid.UUID]models.PermissionLevel{dashboardID: models.PermissionView},
+		drafts: map[uuid.UUID]bool{},
+		params: map[uuid.UUID]json.RawMessage{dashboardID: params},
+		owners: map[uuid.UUID]uuid.UUID{dashboardID: ownerID},
+	}
+
+	trino := repository.NewMockTrinoExecutor()
+	trino.SetQueryResult(queryText, result)
+
+	roleRepo := repository.NewMockRoleRepository()
+	roleRepo.AllowedCatalogs[ownerID] = []string{"hive"}
+
+	return &DashboardHandler{
+		viewer:       viewer,
+		savedQueries: fakeSavedQueries{queryID: {ID: queryID, QueryText: queryText}},
+		trinoService: trino,
+		roleService:  services.NewRoleService(roleRepo),
+	}, dashboardID
+}
+
+func getParameterOptions(handler *DashboardHandler, dashboardID uuid.UUID) (int, []byte) {
+	c, w := createTestContext("POST", "/api/dashboards/"+dashboardID.String()+"/parameters/region/options", nil)
+	c.Params = gin.Params{{Key: "id", Value: dashboardID.String()}, {Key: "name", Value: "region"}}
+	handler.GetParameterOptions(c)
+	return w.Code, w.Body.Bytes()
+}
+
+func TestGetParameterOptions_CTEWithExtraColumns(t *testing.T) {
+	query := `WITH ranked AS (
+  SELECT code, name, row_number() OVER (ORDER BY name) AS rn FROM hive.sales.regions
+)
+SELECT code, name, rn FROM ranked ORDER BY rn`
+	handler, dashboardID := setupParameterOptionsTest(query, &models.QueryResult{
+		Columns: []string{"code", "name", "rn"},
+		Rows: [][]interface{}{
+			{"apac", "Asia Pacific", 1},
+			{"emea", nil, 2},       // no label: falls back to the value
+			{nil, "Unassigned", 3}, // no value: skipped
+		},
+	})
+
+	code, body := getParameterOptions(handler, dashboardID)
+	if code != http.StatusOK {
+		t.Fatalf("GetParameterOptions() status = %d, want %d: %s", code, http.StatusOK, body)
+	}
+
+	var got []models.ParameterOption
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	want := []models.ParameterOption{{Value: "apac", Label: "Asia Pacific"}, {Value: "emea", Label: "emea"}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("GetParameterOptions() = %+v, want %+v", got, want)
+	}
+}
+
+func TestGetParameterOptions_CTEEnforcesOwnerCatalogs(t *testing.T) {
+	query := "WITH u AS (SELECT region FROM postgres.public.users) SELECT DISTINCT region FROM u"
+	handler, dashboardID := setupParameterOptionsTest(query, &models.QueryResult{})
+
+	code, _ := getParameterOptions(handler, dashboardID)
+	if code != http.StatusForbidden {
+		t.Fatalf("GetParameterOptions() status = %d, want %d", code, http.StatusForbidden)
+	}
+}
+
+func TestParameterOptionsFromResult_Cap(t *testing.T) {
+	result := &models.QueryResult{Rows: [][]interface{}{{nil}, {"a"}, {}, {"b"}, {"c"}}}
+
+	got := parameterOptionsFromResult(result, 2)
+	if len(got) != 2 || got[0].Value != "a" || got[1].Value != "b" {
+		t.Fatalf("parameterOptionsFromResult() = %+v, want options a and b", got)
+	}
+}
+
+func TestParameterOptionsFromResult_TypedValues(t *testing.T) {
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		row       []interface{}
+		wantValue string
+		wantLabel string
+	}{
+		{"int", []interface{}{int64(42)}, "42", "42"},
+		{"large float from cache", []interface{}{float64(1000000), "one million"}, "1000000", "one million"},
+		{"decimal float", []interface{}{float64(0.25)}, "0.25", "0.25"},
+		{"date", []interface{}{day}, "2024-03-01T00:00:00Z", "2024-03-01T00:00:00Z"},
+		{"date label", []interface{}{"2024-03", day}, "2024-03", "2024-03-01T00:00:00Z"},
+		{"boolean", []interface{}{true, false}, "true", "false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parameterOptionsFromResult(&models.QueryResult{Rows: [][]interface{}{tt.row}}, 10)
+			if len(got) != 1 || got[0].Value != tt.wantValue || got[0].Label != tt.wantLabel {
+				t.Fatalf("parameterOptionsFromResult() = %+v, want value %q label %q", got, tt.wantValue, tt.wantLabel)
+			}
+		})
+	}
+}
+
+func TestQueryExecutionContext_Precedence(t *testing.T) {
+	handler := &DashboardHandler{defaultCatalog: "hive", defaultSchema: "default"}
+	str := func(v string) *string { return &v }
+
+	tests := []struct {
+		name                      string
+		queryCatalog, querySchema *string
+		dashCatalog, dashSchema   *string
+		wantCatalog, wantSchema   string
+	}{
+		{"server defaults", nil, nil, nil, nil, "hive", "default"},
+		{"dashboard overrides server", nil, nil, str("iceberg"), str("finance"), "iceberg", "finance"},
+		{"query overrides dashboard", str("postgres"), str("public"), str("iceberg"), str("finance"), "postgres", "public"},
+		{"fields resolve independently", nil, str("public"), str("iceberg"), nil, "iceberg", "public"},
+		{"empty values are ignored", str(""), str(""), str(""), str("finance"), "hive", "finance"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := &models.SavedQuery{Catalog: tt.queryCatalog, SchemaName: tt.querySchema}
+			catalog, schema := handler.queryExecutionContext(query, tt.dashCatalog, tt.dashSchema)
+			if catalog != tt.wantCatalog || schema != tt.wantSchema {
+				t.Errorf("queryExecutionContext() = %s.%s, want %s.%s", catalog, schema, tt.wantCatalog, tt.wantSchema)
+			}
+		})
+	}
+}
+
+func TestRenderDashboard_UsesDashboardCatalogDefaults(t *testing.T) {
+	f := setupRenderTest()
+	f.viewer.catalogs = map[uuid.UUID]string{f.dashboardID: "hive"}
+	f.viewer.schemas = map[uuid.UUID]string{f.dashboardID: "finance"}
+
+	trino := f.handler.trinoService.(*repository.MockTrinoExecutor)
+	var mu sync.Mutex
+	var contexts []string
+	trino.ExecuteQueryFunc = func(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error) {
+		mu.Lock()
+		contexts = append(contexts, catalog+"."+schema)
+		mu.Unlock()
+		return &models.QueryResult{Columns: []string{"region"}, Rows: [][]interface{}{{"emea"}}, RowCount: 1}, nil
+	}
+
+	code, body := renderDashboard(f.handler, f.dashboardID.String(), models.WidgetDataRequest{
+		Parameters: map[string]interface{}{"region": "emea"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("RenderDashboard() status = %d, want %d: %s", code, http.StatusOK, body)
+	}
+	if len(contexts) == 0 {
+		t.Fatal("RenderDashboard() executed no queries")
+	}
+	for _, got := range contexts {
+		if got != "hive.finance" {
+			t.Errorf("query ran in %s, want the dashboard default hive.finance", got)
+		}
+	}
+}
+
+func TestWidgetDataETag_ChangesWithParametersAndCacheTime(t *testing.T) {
+	widget := &models.Widget{ID: uuid.New(), UpdatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cachedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	defs := []models.ParameterDefinition{{Name: "region", Type: models.ParameterTypeText, SqlFormat: models.SqlFormatString}}
+	query := "SELECT * FROM sales WHERE region = {{region}}"
+
+	east, _ := replaceParametersWithDefs(query, map[string]interface{}{"region": "east"}, defs, false)
+	west, _ := replaceParametersWithDefs(query, map[string]interface{}{"region": "west"}, defs, false)
+
+	base := widgetDataETag(widget, east, "hive", "sales", cachedAt)
+	if again := widgetDataETag(widget, east, "hive", "sales", cachedAt); again != base {
+		t.Errorf("ETag is not stable: %s, then %s", base, again)
+	}
+	if other := widgetDataETag(widget, west, "hive", "sales", cachedAt); other == base {
+		t.Error("ETag did not change with the parameter values")
+	}
+	if other := widgetDataETag(widget, east, "hive", "sales", cachedAt.Add(time.Second)); other == base {
+		t.Error("ETag did not change when the result was re-cached")
+	}
+	if other := widgetDataETag(widget, east, "hive", "marketing", cachedAt); other == base {
+		t.Error("ETag did not change with the schema")
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{`"abc"`, true},
+		{`W/"abc"`, true},
+		{`"xyz", "abc"`, true},
+		{`*`, true},
+		{`"xyz"`, false},
+		{`abc`, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.header, `"abc"`); got != tt.want {
+			t.Errorf("etagMatches(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestWidgetDataNotModified(t *testing.T) {
+	trino := repository.NewMockTrinoExecutor()
+	cachedAt := time.Now().Add(-time.Minute)
+	trino.CachedResultTimeFunc = func(ctx context.Context, query, catalog, schema string, maxStaleness time.Duration) (time.Time, time.Time, bool) {
+		return cachedAt, cachedAt.Add(10 * time.Minute), query == "SELECT 1"
+	}
+	handler := &DashboardHandler{trinoService: trino}
+	widget := &models.Widget{ID: uuid.New()}
+	etag := widgetDataETag(widget, "SELECT 1", "hive", "sales", cachedAt)
+
+	c, w := createTestContext(http.MethodGet, "/", nil)
+	c.Request.Header.Set("If-None-Match", etag)
+	if !handler.widgetDataNotModified(c, widget, "SELECT 1", "hive", "sales") {
+		t.Fatal("widgetDataNotModified() = false for the current ETag")
+	}
+	c.Writer.WriteHeaderNow()
+	if w.Code != http.StatusNotModified {
+		t.Errorf("status = %d, want 304", w.Code)
+	}
+	if got := w.Header().Get("ETag"); got != etag {
+		t.Errorf("ETag = %s, want %s", got, etag)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "private, max-age=540" && got != "private, max-age=539" {
+		t.Errorf("Cache-Control = %q, want the remaining 9 minutes", got)
+	}
+
+	c, _ = createTestContext(http.MethodGet, "/", nil)
+	c.Request.Header.Set("If-None-Match", etag)
+	if handler.widgetDataNotModified(c, widget, "SELECT 2", "hive", "sales") {
+		t.Error("widgetDataNotModified() = true for a query with no cached result")
+	}
+
+	c, _ = createTestContext(http.MethodGet, "/", nil)
+	c.Request.Header.Set("If-None-Match", widgetDataETag(widget, "SELECT 1", "hive", "sales", cachedAt.Add(-time.Hour)))
+	if handler.widgetDataNotModified(c, widget, "SELECT 1", "hive", "sales") {
+		t.Error("widgetDataNotModified() = true for the ETag of an older cache entry")
+	}
+}
+
+func TestReplaceParametersWithDefs_InList(t *testing.T) {
+	ids := models.ParameterDefinition{Name: "ids", Type: models.ParameterTypeMultiSelect, SqlFormat: models.SqlFormatStringList}
+	nums := models.ParameterDefinition{Name: "nums", Type: models.ParameterTypeMultiSelect, SqlFormat: models.SqlFormatNumberList}
+	matchNone := func(def models.ParameterDefinition) models.ParameterDefinition {
+		def.EmptyBehavior = models.EmptyBehaviorMatchNone
+		return def
+	}
+
+	tests := []struct {
+		name        string
+		query       string
+		def         models.ParameterDefinition
+		value       interface{}
+		want        string
+		wantMissing bool
+	}{
+		{"single element", "SELECT * FROM t WHERE id IN ({{ids}})", ids, []interface{}{"a1"}, "SELECT * FROM t WHERE id IN ('a1')", false},
+		{"several elements", "SELECT * FROM t WHERE id IN ({{ids}})", ids, []interface{}{"a1", "b2"}, "SELECT * FROM t WHERE id IN ('a1','b2')", false},
+		{"commas and quotes stay inside an item", "SELECT * FROM t WHERE id IN ({{ids}})", ids, []interface{}{"a,b", "it's"}, "SELECT * FROM t WHERE id IN ('a,b','it''s')", false},
+		{"injection attempt is quoted", "SELECT * FROM t WHERE id IN ({{ids}})", ids, []interface{}{"x') OR 1=1 --"}, "SELECT * FROM t WHERE id IN ('x'') OR 1=1 --')", false},
+		{"number items", "SELECT * FROM t WHERE n IN ({{nums}})", nums, []interface{}{float64(1000000), float64(2.5)}, "SELECT * FROM t WHERE n IN (1000000,2.5)", false},
+		{"empty list is missing", "SELECT * FROM t WHERE id IN ({{ids}})", ids, []interface{}{}, "", true},
+		{"empty list matching none", "SELECT * FROM t WHERE id IN ({{ids}})", matchNone(ids), []interface{}{}, "SELECT * FROM t WHERE id IN (NULL)", false},
+		{"empty number list matching none", "SELECT * FROM t WHERE n IN ({{nums}})", matchNone(nums), []interface{}{}, "SELECT * FROM t WHERE n IN (NULL)", false},
+		{"empty string matching none", "SELECT * FROM t WHERE id IN ({{ids}})", matchNone(ids), "", "SELECT * FROM t WHERE id IN (NULL)", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, missing := replaceParametersWithDefs(tt.query, map[string]interface{}{tt.def.Name: tt.value},
+				[]models.ParameterDefinition{tt.def}, false)
+			if tt.wantMissing {
+				if len(missing) != 1 || missing[0] != tt.def.Name {
+					t.Fatalf("replaceParametersWithDefs() missing = %v, want [%s]", missing, tt.def.Name)
+				}
+				return
+			}
+			if len(missing) != 0 {
+				t.Fatalf("replaceParametersWithDefs() missing = %v, want none", missing)
+			}
+			if got != tt.want {
+				t.Fatalf("replaceParametersWithDefs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParameterTermFilter(t *testing.T) {
+	defs := []models.ParameterDefinition{
+		{Name: "table", SqlFormat: models.SqlFormatIdentifier, AllowQualified: true},
+		{Name: "token", SqlFormat: models.SqlFormatRaw},
+		{Name: "region", SqlFormat: models.SqlFormatString},
+	}
+	filter := newParameterTermFilter([]string{"SLEEP", "information_schema", "pg_"}, []string{"pg_region"})
+
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		wantErr bool
+	}{
+		{"plain identifier", map[string]interface{}{"table": "sales.orders"}, false},
+		{"denied schema", map[string]interface{}{"table": "information_schema.tables"}, true},
+		{"denied prefix", map[string]interface{}{"token": "pg_sleep"}, true},
+		{"case-insensitive", map[string]interface{}{"token": "Sleep"}, true},
+		{"allowed word with a denied prefix", map[string]interface{}{"token": "pg_region"}, false},
+		{"denied term inside a word", map[string]interface{}{"token": "upg_x"}, false},
+		{"list item", map[string]interface{}{"token": []interface{}{"emea", "pg_catalog"}}, true},
+		{"parameter without a definition is raw", map[string]interface{}{"other": "pg_user"}, true},
+		{"quoted formats are not checked", map[string]interface{}{"region": "pg_sleep"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := filter.check(tt.params, defs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("check(%v) error = %v, wantErr %v", tt.params, err, tt.wantErr)
+			}
+			var validationErr *models.ValidationError
+			if err != nil && (!errors.As(err, &validationErr) || !strings.HasPrefix(validationErr.Field, "parameters.")) {
+				t.Fatalf("check() error = %v, want a validation error on the parameter", err)
+			}
+		})
+	}
+
+	if err := (parameterTermFilter{}).check(map[string]interface{}{"token": "pg_sleep"}, defs); err != nil {
+		t.Fatalf("check() without denied terms = %v, want nil", err)
+	}
+}
+
+func TestRenderDashboard_RejectsDeniedTermsForViewers(t *testing.T) {
+	f := setupRenderTest()
+	f.handler.SetParameterTerms([]string{"pg_"}, nil)
+
+	code, body := renderDashboard(f.handler, f.dashboardID.String(), models.WidgetDataRequest{
+		Parameters: map[string]interface{}{"region": "pg_sleep"},
+	})
+	if code != http.StatusBadRequest || !strings.Contains(string(body), `"field":"parameters.region"`) {
+		t.Fatalf("RenderDashboard() by a viewer = %d %s, want 400 on parameters.region", code, body)
+	}
+
+	code, body = renderDashboard(f.handler, f.dashboardID.String(), models.WidgetDataRequest{
+		Parameters: map[string]interface{}{"region": "emea"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("RenderDashboard() with an allowed value = %d %s, want 200", code, body)
+	}
+
+	// Editors may insert raw values, so the denylist does not apply to them
+	f.viewer.levels[f.dashboardID] = models.PermissionEdit
+	code, body = renderDashboard(f.handler, f.dashboardID.String(), models.WidgetDataRequest{
+		Parameters: map[string]interface{}{"region": "pg_sleep"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("RenderDashboard() by an editor = %d %s, want 200", code, body)
+	}
+}
+
+func TestRenderDashboard_InlineWidgetQuery(t *testing.T) {
+	f := setupRenderTest()
+	addInline := func(queryText string, queryID *uuid.UUID) uuid.UUID {
+		catalog, schema := "hive", "sales"
+		w := &models.Widget{ID: uuid.New(), DashboardID: f.dashboardID, Name: queryText, ChartType: "table",
+			QueryID: queryID, QueryText: &queryText, Catalog: &catalog, Schema: &schema}
+		f.viewer.widgets[w.ID] = w
+		return w.ID
+	}
+	// The inline SQL is preferred over the (denied) saved query the widget still references
+	inline := addInline("SELECT * FROM orders WHERE region = '{{region}}'", f.viewer.widgets[f.denied].QueryID)
+	denied := addInline("SELECT * FROM postgres.public.users", nil)
+
+	var executed []string
+	trino := f.handler.trinoService.(*repository.MockTrinoExecutor)
+	trino.ExecuteQueryFunc = func(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error) {
+		executed = append(executed, catalog+"."+schema+": "+query)
+		return &models.QueryResult{Columns: []string{"region"}, Rows: [][]interface{}{{"emea"}}, RowCount: 1}, nil
+	}
+
+	code, body := renderDashboard(f.handler, f.dashboardID.String(), models.WidgetDataRequest{
+		Parameters: map[string]interface{}{"region": "emea"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("RenderDashboard() status = %d, want %d: %s", code, http.StatusOK, body)
+	}
+
+	var got models.DashboardRenderResponse
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	byID := map[uuid.UUID]models.WidgetDataResponse{}
+	for _, w := range got.Widgets {
+		byID[w.WidgetID] = w
+	}
+
+	if w := byID[inline]; w.Error != "" || w.QueryResult == nil || w.QueryResult.RowCount != 1 {
+		t.Errorf("inline widget = %+v, want one row and no error", w)
+	}
+	want := "hive.sales: SELECT * FROM orders WHERE region = 'emea'"
+	found := false
+	for _, q := range executed {
+		found = found || q == want
+	}
+	if !found {
+		t.Errorf("executed = %q, want %q", executed, want)
+	}
+	if w := byID[denied]; w.QueryResult != nil || w.Error != "access denied to catalog: postgres" {
+		t.Errorf("denied inline widget = %+v, want the owner's catalog to be enforced", w)
+	}
+}
+
+func TestRenderDashboard_InlineWidgetQueryChecksEditor(t *testing.T) {
+	f := setupRenderTest()
+	ownerID := f.viewer.owners[f.dashboardID]
+	f.roles.AllowedCatalogs[ownerID] = []string{"hive", "postgres"}
+	editor := uuid.New()
+	f.roles.AllowedCatalogs[editor] = []string{"hive"}
+
+	addInline := func(queryText string, updatedBy uuid.UUID) uuid.UUID {
+		w := &models.Widget{ID: uuid.New(), DashboardID: f.dashboardID, Name: queryText, ChartType: "table",
+			QueryText: &queryText, UpdatedBy: &updatedBy}
+		f.viewer.widgets[w.ID] = w
+		return w.ID
+	}
+	byOwner := addInline("SELECT * FROM postgres.public.users", ownerID)
+	byEditor := addInline("SELECT * FROM postgres.public.users", editor)
+	editorHive := addInline("SELECT * FROM hive.sales.orders", editor)
+
+	code, body := renderDashboard(f.handler, f.dashboardID.String(), models.WidgetDataRequest{
+		Parameters: map[string]interface{}{"region": "emea"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("RenderDashboard() status = %d, want %d: %s", code, http.StatusOK, body)
+	}
+	var got models.DashboardRenderResponse
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	byID := map[uuid.UUID]models.WidgetDataResponse{}
+	for _, w := range got.Widgets {
+		byID[w.WidgetID] = w
+	}
+
+	if w := byID[byOwner]; w.Error != "" || w.QueryResult == nil {
+		t.Errorf("owner's inline widget = %+v, want data", w)
+	}
+	// The owner may read postgres, but the editor who wrote the SQL may not
+	if w := byID[byEditor]; w.QueryResult != nil || w.Error != "access denied to catalog: postgres" {
+		t.Errorf("editor's inline widget = %+v, want the editor's catalogs to be enforced", w)
+	}
+	if w := byID[editorHive]; w.Error != "" || w.QueryResult == nil {
+		t.Errorf("editor's hive widget = %+v, want data", w)
+	}
+}
+
+func TestRenderDashboard_ReadOnlyModeAppliesAtExecution(t *testing.T) {
+	f := setupRenderTest()
+	readOnly := services.NewQueryService(nil)
+	readOnly.SetReadOnlyMode(true)
+	f.handler.statements = readOnly
+	// Saved before read-only mode was enabled
+	writeQuery := f.viewer.widgets[f.failing].QueryID
+	f.handler.savedQueries.(fakeSavedQueries)[*writeQuery].QueryText = "DELETE FROM hive.sales.orders"
+
+	var executed []string
+	trino := f.handler.trinoService.(*repository.MockTrinoExecutor)
+	trino.ExecuteQueryFunc = func(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error) {
+		executed = append(executed, query)
+		return &models.QueryResult{Columns: []string{"region"}, Rows: [][]interface{}{{"emea"}}, RowCount: 1}, nil
+	}
+
+	code, body := renderDashboard(f.handler, f.dashboardID.String(), models.WidgetDataRequest{
+		Parameters: map[string]interface{}{"region": "emea"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("RenderDashboard() status = %d, want %d: %s", code, http.StatusOK, body)
+	}
+
+	var got models.DashboardRenderResponse
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	for _, w := range got.Widgets {
+		if w.WidgetID == f.failing && (w.QueryResult != nil || !strings.Contains(w.Error, "read-only")) {
+			t.Errorf("write widget = %+v, want it rejected by read-only mode", w)
+		}
+	}
+	for _, q := range executed {
+		if strings.HasPrefix(q, "DELETE") {
+			t.Errorf("executed %q in read-only mode", q)
+		}
+	}
+}
@@ -15,23 +15,26 @@ import (
 )
 
 type ExportHandler struct {
-	trinoExecutor  repository.TrinoExecutor
-	roleService    *services.RoleService
-	defaultCatalog string
-	defaultSchema  string
+	trinoExecutor   repository.TrinoExecutor
+	historyRecorder repository.QueryHistoryRecorder
+	roleService     *services.RoleService
+	defaultCatalog  string
+	defaultSchema   string
 }
 
 func NewExportHandler(
 	trinoExecutor repository.TrinoExecutor,
+	historyRecorder repository.QueryHistoryRecorder,
 	roleService *services.RoleService,
 	defaultCatalog string,
 	defaultSchema string,
 ) *ExportHandler {
 	return &ExportHandler{
-		trinoExecutor:  trinoExecutor,
-		roleService:    roleService,
-		defaultCatalog: defaultCatalog,
-		defaultSchema:  defaultSchema,
+		trinoExecutor:   trinoExecutor,
+		historyRecorder: historyRecorder,
+		roleService:     roleService,
+		defaultCatalog:  defaultCatalog,
+		defaultSchema:   defaultSchema,
 	}
 }
 
@@ -68,7 +71,7 @@ func (h *ExportHandler) export(c *gin.Context, format string) {
 		schema = h.defaultSchema
 	}
 
-	if err := enforceCatalogAccess(c.Request.Context(), h.roleService, userID, req.Query, catalog); err != nil {
+	if err := enforceCatalogAccess(c.Request.Context(), h.roleService, userID, req.Query, catalog, schema); err != nil {
 		if errors.Is(err, ErrCatalogAccessDenied) || errors.Is(err, ErrShowCatalogsForbidden) {
 			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 			return
@@ -77,8 +80,18 @@ func (h *ExportHandler) export(c *gin.Context, format string) {
 		return
 	}
 
-	result, err := h.trinoExecutor.ExecuteQuery(c.Request.Context(), req.Query, catalog, schema)
+	filtered, err := applyRowFilters(c.Request.Context(), h.roleService, userID, req.Query, catalog, schema)
 	if err != nil {
+		respondRowFilterError(c, err)
+		return
+	}
+
+	result, err := h.trinoExecutor.ExecuteQuery(c.Request.Context(), filtered, catalog, schema)
+	services.RecordQueryExecution(c.Request.Context(), h.historyRecorder, userID, req.Query, models.QueryHistorySourceExport, nil, result, err)
+	if err != nil {
+		if respondQueryTimeout(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
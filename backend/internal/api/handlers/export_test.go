@@ -17,7 +17,7 @@ import (
 
 func setupExportHandlerTest() (*ExportHandler, *repository.MockTrinoExecutor) {
 	mockTrino := repository.NewMockTrinoExecutor()
-	handler := NewExportHandler(mockTrino, nil, "memory", "default")
+	handler := NewExportHandler(mockTrino, nil, nil, "memory", "default")
 	return handler, mockTrino
 }
 
@@ -228,3 +228,29 @@ func TestExportCSV_SanitizedFilename(t *testing.T) {
 		t.Fatalf("Filename should contain sanitized characters, got: %s", disposition)
 	}
 }
+
+func TestExportCSV_RecordsHistory(t *testing.T) {
+	mockTrino := repository.NewMockTrinoExecutor()
+	recorder := repository.NewMockQueryHistoryRecorder()
+	handler := NewExportHandler(mockTrino, recorder, nil, "memory", "default")
+
+	mockTrino.SetQueryResult("SELECT 1", &models.QueryResult{
+		Columns:  []string{"_col0"},
+		Rows:     [][]interface{}{{1}},
+		RowCount: 1,
+	})
+
+	c, w := createTestContext("POST", "/api/export/csv", ExportRequest{Query: "SELECT 1"})
+	handler.ExportCSV(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("ExportCSV() status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if len(recorder.SavedHistories) != 1 {
+		t.Fatalf("SavedHistories = %d, want 1", len(recorder.SavedHistories))
+	}
+	got := recorder.SavedHistories[0]
+	if got.Source != models.QueryHistorySourceExport || got.Status != "success" || got.UserID != c.MustGet("userID").(uuid.UUID) {
+		t.Fatalf("SavedHistories[0] = %+v, want a successful export entry for the requesting user", got)
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mitsume/backend/internal/models"
+)
+
+// trinoPoolStats is the part of TrinoService that reports connection pool usage
+type trinoPoolStats interface {
+	Stats() map[string]sql.DBStats
+}
+
+// HealthHandler serves the detailed health report for operators
+type HealthHandler struct {
+	pool  *pgxpool.Pool // nil omits database stats
+	trino trinoPoolStats
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(pool *pgxpool.Pool, trino trinoPoolStats) *HealthHandler {
+	return &HealthHandler{pool: pool, trino: trino}
+}
+
+// GetDetailedHealth returns PostgreSQL and Trino connection pool statistics (admin only).
+// GET /health remains the unauthenticated liveness probe.
+// GET /health/detailed
+func (h *HealthHandler) GetDetailedHealth(c *gin.Context) {
+	resp := models.DetailedHealth{Status: "ok", Trino: []models.TrinoPoolStats{}}
+
+	if h.pool != nil {
+		stat := h.pool.Stat()
+		resp.Database = &models.DatabasePoolStats{
+			TotalConns:           stat.TotalConns(),
+			IdleConns:            stat.IdleConns(),
+			AcquiredConns:        stat.AcquiredConns(),
+			ConstructingConns:    stat.ConstructingConns(),
+			MaxConns:             stat.MaxConns(),
+			AcquireCount:         stat.AcquireCount(),
+			EmptyAcquireCount:    stat.EmptyAcquireCount(),
+			CanceledAcquireCount: stat.CanceledAcquireCount(),
+			AcquireDurationMs:    stat.AcquireDuration().Milliseconds(),
+		}
+	}
+
+	for dsn, stats := range h.trino.Stats() {
+		resp.Trino = append(resp.Trino, models.TrinoPoolStats{
+			DSN:                dsn,
+			OpenConnections:    stats.OpenConnections,
+			InUse:              stats.InUse,
+			Idle:               stats.Idle,
+			MaxOpenConnections: stats.MaxOpenConnections,
+			WaitCount:          stats.WaitCount,
+			WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+		})
+	}
+	sort.Slice(resp.Trino, func(i, j int) bool { return resp.Trino[i].DSN < resp.Trino[j].DSN })
+
+	c.JSON(http.StatusOK, resp)
+}
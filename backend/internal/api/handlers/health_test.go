@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/mitsume/backend/internal/models"
+)
+
+type fakeTrinoPoolStats map[string]sql.DBStats
+
+func (f fakeTrinoPoolStats) Stats() map[string]sql.DBStats { return f }
+
+func TestGetDetailedHealth_ReportsTrinoPools(t *testing.T) {
+	handler := NewHealthHandler(nil, fakeTrinoPoolStats{
+		"http://***@***:8080?catalog=memory&schema=default": {OpenConnections: 3, InUse: 1, Idle: 2, MaxOpenConnections: 10},
+		"http://***@***:8080?catalog=hive&schema=sales":     {OpenConnections: 1, Idle: 1, MaxOpenConnections: 10},
+	})
+
+	c, w := createTestContext("GET", "/health/detailed", nil)
+	handler.GetDetailedHealth(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("GetDetailedHealth() status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var resp models.DetailedHealth
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Status != "ok" || resp.Database != nil || len(resp.Trino) != 2 {
+		t.Fatalf("GetDetailedHealth() = %+v, want two Trino pools and no database stats", resp)
+	}
+	if first := resp.Trino[0]; first.DSN != "http://***@***:8080?catalog=hive&schema=sales" || first.Idle != 1 {
+		t.Fatalf("Trino[0] = %+v, want the hive pool first (sorted by DSN)", first)
+	}
+	if second := resp.Trino[1]; second.InUse != 1 || second.OpenConnections != 3 {
+		t.Fatalf("Trino[1] = %+v, want the memory pool stats", second)
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/services"
+)
+
+// MaintenanceHandler lets admins pause and resume query execution
+type MaintenanceHandler struct {
+	maintenanceService *services.MaintenanceService
+	auditService       *services.AuditService
+}
+
+// NewMaintenanceHandler creates a new maintenance handler
+func NewMaintenanceHandler(maintenanceService *services.MaintenanceService, auditService *services.AuditService) *MaintenanceHandler {
+	return &MaintenanceHandler{maintenanceService: maintenanceService, auditService: auditService}
+}
+
+// GetMaintenance returns the current maintenance status
+// GET /admin/maintenance
+func (h *MaintenanceHandler) GetMaintenance(c *gin.Context) {
+	status, err := h.maintenanceService.Status(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// UpdateMaintenance turns maintenance mode on or off
+// PUT /admin/maintenance {"enabled": true, "message": "..."}
+func (h *MaintenanceHandler) UpdateMaintenance(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	var req models.UpdateMaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, err := h.maintenanceService.SetStatus(c.Request.Context(), userID, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), userID, models.AuditActionSetMaintenance, models.AuditTargetSystem, uuid.Nil,
+		map[string]interface{}{"enabled": status.Enabled, "message": status.Message})
+
+	c.JSON(http.StatusOK, status)
+}
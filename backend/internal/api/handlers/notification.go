@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -21,7 +23,8 @@ func NewNotificationHandler(notificationService *services.NotificationService) *
 	}
 }
 
-// GetChannels returns all notification channels for the authenticated user
+// GetChannels returns all notification channels for the authenticated user.
+// ?unused=true limits the list to channels no alert, subscription or dashboard uses.
 func (h *NotificationHandler) GetChannels(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -29,17 +32,25 @@ func (h *NotificationHandler) GetChannels(c *gin.Context) {
 		return
 	}
 
-	channels, err := h.notificationService.GetChannels(c.Request.Context(), userID.(uuid.UUID))
+	var channels []models.NotificationChannel
+	var err error
+	if c.Query("unused") == "true" {
+		channels, err = h.notificationService.GetUnusedChannels(c.Request.Context(), userID.(uuid.UUID))
+	} else {
+		channels, err = h.notificationService.GetChannels(c.Request.Context(), userID.(uuid.UUID))
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if channels == nil {
-		channels = []models.NotificationChannel{}
+	// Responses never include channel secrets, not even encrypted
+	redacted := make([]models.NotificationChannel, len(channels))
+	for i, ch := range channels {
+		redacted[i] = ch.Redacted()
 	}
 
-	c.JSON(http.StatusOK, channels)
+	c.JSON(http.StatusOK, redacted)
 }
 
 // GetChannel returns a specific notification channel
@@ -67,7 +78,7 @@ func (h *NotificationHandler) GetChannel(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, channel)
+	c.JSON(http.StatusOK, channel.Redacted())
 }
 
 // CreateChannel creates a new notification channel
@@ -90,7 +101,7 @@ func (h *NotificationHandler) CreateChannel(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, channel)
+	c.JSON(http.StatusCreated, channel.Redacted())
 }
 
 // UpdateChannel updates a notification channel
@@ -119,7 +130,7 @@ func (h *NotificationHandler) UpdateChannel(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, channel)
+	c.JSON(http.StatusOK, channel.Redacted())
 }
 
 // DeleteChannel deletes a notification channel
@@ -165,3 +176,60 @@ func (h *NotificationHandler) TestChannel(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Test notification sent successfully"})
 }
+
+// GetNotifications returns the authenticated user's in-app notifications, newest first.
+// ?unread=true limits the list to unread notifications.
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Get limit from query params, default to 50
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	unreadOnly := c.Query("unread") == "true"
+
+	notifications, err := h.notificationService.GetInAppNotifications(c.Request.Context(), userID.(uuid.UUID), unreadOnly, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if notifications == nil {
+		notifications = []models.InAppNotification{}
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
+
+// MarkNotificationRead marks one of the authenticated user's in-app notifications as read
+func (h *NotificationHandler) MarkNotificationRead(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	notificationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+
+	if err := h.notificationService.MarkInAppNotificationRead(c.Request.Context(), notificationID, userID.(uuid.UUID)); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
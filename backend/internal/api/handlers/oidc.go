@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/config"
+	"github.com/mitsume/backend/internal/services"
+	"golang.org/x/oauth2"
+)
+
+// oidcProvider signs users in through a generic OpenID Connect provider. Its endpoints are
+// discovered from the issuer on first use; a failed discovery is retried on the next login.
+type oidcProvider struct {
+	cfg    config.OIDCConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+}
+
+// oidcDiscovery is the part of the provider's /.well-known/openid-configuration we use
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+type oidcUserInfo struct {
+	Subject           string `json:"sub"`
+	Email             string `json:"email"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// newOIDCProvider returns nil unless the issuer and client credentials are configured
+func newOIDCProvider(cfg config.OIDCConfig) *oidcProvider {
+	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.ClientSecret == "" {
+		return nil
+	}
+	return &oidcProvider{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (p *oidcProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("openid configuration returned status %d", resp.StatusCode)
+	}
+
+	var discovery oidcDiscovery
+	if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil {
+		return nil, err
+	}
+	if discovery.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("openid configuration issuer %q does not match %q", discovery.Issuer, p.cfg.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.UserinfoEndpoint == "" {
+		return nil, errors.New("openid configuration lacks the authorization, token or userinfo endpoint")
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+func (p *oidcProvider) oauthConfig(discovery *oidcDiscovery) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Scopes:       []string{"openid", "profile", "email"},
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  discovery.AuthorizationEndpoint,
+			TokenURL: discovery.TokenEndpoint,
+		},
+	}
+}
+
+func (p *oidcProvider) authCodeURL(ctx context.Context, state string) (string, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return p.oauthConfig(discovery).AuthCodeURL(state), nil
+}
+
+// userInfo exchanges the authorization code and fetches the signed-in user's claims
+func (p *oidcProvider) userInfo(ctx context.Context, code string) (*oidcUserInfo, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	oauthConfig := p.oauthConfig(discovery)
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.client)
+	token, err := oauthConfig.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange token: %w", err)
+	}
+
+	resp, err := oauthConfig.Client(ctx, token).Get(discovery.UserinfoEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("userinfo returned status %d", resp.StatusCode)
+	}
+
+	var info oidcUserInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+	if info.Subject == "" {
+		return nil, errors.New("userinfo lacks the sub claim")
+	}
+	if info.Name == "" {
+		info.Name = info.PreferredUsername
+	}
+	if info.Name == "" {
+		info.Name = info.Email
+	}
+	return &info, nil
+}
+
+// OIDCLogin returns the provider's authorization URL
+// GET /auth/oidc
+func (h *AuthHandler) OIDCLogin(c *gin.Context) {
+	if h.oidc == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "OIDC not configured"})
+		return
+	}
+
+	state := uuid.New().String()
+	authURL, err := h.oidc.authCodeURL(c.Request.Context(), state)
+	if err != nil {
+		log.Printf("OIDC discovery failed: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "OIDC provider unavailable"})
+		return
+	}
+
+	// Save state in a short-lived cookie to validate on callback
+	c.SetCookie("oidc_state", state, 300, "/", "", false, true)
+
+	c.JSON(http.StatusOK, gin.H{"url": authURL})
+}
+
+// OIDCCallback signs the user in and redirects to the frontend with a token. Users awaiting
+// admin approval (or disabled) are redirected to the login page with error=account_not_active.
+// GET /auth/oidc/callback
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	if h.oidc == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "OIDC not configured"})
+		return
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "authorization code required"})
+		return
+	}
+
+	state := c.Query("state")
+	cookieState, err := c.Cookie("oidc_state")
+	if err != nil || state == "" || cookieState != state {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid oauth state"})
+		return
+	}
+	// Invalidate state cookie
+	c.SetCookie("oidc_state", "", -1, "/", "", false, true)
+
+	info, err := h.oidc.userInfo(c.Request.Context(), code)
+	if err != nil {
+		log.Printf("OIDC sign-in failed: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to get user info"})
+		return
+	}
+	if info.Email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "OIDC provider did not return an email address"})
+		return
+	}
+
+	authResp, err := h.authService.FindOrCreateOIDCUser(c.Request.Context(), info.Subject, info.Email, info.Name)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotActive) {
+			c.Redirect(http.StatusTemporaryRedirect, h.cfg.Server.FrontendURL+"/login?error=account_not_active")
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create or find user"})
+		return
+	}
+
+	c.Redirect(http.StatusTemporaryRedirect, h.cfg.Server.FrontendURL+"/auth/callback?token="+url.QueryEscape(authResp.Token))
+}
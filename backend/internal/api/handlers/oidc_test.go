@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/mitsume/backend/internal/config"
+	"github.com/mitsume/backend/internal/repository"
+	"github.com/mitsume/backend/internal/services"
+)
+
+// newTestOIDCIssuer serves discovery, token and userinfo endpoints for the given claims
+func newTestOIDCIssuer(t *testing.T, claims map[string]string) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"userinfo_endpoint":      server.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "good-code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "access", "token_type": "Bearer"})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(claims)
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func setupOIDCHandlerTest(t *testing.T, issuer string, userCount int) (*AuthHandler, *repository.MockUserRepository) {
+	t.Helper()
+	cfg := &config.Config{
+		JWT:    config.JWTConfig{Secret: "test-secret", ExpireHour: 1},
+		Server: config.ServerConfig{FrontendURL: "http://frontend"},
+		OIDC: config.OIDCConfig{
+			Issuer:       issuer,
+			ClientID:     "mitsume",
+			ClientSecret: "secret",
+			RedirectURL:  "http://localhost:8080/api/auth/oidc/callback",
+		},
+	}
+	userRepo := repository.NewMockUserRepository()
+	roleRepo := repository.NewMockRoleRepository()
+	roleRepo.Create(context.Background(), "admin", "")
+	roleRepo.UserCount = userCount
+	return NewAuthHandler(services.NewAuthService(cfg, userRepo, roleRepo, nil), cfg, nil), userRepo
+}
+
+func oidcCallback(handler *AuthHandler, code string) *httptest.ResponseRecorder {
+	c, w := createTestContext("GET", "/api/auth/oidc/callback?code="+code+"&state=s1", nil)
+	c.Request.AddCookie(&http.Cookie{Name: "oidc_state", Value: "s1"})
+	handler.OIDCCallback(c)
+	return w
+}
+
+func TestOIDCLogin_ReturnsDiscoveredAuthorizationURL(t *testing.T) {
+	issuer := newTestOIDCIssuer(t, nil)
+	handler, _ := setupOIDCHandlerTest(t, issuer.URL, 1)
+
+	c, w := createTestContext("GET", "/api/auth/oidc", nil)
+	handler.OIDCLogin(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var body struct {
+		URL string `json:"url"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	authURL, err := url.Parse(body.URL)
+	if err != nil || !strings.HasPrefix(body.URL, issuer.URL+"/authorize?") {
+		t.Fatalf("url = %q, want the discovered authorization endpoint", body.URL)
+	}
+	if authURL.Query().Get("client_id") != "mitsume" || authURL.Query().Get("state") == "" {
+		t.Fatalf("url = %q, want client_id and state", body.URL)
+	}
+	if !strings.Contains(w.Header().Get("Set-Cookie"), "oidc_state="+authURL.Query().Get("state")) {
+		t.Fatalf("state cookie not set: %q", w.Header().Get("Set-Cookie"))
+	}
+}
+
+func TestOIDCCallback_FirstUserSignsIn(t *testing.T) {
+	issuer := newTestOIDCIssuer(t, map[string]string{"sub": "sub-1", "email": "first@example.com", "name": "First"})
+	handler, userRepo := setupOIDCHandlerTest(t, issuer.URL, 1)
+
+	w := oidcCallback(handler, "good-code")
+
+	if w.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusTemporaryRedirect, w.Body.String())
+	}
+	if location := w.Header().Get("Location"); !strings.HasPrefix(location, "http://frontend/auth/callback?token=") {
+		t.Fatalf("Location = %q, want the frontend callback with a token", location)
+	}
+	if user := userRepo.UsersByOIDC["sub-1"]; user == nil || user.Name != "First" {
+		t.Fatalf("user = %+v, want a user created for the subject", user)
+	}
+}
+
+func TestOIDCCallback_NewUserAwaitsApproval(t *testing.T) {
+	issuer := newTestOIDCIssuer(t, map[string]string{"sub": "sub-2", "email": "second@example.com"})
+	handler, _ := setupOIDCHandlerTest(t, issuer.URL, 2)
+
+	w := oidcCallback(handler, "good-code")
+
+	if location := w.Header().Get("Location"); location != "http://frontend/login?error=account_not_active" {
+		t.Fatalf("Location = %q, want the login page with account_not_active", location)
+	}
+}
+
+func TestOIDCCallback_RejectsBadCode(t *testing.T) {
+	issuer := newTestOIDCIssuer(t, map[string]string{"sub": "sub-1", "email": "first@example.com"})
+	handler, userRepo := setupOIDCHandlerTest(t, issuer.URL, 1)
+
+	w := oidcCallback(handler, "bad-code")
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if len(userRepo.UsersByOIDC) != 0 {
+		t.Fatal("no user should be created")
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mitsume/backend/internal/models"
+)
+
+// parameterBounds are the optional limits of a number or date parameter value
+type parameterBounds struct {
+	Min, Max         *float64
+	MinDate, MaxDate string // YYYY-MM-DD
+}
+
+// boundsOf returns the limits declared by a parameter definition; nil has none
+func boundsOf(def *models.ParameterDefinition) parameterBounds {
+	if def == nil {
+		return parameterBounds{}
+	}
+	return parameterBounds{Min: def.Min, Max: def.Max, MinDate: def.MinDate, MaxDate: def.MaxDate}
+}
+
+// checkNumber fails when a valid number lies outside Min/Max
+func (b parameterBounds) checkNumber(s string) error {
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return nil // not a number; reported by the format check
+	}
+	if b.Min != nil && n < *b.Min {
+		return fmt.Errorf("%s is less than the minimum %v", s, *b.Min)
+	}
+	if b.Max != nil && n > *b.Max {
+		return fmt.Errorf("%s is greater than the maximum %v", s, *b.Max)
+	}
+	return nil
+}
+
+// checkDate fails when a valid YYYY-MM-DD date lies outside MinDate/MaxDate. Such dates
+// compare correctly as strings.
+func (b parameterBounds) checkDate(s string) error {
+	s = strings.TrimSpace(s)
+	if !isValidDate(s) {
+		return nil // reported by the format check
+	}
+	if b.MinDate != "" && s < b.MinDate {
+		return fmt.Errorf("%s is before the earliest date %s", s, b.MinDate)
+	}
+	if b.MaxDate != "" && s > b.MaxDate {
+		return fmt.Errorf("%s is after the latest date %s", s, b.MaxDate)
+	}
+	return nil
+}
+
+// validateParameterValues checks the values given for a dashboard's parameters against their
+// definitions: select and multiselect values must be among the static options, and number and
+// date values within the declared limits. The error is a *models.ValidationError whose field
+// names the parameter, as "parameters.<name>". Values of unknown or undefined format are left to
+// replaceParametersWithDefs, which reports them as missing.
+func validateParameterValues(params map[string]interface{}, defs []models.ParameterDefinition) error {
+	for i := range defs {
+		def := &defs[i]
+		value, ok := params[def.Name]
+		if !ok || isEmptyParameterValue(value) {
+			continue
+		}
+		if err := checkParameterValue(def, value); err != nil {
+			return &models.ValidationError{
+				Field:   "parameters." + def.Name,
+				Message: fmt.Sprintf("invalid value for parameter %s: %v", def.Name, err),
+			}
+		}
+	}
+	return nil
+}
+
+// checkParameterValue checks one parameter value for validateParameterValues
+func checkParameterValue(def *models.ParameterDefinition, value interface{}) error {
+	values := parameterValueStrings(value)
+	if s, ok := value.(string); ok && isListFormat(def.SqlFormat) {
+		// Lists may also be sent comma-separated, as formatParameterValue accepts
+		values = strings.Split(s, ",")
+		for i := range values {
+			values[i] = strings.TrimSpace(values[i])
+		}
+	}
+
+	// Options loaded from a query may change between listing and submitting, so only static
+	// options are enforced
+	if (def.Type == models.ParameterTypeSelect || def.Type == models.ParameterTypeMultiSelect) &&
+		def.OptionsQueryID == nil && len(def.Options) > 0 {
+		allowed := make(map[string]struct{}, len(def.Options))
+		for _, opt := range def.Options {
+			allowed[opt.Value] = struct{}{}
+		}
+		for _, v := range values {
+			if _, ok := allowed[v]; !ok {
+				return fmt.Errorf("%q is not one of the allowed options", v)
+			}
+		}
+	}
+
+	bounds := boundsOf(def)
+	if isDateRangeType(def.Type) {
+		start, end, err := parseDateRangeValue(value)
+		if err != nil {
+			return nil // reported as missing
+		}
+		values = []string{start, end}
+	}
+	for _, v := range values {
+		if err := bounds.checkNumber(v); err != nil {
+			return err
+		}
+		if err := bounds.checkDate(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parameterValueStrings returns the items of a list value, or the value itself, as strings
+func parameterValueStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, formatParameterItem(item))
+		}
+		return items
+	case []string:
+		return v
+	default:
+		return []string{formatParameterItem(v)}
+	}
+}
+
+// formatParameterItem formats a single JSON value as formatParameterValue does
+func formatParameterItem(item interface{}) string {
+	if f, ok := item.(float64); ok && f == float64(int64(f)) {
+		return strconv.FormatInt(int64(f), 10)
+	}
+	return fmt.Sprintf("%v", item)
+}
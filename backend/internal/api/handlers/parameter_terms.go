@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mitsume/backend/internal/models"
+)
+
+// parameterTermFilter rejects raw and identifier parameter values of viewers that contain denied
+// SQL keywords or function names, such as sleep, information_schema or pg_. A word of the value
+// is denied when it starts with a denied term, unless it is itself an allowed term. The zero
+// value allows everything.
+type parameterTermFilter struct {
+	denied  []string // lower case
+	allowed map[string]struct{}
+}
+
+func newParameterTermFilter(denied, allowed []string) parameterTermFilter {
+	f := parameterTermFilter{allowed: make(map[string]struct{}, len(allowed))}
+	for _, term := range denied {
+		if term = strings.ToLower(strings.TrimSpace(term)); term != "" {
+			f.denied = append(f.denied, term)
+		}
+	}
+	for _, term := range allowed {
+		f.allowed[strings.ToLower(strings.TrimSpace(term))] = struct{}{}
+	}
+	return f
+}
+
+// check fails with a *models.ValidationError on the first raw or identifier value containing a
+// denied term. Values of parameters without a definition are inserted raw, so they are checked
+// too.
+func (f parameterTermFilter) check(params map[string]interface{}, defs []models.ParameterDefinition) error {
+	if len(f.denied) == 0 {
+		return nil
+	}
+	for name, value := range params {
+		if def := getParameterDefinition(defs, name); def != nil &&
+			def.SqlFormat != "" && def.SqlFormat != models.SqlFormatRaw && def.SqlFormat != models.SqlFormatIdentifier {
+			continue // quoted or validated by formatParameterValue
+		}
+		if isEmptyParameterValue(value) {
+			continue
+		}
+		for _, s := range parameterValueStrings(value) {
+			if term := f.deniedTerm(s); term != "" {
+				return &models.ValidationError{
+					Field:   "parameters." + name,
+					Message: fmt.Sprintf("value of parameter %s contains the denied term %q", name, term),
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// deniedTerm returns the denied term a value contains, or "" when it contains none
+func (f parameterTermFilter) deniedTerm(value string) string {
+	words := strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return !(r == '_' || r == '$' || (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'))
+	})
+	for _, word := range words {
+		if _, ok := f.allowed[word]; ok {
+			continue
+		}
+		for _, term := range f.denied {
+			if strings.HasPrefix(word, term) {
+				return term
+			}
+		}
+	}
+	return ""
+}
@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"log"
 	"net/http"
+	"slices"
+	"strconv"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -18,6 +22,9 @@ type QueryHandler struct {
 	roleService     *services.RoleService
 	defaultCatalog  string
 	defaultSchema   string
+	readOnly        bool                  // reject statements that could modify data (see services.CheckReadOnlyStatement)
+	pager           *services.ResultPager // nil disables cursor pagination
+	timeouts        services.QueryTimeoutPolicy
 }
 
 func NewQueryHandler(
@@ -26,6 +33,7 @@ func NewQueryHandler(
 	roleService *services.RoleService,
 	defaultCatalog string,
 	defaultSchema string,
+	readOnly bool,
 ) *QueryHandler {
 	return &QueryHandler{
 		trinoExecutor:   trinoExecutor,
@@ -33,9 +41,35 @@ func NewQueryHandler(
 		roleService:     roleService,
 		defaultCatalog:  defaultCatalog,
 		defaultSchema:   defaultSchema,
+		readOnly:        readOnly,
 	}
 }
 
+// SetResultPager enables cursor pagination of query results
+func (h *QueryHandler) SetResultPager(pager *services.ResultPager) {
+	h.pager = pager
+}
+
+// SetQueryTimeouts sets the default and maximum timeouts of ad-hoc queries
+func (h *QueryHandler) SetQueryTimeouts(policy services.QueryTimeoutPolicy) {
+	h.timeouts = policy
+}
+
+// respondReadOnlyError responds 403 when err rejects a statement in read-only mode, and
+// reports whether it did
+func respondReadOnlyError(c *gin.Context, err error) bool {
+	if !errors.Is(err, services.ErrStatementNotReadOnly) && !errors.Is(err, services.ErrMultipleStatements) {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	return true
+}
+
+// ExecuteQuery runs an ad-hoc query from the editor with the requesting user's catalog and
+// schema permissions: every catalog the SQL references, and the session catalog, must be
+// granted by one of the user's roles (admins bypass the check). Denials and SHOW CATALOGS
+// are rejected with 403 before anything is sent to Trino.
+// POST /queries/execute
 func (h *QueryHandler) ExecuteQuery(c *gin.Context) {
 	userID := c.MustGet("userID").(uuid.UUID)
 
@@ -45,48 +79,244 @@ func (h *QueryHandler) ExecuteQuery(c *gin.Context) {
 		return
 	}
 
-	catalog := req.Catalog
+	catalog, schema, filtered, ok := h.authorizeQuery(c, userID, &req)
+	if !ok {
+		return
+	}
+	ctx, ok := h.queryContext(c, userID, &req)
+	if !ok {
+		return
+	}
+
+	result, err := h.executeAdHoc(ctx, userID, req.Query, filtered, catalog, schema)
+	if err != nil {
+		if respondQueryTimeout(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ExecuteQueryPaged runs a query and returns the first page of its result. The complete result
+// is held on the server; next_cursor fetches the following page from GET /queries/results
+// without re-running the query. Repeating the request while the result is held serves it again.
+// POST /queries/execute-paged
+func (h *QueryHandler) ExecuteQueryPaged(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	if h.pager == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "result pagination is not enabled"})
+		return
+	}
+
+	var req models.PagedQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	catalog, schema, filtered, ok := h.authorizeQuery(c, userID, &req.ExecuteQueryRequest)
+	if !ok {
+		return
+	}
+	ctx, ok := h.queryContext(c, userID, &req.ExecuteQueryRequest)
+	if !ok {
+		return
+	}
+
+	var queryErr error
+	page, err := h.pager.Open(c.Request.Context(), userID, req.Query, catalog, schema, req.PageSize, func() (*models.QueryResult, error) {
+		result, err := h.executeAdHoc(ctx, userID, req.Query, filtered, catalog, schema)
+		queryErr = err
+		return result, err
+	})
+	if err != nil {
+		if queryErr != nil {
+			if respondQueryTimeout(c, queryErr) {
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// GetResultPage returns the page of a held result that a cursor points to
+// GET /queries/results?cursor=
+func (h *QueryHandler) GetResultPage(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	if h.pager == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "result pagination is not enabled"})
+		return
+	}
+
+	page, err := h.pager.Next(c.Request.Context(), userID, c.Query("cursor"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidCursor):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrCursorExpired):
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// authorizeQuery resolves the request's catalog and schema, checks read-only mode and the
+// user's catalog permissions and returns the query with the user's row filters applied. It
+// responds and returns ok=false when the query is rejected.
+func (h *QueryHandler) authorizeQuery(c *gin.Context, userID uuid.UUID, req *models.ExecuteQueryRequest) (catalog, schema, filtered string, ok bool) {
+	catalog = req.Catalog
 	if catalog == "" {
 		catalog = h.defaultCatalog
 	}
-	schema := req.Schema
+	schema = req.Schema
 	if schema == "" {
 		schema = h.defaultSchema
 	}
 
+	if h.readOnly {
+		if err := services.CheckReadOnlyStatement(req.Query); err != nil {
+			respondReadOnlyError(c, err)
+			return "", "", "", false
+		}
+	}
+
 	// Enforce catalog permission based on referenced catalogs + effective catalog
-	if err := enforceCatalogAccess(c.Request.Context(), h.roleService, userID, req.Query, catalog); err != nil {
+	if err := enforceCatalogAccess(c.Request.Context(), h.roleService, userID, req.Query, catalog, schema); err != nil {
 		if errors.Is(err, ErrCatalogAccessDenied) || errors.Is(err, ErrShowCatalogsForbidden) {
 			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-			return
+			return "", "", "", false
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return "", "", "", false
+	}
+
+	filtered, err := applyRowFilters(c.Request.Context(), h.roleService, userID, req.Query, catalog, schema)
+	if err != nil {
+		respondRowFilterError(c, err)
+		return "", "", "", false
+	}
+
+	return catalog, schema, filtered, true
+}
+
+// queryContext returns the request context carrying the query's timeout: the requested one,
+// clamped to the cap of the user's roles. It responds and returns ok=false on failure.
+func (h *QueryHandler) queryContext(c *gin.Context, userID uuid.UUID, req *models.ExecuteQueryRequest) (context.Context, bool) {
+	timeout, err := resolveQueryTimeout(c.Request.Context(), h.roleService, h.timeouts, userID, req.TimeoutSeconds)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
 	}
+	return services.WithQueryTimeout(c.Request.Context(), timeout), true
+}
 
+// executeAdHoc runs filtered, the ad-hoc query with the user's row filters applied, and records
+// the query as written in the user's history
+func (h *QueryHandler) executeAdHoc(ctx context.Context, userID uuid.UUID, query, filtered, catalog, schema string) (*models.QueryResult, error) {
 	// Execute query with caching (LOW priority for ad-hoc queries)
-	result, err := h.trinoExecutor.ExecuteQueryWithCache(c.Request.Context(), req.Query, catalog, schema, int(services.CachePriorityLow), nil)
+	result, err := h.trinoExecutor.ExecuteQueryWithCache(ctx, filtered, catalog, schema, int(services.CachePriorityLow), nil)
 	if err != nil {
 		// Save error to history
 		errMsg := err.Error()
 		if h.historyRecorder != nil {
-			if recErr := h.historyRecorder.SaveQueryHistory(c.Request.Context(), userID, req.Query, "error", 0, 0, &errMsg); recErr != nil {
+			if recErr := h.historyRecorder.SaveQueryHistory(ctx, userID, query, "error", 0, 0, &errMsg, models.QueryHistorySourceQuery, nil); recErr != nil {
 				log.Printf("failed to record query error history for user %s: %v", userID, recErr)
 			}
 		}
-
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		return nil, err
 	}
 
 	// Save success to history
 	if h.historyRecorder != nil {
-		if recErr := h.historyRecorder.SaveQueryHistory(c.Request.Context(), userID, req.Query, "success", result.ExecutionTimeMs, result.RowCount, nil); recErr != nil {
+		if recErr := h.historyRecorder.SaveQueryHistory(ctx, userID, query, "success", result.ExecutionTimeMs, result.RowCount, nil, models.QueryHistorySourceQuery, nil); recErr != nil {
 			log.Printf("failed to record query success history for user %s: %v", userID, recErr)
 		}
 	}
 
-	c.JSON(http.StatusOK, result)
+	return result, nil
+}
+
+// validateBatchConcurrency bounds the EXPLAIN statements a batch validation runs at once
+const validateBatchConcurrency = 4
+
+// ValidateBatch validates queries with EXPLAIN (TYPE VALIDATE) without running them,
+// so an import can report every query that would fail before anything is saved.
+// POST /queries/validate-batch
+func (h *QueryHandler) ValidateBatch(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	var req models.ValidateBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	results := make([]models.QueryValidationResult, len(req.Queries))
+	sem := make(chan struct{}, validateBatchConcurrency)
+	var wg sync.WaitGroup
+
+	for i, q := range req.Queries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, q models.ExecuteQueryRequest) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results[i] = models.QueryValidationResult{Index: i, Valid: true}
+			if err := h.validateQuery(ctx, userID, q); err != nil {
+				msg := err.Error()
+				results[i].Valid = false
+				results[i].Error = &msg
+			}
+		}(i, q)
+	}
+	wg.Wait()
+
+	resp := models.ValidateBatchResponse{Results: results}
+	for _, r := range results {
+		if r.Valid {
+			resp.ValidCount++
+		} else {
+			resp.InvalidCount++
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *QueryHandler) validateQuery(ctx context.Context, userID uuid.UUID, q models.ExecuteQueryRequest) error {
+	catalog := q.Catalog
+	if catalog == "" {
+		catalog = h.defaultCatalog
+	}
+	schema := q.Schema
+	if schema == "" {
+		schema = h.defaultSchema
+	}
+
+	if err := enforceCatalogAccess(ctx, h.roleService, userID, q.Query, catalog, schema); err != nil {
+		return err
+	}
+	filtered, err := applyRowFilters(ctx, h.roleService, userID, q.Query, catalog, schema)
+	if err != nil {
+		return err
+	}
+	return services.ValidateQuery(ctx, h.trinoExecutor, filtered, catalog, schema)
 }
 
 func (h *QueryHandler) GetCatalogs(c *gin.Context) {
@@ -132,37 +362,33 @@ func filterCatalogs(available []string, allowed []string) []string {
 }
 
 func (h *QueryHandler) GetSchemas(c *gin.Context) {
-	userID := c.MustGet("userID").(uuid.UUID)
 	catalog := c.Param("catalog")
 	if catalog == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "catalog is required"})
 		return
 	}
 
-	// Check catalog access permission
+	schemas, err := h.trinoExecutor.GetSchemas(c.Request.Context(), catalog)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Filter schemas based on user's schema-level permissions
 	if h.roleService != nil {
-		hasAccess, err := h.roleService.CanUserAccessCatalog(c.Request.Context(), userID, catalog)
+		userID := c.MustGet("userID").(uuid.UUID)
+		allowedSchemas, err := h.roleService.GetUserAllowedSchemas(c.Request.Context(), userID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		if !hasAccess {
-			c.JSON(http.StatusForbidden, gin.H{"error": "access denied to catalog"})
-			return
-		}
-	}
-
-	schemas, err := h.trinoExecutor.GetSchemas(c.Request.Context(), catalog)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		schemas = services.FilterAllowedSchemas(allowedSchemas, catalog, schemas)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"schemas": schemas})
 }
 
 func (h *QueryHandler) GetTables(c *gin.Context) {
-	userID := c.MustGet("userID").(uuid.UUID)
 	catalog := c.Param("catalog")
 	schema := c.Param("schema")
 	if catalog == "" || schema == "" {
@@ -170,19 +396,6 @@ func (h *QueryHandler) GetTables(c *gin.Context) {
 		return
 	}
 
-	// Check catalog access permission
-	if h.roleService != nil {
-		hasAccess, err := h.roleService.CanUserAccessCatalog(c.Request.Context(), userID, catalog)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		if !hasAccess {
-			c.JSON(http.StatusForbidden, gin.H{"error": "access denied to catalog"})
-			return
-		}
-	}
-
 	tables, err := h.trinoExecutor.GetTables(c.Request.Context(), catalog, schema)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -193,7 +406,6 @@ func (h *QueryHandler) GetTables(c *gin.Context) {
 }
 
 func (h *QueryHandler) GetColumns(c *gin.Context) {
-	userID := c.MustGet("userID").(uuid.UUID)
 	catalog := c.Param("catalog")
 	schema := c.Param("schema")
 	table := c.Param("table")
@@ -202,26 +414,90 @@ func (h *QueryHandler) GetColumns(c *gin.Context) {
 		return
 	}
 
-	// Check catalog access permission
+	columns, err := h.trinoExecutor.GetColumns(c.Request.Context(), catalog, schema, table)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"columns": columns})
+}
+
+// Table sample limits
+const (
+	defaultTableSampleLimit = 10
+	maxTableSampleLimit     = 100
+)
+
+// GetTableSample returns the first rows of a table ("peek at the data")
+// GET /catalogs/:catalog/schemas/:schema/tables/:table/sample?limit=n
+func (h *QueryHandler) GetTableSample(c *gin.Context) {
+	catalog := c.Param("catalog")
+	schema := c.Param("schema")
+	table := c.Param("table")
+	if catalog == "" || schema == "" || table == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "catalog, schema, and table are required"})
+		return
+	}
+
+	limit := defaultTableSampleLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = parsed
+	}
+	if limit > maxTableSampleLimit {
+		limit = maxTableSampleLimit
+	}
+
+	// Samples are read directly from the table, so tables with row filters are only
+	// readable through the filtered queries of the editor
 	if h.roleService != nil {
-		hasAccess, err := h.roleService.CanUserAccessCatalog(c.Request.Context(), userID, catalog)
+		filters, err := h.roleService.GetUserRowFilters(c.Request.Context(), c.MustGet("userID").(uuid.UUID))
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		if !hasAccess {
-			c.JSON(http.StatusForbidden, gin.H{"error": "access denied to catalog"})
+		if services.HasRowFilter(filters, catalog, schema, table) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "table has row filters; query it from the editor instead"})
 			return
 		}
 	}
 
-	columns, err := h.trinoExecutor.GetColumns(c.Request.Context(), catalog, schema, table)
+	result, err := h.trinoExecutor.GetTableSample(c.Request.Context(), catalog, schema, table, limit)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"columns": columns})
+	c.JSON(http.StatusOK, result)
+}
+
+// GetTableDDL returns the CREATE TABLE (or CREATE VIEW) statement for a table
+// GET /catalogs/:catalog/schemas/:schema/tables/:table/ddl
+func (h *QueryHandler) GetTableDDL(c *gin.Context) {
+	catalog := c.Param("catalog")
+	schema := c.Param("schema")
+	table := c.Param("table")
+	if catalog == "" || schema == "" || table == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "catalog, schema, and table are required"})
+		return
+	}
+
+	ddl, err := services.GetTableDDL(c.Request.Context(), h.trinoExecutor, catalog, schema, table)
+	if err != nil {
+		if errors.Is(err, services.ErrDDLNotSupported) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, ddl)
 }
 
 func (h *QueryHandler) SearchMetadata(c *gin.Context) {
@@ -253,40 +529,98 @@ func (h *QueryHandler) SearchMetadata(c *gin.Context) {
 	}
 
 	// Get user's allowed catalogs
-	var allowedCatalogs []string
+	allowedCatalogs, err := h.searchableCatalogs(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.trinoExecutor.SearchMetadata(c.Request.Context(), req.Query, req.SearchType, allowedCatalogs, req.Limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	if h.roleService != nil {
-		catalogs, err := h.roleService.GetUserAllowedCatalogs(c.Request.Context(), userID)
+		allowedSchemas, err := h.roleService.GetUserAllowedSchemas(c.Request.Context(), userID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		results = services.FilterAllowedMetadata(allowedSchemas, results)
+	}
 
-		if catalogs == nil {
-			// Admin user - get all catalogs
-			allCatalogs, err := h.trinoExecutor.GetCatalogs(c.Request.Context())
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
-			}
-			allowedCatalogs = allCatalogs
-		} else {
-			allowedCatalogs = catalogs
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// searchableCatalogs returns the catalogs a user may search: the role's allowed catalogs, or
+// every catalog for admins (nil allowed catalogs) and when roles are not configured
+func (h *QueryHandler) searchableCatalogs(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	if h.roleService != nil {
+		catalogs, err := h.roleService.GetUserAllowedCatalogs(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if catalogs != nil {
+			return catalogs, nil
+		}
+	}
+	return h.trinoExecutor.GetCatalogs(ctx)
+}
+
+// AutocompleteMetadata suggests catalogs, schemas, tables and columns starting with q for the
+// editor, within the optional catalog and schema, limited to what the user may see
+// GET /metadata/autocomplete?q=prefix&catalog=...&schema=...&limit=n
+func (h *QueryHandler) AutocompleteMetadata(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := c.MustGet("userID").(uuid.UUID)
+	prefix := c.Query("q")
+	catalog := c.Query("catalog")
+	schema := c.Query("schema")
+
+	if schema != "" && catalog == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "schema requires catalog"})
+		return
+	}
+
+	limit := services.DefaultAutocompleteLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
 		}
-	} else {
-		// No role service - get all catalogs
-		allCatalogs, err := h.trinoExecutor.GetCatalogs(c.Request.Context())
+		limit = parsed
+	}
+
+	allowedCatalogs, err := h.searchableCatalogs(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if catalog != "" && !slices.Contains(allowedCatalogs, catalog) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied to catalog"})
+		return
+	}
+
+	var allowedSchemas []models.SchemaPermission
+	if h.roleService != nil {
+		allowedSchemas, err = h.roleService.GetUserAllowedSchemas(ctx, userID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		allowedCatalogs = allCatalogs
+		if schema != "" && allowedSchemas != nil && !services.SchemaAllowed(allowedSchemas, catalog, schema) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "access denied to schema"})
+			return
+		}
 	}
 
-	results, err := h.trinoExecutor.SearchMetadata(c.Request.Context(), req.Query, req.SearchType, allowedCatalogs, req.Limit)
+	suggestions, err := services.AutocompleteMetadata(ctx, h.trinoExecutor, prefix, catalog, schema, allowedCatalogs, allowedSchemas, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"results": results})
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
 }
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/services"
+)
+
+type QueryJobHandler struct {
+	jobService     *services.QueryJobService
+	roleService    *services.RoleService
+	defaultCatalog string
+	defaultSchema  string
+	readOnly       bool // reject statements that could modify data (see services.CheckReadOnlyStatement)
+	timeouts       services.QueryTimeoutPolicy
+}
+
+func NewQueryJobHandler(
+	jobService *services.QueryJobService,
+	roleService *services.RoleService,
+	defaultCatalog string,
+	defaultSchema string,
+	readOnly bool,
+) *QueryJobHandler {
+	return &QueryJobHandler{
+		jobService:     jobService,
+		roleService:    roleService,
+		defaultCatalog: defaultCatalog,
+		defaultSchema:  defaultSchema,
+		readOnly:       readOnly,
+	}
+}
+
+// SetQueryTimeouts sets the default and maximum timeouts of async queries
+func (h *QueryJobHandler) SetQueryTimeouts(policy services.QueryTimeoutPolicy) {
+	h.timeouts = policy
+}
+
+// ExecuteQueryAsync starts a query in the background and returns the job immediately.
+// If callback_url is set, it is POSTed a signed payload when the job completes; callback
+// URLs are rejected unless WEBHOOK_SECRET is set and the host passes the SSRF checks.
+// POST /queries/execute-async
+func (h *QueryJobHandler) ExecuteQueryAsync(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	var req models.ExecuteQueryAsyncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var callbackURL *string
+	if req.CallbackURL != "" {
+		if err := h.jobService.CheckCallbackURL(c.Request.Context(), req.CallbackURL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		callbackURL = &req.CallbackURL
+	}
+
+	catalog := req.Catalog
+	if catalog == "" {
+		catalog = h.defaultCatalog
+	}
+	schema := req.Schema
+	if schema == "" {
+		schema = h.defaultSchema
+	}
+
+	if h.readOnly {
+		if err := services.CheckReadOnlyStatement(req.Query); err != nil {
+			respondReadOnlyError(c, err)
+			return
+		}
+	}
+
+	if err := enforceCatalogAccess(c.Request.Context(), h.roleService, userID, req.Query, catalog, schema); err != nil {
+		if errors.Is(err, ErrCatalogAccessDenied) || errors.Is(err, ErrShowCatalogsForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filtered, err := applyRowFilters(c.Request.Context(), h.roleService, userID, req.Query, catalog, schema)
+	if err != nil {
+		respondRowFilterError(c, err)
+		return
+	}
+
+	timeout, err := resolveQueryTimeout(c.Request.Context(), h.roleService, h.timeouts, userID, req.TimeoutSeconds)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	job, err := h.jobService.Submit(userID, filtered, catalog, schema, callbackURL, timeout)
+	if err != nil {
+		if errors.Is(err, services.ErrTooManyQueryJobs) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetQueryJob returns the status (and result, once finished) of an async query job
+// GET /queries/jobs/:id
+func (h *QueryJobHandler) GetQueryJob(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid job id"})
+		return
+	}
+
+	job, err := h.jobService.GetJob(jobID, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "job not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/config"
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/repository"
+	"github.com/mitsume/backend/internal/services"
+)
+
+func setupQueryJobHandlerTest(callbacks *services.CallbackService) (*QueryJobHandler, *services.QueryJobService, *repository.MockTrinoExecutor) {
+	mockTrino := repository.NewMockTrinoExecutor()
+	jobService := services.NewQueryJobService(mockTrino, nil, callbacks)
+	return NewQueryJobHandler(jobService, nil, "memory", "default", false), jobService, mockTrino
+}
+
+func TestExecuteQueryAsync_Accepted(t *testing.T) {
+	handler, _, _ := setupQueryJobHandlerTest(nil)
+
+	c, w := createTestContext("POST", "/api/queries/execute-async", models.ExecuteQueryAsyncRequest{Query: "SELECT 1"})
+	handler.ExecuteQueryAsync(c)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusAccepted, w.Body.String())
+	}
+	var job models.QueryJob
+	if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil {
+		t.Fatalf("failed to decode job: %v", err)
+	}
+	if job.Catalog != "memory" || job.Schema != "default" {
+		t.Fatalf("job catalog/schema = %s/%s, want memory/default", job.Catalog, job.Schema)
+	}
+}
+
+func TestExecuteQueryAsync_RejectsCallbackURL(t *testing.T) {
+	cases := []struct {
+		name      string
+		callbacks *services.CallbackService
+		url       string
+	}{
+		{"no secret", services.NewCallbackService(&config.WebhookConfig{}), "https://93.184.216.34/cb"},
+		{"loopback", services.NewCallbackService(&config.WebhookConfig{Secret: "s"}), "http://127.0.0.1:9000/cb"},
+		{"metadata address", services.NewCallbackService(&config.WebhookConfig{Secret: "s"}), "http://169.254.169.254/latest/meta-data"},
+		{"not http", services.NewCallbackService(&config.WebhookConfig{Secret: "s"}), "ftp://93.184.216.34/cb"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, _, mockTrino := setupQueryJobHandlerTest(tc.callbacks)
+
+			c, w := createTestContext("POST", "/api/queries/execute-async", models.ExecuteQueryAsyncRequest{Query: "SELECT 1", CallbackURL: tc.url})
+			handler.ExecuteQueryAsync(c)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body.String())
+			}
+			if len(mockTrino.ExecuteQueryCalls) != 0 {
+				t.Fatalf("query ran despite rejected callback_url")
+			}
+		})
+	}
+}
+
+func TestExecuteQueryAsync_TooManyJobs(t *testing.T) {
+	handler, jobService, mockTrino := setupQueryJobHandlerTest(nil)
+	jobService.SetMaxJobsPerUser(1)
+	release := make(chan struct{})
+	defer close(release)
+	mockTrino.ExecuteQueryFunc = func(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error) {
+		<-release
+		return &models.QueryResult{}, nil
+	}
+	userID := uuid.New()
+
+	c, w := createTestContext("POST", "/api/queries/execute-async", models.ExecuteQueryAsyncRequest{Query: "SELECT 1"})
+	c.Set("userID", userID)
+	handler.ExecuteQueryAsync(c)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("first status = %d, want %d: %s", w.Code, http.StatusAccepted, w.Body.String())
+	}
+
+	c, w = createTestContext("POST", "/api/queries/execute-async", models.ExecuteQueryAsyncRequest{Query: "SELECT 2"})
+	c.Set("userID", userID)
+	handler.ExecuteQueryAsync(c)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second status = %d, want %d: %s", w.Code, http.StatusTooManyRequests, w.Body.String())
+	}
+}
+
+func TestGetQueryJob(t *testing.T) {
+	handler, jobService, _ := setupQueryJobHandlerTest(nil)
+	owner := uuid.New()
+	job, err := jobService.Submit(owner, "SELECT 1", "memory", "default", nil, time.Minute)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		userID uuid.UUID
+		id     string
+		want   int
+	}{
+		{"owner", owner, job.ID.String(), http.StatusOK},
+		{"another user", uuid.New(), job.ID.String(), http.StatusNotFound},
+		{"unknown job", owner, uuid.New().String(), http.StatusNotFound},
+		{"invalid id", owner, "not-a-uuid", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, w := createTestContext("GET", "/api/queries/jobs/"+tc.id, nil)
+			c.Set("userID", tc.userID)
+			c.AddParam("id", tc.id)
+			handler.GetQueryJob(c)
+
+			if w.Code != tc.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tc.want, w.Body.String())
+			}
+		})
+	}
+}
@@ -7,12 +7,15 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/mitsume/backend/internal/models"
 	"github.com/mitsume/backend/internal/repository"
+	"github.com/mitsume/backend/internal/services"
 )
 
 func init() {
@@ -22,7 +25,7 @@ func init() {
 func setupQueryHandlerTest() (*QueryHandler, *repository.MockTrinoExecutor, *repository.MockQueryHistoryRecorder) {
 	mockTrino := repository.NewMockTrinoExecutor()
 	mockHistory := repository.NewMockQueryHistoryRecorder()
-	handler := NewQueryHandler(mockTrino, mockHistory, nil, "memory", "default")
+	handler := NewQueryHandler(mockTrino, mockHistory, nil, "memory", "default", false)
 	return handler, mockTrino, mockHistory
 }
 
@@ -102,6 +105,272 @@ func TestExecuteQuery_Error(t *testing.T) {
 	}
 }
 
+func TestExecuteQuery_ReadOnlyModeRejectsWrites(t *testing.T) {
+	handler, mockTrino, _ := setupQueryHandlerTest()
+	handler.readOnly = true
+
+	for _, query := range []string{"DELETE FROM users", "SELECT 1; DROP TABLE users"} {
+		c, w := createTestContext("POST", "/api/queries/execute", models.ExecuteQueryRequest{Query: query})
+		handler.ExecuteQuery(c)
+
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("ExecuteQuery(%q) status = %d, want %d", query, w.Code, http.StatusForbidden)
+		}
+	}
+	if len(mockTrino.ExecuteQueryCalls) != 0 {
+		t.Fatalf("ExecuteQuery() ran %d queries, want none", len(mockTrino.ExecuteQueryCalls))
+	}
+
+	c, w := createTestContext("POST", "/api/queries/execute", models.ExecuteQueryRequest{Query: "-- report\nSELECT 1"})
+	handler.ExecuteQuery(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("ExecuteQuery() status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+// executeAs runs ExecuteQuery as userID with the given role repository enforcing catalog access
+func executeAs(handler *QueryHandler, roleRepo *repository.MockRoleRepository, userID uuid.UUID, req models.ExecuteQueryRequest) *httptest.ResponseRecorder {
+	handler.roleService = services.NewRoleService(roleRepo)
+	c, w := createTestContext("POST", "/api/queries/execute", req)
+	c.Set("userID", userID)
+	handler.ExecuteQuery(c)
+	return w
+}
+
+func TestExecuteQuery_DeniesCatalogOutsideUserRoles(t *testing.T) {
+	handler, mockTrino, _ := setupQueryHandlerTest()
+	roleRepo := repository.NewMockRoleRepository()
+	userID := uuid.New()
+	roleRepo.AllowedCatalogs[userID] = []string{"hive"}
+
+	w := executeAs(handler, roleRepo, userID, models.ExecuteQueryRequest{
+		Query:   "SELECT * FROM hive.sales.orders o JOIN postgres.public.users u ON o.user_id = u.id",
+		Catalog: "hive",
+		Schema:  "sales",
+	})
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("ExecuteQuery() status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if !strings.Contains(w.Body.String(), "access denied to catalog: postgres") {
+		t.Errorf("ExecuteQuery() body = %s, want the denied catalog named", w.Body.String())
+	}
+	if len(mockTrino.ExecuteQueryCalls) != 0 {
+		t.Fatalf("ExecuteQuery() ran %d queries, want none", len(mockTrino.ExecuteQueryCalls))
+	}
+}
+
+func TestExecuteQuery_AllowsCatalogInUserRoles(t *testing.T) {
+	handler, mockTrino, _ := setupQueryHandlerTest()
+	roleRepo := repository.NewMockRoleRepository()
+	userID := uuid.New()
+	roleRepo.AllowedCatalogs[userID] = []string{"hive"}
+
+	w := executeAs(handler, roleRepo, userID, models.ExecuteQueryRequest{
+		Query:   "SELECT * FROM hive.sales.orders",
+		Catalog: "hive",
+		Schema:  "sales",
+	})
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("ExecuteQuery() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	if len(mockTrino.ExecuteQueryCalls) != 1 {
+		t.Fatalf("ExecuteQuery() ran %d queries, want 1", len(mockTrino.ExecuteQueryCalls))
+	}
+}
+
+func TestExecuteQuery_ShowCatalogsRestrictedToAdmins(t *testing.T) {
+	handler, mockTrino, _ := setupQueryHandlerTest()
+	roleRepo := repository.NewMockRoleRepository()
+	userID, adminID := uuid.New(), uuid.New()
+	roleRepo.AllowedCatalogs[userID] = []string{"hive"}
+	roleRepo.AdminUsers[adminID] = true
+	req := models.ExecuteQueryRequest{Query: "show /* all */ catalogs", Catalog: "hive", Schema: "sales"}
+
+	if w := executeAs(handler, roleRepo, userID, req); w.Code != http.StatusForbidden {
+		t.Fatalf("ExecuteQuery() as user status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if len(mockTrino.ExecuteQueryCalls) != 0 {
+		t.Fatalf("ExecuteQuery() as user ran %d queries, want none", len(mockTrino.ExecuteQueryCalls))
+	}
+
+	// Admins bypass catalog checks, including for catalogs no role grants
+	if w := executeAs(handler, roleRepo, adminID, req); w.Code != http.StatusOK {
+		t.Fatalf("ExecuteQuery() as admin status = %d, want %d", w.Code, http.StatusOK)
+	}
+	req.Query = "SELECT * FROM postgres.public.users"
+	if w := executeAs(handler, roleRepo, adminID, req); w.Code != http.StatusOK {
+		t.Fatalf("ExecuteQuery() as admin on another catalog status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestExecuteQuery_AppliesRowFiltersOfUserRoles(t *testing.T) {
+	handler, mockTrino, mockHistory := setupQueryHandlerTest()
+	roleRepo := repository.NewMockRoleRepository()
+	userID, adminID, roleID := uuid.New(), uuid.New(), uuid.New()
+	roleRepo.Roles[roleID] = &models.Role{ID: roleID, Name: "west"}
+	roleRepo.UserRoles[userID] = []uuid.UUID{roleID}
+	roleRepo.RoleCatalogs[roleID] = []string{"hive"}
+	roleRepo.RoleRowFilters[roleID] = []models.RowFilter{{Catalog: "hive", Schema: "sales", Table: "orders", Predicate: "region = 'west'"}}
+	roleRepo.AdminUsers[adminID] = true
+	req := models.ExecuteQueryRequest{Query: "SELECT * FROM orders o", Catalog: "hive", Schema: "sales"}
+
+	if w := executeAs(handler, roleRepo, userID, req); w.Code != http.StatusOK {
+		t.Fatalf("ExecuteQuery() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	want := "SELECT * FROM (SELECT * FROM orders WHERE (region = 'west')) o"
+	if len(mockTrino.ExecuteQueryCalls) != 1 || mockTrino.ExecuteQueryCalls[0].Query != want {
+		t.Fatalf("ExecuteQuery() ran %+v, want %q", mockTrino.ExecuteQueryCalls, want)
+	}
+	// History keeps the query as the user wrote it
+	if len(mockHistory.SavedHistories) != 1 || mockHistory.SavedHistories[0].QueryText != req.Query {
+		t.Errorf("ExecuteQuery() recorded %+v, want the original query", mockHistory.SavedHistories)
+	}
+
+	// Admins are not filtered
+	if w := executeAs(handler, roleRepo, adminID, req); w.Code != http.StatusOK {
+		t.Fatalf("ExecuteQuery() as admin status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if got := mockTrino.ExecuteQueryCalls[1].Query; got != req.Query {
+		t.Errorf("ExecuteQuery() as admin ran %q, want %q", got, req.Query)
+	}
+}
+
+func TestExecuteQuery_TimeoutClampedToRoleCap(t *testing.T) {
+	handler, mockTrino, _ := setupQueryHandlerTest()
+	handler.SetQueryTimeouts(services.QueryTimeoutPolicy{Default: time.Minute, Max: 10 * time.Minute})
+	roleRepo := repository.NewMockRoleRepository()
+	analystID, serviceID, analystRole, serviceRole := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	roleRepo.Roles[analystRole] = &models.Role{ID: analystRole, Name: "analyst"}
+	roleRepo.Roles[serviceRole] = &models.Role{ID: serviceRole, Name: "service"}
+	roleRepo.UserRoles[analystID] = []uuid.UUID{analystRole}
+	roleRepo.UserRoles[serviceID] = []uuid.UUID{serviceRole}
+	roleRepo.RoleCatalogs[analystRole] = []string{"memory"}
+	roleRepo.RoleCatalogs[serviceRole] = []string{"memory"}
+	roleRepo.RoleTimeouts[analystRole] = 300
+	roleRepo.RoleTimeouts[serviceRole] = 1800
+
+	var got []time.Duration
+	mockTrino.ExecuteQueryFunc = func(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error) {
+		timeout, _ := services.QueryTimeoutFrom(ctx)
+		got = append(got, timeout)
+		return &models.QueryResult{}, nil
+	}
+
+	for _, tc := range []struct {
+		userID    uuid.UUID
+		requested int
+	}{
+		{analystID, 0},
+		{analystID, 3600},
+		{serviceID, 3600},
+	} {
+		req := models.ExecuteQueryRequest{Query: "SELECT 1", TimeoutSeconds: tc.requested}
+		if w := executeAs(handler, roleRepo, tc.userID, req); w.Code != http.StatusOK {
+			t.Fatalf("ExecuteQuery() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+	}
+
+	want := []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute}
+	if len(got) != len(want) {
+		t.Fatalf("ExecuteQuery() ran %d queries, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("query %d ran with timeout %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestExecuteQuery_TimeoutRespondsGatewayTimeout(t *testing.T) {
+	handler, mockTrino, mockHistory := setupQueryHandlerTest()
+	mockTrino.ExecuteQueryError = &services.QueryTimeoutError{Timeout: time.Minute}
+
+	c, w := createTestContext("POST", "/api/queries/execute", models.ExecuteQueryRequest{Query: "SELECT 1"})
+	c.Set("userID", uuid.New())
+	handler.ExecuteQuery(c)
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("ExecuteQuery() status = %d, want %d", w.Code, http.StatusGatewayTimeout)
+	}
+	if !strings.Contains(w.Body.String(), "query timed out after 1m0s") {
+		t.Errorf("ExecuteQuery() body = %s, want the timeout named", w.Body.String())
+	}
+	if len(mockHistory.SavedHistories) != 1 || mockHistory.SavedHistories[0].Status != "error" {
+		t.Errorf("ExecuteQuery() recorded %+v, want one error entry", mockHistory.SavedHistories)
+	}
+}
+
+func TestExecuteQuery_RejectsQueriesRowFiltersCannotRewrite(t *testing.T) {
+	handler, mockTrino, _ := setupQueryHandlerTest()
+	roleRepo := repository.NewMockRoleRepository()
+	userID, roleID := uuid.New(), uuid.New()
+	roleRepo.Roles[roleID] = &models.Role{ID: roleID, Name: "west"}
+	roleRepo.UserRoles[userID] = []uuid.UUID{roleID}
+	roleRepo.RoleCatalogs[roleID] = []string{"hive"}
+	roleRepo.RoleRowFilters[roleID] = []models.RowFilter{{Catalog: "hive", Schema: "sales", Table: "orders", Predicate: "region = 'west'"}}
+
+	w := executeAs(handler, roleRepo, userID, models.ExecuteQueryRequest{
+		Query:   "WITH orders AS (SELECT 1 AS id) SELECT * FROM orders",
+		Catalog: "hive",
+		Schema:  "sales",
+	})
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("ExecuteQuery() status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if len(mockTrino.ExecuteQueryCalls) != 0 {
+		t.Fatalf("ExecuteQuery() ran %d queries, want none", len(mockTrino.ExecuteQueryCalls))
+	}
+}
+
+func TestExecuteQueryPaged_ServesPagesByCursor(t *testing.T) {
+	handler, mockTrino, mockHistory := setupQueryHandlerTest()
+	handler.SetResultPager(services.NewResultPager(services.NewMemoryResultStore(), time.Minute))
+	mockTrino.ExecuteQueryFunc = func(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error) {
+		return &models.QueryResult{Columns: []string{"n"}, Rows: [][]interface{}{{1}, {2}, {3}}, RowCount: 3}, nil
+	}
+	userID := uuid.New()
+
+	body := models.PagedQueryRequest{ExecuteQueryRequest: models.ExecuteQueryRequest{Query: "SELECT n FROM t"}, PageSize: 2}
+	c, w := createTestContext("POST", "/api/queries/execute-paged", body)
+	c.Set("userID", userID)
+	handler.ExecuteQueryPaged(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("ExecuteQueryPaged() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var first models.QueryResultPage
+	json.Unmarshal(w.Body.Bytes(), &first)
+	if first.RowCount != 2 || first.TotalRows != 3 || first.NextCursor == nil {
+		t.Fatalf("first page = %+v, want 2 of 3 rows and a next cursor", first)
+	}
+
+	c, w = createTestContext("GET", "/api/queries/results?cursor="+*first.NextCursor, nil)
+	c.Set("userID", userID)
+	handler.GetResultPage(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("GetResultPage() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var second models.QueryResultPage
+	json.Unmarshal(w.Body.Bytes(), &second)
+	if second.RowCount != 1 || second.NextCursor != nil {
+		t.Fatalf("second page = %+v, want the last row and no cursor", second)
+	}
+	if len(mockTrino.ExecuteQueryCalls) != 1 || len(mockHistory.SavedHistories) != 1 {
+		t.Fatalf("query ran %d times with %d history entries, want 1 and 1", len(mockTrino.ExecuteQueryCalls), len(mockHistory.SavedHistories))
+	}
+
+	// A cursor is bound to the user it was issued to
+	c, w = createTestContext("GET", "/api/queries/results?cursor="+*first.NextCursor, nil)
+	handler.GetResultPage(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("GetResultPage() by another user status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
 func TestGetCatalogs_Success(t *testing.T) {
 	handler, mockTrino, _ := setupQueryHandlerTest()
 
@@ -280,3 +549,298 @@ func TestExecuteQuery_CustomFunc(t *testing.T) {
 		t.Fatalf("Custom function called %d times, want 1", callCount)
 	}
 }
+
+func TestGetTableSample_CapsLimit(t *testing.T) {
+	handler, mockTrino, _ := setupQueryHandlerTest()
+
+	var gotLimit int
+	mockTrino.GetTableSampleFunc = func(ctx context.Context, catalog, schema, table string, limit int) (*models.QueryResult, error) {
+		gotLimit = limit
+		return &models.QueryResult{Columns: []string{"id"}, Rows: [][]interface{}{{1}}, RowCount: 1}, nil
+	}
+
+	c, w := createTestContext("GET", "/api/catalogs/memory/schemas/default/tables/users/sample?limit=100000", nil)
+	c.Params = gin.Params{
+		{Key: "catalog", Value: "memory"},
+		{Key: "schema", Value: "default"},
+		{Key: "table", Value: "users"},
+	}
+
+	handler.GetTableSample(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("GetTableSample() status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if gotLimit != maxTableSampleLimit {
+		t.Fatalf("GetTableSample() limit = %d, want %d", gotLimit, maxTableSampleLimit)
+	}
+}
+
+func TestGetTableSample_ForbiddenForTableWithRowFilter(t *testing.T) {
+	handler, mockTrino, _ := setupQueryHandlerTest()
+	roleRepo := repository.NewMockRoleRepository()
+	userID, roleID := uuid.New(), uuid.New()
+	roleRepo.UserRoles[userID] = []uuid.UUID{roleID}
+	roleRepo.RoleRowFilters[roleID] = []models.RowFilter{{Catalog: "memory", Schema: "default", Table: "users", Predicate: "active"}}
+	handler.roleService = services.NewRoleService(roleRepo)
+
+	c, w := createTestContext("GET", "/api/catalogs/memory/schemas/default/tables/users/sample", nil)
+	c.Set("userID", userID)
+	c.Params = gin.Params{
+		{Key: "catalog", Value: "memory"},
+		{Key: "schema", Value: "default"},
+		{Key: "table", Value: "users"},
+	}
+
+	handler.GetTableSample(c)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("GetTableSample() status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if len(mockTrino.ExecuteQueryCalls) != 0 {
+		t.Fatalf("GetTableSample() ran %d queries, want none", len(mockTrino.ExecuteQueryCalls))
+	}
+}
+
+func TestGetTableSample_DefaultLimit(t *testing.T) {
+	handler, mockTrino, _ := setupQueryHandlerTest()
+
+	c, w := createTestContext("GET", "/api/catalogs/memory/schemas/default/tables/users/sample", nil)
+	c.Params = gin.Params{
+		{Key: "catalog", Value: "memory"},
+		{Key: "schema", Value: "default"},
+		{Key: "table", Value: "users"},
+	}
+
+	handler.GetTableSample(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("GetTableSample() status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if len(mockTrino.ExecuteQueryCalls) != 1 {
+		t.Fatalf("ExecuteQuery called %d times, want 1", len(mockTrino.ExecuteQueryCalls))
+	}
+	want := `SELECT * FROM "memory"."default"."users" LIMIT 10`
+	if got := mockTrino.ExecuteQueryCalls[0].Query; got != want {
+		t.Fatalf("Query = %q, want %q", got, want)
+	}
+}
+
+func TestGetTableSample_InvalidLimit(t *testing.T) {
+	handler, _, _ := setupQueryHandlerTest()
+
+	c, w := createTestContext("GET", "/api/catalogs/memory/schemas/default/tables/users/sample?limit=-5", nil)
+	c.Params = gin.Params{
+		{Key: "catalog", Value: "memory"},
+		{Key: "schema", Value: "default"},
+		{Key: "table", Value: "users"},
+	}
+
+	handler.GetTableSample(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("GetTableSample() status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestGetTableDDL_Table(t *testing.T) {
+	handler, mockTrino, _ := setupQueryHandlerTest()
+	mockTrino.QueryResults[`SHOW CREATE TABLE "memory"."default"."users"`] = &models.QueryResult{
+		Columns:  []string{"Create Table"},
+		Rows:     [][]interface{}{{"CREATE TABLE memory.default.users (id bigint)"}},
+		RowCount: 1,
+	}
+
+	c, w := createTestContext("GET", "/api/catalogs/memory/schemas/default/tables/users/ddl", nil)
+	c.Params = gin.Params{
+		{Key: "catalog", Value: "memory"},
+		{Key: "schema", Value: "default"},
+		{Key: "table", Value: "users"},
+	}
+
+	handler.GetTableDDL(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("GetTableDDL() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var ddl models.TableDDL
+	if err := json.Unmarshal(w.Body.Bytes(), &ddl); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if ddl.Type != "table" {
+		t.Fatalf("GetTableDDL() type = %q, want %q", ddl.Type, "table")
+	}
+	want := `SHOW CREATE TABLE "memory"."default"."users"`
+	if got := mockTrino.ExecuteQueryCalls[0].Query; got != want {
+		t.Fatalf("Query = %q, want %q", got, want)
+	}
+}
+
+func TestGetTableDDL_ViewFallback(t *testing.T) {
+	handler, mockTrino, _ := setupQueryHandlerTest()
+	mockTrino.ExecuteQueryFunc = func(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error) {
+		if strings.HasPrefix(query, "SHOW CREATE TABLE") {
+			return nil, errors.New("Relation 'memory.default.active_users' is a view, not a table")
+		}
+		return &models.QueryResult{
+			Columns:  []string{"Create View"},
+			Rows:     [][]interface{}{{"CREATE VIEW memory.default.active_users AS SELECT 1"}},
+			RowCount: 1,
+		}, nil
+	}
+
+	c, w := createTestContext("GET", "/api/catalogs/memory/schemas/default/tables/active_users/ddl", nil)
+	c.Params = gin.Params{
+		{Key: "catalog", Value: "memory"},
+		{Key: "schema", Value: "default"},
+		{Key: "table", Value: "active_users"},
+	}
+
+	handler.GetTableDDL(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("GetTableDDL() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var ddl models.TableDDL
+	if err := json.Unmarshal(w.Body.Bytes(), &ddl); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if ddl.Type != "view" {
+		t.Fatalf("GetTableDDL() type = %q, want %q", ddl.Type, "view")
+	}
+	if ddl.DDL != "CREATE VIEW memory.default.active_users AS SELECT 1" {
+		t.Fatalf("GetTableDDL() ddl = %q", ddl.DDL)
+	}
+	if len(mockTrino.ExecuteQueryCalls) != 2 {
+		t.Fatalf("ExecuteQuery called %d times, want 2", len(mockTrino.ExecuteQueryCalls))
+	}
+}
+
+func TestGetTableDDL_NotSupported(t *testing.T) {
+	handler, mockTrino, _ := setupQueryHandlerTest()
+	mockTrino.ExecuteQueryError = errors.New("This connector does not support SHOW CREATE")
+
+	c, w := createTestContext("GET", "/api/catalogs/memory/schemas/default/tables/users/ddl", nil)
+	c.Params = gin.Params{
+		{Key: "catalog", Value: "memory"},
+		{Key: "schema", Value: "default"},
+		{Key: "table", Value: "users"},
+	}
+
+	handler.GetTableDDL(c)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("GetTableDDL() status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestValidateBatch_ReportsPerQueryResults(t *testing.T) {
+	handler, mockTrino, _ := setupQueryHandlerTest()
+	mockTrino.ExecuteQueryFunc = func(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error) {
+		if strings.Contains(query, "missing_table") {
+			return nil, errors.New("line 1:15: Table 'memory.default.missing_table' does not exist")
+		}
+		return &models.QueryResult{}, nil
+	}
+
+	body := models.ValidateBatchRequest{
+		Queries: []models.ExecuteQueryRequest{
+			{Query: "SELECT * FROM users;"},
+			{Query: "SELECT * FROM missing_table"},
+			{Query: "SELECT 1", Catalog: "other"},
+		},
+	}
+	c, w := createTestContext("POST", "/api/queries/validate-batch", body)
+
+	handler.ValidateBatch(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("ValidateBatch() status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var resp models.ValidateBatchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.ValidCount != 2 || resp.InvalidCount != 1 {
+		t.Fatalf("ValidateBatch() valid/invalid = %d/%d, want 2/1", resp.ValidCount, resp.InvalidCount)
+	}
+	for i, r := range resp.Results {
+		if r.Index != i {
+			t.Fatalf("results[%d].Index = %d", i, r.Index)
+		}
+	}
+	if resp.Results[1].Valid || resp.Results[1].Error == nil || !strings.Contains(*resp.Results[1].Error, "does not exist") {
+		t.Fatalf("results[1] = %+v, want the Trino validation error", resp.Results[1])
+	}
+
+	if len(mockTrino.ExecuteQueryCalls) != 3 {
+		t.Fatalf("ExecuteQuery called %d times, want 3", len(mockTrino.ExecuteQueryCalls))
+	}
+	for _, call := range mockTrino.ExecuteQueryCalls {
+		if !strings.HasPrefix(call.Query, "EXPLAIN (TYPE VALIDATE) ") || strings.HasSuffix(call.Query, ";") {
+			t.Fatalf("unexpected validation query %q", call.Query)
+		}
+		if call.Query == "EXPLAIN (TYPE VALIDATE) SELECT 1" && call.Catalog != "other" {
+			t.Fatalf("catalog = %q, want %q", call.Catalog, "other")
+		}
+	}
+}
+
+func TestValidateBatch_RejectsEmptyBatch(t *testing.T) {
+	handler, _, _ := setupQueryHandlerTest()
+
+	c, w := createTestContext("POST", "/api/queries/validate-batch", models.ValidateBatchRequest{})
+
+	handler.ValidateBatch(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("ValidateBatch() status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAutocompleteMetadata_ReturnsSuggestions(t *testing.T) {
+	handler, mockTrino, _ := setupQueryHandlerTest()
+	mockTrino.SetupCatalog("hive", map[string][]string{"sales": {"orders", "customers"}})
+
+	c, w := createTestContext("GET", "/api/metadata/autocomplete?q=ord&catalog=hive&schema=sales", nil)
+	handler.AutocompleteMetadata(c)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("AutocompleteMetadata() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var response struct {
+		Suggestions []models.MetadataSuggestion `json:"suggestions"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(response.Suggestions) != 1 || response.Suggestions[0].Kind != "table" || response.Suggestions[0].Name != "orders" {
+		t.Fatalf("suggestions = %+v, want the orders table", response.Suggestions)
+	}
+}
+
+func TestAutocompleteMetadata_RejectsUnknownCatalog(t *testing.T) {
+	handler, mockTrino, _ := setupQueryHandlerTest()
+	mockTrino.SetupCatalog("hive", map[string][]string{"sales": {"orders"}})
+
+	c, w := createTestContext("GET", "/api/metadata/autocomplete?q=ord&catalog=secret", nil)
+	handler.AutocompleteMetadata(c)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("AutocompleteMetadata() status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestAutocompleteMetadata_SchemaRequiresCatalog(t *testing.T) {
+	handler, _, _ := setupQueryHandlerTest()
+
+	c, w := createTestContext("GET", "/api/metadata/autocomplete?q=ord&schema=sales", nil)
+	handler.AutocompleteMetadata(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("AutocompleteMetadata() status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/services"
+)
+
+// resolveQueryTimeout returns the timeout of a user's query that requested requestedSeconds
+// (0 for the default), clamped to the cap of the user's roles. Pass it to
+// services.WithQueryTimeout.
+func resolveQueryTimeout(
+	ctx context.Context,
+	roleService *services.RoleService,
+	policy services.QueryTimeoutPolicy,
+	userID uuid.UUID,
+	requestedSeconds int,
+) (time.Duration, error) {
+	var roleCap *time.Duration
+	if roleService != nil {
+		var err error
+		if roleCap, err = roleService.GetUserQueryTimeoutCap(ctx, userID); err != nil {
+			return 0, err
+		}
+	}
+	return policy.Effective(time.Duration(requestedSeconds)*time.Second, roleCap), nil
+}
+
+// respondQueryTimeout responds 504 when err is a query timeout, and reports whether it did
+func respondQueryTimeout(c *gin.Context, err error) bool {
+	var timeoutErr *services.QueryTimeoutError
+	if !errors.As(err, &timeoutErr) {
+		return false
+	}
+	c.JSON(http.StatusGatewayTimeout, gin.H{
+		"error":           timeoutErr.Error(),
+		"timeout_seconds": int(timeoutErr.Timeout / time.Second),
+	})
+	return true
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+)
+
+// maxRelativeDays bounds last_N_days (about ten years)
+const maxRelativeDays = 3660
+
+// isDateRangeType reports whether a parameter type maps to start/end placeholders
+func isDateRangeType(t models.ParameterType) bool {
+	return t == models.ParameterTypeDateRange || t == models.ParameterTypeRelativeDate
+}
+
+// resolveRelativeDate returns the first and last day (YYYY-MM-DD, both inclusive) of a relative
+// period as seen at now, in now's location. Tokens are today, yesterday, last_N_days (ending
+// today), this_week and last_week (Monday to Sunday), this_month, last_month, this_quarter,
+// last_quarter, this_year, last_year, mtd and ytd (up to today).
+func resolveRelativeDate(token string, now time.Time) (start, end string, ok bool) {
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	day := func(t time.Time) string { return t.Format("2006-01-02") }
+	monthStart := time.Date(y, m, 1, 0, 0, 0, 0, now.Location())
+	quarterStart := time.Date(y, m-(m-1)%3, 1, 0, 0, 0, 0, now.Location())
+	yearStart := time.Date(y, 1, 1, 0, 0, 0, 0, now.Location())
+	weekStart := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7)) // Monday
+
+	switch token = strings.ToLower(strings.TrimSpace(token)); token {
+	case "today":
+		return day(today), day(today), true
+	case "yesterday":
+		return day(today.AddDate(0, 0, -1)), day(today.AddDate(0, 0, -1)), true
+	case "this_week":
+		return day(weekStart), day(weekStart.AddDate(0, 0, 6)), true
+	case "last_week":
+		return day(weekStart.AddDate(0, 0, -7)), day(weekStart.AddDate(0, 0, -1)), true
+	case "this_month":
+		return day(monthStart), day(monthStart.AddDate(0, 1, -1)), true
+	case "last_month":
+		return day(monthStart.AddDate(0, -1, 0)), day(monthStart.AddDate(0, 0, -1)), true
+	case "mtd":
+		return day(monthStart), day(today), true
+	case "this_quarter":
+		return day(quarterStart), day(quarterStart.AddDate(0, 3, -1)), true
+	case "last_quarter":
+		return day(quarterStart.AddDate(0, -3, 0)), day(quarterStart.AddDate(0, 0, -1)), true
+	case "this_year":
+		return day(yearStart), day(yearStart.AddDate(1, 0, -1)), true
+	case "last_year":
+		return day(yearStart.AddDate(-1, 0, 0)), day(yearStart.AddDate(0, 0, -1)), true
+	case "ytd":
+		return day(yearStart), day(today), true
+	}
+
+	if n, found := strings.CutPrefix(token, "last_"); found {
+		if n, found = strings.CutSuffix(n, "_days"); found {
+			days, err := strconv.Atoi(n)
+			if err != nil || days < 1 || days > maxRelativeDays {
+				return "", "", false
+			}
+			return day(today.AddDate(0, 0, 1-days)), day(today), true
+		}
+	}
+	return "", "", false
+}
+
+// resolveRelativeDates returns params with the relative date tokens of date range and relative
+// date parameters, given or taken from their defaults, replaced by concrete start/end dates as
+// seen at now. params itself is not modified; unknown tokens are left for
+// replaceParametersWithDefs to report as missing.
+func resolveRelativeDates(params map[string]interface{}, defs []models.ParameterDefinition, now time.Time) map[string]interface{} {
+	resolved, copied := params, false
+	for i := range defs {
+		def := &defs[i]
+		if !isDateRangeType(def.Type) {
+			continue
+		}
+		value, ok := params[def.Name]
+		if (!ok || value == nil) && def.DefaultValue != nil {
+			value = parameterDefaultValue(def)
+		}
+		token, isString := value.(string)
+		if !isString {
+			continue
+		}
+		start, end, ok := resolveRelativeDate(token, now)
+		if !ok {
+			continue
+		}
+		if !copied {
+			resolved = make(map[string]interface{}, len(params)+1)
+			for k, v := range params {
+				resolved[k] = v
+			}
+			copied = true
+		}
+		resolved[def.Name] = map[string]interface{}{"start": start, "end": end}
+	}
+	return resolved
+}
+
+// resolveParameterDates resolves relative date tokens in the dashboard's timezone, as
+// resolveRelativeDates. Dashboards without date range parameters are not looked up.
+func (h *DashboardHandler) resolveParameterDates(
+	ctx context.Context,
+	dashboardID uuid.UUID,
+	params map[string]interface{},
+	defs []models.ParameterDefinition,
+) (map[string]interface{}, error) {
+	hasDateRange := false
+	for i := range defs {
+		hasDateRange = hasDateRange || isDateRangeType(defs[i].Type)
+	}
+	if !hasDateRange {
+		return params, nil
+	}
+	loc, err := h.viewer.GetDashboardLocation(ctx, dashboardID)
+	if err != nil {
+		return nil, err
+	}
+	return resolveRelativeDates(params, defs, time.Now().In(loc)), nil
+}
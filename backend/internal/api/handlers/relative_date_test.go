@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mitsume/backend/internal/models"
+)
+
+func TestResolveRelativeDate(t *testing.T) {
+	tests := []struct {
+		name      string
+		token     string
+		now       time.Time
+		wantStart string
+		wantEnd   string
+	}{
+		{"today", "today", time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC), "2024-03-15", "2024-03-15"},
+		{"yesterday across a month", "yesterday", time.Date(2024, 3, 1, 0, 30, 0, 0, time.UTC), "2024-02-29", "2024-02-29"},
+		{"last 7 days ends today", "last_7_days", time.Date(2024, 3, 3, 12, 0, 0, 0, time.UTC), "2024-02-26", "2024-03-03"},
+		{"last 30 days across a year", "last_30_days", time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC), "2023-12-12", "2024-01-10"},
+		{"this month in a leap February", "this_month", time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC), "2024-02-01", "2024-02-29"},
+		{"last month in January", "last_month", time.Date(2024, 1, 31, 23, 0, 0, 0, time.UTC), "2023-12-01", "2023-12-31"},
+		{"last month from March 31st", "last_month", time.Date(2023, 3, 31, 0, 0, 0, 0, time.UTC), "2023-02-01", "2023-02-28"},
+		{"month to date", "mtd", time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC), "2024-05-01", "2024-05-20"},
+		{"this week on a Sunday", "this_week", time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), "2024-03-04", "2024-03-10"},
+		{"last week across a year", "last_week", time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), "2023-12-25", "2023-12-31"},
+		{"this quarter", "this_quarter", time.Date(2024, 8, 15, 0, 0, 0, 0, time.UTC), "2024-07-01", "2024-09-30"},
+		{"last quarter across a year", "last_quarter", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), "2023-10-01", "2023-12-31"},
+		{"this year", "this_year", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), "2024-01-01", "2024-12-31"},
+		{"last year", "LAST_YEAR", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "2023-01-01", "2023-12-31"},
+		{"year to date on January 1st", "ytd", time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC), "2025-01-01", "2025-01-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, ok := resolveRelativeDate(tt.token, tt.now)
+			if !ok || start != tt.wantStart || end != tt.wantEnd {
+				t.Fatalf("resolveRelativeDate(%q, %v) = %s, %s, %v, want %s, %s", tt.token, tt.now, start, end, ok, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+
+	for _, token := range []string{"last_0_days", "last_x_days", "next_month", "last_99999_days", "2024-01-01,2024-01-31"} {
+		if _, _, ok := resolveRelativeDate(token, time.Now()); ok {
+			t.Errorf("resolveRelativeDate(%q) ok = true, want false", token)
+		}
+	}
+}
+
+func TestResolveRelativeDate_UsesTheLocationOfNow(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	// 2024-03-31 20:00 UTC is already April 1st in Tokyo
+	now := time.Date(2024, 3, 31, 20, 0, 0, 0, time.UTC)
+
+	if start, end, _ := resolveRelativeDate("this_month", now); start != "2024-03-01" || end != "2024-03-31" {
+		t.Errorf("resolveRelativeDate(this_month) in UTC = %s, %s, want March", start, end)
+	}
+	if start, end, _ := resolveRelativeDate("this_month", now.In(tokyo)); start != "2024-04-01" || end != "2024-04-30" {
+		t.Errorf("resolveRelativeDate(this_month) in Tokyo = %s, %s, want April", start, end)
+	}
+}
+
+func TestResolveRelativeDates(t *testing.T) {
+	defs := []models.ParameterDefinition{
+		{Name: "period", Type: models.ParameterTypeRelativeDate, DefaultValue: "last_7_days"},
+		{Name: "range", Type: models.ParameterTypeDateRange, Targets: &models.DateRangeTargets{Start: "from", End: "to"}},
+		{Name: "region", Type: models.ParameterTypeText, SqlFormat: models.SqlFormatString},
+	}
+	now := time.Date(2024, 3, 3, 12, 0, 0, 0, time.UTC)
+	params := map[string]interface{}{"range": "this_month", "region": "last_month"}
+
+	resolved := resolveRelativeDates(params, defs, now)
+	if params["range"] != "this_month" {
+		t.Fatalf("resolveRelativeDates() modified the caller's params: %v", params)
+	}
+
+	query := "SELECT * FROM t WHERE d BETWEEN {{period_start}} AND {{period_end}} AND d BETWEEN {{from}} AND {{to}} AND region = {{region}}"
+	got, missing := replaceParametersWithDefs(query, resolved, defs, false)
+	if len(missing) != 0 {
+		t.Fatalf("replaceParametersWithDefs() missing = %v, want none", missing)
+	}
+	want := "SELECT * FROM t WHERE d BETWEEN DATE '2024-02-26' AND DATE '2024-03-03' AND d BETWEEN DATE '2024-03-01' AND DATE '2024-03-31' AND region = 'last_month'"
+	if got != want {
+		t.Fatalf("replaceParametersWithDefs() = %q, want %q", got, want)
+	}
+
+	// An unknown token is reported as missing
+	_, missing = replaceParametersWithDefs("SELECT {{period}}",
+		resolveRelativeDates(map[string]interface{}{"period": "next_decade"}, defs, now), defs, false)
+	if len(missing) != 1 || missing[0] != "period" {
+		t.Fatalf("replaceParametersWithDefs() missing = %v, want [period]", missing)
+	}
+}
@@ -14,12 +14,14 @@ import (
 type RoleHandler struct {
 	roleService  *services.RoleService
 	trinoService *services.TrinoService
+	auditService *services.AuditService // nil disables audit logging
 }
 
-func NewRoleHandler(roleService *services.RoleService, trinoService *services.TrinoService) *RoleHandler {
+func NewRoleHandler(roleService *services.RoleService, trinoService *services.TrinoService, auditService *services.AuditService) *RoleHandler {
 	return &RoleHandler{
 		roleService:  roleService,
 		trinoService: trinoService,
+		auditService: auditService,
 	}
 }
 
@@ -82,6 +84,9 @@ func (h *RoleHandler) CreateRole(c *gin.Context) {
 		return
 	}
 
+	h.auditService.Record(c.Request.Context(), userID, models.AuditActionRoleCreate, models.AuditTargetRole, role.ID,
+		map[string]interface{}{"name": role.Name})
+
 	c.JSON(http.StatusCreated, role)
 }
 
@@ -117,10 +122,17 @@ func (h *RoleHandler) UpdateRole(c *gin.Context) {
 			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 			return
 		}
+		if errors.Is(err, services.ErrParentRoleNotFound) || errors.Is(err, services.ErrRoleCycle) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	h.auditService.Record(c.Request.Context(), userID, models.AuditActionRoleUpdate, models.AuditTargetRole, roleID,
+		map[string]interface{}{"name": req.Name, "description": req.Description, "parent_role_id": req.ParentRoleID})
+
 	c.JSON(http.StatusOK, role)
 }
 
@@ -149,6 +161,8 @@ func (h *RoleHandler) DeleteRole(c *gin.Context) {
 		return
 	}
 
+	h.auditService.Record(c.Request.Context(), userID, models.AuditActionRoleDelete, models.AuditTargetRole, roleID, nil)
+
 	c.JSON(http.StatusNoContent, nil)
 }
 
@@ -181,9 +195,120 @@ func (h *RoleHandler) SetRoleCatalogs(c *gin.Context) {
 		return
 	}
 
+	h.auditService.Record(c.Request.Context(), userID, models.AuditActionRoleSetCatalogs, models.AuditTargetRole, roleID,
+		map[string]interface{}{"catalogs": req.Catalogs})
+
 	c.JSON(http.StatusOK, gin.H{"message": "catalogs updated"})
 }
 
+func (h *RoleHandler) SetRoleSchemas(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	roleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role id"})
+		return
+	}
+
+	var req models.SetSchemaPermissionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.roleService.SetRoleSchemas(c.Request.Context(), userID, roleID, req.Schemas); err != nil {
+		if errors.Is(err, services.ErrUnauthorized) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrRoleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), userID, models.AuditActionRoleSetSchemas, models.AuditTargetRole, roleID,
+		map[string]interface{}{"schemas": req.Schemas})
+
+	c.JSON(http.StatusOK, gin.H{"message": "schemas updated"})
+}
+
+// SetRoleRowFilters replaces the role's row filters
+// PUT /admin/roles/:id/row-filters
+func (h *RoleHandler) SetRoleRowFilters(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	roleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role id"})
+		return
+	}
+
+	var req models.SetRowFiltersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.roleService.SetRoleRowFilters(c.Request.Context(), userID, roleID, req.Filters); err != nil {
+		var validationErr *models.ValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Message, "field": validationErr.Field})
+			return
+		}
+		if errors.Is(err, services.ErrUnauthorized) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrRoleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), userID, models.AuditActionRoleSetRowFilters, models.AuditTargetRole, roleID,
+		map[string]interface{}{"filters": req.Filters})
+
+	c.JSON(http.StatusOK, gin.H{"message": "row filters updated"})
+}
+
+// SetRoleQueryTimeout sets the role's cap on query timeouts
+// PUT /admin/roles/:id/query-timeout
+func (h *RoleHandler) SetRoleQueryTimeout(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	roleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role id"})
+		return
+	}
+
+	var req models.SetQueryTimeoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.roleService.SetRoleQueryTimeout(c.Request.Context(), userID, roleID, req.MaxQueryTimeoutSeconds); err != nil {
+		if errors.Is(err, services.ErrUnauthorized) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrRoleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.auditService.Record(c.Request.Context(), userID, models.AuditActionRoleSetTimeout, models.AuditTargetRole, roleID,
+		map[string]interface{}{"max_query_timeout_seconds": req.MaxQueryTimeoutSeconds})
+
+	c.JSON(http.StatusOK, gin.H{"message": "query timeout updated"})
+}
+
 func (h *RoleHandler) GetAvailableCatalogs(c *gin.Context) {
 	catalogs, err := h.trinoService.GetCatalogs(c.Request.Context())
 	if err != nil {
@@ -247,6 +372,9 @@ func (h *RoleHandler) AssignRole(c *gin.Context) {
 		return
 	}
 
+	h.auditService.Record(c.Request.Context(), adminUserID, models.AuditActionUserAssignRole, models.AuditTargetUser, targetUserID,
+		map[string]interface{}{"role_id": req.RoleID})
+
 	c.JSON(http.StatusOK, gin.H{"message": "role assigned"})
 }
 
@@ -280,5 +408,8 @@ func (h *RoleHandler) UnassignRole(c *gin.Context) {
 		return
 	}
 
+	h.auditService.Record(c.Request.Context(), adminUserID, models.AuditActionUserUnassignRole, models.AuditTargetUser, targetUserID,
+		map[string]interface{}{"role_id": roleID})
+
 	c.JSON(http.StatusOK, gin.H{"message": "role unassigned"})
 }
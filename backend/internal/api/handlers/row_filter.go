@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/services"
+)
+
+// applyRowFilters rewrites query with the row filters of the user's roles, returning it
+// unchanged for admins and users without filters. Queries that read a filtered table in a
+// way that cannot be rewritten fail with services.ErrRowFilterUnsupported.
+func applyRowFilters(
+	ctx context.Context,
+	roleService *services.RoleService,
+	userID uuid.UUID,
+	query string,
+	catalog string,
+	schema string,
+) (string, error) {
+	if roleService == nil {
+		return query, nil
+	}
+
+	filters, err := roleService.GetUserRowFilters(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	return services.ApplyRowFilters(query, catalog, schema, filters)
+}
+
+// respondRowFilterError writes the response for an error from applyRowFilters: 403 for queries
+// the row filters cannot be applied to, 500 for anything else
+func respondRowFilterError(c *gin.Context, err error) {
+	if respondRowFilterRejection(c, err) {
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// respondRowFilterRejection writes 403 and returns true when err rejects a query the row filters
+// cannot be applied to
+func respondRowFilterRejection(c *gin.Context, err error) bool {
+	if !errors.Is(err, services.ErrRowFilterUnsupported) {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	return true
+}
@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -11,13 +15,27 @@ import (
 	"github.com/mitsume/backend/internal/services"
 )
 
+// savedQueryImporter is the part of QueryService that ImportSavedQueries uses
+type savedQueryImporter interface {
+	CheckStatement(query string) error
+	ImportSavedQueries(ctx context.Context, userID uuid.UUID, reqs []models.SaveQueryRequest) ([]uuid.UUID, error)
+}
+
 type SavedQueryHandler struct {
-	queryService *services.QueryService
+	queryService   *services.QueryService
+	importer       savedQueryImporter
+	roleService    *services.RoleService
+	defaultCatalog string
+	defaultSchema  string
 }
 
-func NewSavedQueryHandler(queryService *services.QueryService) *SavedQueryHandler {
+func NewSavedQueryHandler(queryService *services.QueryService, roleService *services.RoleService, defaultCatalog, defaultSchema string) *SavedQueryHandler {
 	return &SavedQueryHandler{
-		queryService: queryService,
+		queryService:   queryService,
+		importer:       queryService,
+		roleService:    roleService,
+		defaultCatalog: defaultCatalog,
+		defaultSchema:  defaultSchema,
 	}
 }
 
@@ -65,6 +83,9 @@ func (h *SavedQueryHandler) CreateSavedQuery(c *gin.Context) {
 
 	query, err := h.queryService.CreateSavedQuery(c.Request.Context(), userID, &req)
 	if err != nil {
+		if respondReadOnlyError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -72,6 +93,87 @@ func (h *SavedQueryHandler) CreateSavedQuery(c *gin.Context) {
 	c.JSON(http.StatusCreated, query)
 }
 
+// ImportSavedQueries creates many saved queries at once, e.g. when migrating from another tool.
+// Each item is checked on its own (name, query text, read-only mode and the user's catalog
+// access) and reported with its error; the remaining items are created in one transaction.
+// POST /queries/saved/import
+func (h *SavedQueryHandler) ImportSavedQueries(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	ctx := c.Request.Context()
+
+	var items []models.ImportSavedQueryItem
+	if err := c.ShouldBindJSON(&items); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "request body must be an array of queries"})
+		return
+	}
+	if len(items) == 0 || len(items) > models.MaxSavedQueryImport {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("import between 1 and %d queries", models.MaxSavedQueryImport)})
+		return
+	}
+
+	resp := models.ImportSavedQueriesResponse{Results: make([]models.SavedQueryImportResult, len(items))}
+	var valid []models.SaveQueryRequest
+	var validIndexes []int
+	for i, item := range items {
+		resp.Results[i].Index = i
+		if err := h.validateImportItem(ctx, userID, item); err != nil {
+			msg := err.Error()
+			resp.Results[i].Error = &msg
+			continue
+		}
+		req := models.SaveQueryRequest{Name: item.Name, Description: item.Description, QueryText: item.QueryText}
+		if item.Catalog != "" {
+			req.Catalog = &item.Catalog
+		}
+		if item.Schema != "" {
+			req.SchemaName = &item.Schema
+		}
+		valid = append(valid, req)
+		validIndexes = append(validIndexes, i)
+	}
+
+	if len(valid) > 0 {
+		ids, err := h.importer.ImportSavedQueries(ctx, userID, valid)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import queries: " + err.Error()})
+			return
+		}
+		for j, i := range validIndexes {
+			resp.Results[i].ID = &ids[j]
+		}
+	}
+
+	resp.CreatedCount = len(valid)
+	resp.FailedCount = len(items) - len(valid)
+	c.JSON(http.StatusOK, resp)
+}
+
+// validateImportItem reports why an imported query cannot be saved for the user
+func (h *SavedQueryHandler) validateImportItem(ctx context.Context, userID uuid.UUID, item models.ImportSavedQueryItem) error {
+	if strings.TrimSpace(item.Name) == "" {
+		return errors.New("name is required")
+	}
+	if utf8.RuneCountInString(item.Name) > 255 {
+		return errors.New("name too long (max 255 characters)")
+	}
+	if strings.TrimSpace(item.QueryText) == "" {
+		return errors.New("query_text is required")
+	}
+	if err := h.importer.CheckStatement(item.QueryText); err != nil {
+		return err
+	}
+
+	catalog := item.Catalog
+	if catalog == "" {
+		catalog = h.defaultCatalog
+	}
+	schema := item.Schema
+	if schema == "" {
+		schema = h.defaultSchema
+	}
+	return enforceCatalogAccess(ctx, h.roleService, userID, item.QueryText, catalog, schema)
+}
+
 func (h *SavedQueryHandler) UpdateSavedQuery(c *gin.Context) {
 	userID := c.MustGet("userID").(uuid.UUID)
 	queryID, err := uuid.Parse(c.Param("id"))
@@ -88,6 +190,9 @@ func (h *SavedQueryHandler) UpdateSavedQuery(c *gin.Context) {
 
 	query, err := h.queryService.UpdateSavedQuery(c.Request.Context(), queryID, userID, &req)
 	if err != nil {
+		if respondReadOnlyError(c, err) {
+			return
+		}
 		if errors.Is(err, services.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "query not found"})
 			return
@@ -148,3 +253,50 @@ func (h *SavedQueryHandler) GetQueryHistory(c *gin.Context) {
 
 	c.JSON(http.StatusOK, history)
 }
+
+// GetSlowQueries lists recent executions that reached the slow query threshold, slowest first.
+// threshold_ms overrides the configured threshold; days (default 7, at most 90), limit and offset page the list.
+// GET /queries/slow (admin)
+func (h *SavedQueryHandler) GetSlowQueries(c *gin.Context) {
+	thresholdMs, _ := strconv.Atoi(c.Query("threshold_ms"))
+	days, _ := strconv.Atoi(c.Query("days"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+
+	slow, err := h.queryService.GetSlowQueries(c.Request.Context(), thresholdMs, days, limit, offset)
+	if err != nil {
+		if errors.Is(err, services.ErrSlowQueryReportingDisabled) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "slow query reporting is disabled; set SLOW_QUERY_THRESHOLD_MS or pass threshold_ms"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, slow)
+}
+
+// GetSavedQueryPerformance summarizes recent executions of one of the user's saved queries.
+// days (default 30, at most 90) sets the window.
+// GET /queries/saved/:id/performance
+func (h *SavedQueryHandler) GetSavedQueryPerformance(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	queryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid query id"})
+		return
+	}
+	days, _ := strconv.Atoi(c.Query("days"))
+
+	perf, err := h.queryService.GetSavedQueryPerformance(c.Request.Context(), queryID, userID, days)
+	if err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "query not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, perf)
+}
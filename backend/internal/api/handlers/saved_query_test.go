@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/repository"
+	"github.com/mitsume/backend/internal/services"
+)
+
+// fakeSavedQueryImporter records imported queries, optionally rejecting writes as in read-only mode
+type fakeSavedQueryImporter struct {
+	readOnly bool
+	err      error
+	imported []models.SaveQueryRequest
+}
+
+func (f *fakeSavedQueryImporter) CheckStatement(query string) error {
+	if !f.readOnly {
+		return nil
+	}
+	return services.CheckReadOnlyStatement(query)
+}
+
+func (f *fakeSavedQueryImporter) ImportSavedQueries(ctx context.Context, userID uuid.UUID, reqs []models.SaveQueryRequest) ([]uuid.UUID, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.imported = append(f.imported, reqs...)
+	ids := make([]uuid.UUID, len(reqs))
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+	return ids, nil
+}
+
+func importSavedQueries(handler *SavedQueryHandler, userID uuid.UUID, body interface{}) (int, models.ImportSavedQueriesResponse) {
+	c, w := createTestContext("POST", "/api/queries/saved/import", body)
+	c.Set("userID", userID)
+	handler.ImportSavedQueries(c)
+
+	var got models.ImportSavedQueriesResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	return w.Code, got
+}
+
+func TestImportSavedQueries_ReportsPerItemFailures(t *testing.T) {
+	roleRepo := repository.NewMockRoleRepository()
+	userID := uuid.New()
+	roleRepo.AllowedCatalogs[userID] = []string{"hive"}
+	importer := &fakeSavedQueryImporter{readOnly: true}
+	handler := &SavedQueryHandler{importer: importer, roleService: services.NewRoleService(roleRepo), defaultCatalog: "hive", defaultSchema: "sales"}
+
+	code, got := importSavedQueries(handler, userID, []models.ImportSavedQueryItem{
+		{Name: "Orders", QueryText: "SELECT * FROM orders"},
+		{Name: "Users", QueryText: "SELECT * FROM postgres.public.users"},
+		{Name: " ", QueryText: "SELECT 1"},
+		{Name: "Cleanup", QueryText: "DELETE FROM orders"},
+		{Name: "Revenue", QueryText: "SELECT sum(total) FROM hive.sales.orders", Catalog: "hive", Schema: "sales"},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("ImportSavedQueries() status = %d, want %d", code, http.StatusOK)
+	}
+	if got.CreatedCount != 2 || got.FailedCount != 3 || len(got.Results) != 5 {
+		t.Fatalf("ImportSavedQueries() = %+v, want 2 created and 3 failed", got)
+	}
+
+	for _, i := range []int{0, 4} {
+		if r := got.Results[i]; r.Index != i || r.ID == nil || r.Error != nil {
+			t.Errorf("result %d = %+v, want a created query", i, r)
+		}
+	}
+	wantErrors := map[int]string{1: "access denied to catalog: postgres", 2: "name is required", 3: "read-only"}
+	for i, want := range wantErrors {
+		if r := got.Results[i]; r.ID != nil || r.Error == nil || !strings.Contains(*r.Error, want) {
+			t.Errorf("result %d = %+v, want an error containing %q", i, r, want)
+		}
+	}
+
+	if len(importer.imported) != 2 || importer.imported[1].Catalog == nil || *importer.imported[1].Catalog != "hive" || importer.imported[0].Catalog != nil {
+		t.Errorf("imported = %+v, want the valid queries with their own catalog only where given", importer.imported)
+	}
+}
+
+func TestImportSavedQueries_FailedTransactionCreatesNothing(t *testing.T) {
+	handler := &SavedQueryHandler{importer: &fakeSavedQueryImporter{err: errors.New("connection reset")}}
+
+	code, _ := importSavedQueries(handler, uuid.New(), []models.ImportSavedQueryItem{{Name: "Orders", QueryText: "SELECT 1"}})
+	if code != http.StatusInternalServerError {
+		t.Fatalf("ImportSavedQueries() status = %d, want %d", code, http.StatusInternalServerError)
+	}
+}
+
+func TestImportSavedQueries_RejectsInvalidBatches(t *testing.T) {
+	importer := &fakeSavedQueryImporter{}
+	handler := &SavedQueryHandler{importer: importer}
+
+	tooMany := make([]models.ImportSavedQueryItem, models.MaxSavedQueryImport+1)
+	for _, body := range []interface{}{[]models.ImportSavedQueryItem{}, tooMany, map[string]string{"name": "Orders"}} {
+		if code, _ := importSavedQueries(handler, uuid.New(), body); code != http.StatusBadRequest {
+			t.Errorf("ImportSavedQueries(%T) status = %d, want %d", body, code, http.StatusBadRequest)
+		}
+	}
+	if len(importer.imported) != 0 {
+		t.Errorf("imported %d queries, want none", len(importer.imported))
+	}
+}
@@ -3,140 +3,127 @@ package handlers
 import (
 	"context"
 	"errors"
-	"regexp"
+	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
 	"github.com/mitsume/backend/internal/services"
 )
 
 var (
-	ErrCatalogAccessDenied   = errors.New("access denied to catalog")
+	ErrCatalogAccessDenied   = errors.New("access denied to catalog or schema")
 	ErrShowCatalogsForbidden = errors.New("SHOW CATALOGS is not allowed; use the catalogs API instead")
 )
 
-var (
-	showCatalogsPattern = regexp.MustCompile(`(?i)\bSHOW\s+CATALOGS\b`)
-
-	// catalog.schema.table references (quoted or unquoted identifiers)
-	catalogThreePartPattern = regexp.MustCompile(`(?i)([a-zA-Z_][a-zA-Z0-9_]*|"[^"]+")\s*\.\s*([a-zA-Z_][a-zA-Z0-9_]*|"[^"]+")\s*\.\s*([a-zA-Z_][a-zA-Z0-9_]*|"[^"]+")`)
-
-	// Trino metadata statements
-	showSchemasFromCatalogPattern = regexp.MustCompile(`(?i)\bSHOW\s+SCHEMAS\s+(?:FROM|IN)\s+("([^"]+)"|[a-zA-Z_][a-zA-Z0-9_]*)`)
-	showTablesFromCatalogSchema   = regexp.MustCompile(`(?i)\bSHOW\s+TABLES\s+(?:FROM|IN)\s+("([^"]+)"|[a-zA-Z_][a-zA-Z0-9_]*)\s*\.`)
-	useCatalogSchemaPattern       = regexp.MustCompile(`(?i)\bUSE\s+("([^"]+)"|[a-zA-Z_][a-zA-Z0-9_]*)\s*\.`)
-)
+// CatalogAccessError names the first catalog (or catalog.schema) of a query the user may not
+// access. It matches ErrCatalogAccessDenied with errors.Is.
+type CatalogAccessError struct {
+	Catalog string
+	Schema  string // empty when access to the catalog itself was denied
+}
 
-func unquoteIdentifier(identifier string) string {
-	if len(identifier) >= 2 && identifier[0] == '"' && identifier[len(identifier)-1] == '"' {
-		return identifier[1 : len(identifier)-1]
+func (e *CatalogAccessError) Error() string {
+	if e.Schema == "" {
+		return "access denied to catalog: " + e.Catalog
 	}
-	return identifier
+	return "access denied to schema: " + e.Catalog + "." + e.Schema
 }
 
-func extractReferencedCatalogs(query string) []string {
-	seen := make(map[string]struct{})
-	catalogs := make([]string, 0)
+func (e *CatalogAccessError) Is(target error) bool {
+	return target == ErrCatalogAccessDenied
+}
 
-	add := func(catalog string) {
-		if catalog == "" {
-			return
-		}
-		if _, ok := seen[catalog]; ok {
-			return
-		}
-		seen[catalog] = struct{}{}
-		catalogs = append(catalogs, catalog)
-	}
+// schemaRef is a catalog.schema a query touches. An empty schema only requires access to
+// some schema of the catalog (e.g. SHOW SCHEMAS FROM catalog).
+type schemaRef struct {
+	catalog string
+	schema  string
+}
 
-	for _, match := range catalogThreePartPattern.FindAllStringSubmatch(query, -1) {
-		if len(match) > 1 {
-			add(unquoteIdentifier(match[1]))
+// extractReferencedSchemas returns the catalog.schema pairs referenced by query (see
+// services.SchemaReferences), without duplicates. Names without a catalog are resolved against
+// effectiveCatalog; they are skipped when it is empty. Queries whose table references cannot be
+// classified fail with services.ErrUnclassifiedTableReference.
+func extractReferencedSchemas(query, effectiveCatalog string) ([]schemaRef, error) {
+	found, err := services.SchemaReferences(query)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[schemaRef]struct{})
+	refs := make([]schemaRef, 0)
+	for _, r := range found {
+		ref := schemaRef{catalog: r.Catalog, schema: r.Schema}
+		if ref.catalog == "" {
+			ref.catalog = effectiveCatalog
 		}
-	}
-	for _, match := range showSchemasFromCatalogPattern.FindAllStringSubmatch(query, -1) {
-		if len(match) > 2 && match[2] != "" {
-			add(match[2])
+		if ref.catalog == "" {
 			continue
 		}
-		if len(match) > 1 {
-			add(unquoteIdentifier(match[1]))
-		}
-	}
-	for _, match := range showTablesFromCatalogSchema.FindAllStringSubmatch(query, -1) {
-		if len(match) > 2 && match[2] != "" {
-			add(match[2])
+		if _, ok := seen[ref]; ok {
 			continue
 		}
-		if len(match) > 1 {
-			add(unquoteIdentifier(match[1]))
-		}
-	}
-	for _, match := range useCatalogSchemaPattern.FindAllStringSubmatch(query, -1) {
-		if len(match) > 2 && match[2] != "" {
-			add(match[2])
-			continue
-		}
-		if len(match) > 1 {
-			add(unquoteIdentifier(match[1]))
-		}
+		seen[ref] = struct{}{}
+		refs = append(refs, ref)
 	}
-
-	return catalogs
+	return refs, nil
 }
 
-func userCanAccessCatalogs(allowedCatalogs []string, catalogs []string) bool {
-	if len(catalogs) == 0 {
-		return true
-	}
-	if allowedCatalogs == nil {
-		return true
-	}
-	allowedSet := make(map[string]struct{}, len(allowedCatalogs))
-	for _, c := range allowedCatalogs {
-		allowedSet[c] = struct{}{}
+// checkSchemaAccess returns a *CatalogAccessError for the first ref allowedSchemas does not
+// cover; nil allowedSchemas means admin
+func checkSchemaAccess(allowedSchemas []models.SchemaPermission, refs []schemaRef) error {
+	if allowedSchemas == nil {
+		return nil
 	}
-
-	for _, catalog := range catalogs {
-		if _, ok := allowedSet[catalog]; !ok {
-			return false
+	for _, ref := range refs {
+		if services.SchemaAllowed(allowedSchemas, ref.catalog, "") {
+			if !services.SchemaAllowed(allowedSchemas, ref.catalog, ref.schema) {
+				return &CatalogAccessError{Catalog: ref.catalog, Schema: ref.schema}
+			}
+			continue
 		}
+		return &CatalogAccessError{Catalog: ref.catalog}
 	}
-	return true
+	return nil
 }
 
+// enforceCatalogAccess checks that the user may read every catalog and schema the query
+// references, including the session catalog and schema it runs in. Catalog-level grants
+// cover all schemas of the catalog. Denials are reported as *CatalogAccessError.
 func enforceCatalogAccess(
 	ctx context.Context,
 	roleService *services.RoleService,
 	userID uuid.UUID,
 	query string,
 	effectiveCatalog string,
+	effectiveSchema string,
 ) error {
 	if roleService == nil {
 		return nil
 	}
 
-	allowedCatalogs, err := roleService.GetUserAllowedCatalogs(ctx, userID)
+	allowedSchemas, err := roleService.GetUserAllowedSchemas(ctx, userID)
 	if err != nil {
 		return err
 	}
 
+	// nil means admin has access to all catalogs
+	if allowedSchemas == nil {
+		return nil
+	}
+
 	// Block SHOW CATALOGS for non-admin users to avoid metadata leakage; use /catalogs instead.
-	if allowedCatalogs != nil && showCatalogsPattern.MatchString(query) {
+	if services.ShowsCatalogs(query) {
 		return ErrShowCatalogsForbidden
 	}
 
-	requiredCatalogs := extractReferencedCatalogs(query)
-	if effectiveCatalog != "" {
-		requiredCatalogs = append(requiredCatalogs, effectiveCatalog)
+	requiredSchemas, err := extractReferencedSchemas(query, effectiveCatalog)
+	if err != nil {
+		// A reference the guard cannot classify could name any catalog
+		return fmt.Errorf("%w: %v", ErrCatalogAccessDenied, err)
 	}
-
-	// nil means admin has access to all catalogs
-	if allowedCatalogs == nil {
-		return nil
+	if effectiveCatalog != "" {
+		requiredSchemas = append(requiredSchemas, schemaRef{catalog: effectiveCatalog, schema: effectiveSchema})
 	}
 
-	if !userCanAccessCatalogs(allowedCatalogs, requiredCatalogs) {
-		return ErrCatalogAccessDenied
-	}
-	return nil
+	return checkSchemaAccess(allowedSchemas, requiredSchemas)
 }
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/repository"
+	"github.com/mitsume/backend/internal/services"
+)
+
+func TestExtractReferencedSchemas(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []schemaRef
+	}{
+		{
+			name:  "three-part name",
+			query: `SELECT * FROM hive."Sales".orders`,
+			want:  []schemaRef{{"hive", "sales"}},
+		},
+		{
+			name:  "schema.table resolved against session catalog",
+			query: "SELECT * FROM sales.orders o JOIN hr.people p ON o.owner = p.id",
+			want:  []schemaRef{{"memory", "sales"}, {"memory", "hr"}},
+		},
+		{
+			name:  "column references and EXTRACT are not schemas",
+			query: "SELECT o.id, EXTRACT(YEAR FROM o.created_at) FROM orders o",
+			want:  []schemaRef{},
+		},
+		{
+			name:  "show schemas needs only the catalog",
+			query: "SHOW SCHEMAS FROM hive",
+			want:  []schemaRef{{"hive", ""}},
+		},
+		{
+			name:  "show tables",
+			query: "SHOW TABLES FROM hive.sales; SHOW TABLES IN hr",
+			want:  []schemaRef{{"hive", "sales"}, {"memory", "hr"}},
+		},
+		{
+			name:  "use",
+			query: `USE "hive"."sales"`,
+			want:  []schemaRef{{"hive", "sales"}},
+		},
+		{
+			name:  "every entry of a FROM list",
+			query: "SELECT * FROM finance.a, hr.salaries",
+			want:  []schemaRef{{"memory", "finance"}, {"memory", "hr"}},
+		},
+		{
+			name:  "FROM list after a join condition",
+			query: "SELECT * FROM finance.a JOIN finance.b ON a.id = b.id, hr.salaries WHERE a.x IN (1, 2)",
+			want:  []schemaRef{{"memory", "finance"}, {"memory", "hr"}},
+		},
+		{
+			name:  "parenthesized FROM item",
+			query: "SELECT * FROM (hr.salaries)",
+			want:  []schemaRef{{"memory", "hr"}},
+		},
+		{
+			name:  "parenthesized join",
+			query: "SELECT * FROM ((finance.a JOIN hr.salaries ON a.id = salaries.id))",
+			want:  []schemaRef{{"memory", "finance"}, {"memory", "hr"}},
+		},
+		{
+			name:  "FROM list after a subquery",
+			query: "SELECT * FROM (SELECT o.id, o.name FROM orders o) s, hr.salaries",
+			want:  []schemaRef{{"memory", "hr"}},
+		},
+		{
+			name:  "describe",
+			query: "DESCRIBE hr.salaries",
+			want:  []schemaRef{{"memory", "hr"}},
+		},
+		{
+			name:  "table statement",
+			query: "TABLE hr.salaries",
+			want:  []schemaRef{{"memory", "hr"}},
+		},
+		{
+			name:  "show columns",
+			query: "SHOW COLUMNS FROM hr.salaries; SHOW COLUMNS IN finance.a",
+			want:  []schemaRef{{"memory", "hr"}, {"memory", "finance"}},
+		},
+		{
+			name:  "IS DISTINCT FROM and select-list commas are not references",
+			query: "SELECT a.x, b.y FROM t a JOIN u b ON a.x IS DISTINCT FROM b.y ORDER BY a.x, b.y",
+			want:  []schemaRef{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractReferencedSchemas(tt.query, "memory")
+			if err != nil {
+				t.Fatalf("extractReferencedSchemas() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("extractReferencedSchemas() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckSchemaAccess(t *testing.T) {
+	allowed := []models.SchemaPermission{
+		{Catalog: "memory", Schema: models.AllSchemas},
+		{Catalog: "hive", Schema: "sales"},
+	}
+
+	tests := []struct {
+		name string
+		refs []schemaRef
+		want error // nil, or the expected *CatalogAccessError
+	}{
+		{"catalog grant covers every schema", []schemaRef{{"memory", "anything"}}, nil},
+		{"granted schema", []schemaRef{{"hive", "sales"}}, nil},
+		{"other schema of a schema-granted catalog", []schemaRef{{"hive", "hr"}}, &CatalogAccessError{Catalog: "hive", Schema: "hr"}},
+		{"catalog-only ref with a schema grant", []schemaRef{{"hive", ""}}, nil},
+		{"ungranted catalog", []schemaRef{{"secret", "x"}}, &CatalogAccessError{Catalog: "secret"}},
+		{"first denied ref is reported", []schemaRef{{"hive", "sales"}, {"postgres", "b"}, {"hive", "hr"}}, &CatalogAccessError{Catalog: "postgres"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSchemaAccess(allowed, tt.refs)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("checkSchemaAccess() error = %v, want nil", err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(err, tt.want) {
+				t.Fatalf("checkSchemaAccess() error = %#v, want %#v", err, tt.want)
+			}
+			if !errors.Is(err, ErrCatalogAccessDenied) {
+				t.Fatalf("checkSchemaAccess() error = %v, want it to match ErrCatalogAccessDenied", err)
+			}
+		})
+	}
+
+	if err := checkSchemaAccess(nil, []schemaRef{{"secret", "x"}}); err != nil {
+		t.Fatalf("checkSchemaAccess(nil) error = %v, want nil for admin", err)
+	}
+}
+
+func TestExtractReferencedSchemas_Lexing(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []schemaRef
+	}{
+		{
+			name:  "comments cannot hide a qualified name",
+			query: "SELECT * FROM hive/* x */./* y */a.t",
+			want:  []schemaRef{{"hive", "a"}},
+		},
+		{
+			name:  "line comment between parts",
+			query: "SELECT * FROM hive --\n.a.t",
+			want:  []schemaRef{{"hive", "a"}},
+		},
+		{
+			name:  "references in comments and string literals are ignored",
+			query: "SELECT 'postgres.b.u' AS s -- secret.x.y\nFROM t",
+			want:  []schemaRef{},
+		},
+		{
+			name:  "unquoted identifiers are case-insensitive",
+			query: "SELECT * FROM HIVE.Sales.orders",
+			want:  []schemaRef{{"hive", "sales"}},
+		},
+		{
+			name:  "quoted identifiers are compared in lower case, as Trino does",
+			query: `SELECT * FROM "Hive"."SALES".orders`,
+			want:  []schemaRef{{"hive", "sales"}},
+		},
+		{
+			name:  "quoted identifier with an escaped quote",
+			query: `SELECT * FROM "we""ird".s.t`,
+			want:  []schemaRef{{`we"ird`, "s"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractReferencedSchemas(tt.query, "")
+			if err != nil {
+				t.Fatalf("extractReferencedSchemas() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("extractReferencedSchemas() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractReferencedSchemas_Unclassified(t *testing.T) {
+	for _, query := range []string{
+		"SELECT * FROM hr.salaries.x.y",
+		"SELECT * FROM hr.",
+		"SELECT * FROM finance.a, 'hr.salaries'",
+		"SELECT * FROM hr.salaries WHERE name = 'unterminated",
+	} {
+		if _, err := extractReferencedSchemas(query, "memory"); !errors.Is(err, services.ErrUnclassifiedTableReference) {
+			t.Errorf("extractReferencedSchemas(%q) error = %v, want ErrUnclassifiedTableReference", query, err)
+		}
+	}
+}
+
+func TestEnforceCatalogAccess_DeniesBypasses(t *testing.T) {
+	userID := uuid.New()
+	roleRepo := repository.NewMockRoleRepository()
+	roleID := uuid.New()
+	roleRepo.UserRoles[userID] = []uuid.UUID{roleID}
+	roleRepo.RoleSchemas[roleID] = []models.SchemaPermission{{Catalog: "hive", Schema: "finance"}}
+	roleService := services.NewRoleService(roleRepo)
+
+	for _, query := range []string{
+		"SELECT * FROM finance.a, hr.salaries",
+		"DESCRIBE hr.salaries",
+		"TABLE hr.salaries",
+		"SELECT * FROM (hr.salaries)",
+		"SHOW COLUMNS FROM hr.salaries",
+		"SELECT * FROM finance.a, hr.",
+	} {
+		err := enforceCatalogAccess(context.Background(), roleService, userID, query, "hive", "finance")
+		if !errors.Is(err, ErrCatalogAccessDenied) {
+			t.Errorf("enforceCatalogAccess(%q) error = %v, want %v", query, err, ErrCatalogAccessDenied)
+		}
+	}
+
+	if err := enforceCatalogAccess(context.Background(), roleService, userID, "SELECT * FROM finance.a, (finance.b)", "hive", "finance"); err != nil {
+		t.Fatalf("enforceCatalogAccess() error = %v, want nil", err)
+	}
+}
+
+func TestEnforceCatalogAccess_NamesDeniedCatalog(t *testing.T) {
+	userID := uuid.New()
+	roleRepo := repository.NewMockRoleRepository()
+	roleRepo.AllowedCatalogs[userID] = []string{"hive"}
+	roleService := services.NewRoleService(roleRepo)
+
+	err := enforceCatalogAccess(context.Background(), roleService, userID,
+		"SELECT * FROM hive.a.t JOIN postgres.b.u ON t.id = u.id", "hive", "a")
+	if !errors.Is(err, ErrCatalogAccessDenied) {
+		t.Fatalf("enforceCatalogAccess() error = %v, want %v", err, ErrCatalogAccessDenied)
+	}
+	if err.Error() != "access denied to catalog: postgres" {
+		t.Fatalf("enforceCatalogAccess() error = %q, want it to name postgres", err.Error())
+	}
+
+	if err := enforceCatalogAccess(context.Background(), roleService, userID, "SELECT * FROM hive.a.t", "hive", "a"); err != nil {
+		t.Fatalf("enforceCatalogAccess() error = %v, want nil", err)
+	}
+}
@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -86,7 +90,10 @@ func (h *SubscriptionHandler) CreateSubscription(c *gin.Context) {
 
 	subscription, err := h.subscriptionService.CreateSubscription(c.Request.Context(), userID.(uuid.UUID), &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if respondActiveLimitError(c, err) {
+			return
+		}
+		respondValidationError(c, err)
 		return
 	}
 
@@ -115,13 +122,71 @@ func (h *SubscriptionHandler) UpdateSubscription(c *gin.Context) {
 
 	subscription, err := h.subscriptionService.UpdateSubscription(c.Request.Context(), subID, userID.(uuid.UUID), &req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if respondActiveLimitError(c, err) {
+			return
+		}
+		respondValidationError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, subscription)
 }
 
+// ValidateCron checks a cron expression and timezone and previews the next runs. With
+// "seconds": true the expression has a leading seconds field. Invalid input responds 400 with
+// "valid": false and the offending field.
+// POST /subscriptions/validate-cron
+func (h *SubscriptionHandler) ValidateCron(c *gin.Context) {
+	var req models.ValidateCronRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preview := h.subscriptionService.PreviewSchedule
+	if req.Seconds {
+		preview = h.subscriptionService.PreviewScheduleWithSeconds
+	}
+	result, err := preview(req.Cron, req.Timezone, time.Now())
+	if err != nil {
+		respondInvalidSchedule(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// PreviewSchedule checks a subscription's schedule, given as when creating one (schedule_cron or
+// schedule_interval_minutes, and timezone), and previews its next runs. Invalid input responds
+// 400 as ValidateCron.
+// POST /subscriptions/preview-schedule
+func (h *SubscriptionHandler) PreviewSchedule(c *gin.Context) {
+	var req models.PreviewScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.subscriptionService.PreviewSubscriptionSchedule(req.ScheduleCron, req.ScheduleIntervalMinutes, req.Timezone, time.Now())
+	if err != nil {
+		respondInvalidSchedule(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// respondInvalidSchedule responds 400 with "valid": false and, for a validation error, the
+// offending field
+func respondInvalidSchedule(c *gin.Context, err error) {
+	var validationErr *models.ValidationError
+	if errors.As(err, &validationErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"valid": false, "error": validationErr.Message, "field": validationErr.Field})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"valid": false, "error": err.Error()})
+}
+
 // DeleteSubscription deletes a subscription
 func (h *SubscriptionHandler) DeleteSubscription(c *gin.Context) {
 	userID, exists := c.Get("userID")
@@ -165,3 +230,96 @@ func (h *SubscriptionHandler) TriggerSubscription(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Subscription triggered successfully"})
 }
+
+// GetSubscriptionHistory returns the recent runs of a subscription with each channel's outcome
+// GET /subscriptions/:id/history
+func (h *SubscriptionHandler) GetSubscriptionHistory(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	subID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		return
+	}
+
+	// Verify ownership
+	subscription, err := h.subscriptionService.GetSubscriptionByID(c.Request.Context(), subID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+
+	if subscription.UserID != userID.(uuid.UUID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not authorized"})
+		return
+	}
+
+	// Get limit from query params, default to 50
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	history, err := h.subscriptionService.GetSubscriptionHistory(c.Request.Context(), subID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if history == nil {
+		history = []models.SubscriptionHistory{}
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// PauseSubscription stops a subscription from running until it is resumed
+// POST /subscriptions/:id/pause
+func (h *SubscriptionHandler) PauseSubscription(c *gin.Context) {
+	h.changeSubscription(c, h.subscriptionService.PauseSubscription)
+}
+
+// ResumeSubscription reactivates a paused subscription from its next scheduled time
+// POST /subscriptions/:id/resume
+func (h *SubscriptionHandler) ResumeSubscription(c *gin.Context) {
+	h.changeSubscription(c, h.subscriptionService.ResumeSubscription)
+}
+
+// SkipNextRun moves a subscription's next run to the following scheduled time without sending
+// POST /subscriptions/:id/skip-next
+func (h *SubscriptionHandler) SkipNextRun(c *gin.Context) {
+	h.changeSubscription(c, h.subscriptionService.SkipNextRun)
+}
+
+// changeSubscription applies change to the subscription in the path for the authenticated user
+// and responds with the updated subscription
+func (h *SubscriptionHandler) changeSubscription(c *gin.Context, change func(ctx context.Context, id, userID uuid.UUID) (*models.DashboardSubscription, error)) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	subID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subscription ID"})
+		return
+	}
+
+	subscription, err := change(c.Request.Context(), subID, userID.(uuid.UUID))
+	if err != nil {
+		if respondActiveLimitError(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, subscription)
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/services"
+)
+
+func TestValidateCron(t *testing.T) {
+	handler := NewSubscriptionHandler(services.NewSubscriptionService(nil, nil, nil))
+
+	c, w := createTestContext("POST", "/api/subscriptions/validate-cron", models.ValidateCronRequest{Cron: "0 9 * * 1-5", Timezone: "UTC"})
+	handler.ValidateCron(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("ValidateCron() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var preview models.SchedulePreview
+	if err := json.Unmarshal(w.Body.Bytes(), &preview); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(preview.NextRuns) != 5 || preview.Description != "At 09:00, Monday through Friday" {
+		t.Errorf("ValidateCron() = %+v, want 5 runs and a description", preview)
+	}
+
+	c, w = createTestContext("POST", "/api/subscriptions/validate-cron", models.ValidateCronRequest{Cron: "0 9 * * *", Timezone: "Asia/Nowhere"})
+	handler.ValidateCron(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("ValidateCron() with unknown timezone status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	var body map[string]string
+	_ = json.Unmarshal(w.Body.Bytes(), &body)
+	if body["field"] != "timezone" {
+		t.Errorf("ValidateCron() error field = %q, want timezone", body["field"])
+	}
+}
+
+func TestValidateCron_InvalidExpression(t *testing.T) {
+	handler := NewSubscriptionHandler(services.NewSubscriptionService(nil, nil, nil))
+
+	c, w := createTestContext("POST", "/api/subscriptions/validate-cron", models.ValidateCronRequest{Cron: "0 25 * * *", Timezone: "UTC"})
+	handler.ValidateCron(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("ValidateCron() status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if body["valid"] != false || body["field"] != "schedule_cron" || body["error"] == "" {
+		t.Errorf("ValidateCron() body = %v, want valid=false with an error on schedule_cron", body)
+	}
+}
+
+func TestValidateCron_Seconds(t *testing.T) {
+	handler := NewSubscriptionHandler(services.NewSubscriptionService(nil, nil, nil))
+
+	c, w := createTestContext("POST", "/api/subscriptions/validate-cron", models.ValidateCronRequest{Cron: "30 0 9 * * *", Timezone: "UTC", Seconds: true})
+	handler.ValidateCron(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("ValidateCron() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var preview models.SchedulePreview
+	if err := json.Unmarshal(w.Body.Bytes(), &preview); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if !preview.Valid || len(preview.NextRuns) != 5 || preview.NextRuns[0].Second() != 30 {
+		t.Errorf("ValidateCron() = %+v, want 5 runs at second 30", preview)
+	}
+
+	// Without the flag the same expression has one field too many
+	c, w = createTestContext("POST", "/api/subscriptions/validate-cron", models.ValidateCronRequest{Cron: "30 0 9 * * *", Timezone: "UTC"})
+	handler.ValidateCron(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("ValidateCron() without seconds status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestPreviewSchedule(t *testing.T) {
+	handler := NewSubscriptionHandler(services.NewSubscriptionService(nil, nil, nil))
+
+	c, w := createTestContext("POST", "/api/subscriptions/preview-schedule", models.PreviewScheduleRequest{ScheduleCron: "0 9 * * *", Timezone: "Asia/Tokyo"})
+	handler.PreviewSchedule(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PreviewSchedule() status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var preview models.SchedulePreview
+	if err := json.Unmarshal(w.Body.Bytes(), &preview); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if !preview.Valid || len(preview.NextRuns) != 5 || preview.Description != "At 09:00" {
+		t.Fatalf("PreviewSchedule() = %+v, want 5 runs at 09:00", preview)
+	}
+	for i, run := range preview.NextRuns {
+		if _, offset := run.Zone(); run.Hour() != 9 || offset != 9*60*60 {
+			t.Errorf("PreviewSchedule() run %d = %v, want 09:00 in Asia/Tokyo", i, run)
+		}
+		if i > 0 && run.Sub(preview.NextRuns[i-1]) != 24*time.Hour {
+			t.Errorf("PreviewSchedule() run %d = %v, want a day after %v", i, run, preview.NextRuns[i-1])
+		}
+	}
+
+	interval := 360
+	c, w = createTestContext("POST", "/api/subscriptions/preview-schedule", models.PreviewScheduleRequest{ScheduleIntervalMinutes: &interval, Timezone: "UTC"})
+	handler.PreviewSchedule(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PreviewSchedule() with interval status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	preview = models.SchedulePreview{}
+	if err := json.Unmarshal(w.Body.Bytes(), &preview); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if len(preview.NextRuns) != 5 || preview.Description != "Every 6 hours" || preview.NextRuns[1].Sub(preview.NextRuns[0]) != 6*time.Hour {
+		t.Errorf("PreviewSchedule() with interval = %+v, want runs 6 hours apart", preview)
+	}
+}
+
+func TestPreviewSchedule_InvalidInput(t *testing.T) {
+	handler := NewSubscriptionHandler(services.NewSubscriptionService(nil, nil, nil))
+	interval := 60
+
+	tests := []struct {
+		name      string
+		req       models.PreviewScheduleRequest
+		wantField string
+	}{
+		{"bad cron", models.PreviewScheduleRequest{ScheduleCron: "0 25 * * *", Timezone: "UTC"}, "schedule_cron"},
+		{"unknown timezone", models.PreviewScheduleRequest{ScheduleCron: "0 9 * * *", Timezone: "Asia/Nowhere"}, "timezone"},
+		{"no schedule", models.PreviewScheduleRequest{Timezone: "UTC"}, "schedule_cron"},
+		{"both schedules", models.PreviewScheduleRequest{ScheduleCron: "0 9 * * *", ScheduleIntervalMinutes: &interval}, "schedule_interval_minutes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := createTestContext("POST", "/api/subscriptions/preview-schedule", tt.req)
+			handler.PreviewSchedule(c)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("PreviewSchedule() status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if body["valid"] != false || body["field"] != tt.wantField || body["error"] == "" {
+				t.Errorf("PreviewSchedule() body = %v, want valid=false with an error on %s", body, tt.wantField)
+			}
+		})
+	}
+}
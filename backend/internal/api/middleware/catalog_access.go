@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/services"
+)
+
+// CatalogAccessMiddleware rejects requests whose :catalog path parameter names a catalog
+// the user is not allowed to access, or whose :schema path parameter (when present) names a
+// schema of that catalog the user is not allowed to access. It must run after AuthMiddleware.
+func CatalogAccessMiddleware(roleService *services.RoleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		catalog := c.Param("catalog")
+		if catalog == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "catalog is required"})
+			c.Abort()
+			return
+		}
+
+		userIDRaw, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
+			c.Abort()
+			return
+		}
+
+		userID, ok := userIDRaw.(uuid.UUID)
+		if !ok {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
+			c.Abort()
+			return
+		}
+
+		hasAccess, err := roleService.CanUserAccessCatalog(c.Request.Context(), userID, catalog)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check catalog access"})
+			c.Abort()
+			return
+		}
+
+		if !hasAccess {
+			c.JSON(http.StatusForbidden, gin.H{"error": "access denied to catalog"})
+			c.Abort()
+			return
+		}
+
+		if schema := c.Param("schema"); schema != "" {
+			hasAccess, err := roleService.CanUserAccessSchema(c.Request.Context(), userID, catalog, schema)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check schema access"})
+				c.Abort()
+				return
+			}
+
+			if !hasAccess {
+				c.JSON(http.StatusForbidden, gin.H{"error": "access denied to schema"})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/repository"
+	"github.com/mitsume/backend/internal/services"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func setupCatalogAccessRouter(userID uuid.UUID, roleRepo *repository.MockRoleRepository, handlerCalled *bool) *gin.Engine {
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", userID)
+		c.Next()
+	})
+	catalogs := r.Group("/catalogs/:catalog")
+	catalogs.Use(CatalogAccessMiddleware(services.NewRoleService(roleRepo)))
+	catalogs.GET("/schemas", func(c *gin.Context) {
+		*handlerCalled = true
+		c.Status(http.StatusOK)
+	})
+	catalogs.GET("/schemas/:schema/tables", func(c *gin.Context) {
+		*handlerCalled = true
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func TestCatalogAccessMiddleware_Denied(t *testing.T) {
+	userID := uuid.New()
+	roleRepo := repository.NewMockRoleRepository()
+	roleRepo.AllowedCatalogs[userID] = []string{"memory"}
+
+	handlerCalled := false
+	r := setupCatalogAccessRouter(userID, roleRepo, &handlerCalled)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/catalogs/secret/schemas", nil))
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if handlerCalled {
+		t.Fatal("handler ran for a disallowed catalog")
+	}
+}
+
+func TestCatalogAccessMiddleware_Allowed(t *testing.T) {
+	userID := uuid.New()
+	roleRepo := repository.NewMockRoleRepository()
+	roleRepo.AllowedCatalogs[userID] = []string{"memory"}
+
+	handlerCalled := false
+	r := setupCatalogAccessRouter(userID, roleRepo, &handlerCalled)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/catalogs/memory/schemas", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if !handlerCalled {
+		t.Fatal("handler did not run for an allowed catalog")
+	}
+}
+
+func TestCatalogAccessMiddleware_Admin(t *testing.T) {
+	userID := uuid.New()
+	roleRepo := repository.NewMockRoleRepository()
+	roleRepo.AdminUsers[userID] = true
+
+	handlerCalled := false
+	r := setupCatalogAccessRouter(userID, roleRepo, &handlerCalled)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/catalogs/secret/schemas", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestCatalogAccessMiddleware_Schema(t *testing.T) {
+	userID := uuid.New()
+	roleRepo := repository.NewMockRoleRepository()
+	role, _ := roleRepo.Create(context.Background(), "sales", "")
+	roleRepo.RoleSchemas[role.ID] = []models.SchemaPermission{{Catalog: "hive", Schema: "sales"}}
+	roleRepo.UserRoles[userID] = []uuid.UUID{role.ID}
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/catalogs/hive/schemas", http.StatusOK},
+		{"/catalogs/hive/schemas/sales/tables", http.StatusOK},
+		{"/catalogs/hive/schemas/hr/tables", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		handlerCalled := false
+		r := setupCatalogAccessRouter(userID, roleRepo, &handlerCalled)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if w.Code != tt.want {
+			t.Errorf("GET %s status = %d, want %d", tt.path, w.Code, tt.want)
+		}
+		if handlerCalled != (tt.want == http.StatusOK) {
+			t.Errorf("GET %s handlerCalled = %v", tt.path, handlerCalled)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mitsume/backend/internal/services"
+)
+
+// Maintenance rejects requests with 503 while maintenance mode is on. Put it on routes that
+// run queries against Trino; everything else keeps working during maintenance.
+func Maintenance(checker services.MaintenanceChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if active, message := checker.InMaintenance(c.Request.Context()); active {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": message, "maintenance": true})
+			return
+		}
+		c.Next()
+	}
+}
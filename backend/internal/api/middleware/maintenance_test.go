@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type fakeMaintenance struct {
+	active  bool
+	message string
+}
+
+func (f *fakeMaintenance) InMaintenance(ctx context.Context) (bool, string) {
+	return f.active, f.message
+}
+
+func TestMaintenance(t *testing.T) {
+	checker := &fakeMaintenance{}
+	r := gin.New()
+	r.POST("/execute", Maintenance(checker), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/execute", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	checker.active = true
+	checker.message = "Trino upgrade until 10:00"
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/execute", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	var body struct {
+		Error       string `json:"error"`
+		Maintenance bool   `json:"maintenance"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if body.Error != checker.message || !body.Maintenance {
+		t.Fatalf("body = %+v, want the maintenance message", body)
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func getMetrics(token, authorization string) int {
+	r := gin.New()
+	r.GET("/metrics", MetricsAuth(token), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestMetricsAuth(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		want          int
+	}{
+		{"no token configured", "", "", http.StatusOK},
+		{"matching token", "s3cret", "Bearer s3cret", http.StatusOK},
+		{"missing header", "s3cret", "", http.StatusUnauthorized},
+		{"wrong token", "s3cret", "Bearer other", http.StatusUnauthorized},
+		{"token without scheme", "s3cret", "s3cret", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getMetrics(tt.token, tt.authorization); got != tt.want {
+				t.Fatalf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
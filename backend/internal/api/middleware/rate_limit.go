@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/services"
+)
+
+// RateLimitKeyFunc returns the identity a request is limited by; an empty key skips limiting
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// ClientIPKey limits by client IP (honoring TRUSTED_PROXIES)
+func ClientIPKey(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// UserIDKey limits by the authenticated user; it must run after AuthMiddleware
+func UserIDKey(c *gin.Context) string {
+	userID, ok := c.Get("userID")
+	if !ok {
+		return ""
+	}
+	id, ok := userID.(uuid.UUID)
+	if !ok {
+		return ""
+	}
+	return id.String()
+}
+
+// RateLimit rejects requests over limit with 429 and a Retry-After header.
+// Buckets are named "<name>:<key>", so each limited route group has its own budget.
+// Limiter errors are logged and the request is let through.
+func RateLimit(limiter services.RateLimiter, name string, limit services.RateLimit, keyFunc RateLimitKeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := keyFunc(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		allowed, retryAfter, err := limiter.Allow(c.Request.Context(), name+":"+key, limit)
+		if err != nil {
+			log.Printf("Rate limiter error for %s: %v", name, err)
+			c.Next()
+			return
+		}
+		if !allowed {
+			AbortRateLimited(c, retryAfter)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// AbortRateLimited responds 429 with a Retry-After header of at least one second
+func AbortRateLimited(c *gin.Context, retryAfter time.Duration) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+}
+
+// ConcurrencyLimitFunc returns how many requests the identity may run at once (0 or less disables the limit)
+type ConcurrencyLimitFunc func(c *gin.Context) int
+
+// ConcurrencyLimit rejects a request with 429 while the identity already has limit requests in
+// flight. Slots are named "<name>:<key>" and held until the handler returns.
+// Limiter errors are logged and the request is let through.
+func ConcurrencyLimit(limiter services.ConcurrencyLimiter, name string, limitFunc ConcurrencyLimitFunc, keyFunc RateLimitKeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := keyFunc(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		release, acquired, err := limiter.Acquire(c.Request.Context(), name+":"+key, limitFunc(c))
+		if err != nil {
+			log.Printf("Concurrency limiter error for %s: %v", name, err)
+			c.Next()
+			return
+		}
+		if !acquired {
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many concurrent " + name + " requests; wait for one to finish"})
+			return
+		}
+		defer release()
+
+		c.Next()
+	}
+}
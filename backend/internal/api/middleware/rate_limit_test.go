@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/services"
+)
+
+func setupRateLimitRouter(limit services.RateLimit, keyFunc RateLimitKeyFunc, userID *uuid.UUID) *gin.Engine {
+	r := gin.New()
+	if userID != nil {
+		r.Use(func(c *gin.Context) {
+			c.Set("userID", *userID)
+			c.Next()
+		})
+	}
+	r.POST("/limited", RateLimit(services.NewMemoryRateLimiter(), "test", limit, keyFunc), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func postLimited(r *gin.Engine, remoteAddr string) *httptest.ResponseRecorder {
+	return postLimitedForwarded(r, remoteAddr, "")
+}
+
+func postLimitedForwarded(r *gin.Engine, remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/limited", nil)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimit_ClientIPExceeded(t *testing.T) {
+	r := setupRateLimitRouter(services.RateLimit{PerMinute: 1, Burst: 2}, ClientIPKey, nil)
+
+	for i := 0; i < 2; i++ {
+		if w := postLimited(r, "192.0.2.1:1234"); w.Code != http.StatusOK {
+			t.Fatalf("request %d status = %d, want %d", i+1, w.Code, http.StatusOK)
+		}
+	}
+
+	w := postLimited(r, "192.0.2.1:1234")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if got := w.Header().Get("Retry-After"); got == "" || got == "0" {
+		t.Fatalf("Retry-After = %q, want a positive number of seconds", got)
+	}
+
+	// Another client has its own bucket
+	if w := postLimited(r, "192.0.2.2:1234"); w.Code != http.StatusOK {
+		t.Fatalf("other client status = %d, want %d", w.Code, http.StatusOK)
+	}
+}
+
+func TestRateLimit_UserID(t *testing.T) {
+	userID := uuid.New()
+	r := setupRateLimitRouter(services.RateLimit{PerMinute: 1, Burst: 1}, UserIDKey, &userID)
+
+	if w := postLimited(r, "192.0.2.1:1234"); w.Code != http.StatusOK {
+		t.Fatalf("first request status = %d, want %d", w.Code, http.StatusOK)
+	}
+	// Same user from a different IP shares the bucket
+	if w := postLimited(r, "192.0.2.2:1234"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+}
+
+func TestRateLimit_ZeroLimitDisabled(t *testing.T) {
+	r := setupRateLimitRouter(services.RateLimit{}, ClientIPKey, nil)
+
+	for i := 0; i < 20; i++ {
+		if w := postLimited(r, "192.0.2.1:1234"); w.Code != http.StatusOK {
+			t.Fatalf("request %d status = %d, want %d", i+1, w.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestRateLimit_ClientIPRespectsTrustedProxies(t *testing.T) {
+	r := setupRateLimitRouter(services.RateLimit{PerMinute: 1, Burst: 1}, ClientIPKey, nil)
+	if err := r.SetTrustedProxies([]string{"10.0.0.1"}); err != nil {
+		t.Fatalf("SetTrustedProxies() error = %v", err)
+	}
+
+	// Behind the trusted proxy, each forwarded client has its own bucket
+	if w := postLimitedForwarded(r, "10.0.0.1:1234", "198.51.100.1"); w.Code != http.StatusOK {
+		t.Fatalf("client 1 status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := postLimitedForwarded(r, "10.0.0.1:1234", "198.51.100.2"); w.Code != http.StatusOK {
+		t.Fatalf("client 2 status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	// An untrusted peer cannot escape its bucket by spoofing X-Forwarded-For
+	if w := postLimitedForwarded(r, "192.0.2.9:1234", "198.51.100.3"); w.Code != http.StatusOK {
+		t.Fatalf("untrusted peer first status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := postLimitedForwarded(r, "192.0.2.9:1234", "198.51.100.4"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("untrusted peer spoofed status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+}
+
+func TestRateLimit_SeparateBucketsPerName(t *testing.T) {
+	limiter := services.NewMemoryRateLimiter()
+	limit := services.RateLimit{PerMinute: 1, Burst: 1}
+
+	r := gin.New()
+	r.POST("/execute", RateLimit(limiter, "query", limit, ClientIPKey), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/export", RateLimit(limiter, "export", limit, ClientIPKey), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/execute", "/export"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s status = %d, want %d", path, w.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestConcurrencyLimit_RejectsWhileSlotsAreHeld(t *testing.T) {
+	userID := uuid.New()
+	entered := make(chan struct{})
+	finish := make(chan struct{})
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", userID)
+		c.Next()
+	})
+	limit := func(c *gin.Context) int { return 1 }
+	r.POST("/limited", ConcurrencyLimit(services.NewMemoryConcurrencyLimiter(), "export", limit, UserIDKey), func(c *gin.Context) {
+		if c.Query("block") != "" {
+			entered <- struct{}{}
+			<-finish
+		}
+		c.Status(http.StatusOK)
+	})
+
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/limited?block=1", nil))
+		done <- w.Code
+	}()
+	<-entered
+
+	if w := postLimited(r, "192.0.2.1:1234"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status while a request is in flight = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+
+	close(finish)
+	if code := <-done; code != http.StatusOK {
+		t.Fatalf("blocked request status = %d, want %d", code, http.StatusOK)
+	}
+	if w := postLimited(r, "192.0.2.1:1234"); w.Code != http.StatusOK {
+		t.Fatalf("status after the slot was released = %d, want %d", w.Code, http.StatusOK)
+	}
+}
@@ -1,11 +1,16 @@
 package api
 
 import (
+	"log"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/mitsume/backend/internal/api/handlers"
 	"github.com/mitsume/backend/internal/api/middleware"
 	"github.com/mitsume/backend/internal/config"
 	"github.com/mitsume/backend/internal/database"
+	"github.com/mitsume/backend/internal/metrics"
 	"github.com/mitsume/backend/internal/repository"
 	"github.com/mitsume/backend/internal/services"
 )
@@ -15,32 +20,113 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, cacheService *services.Query
 	userRepo := repository.NewPostgresUserRepository(database.GetPool())
 	roleRepo := repository.NewPostgresRoleRepository(database.GetPool())
 	layoutTemplateRepo := repository.NewPostgresLayoutTemplateRepository(database.GetPool())
+	chartThemeRepo := repository.NewPostgresChartThemeRepository(database.GetPool())
+	refreshTokenRepo := repository.NewPostgresRefreshTokenRepository(database.GetPool())
 
 	// Services
-	authService := services.NewAuthService(cfg, userRepo, roleRepo)
+	authService := services.NewAuthService(cfg, userRepo, roleRepo, refreshTokenRepo)
+	authService.SetStateCache(cacheService)
+	authService.SetSessionTracker(services.NewSessionTracker(cacheService))
+	if cfg.Admin.NotifyOnApproval {
+		if cfg.Notification.SMTP.Host == "" {
+			log.Printf("[WARN] MITSUME_ADMIN_NOTIFY_ON_APPROVAL is set but SMTP_HOST is not; account status emails will fail")
+		}
+		authService.SetAccountMailer(services.NewEmailNotifier(&cfg.Notification.SMTP))
+	}
 	trinoService := services.NewTrinoService(&cfg.Trino)
 	cachedTrinoService := services.NewCachedTrinoService(trinoService, cacheService, &cfg.Cache)
 	queryService := services.NewQueryService(cacheService)
+	queryService.SetReadOnlyMode(cfg.Trino.ReadOnlyMode)
+	queryService.SetSlowQueryThreshold(cfg.Trino.SlowQueryThresholdMs)
 	dashboardService := services.NewDashboardService()
+	dashboardService.SetUniqueNames(cfg.Dashboard.UniqueNames)
+	dashboardService.SetMaxVersions(cfg.Dashboard.MaxVersions)
+	dashboardService.SetOwnerOnlyParameters(cfg.Dashboard.OwnerOnlyParameters)
+	dashboardService.SetStatementChecker(queryService)
 	notificationService := services.NewNotificationService(database.GetPool(), &cfg.Notification)
 	alertService := services.NewAlertService(database.GetPool(), cachedTrinoService, notificationService, queryService)
 	subscriptionService := services.NewSubscriptionService(database.GetPool(), notificationService, dashboardService)
 	roleService := services.NewRoleService(roleRepo)
+	alertService.SetActiveLimit(cfg.Limits.MaxActiveAlertsPerUser, roleService)
+	alertService.SetRowFilters(roleService)
+	subscriptionService.SetActiveLimit(cfg.Limits.MaxActiveSubscriptionsPerUser, roleService)
+	subscriptionService.SetFailureLimit(cfg.Limits.MaxSubscriptionFailures)
+	dashboardService.SetRevealForbidden(cfg.Dashboard.RevealForbidden, roleService)
+	callbackService := services.NewCallbackService(&cfg.Webhook)
+	queryJobService := services.NewQueryJobService(cachedTrinoService, queryService, callbackService)
+	queryJobService.SetMaxJobsPerUser(cfg.Limits.MaxQueryJobsPerUser)
+	widgetHealthService := services.NewWidgetHealthService(database.GetPool(), notificationService)
+	annotationService := services.NewAnnotationService(database.GetPool(), dashboardService)
+	searchService := services.NewSearchService(dashboardService, queryService, cachedTrinoService, roleService)
+	auditService := services.NewAuditService(database.GetPool())
+	maintenanceService := services.NewMaintenanceService(database.GetPool())
+	rateLimiter := services.NewRateLimiter(cacheService)
 
 	// Handlers
-	authHandler := handlers.NewAuthHandler(authService, cfg)
-	queryHandler := handlers.NewQueryHandler(cachedTrinoService, queryService, roleService, cfg.Trino.Catalog, cfg.Trino.Schema)
-	savedQueryHandler := handlers.NewSavedQueryHandler(queryService)
-	dashboardHandler := handlers.NewDashboardHandler(dashboardService, cachedTrinoService, queryService, roleService, cfg.Trino.Catalog, cfg.Trino.Schema)
-	exportHandler := handlers.NewExportHandler(trinoService, roleService, cfg.Trino.Catalog, cfg.Trino.Schema) // Export uses non-cached version
+	authHandler := handlers.NewAuthHandler(authService, cfg, auditService)
+	queryHandler := handlers.NewQueryHandler(cachedTrinoService, queryService, roleService, cfg.Trino.Catalog, cfg.Trino.Schema, cfg.Trino.ReadOnlyMode)
+	if cfg.Trino.ResultIdleMinutes > 0 {
+		queryHandler.SetResultPager(services.NewResultPager(services.NewResultStore(cacheService), time.Duration(cfg.Trino.ResultIdleMinutes)*time.Minute))
+	}
+	queryJobHandler := handlers.NewQueryJobHandler(queryJobService, roleService, cfg.Trino.Catalog, cfg.Trino.Schema, cfg.Trino.ReadOnlyMode)
+	queryTimeouts := services.NewQueryTimeoutPolicy(&cfg.Trino)
+	queryHandler.SetQueryTimeouts(queryTimeouts)
+	queryJobHandler.SetQueryTimeouts(queryTimeouts)
+	savedQueryHandler := handlers.NewSavedQueryHandler(queryService, roleService, cfg.Trino.Catalog, cfg.Trino.Schema)
+	dashboardHandler := handlers.NewDashboardHandler(dashboardService, cachedTrinoService, queryService, roleService, cfg.Trino.Catalog, cfg.Trino.Schema, cfg.Dashboard.AllowedChartTypes, cfg.Dashboard.MaxParameters, widgetHealthService, annotationService, auditService)
+	dashboardHandler.SetRenderConcurrency(cfg.Dashboard.RenderConcurrency)
+	dashboardHandler.SetParameterTerms(cfg.Dashboard.ParameterDenylist, cfg.Dashboard.ParameterAllowlist)
+	exportHandler := handlers.NewExportHandler(trinoService, queryService, roleService, cfg.Trino.Catalog, cfg.Trino.Schema) // Export uses non-cached version
 	notificationHandler := handlers.NewNotificationHandler(notificationService)
-	alertHandler := handlers.NewAlertHandler(alertService, notificationService)
+	alertHandler := handlers.NewAlertHandler(alertService, notificationService, queryService, roleService, cfg.Trino.Catalog, cfg.Trino.Schema)
 	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService)
-	roleHandler := handlers.NewRoleHandler(roleService, trinoService) // Role handler uses non-cached version for catalog listing
+	annotationHandler := handlers.NewAnnotationHandler(annotationService)
+	roleHandler := handlers.NewRoleHandler(roleService, trinoService, auditService) // Role handler uses non-cached version for catalog listing
 	layoutTemplateHandler := handlers.NewLayoutTemplateHandler(layoutTemplateRepo)
+	chartThemeHandler := handlers.NewChartThemeHandler(chartThemeRepo, roleService)
+	configHandler := handlers.NewConfigHandler(cfg)
+	searchHandler := handlers.NewSearchHandler(searchService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	maintenanceHandler := handlers.NewMaintenanceHandler(maintenanceService, auditService)
+	healthHandler := handlers.NewHealthHandler(database.GetPool(), trinoService)
 
 	// Middleware
 	r.Use(middleware.CORSMiddleware(cfg.Server.FrontendURL))
+	if cfg.Metrics.Enabled {
+		metrics.Register()
+		metrics.SetCatalogLabels(append([]string{cfg.Trino.Catalog}, cfg.Metrics.Catalogs...))
+		r.Use(middleware.MetricsMiddleware())
+		r.GET(cfg.Metrics.Path, middleware.MetricsAuth(cfg.Metrics.Token), gin.WrapH(metrics.Handler()))
+	}
+
+	// Rate limits: expensive endpoints get their own, tighter buckets on top of the default per-user limit
+	rateLimit := func(name string, perMinute, burst int, keyFunc middleware.RateLimitKeyFunc) gin.HandlerFunc {
+		var limit services.RateLimit // the zero limit lets every request through
+		if cfg.RateLimit.Enabled {
+			limit = services.RateLimit{PerMinute: perMinute, Burst: burst}
+		}
+		return middleware.RateLimit(rateLimiter, name, limit, keyFunc)
+	}
+	loginLimiter := rateLimit("login", cfg.RateLimit.AuthPerMinute, cfg.RateLimit.AuthBurst, middleware.ClientIPKey)
+	registerLimiter := rateLimit("register", cfg.RateLimit.AuthPerMinute, cfg.RateLimit.AuthBurst, middleware.ClientIPKey)
+	if cfg.RateLimit.Enabled {
+		authHandler.SetLoginLimiter(rateLimiter, services.RateLimit{PerMinute: cfg.RateLimit.LoginAccountPerMinute, Burst: cfg.RateLimit.LoginAccountBurst})
+	}
+	defaultLimiter := rateLimit("default", cfg.RateLimit.DefaultPerMinute, cfg.RateLimit.DefaultBurst, middleware.UserIDKey)
+	queryLimiter := rateLimit("query", cfg.RateLimit.QueryPerMinute, cfg.RateLimit.QueryBurst, middleware.UserIDKey)
+	exportLimiter := rateLimit("export", cfg.RateLimit.ExportPerMinute, cfg.RateLimit.ExportBurst, middleware.UserIDKey)
+	exportSlots := middleware.ConcurrencyLimit(services.NewConcurrencyLimiter(cacheService), "export", func(c *gin.Context) int {
+		isAdmin, err := roleService.IsAdmin(c.Request.Context(), c.MustGet("userID").(uuid.UUID))
+		if err != nil {
+			log.Printf("Failed to check admin status for export limit: %v", err)
+		}
+		if isAdmin {
+			return cfg.Limits.MaxConcurrentExportsAdmin
+		}
+		return cfg.Limits.MaxConcurrentExportsPerUser
+	}, middleware.UserIDKey)
+	// Routes that run queries against Trino answer 503 while maintenance mode is on
+	maintenance := middleware.Maintenance(maintenanceService)
 
 	// API routes
 	api := r.Group("/api")
@@ -48,40 +134,57 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, cacheService *services.Query
 		// Auth routes (public)
 		auth := api.Group("/auth")
 		{
-			auth.POST("/register", authHandler.Register)
-			auth.POST("/login", authHandler.Login)
+			auth.POST("/register", registerLimiter, authHandler.Register)
+			auth.POST("/login", loginLimiter, authHandler.Login)
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/logout", authHandler.Logout)
 			auth.GET("/google", authHandler.GoogleLogin)
 			auth.GET("/google/callback", authHandler.GoogleCallback)
+			auth.GET("/oidc", authHandler.OIDCLogin)
+			auth.GET("/oidc/callback", authHandler.OIDCCallback)
 		}
 
+		// Public client configuration
+		api.GET("/config", configHandler.GetPublicConfig)
+
 		// Protected routes
 		protected := api.Group("")
-		protected.Use(middleware.AuthMiddleware(authService))
+		protected.Use(middleware.AuthMiddleware(authService), defaultLimiter)
 		{
 			// User
 			protected.GET("/auth/me", authHandler.Me)
+			protected.POST("/auth/change-password", loginLimiter, authHandler.ChangePassword)
 
 			// Query execution
-			protected.POST("/queries/execute", queryHandler.ExecuteQuery)
-			protected.GET("/catalogs", queryHandler.GetCatalogs)
-			protected.GET("/catalogs/:catalog/schemas", queryHandler.GetSchemas)
-			protected.GET("/catalogs/:catalog/schemas/:schema/tables", queryHandler.GetTables)
-			protected.GET("/catalogs/:catalog/schemas/:schema/tables/:table/columns", queryHandler.GetColumns)
-			protected.POST("/search/metadata", queryHandler.SearchMetadata)
+			protected.POST("/queries/execute", maintenance, queryLimiter, queryHandler.ExecuteQuery)
+			protected.POST("/queries/execute-async", maintenance, queryLimiter, queryJobHandler.ExecuteQueryAsync)
+			protected.POST("/queries/execute-paged", maintenance, queryLimiter, queryHandler.ExecuteQueryPaged)
+			protected.GET("/queries/results", queryHandler.GetResultPage)
+			protected.POST("/queries/validate-batch", maintenance, queryLimiter, queryHandler.ValidateBatch)
+			protected.GET("/queries/jobs/:id", queryJobHandler.GetQueryJob)
+
+			// Metadata and search
+			registerMetadataRoutes(protected, queryHandler, searchHandler, maintenance)
+
+			// Catalog-scoped metadata
+			registerCatalogRoutes(protected, queryHandler, roleService, maintenance)
 
 			// Saved queries
 			protected.GET("/queries/saved", savedQueryHandler.GetSavedQueries)
 			protected.GET("/queries/saved/:id", savedQueryHandler.GetSavedQuery)
 			protected.POST("/queries/saved", savedQueryHandler.CreateSavedQuery)
+			protected.POST("/queries/saved/import", savedQueryHandler.ImportSavedQueries)
 			protected.PUT("/queries/saved/:id", savedQueryHandler.UpdateSavedQuery)
 			protected.DELETE("/queries/saved/:id", savedQueryHandler.DeleteSavedQuery)
+			protected.GET("/queries/saved/:id/performance", savedQueryHandler.GetSavedQueryPerformance)
 
 			// Query history
 			protected.GET("/queries/history", savedQueryHandler.GetQueryHistory)
+			protected.GET("/queries/slow", middleware.AdminMiddleware(roleService), savedQueryHandler.GetSlowQueries)
 
 			// Export
-			protected.POST("/export/csv", exportHandler.ExportCSV)
-			protected.POST("/export/tsv", exportHandler.ExportTSV)
+			protected.POST("/export/csv", maintenance, exportLimiter, exportSlots, exportHandler.ExportCSV)
+			protected.POST("/export/tsv", maintenance, exportLimiter, exportSlots, exportHandler.ExportTSV)
 
 			// Dashboards
 			protected.GET("/dashboards", dashboardHandler.GetDashboards)
@@ -89,15 +192,26 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, cacheService *services.Query
 			protected.POST("/dashboards", dashboardHandler.CreateDashboard)
 			protected.PUT("/dashboards/:id", dashboardHandler.UpdateDashboard)
 			protected.DELETE("/dashboards/:id", dashboardHandler.DeleteDashboard)
+			protected.GET("/dashboards/trash", dashboardHandler.GetTrash)
+			protected.POST("/dashboards/:id/restore", dashboardHandler.RestoreDashboard)
+			protected.POST("/dashboards/:id/archive", dashboardHandler.ArchiveDashboard)
+			protected.POST("/dashboards/:id/unarchive", dashboardHandler.UnarchiveDashboard)
+			protected.POST("/dashboards/:id/clone", dashboardHandler.CloneDashboard)
+			protected.GET("/dashboards/:id/activity", dashboardHandler.GetDashboardActivity)
+			protected.GET("/dashboards/:id/export", dashboardHandler.ExportDashboard)
+			protected.POST("/dashboards/import", dashboardHandler.ImportDashboard)
 			// Draft management
 			protected.GET("/dashboards/:id/draft", dashboardHandler.GetDraft)
 			protected.POST("/dashboards/:id/draft", dashboardHandler.CreateDraft)
 			protected.POST("/dashboards/:id/save-draft", dashboardHandler.SaveAsDraft)
 			protected.POST("/dashboards/:id/publish", dashboardHandler.PublishDraft)
 			protected.DELETE("/dashboards/:id/discard-draft", dashboardHandler.DiscardDraft)
+			protected.GET("/dashboards/:id/versions", dashboardHandler.GetDashboardVersions)
+			protected.POST("/dashboards/:id/versions/:version/restore", dashboardHandler.RestoreDashboardVersion)
 
 			// Dashboard widgets
 			protected.POST("/dashboards/:id/widgets", dashboardHandler.CreateWidget)
+			protected.GET("/dashboards/:id/widgets/:widgetId", dashboardHandler.GetWidget)
 			protected.PUT("/dashboards/:id/widgets/:widgetId", dashboardHandler.UpdateWidget)
 			protected.DELETE("/dashboards/:id/widgets/:widgetId", dashboardHandler.DeleteWidget)
 			protected.POST("/dashboards/:id/widgets/:widgetId/duplicate", dashboardHandler.DuplicateWidget)
@@ -108,13 +222,16 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, cacheService *services.Query
 			protected.POST("/dashboards/:id/permissions", dashboardHandler.GrantPermission)
 			protected.DELETE("/dashboards/:id/permissions/:permId", dashboardHandler.RevokePermission)
 			protected.PUT("/dashboards/:id/visibility", dashboardHandler.UpdateVisibility)
+			protected.PUT("/dashboards/:id/error-notification", dashboardHandler.UpdateErrorNotification)
 
 			// Widget data (executes query using dashboard owner's permissions)
-			protected.GET("/dashboards/:id/widgets/:widgetId/data", dashboardHandler.GetWidgetData)
-			protected.POST("/dashboards/:id/widgets/:widgetId/data", dashboardHandler.GetWidgetDataWithParams)
+			protected.GET("/dashboards/:id/widgets/:widgetId/data", maintenance, dashboardHandler.GetWidgetData)
+			protected.POST("/dashboards/:id/widgets/:widgetId/data", maintenance, dashboardHandler.GetWidgetDataWithParams)
+			protected.POST("/dashboards/:id/render", maintenance, dashboardHandler.RenderDashboard)
+			protected.POST("/dashboards/:id/data", maintenance, dashboardHandler.GetDashboardData)
 
 			// Parameter dynamic options
-			protected.POST("/dashboards/:id/parameters/:name/options", dashboardHandler.GetParameterOptions)
+			protected.POST("/dashboards/:id/parameters/:name/options", maintenance, dashboardHandler.GetParameterOptions)
 
 			// Notification channels
 			protected.GET("/notification-channels", notificationHandler.GetChannels)
@@ -124,45 +241,79 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, cacheService *services.Query
 			protected.DELETE("/notification-channels/:id", notificationHandler.DeleteChannel)
 			protected.POST("/notification-channels/:id/test", notificationHandler.TestChannel)
 
+			// In-app notifications
+			protected.GET("/notifications", notificationHandler.GetNotifications)
+			protected.POST("/notifications/:id/read", notificationHandler.MarkNotificationRead)
+
 			// Alerts
 			protected.GET("/alerts", alertHandler.GetAlerts)
 			protected.POST("/alerts", alertHandler.CreateAlert)
+			protected.POST("/alerts/preview", maintenance, alertHandler.PreviewAlert)
+			protected.GET("/alerts/digest-settings", alertHandler.GetDigestSettings)
+			protected.PUT("/alerts/digest-settings", alertHandler.UpdateDigestSettings)
 			protected.GET("/alerts/:id", alertHandler.GetAlert)
 			protected.PUT("/alerts/:id", alertHandler.UpdateAlert)
 			protected.DELETE("/alerts/:id", alertHandler.DeleteAlert)
-			protected.POST("/alerts/:id/test", alertHandler.TestAlert)
+			protected.POST("/alerts/:id/test", maintenance, alertHandler.TestAlert)
 			protected.GET("/alerts/:id/history", alertHandler.GetAlertHistory)
+			protected.POST("/alerts/:id/snooze", alertHandler.SnoozeAlert)
+			protected.DELETE("/alerts/:id/snooze", alertHandler.UnsnoozeAlert)
+			protected.POST("/alerts/:id/acknowledge", alertHandler.AcknowledgeAlert)
 
 			// Subscriptions
 			protected.GET("/subscriptions", subscriptionHandler.GetSubscriptions)
 			protected.POST("/subscriptions", subscriptionHandler.CreateSubscription)
+			protected.POST("/subscriptions/validate-cron", subscriptionHandler.ValidateCron)
+			protected.POST("/subscriptions/preview-schedule", subscriptionHandler.PreviewSchedule)
 			protected.GET("/subscriptions/:id", subscriptionHandler.GetSubscription)
 			protected.PUT("/subscriptions/:id", subscriptionHandler.UpdateSubscription)
 			protected.DELETE("/subscriptions/:id", subscriptionHandler.DeleteSubscription)
-			protected.POST("/subscriptions/:id/trigger", subscriptionHandler.TriggerSubscription)
+			protected.POST("/subscriptions/:id/trigger", maintenance, subscriptionHandler.TriggerSubscription)
+			protected.GET("/subscriptions/:id/history", subscriptionHandler.GetSubscriptionHistory)
+			protected.POST("/subscriptions/:id/pause", subscriptionHandler.PauseSubscription)
+			protected.POST("/subscriptions/:id/resume", subscriptionHandler.ResumeSubscription)
+			protected.POST("/subscriptions/:id/skip-next", subscriptionHandler.SkipNextRun)
+
+			// Chart annotations
+			protected.GET("/annotations", annotationHandler.GetAnnotations)
+			protected.POST("/annotations", annotationHandler.CreateAnnotation)
+			protected.PUT("/annotations/:id", annotationHandler.UpdateAnnotation)
+			protected.DELETE("/annotations/:id", annotationHandler.DeleteAnnotation)
 
 			// Layout templates
 			protected.GET("/layout-templates", layoutTemplateHandler.GetLayoutTemplates)
 			protected.POST("/layout-templates", layoutTemplateHandler.CreateLayoutTemplate)
 			protected.DELETE("/layout-templates/:id", layoutTemplateHandler.DeleteLayoutTemplate)
 
+			// Chart themes (system themes are created and deleted by admins)
+			protected.GET("/chart-themes", chartThemeHandler.GetChartThemes)
+			protected.POST("/chart-themes", chartThemeHandler.CreateChartTheme)
+			protected.DELETE("/chart-themes/:id", chartThemeHandler.DeleteChartTheme)
+
 			// Admin routes (requires admin role)
 			admin := protected.Group("/admin")
 			admin.Use(middleware.AdminMiddleware(roleService))
 			{
 				// Role management
-				admin.GET("/roles", roleHandler.GetRoles)
-				admin.POST("/roles", roleHandler.CreateRole)
-				admin.GET("/roles/:id", roleHandler.GetRole)
-				admin.PUT("/roles/:id", roleHandler.UpdateRole)
-				admin.DELETE("/roles/:id", roleHandler.DeleteRole)
-				admin.PUT("/roles/:id/catalogs", roleHandler.SetRoleCatalogs)
-				admin.GET("/catalogs/available", roleHandler.GetAvailableCatalogs)
+				registerRoleRoutes(admin, roleHandler, maintenance)
 
 				// User-role management
 				admin.GET("/users", roleHandler.GetUsersWithRoles)
+				admin.POST("/users/approve-bulk", authHandler.ApproveUsers)
+				admin.PUT("/users/:userId/status", authHandler.UpdateUserStatus)
+				admin.POST("/users/:userId/reset-password", authHandler.ResetPassword)
 				admin.POST("/users/:userId/roles", roleHandler.AssignRole)
 				admin.DELETE("/users/:userId/roles/:roleId", roleHandler.UnassignRole)
+
+				// Audit log
+				admin.GET("/audit-log", auditHandler.GetAuditLog)
+
+				// Maintenance mode (pauses query execution and scheduled evaluation)
+				admin.GET("/maintenance", maintenanceHandler.GetMaintenance)
+				admin.PUT("/maintenance", maintenanceHandler.UpdateMaintenance)
+
+				// Widget debugging (resolves a widget's query without executing it)
+				admin.GET("/dashboards/:id/widgets/:widgetId/debug", dashboardHandler.DebugWidgetQuery)
 			}
 		}
 	}
@@ -171,4 +322,44 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, cacheService *services.Query
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
+	r.GET("/health/detailed", middleware.AuthMiddleware(authService), middleware.AdminMiddleware(roleService), healthHandler.GetDetailedHealth)
+}
+
+// registerMetadataRoutes adds the catalog list, metadata search and global search routes. They
+// all read Trino metadata, so they are closed during maintenance.
+func registerMetadataRoutes(protected *gin.RouterGroup, queryHandler *handlers.QueryHandler, searchHandler *handlers.SearchHandler, maintenance gin.HandlerFunc) {
+	protected.GET("/catalogs", maintenance, queryHandler.GetCatalogs)
+	protected.POST("/search/metadata", maintenance, queryHandler.SearchMetadata)
+	protected.GET("/metadata/autocomplete", maintenance, queryHandler.AutocompleteMetadata)
+	protected.GET("/search", maintenance, searchHandler.Search)
+}
+
+// registerRoleRoutes adds the admin role management routes. Listing the catalogs available to
+// grant queries Trino, so that route is closed during maintenance.
+func registerRoleRoutes(admin *gin.RouterGroup, roleHandler *handlers.RoleHandler, maintenance gin.HandlerFunc) {
+	admin.GET("/roles", roleHandler.GetRoles)
+	admin.POST("/roles", roleHandler.CreateRole)
+	admin.GET("/roles/:id", roleHandler.GetRole)
+	admin.PUT("/roles/:id", roleHandler.UpdateRole)
+	admin.DELETE("/roles/:id", roleHandler.DeleteRole)
+	admin.PUT("/roles/:id/catalogs", roleHandler.SetRoleCatalogs)
+	admin.PUT("/roles/:id/schemas", roleHandler.SetRoleSchemas)
+	admin.PUT("/roles/:id/row-filters", roleHandler.SetRoleRowFilters)
+	admin.PUT("/roles/:id/query-timeout", roleHandler.SetRoleQueryTimeout)
+	admin.GET("/catalogs/available", maintenance, roleHandler.GetAvailableCatalogs)
+}
+
+// registerCatalogRoutes adds the catalog-scoped metadata routes. Access to :catalog is enforced
+// by middleware for the whole group, so the handlers do not check it themselves. Every route
+// queries Trino, so the whole group is closed during maintenance.
+func registerCatalogRoutes(protected *gin.RouterGroup, queryHandler *handlers.QueryHandler, roleService *services.RoleService, maintenance gin.HandlerFunc) {
+	catalogScoped := protected.Group("/catalogs/:catalog")
+	catalogScoped.Use(maintenance, middleware.CatalogAccessMiddleware(roleService))
+	{
+		catalogScoped.GET("/schemas", queryHandler.GetSchemas)
+		catalogScoped.GET("/schemas/:schema/tables", queryHandler.GetTables)
+		catalogScoped.GET("/schemas/:schema/tables/:table/columns", queryHandler.GetColumns)
+		catalogScoped.GET("/schemas/:schema/tables/:table/sample", queryHandler.GetTableSample)
+		catalogScoped.GET("/schemas/:schema/tables/:table/ddl", queryHandler.GetTableDDL)
+	}
 }
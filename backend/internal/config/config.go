@@ -2,25 +2,81 @@ package config
 
 import (
 	"errors"
+	"log"
+	"net"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+
+	"github.com/mitsume/backend/internal/crypto"
+	"github.com/mitsume/backend/internal/models"
 )
 
 type Config struct {
+	Profile      string // MITSUME_PROFILE (default: none) - loaded from MITSUME_PROFILE_DIR/<profile>.env (default dir: profiles)
 	Server       ServerConfig
 	Database     DatabaseConfig
 	Trino        TrinoConfig
 	JWT          JWTConfig
 	Google       GoogleOAuthConfig
+	OIDC         OIDCConfig
 	Notification NotificationConfig
 	Cache        CacheConfig
 	Admin        AdminConfig
+	Webhook      WebhookConfig
+	Metrics      MetricsConfig
+	RateLimit    RateLimitConfig
+	Dashboard    DashboardConfig
+	Limits       LimitsConfig
+}
+
+// LimitsConfig caps per-user load; 0 disables a limit. Admins are exempt from the scheduler
+// limits and have their own export limit; the query history cap applies to everyone.
+type LimitsConfig struct {
+	MaxActiveAlertsPerUser        int // MAX_ACTIVE_ALERTS_PER_USER (default: 100)
+	MaxActiveSubscriptionsPerUser int // MAX_ACTIVE_SUBSCRIPTIONS_PER_USER (default: 100)
+	MaxSubscriptionFailures       int // MAX_SUBSCRIPTION_FAILURES (default: 0) - consecutive failed runs that pause a subscription
+	MaxConcurrentExportsPerUser   int // MAX_CONCURRENT_EXPORTS_PER_USER (default: 2)
+	MaxConcurrentExportsAdmin     int // MAX_CONCURRENT_EXPORTS_ADMIN (default: 5)
+	MaxQueryHistoryPerUser        int // MAX_QUERY_HISTORY_PER_USER (default: 1000) - older entries are trimmed hourly
+	MaxQueryJobsPerUser           int // MAX_QUERY_JOBS_PER_USER (default: 5) - pending or running async queries
+}
+
+type DashboardConfig struct {
+	AllowedChartTypes   []string // ALLOWED_CHART_TYPES (comma-separated; empty allows all chart types)
+	MaxParameters       int      // MAX_DASHBOARD_PARAMETERS (default: 50; 0 disables the limit)
+	UniqueNames         bool     // DASHBOARD_UNIQUE_NAMES (default: false) - reject duplicate names among a user's own dashboards
+	TrashRetentionDays  int      // DASHBOARD_TRASH_RETENTION_DAYS (default: 30; 0 keeps deleted dashboards until restored)
+	TrashWarningDays    int      // DASHBOARD_TRASH_WARNING_DAYS (default: 3; 0 disables warning owners before a purge)
+	MaxVersions         int      // DASHBOARD_MAX_VERSIONS (default: 20; 0 keeps every version) - snapshots kept per dashboard
+	RenderConcurrency   int      // DASHBOARD_RENDER_CONCURRENCY (default: 4) - widget queries a dashboard render runs at once
+	RevealForbidden     bool     // DASHBOARD_REVEAL_FORBIDDEN (default: false) - answer admins 403 instead of 404 for dashboards they cannot view
+	OwnerOnlyParameters bool     // DASHBOARD_OWNER_ONLY_PARAMETERS (default: false) - only owners may change parameter definitions
+	ParameterDenylist   []string // DASHBOARD_PARAMETER_DENYLIST (comma-separated; empty disables) - SQL terms rejected in viewers' raw/identifier values
+	ParameterAllowlist  []string // DASHBOARD_PARAMETER_ALLOWLIST (comma-separated) - words accepted even when they start with a denied term
+}
+
+type MetricsConfig struct {
+	Enabled  bool     // METRICS_ENABLED (default: false)
+	Path     string   // METRICS_PATH (default: "/metrics")
+	Token    string   // METRICS_TOKEN (optional) - bearer token scrapers must send; empty leaves the endpoint open
+	Catalogs []string // METRICS_CATALOGS (comma-separated) - catalogs labeled by name in query metrics; TRINO_CATALOG is always included, others are labeled "other"
+}
+
+type WebhookConfig struct {
+	Secret             string   // WEBHOOK_SECRET (HMAC key for signing callback payloads) - callbacks are rejected while unset
+	MaxRetries         int      // WEBHOOK_MAX_RETRIES (default: 3)
+	TimeoutSeconds     int      // WEBHOOK_TIMEOUT_SECONDS (default: 10)
+	RetryBackoffMillis int      // WEBHOOK_RETRY_BACKOFF_MS (default: 1000, doubled per attempt)
+	AllowedHosts       []string // WEBHOOK_ALLOWED_HOSTS (comma-separated; empty allows any host resolving to public addresses only)
 }
 
 type AdminConfig struct {
 	Username          string // MITSUME_ADMIN_USERNAME (default: "admin")
 	Password          string // MITSUME_ADMIN_PASSWORD (required for creation)
 	PasswordMinLength int    // MITSUME_ADMIN_PASSWORD_MIN_LENGTH (default: 0)
+	NotifyOnApproval  bool   // MITSUME_ADMIN_NOTIFY_ON_APPROVAL (default: false) - email users when an admin activates or disables their account
 }
 
 type CacheConfig struct {
@@ -29,14 +85,17 @@ type CacheConfig struct {
 	RedisPort        int
 	RedisPassword    string
 	RedisDB          int
-	TTLHighSeconds   int
-	TTLNormalSeconds int
-	TTLLowSeconds    int
+	TTLHighSeconds   int // CACHE_TTL_HIGH_SECONDS (default: 3600) - scheduled alert queries
+	TTLNormalSeconds int // CACHE_TTL_NORMAL_SECONDS (default: 600) - dashboard widgets
+	TTLLowSeconds    int // CACHE_TTL_LOW_SECONDS (default: 60) - ad-hoc queries
 	KeyPrefix        string
 }
 
 type NotificationConfig struct {
 	SMTP SMTPConfig
+	// EncryptionKey encrypts channel configs at rest (NOTIFICATION_ENCRYPTION_KEY, a base64-encoded
+	// 32-byte key). Configs are stored in plaintext when it is empty.
+	EncryptionKey string
 }
 
 type SMTPConfig struct {
@@ -52,6 +111,9 @@ type ServerConfig struct {
 	Port        string
 	Mode        string
 	FrontendURL string
+	// TrustedProxies lists the proxy IPs/CIDRs whose X-Forwarded-For headers are trusted.
+	// nil when TRUSTED_PROXIES is unset (Gin's default applies); empty when every entry was invalid.
+	TrustedProxies []string
 }
 
 type DatabaseConfig struct {
@@ -64,16 +126,43 @@ type DatabaseConfig struct {
 }
 
 type TrinoConfig struct {
-	Host    string
-	Port    string
-	User    string
-	Catalog string
-	Schema  string
+	Host         string
+	Port         string
+	User         string
+	Catalog      string
+	Schema       string
+	ReadOnlyMode bool // TRINO_READ_ONLY (default: false) - reject statements other than SELECT/SHOW/DESCRIBE/EXPLAIN/WITH
+
+	ResultIdleMinutes int // QUERY_RESULT_IDLE_MINUTES (default: 10, 0 disables cursor pagination) - how long a paged result is held after its last page read
+
+	SlowQueryThresholdMs int // SLOW_QUERY_THRESHOLD_MS (default: 10000, 0 disables slow query reporting) - executions at least this long are reported as slow
+
+	QueryTimeoutSeconds    int // QUERY_TIMEOUT_SECONDS (default: 60) - timeout of queries that do not request one
+	MaxQueryTimeoutSeconds int // QUERY_MAX_TIMEOUT_SECONDS (default: 1800) - longest timeout a request can ask for, unless one of the user's roles sets its own cap
 }
 
 type JWTConfig struct {
-	Secret     string
-	ExpireHour int
+	Secret                    string
+	ExpireHour                int // JWT_EXPIRE_HOURS (default: 1) - access token lifetime
+	RefreshExpireDays         int // JWT_REFRESH_EXPIRE_DAYS (default: 30)
+	StatusCacheSeconds        int // JWT_STATUS_CACHE_SECONDS (default: 30, 0 disables) - user status cache TTL
+	SessionMaxLifetimeHours   int // SESSION_MAX_LIFETIME_HOURS (default: 0, disabled) - absolute session lifetime across refreshes
+	SessionIdleTimeoutMinutes int // SESSION_IDLE_TIMEOUT_MINUTES (default: 0, disabled) - reject sessions idle this long
+}
+
+// RateLimitConfig holds token bucket limits; a per-minute or burst value of 0 disables that limit
+type RateLimitConfig struct {
+	Enabled               bool // RATE_LIMIT_ENABLED (default: true)
+	AuthPerMinute         int  // RATE_LIMIT_AUTH_PER_MINUTE (default: 10) - per client IP, separately on /auth/login and /auth/register
+	AuthBurst             int  // RATE_LIMIT_AUTH_BURST (default: 5)
+	LoginAccountPerMinute int  // RATE_LIMIT_LOGIN_ACCOUNT_PER_MINUTE (default: 5) - per account on /auth/login, across client IPs
+	LoginAccountBurst     int  // RATE_LIMIT_LOGIN_ACCOUNT_BURST (default: 10)
+	QueryPerMinute        int  // RATE_LIMIT_QUERY_PER_MINUTE (default: 60) - per user on /queries/execute and /queries/execute-async
+	QueryBurst            int  // RATE_LIMIT_QUERY_BURST (default: 10)
+	ExportPerMinute       int  // RATE_LIMIT_EXPORT_PER_MINUTE (default: 10) - per user on /export/*
+	ExportBurst           int  // RATE_LIMIT_EXPORT_BURST (default: 3)
+	DefaultPerMinute      int  // RATE_LIMIT_DEFAULT_PER_MINUTE (default: 300) - per user on every authenticated route
+	DefaultBurst          int  // RATE_LIMIT_DEFAULT_BURST (default: 100)
 }
 
 type GoogleOAuthConfig struct {
@@ -82,86 +171,201 @@ type GoogleOAuthConfig struct {
 	RedirectURL  string
 }
 
+// OIDCConfig configures sign-in through a generic OpenID Connect provider (Okta, Keycloak, Entra ID, ...).
+// Endpoints are discovered from Issuer; SSO is disabled unless Issuer, ClientID and ClientSecret are set.
+type OIDCConfig struct {
+	Issuer       string // OIDC_ISSUER (e.g. https://accounts.example.com/realms/main)
+	ClientID     string // OIDC_CLIENT_ID
+	ClientSecret string // OIDC_CLIENT_SECRET
+	RedirectURL  string // OIDC_REDIRECT_URL (default: http://localhost:8080/api/auth/oidc/callback)
+}
+
+// Load reads the configuration from environment variables layered over the profile named by
+// MITSUME_PROFILE, so a profile holds an environment's settings and the environment overrides them
 func Load() (*Config, error) {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		return nil, errors.New("JWT_SECRET environment variable is required but not set")
+	profileName := os.Getenv("MITSUME_PROFILE")
+	profileDir := os.Getenv("MITSUME_PROFILE_DIR")
+	if profileDir == "" {
+		profileDir = "profiles"
 	}
+	values, err := loadProfile(profileName, profileDir)
+	if err != nil {
+		return nil, err
+	}
+	env := profileLookup(values)
 
 	// Validate MITSUME_ADMIN_PASSWORD_MIN_LENGTH
-	adminPasswordMinLength, err := getEnvIntValidated("MITSUME_ADMIN_PASSWORD_MIN_LENGTH", 0)
+	adminPasswordMinLength, err := getEnvIntValidated(env, "MITSUME_ADMIN_PASSWORD_MIN_LENGTH", 0)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Config{
+	cfg := &Config{
+		Profile: profileName,
 		Server: ServerConfig{
-			Port:        getEnv("SERVER_PORT", "8080"),
-			Mode:        getEnv("GIN_MODE", "debug"),
-			FrontendURL: getEnv("FRONTEND_URL", "http://localhost:5173"),
+			Port:           getEnv(env, "SERVER_PORT", "8080"),
+			Mode:           getEnv(env, "GIN_MODE", "debug"),
+			FrontendURL:    getEnv(env, "FRONTEND_URL", "http://localhost:5173"),
+			TrustedProxies: parseTrustedProxies(env("TRUSTED_PROXIES")),
 		},
 		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnv("DB_PORT", "5432"),
-			User:     getEnv("DB_USER", "mitsume"),
-			Password: getEnv("DB_PASSWORD", "mitsume"),
-			DBName:   getEnv("DB_NAME", "mitsume"),
-			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+			Host:     getEnv(env, "DB_HOST", "localhost"),
+			Port:     getEnv(env, "DB_PORT", "5432"),
+			User:     getEnv(env, "DB_USER", "mitsume"),
+			Password: getEnv(env, "DB_PASSWORD", "mitsume"),
+			DBName:   getEnv(env, "DB_NAME", "mitsume"),
+			SSLMode:  getEnv(env, "DB_SSLMODE", "disable"),
 		},
 		Trino: TrinoConfig{
-			Host:    getEnv("TRINO_HOST", "localhost"),
-			Port:    getEnv("TRINO_PORT", "8080"),
-			User:    getEnv("TRINO_USER", "mitsume"),
-			Catalog: getEnv("TRINO_CATALOG", "memory"),
-			Schema:  getEnv("TRINO_SCHEMA", "default"),
+			Host:         getEnv(env, "TRINO_HOST", "localhost"),
+			Port:         getEnv(env, "TRINO_PORT", "8080"),
+			User:         getEnv(env, "TRINO_USER", "mitsume"),
+			Catalog:      getEnv(env, "TRINO_CATALOG", "memory"),
+			Schema:       getEnv(env, "TRINO_SCHEMA", "default"),
+			ReadOnlyMode: getEnvBool(env, "TRINO_READ_ONLY", false),
+
+			ResultIdleMinutes: getEnvInt(env, "QUERY_RESULT_IDLE_MINUTES", 10),
+
+			SlowQueryThresholdMs: getEnvInt(env, "SLOW_QUERY_THRESHOLD_MS", 10000),
+
+			QueryTimeoutSeconds:    getEnvInt(env, "QUERY_TIMEOUT_SECONDS", 60),
+			MaxQueryTimeoutSeconds: getEnvInt(env, "QUERY_MAX_TIMEOUT_SECONDS", 1800),
 		},
 		JWT: JWTConfig{
-			Secret:     jwtSecret,
-			ExpireHour: 24,
+			Secret:                    env("JWT_SECRET"),
+			ExpireHour:                getEnvInt(env, "JWT_EXPIRE_HOURS", 1),
+			RefreshExpireDays:         getEnvInt(env, "JWT_REFRESH_EXPIRE_DAYS", 30),
+			StatusCacheSeconds:        getEnvInt(env, "JWT_STATUS_CACHE_SECONDS", 30),
+			SessionMaxLifetimeHours:   getEnvInt(env, "SESSION_MAX_LIFETIME_HOURS", 0),
+			SessionIdleTimeoutMinutes: getEnvInt(env, "SESSION_IDLE_TIMEOUT_MINUTES", 0),
 		},
 		Google: GoogleOAuthConfig{
-			ClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
-			ClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
-			RedirectURL:  getEnv("GOOGLE_REDIRECT_URL", "http://localhost:8080/api/auth/google/callback"),
+			ClientID:     getEnv(env, "GOOGLE_CLIENT_ID", ""),
+			ClientSecret: getEnv(env, "GOOGLE_CLIENT_SECRET", ""),
+			RedirectURL:  getEnv(env, "GOOGLE_REDIRECT_URL", "http://localhost:8080/api/auth/google/callback"),
+		},
+		OIDC: OIDCConfig{
+			Issuer:       strings.TrimSuffix(getEnv(env, "OIDC_ISSUER", ""), "/"),
+			ClientID:     getEnv(env, "OIDC_CLIENT_ID", ""),
+			ClientSecret: getEnv(env, "OIDC_CLIENT_SECRET", ""),
+			RedirectURL:  getEnv(env, "OIDC_REDIRECT_URL", "http://localhost:8080/api/auth/oidc/callback"),
 		},
 		Notification: NotificationConfig{
 			SMTP: SMTPConfig{
-				Host:     getEnv("SMTP_HOST", ""),
-				Port:     getEnv("SMTP_PORT", "587"),
-				Username: getEnv("SMTP_USERNAME", ""),
-				Password: getEnv("SMTP_PASSWORD", ""),
-				From:     getEnv("SMTP_FROM", ""),
-				UseTLS:   getEnv("SMTP_USE_TLS", "true") == "true",
+				Host:     getEnv(env, "SMTP_HOST", ""),
+				Port:     getEnv(env, "SMTP_PORT", "587"),
+				Username: getEnv(env, "SMTP_USERNAME", ""),
+				Password: getEnv(env, "SMTP_PASSWORD", ""),
+				From:     getEnv(env, "SMTP_FROM", ""),
+				UseTLS:   getEnv(env, "SMTP_USE_TLS", "true") == "true",
 			},
+			EncryptionKey: getEnv(env, "NOTIFICATION_ENCRYPTION_KEY", ""),
 		},
 		Cache: CacheConfig{
-			Enabled:          getEnvBool("CACHE_ENABLED", false),
-			RedisHost:        getEnv("REDIS_HOST", "localhost"),
-			RedisPort:        getEnvInt("REDIS_PORT", 6379),
-			RedisPassword:    getEnv("REDIS_PASSWORD", ""),
-			RedisDB:          getEnvInt("REDIS_DB", 0),
-			TTLHighSeconds:   getEnvInt("CACHE_TTL_HIGH_SECONDS", 3600),
-			TTLNormalSeconds: getEnvInt("CACHE_TTL_NORMAL_SECONDS", 600),
-			TTLLowSeconds:    getEnvInt("CACHE_TTL_LOW_SECONDS", 60),
-			KeyPrefix:        getEnv("CACHE_KEY_PREFIX", "mitsume:cache:"),
+			Enabled:          getEnvBool(env, "CACHE_ENABLED", false),
+			RedisHost:        getEnv(env, "REDIS_HOST", "localhost"),
+			RedisPort:        getEnvInt(env, "REDIS_PORT", 6379),
+			RedisPassword:    getEnv(env, "REDIS_PASSWORD", ""),
+			RedisDB:          getEnvInt(env, "REDIS_DB", 0),
+			TTLHighSeconds:   getEnvInt(env, "CACHE_TTL_HIGH_SECONDS", 3600),
+			TTLNormalSeconds: getEnvInt(env, "CACHE_TTL_NORMAL_SECONDS", 600),
+			TTLLowSeconds:    getEnvInt(env, "CACHE_TTL_LOW_SECONDS", 60),
+			KeyPrefix:        getEnv(env, "CACHE_KEY_PREFIX", "mitsume:cache:"),
 		},
 		Admin: AdminConfig{
-			Username:          getEnv("MITSUME_ADMIN_USERNAME", "admin"),
-			Password:          os.Getenv("MITSUME_ADMIN_PASSWORD"), // No default - empty means skip
+			Username:          getEnv(env, "MITSUME_ADMIN_USERNAME", "admin"),
+			Password:          env("MITSUME_ADMIN_PASSWORD"), // No default - empty means skip
 			PasswordMinLength: adminPasswordMinLength,
+			NotifyOnApproval:  getEnvBool(env, "MITSUME_ADMIN_NOTIFY_ON_APPROVAL", false),
+		},
+		Webhook: WebhookConfig{
+			Secret:             getEnv(env, "WEBHOOK_SECRET", ""),
+			MaxRetries:         getEnvInt(env, "WEBHOOK_MAX_RETRIES", 3),
+			TimeoutSeconds:     getEnvInt(env, "WEBHOOK_TIMEOUT_SECONDS", 10),
+			RetryBackoffMillis: getEnvInt(env, "WEBHOOK_RETRY_BACKOFF_MS", 1000),
+			AllowedHosts:       getEnvList(env, "WEBHOOK_ALLOWED_HOSTS"),
+		},
+		Metrics: MetricsConfig{
+			Enabled:  getEnvBool(env, "METRICS_ENABLED", false),
+			Path:     getEnv(env, "METRICS_PATH", "/metrics"),
+			Token:    getEnv(env, "METRICS_TOKEN", ""),
+			Catalogs: getEnvList(env, "METRICS_CATALOGS"),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:               getEnvBool(env, "RATE_LIMIT_ENABLED", true),
+			AuthPerMinute:         getEnvInt(env, "RATE_LIMIT_AUTH_PER_MINUTE", 10),
+			AuthBurst:             getEnvInt(env, "RATE_LIMIT_AUTH_BURST", 5),
+			LoginAccountPerMinute: getEnvInt(env, "RATE_LIMIT_LOGIN_ACCOUNT_PER_MINUTE", 5),
+			LoginAccountBurst:     getEnvInt(env, "RATE_LIMIT_LOGIN_ACCOUNT_BURST", 10),
+			QueryPerMinute:        getEnvInt(env, "RATE_LIMIT_QUERY_PER_MINUTE", 60),
+			QueryBurst:            getEnvInt(env, "RATE_LIMIT_QUERY_BURST", 10),
+			ExportPerMinute:       getEnvInt(env, "RATE_LIMIT_EXPORT_PER_MINUTE", 10),
+			ExportBurst:           getEnvInt(env, "RATE_LIMIT_EXPORT_BURST", 3),
+			DefaultPerMinute:      getEnvInt(env, "RATE_LIMIT_DEFAULT_PER_MINUTE", 300),
+			DefaultBurst:          getEnvInt(env, "RATE_LIMIT_DEFAULT_BURST", 100),
+		},
+		Dashboard: DashboardConfig{
+			AllowedChartTypes:   getEnvList(env, "ALLOWED_CHART_TYPES"),
+			MaxParameters:       getEnvInt(env, "MAX_DASHBOARD_PARAMETERS", 50),
+			UniqueNames:         getEnvBool(env, "DASHBOARD_UNIQUE_NAMES", false),
+			TrashRetentionDays:  getEnvInt(env, "DASHBOARD_TRASH_RETENTION_DAYS", 30),
+			TrashWarningDays:    getEnvInt(env, "DASHBOARD_TRASH_WARNING_DAYS", 3),
+			MaxVersions:         getEnvInt(env, "DASHBOARD_MAX_VERSIONS", 20),
+			RenderConcurrency:   getEnvInt(env, "DASHBOARD_RENDER_CONCURRENCY", 4),
+			RevealForbidden:     getEnvBool(env, "DASHBOARD_REVEAL_FORBIDDEN", false),
+			OwnerOnlyParameters: getEnvBool(env, "DASHBOARD_OWNER_ONLY_PARAMETERS", false),
+			ParameterDenylist:   getEnvList(env, "DASHBOARD_PARAMETER_DENYLIST"),
+			ParameterAllowlist:  getEnvList(env, "DASHBOARD_PARAMETER_ALLOWLIST"),
+		},
+		Limits: LimitsConfig{
+			MaxActiveAlertsPerUser:        getEnvInt(env, "MAX_ACTIVE_ALERTS_PER_USER", 100),
+			MaxActiveSubscriptionsPerUser: getEnvInt(env, "MAX_ACTIVE_SUBSCRIPTIONS_PER_USER", 100),
+			MaxSubscriptionFailures:       getEnvInt(env, "MAX_SUBSCRIPTION_FAILURES", 0),
+			MaxConcurrentExportsPerUser:   getEnvInt(env, "MAX_CONCURRENT_EXPORTS_PER_USER", 2),
+			MaxConcurrentExportsAdmin:     getEnvInt(env, "MAX_CONCURRENT_EXPORTS_ADMIN", 5),
+			MaxQueryHistoryPerUser:        getEnvInt(env, "MAX_QUERY_HISTORY_PER_USER", 1000),
+			MaxQueryJobsPerUser:           getEnvInt(env, "MAX_QUERY_JOBS_PER_USER", 5),
 		},
-	}, nil
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate checks settings the server cannot start without, after the profile and
+// environment have been merged
+func (c *Config) Validate() error {
+	if c.JWT.Secret == "" {
+		return errors.New("JWT_SECRET environment variable is required but not set")
+	}
+	if c.Admin.PasswordMinLength < 0 {
+		return errors.New("MITSUME_ADMIN_PASSWORD_MIN_LENGTH must be a non-negative integer")
+	}
+	if key := c.Notification.EncryptionKey; key != "" {
+		if _, err := crypto.NewCipherFromBase64(key); err != nil {
+			return errors.New("NOTIFICATION_ENCRYPTION_KEY: " + err.Error())
+		}
+	}
+	for _, chartType := range c.Dashboard.AllowedChartTypes {
+		if !slices.Contains(models.ChartTypes, chartType) {
+			return errors.New("ALLOWED_CHART_TYPES: unknown chart type " + strconv.Quote(chartType) +
+				" (must be one of " + strings.Join(models.ChartTypes, ", ") + ")")
+		}
+	}
+	return nil
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
+func getEnv(env lookupFunc, key, defaultValue string) string {
+	if value := env(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
 
-func getEnvInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
+func getEnvInt(env lookupFunc, key string, defaultValue int) int {
+	if value := env(key); value != "" {
 		if intVal, err := strconv.Atoi(value); err == nil {
 			return intVal
 		}
@@ -169,8 +373,8 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
-func getEnvBool(key string, defaultValue bool) bool {
-	if value := os.Getenv(key); value != "" {
+func getEnvBool(env lookupFunc, key string, defaultValue bool) bool {
+	if value := env(key); value != "" {
 		if boolVal, err := strconv.ParseBool(value); err == nil {
 			return boolVal
 		}
@@ -178,10 +382,60 @@ func getEnvBool(key string, defaultValue bool) bool {
 	return defaultValue
 }
 
+// getEnvList splits a comma-separated environment variable into trimmed, non-empty values.
+// Returns nil if the variable is unset or empty.
+func getEnvList(env lookupFunc, key string) []string {
+	value := env(key)
+	if value == "" {
+		return nil
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseTrustedProxies parses a comma-separated list of IPs and CIDRs.
+// Entries are trimmed of all whitespace and normalized; invalid entries are logged and skipped.
+// Returns nil for an empty value.
+func parseTrustedProxies(value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+
+	proxies := []string{}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if strings.Contains(item, "/") {
+			_, network, err := net.ParseCIDR(item)
+			if err != nil {
+				log.Printf("[WARN] Ignoring invalid TRUSTED_PROXIES entry %q: not a valid CIDR", item)
+				continue
+			}
+			proxies = append(proxies, network.String())
+			continue
+		}
+		ip := net.ParseIP(item)
+		if ip == nil {
+			log.Printf("[WARN] Ignoring invalid TRUSTED_PROXIES entry %q: not a valid IP address", item)
+			continue
+		}
+		proxies = append(proxies, ip.String())
+	}
+	return proxies
+}
+
 // getEnvIntValidated gets an integer from environment variable with validation.
 // Returns an error if the value is not a valid non-negative integer.
-func getEnvIntValidated(key string, defaultValue int) (int, error) {
-	value := os.Getenv(key)
+func getEnvIntValidated(env lookupFunc, key string, defaultValue int) (int, error) {
+	value := env(key)
 	if value == "" {
 		return defaultValue, nil
 	}
@@ -39,6 +39,29 @@ func TestLoad_PasswordMinLengthInvalidString_ReturnsError(t *testing.T) {
 	}
 }
 
+func TestLoad_NotifyOnApproval(t *testing.T) {
+	os.Setenv("JWT_SECRET", "test-secret")
+	defer os.Unsetenv("JWT_SECRET")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Admin.NotifyOnApproval {
+		t.Error("Expected NotifyOnApproval to default to false")
+	}
+
+	os.Setenv("MITSUME_ADMIN_NOTIFY_ON_APPROVAL", "true")
+	defer os.Unsetenv("MITSUME_ADMIN_NOTIFY_ON_APPROVAL")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !cfg.Admin.NotifyOnApproval {
+		t.Error("Expected NotifyOnApproval to be true")
+	}
+}
+
 func TestLoad_PasswordMinLengthValidZero_Succeeds(t *testing.T) {
 	// Set required env vars
 	os.Setenv("JWT_SECRET", "test-secret")
@@ -89,6 +112,109 @@ func TestLoad_PasswordMinLengthNotSet_UsesDefault(t *testing.T) {
 	}
 }
 
+func TestLoad_AllowedChartTypesParsed(t *testing.T) {
+	// Set required env vars
+	os.Setenv("JWT_SECRET", "test-secret")
+	os.Setenv("ALLOWED_CHART_TYPES", " bar, line ,,table ")
+	defer os.Unsetenv("JWT_SECRET")
+	defer os.Unsetenv("ALLOWED_CHART_TYPES")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	want := []string{"bar", "line", "table"}
+	got := cfg.Dashboard.AllowedChartTypes
+	if len(got) != len(want) {
+		t.Fatalf("Expected AllowedChartTypes %v, got: %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected AllowedChartTypes %v, got: %v", want, got)
+		}
+	}
+}
+
+func TestLoad_AllowedChartTypesNotSet_AllowsAll(t *testing.T) {
+	// Set required env vars
+	os.Setenv("JWT_SECRET", "test-secret")
+	os.Unsetenv("ALLOWED_CHART_TYPES")
+	defer os.Unsetenv("JWT_SECRET")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if cfg.Dashboard.AllowedChartTypes != nil {
+		t.Errorf("Expected nil AllowedChartTypes, got: %v", cfg.Dashboard.AllowedChartTypes)
+	}
+}
+
+func TestLoad_AllowedChartTypesUnknown_ReturnsError(t *testing.T) {
+	// Set required env vars
+	os.Setenv("JWT_SECRET", "test-secret")
+	os.Setenv("ALLOWED_CHART_TYPES", "bar,barchart")
+	defer os.Unsetenv("JWT_SECRET")
+	defer os.Unsetenv("ALLOWED_CHART_TYPES")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Expected error for unknown chart type, got nil")
+	}
+	if !contains(err.Error(), `"barchart"`) {
+		t.Errorf("Expected error to name the unknown chart type, got: %v", err)
+	}
+}
+
+func TestLoad_RateLimitDefaults(t *testing.T) {
+	// Set required env vars
+	os.Setenv("JWT_SECRET", "test-secret")
+	os.Setenv("RATE_LIMIT_EXPORT_PER_MINUTE", "2")
+	defer os.Unsetenv("JWT_SECRET")
+	defer os.Unsetenv("RATE_LIMIT_EXPORT_PER_MINUTE")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !cfg.RateLimit.Enabled {
+		t.Error("Expected rate limiting to be enabled by default")
+	}
+	if cfg.RateLimit.ExportPerMinute != 2 {
+		t.Errorf("Expected ExportPerMinute 2, got: %d", cfg.RateLimit.ExportPerMinute)
+	}
+	if cfg.RateLimit.QueryPerMinute != 60 || cfg.RateLimit.DefaultPerMinute != 300 {
+		t.Errorf("Expected default QueryPerMinute 60 and DefaultPerMinute 300, got: %d and %d",
+			cfg.RateLimit.QueryPerMinute, cfg.RateLimit.DefaultPerMinute)
+	}
+	if cfg.RateLimit.LoginAccountPerMinute != 5 || cfg.RateLimit.LoginAccountBurst != 10 {
+		t.Errorf("Expected default LoginAccountPerMinute 5 and LoginAccountBurst 10, got: %d and %d",
+			cfg.RateLimit.LoginAccountPerMinute, cfg.RateLimit.LoginAccountBurst)
+	}
+}
+
+func TestLoad_QueryTimeouts(t *testing.T) {
+	os.Setenv("JWT_SECRET", "test-secret")
+	os.Setenv("QUERY_MAX_TIMEOUT_SECONDS", "600")
+	defer os.Unsetenv("JWT_SECRET")
+	defer os.Unsetenv("QUERY_MAX_TIMEOUT_SECONDS")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if cfg.Trino.QueryTimeoutSeconds != 60 {
+		t.Errorf("Expected default QueryTimeoutSeconds 60, got: %d", cfg.Trino.QueryTimeoutSeconds)
+	}
+	if cfg.Trino.MaxQueryTimeoutSeconds != 600 {
+		t.Errorf("Expected MaxQueryTimeoutSeconds 600, got: %d", cfg.Trino.MaxQueryTimeoutSeconds)
+	}
+}
+
 func contains(s, substr string) bool {
 	for i := 0; i <= len(s)-len(substr); i++ {
 		if s[i:i+len(substr)] == substr {
@@ -97,3 +223,68 @@ func contains(s, substr string) bool {
 	}
 	return false
 }
+
+func TestLoad_NotificationEncryptionKeyInvalid_ReturnsError(t *testing.T) {
+	os.Setenv("JWT_SECRET", "test-secret")
+	os.Setenv("NOTIFICATION_ENCRYPTION_KEY", "dG9vIHNob3J0") // "too short"
+	defer os.Unsetenv("JWT_SECRET")
+	defer os.Unsetenv("NOTIFICATION_ENCRYPTION_KEY")
+
+	_, err := Load()
+	if err == nil || !contains(err.Error(), "NOTIFICATION_ENCRYPTION_KEY") {
+		t.Fatalf("Expected an error naming NOTIFICATION_ENCRYPTION_KEY, got: %v", err)
+	}
+}
+
+func TestLoad_NotificationEncryptionKeyValid_Succeeds(t *testing.T) {
+	key := "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=" // 32 zero bytes
+	os.Setenv("JWT_SECRET", "test-secret")
+	os.Setenv("NOTIFICATION_ENCRYPTION_KEY", key)
+	defer os.Unsetenv("JWT_SECRET")
+	defer os.Unsetenv("NOTIFICATION_ENCRYPTION_KEY")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.Notification.EncryptionKey != key {
+		t.Errorf("Expected EncryptionKey %q, got %q", key, cfg.Notification.EncryptionKey)
+	}
+}
+
+func TestParseTrustedProxies_MixedValidAndInvalid(t *testing.T) {
+	got := parseTrustedProxies(" 10.0.0.1 ,\t192.168.0.0/16\n, not-an-ip, 10.0.0.0/33, ,::1, 172.16.5.4/12 ")
+
+	want := []string{"10.0.0.1", "192.168.0.0/16", "::1", "172.16.0.0/12"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected TrustedProxies %v, got: %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected TrustedProxies %v, got: %v", want, got)
+		}
+	}
+}
+
+func TestParseTrustedProxies_AllInvalid_ReturnsEmpty(t *testing.T) {
+	got := parseTrustedProxies("proxy.local, 300.1.1.1")
+	if got == nil || len(got) != 0 {
+		t.Errorf("Expected empty non-nil TrustedProxies, got: %#v", got)
+	}
+}
+
+func TestLoad_TrustedProxiesNotSet_IsNil(t *testing.T) {
+	// Set required env vars
+	os.Setenv("JWT_SECRET", "test-secret")
+	os.Unsetenv("TRUSTED_PROXIES")
+	defer os.Unsetenv("JWT_SECRET")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if cfg.Server.TrustedProxies != nil {
+		t.Errorf("Expected nil TrustedProxies, got: %v", cfg.Server.TrustedProxies)
+	}
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	profileKeyPattern  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// lookupFunc returns the value of a configuration variable, or "" when it is not set
+type lookupFunc func(key string) string
+
+// profileLookup returns a lookupFunc reading the environment layered over profile, the
+// variables of the profile named by MITSUME_PROFILE. A variable set in the environment wins
+// even when empty, so a profile's value can be cleared without editing the profile.
+func profileLookup(profile map[string]string) lookupFunc {
+	return func(key string) string {
+		if value, ok := os.LookupEnv(key); ok {
+			return value
+		}
+		return profile[key]
+	}
+}
+
+// loadProfile reads <dir>/<name>.env. An empty name loads no profile; a named profile that
+// cannot be read is an error rather than a silent fall back to defaults.
+func loadProfile(name, dir string) (map[string]string, error) {
+	if name == "" {
+		return nil, nil
+	}
+	if !profileNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("MITSUME_PROFILE must contain only letters, digits, '-' and '_', got: %s", name)
+	}
+
+	path := filepath.Join(dir, name+".env")
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open profile %q: %w", name, err)
+	}
+	defer f.Close()
+
+	values, err := parseProfile(f)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// parseProfile reads KEY=VALUE lines in the format of .env files. Blank lines and lines
+// starting with # are skipped, an "export " prefix is allowed and values may be quoted.
+func parseProfile(r io.Reader) (map[string]string, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !profileKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNo)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeProfile(t *testing.T, name, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name+".env"), []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write profile: %v", err)
+	}
+	return dir
+}
+
+// unsetEnv unsets key for the test; t.Setenv records the value to restore afterwards
+func unsetEnv(t *testing.T, key string) {
+	t.Helper()
+	t.Setenv(key, "")
+	os.Unsetenv(key)
+}
+
+func TestParseProfile(t *testing.T) {
+	values, err := parseProfile(strings.NewReader(`
+# staging
+GIN_MODE=release
+export DB_HOST = db.staging.internal
+TRINO_CATALOG="hive"
+SMTP_FROM='alerts@example.com'
+EMPTY=
+`))
+	if err != nil {
+		t.Fatalf("parseProfile() error = %v", err)
+	}
+
+	want := map[string]string{
+		"GIN_MODE":      "release",
+		"DB_HOST":       "db.staging.internal",
+		"TRINO_CATALOG": "hive",
+		"SMTP_FROM":     "alerts@example.com",
+		"EMPTY":         "",
+	}
+	if len(values) != len(want) {
+		t.Fatalf("values = %v, want %v", values, want)
+	}
+	for key, value := range want {
+		if values[key] != value {
+			t.Errorf("%s = %q, want %q", key, values[key], value)
+		}
+	}
+}
+
+func TestParseProfile_InvalidLine(t *testing.T) {
+	_, err := parseProfile(strings.NewReader("GIN_MODE=release\nnot a setting\n"))
+	if err == nil || !contains(err.Error(), "line 2") {
+		t.Fatalf("Expected an error naming line 2, got: %v", err)
+	}
+}
+
+func TestLoad_ProfileLayeredUnderEnvironment(t *testing.T) {
+	dir := writeProfile(t, "staging", "JWT_SECRET=profile-secret\nDB_HOST=db.staging.internal\nGIN_MODE=release\nMAX_DASHBOARD_PARAMETERS=10\nWEBHOOK_ALLOWED_HOSTS=hooks.staging.internal\n")
+	t.Setenv("MITSUME_PROFILE", "staging")
+	t.Setenv("MITSUME_PROFILE_DIR", dir)
+	unsetEnv(t, "JWT_SECRET")
+	unsetEnv(t, "DB_HOST")
+	unsetEnv(t, "MAX_DASHBOARD_PARAMETERS")
+	t.Setenv("GIN_MODE", "debug")         // the environment wins over the profile
+	t.Setenv("WEBHOOK_ALLOWED_HOSTS", "") // even when empty
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Profile != "staging" {
+		t.Errorf("Profile = %q, want staging", cfg.Profile)
+	}
+	if cfg.JWT.Secret != "profile-secret" {
+		t.Errorf("JWT.Secret = %q, want the profile's value", cfg.JWT.Secret)
+	}
+	if cfg.Database.Host != "db.staging.internal" {
+		t.Errorf("Database.Host = %q, want the profile's value", cfg.Database.Host)
+	}
+	if cfg.Dashboard.MaxParameters != 10 {
+		t.Errorf("Dashboard.MaxParameters = %d, want 10", cfg.Dashboard.MaxParameters)
+	}
+	if cfg.Server.Mode != "debug" {
+		t.Errorf("Server.Mode = %q, want the environment's value", cfg.Server.Mode)
+	}
+	if cfg.Webhook.AllowedHosts != nil {
+		t.Errorf("Webhook.AllowedHosts = %v, want the profile's value cleared by the empty environment variable", cfg.Webhook.AllowedHosts)
+	}
+	if cfg.Database.Port != "5432" {
+		t.Errorf("Database.Port = %q, want the default", cfg.Database.Port)
+	}
+
+	// Without a profile its values no longer apply
+	t.Setenv("MITSUME_PROFILE", "")
+	t.Setenv("JWT_SECRET", "test-secret")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Database.Host != "localhost" {
+		t.Errorf("Database.Host = %q, want the default after the profile is unset", cfg.Database.Host)
+	}
+}
+
+func TestLoad_ProfileValidated(t *testing.T) {
+	dir := writeProfile(t, "prod", "NOTIFICATION_ENCRYPTION_KEY=dG9vIHNob3J0\n")
+	t.Setenv("MITSUME_PROFILE", "prod")
+	t.Setenv("MITSUME_PROFILE_DIR", dir)
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	_, err := Load()
+	if err == nil || !contains(err.Error(), "NOTIFICATION_ENCRYPTION_KEY") {
+		t.Fatalf("Expected an error naming NOTIFICATION_ENCRYPTION_KEY, got: %v", err)
+	}
+}
+
+func TestLoad_ProfileMissingOrInvalidName(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("MITSUME_PROFILE_DIR", t.TempDir())
+
+	t.Setenv("MITSUME_PROFILE", "staging")
+	if _, err := Load(); err == nil || !contains(err.Error(), "staging") {
+		t.Fatalf("Expected an error for a missing profile, got: %v", err)
+	}
+
+	t.Setenv("MITSUME_PROFILE", "../secrets")
+	if _, err := Load(); err == nil || !contains(err.Error(), "MITSUME_PROFILE") {
+		t.Fatalf("Expected an error for an invalid profile name, got: %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := &Config{JWT: JWTConfig{Secret: "secret"}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	cfg.JWT.Secret = ""
+	if err := cfg.Validate(); err == nil || !contains(err.Error(), "JWT_SECRET") {
+		t.Fatalf("Expected an error naming JWT_SECRET, got: %v", err)
+	}
+
+	cfg.JWT.Secret = "secret"
+	cfg.Admin.PasswordMinLength = -1
+	if err := cfg.Validate(); err == nil || !contains(err.Error(), "non-negative") {
+		t.Fatalf("Expected a non-negative error, got: %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+// Package crypto encrypts secrets stored in the database with envelope encryption: each value
+// is sealed with its own random data key, and the data key is sealed with the master key from
+// the configuration. Both layers use AES-256-GCM.
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// KeySize is the size in bytes of master and data keys (AES-256)
+const KeySize = 32
+
+// envelopeVersion is written to every envelope so the format can change later
+const envelopeVersion = 1
+
+var (
+	ErrInvalidKey      = fmt.Errorf("encryption key must be %d bytes, base64-encoded", KeySize)
+	ErrDecryptFailed   = errors.New("failed to decrypt: wrong key or corrupted data")
+	ErrUnknownEnvelope = errors.New("unsupported envelope version")
+)
+
+// Envelope is an encrypted value with its sealed data key
+type Envelope struct {
+	Version    int    `json:"v"`
+	DataKey    string `json:"key"`  // data key sealed with the master key, base64
+	Ciphertext string `json:"data"` // value sealed with the data key, base64
+}
+
+// Cipher seals and opens envelopes with a master key
+type Cipher struct {
+	master cipher.AEAD
+}
+
+// NewCipher creates a Cipher from a KeySize-byte master key
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != KeySize {
+		return nil, ErrInvalidKey
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{master: aead}, nil
+}
+
+// NewCipherFromBase64 creates a Cipher from a base64-encoded master key, as read from the
+// environment
+func NewCipherFromBase64(encoded string) (*Cipher, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	return NewCipher(key)
+}
+
+// Seal encrypts plaintext under a new data key. additionalData is authenticated but not
+// encrypted; Open must be given the same value.
+func (c *Cipher) Seal(plaintext, additionalData []byte) (*Envelope, error) {
+	dataKey := make([]byte, KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sealedKey, err := seal(c.master, dataKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := seal(data, plaintext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{
+		Version:    envelopeVersion,
+		DataKey:    base64.StdEncoding.EncodeToString(sealedKey),
+		Ciphertext: base64.StdEncoding.EncodeToString(ciphertext),
+	}, nil
+}
+
+// Open decrypts an envelope sealed by Seal with the same master key and additional data
+func (c *Cipher) Open(e *Envelope, additionalData []byte) ([]byte, error) {
+	if e.Version != envelopeVersion {
+		return nil, ErrUnknownEnvelope
+	}
+	sealedKey, err := base64.StdEncoding.DecodeString(e.DataKey)
+	if err != nil {
+		return nil, ErrDecryptFailed
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(e.Ciphertext)
+	if err != nil {
+		return nil, ErrDecryptFailed
+	}
+
+	dataKey, err := open(c.master, sealedKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, ErrDecryptFailed
+	}
+	return open(data, ciphertext, additionalData)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts plaintext with a random nonce, returned as the prefix of the ciphertext
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrDecryptFailed
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrDecryptFailed
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,89 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func newTestCipher(t *testing.T, fill byte) *Cipher {
+	t.Helper()
+	c, err := NewCipher(bytes.Repeat([]byte{fill}, KeySize))
+	if err != nil {
+		t.Fatalf("NewCipher() error = %v", err)
+	}
+	return c
+}
+
+func TestCipher_SealOpenRoundTrip(t *testing.T) {
+	c := newTestCipher(t, 1)
+	plaintext := []byte(`{"webhook_url":"https://hooks.slack.com/services/T000/B000/XXXX"}`)
+
+	e, err := c.Seal(plaintext, []byte("aad"))
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if bytes.Contains([]byte(e.Ciphertext+e.DataKey), []byte("hooks.slack.com")) {
+		t.Fatalf("Seal() envelope contains the plaintext: %+v", e)
+	}
+
+	got, err := c.Open(e, []byte("aad"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("Open() = %s, want %s", got, plaintext)
+	}
+}
+
+func TestCipher_SealUsesFreshDataKeys(t *testing.T) {
+	c := newTestCipher(t, 1)
+	a, _ := c.Seal([]byte("secret"), nil)
+	b, _ := c.Seal([]byte("secret"), nil)
+	if a.DataKey == b.DataKey || a.Ciphertext == b.Ciphertext {
+		t.Fatal("Seal() produced identical envelopes for the same plaintext")
+	}
+}
+
+func TestCipher_OpenRejectsWrongKeyAndTampering(t *testing.T) {
+	c := newTestCipher(t, 1)
+	e, err := c.Seal([]byte("secret"), []byte("aad"))
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+
+	if _, err := newTestCipher(t, 2).Open(e, []byte("aad")); !errors.Is(err, ErrDecryptFailed) {
+		t.Errorf("Open() with another key error = %v, want %v", err, ErrDecryptFailed)
+	}
+	if _, err := c.Open(e, []byte("other")); !errors.Is(err, ErrDecryptFailed) {
+		t.Errorf("Open() with other additional data error = %v, want %v", err, ErrDecryptFailed)
+	}
+
+	tampered := *e
+	raw, _ := base64.StdEncoding.DecodeString(e.Ciphertext)
+	raw[len(raw)-1] ^= 0xff
+	tampered.Ciphertext = base64.StdEncoding.EncodeToString(raw)
+	if _, err := c.Open(&tampered, []byte("aad")); !errors.Is(err, ErrDecryptFailed) {
+		t.Errorf("Open() of tampered ciphertext error = %v, want %v", err, ErrDecryptFailed)
+	}
+
+	tampered = *e
+	tampered.Version = 2
+	if _, err := c.Open(&tampered, []byte("aad")); !errors.Is(err, ErrUnknownEnvelope) {
+		t.Errorf("Open() of unknown version error = %v, want %v", err, ErrUnknownEnvelope)
+	}
+}
+
+func TestNewCipherFromBase64(t *testing.T) {
+	valid := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, KeySize))
+	if _, err := NewCipherFromBase64(valid); err != nil {
+		t.Fatalf("NewCipherFromBase64() error = %v", err)
+	}
+
+	for _, key := range []string{"", "not base64!", base64.StdEncoding.EncodeToString([]byte("too short"))} {
+		if _, err := NewCipherFromBase64(key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("NewCipherFromBase64(%q) error = %v, want %v", key, err, ErrInvalidKey)
+		}
+	}
+}
@@ -327,6 +327,252 @@ func RunMigrations() error {
 
 		// Make email nullable for admin users
 		`ALTER TABLE users ALTER COLUMN email DROP NOT NULL`,
+
+		// Multi-condition alerts: conditions combined with logic_operator ('and'/'or').
+		// Alerts without rows in alert_conditions use the legacy condition_* columns.
+		`ALTER TABLE query_alerts ADD COLUMN IF NOT EXISTS logic_operator VARCHAR(10) DEFAULT 'and'`,
+		`CREATE TABLE IF NOT EXISTS alert_conditions (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			alert_id UUID NOT NULL REFERENCES query_alerts(id) ON DELETE CASCADE,
+			position INTEGER NOT NULL DEFAULT 0,
+			column_name VARCHAR(255) NOT NULL,
+			operator VARCHAR(20) NOT NULL,
+			value TEXT NOT NULL,
+			aggregation VARCHAR(20),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_alert_conditions_alert_id ON alert_conditions(alert_id, position)`,
+
+		// Alert snooze and acknowledgement
+		`ALTER TABLE query_alerts ADD COLUMN IF NOT EXISTS snoozed_until TIMESTAMP`,
+		`ALTER TABLE alert_history ADD COLUMN IF NOT EXISTS acknowledged_by UUID REFERENCES users(id) ON DELETE SET NULL`,
+		`ALTER TABLE alert_history ADD COLUMN IF NOT EXISTS acknowledged_at TIMESTAMP`,
+
+		// Refresh tokens and access token revocation
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version INTEGER NOT NULL DEFAULT 0`,
+		`CREATE TABLE IF NOT EXISTS refresh_tokens (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			token_hash VARCHAR(64) UNIQUE NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id)`,
+
+		// Widget error state tracking
+		`ALTER TABLE dashboard_widgets ADD COLUMN IF NOT EXISTS last_error TEXT`,
+		`ALTER TABLE dashboard_widgets ADD COLUMN IF NOT EXISTS last_error_at TIMESTAMP`,
+		`ALTER TABLE dashboards ADD COLUMN IF NOT EXISTS error_notification_channel_id UUID REFERENCES notification_channels(id) ON DELETE SET NULL`,
+
+		// User account status (active/disabled/pending)
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'active'`,
+
+		// Per-widget freshness requirement for cached results
+		`ALTER TABLE dashboard_widgets ADD COLUMN IF NOT EXISTS max_staleness_seconds INTEGER`,
+
+		// Chart annotations (deploys, incidents, ...); dashboard_id NULL means personal
+		`CREATE TABLE IF NOT EXISTS annotations (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			dashboard_id UUID REFERENCES dashboards(id) ON DELETE CASCADE,
+			occurred_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			label VARCHAR(255) NOT NULL,
+			color VARCHAR(20),
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_annotations_dashboard_time ON annotations(dashboard_id, occurred_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_annotations_user_time ON annotations(user_id, occurred_at) WHERE dashboard_id IS NULL`,
+
+		// Audit log of admin and permission-changing actions. actor_id has no foreign key so
+		// entries outlive deleted users.
+		`CREATE TABLE IF NOT EXISTS audit_log (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			actor_id UUID NOT NULL,
+			action VARCHAR(100) NOT NULL,
+			target_type VARCHAR(50) NOT NULL,
+			target_id UUID NOT NULL,
+			metadata JSONB,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id, created_at DESC)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action, created_at DESC)`,
+
+		// Session identity carried across refresh token rotation (absolute lifetime / idle timeout)
+		`ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS session_id UUID`,
+		`ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS session_started_at TIMESTAMP WITH TIME ZONE`,
+
+		// Who created / last edited dashboards and widgets (distinct from dashboard ownership; NULL when unknown)
+		`ALTER TABLE dashboards ADD COLUMN IF NOT EXISTS created_by UUID REFERENCES users(id) ON DELETE SET NULL`,
+		`ALTER TABLE dashboards ADD COLUMN IF NOT EXISTS updated_by UUID REFERENCES users(id) ON DELETE SET NULL`,
+		`ALTER TABLE dashboard_widgets ADD COLUMN IF NOT EXISTS created_by UUID REFERENCES users(id) ON DELETE SET NULL`,
+		`ALTER TABLE dashboard_widgets ADD COLUMN IF NOT EXISTS updated_by UUID REFERENCES users(id) ON DELETE SET NULL`,
+
+		// Role inheritance for catalog permissions (catalogs are unioned up the parent chain)
+		`ALTER TABLE roles ADD COLUMN IF NOT EXISTS parent_role_id UUID REFERENCES roles(id) ON DELETE SET NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_roles_parent_role_id ON roles(parent_role_id)`,
+
+		// Alert severity (critical alerts bypass the daily digest)
+		`ALTER TABLE query_alerts ADD COLUMN IF NOT EXISTS severity VARCHAR(20) NOT NULL DEFAULT 'warning'`,
+
+		// Per-user daily alert digest: settings and the triggers queued for the next digest
+		`CREATE TABLE IF NOT EXISTS alert_digest_settings (
+			user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			enabled BOOLEAN NOT NULL DEFAULT FALSE,
+			send_time VARCHAR(5) NOT NULL DEFAULT '09:00',
+			timezone VARCHAR(100) NOT NULL DEFAULT 'Asia/Tokyo',
+			last_sent_at TIMESTAMP WITH TIME ZONE,
+			next_send_at TIMESTAMP WITH TIME ZONE,
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_alert_digest_settings_next_send ON alert_digest_settings(next_send_at) WHERE next_send_at IS NOT NULL`,
+		`CREATE TABLE IF NOT EXISTS alert_digest_queue (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			channel_id UUID NOT NULL REFERENCES notification_channels(id) ON DELETE CASCADE,
+			alert_id UUID NOT NULL REFERENCES query_alerts(id) ON DELETE CASCADE,
+			alert_name VARCHAR(255) NOT NULL,
+			severity VARCHAR(20) NOT NULL,
+			condition_met_value TEXT,
+			triggered_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_alert_digest_queue_user ON alert_digest_queue(user_id, triggered_at)`,
+
+		// Dashboard activity feed: create/update/delete events on dashboards and their widgets
+		`CREATE TABLE IF NOT EXISTS dashboard_events (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			dashboard_id UUID NOT NULL REFERENCES dashboards(id) ON DELETE CASCADE,
+			actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+			action VARCHAR(20) NOT NULL,
+			target_type VARCHAR(20) NOT NULL,
+			target_id UUID NOT NULL,
+			target_name VARCHAR(255) NOT NULL DEFAULT '',
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_dashboard_events_dashboard ON dashboard_events(dashboard_id, created_at DESC)`,
+
+		// Role-Schema permissions (schema_name '*' grants every schema of the catalog)
+		`CREATE TABLE IF NOT EXISTS role_schema_permissions (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			role_id UUID REFERENCES roles(id) ON DELETE CASCADE,
+			catalog_name VARCHAR(255) NOT NULL,
+			schema_name VARCHAR(255) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(role_id, catalog_name, schema_name)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_role_schema_permissions_role_id ON role_schema_permissions(role_id)`,
+
+		// Tag query history with the executing feature (query, widget, alert, ...)
+		`ALTER TABLE query_history ADD COLUMN IF NOT EXISTS source VARCHAR(50) NOT NULL DEFAULT 'query'`,
+		`ALTER TABLE query_history ADD COLUMN IF NOT EXISTS source_id UUID`,
+		`CREATE INDEX IF NOT EXISTS idx_query_history_source ON query_history(source, source_id)`,
+
+		// Refresh tokens record the token version they were issued at (NULL for older tokens)
+		`ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS token_version INTEGER`,
+
+		// Generic OIDC sign-in: the provider's subject identifies the user
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS oidc_subject VARCHAR(255)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_oidc_subject ON users(oidc_subject) WHERE oidc_subject IS NOT NULL`,
+
+		// Chart themes (colors, fonts) that widgets reference by ID; system themes are managed by admins
+		`CREATE TABLE IF NOT EXISTS chart_themes (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+			name VARCHAR(255) NOT NULL,
+			config JSONB NOT NULL,
+			is_system BOOLEAN DEFAULT FALSE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_chart_themes_user_id ON chart_themes(user_id) WHERE user_id IS NOT NULL`,
+		`ALTER TABLE dashboard_widgets ADD COLUMN IF NOT EXISTS theme_id UUID REFERENCES chart_themes(id) ON DELETE SET NULL`,
+
+		// Archived dashboards are hidden from the default list but otherwise unchanged
+		`ALTER TABLE dashboards ADD COLUMN IF NOT EXISTS is_archived BOOLEAN NOT NULL DEFAULT false`,
+
+		// Soft delete: deleted dashboards stay in the owner's trash until restored or purged
+		`ALTER TABLE dashboards ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP`,
+		`CREATE INDEX IF NOT EXISTS idx_dashboards_deleted_at ON dashboards(deleted_at) WHERE deleted_at IS NOT NULL`,
+
+		// Per-dashboard catalog/schema used by widget queries that do not set their own
+		`ALTER TABLE dashboards ADD COLUMN IF NOT EXISTS default_catalog VARCHAR(255)`,
+		`ALTER TABLE dashboards ADD COLUMN IF NOT EXISTS default_schema VARCHAR(255)`,
+
+		// Version history: a snapshot of the published dashboard taken each time a draft is published over it
+		`CREATE TABLE IF NOT EXISTS dashboard_versions (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			dashboard_id UUID NOT NULL REFERENCES dashboards(id) ON DELETE CASCADE,
+			version INTEGER NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			description TEXT,
+			layout JSONB,
+			parameters JSONB,
+			default_catalog VARCHAR(255),
+			default_schema VARCHAR(255),
+			widgets JSONB NOT NULL DEFAULT '[]',
+			created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (dashboard_id, version)
+		)`,
+
+		// Subscription run history: one row per delivery attempt with each channel's outcome
+		`CREATE TABLE IF NOT EXISTS subscription_history (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			subscription_id UUID NOT NULL REFERENCES dashboard_subscriptions(id) ON DELETE CASCADE,
+			run_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			status VARCHAR(50) NOT NULL,
+			channel_results JSONB NOT NULL DEFAULT '[]',
+			error_message TEXT
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_subscription_history_subscription ON subscription_history(subscription_id, run_at DESC)`,
+
+		// In-app notifications: the inbox of users subscribed through an in_app channel
+		`CREATE TABLE IF NOT EXISTS in_app_notifications (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			title TEXT NOT NULL,
+			body TEXT NOT NULL DEFAULT '',
+			read_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_in_app_notifications_user ON in_app_notifications(user_id, created_at DESC)`,
+
+		// Per-role row filters, applied by rewriting queries that read the table
+		`CREATE TABLE IF NOT EXISTS role_row_filters (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+			catalog_name VARCHAR(255) NOT NULL,
+			schema_name VARCHAR(255) NOT NULL,
+			table_name VARCHAR(255) NOT NULL,
+			predicate TEXT NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_role_row_filters_role_id ON role_row_filters(role_id)`,
+
+		// Per-role cap on query timeouts (NULL: QUERY_MAX_TIMEOUT_SECONDS applies)
+		`ALTER TABLE roles ADD COLUMN IF NOT EXISTS max_query_timeout_seconds INTEGER`,
+
+		// Fixed-interval subscription schedules (NULL: the subscription runs on schedule_cron)
+		`ALTER TABLE dashboard_subscriptions ADD COLUMN IF NOT EXISTS schedule_interval_minutes INTEGER`,
+
+		// Timezone relative date parameters are resolved in (NULL: Asia/Tokyo)
+		`ALTER TABLE dashboards ADD COLUMN IF NOT EXISTS timezone VARCHAR(100)`,
+
+		// Server-wide settings changed at runtime by admins, such as maintenance mode
+		`CREATE TABLE IF NOT EXISTS system_settings (
+			key VARCHAR(100) PRIMARY KEY,
+			value JSONB NOT NULL,
+			updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Ad-hoc SQL on a widget, run instead of query_id when set
+		`ALTER TABLE dashboard_widgets ADD COLUMN IF NOT EXISTS query_text TEXT`,
+		`ALTER TABLE dashboard_widgets ADD COLUMN IF NOT EXISTS catalog VARCHAR(255)`,
+		`ALTER TABLE dashboard_widgets ADD COLUMN IF NOT EXISTS schema_name VARCHAR(255)`,
+
+		// When the owner of a trashed dashboard was warned that it will be purged
+		`ALTER TABLE dashboards ADD COLUMN IF NOT EXISTS purge_warned_at TIMESTAMP`,
 	}
 
 	for _, migration := range migrations {
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TxBeginner starts transactions; *pgxpool.Pool implements it
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// WithTx runs fn in a transaction on the shared pool. The transaction is committed when fn
+// returns nil and rolled back when it returns an error or panics; the panic is re-raised.
+func WithTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	return WithTxOn(ctx, pool, fn)
+}
+
+// WithTxOn is WithTx for a pool other than the shared one, such as a repository's
+func WithTxOn(ctx context.Context, db TxBeginner, fn func(tx pgx.Tx) error) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback(ctx)
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback(ctx)
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// fakeTx records whether it was committed or rolled back
+type fakeTx struct {
+	pgx.Tx
+	committed  bool
+	rolledBack bool
+}
+
+func (t *fakeTx) Commit(ctx context.Context) error {
+	t.committed = true
+	return nil
+}
+
+func (t *fakeTx) Rollback(ctx context.Context) error {
+	t.rolledBack = true
+	return nil
+}
+
+type fakeBeginner struct {
+	tx  *fakeTx
+	err error
+}
+
+func (b *fakeBeginner) Begin(ctx context.Context) (pgx.Tx, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	b.tx = &fakeTx{}
+	return b.tx, nil
+}
+
+func TestWithTxOn_CommitsOnSuccess(t *testing.T) {
+	db := &fakeBeginner{}
+	if err := WithTxOn(context.Background(), db, func(tx pgx.Tx) error { return nil }); err != nil {
+		t.Fatalf("WithTxOn() error = %v", err)
+	}
+	if !db.tx.committed || db.tx.rolledBack {
+		t.Errorf("committed = %v, rolled back = %v, want committed only", db.tx.committed, db.tx.rolledBack)
+	}
+}
+
+func TestWithTxOn_RollsBackOnError(t *testing.T) {
+	db := &fakeBeginner{}
+	want := errors.New("insert failed")
+	if err := WithTxOn(context.Background(), db, func(tx pgx.Tx) error { return want }); !errors.Is(err, want) {
+		t.Fatalf("WithTxOn() error = %v, want %v", err, want)
+	}
+	if db.tx.committed || !db.tx.rolledBack {
+		t.Errorf("committed = %v, rolled back = %v, want rolled back only", db.tx.committed, db.tx.rolledBack)
+	}
+}
+
+func TestWithTxOn_RollsBackAndRepanicsOnPanic(t *testing.T) {
+	db := &fakeBeginner{}
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("recovered %v, want the original panic", p)
+		}
+		if db.tx.committed || !db.tx.rolledBack {
+			t.Errorf("committed = %v, rolled back = %v, want rolled back only", db.tx.committed, db.tx.rolledBack)
+		}
+	}()
+	_ = WithTxOn(context.Background(), db, func(tx pgx.Tx) error { panic("boom") })
+}
+
+func TestWithTxOn_BeginError(t *testing.T) {
+	want := errors.New("connection refused")
+	called := false
+	err := WithTxOn(context.Background(), &fakeBeginner{err: want}, func(tx pgx.Tx) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, want) || called {
+		t.Errorf("WithTxOn() error = %v, fn called = %v, want the Begin error without calling fn", err, called)
+	}
+}
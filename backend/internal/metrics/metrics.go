@@ -0,0 +1,186 @@
+// Package metrics defines the Prometheus collectors exposed on /metrics.
+//
+// Collectors are always safe to update; they are only registered (and therefore
+// exported) when metrics are enabled via METRICS_ENABLED.
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "mitsume"
+
+// otherCatalog is the catalog label of queries against catalogs not set with SetCatalogLabels
+const otherCatalog = "other"
+
+var (
+	// QueryDuration observes Trino query execution time, labeled by catalog and status (success/error)
+	QueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "query_duration_seconds",
+		Help:      "Trino query execution duration in seconds.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"catalog", "status"})
+
+	// QueriesTotal counts executed Trino queries, labeled by catalog and status (success/error)
+	QueriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "queries_total",
+		Help:      "Total number of Trino queries executed.",
+	}, []string{"catalog", "status"})
+
+	// CacheRequestsTotal counts query cache lookups, labeled by result (hit/miss)
+	CacheRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Total number of query cache lookups.",
+	}, []string{"result"})
+
+	// AlertEvaluationsTotal counts alert evaluations, labeled by outcome (triggered/not_triggered/error)
+	AlertEvaluationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "alert_evaluations_total",
+		Help:      "Total number of alert evaluations.",
+	}, []string{"outcome"})
+
+	// AlertTriggersTotal counts alert notifications actually dispatched (outside cooldown)
+	AlertTriggersTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "alert_triggers_total",
+		Help:      "Total number of alerts that fired and dispatched notifications.",
+	})
+
+	// SubscriptionRunsTotal counts subscription executions, labeled by status (success/error)
+	SubscriptionRunsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "subscription_runs_total",
+		Help:      "Total number of subscription executions.",
+	}, []string{"status"})
+
+	// NotificationsSentTotal counts notification sends, labeled by channel type and status (success/error)
+	NotificationsSentTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_sent_total",
+		Help:      "Total number of notification sends.",
+	}, []string{"channel_type", "status"})
+
+	// HTTPRequestsInFlight tracks HTTP requests currently being served
+	HTTPRequestsInFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "Number of HTTP requests currently being served.",
+	})
+
+	// HTTPRequestsTotal counts HTTP requests, labeled by method, route and status code
+	HTTPRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Total number of HTTP requests.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration observes HTTP request latency, labeled by method and route
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+)
+
+var (
+	registry     = prometheus.NewRegistry()
+	registerOnce sync.Once
+
+	catalogLabelsMu sync.RWMutex
+	catalogLabels   = map[string]bool{}
+)
+
+// SetCatalogLabels sets the catalogs whose queries are labeled with the catalog name. The
+// catalog of a query comes from the request, so queries against any other catalog are labeled
+// "other" to keep the number of series bounded.
+func SetCatalogLabels(catalogs []string) {
+	labels := make(map[string]bool, len(catalogs))
+	for _, catalog := range catalogs {
+		labels[catalog] = true
+	}
+	catalogLabelsMu.Lock()
+	catalogLabels = labels
+	catalogLabelsMu.Unlock()
+}
+
+// catalogLabel returns the label value for catalog
+func catalogLabel(catalog string) string {
+	catalogLabelsMu.RLock()
+	defer catalogLabelsMu.RUnlock()
+	if catalogLabels[catalog] {
+		return catalog
+	}
+	return otherCatalog
+}
+
+// Register registers all collectors (plus Go runtime and process collectors).
+// It is safe to call more than once.
+func Register() {
+	registerOnce.Do(func() {
+		registry.MustRegister(
+			collectors.NewGoCollector(),
+			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+			QueryDuration,
+			QueriesTotal,
+			CacheRequestsTotal,
+			AlertEvaluationsTotal,
+			AlertTriggersTotal,
+			SubscriptionRunsTotal,
+			NotificationsSentTotal,
+			HTTPRequestsInFlight,
+			HTTPRequestsTotal,
+			HTTPRequestDuration,
+		)
+	})
+}
+
+// Handler returns the HTTP handler serving the registered metrics
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveQuery records a single query execution. Catalogs not set with SetCatalogLabels are
+// recorded as "other".
+func ObserveQuery(catalog string, duration time.Duration, err error) {
+	catalog = catalogLabel(catalog)
+	status := statusLabel(err)
+	QueriesTotal.WithLabelValues(catalog, status).Inc()
+	QueryDuration.WithLabelValues(catalog, status).Observe(duration.Seconds())
+}
+
+// ObserveCacheLookup records a cache hit or miss
+func ObserveCacheLookup(hit bool) {
+	if hit {
+		CacheRequestsTotal.WithLabelValues("hit").Inc()
+		return
+	}
+	CacheRequestsTotal.WithLabelValues("miss").Inc()
+}
+
+// ObserveSubscriptionRun records a subscription execution
+func ObserveSubscriptionRun(err error) {
+	SubscriptionRunsTotal.WithLabelValues(statusLabel(err)).Inc()
+}
+
+// ObserveNotification records a notification send to a channel of the given type
+func ObserveNotification(channelType string, err error) {
+	NotificationsSentTotal.WithLabelValues(channelType, statusLabel(err)).Inc()
+}
+
+func statusLabel(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
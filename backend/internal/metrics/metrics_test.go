@@ -0,0 +1,23 @@
+package metrics
+
+import "testing"
+
+func TestCatalogLabel(t *testing.T) {
+	SetCatalogLabels([]string{"hive", "iceberg"})
+	t.Cleanup(func() { SetCatalogLabels(nil) })
+
+	tests := []struct {
+		catalog string
+		want    string
+	}{
+		{"hive", "hive"},
+		{"iceberg", "iceberg"},
+		{"made_up_by_a_request", "other"},
+		{"", "other"},
+	}
+	for _, tt := range tests {
+		if got := catalogLabel(tt.catalog); got != tt.want {
+			t.Errorf("catalogLabel(%q) = %q, want %q", tt.catalog, got, tt.want)
+		}
+	}
+}
@@ -32,6 +32,31 @@ const (
 	AggregationFirst Aggregation = "first"
 )
 
+// LogicOperator determines how multiple alert conditions are combined
+type LogicOperator string
+
+const (
+	LogicAnd LogicOperator = "and"
+	LogicOr  LogicOperator = "or"
+)
+
+// AlertSeverity ranks how urgent an alert is
+type AlertSeverity string
+
+const (
+	SeverityInfo     AlertSeverity = "info"
+	SeverityWarning  AlertSeverity = "warning"
+	SeverityCritical AlertSeverity = "critical" // always notified immediately, even in digest mode
+)
+
+// AlertCondition is a single column/threshold check within an alert
+type AlertCondition struct {
+	Column      string            `json:"column" binding:"required"`
+	Operator    ConditionOperator `json:"operator" binding:"required"`
+	Value       string            `json:"value" binding:"required"`
+	Aggregation *Aggregation      `json:"aggregation"`
+}
+
 // QueryAlert represents a threshold-based alert
 type QueryAlert struct {
 	ID                   uuid.UUID         `json:"id"`
@@ -43,31 +68,60 @@ type QueryAlert struct {
 	ConditionOperator    ConditionOperator `json:"condition_operator"`
 	ConditionValue       string            `json:"condition_value"`
 	Aggregation          *Aggregation      `json:"aggregation"`
+	LogicOperator        LogicOperator     `json:"logic_operator"`
+	Conditions           []AlertCondition  `json:"conditions,omitempty"`
+	Severity             AlertSeverity     `json:"severity"`
 	CheckIntervalMinutes int               `json:"check_interval_minutes"`
 	CooldownMinutes      int               `json:"cooldown_minutes"`
 	IsActive             bool              `json:"is_active"`
 	LastCheckedAt        *time.Time        `json:"last_checked_at"`
 	LastTriggeredAt      *time.Time        `json:"last_triggered_at"`
 	NextCheckAt          *time.Time        `json:"next_check_at"`
+	SnoozedUntil         *time.Time        `json:"snoozed_until"`
 	CreatedAt            time.Time         `json:"created_at"`
 	UpdatedAt            time.Time         `json:"updated_at"`
 	ChannelIDs           []uuid.UUID       `json:"channel_ids,omitempty"`
 }
 
-// CreateAlertRequest is the request body for creating an alert
+// CreateAlertRequest is the request body for creating an alert.
+// Either the single condition_* fields or a non-empty conditions list is required.
 type CreateAlertRequest struct {
 	QueryID              uuid.UUID         `json:"query_id" binding:"required"`
 	Name                 string            `json:"name" binding:"required"`
 	Description          *string           `json:"description"`
-	ConditionColumn      string            `json:"condition_column" binding:"required"`
-	ConditionOperator    ConditionOperator `json:"condition_operator" binding:"required"`
-	ConditionValue       string            `json:"condition_value" binding:"required"`
+	ConditionColumn      string            `json:"condition_column"`
+	ConditionOperator    ConditionOperator `json:"condition_operator"`
+	ConditionValue       string            `json:"condition_value"`
 	Aggregation          *Aggregation      `json:"aggregation"`
+	LogicOperator        LogicOperator     `json:"logic_operator"`
+	Conditions           []AlertCondition  `json:"conditions" binding:"omitempty,dive"`
+	Severity             AlertSeverity     `json:"severity"`
 	CheckIntervalMinutes int               `json:"check_interval_minutes"`
 	CooldownMinutes      int               `json:"cooldown_minutes"`
 	ChannelIDs           []uuid.UUID       `json:"channel_ids" binding:"required"`
 }
 
+// PreviewAlertRequest is the part of a CreateAlertRequest needed to evaluate an alert, so an
+// alert being edited can be previewed with the same body. Other fields are ignored.
+type PreviewAlertRequest struct {
+	QueryID           uuid.UUID         `json:"query_id" binding:"required"`
+	ConditionColumn   string            `json:"condition_column"`
+	ConditionOperator ConditionOperator `json:"condition_operator"`
+	ConditionValue    string            `json:"condition_value"`
+	Aggregation       *Aggregation      `json:"aggregation"`
+	LogicOperator     LogicOperator     `json:"logic_operator"`
+	Conditions        []AlertCondition  `json:"conditions" binding:"omitempty,dive"`
+}
+
+// AlertPreviewResponse is the outcome of evaluating an unsaved alert definition
+type AlertPreviewResponse struct {
+	Triggered     bool             `json:"triggered"`
+	ActualValue   string           `json:"actual_value"` // empty when the query returned no rows
+	RowCount      int              `json:"row_count"`
+	LogicOperator LogicOperator    `json:"logic_operator"`
+	Conditions    []AlertCondition `json:"conditions"`
+}
+
 // UpdateAlertRequest is the request body for updating an alert
 type UpdateAlertRequest struct {
 	Name                 string            `json:"name,omitempty"`
@@ -76,6 +130,9 @@ type UpdateAlertRequest struct {
 	ConditionOperator    ConditionOperator `json:"condition_operator,omitempty"`
 	ConditionValue       string            `json:"condition_value,omitempty"`
 	Aggregation          *Aggregation      `json:"aggregation,omitempty"`
+	LogicOperator        LogicOperator     `json:"logic_operator,omitempty"`
+	Conditions           []AlertCondition  `json:"conditions,omitempty" binding:"omitempty,dive"`
+	Severity             AlertSeverity     `json:"severity,omitempty"`
 	CheckIntervalMinutes int               `json:"check_interval_minutes,omitempty"`
 	CooldownMinutes      int               `json:"cooldown_minutes,omitempty"`
 	IsActive             *bool             `json:"is_active,omitempty"`
@@ -91,6 +148,42 @@ type AlertHistory struct {
 	NotificationStatus  string          `json:"notification_status"`
 	NotificationDetails json.RawMessage `json:"notification_details"`
 	ErrorMessage        *string         `json:"error_message"`
+	AcknowledgedBy      *uuid.UUID      `json:"acknowledged_by"`
+	AcknowledgedAt      *time.Time      `json:"acknowledged_at"`
+}
+
+// SnoozeAlertRequest is the request body for snoozing an alert's notifications
+type SnoozeAlertRequest struct {
+	DurationMinutes int `json:"duration_minutes" binding:"required,min=1,max=43200"`
+}
+
+// AlertDigestSettings is a user's choice to receive non-critical alert triggers as one daily
+// digest per channel instead of individual notifications
+type AlertDigestSettings struct {
+	UserID     uuid.UUID  `json:"user_id"`
+	Enabled    bool       `json:"enabled"`
+	SendTime   string     `json:"send_time"` // HH:MM in Timezone
+	Timezone   string     `json:"timezone"`
+	LastSentAt *time.Time `json:"last_sent_at"`
+	NextSendAt *time.Time `json:"next_send_at"`
+}
+
+// UpdateAlertDigestSettingsRequest is the request body for changing digest settings
+type UpdateAlertDigestSettingsRequest struct {
+	Enabled  bool   `json:"enabled"`
+	SendTime string `json:"send_time"`
+	Timezone string `json:"timezone"`
+}
+
+// AlertDigestEntry is a trigger waiting to be sent in the next digest for one channel
+type AlertDigestEntry struct {
+	ID                uuid.UUID
+	ChannelID         uuid.UUID
+	AlertID           uuid.UUID
+	AlertName         string
+	Severity          AlertSeverity
+	ConditionMetValue string
+	TriggeredAt       time.Time
 }
 
 // NotificationMessage represents a notification payload
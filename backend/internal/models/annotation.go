@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Annotation marks a point in time (a deploy, an incident, ...) on time-series charts.
+// Dashboard annotations (DashboardID set) are shared with everyone who can view the dashboard;
+// personal annotations (DashboardID nil) are shown only to their creator, on every dashboard.
+type Annotation struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	DashboardID *uuid.UUID `json:"dashboard_id,omitempty"`
+	OccurredAt  time.Time  `json:"occurred_at"`
+	Label       string     `json:"label"`
+	Color       *string    `json:"color,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type CreateAnnotationRequest struct {
+	DashboardID *uuid.UUID `json:"dashboard_id"`
+	OccurredAt  time.Time  `json:"occurred_at" binding:"required"`
+	Label       string     `json:"label" binding:"required,max=255"`
+	Color       *string    `json:"color" binding:"omitempty,hexcolor"`
+}
+
+type UpdateAnnotationRequest struct {
+	OccurredAt *time.Time `json:"occurred_at"`
+	Label      string     `json:"label" binding:"omitempty,max=255"`
+	Color      *string    `json:"color" binding:"omitempty,hexcolor"`
+}
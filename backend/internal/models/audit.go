@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit actions recorded by AuditService
+const (
+	AuditActionRoleCreate          = "role.create"
+	AuditActionRoleUpdate          = "role.update"
+	AuditActionRoleDelete          = "role.delete"
+	AuditActionRoleSetCatalogs     = "role.set_catalogs"
+	AuditActionRoleSetSchemas      = "role.set_schemas"
+	AuditActionRoleSetRowFilters   = "role.set_row_filters"
+	AuditActionRoleSetTimeout      = "role.set_query_timeout"
+	AuditActionUserAssignRole      = "user.assign_role"
+	AuditActionUserUnassignRole    = "user.unassign_role"
+	AuditActionUserSetStatus       = "user.set_status"
+	AuditActionUserApprove         = "user.approve"
+	AuditActionUserResetPassword   = "user.reset_password"
+	AuditActionDashboardGrant      = "dashboard.grant_permission"
+	AuditActionDashboardRevoke     = "dashboard.revoke_permission"
+	AuditActionDashboardVisibility = "dashboard.update_visibility"
+	AuditActionWidgetDebug         = "dashboard.debug_widget"
+	AuditActionSetMaintenance      = "system.set_maintenance"
+)
+
+// Audit target types
+const (
+	AuditTargetRole      = "role"
+	AuditTargetUser      = "user"
+	AuditTargetDashboard = "dashboard"
+	AuditTargetSystem    = "system" // target ID is uuid.Nil
+)
+
+// AuditLogEntry records who performed an admin or permission-changing action on what
+type AuditLogEntry struct {
+	ID         uuid.UUID       `json:"id"`
+	ActorID    uuid.UUID       `json:"actor_id"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   uuid.UUID       `json:"target_id"`
+	Metadata   json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// AuditLogFilter narrows an audit log listing; zero fields match everything.
+// From is inclusive and To exclusive.
+type AuditLogFilter struct {
+	ActorID *uuid.UUID
+	Action  string
+	From    *time.Time
+	To      *time.Time
+	Limit   int
+	Offset  int
+}
+
+type AuditLogResponse struct {
+	Entries []AuditLogEntry `json:"entries"`
+	Total   int             `json:"total"`
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChartTheme is a stored set of chart styles (colors, fonts) that widgets reference by ID.
+// System themes are managed by admins and visible to everyone; other themes belong to one user.
+type ChartTheme struct {
+	ID        uuid.UUID       `json:"id"`
+	UserID    *uuid.UUID      `json:"user_id,omitempty"`
+	Name      string          `json:"name"`
+	Config    json.RawMessage `json:"config"`
+	IsSystem  bool            `json:"is_system"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type CreateChartThemeRequest struct {
+	Name     string          `json:"name" binding:"required"`
+	Config   json.RawMessage `json:"config" binding:"required"`
+	IsSystem bool            `json:"is_system"` // admins only
+}
+
+// ChartThemeConfig lists the chart_config keys a theme may set. The keys match chart_config so a
+// theme is applied by merging its config under the widget's own chart_config.
+type ChartThemeConfig struct {
+	ColorScheme     string   `json:"colorScheme,omitempty"`
+	CustomColors    []string `json:"customColors,omitempty"`
+	FontFamily      string   `json:"fontFamily,omitempty"`
+	FontSize        int      `json:"fontSize,omitempty"`
+	BackgroundColor string   `json:"backgroundColor,omitempty"`
+	TextColor       string   `json:"textColor,omitempty"`
+}
+
+// Chart theme validation constants
+const (
+	MaxChartThemeNameLength = 100
+	MaxChartThemeConfigSize = 8 * 1024
+	MaxChartThemeColors     = 20
+	MaxChartThemeFontFamily = 100
+	MaxChartThemeSchemeName = 50
+	MinChartThemeFontSize   = 8
+	MaxChartThemeFontSize   = 48
+)
+
+var (
+	hexColorPattern   = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+	fontFamilyPattern = regexp.MustCompile(`^[A-Za-z0-9 ,'"-]+$`)
+)
+
+// ValidateChartThemeConfig checks that a theme config is an object holding only the keys of
+// ChartThemeConfig, with hex colors and a plain font family list
+func ValidateChartThemeConfig(configJSON json.RawMessage) error {
+	if len(configJSON) > MaxChartThemeConfigSize {
+		return &ValidationError{Field: "config", Message: "config JSON too large (max 8KB)"}
+	}
+
+	var cfg ChartThemeConfig
+	dec := json.NewDecoder(bytes.NewReader(configJSON))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil || bytes.Equal(bytes.TrimSpace(configJSON), []byte("null")) {
+		return &ValidationError{Field: "config", Message: "config must be an object with colorScheme, customColors, fontFamily, fontSize, backgroundColor or textColor"}
+	}
+
+	if len(cfg.ColorScheme) > MaxChartThemeSchemeName {
+		return &ValidationError{Field: "config", Message: "colorScheme too long"}
+	}
+	if len(cfg.CustomColors) > MaxChartThemeColors {
+		return &ValidationError{Field: "config", Message: "too many customColors"}
+	}
+	for _, color := range cfg.CustomColors {
+		if !hexColorPattern.MatchString(color) {
+			return &ValidationError{Field: "config", Message: "invalid color in customColors: " + color}
+		}
+	}
+	if cfg.BackgroundColor != "" && !hexColorPattern.MatchString(cfg.BackgroundColor) {
+		return &ValidationError{Field: "config", Message: "invalid backgroundColor"}
+	}
+	if cfg.TextColor != "" && !hexColorPattern.MatchString(cfg.TextColor) {
+		return &ValidationError{Field: "config", Message: "invalid textColor"}
+	}
+	if cfg.FontFamily != "" && (len(cfg.FontFamily) > MaxChartThemeFontFamily || !fontFamilyPattern.MatchString(cfg.FontFamily)) {
+		return &ValidationError{Field: "config", Message: "invalid fontFamily"}
+	}
+	if cfg.FontSize != 0 && (cfg.FontSize < MinChartThemeFontSize || cfg.FontSize > MaxChartThemeFontSize) {
+		return &ValidationError{Field: "config", Message: "fontSize out of range"}
+	}
+
+	return nil
+}
+
+// MergeChartTheme returns chartConfig with the theme's keys filled in where chartConfig does not
+// set them, so a widget's own settings win over its theme. chartConfig is returned unchanged
+// when there is no theme or either side is not a JSON object.
+func MergeChartTheme(themeConfig, chartConfig json.RawMessage) json.RawMessage {
+	if len(themeConfig) == 0 {
+		return chartConfig
+	}
+
+	merged := map[string]json.RawMessage{}
+	if err := json.Unmarshal(themeConfig, &merged); err != nil || merged == nil {
+		return chartConfig
+	}
+	if len(chartConfig) > 0 {
+		var own map[string]json.RawMessage
+		if err := json.Unmarshal(chartConfig, &own); err != nil {
+			return chartConfig
+		}
+		for key, value := range own {
+			merged[key] = value
+		}
+	}
+
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return chartConfig
+	}
+	return data
+}
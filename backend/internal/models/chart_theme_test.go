@@ -0,0 +1,60 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValidateChartThemeConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{"colors and font", `{"customColors":["#5470c6","#91CC75"],"fontFamily":"Inter, sans-serif","fontSize":12}`, false},
+		{"named scheme", `{"colorScheme":"pastel","backgroundColor":"#fff","textColor":"#333333"}`, false},
+		{"empty object", `{}`, false},
+		{"null", `null`, true},
+		{"array", `["#ffffff"]`, true},
+		{"unknown key", `{"xAxis":"day"}`, true},
+		{"color name", `{"customColors":["red"]}`, true},
+		{"css injection in color", `{"backgroundColor":"#fff;position:fixed"}`, true},
+		{"css injection in font", `{"fontFamily":"Inter; } body { display: none"}`, true},
+		{"font too small", `{"fontSize":2}`, true},
+		{"font too large", `{"fontSize":200}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateChartThemeConfig(json.RawMessage(tt.config))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateChartThemeConfig(%s) error = %v, wantErr %v", tt.config, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMergeChartTheme(t *testing.T) {
+	tests := []struct {
+		name        string
+		theme       string
+		chartConfig string
+		want        string
+	}{
+		{"no theme", ``, `{"xAxis":"day"}`, `{"xAxis":"day"}`},
+		{"theme fills in", `{"colorScheme":"pastel"}`, `{"xAxis":"day"}`, `{"colorScheme":"pastel","xAxis":"day"}`},
+		{"widget wins", `{"colorScheme":"pastel","fontSize":12}`, `{"colorScheme":"vivid"}`, `{"colorScheme":"vivid","fontSize":12}`},
+		{"no chart config", `{"fontSize":12}`, ``, `{"fontSize":12}`},
+		{"null chart config", `{"fontSize":12}`, `null`, `{"fontSize":12}`},
+		{"chart config not an object", `{"fontSize":12}`, `[1]`, `[1]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MergeChartTheme(json.RawMessage(tt.theme), json.RawMessage(tt.chartConfig))
+			if string(got) != tt.want {
+				t.Fatalf("MergeChartTheme() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
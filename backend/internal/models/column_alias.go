@@ -0,0 +1,98 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// chartConfigColumnAliasesKey is the chart_config key holding a widget's column aliases: an
+// object mapping result column names to the labels returned in their place
+const chartConfigColumnAliasesKey = "columnAliases"
+
+// MaxColumnAliasLength caps the length of a column alias label
+const MaxColumnAliasLength = 255
+
+// ColumnAliases returns the column aliases set in a widget's chart_config, or nil when it sets
+// none. The config is assumed to have passed ValidateChartConfig.
+func ColumnAliases(chartConfigJSON json.RawMessage) map[string]string {
+	if len(chartConfigJSON) == 0 {
+		return nil
+	}
+	var cfg struct {
+		ColumnAliases map[string]string `json:"columnAliases"`
+	}
+	if err := json.Unmarshal(chartConfigJSON, &cfg); err != nil {
+		return nil
+	}
+	return cfg.ColumnAliases
+}
+
+// validateColumnAliases checks the columnAliases key of a chart_config: an object of non-empty
+// column names mapped to distinct, non-empty labels
+func validateColumnAliases(chartConfigJSON json.RawMessage) error {
+	var cfg map[string]json.RawMessage
+	if err := json.Unmarshal(chartConfigJSON, &cfg); err != nil {
+		return nil // not an object, so there are no aliases
+	}
+	raw, ok := cfg[chartConfigColumnAliasesKey]
+	if !ok || string(raw) == "null" {
+		return nil
+	}
+
+	var aliases map[string]string
+	if err := json.Unmarshal(raw, &aliases); err != nil {
+		return &ValidationError{Field: "chart_config", Message: "columnAliases must map column names to labels"}
+	}
+	labels := make(map[string]string, len(aliases))
+	for column, label := range aliases {
+		if strings.TrimSpace(column) == "" {
+			return &ValidationError{Field: "chart_config", Message: "columnAliases has an empty column name"}
+		}
+		if strings.TrimSpace(label) == "" {
+			return &ValidationError{Field: "chart_config", Message: "columnAliases has an empty label for column " + column}
+		}
+		if len(label) > MaxColumnAliasLength {
+			return &ValidationError{Field: "chart_config", Message: "columnAliases label too long for column " + column}
+		}
+		if other, dup := labels[label]; dup {
+			return &ValidationError{Field: "chart_config", Message: fmt.Sprintf("columnAliases gives columns %s and %s the same label", other, column)}
+		}
+		labels[label] = column
+	}
+	return nil
+}
+
+// ApplyColumnAliases returns a copy of result with aliased columns renamed; rows are shared with
+// result. Every alias must name a column of the result, and no label may collide with another
+// column's name.
+func ApplyColumnAliases(result *QueryResult, aliases map[string]string) (*QueryResult, error) {
+	if len(aliases) == 0 || result == nil {
+		return result, nil
+	}
+
+	present := make(map[string]bool, len(result.Columns))
+	for _, column := range result.Columns {
+		present[column] = true
+	}
+	for column := range aliases {
+		if !present[column] {
+			return nil, fmt.Errorf("column alias refers to unknown column: %s", column)
+		}
+	}
+
+	aliased := *result
+	aliased.Columns = make([]string, len(result.Columns))
+	seen := make(map[string]bool, len(result.Columns))
+	for i, column := range result.Columns {
+		if label, ok := aliases[column]; ok {
+			column = label
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("column alias %s duplicates another column", column)
+		}
+		seen[column] = true
+		aliased.Columns[i] = column
+	}
+	return &aliased, nil
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidateChartConfig_ColumnAliases(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{"no aliases", `{"xAxis":"day"}`, false},
+		{"aliases", `{"columnAliases":{"cnt":"Orders","rev":"Revenue"}}`, false},
+		{"null aliases", `{"columnAliases":null}`, false},
+		{"not an object", `{"columnAliases":["Orders"]}`, true},
+		{"non-string label", `{"columnAliases":{"cnt":1}}`, true},
+		{"empty label", `{"columnAliases":{"cnt":"  "}}`, true},
+		{"empty column", `{"columnAliases":{"":"Orders"}}`, true},
+		{"duplicate label", `{"columnAliases":{"cnt":"Total","rev":"Total"}}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateChartConfig(json.RawMessage(tt.config))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateChartConfig(%s) error = %v, wantErr %v", tt.config, err, tt.wantErr)
+			}
+			var validationErr *ValidationError
+			if err != nil && (!errors.As(err, &validationErr) || validationErr.Field != "chart_config") {
+				t.Fatalf("ValidateChartConfig() error = %#v, want a chart_config ValidationError", err)
+			}
+		})
+	}
+}
+
+func TestApplyColumnAliases(t *testing.T) {
+	result := &QueryResult{Columns: []string{"day", "cnt"}, Rows: [][]interface{}{{"2024-01-01", 3}}, RowCount: 1}
+
+	got, err := ApplyColumnAliases(result, ColumnAliases(json.RawMessage(`{"columnAliases":{"cnt":"Orders"}}`)))
+	if err != nil {
+		t.Fatalf("ApplyColumnAliases() error = %v", err)
+	}
+	if !reflect.DeepEqual(got.Columns, []string{"day", "Orders"}) || got.RowCount != 1 || len(got.Rows) != 1 {
+		t.Errorf("ApplyColumnAliases() = %+v, want cnt renamed to Orders and the rows kept", got)
+	}
+	if result.Columns[1] != "cnt" {
+		t.Errorf("original columns = %v, want them left unchanged", result.Columns)
+	}
+
+	if got, err := ApplyColumnAliases(result, nil); err != nil || got != result {
+		t.Errorf("ApplyColumnAliases() without aliases = %v, %v, want the result unchanged", got, err)
+	}
+	if _, err := ApplyColumnAliases(result, map[string]string{"revenue": "Revenue"}); err == nil {
+		t.Error("ApplyColumnAliases() with an unknown column error = nil, want an error")
+	}
+	if _, err := ApplyColumnAliases(result, map[string]string{"cnt": "day"}); err == nil {
+		t.Error("ApplyColumnAliases() with a label clashing with another column error = nil, want an error")
+	}
+	if got, err := ApplyColumnAliases(result, map[string]string{"cnt": "day", "day": "cnt"}); err != nil || !reflect.DeepEqual(got.Columns, []string{"cnt", "day"}) {
+		t.Errorf("ApplyColumnAliases() swapping names = %v, %v, want the columns swapped", got, err)
+	}
+}
@@ -43,9 +43,22 @@ type Dashboard struct {
 	Parameters  json.RawMessage `json:"parameters"`
 	IsDraft     bool            `json:"is_draft"`            // Draft mode flag
 	DraftOf     *uuid.UUID      `json:"draft_of,omitempty"`  // Original dashboard ID if this is a draft
+	IsArchived  bool            `json:"is_archived"`         // Hidden from the default list; still viewable and editable
+	DeletedAt   *time.Time      `json:"deleted_at,omitempty"` // Set while the dashboard is in the owner's trash
+	CreatedBy   *uuid.UUID      `json:"created_by"`          // User who created the dashboard (nil if unknown)
+	UpdatedBy   *uuid.UUID      `json:"updated_by"`          // User who last edited the dashboard (nil if unknown)
 	CreatedAt   time.Time       `json:"created_at"`
 	UpdatedAt   time.Time       `json:"updated_at"`
 	Widgets     []Widget        `json:"widgets,omitempty"`
+	// Number of widgets whose last data fetch failed
+	WidgetErrorCount int `json:"widget_error_count"`
+	// Channel notified when a widget starts failing (owner setting)
+	ErrorNotificationChannelID *uuid.UUID `json:"error_notification_channel_id,omitempty"`
+	// Catalog/schema for widget queries that do not set their own (nil uses the server defaults)
+	DefaultCatalog *string `json:"default_catalog,omitempty"`
+	DefaultSchema  *string `json:"default_schema,omitempty"`
+	// IANA timezone relative date parameters are resolved in (nil uses Asia/Tokyo)
+	Timezone *string `json:"timezone,omitempty"`
 	// Permission info (populated when fetching for a specific user)
 	MyPermission PermissionLevel       `json:"my_permission,omitempty"`
 	Permissions  []DashboardPermission `json:"permissions,omitempty"`
@@ -61,6 +74,9 @@ const (
 	ParameterTypeDateRange   ParameterType = "daterange"
 	ParameterTypeSelect      ParameterType = "select"
 	ParameterTypeMultiSelect ParameterType = "multiselect"
+	// A rolling period given as a token such as last_7_days or this_month, resolved to start/end
+	// dates in the dashboard's timezone when the query runs
+	ParameterTypeRelativeDate ParameterType = "relativedate"
 )
 
 // SqlFormat represents how the parameter value should be formatted in SQL
@@ -110,6 +126,11 @@ type ParameterDefinition struct {
 	OptionsQueryID *uuid.UUID        `json:"options_query_id,omitempty"` // Saved query ID for dynamic options
 	DependsOn      []string          `json:"depends_on,omitempty"`       // Cascade: parameter names this depends on
 	EmptyBehavior  EmptyBehavior     `json:"empty_behavior,omitempty"`   // How to handle empty values
+	AllowQualified bool              `json:"allow_qualified,omitempty"`  // Identifier format: accept dot-qualified names (schema.table)
+	Min            *float64          `json:"min,omitempty"`              // Number formats: smallest accepted value
+	Max            *float64          `json:"max,omitempty"`              // Number formats: largest accepted value
+	MinDate        string            `json:"min_date,omitempty"`         // Date format: earliest accepted date (YYYY-MM-DD)
+	MaxDate        string            `json:"max_date,omitempty"`         // Date format: latest accepted date (YYYY-MM-DD)
 }
 
 // DashboardPermission represents a permission granted to a user or role
@@ -132,14 +153,44 @@ type Widget struct {
 	DashboardID         uuid.UUID       `json:"dashboard_id"`
 	Name                string          `json:"name"`
 	QueryID             *uuid.UUID      `json:"query_id"`
+	// QueryText is ad-hoc SQL run instead of the saved query, in Catalog and Schema when set
+	QueryText           *string         `json:"query_text,omitempty"`
+	Catalog             *string         `json:"catalog,omitempty"`
+	Schema              *string         `json:"schema,omitempty"`
 	ChartType           string          `json:"chart_type"`
 	ChartConfig         json.RawMessage `json:"chart_config"`
 	Position            json.RawMessage `json:"position"`
 	ResponsivePositions json.RawMessage `json:"responsive_positions,omitempty"`
+	ThemeID             *uuid.UUID      `json:"theme_id,omitempty"`
+	ThemeConfig         json.RawMessage `json:"theme_config,omitempty"` // Config of the referenced theme, merged under chart_config when rendering
+	// MaxStalenessSeconds forces a re-run when the cached result is older than this, even within the cache TTL (nil or 0: any cached result is served)
+	MaxStalenessSeconds *int            `json:"max_staleness_seconds,omitempty"`
+	LastError           *string         `json:"last_error,omitempty"`
+	LastErrorAt         *time.Time      `json:"last_error_at,omitempty"`
+	CreatedBy           *uuid.UUID      `json:"created_by"` // User who created the widget (nil if unknown)
+	UpdatedBy           *uuid.UUID      `json:"updated_by"` // User who last edited the widget (nil if unknown)
 	CreatedAt           time.Time       `json:"created_at"`
 	UpdatedAt           time.Time       `json:"updated_at"`
 }
 
+// HasInlineQuery reports whether the widget runs its own SQL rather than a saved query
+func (w *Widget) HasInlineQuery() bool {
+	return w.QueryText != nil && *w.QueryText != ""
+}
+
+// HasQuery reports whether the widget has data to load (text widgets have none)
+func (w *Widget) HasQuery() bool {
+	return w.HasInlineQuery() || w.QueryID != nil
+}
+
+// UpdateErrorNotificationRequest sets (or clears, when null) the channel notified about failing widgets
+type UpdateErrorNotificationRequest struct {
+	ChannelID *uuid.UUID `json:"channel_id"`
+}
+
+// DashboardFilterArchived lists archived dashboards instead of the others (GET /dashboards?filter=archived)
+const DashboardFilterArchived = "archived"
+
 type CreateDashboardRequest struct {
 	Name        string  `json:"name" binding:"required"`
 	Description *string `json:"description"`
@@ -150,24 +201,40 @@ type UpdateDashboardRequest struct {
 	Description *string         `json:"description"`
 	Layout      json.RawMessage `json:"layout"`
 	Parameters  json.RawMessage `json:"parameters"`
+	// Omit to keep the current value, or send "" to fall back to the server defaults
+	DefaultCatalog *string `json:"default_catalog"`
+	DefaultSchema  *string `json:"default_schema"`
+	// Omit to keep the current timezone, or send "" to fall back to Asia/Tokyo
+	Timezone *string `json:"timezone"`
 }
 
 type CreateWidgetRequest struct {
 	Name                string          `json:"name" binding:"required"`
 	QueryID             *uuid.UUID      `json:"query_id"`
+	QueryText           *string         `json:"query_text,omitempty"`
+	Catalog             *string         `json:"catalog,omitempty"`
+	Schema              *string         `json:"schema,omitempty"`
 	ChartType           string          `json:"chart_type" binding:"required"`
 	ChartConfig         json.RawMessage `json:"chart_config"`
 	Position            json.RawMessage `json:"position" binding:"required"`
 	ResponsivePositions json.RawMessage `json:"responsive_positions,omitempty"`
+	ThemeID             *uuid.UUID      `json:"theme_id,omitempty"`
+	MaxStalenessSeconds *int            `json:"max_staleness_seconds,omitempty" binding:"omitempty,min=0"`
 }
 
 type UpdateWidgetRequest struct {
 	Name                string          `json:"name"`
 	QueryID             *uuid.UUID      `json:"query_id"`
+	// An empty query_text, catalog or schema clears it; a widget without query_text uses query_id again
+	QueryText           *string         `json:"query_text,omitempty"`
+	Catalog             *string         `json:"catalog,omitempty"`
+	Schema              *string         `json:"schema,omitempty"`
 	ChartType           string          `json:"chart_type"`
 	ChartConfig         json.RawMessage `json:"chart_config"`
 	Position            json.RawMessage `json:"position"`
 	ResponsivePositions json.RawMessage `json:"responsive_positions,omitempty"`
+	ThemeID             *uuid.UUID      `json:"theme_id,omitempty"`
+	MaxStalenessSeconds *int            `json:"max_staleness_seconds,omitempty" binding:"omitempty,min=0"`
 }
 
 // Dashboard permission request types
@@ -319,11 +386,63 @@ var ValidBreakpoints = map[string]int{
 	"xs": 4,  // Extra small: 4 columns
 }
 
+// ChartTypes lists every chart type supported by the frontend
+var ChartTypes = []string{
+	"bar", "line", "pie", "area", "scatter",
+	"table", "markdown", "counter", "pivot",
+	"donut", "combo", "heatmap",
+	"gauge", "progress",
+	"funnel", "treemap", "bubble", "sunburst", "boxplot",
+}
+
+// ValidateChartType checks that chartType is in the allowed list.
+// An empty allowed list permits every chart type.
+func ValidateChartType(chartType string, allowed []string) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+	for _, t := range allowed {
+		if t == chartType {
+			return nil
+		}
+	}
+	return &ValidationError{Field: "chart_type", Message: "chart type is not allowed: " + chartType}
+}
+
 // Chart config validation constants
 const (
 	MaxChartConfigSize = 64 * 1024 // 64KB limit for chart_config JSON
 )
 
+// Parameters validation constants
+const (
+	MaxParametersSize = 64 * 1024 // 64KB limit for dashboard parameters JSON
+)
+
+// ValidateDashboardParameters validates the size of a dashboard's parameters JSON and that it
+// holds at most maxCount parameter definitions (maxCount <= 0 disables the count limit)
+func ValidateDashboardParameters(parametersJSON json.RawMessage, maxCount int) error {
+	if len(parametersJSON) == 0 {
+		return nil // Parameters are not being updated
+	}
+
+	// Size check (prevent DoS with huge JSON)
+	if len(parametersJSON) > MaxParametersSize {
+		return &ValidationError{Field: "parameters", Message: "parameters JSON too large (max 64KB)"}
+	}
+
+	var params []json.RawMessage
+	if err := json.Unmarshal(parametersJSON, &params); err != nil {
+		return &ValidationError{Field: "parameters", Message: "invalid parameters format: must be an array of parameter definitions"}
+	}
+
+	if maxCount > 0 && len(params) > maxCount {
+		return &ValidationError{Field: "parameters", Message: "too many parameters (max " + strconv.Itoa(maxCount) + ")"}
+	}
+
+	return nil
+}
+
 // ValidateChartConfig validates chart_config JSONB field size
 func ValidateChartConfig(chartConfigJSON json.RawMessage) error {
 	if len(chartConfigJSON) == 0 {
@@ -341,7 +460,7 @@ func ValidateChartConfig(chartConfigJSON json.RawMessage) error {
 		return &ValidationError{Field: "chart_config", Message: "invalid chart_config JSON format"}
 	}
 
-	return nil
+	return validateColumnAliases(chartConfigJSON)
 }
 
 // ValidateResponsivePositions validates responsive_positions JSONB field
@@ -411,3 +530,96 @@ type BatchWidgetUpdateResponse struct {
 type PublishDraftRequest struct {
 	// No fields needed - draft ID comes from URL
 }
+
+// DashboardExportVersion is the schema version stamped on exported dashboards.
+// Imports of any other version are rejected.
+const DashboardExportVersion = 1
+
+// DashboardExport is a portable dashboard document used to move dashboards between environments
+// (GET /dashboards/:id/export, POST /dashboards/import)
+type DashboardExport struct {
+	Version    int               `json:"version" binding:"required"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Dashboard  ExportedDashboard `json:"dashboard" binding:"required"`
+	Widgets    []ExportedWidget  `json:"widgets"`
+	Queries    []ExportedQuery   `json:"queries"` // Saved queries referenced by widgets
+}
+
+// ExportedDashboard is the dashboard metadata of an export
+type ExportedDashboard struct {
+	Name        string          `json:"name" binding:"required"`
+	Description *string         `json:"description"`
+	Layout      json.RawMessage `json:"layout"`
+	Parameters  json.RawMessage `json:"parameters"`
+	// Catalog/schema defaults, omitted when the dashboard uses the server defaults
+	DefaultCatalog *string `json:"default_catalog,omitempty"`
+	DefaultSchema  *string `json:"default_schema,omitempty"`
+}
+
+// ExportedWidget is a widget of an export; QueryRef points at an ExportedQuery.Ref
+type ExportedWidget struct {
+	Name                string          `json:"name"`
+	QueryRef            *string         `json:"query_ref,omitempty"`
+	QueryText           *string         `json:"query_text,omitempty"`
+	Catalog             *string         `json:"catalog,omitempty"`
+	Schema              *string         `json:"schema,omitempty"`
+	ChartType           string          `json:"chart_type"`
+	ChartConfig         json.RawMessage `json:"chart_config"`
+	Position            json.RawMessage `json:"position"`
+	ResponsivePositions json.RawMessage `json:"responsive_positions,omitempty"`
+	MaxStalenessSeconds *int            `json:"max_staleness_seconds,omitempty"`
+}
+
+// ExportedQuery is a saved query definition of an export. Ref identifies it within the
+// document only; imports create a new saved query for it.
+type ExportedQuery struct {
+	Ref         string  `json:"ref"`
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+	QueryText   string  `json:"query_text"`
+	Catalog     *string `json:"catalog,omitempty"`
+	SchemaName  *string `json:"schema_name,omitempty"`
+}
+
+// Dashboard activity actions and target types recorded in dashboard_events
+const (
+	DashboardEventCreate    = "create"
+	DashboardEventUpdate    = "update"
+	DashboardEventDelete    = "delete"
+	DashboardEventArchive   = "archive"
+	DashboardEventUnarchive = "unarchive"
+	DashboardEventRestore   = "restore"
+
+	DashboardEventTargetDashboard = "dashboard"
+	DashboardEventTargetWidget    = "widget"
+)
+
+// DashboardEvent is one change to a dashboard or one of its widgets
+type DashboardEvent struct {
+	ID          uuid.UUID  `json:"id"`
+	DashboardID uuid.UUID  `json:"dashboard_id"`
+	ActorID     *uuid.UUID `json:"actor_id"` // nil once the user is deleted
+	ActorName   *string    `json:"actor_name"`
+	Action      string     `json:"action"`
+	TargetType  string     `json:"target_type"`
+	TargetID    uuid.UUID  `json:"target_id"`
+	TargetName  string     `json:"target_name"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type DashboardActivityResponse struct {
+	Events []DashboardEvent `json:"events"`
+	Total  int              `json:"total"`
+}
+
+// DashboardVersion is a snapshot of a published dashboard, taken when a draft was published
+// over it. Listings leave out the snapshot's layout and widgets.
+type DashboardVersion struct {
+	DashboardID uuid.UUID  `json:"dashboard_id"`
+	Version     int        `json:"version"`
+	Name        string     `json:"name"`
+	Description *string    `json:"description"`
+	WidgetCount int        `json:"widget_count"`
+	CreatedBy   *uuid.UUID `json:"created_by"` // User whose publish replaced this version (nil once deleted)
+	CreatedAt   time.Time  `json:"created_at"`
+}
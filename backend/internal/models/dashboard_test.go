@@ -0,0 +1,62 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func parameterList(n int) json.RawMessage {
+	params := make([]ParameterDefinition, n)
+	for i := range params {
+		params[i] = ParameterDefinition{Name: "p" + strconv.Itoa(i)}
+	}
+	data, _ := json.Marshal(params)
+	return data
+}
+
+func TestValidateDashboardParameters_Count(t *testing.T) {
+	tests := []struct {
+		name     string
+		params   json.RawMessage
+		maxCount int
+		wantErr  bool
+	}{
+		{"not updated", nil, 2, false},
+		{"empty array", json.RawMessage(`[]`), 2, false},
+		{"at the limit", parameterList(2), 2, false},
+		{"one over the limit", parameterList(3), 2, true},
+		{"limit disabled", parameterList(100), 0, false},
+		{"not an array", json.RawMessage(`{"name":"a"}`), 2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDashboardParameters(tt.params, tt.maxCount)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateDashboardParameters() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var validationErr *ValidationError
+			if err != nil && (!errors.As(err, &validationErr) || validationErr.Field != "parameters") {
+				t.Fatalf("ValidateDashboardParameters() error = %#v, want a parameters ValidationError", err)
+			}
+		})
+	}
+}
+
+func TestValidateDashboardParameters_Size(t *testing.T) {
+	// A single string parameter padded so the JSON is exactly n bytes long
+	sized := func(n int) json.RawMessage {
+		prefix, suffix := `[{"name":"`, `"}]`
+		return json.RawMessage(prefix + strings.Repeat("a", n-len(prefix)-len(suffix)) + suffix)
+	}
+
+	if err := ValidateDashboardParameters(sized(MaxParametersSize), 0); err != nil {
+		t.Fatalf("ValidateDashboardParameters() at %d bytes error = %v", MaxParametersSize, err)
+	}
+	if err := ValidateDashboardParameters(sized(MaxParametersSize+1), 0); err == nil {
+		t.Fatalf("ValidateDashboardParameters() at %d bytes error = nil, want too large", MaxParametersSize+1)
+	}
+}
@@ -0,0 +1,32 @@
+package models
+
+// DetailedHealth reports connection pool usage for operators
+type DetailedHealth struct {
+	Status   string             `json:"status"`
+	Database *DatabasePoolStats `json:"database,omitempty"`
+	Trino    []TrinoPoolStats   `json:"trino"`
+}
+
+// DatabasePoolStats is a snapshot of the PostgreSQL connection pool
+type DatabasePoolStats struct {
+	TotalConns           int32 `json:"total_conns"`
+	IdleConns            int32 `json:"idle_conns"`
+	AcquiredConns        int32 `json:"acquired_conns"`
+	ConstructingConns    int32 `json:"constructing_conns"`
+	MaxConns             int32 `json:"max_conns"`
+	AcquireCount         int64 `json:"acquire_count"`
+	EmptyAcquireCount    int64 `json:"empty_acquire_count"`
+	CanceledAcquireCount int64 `json:"canceled_acquire_count"`
+	AcquireDurationMs    int64 `json:"acquire_duration_ms"`
+}
+
+// TrinoPoolStats is a snapshot of one Trino connection pool; DSN has its user and host masked
+type TrinoPoolStats struct {
+	DSN                string `json:"dsn"`
+	OpenConnections    int    `json:"open_connections"`
+	InUse              int    `json:"in_use"`
+	Idle               int    `json:"idle"`
+	MaxOpenConnections int    `json:"max_open_connections"`
+	WaitCount          int64  `json:"wait_count"`
+	WaitDurationMs     int64  `json:"wait_duration_ms"`
+}
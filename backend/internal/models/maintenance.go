@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaintenanceStatus is the global maintenance switch. While it is enabled no query reaches
+// Trino: interactive execution answers 503 and the scheduler leaves alerts and subscriptions due.
+type MaintenanceStatus struct {
+	Enabled   bool       `json:"enabled"`
+	Message   string     `json:"message,omitempty"` // shown to users in the 503 response
+	UpdatedBy *uuid.UUID `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// UpdateMaintenanceRequest turns maintenance mode on or off
+type UpdateMaintenanceRequest struct {
+	Enabled *bool  `json:"enabled" binding:"required"`
+	Message string `json:"message"`
+}
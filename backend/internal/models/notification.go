@@ -14,6 +14,7 @@ const (
 	ChannelTypeSlack      ChannelType = "slack"
 	ChannelTypeEmail      ChannelType = "email"
 	ChannelTypeGoogleChat ChannelType = "google_chat"
+	ChannelTypeInApp      ChannelType = "in_app"
 )
 
 // NotificationChannel represents a configured notification destination
@@ -28,6 +29,31 @@ type NotificationChannel struct {
 	UpdatedAt   time.Time       `json:"updated_at"`
 }
 
+// channelSecretFields lists the config fields of each channel type that hold credentials
+var channelSecretFields = map[ChannelType][]string{
+	ChannelTypeSlack:      {"webhook_url"},
+	ChannelTypeGoogleChat: {"webhook_url"},
+}
+
+// Redacted returns a copy of the channel for API responses, with the secret fields of its
+// config removed. A config that is not a JSON object is replaced with an empty one.
+func (ch NotificationChannel) Redacted() NotificationChannel {
+	var config map[string]json.RawMessage
+	if err := json.Unmarshal(ch.Config, &config); err != nil || config == nil {
+		ch.Config = json.RawMessage(`{}`)
+		return ch
+	}
+	for _, field := range channelSecretFields[ch.ChannelType] {
+		delete(config, field)
+	}
+	redacted, err := json.Marshal(config)
+	if err != nil {
+		redacted = []byte(`{}`)
+	}
+	ch.Config = redacted
+	return ch
+}
+
 // SlackChannelConfig for Slack webhook configuration
 type SlackChannelConfig struct {
 	WebhookURL string `json:"webhook_url"`
@@ -45,6 +71,22 @@ type GoogleChatChannelConfig struct {
 	WebhookURL string `json:"webhook_url"`
 }
 
+// InAppChannelConfig for in-app notifications. The recipient is always the channel's owner;
+// the config is filled in by the server rather than taken from the request.
+type InAppChannelConfig struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// InAppNotification is a notification delivered to a user's in-app inbox
+type InAppNotification struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
 // CreateNotificationChannelRequest is the request body for creating a notification channel
 type CreateNotificationChannelRequest struct {
 	Name        string          `json:"name" binding:"required"`
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNotificationChannel_Redacted(t *testing.T) {
+	tests := []struct {
+		name        string
+		channelType ChannelType
+		config      string
+		want        string
+	}{
+		{"slack webhook", ChannelTypeSlack, `{"webhook_url":"https://hooks.slack.com/x","channel":"#alerts"}`, `{"channel":"#alerts"}`},
+		{"google chat webhook", ChannelTypeGoogleChat, `{"webhook_url":"https://chat.googleapis.com/x"}`, `{}`},
+		{"email recipients are kept", ChannelTypeEmail, `{"recipients":["a@example.com"]}`, `{"recipients":["a@example.com"]}`},
+		{"not an object", ChannelTypeSlack, `"opaque"`, `{}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := NotificationChannel{ChannelType: tt.channelType, Config: json.RawMessage(tt.config)}
+			got := ch.Redacted()
+			if string(got.Config) != tt.want {
+				t.Errorf("Redacted().Config = %s, want %s", got.Config, tt.want)
+			}
+			if string(ch.Config) != tt.config {
+				t.Errorf("Redacted() modified the original config: %s", ch.Config)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// parameterTextPattern matches {{name}} placeholders, as in queries
+var parameterTextPattern = regexp.MustCompile(`\{\{([a-zA-Z_][a-zA-Z0-9_]*)\}\}`)
+
+// maxParameterTextValue caps the characters a single substituted value adds to a text
+const maxParameterTextValue = 100
+
+// RenderParameterText substitutes {{name}} placeholders in a dashboard title or description with
+// the display form of the parameter's value, falling back to the parameter's default value.
+// Values are inserted as plain text: control characters are dropped and long values truncated.
+// The text is returned unchanged if any placeholder has no value.
+func RenderParameterText(text string, values map[string]interface{}, defs []ParameterDefinition) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+
+	defaults := make(map[string]interface{}, len(defs))
+	for _, def := range defs {
+		defaults[def.Name] = def.DefaultValue
+	}
+
+	missing := false
+	rendered := parameterTextPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		name := placeholder[2 : len(placeholder)-2]
+		display := parameterDisplayValue(values[name])
+		if display == "" {
+			display = parameterDisplayValue(defaults[name])
+		}
+		if display == "" {
+			missing = true
+			return placeholder
+		}
+		return display
+	})
+	if missing {
+		return text
+	}
+	return rendered
+}
+
+// parameterDisplayValue formats a parameter value for display: lists are joined with ", " and
+// date ranges shown as "start – end". It returns "" for missing or empty values.
+func parameterDisplayValue(value interface{}) string {
+	var display string
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		display = v
+	case []string:
+		display = strings.Join(v, ", ")
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			if s := parameterDisplayValue(item); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		display = strings.Join(parts, ", ")
+	case map[string]interface{}:
+		start, _ := v["start"].(string)
+		end, _ := v["end"].(string)
+		if start == "" || end == "" {
+			return ""
+		}
+		display = start + " – " + end
+	default:
+		display = fmt.Sprint(v)
+	}
+
+	display = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, display))
+	if runes := []rune(display); len(runes) > maxParameterTextValue {
+		display = string(runes[:maxParameterTextValue]) + "…"
+	}
+	return display
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderParameterText(t *testing.T) {
+	defs := []ParameterDefinition{
+		{Name: "region", Type: "text", DefaultValue: "global"},
+		{Name: "day", Type: "date"},
+	}
+
+	tests := []struct {
+		name   string
+		text   string
+		values map[string]interface{}
+		want   string
+	}{
+		{"no placeholders", "Sales", nil, "Sales"},
+		{"value", "Sales for {{region}}", map[string]interface{}{"region": "emea"}, "Sales for emea"},
+		{"default value", "Sales for {{region}}", nil, "Sales for global"},
+		{"empty value uses default", "Sales for {{region}}", map[string]interface{}{"region": ""}, "Sales for global"},
+		{"missing value keeps raw text", "{{region}} on {{day}}", map[string]interface{}{"region": "emea"}, "{{region}} on {{day}}"},
+		{"multi-select", "Sales for {{region}}", map[string]interface{}{"region": []interface{}{"emea", "apac"}}, "Sales for emea, apac"},
+		{"date range", "Sales {{day}}", map[string]interface{}{"day": map[string]interface{}{"start": "2024-01-01", "end": "2024-01-31"}}, "Sales 2024-01-01 – 2024-01-31"},
+		{"number", "Top {{day}}", map[string]interface{}{"day": float64(10)}, "Top 10"},
+		{"control characters dropped", "Sales for {{region}}", map[string]interface{}{"region": "em\nea\x00"}, "Sales for emea"},
+		{"value is not expanded again", "Sales for {{region}}", map[string]interface{}{"region": "{{day}}"}, "Sales for {{day}}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderParameterText(tt.text, tt.values, defs); got != tt.want {
+				t.Fatalf("RenderParameterText(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderParameterText_TruncatesLongValues(t *testing.T) {
+	got := RenderParameterText("{{region}}", map[string]interface{}{"region": strings.Repeat("a", 500)}, nil)
+	if want := strings.Repeat("a", maxParameterTextValue) + "…"; got != want {
+		t.Fatalf("RenderParameterText() = %d chars, want %d", len([]rune(got)), len([]rune(want)))
+	}
+}
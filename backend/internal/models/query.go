@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -18,21 +19,73 @@ type SavedQuery struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// QueryHistorySource identifies the feature that executed a query recorded in the history
+type QueryHistorySource string
+
+const (
+	QueryHistorySourceQuery            QueryHistorySource = "query"             // POST /queries/execute
+	QueryHistorySourceQueryJob         QueryHistorySource = "query_job"         // POST /queries/execute-async
+	QueryHistorySourceExport           QueryHistorySource = "export"            // CSV/TSV export
+	QueryHistorySourceWidget           QueryHistorySource = "widget"            // dashboard widget data (source_id: widget)
+	QueryHistorySourceParameterOptions QueryHistorySource = "parameter_options" // dashboard parameter options (source_id: dashboard)
+	QueryHistorySourceAlert            QueryHistorySource = "alert"             // alert evaluation (source_id: alert)
+)
+
 type QueryHistory struct {
-	ID              uuid.UUID `json:"id"`
-	UserID          uuid.UUID `json:"user_id"`
-	QueryText       string    `json:"query_text"`
-	Status          string    `json:"status"`
-	ExecutionTimeMs *int      `json:"execution_time_ms"`
-	RowCount        *int      `json:"row_count"`
-	ErrorMessage    *string   `json:"error_message"`
-	ExecutedAt      time.Time `json:"executed_at"`
+	ID              uuid.UUID          `json:"id"`
+	UserID          uuid.UUID          `json:"user_id"`
+	QueryText       string             `json:"query_text"`
+	Status          string             `json:"status"`
+	ExecutionTimeMs *int               `json:"execution_time_ms"`
+	RowCount        *int               `json:"row_count"`
+	ErrorMessage    *string            `json:"error_message"`
+	Source          QueryHistorySource `json:"source"`
+	SourceID        *uuid.UUID         `json:"source_id,omitempty"`
+	ExecutedAt      time.Time          `json:"executed_at"`
 }
 
 type ExecuteQueryRequest struct {
 	Query   string `json:"query" binding:"required"`
 	Catalog string `json:"catalog"`
 	Schema  string `json:"schema"`
+
+	// TimeoutSeconds overrides QUERY_TIMEOUT_SECONDS, up to the cap of the user's roles or
+	// QUERY_MAX_TIMEOUT_SECONDS (0 for the default)
+	TimeoutSeconds int `json:"timeout_seconds" binding:"min=0"`
+}
+
+// PagedQueryRequest executes a query and returns its first page; later pages are read by cursor
+type PagedQueryRequest struct {
+	ExecuteQueryRequest
+	PageSize int `json:"page_size"` // default 1000, at most 10000
+}
+
+// QueryResultPage is one page of a result held on the server. NextCursor is nil on the last page.
+type QueryResultPage struct {
+	Columns         []string        `json:"columns"`
+	Rows            [][]interface{} `json:"rows"`
+	RowCount        int             `json:"row_count"`
+	TotalRows       int             `json:"total_rows"`
+	ExecutionTimeMs int64           `json:"execution_time_ms"`
+	NextCursor      *string         `json:"next_cursor,omitempty"`
+}
+
+// ValidateBatchRequest is a list of queries to validate without running them
+type ValidateBatchRequest struct {
+	Queries []ExecuteQueryRequest `json:"queries" binding:"required,min=1,max=100,dive"`
+}
+
+// QueryValidationResult is the outcome of validating the query at Index in the request
+type QueryValidationResult struct {
+	Index int     `json:"index"`
+	Valid bool    `json:"valid"`
+	Error *string `json:"error,omitempty"`
+}
+
+type ValidateBatchResponse struct {
+	Results      []QueryValidationResult `json:"results"`
+	ValidCount   int                     `json:"valid_count"`
+	InvalidCount int                     `json:"invalid_count"`
 }
 
 type QueryResult struct {
@@ -50,6 +103,32 @@ type SaveQueryRequest struct {
 	SchemaName  *string `json:"schema_name"`
 }
 
+// MaxSavedQueryImport caps the queries a single import may create
+const MaxSavedQueryImport = 100
+
+// ImportSavedQueryItem is one query of a saved query import (POST /queries/saved/import)
+type ImportSavedQueryItem struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+	QueryText   string  `json:"query_text"`
+	Catalog     string  `json:"catalog"`
+	Schema      string  `json:"schema"`
+}
+
+// SavedQueryImportResult is the outcome of importing the query at Index in the request: the
+// created query's ID, or the reason it was skipped
+type SavedQueryImportResult struct {
+	Index int        `json:"index"`
+	ID    *uuid.UUID `json:"id,omitempty"`
+	Error *string    `json:"error,omitempty"`
+}
+
+type ImportSavedQueriesResponse struct {
+	Results      []SavedQueryImportResult `json:"results"`
+	CreatedCount int                      `json:"created_count"`
+	FailedCount  int                      `json:"failed_count"`
+}
+
 type UpdateQueryRequest struct {
 	Name        string  `json:"name"`
 	Description *string `json:"description"`
@@ -61,15 +140,51 @@ type UpdateQueryRequest struct {
 // WidgetDataRequest represents a request to get widget data with parameters
 type WidgetDataRequest struct {
 	Parameters map[string]interface{} `json:"parameters"`
+	// BypassCache re-runs the query instead of serving a cached result and caches the fresh
+	// result (editors and owners only)
+	BypassCache bool `json:"bypass_cache,omitempty"`
 }
 
 // WidgetDataResponse represents the result of executing a widget's query
 type WidgetDataResponse struct {
 	WidgetID           uuid.UUID       `json:"widget_id"`
 	QueryResult        *QueryResult    `json:"query_result,omitempty"`
+	ChartConfig        json.RawMessage `json:"chart_config,omitempty"` // The widget's chart_config with its theme merged in
 	Error              string          `json:"error,omitempty"`
 	RequiredParameters []string        `json:"required_parameters,omitempty"`
 	MissingParameters  []string        `json:"missing_parameters,omitempty"`
+	Annotations        []Annotation    `json:"annotations,omitempty"` // Annotations within the result's time range (time-series widgets only)
+}
+
+// WidgetQueryDebug describes how a widget's query would be executed, for admins debugging
+// widgets. The query is resolved and access-checked but not run.
+type WidgetQueryDebug struct {
+	WidgetID           uuid.UUID  `json:"widget_id"`
+	QueryID            *uuid.UUID `json:"query_id,omitempty"` // Nil when the widget runs inline SQL
+	QueryText          string     `json:"query_text"`         // The saved query or inline SQL before parameter substitution
+	ResolvedQuery      string     `json:"resolved_query"`     // The SQL sent to Trino, empty while parameters are missing
+	RequiredParameters []string   `json:"required_parameters,omitempty"`
+	MissingParameters  []string   `json:"missing_parameters,omitempty"`
+	Catalog            string     `json:"catalog"`
+	Schema             string     `json:"schema"`
+	OwnerID            uuid.UUID  `json:"owner_id"` // The dashboard owner whose catalog permissions are enforced
+	AccessAllowed      bool       `json:"access_allowed"`
+	AccessError        string     `json:"access_error,omitempty"`
+}
+
+// DashboardRenderResponse holds the resolved data of every query widget on a dashboard
+type DashboardRenderResponse struct {
+	DashboardID uuid.UUID            `json:"dashboard_id"`
+	Title       string               `json:"title"`                 // Dashboard name with {{param}} placeholders substituted
+	Description *string              `json:"description,omitempty"` // Dashboard description with {{param}} placeholders substituted
+	Widgets     []WidgetDataResponse `json:"widgets"`
+}
+
+// DashboardDataResponse is the data of every widget of a dashboard, keyed by widget ID
+type DashboardDataResponse struct {
+	DashboardID       uuid.UUID                        `json:"dashboard_id"`
+	Widgets           map[uuid.UUID]WidgetDataResponse `json:"widgets"`
+	MissingParameters []string                         `json:"missing_parameters"` // Parameters missing for any widget, sorted
 }
 
 // ParameterOptionsRequest represents a request to get dynamic options for a parameter
@@ -86,6 +201,15 @@ type ColumnInfo struct {
 	OrdinalPosition int     `json:"ordinal_position"`
 }
 
+// TableDDL represents the CREATE statement of a table or view
+type TableDDL struct {
+	Catalog string `json:"catalog"`
+	Schema  string `json:"schema"`
+	Table   string `json:"table"`
+	Type    string `json:"type"` // "table" or "view"
+	DDL     string `json:"ddl"`
+}
+
 // MetadataSearchResult represents a single search result from metadata search
 type MetadataSearchResult struct {
 	Catalog string `json:"catalog"`
@@ -95,9 +219,85 @@ type MetadataSearchResult struct {
 	Type    string `json:"type"` // "table" or "column"
 }
 
+// MetadataSuggestion is an editor autocomplete suggestion. Name is the suggested identifier;
+// the other fields locate it (a schema has a catalog, a column has a catalog, schema and table).
+type MetadataSuggestion struct {
+	Kind    string `json:"kind"` // "catalog", "schema", "table" or "column"
+	Name    string `json:"name"`
+	Catalog string `json:"catalog"`
+	Schema  string `json:"schema,omitempty"`
+	Table   string `json:"table,omitempty"`
+}
+
 // MetadataSearchRequest represents a request to search metadata
 type MetadataSearchRequest struct {
 	Query      string `json:"query" binding:"required"`
 	SearchType string `json:"search_type"` // "table", "column", or "all"
 	Limit      int    `json:"limit"`
 }
+
+// QueryJobStatus represents the lifecycle state of an async query job
+type QueryJobStatus string
+
+const (
+	QueryJobStatusPending   QueryJobStatus = "pending"
+	QueryJobStatusRunning   QueryJobStatus = "running"
+	QueryJobStatusSucceeded QueryJobStatus = "succeeded"
+	QueryJobStatusFailed    QueryJobStatus = "failed"
+)
+
+// QueryJob represents a query executed asynchronously
+type QueryJob struct {
+	ID          uuid.UUID      `json:"id"`
+	UserID      uuid.UUID      `json:"user_id"`
+	QueryText   string         `json:"query_text"`
+	Catalog     string         `json:"catalog"`
+	Schema      string         `json:"schema"`
+	Status      QueryJobStatus `json:"status"`
+	CallbackURL *string        `json:"callback_url,omitempty"`
+	Result      *QueryResult   `json:"result,omitempty"`
+	Error       *string        `json:"error,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	CompletedAt *time.Time     `json:"completed_at,omitempty"`
+}
+
+// ExecuteQueryAsyncRequest represents a request to run a query in the background
+type ExecuteQueryAsyncRequest struct {
+	Query       string `json:"query" binding:"required"`
+	Catalog     string `json:"catalog"`
+	Schema      string `json:"schema"`
+	CallbackURL string `json:"callback_url"` // Optional URL notified when the job completes
+
+	TimeoutSeconds int `json:"timeout_seconds" binding:"min=0"` // as in ExecuteQueryRequest
+}
+
+// QueryJobCallbackPayload is the body POSTed to a job's callback URL on completion
+type QueryJobCallbackPayload struct {
+	JobID       uuid.UUID      `json:"job_id"`
+	Status      QueryJobStatus `json:"status"`
+	RowCount    int            `json:"row_count,omitempty"`
+	Error       *string        `json:"error,omitempty"`
+	CompletedAt time.Time      `json:"completed_at"`
+}
+
+// SlowQuery is a successful execution from query history that took at least the slow query threshold
+type SlowQuery struct {
+	QueryHistory
+	UserEmail string `json:"user_email"`
+}
+
+// SavedQueryPerformance summarizes recent executions of a saved query: widget and alert runs of
+// the query, and runs of its exact text by its owner. Timings cover successful runs only.
+type SavedQueryPerformance struct {
+	QueryID     uuid.UUID      `json:"query_id"`
+	Days        int            `json:"days"`         // Window covered, ending now
+	ThresholdMs int            `json:"threshold_ms"` // Slow query threshold; 0 when reporting is disabled
+	RunCount    int            `json:"run_count"`
+	ErrorCount  int            `json:"error_count"`
+	SlowCount   int            `json:"slow_count"`
+	AvgMs       int64          `json:"avg_ms"`
+	P95Ms       int64          `json:"p95_ms"`
+	MaxMs       int64          `json:"max_ms"`
+	LastRunAt   *time.Time     `json:"last_run_at"`
+	SlowestRuns []QueryHistory `json:"slowest_runs"` // Slow runs, slowest first
+}
@@ -7,17 +7,42 @@ import (
 )
 
 type Role struct {
-	ID          uuid.UUID `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	IsSystem    bool      `json:"is_system"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID           uuid.UUID  `json:"id"`
+	Name         string     `json:"name"`
+	Description  string     `json:"description"`
+	ParentRoleID *uuid.UUID `json:"parent_role_id"`
+	IsSystem     bool       `json:"is_system"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
 }
 
 type RoleWithCatalogs struct {
 	Role
-	Catalogs []string `json:"catalogs"`
+	Catalogs   []string           `json:"catalogs"`
+	Schemas    []SchemaPermission `json:"schemas"`
+	RowFilters []RowFilter        `json:"row_filters"`
+
+	// MaxQueryTimeoutSeconds caps the timeout of the role's users' queries (nil: QUERY_MAX_TIMEOUT_SECONDS)
+	MaxQueryTimeoutSeconds *int `json:"max_query_timeout_seconds"`
+}
+
+// AllSchemas as a SchemaPermission.Schema grants every schema in the catalog
+const AllSchemas = "*"
+
+// SchemaPermission grants access to one schema of a catalog (or all of them with AllSchemas).
+// A catalog-level grant is equivalent to a SchemaPermission with AllSchemas.
+type SchemaPermission struct {
+	Catalog string `json:"catalog" binding:"required"`
+	Schema  string `json:"schema" binding:"required"`
+}
+
+// RowFilter limits the rows of a table that a role's users can read to those matching
+// Predicate, a SQL boolean expression over the table's columns
+type RowFilter struct {
+	Catalog   string `json:"catalog" binding:"required"`
+	Schema    string `json:"schema" binding:"required"`
+	Table     string `json:"table" binding:"required"`
+	Predicate string `json:"predicate" binding:"required"`
 }
 
 type UserWithRoles struct {
@@ -47,14 +72,28 @@ type CreateRoleRequest struct {
 }
 
 type UpdateRoleRequest struct {
-	Name        string `json:"name" binding:"omitempty,min=1,max=100"`
-	Description string `json:"description"`
+	Name         string     `json:"name" binding:"omitempty,min=1,max=100"`
+	Description  string     `json:"description"`
+	ParentRoleID *uuid.UUID `json:"parent_role_id"`
 }
 
 type SetCatalogPermissionsRequest struct {
 	Catalogs []string `json:"catalogs" binding:"required"`
 }
 
+type SetSchemaPermissionsRequest struct {
+	Schemas []SchemaPermission `json:"schemas" binding:"required,dive"`
+}
+
+type SetRowFiltersRequest struct {
+	Filters []RowFilter `json:"filters" binding:"required,dive"`
+}
+
+// SetQueryTimeoutRequest sets a role's cap on query timeouts; null removes it
+type SetQueryTimeoutRequest struct {
+	MaxQueryTimeoutSeconds *int `json:"max_query_timeout_seconds" binding:"omitempty,min=1"`
+}
+
 type AssignRoleRequest struct {
 	RoleID uuid.UUID `json:"role_id" binding:"required"`
 }
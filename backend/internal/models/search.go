@@ -0,0 +1,34 @@
+package models
+
+import "github.com/google/uuid"
+
+// SearchResultType tags the kind of object a unified search result refers to
+type SearchResultType string
+
+const (
+	SearchResultDashboard  SearchResultType = "dashboard"
+	SearchResultSavedQuery SearchResultType = "saved_query"
+	SearchResultTable      SearchResultType = "table"
+	SearchResultColumn     SearchResultType = "column"
+)
+
+// SearchResult is a single hit from the unified search
+type SearchResult struct {
+	Type        SearchResultType `json:"type"`
+	ID          *uuid.UUID       `json:"id,omitempty"` // dashboards and saved queries only
+	Name        string           `json:"name"`
+	Description *string          `json:"description,omitempty"`
+	Catalog     string           `json:"catalog,omitempty"`
+	Schema      string           `json:"schema,omitempty"`
+	Table       string           `json:"table,omitempty"`
+	Column      string           `json:"column,omitempty"`
+	Score       int              `json:"score"`
+}
+
+// SearchResponse is a page of unified search results ordered by score
+type SearchResponse struct {
+	Results []SearchResult `json:"results"`
+	Total   int            `json:"total"`
+	Limit   int            `json:"limit"`
+	Offset  int            `json:"offset"`
+}
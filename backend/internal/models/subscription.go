@@ -20,7 +20,9 @@ type DashboardSubscription struct {
 	UserID      uuid.UUID          `json:"user_id"`
 	DashboardID uuid.UUID          `json:"dashboard_id"`
 	Name        string             `json:"name"`
-	ScheduleCron string            `json:"schedule_cron"`
+	ScheduleCron string            `json:"schedule_cron"` // empty for interval schedules
+	// ScheduleIntervalMinutes runs the subscription every N minutes instead of on ScheduleCron
+	ScheduleIntervalMinutes *int   `json:"schedule_interval_minutes,omitempty"`
 	Timezone    string             `json:"timezone"`
 	Format      SubscriptionFormat `json:"format"`
 	IsActive    bool               `json:"is_active"`
@@ -29,13 +31,18 @@ type DashboardSubscription struct {
 	CreatedAt   time.Time          `json:"created_at"`
 	UpdatedAt   time.Time          `json:"updated_at"`
 	ChannelIDs  []uuid.UUID        `json:"channel_ids,omitempty"`
+	// TimezoneInvalid is set when the stored timezone is not a valid IANA name; such
+	// subscriptions run on UTC until the timezone is corrected
+	TimezoneInvalid bool `json:"timezone_invalid,omitempty"`
 }
 
 // CreateSubscriptionRequest is the request body for creating a subscription
 type CreateSubscriptionRequest struct {
 	DashboardID  uuid.UUID          `json:"dashboard_id" binding:"required"`
 	Name         string             `json:"name" binding:"required"`
-	ScheduleCron string             `json:"schedule_cron" binding:"required"`
+	ScheduleCron string             `json:"schedule_cron"`
+	// ScheduleIntervalMinutes replaces ScheduleCron with a fixed interval; exactly one is required
+	ScheduleIntervalMinutes *int    `json:"schedule_interval_minutes"`
 	Timezone     string             `json:"timezone"`
 	Format       SubscriptionFormat `json:"format"`
 	ChannelIDs   []uuid.UUID        `json:"channel_ids" binding:"required"`
@@ -45,8 +52,64 @@ type CreateSubscriptionRequest struct {
 type UpdateSubscriptionRequest struct {
 	Name         string             `json:"name,omitempty"`
 	ScheduleCron string             `json:"schedule_cron,omitempty"`
+	// ScheduleIntervalMinutes switches the subscription to a fixed interval; it may not be given
+	// with ScheduleCron
+	ScheduleIntervalMinutes *int    `json:"schedule_interval_minutes,omitempty"`
 	Timezone     string             `json:"timezone,omitempty"`
 	Format       SubscriptionFormat `json:"format,omitempty"`
 	IsActive     *bool              `json:"is_active,omitempty"`
 	ChannelIDs   []uuid.UUID        `json:"channel_ids,omitempty"`
 }
+
+// ValidateCronRequest is the request body for previewing a subscription schedule
+type ValidateCronRequest struct {
+	Cron     string `json:"cron" binding:"required"`
+	Timezone string `json:"timezone"` // defaults to Asia/Tokyo, as for new subscriptions
+	Seconds  bool   `json:"seconds"`  // the expression has a leading seconds field (6 fields)
+}
+
+// PreviewScheduleRequest is the request body for previewing a subscription's schedule, given
+// as in CreateSubscriptionRequest
+type PreviewScheduleRequest struct {
+	ScheduleCron            string `json:"schedule_cron"`
+	ScheduleIntervalMinutes *int   `json:"schedule_interval_minutes"`
+	Timezone                string `json:"timezone"` // defaults to Asia/Tokyo, as for new subscriptions
+}
+
+// SchedulePreview describes a cron schedule and its next runs in the schedule's timezone
+type SchedulePreview struct {
+	Valid       bool        `json:"valid"`
+	Cron        string      `json:"cron"`
+	IntervalMinutes *int    `json:"interval_minutes,omitempty"`
+	Timezone    string      `json:"timezone"`
+	Seconds     bool        `json:"seconds,omitempty"`
+	Description string      `json:"description"`
+	NextRuns    []time.Time `json:"next_runs"`
+}
+
+// Subscription run statuses recorded in subscription_history
+const (
+	SubscriptionRunSent    = "sent"    // every channel received the report
+	SubscriptionRunPartial = "partial" // some channels failed
+	SubscriptionRunFailed  = "failed"  // nothing was delivered
+)
+
+// SubscriptionChannelResult is the outcome of delivering one subscription run to one channel.
+// The channel's name and type are copied so the history stays readable after the channel is deleted.
+type SubscriptionChannelResult struct {
+	ChannelID   uuid.UUID   `json:"channel_id"`
+	ChannelName string      `json:"channel_name"`
+	ChannelType ChannelType `json:"channel_type"`
+	Status      string      `json:"status"` // "sent" or "failed"
+	Error       *string     `json:"error,omitempty"`
+}
+
+// SubscriptionHistory records one run of a subscription, scheduled or triggered manually
+type SubscriptionHistory struct {
+	ID             uuid.UUID                   `json:"id"`
+	SubscriptionID uuid.UUID                   `json:"subscription_id"`
+	RunAt          time.Time                   `json:"run_at"`
+	Status         string                      `json:"status"`
+	ChannelResults []SubscriptionChannelResult `json:"channel_results"`
+	ErrorMessage   *string                     `json:"error_message"`
+}
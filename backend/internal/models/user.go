@@ -18,6 +18,57 @@ type User struct {
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
+// UserStatus is the account state of a user; only active users can authenticate
+type UserStatus string
+
+const (
+	UserStatusActive   UserStatus = "active"
+	UserStatusDisabled UserStatus = "disabled"
+	UserStatusPending  UserStatus = "pending"
+)
+
+// UserAuthState is the per-user data checked on every authenticated request
+type UserAuthState struct {
+	TokenVersion int        `json:"token_version"`
+	Status       UserStatus `json:"status"`
+}
+
+// UpdateUserStatusRequest is the admin request to enable or disable a user
+type UpdateUserStatusRequest struct {
+	Status UserStatus `json:"status" binding:"required,oneof=active disabled"`
+}
+
+// ApproveUsersRequest is the admin request to activate several pending users at once,
+// optionally assigning them a role
+type ApproveUsersRequest struct {
+	UserIDs []uuid.UUID `json:"user_ids" binding:"required,min=1,max=500"`
+	RoleID  *uuid.UUID  `json:"role_id,omitempty"`
+}
+
+// UserApprovalOutcome is what happened to one user of a bulk approval
+type UserApprovalOutcome string
+
+const (
+	UserApprovalApproved   UserApprovalOutcome = "approved"
+	UserApprovalNotPending UserApprovalOutcome = "not_pending" // already active or disabled; left unchanged
+	UserApprovalNotFound   UserApprovalOutcome = "not_found"
+	UserApprovalSelf       UserApprovalOutcome = "self" // admins cannot change their own status
+)
+
+// UserApprovalResult is the outcome of a bulk approval for one user ID. Status is the user's
+// status after the approval, omitted for unknown users.
+type UserApprovalResult struct {
+	UserID  uuid.UUID           `json:"user_id"`
+	Outcome UserApprovalOutcome `json:"outcome"`
+	Status  UserStatus          `json:"status,omitempty"`
+}
+
+// ApproveUsersResponse lists the outcome of every requested user ID, in request order
+type ApproveUsersResponse struct {
+	Results  []UserApprovalResult `json:"results"`
+	Approved int                  `json:"approved"`
+}
+
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required"`    // Email or username for admin users
 	Password string `json:"password" binding:"required"` // No min length for admin flexibility
@@ -29,7 +80,43 @@ type RegisterRequest struct {
 	Name     string `json:"name" binding:"required"`
 }
 
+// ResetPasswordResponse carries the temporary password set by an admin password reset.
+// It is only returned once and must be passed on to the user.
+type ResetPasswordResponse struct {
+	TemporaryPassword string `json:"temporary_password"`
+}
+
+// ChangePasswordRequest is a user's request to replace their own password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	User         User   `json:"user"`
+}
+
+// Session is one sign-in: it starts at login and is carried over when tokens are refreshed
+type Session struct {
+	ID        uuid.UUID
+	StartedAt time.Time
+}
+
+// RefreshToken is a stored (hashed) refresh token
+type RefreshToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	TokenHash string
+	Session   *Session // nil for tokens issued before sessions were tracked
+	// TokenVersion is the user's token version when the token was issued; nil for tokens
+	// issued before versions were recorded
+	TokenVersion *int
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
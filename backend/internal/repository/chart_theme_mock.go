@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+)
+
+// MockChartThemeRepository is a mock implementation of ChartThemeRepository for testing
+type MockChartThemeRepository struct {
+	Themes []*models.ChartTheme
+}
+
+// NewMockChartThemeRepository creates a new MockChartThemeRepository
+func NewMockChartThemeRepository() *MockChartThemeRepository {
+	return &MockChartThemeRepository{}
+}
+
+func (m *MockChartThemeRepository) GetAll(ctx context.Context, userID uuid.UUID) ([]models.ChartTheme, error) {
+	var themes []models.ChartTheme
+	for _, t := range m.Themes {
+		if t.IsSystem || (t.UserID != nil && *t.UserID == userID) {
+			themes = append(themes, *t)
+		}
+	}
+	return themes, nil
+}
+
+func (m *MockChartThemeRepository) Create(ctx context.Context, userID uuid.UUID, req *models.CreateChartThemeRequest) (*models.ChartTheme, error) {
+	t := &models.ChartTheme{
+		ID:        uuid.New(),
+		Name:      req.Name,
+		Config:    req.Config,
+		IsSystem:  req.IsSystem,
+		CreatedAt: time.Now(),
+	}
+	if !req.IsSystem {
+		t.UserID = &userID
+	}
+	m.Themes = append(m.Themes, t)
+	return t, nil
+}
+
+func (m *MockChartThemeRepository) Delete(ctx context.Context, id uuid.UUID, userID uuid.UUID, isAdmin bool) error {
+	for i, t := range m.Themes {
+		if t.ID != id {
+			continue
+		}
+		if t.IsSystem && !isAdmin || !t.IsSystem && (t.UserID == nil || *t.UserID != userID) {
+			return ErrNotFound
+		}
+		m.Themes = append(m.Themes[:i], m.Themes[i+1:]...)
+		return nil
+	}
+	return ErrNotFound
+}
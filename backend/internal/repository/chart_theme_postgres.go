@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mitsume/backend/internal/models"
+)
+
+type ChartThemeRepository interface {
+	GetAll(ctx context.Context, userID uuid.UUID) ([]models.ChartTheme, error)
+	Create(ctx context.Context, userID uuid.UUID, req *models.CreateChartThemeRequest) (*models.ChartTheme, error)
+	Delete(ctx context.Context, id uuid.UUID, userID uuid.UUID, isAdmin bool) error
+}
+
+type PostgresChartThemeRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresChartThemeRepository(pool *pgxpool.Pool) *PostgresChartThemeRepository {
+	return &PostgresChartThemeRepository{pool: pool}
+}
+
+// GetAll returns all chart themes (system + user's own)
+func (r *PostgresChartThemeRepository) GetAll(ctx context.Context, userID uuid.UUID) ([]models.ChartTheme, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, user_id, name, config, is_system, created_at
+		FROM chart_themes
+		WHERE is_system = true OR user_id = $1
+		ORDER BY is_system DESC, name
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var themes []models.ChartTheme
+	for rows.Next() {
+		var t models.ChartTheme
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.Config, &t.IsSystem, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		themes = append(themes, t)
+	}
+
+	return themes, rows.Err()
+}
+
+// Create creates a chart theme. System themes belong to no user, so they outlive the admin
+// who created them.
+func (r *PostgresChartThemeRepository) Create(ctx context.Context, userID uuid.UUID, req *models.CreateChartThemeRequest) (*models.ChartTheme, error) {
+	owner := &userID
+	if req.IsSystem {
+		owner = nil
+	}
+
+	var t models.ChartTheme
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO chart_themes (user_id, name, config, is_system)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, user_id, name, config, is_system, created_at
+	`, owner, req.Name, req.Config, req.IsSystem).Scan(
+		&t.ID, &t.UserID, &t.Name, &t.Config, &t.IsSystem, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Delete deletes a user's own theme, or a system theme when isAdmin is set. Widgets using the
+// theme fall back to their own chart_config.
+func (r *PostgresChartThemeRepository) Delete(ctx context.Context, id uuid.UUID, userID uuid.UUID, isAdmin bool) error {
+	result, err := r.pool.Exec(ctx, `
+		DELETE FROM chart_themes
+		WHERE id = $1 AND ((is_system = false AND user_id = $2) OR (is_system = true AND $3))
+	`, id, userID, isAdmin)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
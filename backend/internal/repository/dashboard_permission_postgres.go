@@ -21,6 +21,7 @@ func NewPostgresDashboardPermissionRepository(pool *pgxpool.Pool) *PostgresDashb
 // GetUserPermissionLevel returns the permission level for a user on a dashboard
 // For drafts (is_draft=true), permission is evaluated against the original dashboard (draft_of)
 // Drafts are never treated as public (even if the original is public)
+// Soft-deleted dashboards (and their drafts) are reported as not found
 func (r *PostgresDashboardPermissionRepository) GetUserPermissionLevel(ctx context.Context, dashboardID, userID uuid.UUID) (models.PermissionLevel, error) {
 	// First, check if dashboard exists and get draft info
 	var ownerID uuid.UUID
@@ -29,7 +30,7 @@ func (r *PostgresDashboardPermissionRepository) GetUserPermissionLevel(ctx conte
 	var isPublic bool
 	err := r.pool.QueryRow(ctx,
 		`SELECT user_id, COALESCE(is_draft, false), draft_of, COALESCE(is_public, false)
-		 FROM dashboards WHERE id = $1`,
+		 FROM dashboards WHERE id = $1 AND deleted_at IS NULL`,
 		dashboardID,
 	).Scan(&ownerID, &isDraft, &draftOf, &isPublic)
 	if err != nil {
@@ -49,7 +50,7 @@ func (r *PostgresDashboardPermissionRepository) GetUserPermissionLevel(ctx conte
 		permDashboardID = *draftOf
 		// Get original dashboard's owner and public status
 		err = r.pool.QueryRow(ctx,
-			`SELECT user_id, COALESCE(is_public, false) FROM dashboards WHERE id = $1`,
+			`SELECT user_id, COALESCE(is_public, false) FROM dashboards WHERE id = $1 AND deleted_at IS NULL`,
 			permDashboardID,
 		).Scan(&permOwnerID, &permIsPublic)
 		if err != nil {
@@ -109,10 +110,11 @@ func (r *PostgresDashboardPermissionRepository) GetUserPermissionLevel(ctx conte
 
 // GetAccessibleDashboards returns all dashboards accessible to a user
 // Note: Excludes draft dashboards (is_draft = true) from the list - drafts are accessed via their original dashboard
-func (r *PostgresDashboardPermissionRepository) GetAccessibleDashboards(ctx context.Context, userID uuid.UUID) ([]models.Dashboard, error) {
+func (r *PostgresDashboardPermissionRepository) GetAccessibleDashboards(ctx context.Context, userID uuid.UUID, archived bool) ([]models.Dashboard, error) {
 	rows, err := r.pool.Query(ctx,
 		`SELECT DISTINCT d.id, d.user_id, d.name, d.description, d.layout, COALESCE(d.is_public, false), COALESCE(d.parameters, '[]'),
-		        COALESCE(d.is_draft, false), d.draft_of, d.created_at, d.updated_at,
+		        COALESCE(d.is_draft, false), d.draft_of, COALESCE(d.is_archived, false), d.created_by, d.updated_by, d.created_at, d.updated_at,
+		        (SELECT COUNT(*) FROM dashboard_widgets w WHERE w.dashboard_id = d.id AND w.last_error IS NOT NULL),
 		        CASE
 		            WHEN d.user_id = $1 THEN 'owner'
 		            WHEN dp_user.permission_level IS NOT NULL THEN dp_user.permission_level
@@ -135,8 +137,10 @@ func (r *PostgresDashboardPermissionRepository) GetAccessibleDashboards(ctx cont
 		    OR dp_role.dashboard_id IS NOT NULL
 		    OR COALESCE(d.is_public, false) = true)
 		   AND COALESCE(d.is_draft, false) = false
+		   AND COALESCE(d.is_archived, false) = $2
+		   AND d.deleted_at IS NULL
 		 ORDER BY d.updated_at DESC`,
-		userID,
+		userID, archived,
 	)
 	if err != nil {
 		return nil, err
@@ -148,7 +152,7 @@ func (r *PostgresDashboardPermissionRepository) GetAccessibleDashboards(ctx cont
 		var d models.Dashboard
 		var myPermission string
 		if err := rows.Scan(&d.ID, &d.UserID, &d.Name, &d.Description, &d.Layout, &d.IsPublic, &d.Parameters,
-			&d.IsDraft, &d.DraftOf, &d.CreatedAt, &d.UpdatedAt, &myPermission); err != nil {
+			&d.IsDraft, &d.DraftOf, &d.IsArchived, &d.CreatedBy, &d.UpdatedBy, &d.CreatedAt, &d.UpdatedAt, &d.WidgetErrorCount, &myPermission); err != nil {
 			return nil, err
 		}
 		d.MyPermission = models.PermissionLevel(myPermission)
@@ -161,6 +165,7 @@ func (r *PostgresDashboardPermissionRepository) GetAccessibleDashboards(ctx cont
 // GetDashboardByIDWithPermission returns a dashboard if user has appropriate permission
 // For drafts (is_draft=true): requires edit permission (editors/owners only)
 // For published dashboards: requires view permission
+// Without it ErrForbidden is returned; callers decide whether to reveal that the dashboard exists.
 func (r *PostgresDashboardPermissionRepository) GetDashboardByIDWithPermission(ctx context.Context, dashboardID, userID uuid.UUID) (*models.Dashboard, error) {
 	permLevel, err := r.GetUserPermissionLevel(ctx, dashboardID, userID)
 	if err != nil {
@@ -184,22 +189,24 @@ func (r *PostgresDashboardPermissionRepository) GetDashboardByIDWithPermission(c
 	// Published dashboards only require view permission
 	if isDraft {
 		if !permLevel.CanEdit() {
-			return nil, ErrNotFound
+			return nil, ErrForbidden
 		}
 	} else {
 		if !permLevel.CanView() {
-			return nil, ErrNotFound
+			return nil, ErrForbidden
 		}
 	}
 
 	var d models.Dashboard
 	err = r.pool.QueryRow(ctx,
 		`SELECT id, user_id, name, description, layout, COALESCE(is_public, false), COALESCE(parameters, '[]'),
-		        COALESCE(is_draft, false), draft_of, created_at, updated_at
+		        COALESCE(is_draft, false), draft_of, COALESCE(is_archived, false), error_notification_channel_id,
+		        default_catalog, default_schema, timezone, created_by, updated_by, created_at, updated_at
 		 FROM dashboards WHERE id = $1`,
 		dashboardID,
 	).Scan(&d.ID, &d.UserID, &d.Name, &d.Description, &d.Layout, &d.IsPublic, &d.Parameters,
-		&d.IsDraft, &d.DraftOf, &d.CreatedAt, &d.UpdatedAt)
+		&d.IsDraft, &d.DraftOf, &d.IsArchived, &d.ErrorNotificationChannelID,
+		&d.DefaultCatalog, &d.DefaultSchema, &d.Timezone, &d.CreatedBy, &d.UpdatedBy, &d.CreatedAt, &d.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -5,4 +5,6 @@ import "errors"
 var (
 	// ErrNotFound is returned when a requested resource is not found
 	ErrNotFound = errors.New("resource not found")
+	// ErrForbidden is returned when a resource exists but the user may not access it
+	ErrForbidden = errors.New("resource access denied")
 )
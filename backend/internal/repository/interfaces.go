@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/mitsume/backend/internal/models"
@@ -24,6 +25,9 @@ type UserRepository interface {
 	// FindByGoogleID retrieves a user by their Google ID
 	FindByGoogleID(ctx context.Context, googleID string) (*models.User, error)
 
+	// FindByOIDCSubject retrieves a user by the subject issued by the OIDC provider
+	FindByOIDCSubject(ctx context.Context, subject string) (*models.User, error)
+
 	// ExistsByEmail checks if a user with the given email exists
 	ExistsByEmail(ctx context.Context, email string) (bool, error)
 
@@ -38,6 +42,45 @@ type UserRepository interface {
 
 	// CreateGoogleUser creates a new user authenticated via Google
 	CreateGoogleUser(ctx context.Context, email, name, googleID string) (*models.User, error)
+
+	// CreateOIDCUser creates a new user authenticated via the OIDC provider, in pending status
+	CreateOIDCUser(ctx context.Context, email, name, subject string) (*models.User, error)
+
+	// GetAuthState returns the user's current token version and account status.
+	// Access tokens carrying an older version, or belonging to a non-active user, are rejected.
+	GetAuthState(ctx context.Context, id uuid.UUID) (*models.UserAuthState, error)
+
+	// SetStatus updates the user's account status
+	SetStatus(ctx context.Context, id uuid.UUID, status models.UserStatus) error
+
+	// ApprovePending activates those of the given users that are pending and, when roleID is set,
+	// assigns them that role, in one transaction. It returns the status each existing user had
+	// before; users missing from the result do not exist.
+	ApprovePending(ctx context.Context, ids []uuid.UUID, roleID *uuid.UUID, approvedBy uuid.UUID) (map[uuid.UUID]models.UserStatus, error)
+
+	// GetPasswordHash returns the bcrypt hash of a local user's password
+	GetPasswordHash(ctx context.Context, id uuid.UUID) (string, error)
+
+	// UpdatePasswordHash replaces the user's password hash
+	UpdatePasswordHash(ctx context.Context, id uuid.UUID, passwordHash string) error
+
+	// IncrementTokenVersion bumps the user's token version, invalidating every access token issued so far
+	IncrementTokenVersion(ctx context.Context, id uuid.UUID) error
+}
+
+// RefreshTokenRepository defines the interface for refresh token storage.
+// Tokens are stored only as hashes.
+type RefreshTokenRepository interface {
+	// Create stores a new refresh token hash for the user's session, issued at the user's
+	// tokenVersion
+	Create(ctx context.Context, userID uuid.UUID, tokenHash string, tokenVersion int, session models.Session, expiresAt time.Time) error
+
+	// ConsumeByHash deletes an unexpired refresh token by its hash and returns it. Only one of
+	// several concurrent calls for the same hash gets the token; the others get ErrNotFound.
+	ConsumeByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+
+	// DeleteByUser removes every refresh token of the user
+	DeleteByUser(ctx context.Context, userID uuid.UUID) error
 }
 
 // TrinoExecutor defines the interface for Trino query execution
@@ -57,6 +100,9 @@ type TrinoExecutor interface {
 	// GetColumns returns a list of columns in the specified table
 	GetColumns(ctx context.Context, catalog, schema, table string) ([]models.ColumnInfo, error)
 
+	// GetTableSample returns up to limit rows from the specified table
+	GetTableSample(ctx context.Context, catalog, schema, table string, limit int) (*models.QueryResult, error)
+
 	// SearchMetadata searches for tables and columns across catalogs
 	// searchType: "table", "column", or "all"
 	// catalogs: list of catalogs to search in (for permission filtering)
@@ -71,12 +117,24 @@ type CachedTrinoExecutor interface {
 	// priority: 1=Low (ad-hoc), 2=Normal (widget), 3=High (scheduled)
 	// savedQueryID is used for cache invalidation
 	ExecuteQueryWithCache(ctx context.Context, query, catalog, schema string, priority int, savedQueryID *uuid.UUID) (*models.QueryResult, error)
+
+	// ExecuteQueryWithMaxStaleness is ExecuteQueryWithCache, but cached results older than
+	// maxStaleness are re-computed even within their TTL (0 means no limit)
+	ExecuteQueryWithMaxStaleness(ctx context.Context, query, catalog, schema string, priority int, savedQueryID *uuid.UUID, maxStaleness time.Duration) (*models.QueryResult, error)
+
+	// RefreshQuery executes a query without reading the cache and repopulates the cache with the result
+	RefreshQuery(ctx context.Context, query, catalog, schema string, priority int, savedQueryID *uuid.UUID) (*models.QueryResult, error)
+
+	// CachedResultTime reports when the cached result of a query was computed and when it expires,
+	// without executing the query; ok is false when no fresh result is cached
+	CachedResultTime(ctx context.Context, query, catalog, schema string, priority int, maxStaleness time.Duration) (cachedAt, expiresAt time.Time, ok bool)
 }
 
 // QueryHistoryRecorder defines the interface for recording query execution history
 type QueryHistoryRecorder interface {
-	// SaveQueryHistory records a query execution in the history
-	SaveQueryHistory(ctx context.Context, userID uuid.UUID, queryText, status string, executionTimeMs int64, rowCount int, errorMsg *string) error
+	// SaveQueryHistory records a query execution in the history, tagged with the feature that
+	// ran it and the ID of the owning widget, alert or dashboard (nil for ad-hoc executions)
+	SaveQueryHistory(ctx context.Context, userID uuid.UUID, queryText, status string, executionTimeMs int64, rowCount int, errorMsg *string, source models.QueryHistorySource, sourceID *uuid.UUID) error
 }
 
 // SavedQueryRepository defines the interface for saved query data access
@@ -129,10 +187,12 @@ type DashboardPermissionRepository interface {
 	// Returns PermissionOwner if user is owner, checks explicit permissions and role permissions
 	GetUserPermissionLevel(ctx context.Context, dashboardID, userID uuid.UUID) (models.PermissionLevel, error)
 
-	// GetAccessibleDashboards returns all dashboards accessible to a user (owned + shared + public)
-	GetAccessibleDashboards(ctx context.Context, userID uuid.UUID) ([]models.Dashboard, error)
+	// GetAccessibleDashboards returns all dashboards accessible to a user (owned + shared + public),
+	// either the archived ones or the rest
+	GetAccessibleDashboards(ctx context.Context, userID uuid.UUID, archived bool) ([]models.Dashboard, error)
 
-	// GetDashboardByIDWithPermission returns a dashboard if user has at least view permission
+	// GetDashboardByIDWithPermission returns a dashboard if user has at least view permission,
+	// ErrForbidden if it exists but the user may not see it
 	GetDashboardByIDWithPermission(ctx context.Context, dashboardID, userID uuid.UUID) (*models.Dashboard, error)
 
 	// GetDashboardPermissions returns all permissions for a dashboard
@@ -180,8 +240,8 @@ type RoleRepository interface {
 	// Create creates a new role
 	Create(ctx context.Context, name, description string) (*models.Role, error)
 
-	// Update updates an existing role (non-system roles only)
-	Update(ctx context.Context, id uuid.UUID, name, description string) (*models.Role, error)
+	// Update updates an existing role (non-system roles only); a nil parentRoleID clears the parent
+	Update(ctx context.Context, id uuid.UUID, name, description string, parentRoleID *uuid.UUID) (*models.Role, error)
 
 	// Delete deletes a role (non-system roles only)
 	Delete(ctx context.Context, id uuid.UUID) error
@@ -204,9 +264,40 @@ type RoleRepository interface {
 	// SetRoleCatalogs sets the catalog permissions for a role
 	SetRoleCatalogs(ctx context.Context, roleID uuid.UUID, catalogs []string) error
 
-	// GetUserAllowedCatalogs returns all catalogs a user can access (nil means all catalogs for admin)
+	// GetRoleSchemas returns all schema permissions for a role
+	GetRoleSchemas(ctx context.Context, roleID uuid.UUID) ([]models.SchemaPermission, error)
+
+	// SetRoleSchemas sets the schema permissions for a role
+	SetRoleSchemas(ctx context.Context, roleID uuid.UUID, schemas []models.SchemaPermission) error
+
+	// GetRoleRowFilters returns the row filters of a role
+	GetRoleRowFilters(ctx context.Context, roleID uuid.UUID) ([]models.RowFilter, error)
+
+	// SetRoleRowFilters sets the row filters of a role (replaces existing)
+	SetRoleRowFilters(ctx context.Context, roleID uuid.UUID, filters []models.RowFilter) error
+
+	// GetUserRowFilters returns the row filters of a user's roles, including those inherited
+	// through parent roles (nil for admin, who are not filtered)
+	GetUserRowFilters(ctx context.Context, userID uuid.UUID) ([]models.RowFilter, error)
+
+	// GetRoleQueryTimeout returns the role's cap on query timeouts in seconds (nil if unset)
+	GetRoleQueryTimeout(ctx context.Context, roleID uuid.UUID) (*int, error)
+
+	// SetRoleQueryTimeout sets the role's cap on query timeouts in seconds (nil removes it)
+	SetRoleQueryTimeout(ctx context.Context, roleID uuid.UUID, seconds *int) error
+
+	// GetUserMaxQueryTimeout returns the largest query timeout cap of a user's roles, including
+	// those inherited through parent roles (nil for admin and when no role sets a cap)
+	GetUserMaxQueryTimeout(ctx context.Context, userID uuid.UUID) (*int, error)
+
+	// GetUserAllowedCatalogs returns all catalogs a user can access through a catalog or schema
+	// grant, including grants inherited through parent roles (nil means all catalogs for admin)
 	GetUserAllowedCatalogs(ctx context.Context, userID uuid.UUID) ([]string, error)
 
+	// GetUserAllowedSchemas returns the schemas a user can access; catalog grants are returned
+	// with models.AllSchemas (nil means everything for admin)
+	GetUserAllowedSchemas(ctx context.Context, userID uuid.UUID) ([]models.SchemaPermission, error)
+
 	// IsUserAdmin checks if a user has the admin role
 	IsUserAdmin(ctx context.Context, userID uuid.UUID) (bool, error)
 
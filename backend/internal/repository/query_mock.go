@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
 )
 
 // MockQueryHistoryRecorder is a mock implementation of QueryHistoryRecorder for testing
@@ -15,7 +16,7 @@ type MockQueryHistoryRecorder struct {
 	SaveError error
 
 	// Function hook for custom behavior
-	SaveQueryHistoryFunc func(ctx context.Context, userID uuid.UUID, queryText, status string, executionTimeMs int64, rowCount int, errorMsg *string) error
+	SaveQueryHistoryFunc func(ctx context.Context, userID uuid.UUID, queryText, status string, executionTimeMs int64, rowCount int, errorMsg *string, source models.QueryHistorySource, sourceID *uuid.UUID) error
 }
 
 // SavedHistoryCall records a call to SaveQueryHistory
@@ -26,6 +27,8 @@ type SavedHistoryCall struct {
 	ExecutionTimeMs int64
 	RowCount        int
 	ErrorMsg        *string
+	Source          models.QueryHistorySource
+	SourceID        *uuid.UUID
 }
 
 // NewMockQueryHistoryRecorder creates a new MockQueryHistoryRecorder
@@ -35,7 +38,7 @@ func NewMockQueryHistoryRecorder() *MockQueryHistoryRecorder {
 	}
 }
 
-func (m *MockQueryHistoryRecorder) SaveQueryHistory(ctx context.Context, userID uuid.UUID, queryText, status string, executionTimeMs int64, rowCount int, errorMsg *string) error {
+func (m *MockQueryHistoryRecorder) SaveQueryHistory(ctx context.Context, userID uuid.UUID, queryText, status string, executionTimeMs int64, rowCount int, errorMsg *string, source models.QueryHistorySource, sourceID *uuid.UUID) error {
 	// Track the call
 	m.SavedHistories = append(m.SavedHistories, SavedHistoryCall{
 		UserID:          userID,
@@ -44,10 +47,12 @@ func (m *MockQueryHistoryRecorder) SaveQueryHistory(ctx context.Context, userID
 		ExecutionTimeMs: executionTimeMs,
 		RowCount:        rowCount,
 		ErrorMsg:        errorMsg,
+		Source:          source,
+		SourceID:        sourceID,
 	})
 
 	if m.SaveQueryHistoryFunc != nil {
-		return m.SaveQueryHistoryFunc(ctx, userID, queryText, status, executionTimeMs, rowCount, errorMsg)
+		return m.SaveQueryHistoryFunc(ctx, userID, queryText, status, executionTimeMs, rowCount, errorMsg, source, sourceID)
 	}
 
 	return m.SaveError
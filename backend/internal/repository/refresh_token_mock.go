@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+)
+
+// MockRefreshTokenRepository is a mock implementation of RefreshTokenRepository for testing
+type MockRefreshTokenRepository struct {
+	Tokens map[string]*models.RefreshToken // token hash -> token
+}
+
+// NewMockRefreshTokenRepository creates a new MockRefreshTokenRepository
+func NewMockRefreshTokenRepository() *MockRefreshTokenRepository {
+	return &MockRefreshTokenRepository{
+		Tokens: make(map[string]*models.RefreshToken),
+	}
+}
+
+func (m *MockRefreshTokenRepository) Create(ctx context.Context, userID uuid.UUID, tokenHash string, tokenVersion int, session models.Session, expiresAt time.Time) error {
+	m.Tokens[tokenHash] = &models.RefreshToken{
+		ID:           uuid.New(),
+		UserID:       userID,
+		TokenHash:    tokenHash,
+		Session:      &session,
+		TokenVersion: &tokenVersion,
+		ExpiresAt:    expiresAt,
+		CreatedAt:    time.Now(),
+	}
+	return nil
+}
+
+func (m *MockRefreshTokenRepository) ConsumeByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	token, ok := m.Tokens[tokenHash]
+	if !ok || !token.ExpiresAt.After(time.Now()) {
+		return nil, ErrNotFound
+	}
+	delete(m.Tokens, tokenHash)
+	return token, nil
+}
+
+func (m *MockRefreshTokenRepository) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	for hash, token := range m.Tokens {
+		if token.UserID == userID {
+			delete(m.Tokens, hash)
+		}
+	}
+	return nil
+}
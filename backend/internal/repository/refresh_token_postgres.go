@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mitsume/backend/internal/models"
+)
+
+// PostgresRefreshTokenRepository implements RefreshTokenRepository using PostgreSQL
+type PostgresRefreshTokenRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresRefreshTokenRepository creates a new PostgresRefreshTokenRepository
+func NewPostgresRefreshTokenRepository(pool *pgxpool.Pool) *PostgresRefreshTokenRepository {
+	return &PostgresRefreshTokenRepository{pool: pool}
+}
+
+func (r *PostgresRefreshTokenRepository) Create(ctx context.Context, userID uuid.UUID, tokenHash string, tokenVersion int, session models.Session, expiresAt time.Time) error {
+	_, err := r.pool.Exec(ctx,
+		`INSERT INTO refresh_tokens (user_id, token_hash, token_version, session_id, session_started_at, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		userID, tokenHash, tokenVersion, session.ID, session.StartedAt, expiresAt,
+	)
+	return err
+}
+
+func (r *PostgresRefreshTokenRepository) ConsumeByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	var sessionID *uuid.UUID
+	var sessionStartedAt *time.Time
+	err := r.pool.QueryRow(ctx,
+		`DELETE FROM refresh_tokens WHERE token_hash = $1 AND expires_at > CURRENT_TIMESTAMP
+		 RETURNING id, user_id, token_hash, token_version, session_id, session_started_at, expires_at, created_at`,
+		tokenHash,
+	).Scan(&token.ID, &token.UserID, &token.TokenHash, &token.TokenVersion, &sessionID, &sessionStartedAt, &token.ExpiresAt, &token.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if sessionID != nil && sessionStartedAt != nil {
+		token.Session = &models.Session{ID: *sessionID, StartedAt: *sessionStartedAt}
+	}
+	return &token, nil
+}
+
+func (r *PostgresRefreshTokenRepository) DeleteByUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM refresh_tokens WHERE user_id = $1`, userID)
+	return err
+}
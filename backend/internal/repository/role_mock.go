@@ -0,0 +1,290 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+)
+
+// MockRoleRepository is a mock implementation of RoleRepository for testing
+type MockRoleRepository struct {
+	Roles           map[uuid.UUID]*models.Role
+	UserRoles       map[uuid.UUID][]uuid.UUID               // userID -> roleIDs
+	RoleCatalogs    map[uuid.UUID][]string                  // roleID -> catalogs
+	RoleSchemas     map[uuid.UUID][]models.SchemaPermission // roleID -> schemas
+	RoleRowFilters  map[uuid.UUID][]models.RowFilter        // roleID -> row filters
+	RoleTimeouts    map[uuid.UUID]int                       // roleID -> query timeout cap in seconds
+	AdminUsers      map[uuid.UUID]bool
+	AllowedCatalogs map[uuid.UUID][]string // userID -> catalogs (overrides role lookup when set)
+	UserCount       int
+
+	// Function hooks for custom behavior
+	GetUserAllowedCatalogsFunc func(ctx context.Context, userID uuid.UUID) ([]string, error)
+	IsUserAdminFunc            func(ctx context.Context, userID uuid.UUID) (bool, error)
+}
+
+// NewMockRoleRepository creates a new MockRoleRepository
+func NewMockRoleRepository() *MockRoleRepository {
+	return &MockRoleRepository{
+		Roles:           make(map[uuid.UUID]*models.Role),
+		UserRoles:       make(map[uuid.UUID][]uuid.UUID),
+		RoleCatalogs:    make(map[uuid.UUID][]string),
+		RoleSchemas:     make(map[uuid.UUID][]models.SchemaPermission),
+		RoleRowFilters:  make(map[uuid.UUID][]models.RowFilter),
+		RoleTimeouts:    make(map[uuid.UUID]int),
+		AdminUsers:      make(map[uuid.UUID]bool),
+		AllowedCatalogs: make(map[uuid.UUID][]string),
+	}
+}
+
+func (m *MockRoleRepository) GetAll(ctx context.Context) ([]models.Role, error) {
+	roles := make([]models.Role, 0, len(m.Roles))
+	for _, r := range m.Roles {
+		roles = append(roles, *r)
+	}
+	return roles, nil
+}
+
+func (m *MockRoleRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Role, error) {
+	if r, ok := m.Roles[id]; ok {
+		return r, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockRoleRepository) GetByName(ctx context.Context, name string) (*models.Role, error) {
+	for _, r := range m.Roles {
+		if r.Name == name {
+			return r, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockRoleRepository) Create(ctx context.Context, name, description string) (*models.Role, error) {
+	r := &models.Role{ID: uuid.New(), Name: name, Description: description}
+	m.Roles[r.ID] = r
+	return r, nil
+}
+
+func (m *MockRoleRepository) Update(ctx context.Context, id uuid.UUID, name, description string, parentRoleID *uuid.UUID) (*models.Role, error) {
+	r, ok := m.Roles[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if name != "" {
+		r.Name = name
+	}
+	r.Description = description
+	r.ParentRoleID = parentRoleID
+	return r, nil
+}
+
+func (m *MockRoleRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	if _, ok := m.Roles[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.Roles, id)
+	return nil
+}
+
+func (m *MockRoleRepository) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]models.Role, error) {
+	var roles []models.Role
+	for _, roleID := range m.UserRoles[userID] {
+		if r, ok := m.Roles[roleID]; ok {
+			roles = append(roles, *r)
+		}
+	}
+	return roles, nil
+}
+
+func (m *MockRoleRepository) GetRoleUsers(ctx context.Context, roleID uuid.UUID) ([]models.User, error) {
+	var users []models.User
+	for userID, roleIDs := range m.UserRoles {
+		for _, id := range roleIDs {
+			if id == roleID {
+				users = append(users, models.User{ID: userID})
+				break
+			}
+		}
+	}
+	return users, nil
+}
+
+func (m *MockRoleRepository) AssignRole(ctx context.Context, userID, roleID uuid.UUID, assignedBy *uuid.UUID) error {
+	m.UserRoles[userID] = append(m.UserRoles[userID], roleID)
+	return nil
+}
+
+func (m *MockRoleRepository) UnassignRole(ctx context.Context, userID, roleID uuid.UUID) error {
+	roleIDs := m.UserRoles[userID]
+	for i, id := range roleIDs {
+		if id == roleID {
+			m.UserRoles[userID] = append(roleIDs[:i], roleIDs[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *MockRoleRepository) GetRoleCatalogs(ctx context.Context, roleID uuid.UUID) ([]string, error) {
+	return m.RoleCatalogs[roleID], nil
+}
+
+func (m *MockRoleRepository) SetRoleCatalogs(ctx context.Context, roleID uuid.UUID, catalogs []string) error {
+	m.RoleCatalogs[roleID] = catalogs
+	return nil
+}
+
+func (m *MockRoleRepository) GetUserAllowedCatalogs(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	if m.GetUserAllowedCatalogsFunc != nil {
+		return m.GetUserAllowedCatalogsFunc(ctx, userID)
+	}
+	if m.AdminUsers[userID] {
+		return nil, nil // nil means all catalogs
+	}
+	if catalogs, ok := m.AllowedCatalogs[userID]; ok {
+		return catalogs, nil
+	}
+
+	seen := make(map[string]bool)
+	catalogs := []string{}
+	add := func(c string) {
+		if !seen[c] {
+			seen[c] = true
+			catalogs = append(catalogs, c)
+		}
+	}
+	for _, id := range m.effectiveRoles(userID) {
+		for _, c := range m.RoleCatalogs[id] {
+			add(c)
+		}
+		for _, sp := range m.RoleSchemas[id] {
+			add(sp.Catalog)
+		}
+	}
+	return catalogs, nil
+}
+
+func (m *MockRoleRepository) GetRoleSchemas(ctx context.Context, roleID uuid.UUID) ([]models.SchemaPermission, error) {
+	return m.RoleSchemas[roleID], nil
+}
+
+func (m *MockRoleRepository) SetRoleSchemas(ctx context.Context, roleID uuid.UUID, schemas []models.SchemaPermission) error {
+	m.RoleSchemas[roleID] = schemas
+	return nil
+}
+
+func (m *MockRoleRepository) GetRoleRowFilters(ctx context.Context, roleID uuid.UUID) ([]models.RowFilter, error) {
+	return m.RoleRowFilters[roleID], nil
+}
+
+func (m *MockRoleRepository) SetRoleRowFilters(ctx context.Context, roleID uuid.UUID, filters []models.RowFilter) error {
+	m.RoleRowFilters[roleID] = filters
+	return nil
+}
+
+func (m *MockRoleRepository) GetUserRowFilters(ctx context.Context, userID uuid.UUID) ([]models.RowFilter, error) {
+	if m.AdminUsers[userID] {
+		return nil, nil
+	}
+	filters := []models.RowFilter{}
+	for _, id := range m.effectiveRoles(userID) {
+		filters = append(filters, m.RoleRowFilters[id]...)
+	}
+	return filters, nil
+}
+
+func (m *MockRoleRepository) GetRoleQueryTimeout(ctx context.Context, roleID uuid.UUID) (*int, error) {
+	if seconds, ok := m.RoleTimeouts[roleID]; ok {
+		return &seconds, nil
+	}
+	return nil, nil
+}
+
+func (m *MockRoleRepository) SetRoleQueryTimeout(ctx context.Context, roleID uuid.UUID, seconds *int) error {
+	if seconds == nil {
+		delete(m.RoleTimeouts, roleID)
+		return nil
+	}
+	m.RoleTimeouts[roleID] = *seconds
+	return nil
+}
+
+func (m *MockRoleRepository) GetUserMaxQueryTimeout(ctx context.Context, userID uuid.UUID) (*int, error) {
+	if m.AdminUsers[userID] {
+		return nil, nil
+	}
+	var longest *int
+	for _, id := range m.effectiveRoles(userID) {
+		if seconds, ok := m.RoleTimeouts[id]; ok && (longest == nil || seconds > *longest) {
+			longest = &seconds
+		}
+	}
+	return longest, nil
+}
+
+func (m *MockRoleRepository) GetUserAllowedSchemas(ctx context.Context, userID uuid.UUID) ([]models.SchemaPermission, error) {
+	if m.AdminUsers[userID] {
+		return nil, nil
+	}
+	if catalogs, ok := m.AllowedCatalogs[userID]; ok {
+		schemas := []models.SchemaPermission{}
+		for _, c := range catalogs {
+			schemas = append(schemas, models.SchemaPermission{Catalog: c, Schema: models.AllSchemas})
+		}
+		return schemas, nil
+	}
+
+	schemas := []models.SchemaPermission{}
+	for _, id := range m.effectiveRoles(userID) {
+		for _, c := range m.RoleCatalogs[id] {
+			schemas = append(schemas, models.SchemaPermission{Catalog: c, Schema: models.AllSchemas})
+		}
+		schemas = append(schemas, m.RoleSchemas[id]...)
+	}
+	return schemas, nil
+}
+
+// effectiveRoles returns the user's roles and all of their ancestors
+func (m *MockRoleRepository) effectiveRoles(userID uuid.UUID) []uuid.UUID {
+	var ids []uuid.UUID
+	visited := make(map[uuid.UUID]bool)
+	for _, roleID := range m.UserRoles[userID] {
+		for id := &roleID; id != nil && !visited[*id]; {
+			visited[*id] = true
+			ids = append(ids, *id)
+			r, ok := m.Roles[*id]
+			if !ok {
+				break
+			}
+			id = r.ParentRoleID
+		}
+	}
+	return ids
+}
+
+func (m *MockRoleRepository) IsUserAdmin(ctx context.Context, userID uuid.UUID) (bool, error) {
+	if m.IsUserAdminFunc != nil {
+		return m.IsUserAdminFunc(ctx, userID)
+	}
+	return m.AdminUsers[userID], nil
+}
+
+func (m *MockRoleRepository) GetAdminRole(ctx context.Context) (*models.Role, error) {
+	return m.GetByName(ctx, "admin")
+}
+
+func (m *MockRoleRepository) CountUsers(ctx context.Context) (int, error) {
+	return m.UserCount, nil
+}
+
+func (m *MockRoleRepository) GetAllUsersWithRoles(ctx context.Context) ([]models.UserWithRoles, error) {
+	var users []models.UserWithRoles
+	for userID := range m.UserRoles {
+		roles, _ := m.GetUserRoles(ctx, userID)
+		users = append(users, models.UserWithRoles{User: models.User{ID: userID}, Roles: roles})
+	}
+	return users, nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mitsume/backend/internal/database"
 	"github.com/mitsume/backend/internal/models"
 )
 
@@ -23,7 +24,7 @@ func NewPostgresRoleRepository(pool *pgxpool.Pool) *PostgresRoleRepository {
 // GetAll returns all roles
 func (r *PostgresRoleRepository) GetAll(ctx context.Context) ([]models.Role, error) {
 	rows, err := r.pool.Query(ctx,
-		`SELECT id, name, description, is_system, created_at, updated_at
+		`SELECT id, name, description, parent_role_id, is_system, created_at, updated_at
 		 FROM roles ORDER BY is_system DESC, name ASC`)
 	if err != nil {
 		return nil, err
@@ -34,7 +35,7 @@ func (r *PostgresRoleRepository) GetAll(ctx context.Context) ([]models.Role, err
 	for rows.Next() {
 		var role models.Role
 		var description *string
-		if err := rows.Scan(&role.ID, &role.Name, &description, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt); err != nil {
+		if err := rows.Scan(&role.ID, &role.Name, &description, &role.ParentRoleID, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt); err != nil {
 			return nil, err
 		}
 		if description != nil {
@@ -50,10 +51,10 @@ func (r *PostgresRoleRepository) GetByID(ctx context.Context, id uuid.UUID) (*mo
 	var role models.Role
 	var description *string
 	err := r.pool.QueryRow(ctx,
-		`SELECT id, name, description, is_system, created_at, updated_at
+		`SELECT id, name, description, parent_role_id, is_system, created_at, updated_at
 		 FROM roles WHERE id = $1`,
 		id,
-	).Scan(&role.ID, &role.Name, &description, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt)
+	).Scan(&role.ID, &role.Name, &description, &role.ParentRoleID, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -71,10 +72,10 @@ func (r *PostgresRoleRepository) GetByName(ctx context.Context, name string) (*m
 	var role models.Role
 	var description *string
 	err := r.pool.QueryRow(ctx,
-		`SELECT id, name, description, is_system, created_at, updated_at
+		`SELECT id, name, description, parent_role_id, is_system, created_at, updated_at
 		 FROM roles WHERE name = $1`,
 		name,
-	).Scan(&role.ID, &role.Name, &description, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt)
+	).Scan(&role.ID, &role.Name, &description, &role.ParentRoleID, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -94,9 +95,9 @@ func (r *PostgresRoleRepository) Create(ctx context.Context, name, description s
 	err := r.pool.QueryRow(ctx,
 		`INSERT INTO roles (name, description)
 		 VALUES ($1, $2)
-		 RETURNING id, name, description, is_system, created_at, updated_at`,
+		 RETURNING id, name, description, parent_role_id, is_system, created_at, updated_at`,
 		name, description,
-	).Scan(&role.ID, &role.Name, &desc, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt)
+	).Scan(&role.ID, &role.Name, &desc, &role.ParentRoleID, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -107,15 +108,15 @@ func (r *PostgresRoleRepository) Create(ctx context.Context, name, description s
 }
 
 // Update updates an existing role
-func (r *PostgresRoleRepository) Update(ctx context.Context, id uuid.UUID, name, description string) (*models.Role, error) {
+func (r *PostgresRoleRepository) Update(ctx context.Context, id uuid.UUID, name, description string, parentRoleID *uuid.UUID) (*models.Role, error) {
 	var role models.Role
 	var desc *string
 	err := r.pool.QueryRow(ctx,
-		`UPDATE roles SET name = $2, description = $3, updated_at = CURRENT_TIMESTAMP
+		`UPDATE roles SET name = $2, description = $3, parent_role_id = $4, updated_at = CURRENT_TIMESTAMP
 		 WHERE id = $1 AND is_system = FALSE
-		 RETURNING id, name, description, is_system, created_at, updated_at`,
-		id, name, description,
-	).Scan(&role.ID, &role.Name, &desc, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt)
+		 RETURNING id, name, description, parent_role_id, is_system, created_at, updated_at`,
+		id, name, description, parentRoleID,
+	).Scan(&role.ID, &role.Name, &desc, &role.ParentRoleID, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -146,7 +147,7 @@ func (r *PostgresRoleRepository) Delete(ctx context.Context, id uuid.UUID) error
 // GetUserRoles returns all roles assigned to a user
 func (r *PostgresRoleRepository) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]models.Role, error) {
 	rows, err := r.pool.Query(ctx,
-		`SELECT r.id, r.name, r.description, r.is_system, r.created_at, r.updated_at
+		`SELECT r.id, r.name, r.description, r.parent_role_id, r.is_system, r.created_at, r.updated_at
 		 FROM roles r
 		 INNER JOIN user_roles ur ON r.id = ur.role_id
 		 WHERE ur.user_id = $1
@@ -162,7 +163,7 @@ func (r *PostgresRoleRepository) GetUserRoles(ctx context.Context, userID uuid.U
 	for rows.Next() {
 		var role models.Role
 		var description *string
-		if err := rows.Scan(&role.ID, &role.Name, &description, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt); err != nil {
+		if err := rows.Scan(&role.ID, &role.Name, &description, &role.ParentRoleID, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt); err != nil {
 			return nil, err
 		}
 		if description != nil {
@@ -249,23 +250,106 @@ func (r *PostgresRoleRepository) GetRoleCatalogs(ctx context.Context, roleID uui
 
 // SetRoleCatalogs sets the catalog permissions for a role (replaces existing)
 func (r *PostgresRoleRepository) SetRoleCatalogs(ctx context.Context, roleID uuid.UUID, catalogs []string) error {
+	return database.WithTxOn(ctx, r.pool, func(tx pgx.Tx) error {
+		// Delete existing permissions
+		_, err := tx.Exec(ctx, `DELETE FROM role_catalog_permissions WHERE role_id = $1`, roleID)
+		if err != nil {
+			return err
+		}
+
+		// Insert new permissions
+		for _, catalog := range catalogs {
+			_, err = tx.Exec(ctx,
+				`INSERT INTO role_catalog_permissions (role_id, catalog_name) VALUES ($1, $2)`,
+				roleID, catalog,
+			)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetUserAllowedCatalogs returns all catalogs a user can access (union of all catalog and schema
+// permissions, including those inherited from each role's ancestors)
+func (r *PostgresRoleRepository) GetUserAllowedCatalogs(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	// Check if user has admin role (admin can access all catalogs)
+	isAdmin, err := r.IsUserAdmin(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if isAdmin {
+		// Return nil to indicate "all catalogs allowed"
+		return nil, nil
+	}
+
+	rows, err := r.pool.Query(ctx,
+		`WITH RECURSIVE effective_roles(role_id) AS (
+			SELECT role_id FROM user_roles WHERE user_id = $1
+			UNION
+			SELECT r.parent_role_id
+			FROM roles r
+			INNER JOIN effective_roles er ON r.id = er.role_id
+			WHERE r.parent_role_id IS NOT NULL
+		 )
+		 SELECT catalog_name FROM role_catalog_permissions rcp
+		 INNER JOIN effective_roles er ON rcp.role_id = er.role_id
+		 UNION
+		 SELECT catalog_name FROM role_schema_permissions rsp
+		 INNER JOIN effective_roles er ON rsp.role_id = er.role_id
+		 ORDER BY catalog_name`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var catalogs []string
+	for rows.Next() {
+		var catalog string
+		if err := rows.Scan(&catalog); err != nil {
+			return nil, err
+		}
+		catalogs = append(catalogs, catalog)
+	}
+	return catalogs, rows.Err()
+}
+
+// GetRoleSchemas returns all schema permissions for a role
+func (r *PostgresRoleRepository) GetRoleSchemas(ctx context.Context, roleID uuid.UUID) ([]models.SchemaPermission, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT catalog_name, schema_name FROM role_schema_permissions
+		 WHERE role_id = $1 ORDER BY catalog_name, schema_name`,
+		roleID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSchemaPermissions(rows)
+}
+
+// SetRoleSchemas sets the schema permissions for a role (replaces existing)
+func (r *PostgresRoleRepository) SetRoleSchemas(ctx context.Context, roleID uuid.UUID, schemas []models.SchemaPermission) error {
 	tx, err := r.pool.Begin(ctx)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback(ctx)
 
-	// Delete existing permissions
-	_, err = tx.Exec(ctx, `DELETE FROM role_catalog_permissions WHERE role_id = $1`, roleID)
+	_, err = tx.Exec(ctx, `DELETE FROM role_schema_permissions WHERE role_id = $1`, roleID)
 	if err != nil {
 		return err
 	}
 
-	// Insert new permissions
-	for _, catalog := range catalogs {
+	for _, sp := range schemas {
 		_, err = tx.Exec(ctx,
-			`INSERT INTO role_catalog_permissions (role_id, catalog_name) VALUES ($1, $2)`,
-			roleID, catalog,
+			`INSERT INTO role_schema_permissions (role_id, catalog_name, schema_name) VALUES ($1, $2, $3)
+			 ON CONFLICT (role_id, catalog_name, schema_name) DO NOTHING`,
+			roleID, sp.Catalog, sp.Schema,
 		)
 		if err != nil {
 			return err
@@ -275,24 +359,72 @@ func (r *PostgresRoleRepository) SetRoleCatalogs(ctx context.Context, roleID uui
 	return tx.Commit(ctx)
 }
 
-// GetUserAllowedCatalogs returns all catalogs a user can access (union of all role permissions)
-func (r *PostgresRoleRepository) GetUserAllowedCatalogs(ctx context.Context, userID uuid.UUID) ([]string, error) {
-	// Check if user has admin role (admin can access all catalogs)
+// GetRoleRowFilters returns the row filters of a role
+func (r *PostgresRoleRepository) GetRoleRowFilters(ctx context.Context, roleID uuid.UUID) ([]models.RowFilter, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT catalog_name, schema_name, table_name, predicate FROM role_row_filters
+		 WHERE role_id = $1 ORDER BY catalog_name, schema_name, table_name, created_at`,
+		roleID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRowFilters(rows)
+}
+
+// SetRoleRowFilters sets the row filters of a role (replaces existing). Names are stored in
+// lower case, as Trino resolves unquoted identifiers.
+func (r *PostgresRoleRepository) SetRoleRowFilters(ctx context.Context, roleID uuid.UUID, filters []models.RowFilter) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `DELETE FROM role_row_filters WHERE role_id = $1`, roleID)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range filters {
+		_, err = tx.Exec(ctx,
+			`INSERT INTO role_row_filters (role_id, catalog_name, schema_name, table_name, predicate)
+			 VALUES ($1, LOWER($2), LOWER($3), LOWER($4), $5)`,
+			roleID, f.Catalog, f.Schema, f.Table, f.Predicate,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// GetUserRowFilters returns the row filters of all roles a user has, directly or through
+// parent roles. Admins are not filtered, so nil is returned for them.
+func (r *PostgresRoleRepository) GetUserRowFilters(ctx context.Context, userID uuid.UUID) ([]models.RowFilter, error) {
 	isAdmin, err := r.IsUserAdmin(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 	if isAdmin {
-		// Return nil to indicate "all catalogs allowed"
 		return nil, nil
 	}
 
 	rows, err := r.pool.Query(ctx,
-		`SELECT DISTINCT rcp.catalog_name
-		 FROM role_catalog_permissions rcp
-		 INNER JOIN user_roles ur ON rcp.role_id = ur.role_id
-		 WHERE ur.user_id = $1
-		 ORDER BY rcp.catalog_name`,
+		`WITH RECURSIVE effective_roles(role_id) AS (
+			SELECT role_id FROM user_roles WHERE user_id = $1
+			UNION
+			SELECT r.parent_role_id
+			FROM roles r
+			INNER JOIN effective_roles er ON r.id = er.role_id
+			WHERE r.parent_role_id IS NOT NULL
+		 )
+		 SELECT DISTINCT catalog_name, schema_name, table_name, predicate FROM role_row_filters rrf
+		 INNER JOIN effective_roles er ON rrf.role_id = er.role_id
+		 ORDER BY 1, 2, 3, 4`,
 		userID,
 	)
 	if err != nil {
@@ -300,15 +432,130 @@ func (r *PostgresRoleRepository) GetUserAllowedCatalogs(ctx context.Context, use
 	}
 	defer rows.Close()
 
-	var catalogs []string
+	filters, err := scanRowFilters(rows)
+	if err != nil {
+		return nil, err
+	}
+	if filters == nil {
+		filters = []models.RowFilter{}
+	}
+	return filters, nil
+}
+
+// GetRoleQueryTimeout returns the role's cap on query timeouts in seconds (nil if unset)
+func (r *PostgresRoleRepository) GetRoleQueryTimeout(ctx context.Context, roleID uuid.UUID) (*int, error) {
+	var seconds *int
+	err := r.pool.QueryRow(ctx,
+		`SELECT max_query_timeout_seconds FROM roles WHERE id = $1`,
+		roleID,
+	).Scan(&seconds)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return seconds, nil
+}
+
+// SetRoleQueryTimeout sets the role's cap on query timeouts in seconds (nil removes it)
+func (r *PostgresRoleRepository) SetRoleQueryTimeout(ctx context.Context, roleID uuid.UUID, seconds *int) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE roles SET max_query_timeout_seconds = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`,
+		roleID, seconds,
+	)
+	return err
+}
+
+// GetUserMaxQueryTimeout returns the largest query timeout cap among the roles a user has,
+// directly or through parent roles. It is nil for admins and when no role sets a cap.
+func (r *PostgresRoleRepository) GetUserMaxQueryTimeout(ctx context.Context, userID uuid.UUID) (*int, error) {
+	isAdmin, err := r.IsUserAdmin(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if isAdmin {
+		return nil, nil
+	}
+
+	var seconds *int
+	err = r.pool.QueryRow(ctx,
+		`WITH RECURSIVE effective_roles(role_id) AS (
+			SELECT role_id FROM user_roles WHERE user_id = $1
+			UNION
+			SELECT r.parent_role_id
+			FROM roles r
+			INNER JOIN effective_roles er ON r.id = er.role_id
+			WHERE r.parent_role_id IS NOT NULL
+		 )
+		 SELECT MAX(r.max_query_timeout_seconds) FROM roles r
+		 INNER JOIN effective_roles er ON r.id = er.role_id`,
+		userID,
+	).Scan(&seconds)
+	if err != nil {
+		return nil, err
+	}
+	return seconds, nil
+}
+
+func scanRowFilters(rows pgx.Rows) ([]models.RowFilter, error) {
+	var filters []models.RowFilter
 	for rows.Next() {
-		var catalog string
-		if err := rows.Scan(&catalog); err != nil {
+		var f models.RowFilter
+		if err := rows.Scan(&f.Catalog, &f.Schema, &f.Table, &f.Predicate); err != nil {
 			return nil, err
 		}
-		catalogs = append(catalogs, catalog)
+		filters = append(filters, f)
 	}
-	return catalogs, rows.Err()
+	return filters, rows.Err()
+}
+
+// GetUserAllowedSchemas returns all schemas a user can access. Catalog-level grants are
+// returned as (catalog, models.AllSchemas) so they cover every schema of the catalog.
+func (r *PostgresRoleRepository) GetUserAllowedSchemas(ctx context.Context, userID uuid.UUID) ([]models.SchemaPermission, error) {
+	isAdmin, err := r.IsUserAdmin(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if isAdmin {
+		return nil, nil
+	}
+
+	rows, err := r.pool.Query(ctx,
+		`WITH RECURSIVE effective_roles(role_id) AS (
+			SELECT role_id FROM user_roles WHERE user_id = $1
+			UNION
+			SELECT r.parent_role_id
+			FROM roles r
+			INNER JOIN effective_roles er ON r.id = er.role_id
+			WHERE r.parent_role_id IS NOT NULL
+		 )
+		 SELECT catalog_name, $2::text FROM role_catalog_permissions rcp
+		 INNER JOIN effective_roles er ON rcp.role_id = er.role_id
+		 UNION
+		 SELECT catalog_name, schema_name FROM role_schema_permissions rsp
+		 INNER JOIN effective_roles er ON rsp.role_id = er.role_id
+		 ORDER BY 1, 2`,
+		userID, models.AllSchemas,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSchemaPermissions(rows)
+}
+
+func scanSchemaPermissions(rows pgx.Rows) ([]models.SchemaPermission, error) {
+	var schemas []models.SchemaPermission
+	for rows.Next() {
+		var sp models.SchemaPermission
+		if err := rows.Scan(&sp.Catalog, &sp.Schema); err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, sp)
+	}
+	return schemas, rows.Err()
 }
 
 // IsUserAdmin checks if a user has the admin role
@@ -2,7 +2,10 @@ package repository
 
 import (
 	"context"
+	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/mitsume/backend/internal/models"
@@ -12,8 +15,8 @@ import (
 type MockTrinoExecutor struct {
 	// Predefined responses
 	Catalogs []string
-	Schemas  map[string][]string                               // catalog -> schemas
-	Tables   map[string]map[string][]string                    // catalog -> schema -> tables
+	Schemas  map[string][]string                                  // catalog -> schemas
+	Tables   map[string]map[string][]string                       // catalog -> schema -> tables
 	Columns  map[string]map[string]map[string][]models.ColumnInfo // catalog -> schema -> table -> columns
 
 	// Query results
@@ -27,14 +30,19 @@ type MockTrinoExecutor struct {
 	GetColumnsError   error
 
 	// Function hooks for custom behavior
-	ExecuteQueryFunc func(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error)
-	GetCatalogsFunc  func(ctx context.Context) ([]string, error)
-	GetSchemasFunc   func(ctx context.Context, catalog string) ([]string, error)
-	GetTablesFunc    func(ctx context.Context, catalog, schema string) ([]string, error)
-	GetColumnsFunc   func(ctx context.Context, catalog, schema, table string) ([]models.ColumnInfo, error)
+	ExecuteQueryFunc   func(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error)
+	GetCatalogsFunc    func(ctx context.Context) ([]string, error)
+	GetSchemasFunc     func(ctx context.Context, catalog string) ([]string, error)
+	GetTablesFunc      func(ctx context.Context, catalog, schema string) ([]string, error)
+	GetColumnsFunc     func(ctx context.Context, catalog, schema, table string) ([]models.ColumnInfo, error)
+	GetTableSampleFunc func(ctx context.Context, catalog, schema, table string, limit int) (*models.QueryResult, error)
+	// CachedResultTimeFunc answers CachedResultTime; without it nothing is reported as cached
+	CachedResultTimeFunc func(ctx context.Context, query, catalog, schema string, maxStaleness time.Duration) (cachedAt, expiresAt time.Time, ok bool)
 
 	// Call tracking
 	ExecuteQueryCalls []ExecuteQueryCall
+	RefreshQueryCalls []ExecuteQueryCall
+	callsMu           sync.Mutex
 }
 
 // ExecuteQueryCall records a call to ExecuteQuery
@@ -57,11 +65,13 @@ func NewMockTrinoExecutor() *MockTrinoExecutor {
 
 func (m *MockTrinoExecutor) ExecuteQuery(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error) {
 	// Track the call
+	m.callsMu.Lock()
 	m.ExecuteQueryCalls = append(m.ExecuteQueryCalls, ExecuteQueryCall{
 		Query:   query,
 		Catalog: catalog,
 		Schema:  schema,
 	})
+	m.callsMu.Unlock()
 
 	if m.ExecuteQueryFunc != nil {
 		return m.ExecuteQueryFunc(ctx, query, catalog, schema)
@@ -150,6 +160,17 @@ func (m *MockTrinoExecutor) GetColumns(ctx context.Context, catalog, schema, tab
 	return []models.ColumnInfo{}, nil
 }
 
+// GetTableSample implements TrinoExecutor interface
+// By default it delegates to ExecuteQuery so the generated SQL is recorded in ExecuteQueryCalls
+func (m *MockTrinoExecutor) GetTableSample(ctx context.Context, catalog, schema, table string, limit int) (*models.QueryResult, error) {
+	if m.GetTableSampleFunc != nil {
+		return m.GetTableSampleFunc(ctx, catalog, schema, table, limit)
+	}
+
+	query := fmt.Sprintf(`SELECT * FROM "%s"."%s"."%s" LIMIT %d`, catalog, schema, table, limit)
+	return m.ExecuteQuery(ctx, query, catalog, schema)
+}
+
 // SetupCatalog adds a catalog with schemas and tables for testing
 func (m *MockTrinoExecutor) SetupCatalog(catalog string, schemas map[string][]string) {
 	m.Catalogs = append(m.Catalogs, catalog)
@@ -184,6 +205,35 @@ func (m *MockTrinoExecutor) ExecuteQueryWithCache(ctx context.Context, query, ca
 	return m.ExecuteQuery(ctx, query, catalog, schema)
 }
 
+// ExecuteQueryWithMaxStaleness implements CachedTrinoExecutor interface
+// In mock, it simply delegates to ExecuteQuery (no actual caching)
+func (m *MockTrinoExecutor) ExecuteQueryWithMaxStaleness(ctx context.Context, query, catalog, schema string, priority int, savedQueryID *uuid.UUID, maxStaleness time.Duration) (*models.QueryResult, error) {
+	return m.ExecuteQuery(ctx, query, catalog, schema)
+}
+
+// RefreshQuery implements CachedTrinoExecutor interface
+// In mock, it records the call and delegates to ExecuteQuery (no actual caching)
+func (m *MockTrinoExecutor) RefreshQuery(ctx context.Context, query, catalog, schema string, priority int, savedQueryID *uuid.UUID) (*models.QueryResult, error) {
+	m.callsMu.Lock()
+	m.RefreshQueryCalls = append(m.RefreshQueryCalls, ExecuteQueryCall{
+		Query:   query,
+		Catalog: catalog,
+		Schema:  schema,
+	})
+	m.callsMu.Unlock()
+
+	return m.ExecuteQuery(ctx, query, catalog, schema)
+}
+
+// CachedResultTime implements CachedTrinoExecutor interface
+// In mock, it delegates to CachedResultTimeFunc and otherwise reports a cache miss
+func (m *MockTrinoExecutor) CachedResultTime(ctx context.Context, query, catalog, schema string, priority int, maxStaleness time.Duration) (time.Time, time.Time, bool) {
+	if m.CachedResultTimeFunc != nil {
+		return m.CachedResultTimeFunc(ctx, query, catalog, schema, maxStaleness)
+	}
+	return time.Time{}, time.Time{}, false
+}
+
 // SearchMetadata implements TrinoExecutor interface
 // Returns mock search results matching the query string
 func (m *MockTrinoExecutor) SearchMetadata(ctx context.Context, query, searchType string, catalogs []string, limit int) ([]models.MetadataSearchResult, error) {
@@ -13,6 +13,12 @@ type MockUserRepository struct {
 	UsersByEmail    map[string]*models.User
 	UsersByUsername map[string]*models.User
 	UsersByGoogle   map[string]*models.User
+	UsersByOIDC     map[string]*models.User
+	TokenVersions   map[uuid.UUID]int
+	Statuses        map[uuid.UUID]models.UserStatus
+
+	// RoleRepo, when set, receives the role assignments made by ApprovePending
+	RoleRepo RoleRepository
 
 	// Function hooks for custom behavior
 	FindByIDFunc             func(ctx context.Context, id uuid.UUID) (*models.User, error)
@@ -20,11 +26,14 @@ type MockUserRepository struct {
 	FindByUsernameFunc       func(ctx context.Context, username string) (*models.User, error)
 	FindByEmailOrUsernameFunc func(ctx context.Context, identifier string) (*models.User, error)
 	FindByGoogleIDFunc       func(ctx context.Context, googleID string) (*models.User, error)
+	FindByOIDCSubjectFunc    func(ctx context.Context, subject string) (*models.User, error)
 	ExistsByEmailFunc        func(ctx context.Context, email string) (bool, error)
 	ExistsByUsernameFunc     func(ctx context.Context, username string) (bool, error)
 	CreateFunc               func(ctx context.Context, email, passwordHash, name string) (*models.User, error)
 	CreateAdminUserFunc      func(ctx context.Context, username, passwordHash, name string) (*models.User, error)
 	CreateGoogleUserFunc     func(ctx context.Context, email, name, googleID string) (*models.User, error)
+	CreateOIDCUserFunc       func(ctx context.Context, email, name, subject string) (*models.User, error)
+	GetAuthStateFunc         func(ctx context.Context, id uuid.UUID) (*models.UserAuthState, error)
 }
 
 // NewMockUserRepository creates a new MockUserRepository
@@ -34,6 +43,9 @@ func NewMockUserRepository() *MockUserRepository {
 		UsersByEmail:    make(map[string]*models.User),
 		UsersByUsername: make(map[string]*models.User),
 		UsersByGoogle:   make(map[string]*models.User),
+		UsersByOIDC:     make(map[string]*models.User),
+		TokenVersions:   make(map[uuid.UUID]int),
+		Statuses:        make(map[uuid.UUID]models.UserStatus),
 	}
 }
 
@@ -90,6 +102,16 @@ func (m *MockUserRepository) FindByGoogleID(ctx context.Context, googleID string
 	return nil, ErrNotFound
 }
 
+func (m *MockUserRepository) FindByOIDCSubject(ctx context.Context, subject string) (*models.User, error) {
+	if m.FindByOIDCSubjectFunc != nil {
+		return m.FindByOIDCSubjectFunc(ctx, subject)
+	}
+	if user, ok := m.UsersByOIDC[subject]; ok {
+		return user, nil
+	}
+	return nil, ErrNotFound
+}
+
 func (m *MockUserRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
 	if m.ExistsByEmailFunc != nil {
 		return m.ExistsByEmailFunc(ctx, email)
@@ -154,6 +176,91 @@ func (m *MockUserRepository) CreateGoogleUser(ctx context.Context, email, name,
 	return user, nil
 }
 
+// CreateOIDCUser records the new user as pending in Statuses
+func (m *MockUserRepository) CreateOIDCUser(ctx context.Context, email, name, subject string) (*models.User, error) {
+	if m.CreateOIDCUserFunc != nil {
+		return m.CreateOIDCUserFunc(ctx, email, name, subject)
+	}
+	user := &models.User{
+		ID:           uuid.New(),
+		Email:        &email,
+		Name:         name,
+		AuthProvider: "oidc",
+	}
+	m.Users[user.ID] = user
+	m.UsersByEmail[email] = user
+	m.UsersByOIDC[subject] = user
+	m.Statuses[user.ID] = models.UserStatusPending
+	return user, nil
+}
+
+// GetAuthState returns the version recorded in TokenVersions (0 for unknown users)
+// and the status recorded in Statuses (active for unknown users)
+func (m *MockUserRepository) GetAuthState(ctx context.Context, id uuid.UUID) (*models.UserAuthState, error) {
+	if m.GetAuthStateFunc != nil {
+		return m.GetAuthStateFunc(ctx, id)
+	}
+	status, ok := m.Statuses[id]
+	if !ok {
+		status = models.UserStatusActive
+	}
+	return &models.UserAuthState{TokenVersion: m.TokenVersions[id], Status: status}, nil
+}
+
+func (m *MockUserRepository) SetStatus(ctx context.Context, id uuid.UUID, status models.UserStatus) error {
+	m.Statuses[id] = status
+	return nil
+}
+
+// ApprovePending activates users added to Users whose Statuses entry is pending, and assigns
+// roleID through RoleRepo when both are set. Users without a Statuses entry are active.
+func (m *MockUserRepository) ApprovePending(ctx context.Context, ids []uuid.UUID, roleID *uuid.UUID, approvedBy uuid.UUID) (map[uuid.UUID]models.UserStatus, error) {
+	previous := make(map[uuid.UUID]models.UserStatus)
+	for _, id := range ids {
+		if _, ok := m.Users[id]; !ok {
+			continue
+		}
+		status, ok := m.Statuses[id]
+		if !ok {
+			status = models.UserStatusActive
+		}
+		previous[id] = status
+		if status != models.UserStatusPending {
+			continue
+		}
+		m.Statuses[id] = models.UserStatusActive
+		if roleID != nil && m.RoleRepo != nil {
+			if err := m.RoleRepo.AssignRole(ctx, id, *roleID, &approvedBy); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return previous, nil
+}
+
+// GetPasswordHash returns the PasswordHash of a local user added with AddUser
+func (m *MockUserRepository) GetPasswordHash(ctx context.Context, id uuid.UUID) (string, error) {
+	user, ok := m.Users[id]
+	if !ok || user.AuthProvider != "local" || user.PasswordHash == "" {
+		return "", ErrNotFound
+	}
+	return user.PasswordHash, nil
+}
+
+func (m *MockUserRepository) UpdatePasswordHash(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	user, ok := m.Users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	user.PasswordHash = passwordHash
+	return nil
+}
+
+func (m *MockUserRepository) IncrementTokenVersion(ctx context.Context, id uuid.UUID) error {
+	m.TokenVersions[id]++
+	return nil
+}
+
 // AddUser adds a user to the mock repository (helper for tests)
 func (m *MockUserRepository) AddUser(user *models.User) {
 	m.Users[user.ID] = user
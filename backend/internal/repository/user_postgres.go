@@ -7,6 +7,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mitsume/backend/internal/database"
 	"github.com/mitsume/backend/internal/models"
 )
 
@@ -100,6 +101,22 @@ func (r *PostgresUserRepository) FindByGoogleID(ctx context.Context, googleID st
 	return &user, nil
 }
 
+func (r *PostgresUserRepository) FindByOIDCSubject(ctx context.Context, subject string) (*models.User, error) {
+	var user models.User
+	err := r.pool.QueryRow(ctx,
+		`SELECT id, email, username, name, auth_provider, created_at, updated_at
+		 FROM users WHERE oidc_subject = $1`,
+		subject,
+	).Scan(&user.ID, &user.Email, &user.Username, &user.Name, &user.AuthProvider, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (r *PostgresUserRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
 	var exists bool
 	err := r.pool.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)", email).Scan(&exists)
@@ -159,3 +176,139 @@ func (r *PostgresUserRepository) CreateGoogleUser(ctx context.Context, email, na
 	}
 	return &user, nil
 }
+
+func (r *PostgresUserRepository) CreateOIDCUser(ctx context.Context, email, name, subject string) (*models.User, error) {
+	var user models.User
+	err := r.pool.QueryRow(ctx,
+		`INSERT INTO users (email, name, auth_provider, oidc_subject, status)
+		 VALUES ($1, $2, 'oidc', $3, 'pending')
+		 RETURNING id, email, username, name, auth_provider, created_at, updated_at`,
+		email, name, subject,
+	).Scan(&user.ID, &user.Email, &user.Username, &user.Name, &user.AuthProvider, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *PostgresUserRepository) GetAuthState(ctx context.Context, id uuid.UUID) (*models.UserAuthState, error) {
+	var state models.UserAuthState
+	err := r.pool.QueryRow(ctx, "SELECT token_version, status FROM users WHERE id = $1", id).Scan(&state.TokenVersion, &state.Status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (r *PostgresUserRepository) SetStatus(ctx context.Context, id uuid.UUID, status models.UserStatus) error {
+	tag, err := r.pool.Exec(ctx,
+		"UPDATE users SET status = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1",
+		id, string(status),
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresUserRepository) ApprovePending(ctx context.Context, ids []uuid.UUID, roleID *uuid.UUID, approvedBy uuid.UUID) (map[uuid.UUID]models.UserStatus, error) {
+	previous := make(map[uuid.UUID]models.UserStatus, len(ids))
+	err := database.WithTxOn(ctx, r.pool, func(tx pgx.Tx) error {
+		// Lock the rows so a concurrent status change cannot slip between the check and the update
+		rows, err := tx.Query(ctx, "SELECT id, status FROM users WHERE id = ANY($1) FOR UPDATE", ids)
+		if err != nil {
+			return err
+		}
+		var pending []uuid.UUID
+		for rows.Next() {
+			var id uuid.UUID
+			var status string
+			if err := rows.Scan(&id, &status); err != nil {
+				rows.Close()
+				return err
+			}
+			previous[id] = models.UserStatus(status)
+			if previous[id] == models.UserStatusPending {
+				pending = append(pending, id)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		_, err = tx.Exec(ctx,
+			"UPDATE users SET status = 'active', updated_at = CURRENT_TIMESTAMP WHERE id = ANY($1)",
+			pending,
+		)
+		if err != nil {
+			return err
+		}
+		if roleID == nil {
+			return nil
+		}
+		_, err = tx.Exec(ctx,
+			`INSERT INTO user_roles (user_id, role_id, assigned_by)
+			 SELECT unnest($1::uuid[]), $2, $3
+			 ON CONFLICT (user_id, role_id) DO NOTHING`,
+			pending, *roleID, approvedBy,
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return previous, nil
+}
+
+func (r *PostgresUserRepository) GetPasswordHash(ctx context.Context, id uuid.UUID) (string, error) {
+	var hash string
+	err := r.pool.QueryRow(ctx,
+		"SELECT password_hash FROM users WHERE id = $1 AND auth_provider = 'local' AND password_hash IS NOT NULL",
+		id,
+	).Scan(&hash)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return hash, nil
+}
+
+func (r *PostgresUserRepository) UpdatePasswordHash(ctx context.Context, id uuid.UUID, passwordHash string) error {
+	tag, err := r.pool.Exec(ctx,
+		"UPDATE users SET password_hash = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1",
+		id, passwordHash,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresUserRepository) IncrementTokenVersion(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.pool.Exec(ctx,
+		"UPDATE users SET token_version = token_version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $1",
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
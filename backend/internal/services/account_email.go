@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+)
+
+// AccountMailer sends transactional emails to users; *EmailNotifier implements it
+type AccountMailer interface {
+	SendTo(ctx context.Context, recipients []string, msg models.NotificationMessage) error
+}
+
+// SetAccountMailer emails users when an admin activates or disables their account
+// (MITSUME_ADMIN_NOTIFY_ON_APPROVAL). nil disables the emails.
+func (s *AuthService) SetAccountMailer(mailer AccountMailer) {
+	s.mailer = mailer
+}
+
+// accountStatusMessage returns the email telling a user that their account changed from
+// previous to status, or false when the change is not worth an email
+func (s *AuthService) accountStatusMessage(user *models.User, previous, status models.UserStatus) (models.NotificationMessage, bool) {
+	loginURL := strings.TrimRight(s.cfg.Server.FrontendURL, "/") + "/login"
+	switch {
+	case previous == status:
+		return models.NotificationMessage{}, false
+	case status == models.UserStatusActive && previous == models.UserStatusPending:
+		return models.NotificationMessage{
+			Title: "Your Mitsume account has been approved",
+			Body:  fmt.Sprintf("Hello %s,\n\nAn administrator approved your account. You can now sign in at %s", user.Name, loginURL),
+		}, true
+	case status == models.UserStatusActive:
+		return models.NotificationMessage{
+			Title: "Your Mitsume account has been re-enabled",
+			Body:  fmt.Sprintf("Hello %s,\n\nAn administrator re-enabled your account. You can sign in again at %s", user.Name, loginURL),
+		}, true
+	case status == models.UserStatusDisabled:
+		return models.NotificationMessage{
+			Title: "Your Mitsume account has been disabled",
+			Body:  fmt.Sprintf("Hello %s,\n\nAn administrator disabled your account and signed out your sessions. Contact your administrator if you think this is a mistake.", user.Name),
+		}, true
+	}
+	return models.NotificationMessage{}, false
+}
+
+// notifyStatusChange emails the user about their new account status. Users without an email
+// address, such as admin-created ones, are skipped. Failures are logged and never undo the
+// status change.
+func (s *AuthService) notifyStatusChange(ctx context.Context, userID uuid.UUID, previous, status models.UserStatus) {
+	if s.mailer == nil {
+		return
+	}
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to load user %s for account status email: %v", userID, err)
+		return
+	}
+	if user.Email == nil || *user.Email == "" {
+		return
+	}
+	msg, ok := s.accountStatusMessage(user, previous, status)
+	if !ok {
+		return
+	}
+	if err := s.mailer.SendTo(ctx, []string{*user.Email}, msg); err != nil {
+		log.Printf("Failed to send account status email to user %s: %v", userID, err)
+	}
+}
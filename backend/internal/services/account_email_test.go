@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/repository"
+)
+
+// fakeAccountMailer records the emails it is asked to send
+type fakeAccountMailer struct {
+	sent []sentAccountEmail
+	err  error
+}
+
+type sentAccountEmail struct {
+	recipients []string
+	msg        models.NotificationMessage
+}
+
+func (m *fakeAccountMailer) SendTo(ctx context.Context, recipients []string, msg models.NotificationMessage) error {
+	m.sent = append(m.sent, sentAccountEmail{recipients: recipients, msg: msg})
+	return m.err
+}
+
+func newAccountEmailTest(t *testing.T) (*AuthService, *repository.MockUserRepository, *fakeAccountMailer) {
+	t.Helper()
+	cfg := newTestConfig()
+	cfg.Server.FrontendURL = "https://mitsume.example.com/"
+	userRepo := repository.NewMockUserRepository()
+	service := NewAuthService(cfg, userRepo, repository.NewMockRoleRepository(), nil)
+	mailer := &fakeAccountMailer{}
+	service.SetAccountMailer(mailer)
+	return service, userRepo, mailer
+}
+
+func addTestUser(userRepo *repository.MockUserRepository, email string, status models.UserStatus) uuid.UUID {
+	user := &models.User{ID: uuid.New(), Name: "Test User", AuthProvider: "oidc"}
+	if email != "" {
+		user.Email = &email
+	}
+	userRepo.AddUser(user)
+	userRepo.Statuses[user.ID] = status
+	return user.ID
+}
+
+func TestApproveUsers_EmailsApprovedUsers(t *testing.T) {
+	service, userRepo, mailer := newAccountEmailTest(t)
+
+	pendingID := addTestUser(userRepo, "new@example.com", models.UserStatusPending)
+	noEmailID := addTestUser(userRepo, "", models.UserStatusPending)
+	activeID := addTestUser(userRepo, "active@example.com", models.UserStatusActive)
+
+	resp, err := service.ApproveUsers(context.Background(), uuid.New(), []uuid.UUID{pendingID, noEmailID, activeID}, nil)
+	if err != nil {
+		t.Fatalf("ApproveUsers() error = %v", err)
+	}
+	if resp.Approved != 2 {
+		t.Fatalf("Approved = %d, want 2", resp.Approved)
+	}
+
+	// The user without an email address is approved but not emailed; the active user is skipped
+	if len(mailer.sent) != 1 {
+		t.Fatalf("sent %d emails, want 1: %+v", len(mailer.sent), mailer.sent)
+	}
+	sent := mailer.sent[0]
+	if len(sent.recipients) != 1 || sent.recipients[0] != "new@example.com" {
+		t.Errorf("recipients = %v, want [new@example.com]", sent.recipients)
+	}
+	if !strings.Contains(sent.msg.Title, "approved") || !strings.Contains(sent.msg.Body, "https://mitsume.example.com/login") {
+		t.Errorf("email = %+v, want an approval with the login URL", sent.msg)
+	}
+}
+
+func TestSetUserStatus_EmailsStatusChanges(t *testing.T) {
+	service, userRepo, mailer := newAccountEmailTest(t)
+	ctx := context.Background()
+	adminID := uuid.New()
+	userID := addTestUser(userRepo, "user@example.com", models.UserStatusActive)
+
+	if err := service.SetUserStatus(ctx, adminID, userID, models.UserStatusDisabled); err != nil {
+		t.Fatalf("SetUserStatus() error = %v", err)
+	}
+	// Setting the same status again does not email
+	if err := service.SetUserStatus(ctx, adminID, userID, models.UserStatusDisabled); err != nil {
+		t.Fatalf("SetUserStatus() error = %v", err)
+	}
+	if err := service.SetUserStatus(ctx, adminID, userID, models.UserStatusActive); err != nil {
+		t.Fatalf("SetUserStatus() error = %v", err)
+	}
+
+	if len(mailer.sent) != 2 {
+		t.Fatalf("sent %d emails, want 2: %+v", len(mailer.sent), mailer.sent)
+	}
+	if !strings.Contains(mailer.sent[0].msg.Title, "disabled") || !strings.Contains(mailer.sent[1].msg.Title, "re-enabled") {
+		t.Errorf("email titles = %q, %q, want disabled then re-enabled", mailer.sent[0].msg.Title, mailer.sent[1].msg.Title)
+	}
+}
+
+func TestSetUserStatus_EmailFailureKeepsStatus(t *testing.T) {
+	service, userRepo, mailer := newAccountEmailTest(t)
+	mailer.err = errors.New("SMTP not configured")
+	userID := addTestUser(userRepo, "user@example.com", models.UserStatusPending)
+
+	if err := service.SetUserStatus(context.Background(), uuid.New(), userID, models.UserStatusActive); err != nil {
+		t.Fatalf("SetUserStatus() error = %v, want the email failure ignored", err)
+	}
+	if userRepo.Statuses[userID] != models.UserStatusActive {
+		t.Errorf("status = %s, want active", userRepo.Statuses[userID])
+	}
+	if len(mailer.sent) != 1 {
+		t.Errorf("sent %d emails, want 1 attempt", len(mailer.sent))
+	}
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ActiveLimitError is returned when a user already has the maximum number of active
+// alerts or subscriptions
+type ActiveLimitError struct {
+	Resource string // "alerts" or "subscriptions"
+	Count    int
+	Limit    int
+}
+
+func (e *ActiveLimitError) Error() string {
+	return fmt.Sprintf("active %s limit reached (%d/%d): deactivate or delete one first", e.Resource, e.Count, e.Limit)
+}
+
+// AdminChecker reports whether a user is an admin
+type AdminChecker interface {
+	IsAdmin(ctx context.Context, userID uuid.UUID) (bool, error)
+}
+
+// activeLimit caps how many active alerts or subscriptions a user may have.
+// The zero value (or Max <= 0) is unlimited. Admins are exempt.
+type activeLimit struct {
+	Max    int
+	admins AdminChecker
+}
+
+// check fails with *ActiveLimitError when activating one more item would exceed the limit.
+// count returns how many items the user has active now.
+func (l activeLimit) check(ctx context.Context, userID uuid.UUID, resource string, count func() (int, error)) error {
+	if l.Max <= 0 {
+		return nil
+	}
+	if l.admins != nil {
+		isAdmin, err := l.admins.IsAdmin(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if isAdmin {
+			return nil
+		}
+	}
+
+	n, err := count()
+	if err != nil {
+		return fmt.Errorf("failed to count active %s: %w", resource, err)
+	}
+	if n >= l.Max {
+		return &ActiveLimitError{Resource: resource, Count: n, Limit: l.Max}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type fakeAdminChecker map[uuid.UUID]bool
+
+func (f fakeAdminChecker) IsAdmin(ctx context.Context, userID uuid.UUID) (bool, error) {
+	return f[userID], nil
+}
+
+func TestActiveLimit_Check(t *testing.T) {
+	ctx := context.Background()
+	user := uuid.New()
+	admin := uuid.New()
+	limit := activeLimit{Max: 3, admins: fakeAdminChecker{admin: true}}
+	countOf := func(n int) func() (int, error) {
+		return func() (int, error) { return n, nil }
+	}
+
+	if err := limit.check(ctx, user, "alerts", countOf(2)); err != nil {
+		t.Fatalf("check() under limit error = %v", err)
+	}
+
+	err := limit.check(ctx, user, "alerts", countOf(3))
+	var limitErr *ActiveLimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("check() at limit error = %v, want *ActiveLimitError", err)
+	}
+	if limitErr.Count != 3 || limitErr.Limit != 3 || limitErr.Resource != "alerts" {
+		t.Fatalf("ActiveLimitError = %+v", limitErr)
+	}
+
+	if err := limit.check(ctx, admin, "alerts", countOf(10)); err != nil {
+		t.Fatalf("check() for admin error = %v, want nil", err)
+	}
+
+	unlimited := activeLimit{}
+	if err := unlimited.check(ctx, user, "alerts", countOf(1000)); err != nil {
+		t.Fatalf("check() without limit error = %v, want nil", err)
+	}
+}
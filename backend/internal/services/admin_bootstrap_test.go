@@ -50,7 +50,7 @@ func (m *mockRoleRepository) Create(ctx context.Context, name, description strin
 	return &models.Role{ID: uuid.New(), Name: name, Description: description}, nil
 }
 
-func (m *mockRoleRepository) Update(ctx context.Context, id uuid.UUID, name, description string) (*models.Role, error) {
+func (m *mockRoleRepository) Update(ctx context.Context, id uuid.UUID, name, description string, parentRoleID *uuid.UUID) (*models.Role, error) {
 	return &models.Role{ID: id, Name: name, Description: description}, nil
 }
 
@@ -93,6 +93,42 @@ func (m *mockRoleRepository) GetUserAllowedCatalogs(ctx context.Context, userID
 	return nil, nil
 }
 
+func (m *mockRoleRepository) GetRoleSchemas(ctx context.Context, roleID uuid.UUID) ([]models.SchemaPermission, error) {
+	return nil, nil
+}
+
+func (m *mockRoleRepository) SetRoleSchemas(ctx context.Context, roleID uuid.UUID, schemas []models.SchemaPermission) error {
+	return nil
+}
+
+func (m *mockRoleRepository) GetRoleRowFilters(ctx context.Context, roleID uuid.UUID) ([]models.RowFilter, error) {
+	return nil, nil
+}
+
+func (m *mockRoleRepository) SetRoleRowFilters(ctx context.Context, roleID uuid.UUID, filters []models.RowFilter) error {
+	return nil
+}
+
+func (m *mockRoleRepository) GetUserRowFilters(ctx context.Context, userID uuid.UUID) ([]models.RowFilter, error) {
+	return nil, nil
+}
+
+func (m *mockRoleRepository) GetRoleQueryTimeout(ctx context.Context, roleID uuid.UUID) (*int, error) {
+	return nil, nil
+}
+
+func (m *mockRoleRepository) SetRoleQueryTimeout(ctx context.Context, roleID uuid.UUID, seconds *int) error {
+	return nil
+}
+
+func (m *mockRoleRepository) GetUserMaxQueryTimeout(ctx context.Context, userID uuid.UUID) (*int, error) {
+	return nil, nil
+}
+
+func (m *mockRoleRepository) GetUserAllowedSchemas(ctx context.Context, userID uuid.UUID) ([]models.SchemaPermission, error) {
+	return nil, nil
+}
+
 func (m *mockRoleRepository) IsUserAdmin(ctx context.Context, userID uuid.UUID) (bool, error) {
 	roleID, ok := m.assignedRoles[userID]
 	return ok && roleID == m.adminRole.ID, nil
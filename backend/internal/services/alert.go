@@ -3,22 +3,33 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mitsume/backend/internal/database"
 	"github.com/mitsume/backend/internal/models"
 )
 
+// alertColumns is the column list shared by every query that loads a QueryAlert (see scanAlert)
+const alertColumns = `id, user_id, query_id, name, description, condition_column, condition_operator,
+		       condition_value, aggregation, COALESCE(logic_operator, 'and'), severity, check_interval_minutes,
+		       cooldown_minutes, is_active, last_checked_at, last_triggered_at, next_check_at,
+		       snoozed_until, created_at, updated_at`
+
 // AlertService manages query alerts
 type AlertService struct {
 	pool                *pgxpool.Pool
 	trinoService        *CachedTrinoService
 	notificationService *NotificationService
 	queryService        *QueryService
+	activeLimit         activeLimit
+	rowFilters          RowFilterSource // nil runs alert queries unfiltered
 }
 
 // NewAlertService creates a new alert service
@@ -31,12 +42,49 @@ func NewAlertService(pool *pgxpool.Pool, trinoService *CachedTrinoService, notif
 	}
 }
 
+// SetActiveLimit caps how many active alerts a non-admin user may have; max <= 0 is unlimited
+func (s *AlertService) SetActiveLimit(max int, admins AdminChecker) {
+	s.activeLimit = activeLimit{Max: max, admins: admins}
+}
+
+// SetRowFilters makes alert queries apply the row filters of the alert owner's roles. Alerts whose
+// query the filters cannot be applied to are rejected with ErrRowFilterUnsupported.
+func (s *AlertService) SetRowFilters(source RowFilterSource) {
+	s.rowFilters = source
+}
+
+// checkActiveLimit fails with *ActiveLimitError when the user may not activate another alert
+func (s *AlertService) checkActiveLimit(ctx context.Context, userID uuid.UUID) error {
+	return s.activeLimit.check(ctx, userID, "alerts", func() (int, error) {
+		var n int
+		err := s.pool.QueryRow(ctx,
+			`SELECT COUNT(*) FROM query_alerts WHERE user_id = $1 AND is_active = TRUE`, userID,
+		).Scan(&n)
+		return n, err
+	})
+}
+
+// scanAlert scans a row selected with alertColumns
+func scanAlert(row pgx.Row) (*models.QueryAlert, error) {
+	var a models.QueryAlert
+	var aggregation *string
+	if err := row.Scan(&a.ID, &a.UserID, &a.QueryID, &a.Name, &a.Description, &a.ConditionColumn,
+		&a.ConditionOperator, &a.ConditionValue, &aggregation, &a.LogicOperator, &a.Severity, &a.CheckIntervalMinutes,
+		&a.CooldownMinutes, &a.IsActive, &a.LastCheckedAt, &a.LastTriggeredAt, &a.NextCheckAt,
+		&a.SnoozedUntil, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if aggregation != nil {
+		agg := models.Aggregation(*aggregation)
+		a.Aggregation = &agg
+	}
+	return &a, nil
+}
+
 // GetAlerts returns all alerts for a user
 func (s *AlertService) GetAlerts(ctx context.Context, userID uuid.UUID) ([]models.QueryAlert, error) {
 	query := `
-		SELECT id, user_id, query_id, name, description, condition_column, condition_operator,
-		       condition_value, aggregation, check_interval_minutes, cooldown_minutes, is_active,
-		       last_checked_at, last_triggered_at, next_check_at, created_at, updated_at
+		SELECT ` + alertColumns + `
 		FROM query_alerts
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -46,30 +94,34 @@ func (s *AlertService) GetAlerts(ctx context.Context, userID uuid.UUID) ([]model
 	if err != nil {
 		return nil, fmt.Errorf("failed to query alerts: %w", err)
 	}
-	defer rows.Close()
 
 	var alerts []models.QueryAlert
 	for rows.Next() {
-		var a models.QueryAlert
-		var aggregation *string
-		if err := rows.Scan(&a.ID, &a.UserID, &a.QueryID, &a.Name, &a.Description, &a.ConditionColumn,
-			&a.ConditionOperator, &a.ConditionValue, &aggregation, &a.CheckIntervalMinutes, &a.CooldownMinutes,
-			&a.IsActive, &a.LastCheckedAt, &a.LastTriggeredAt, &a.NextCheckAt, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		a, err := scanAlert(rows)
+		if err != nil {
+			rows.Close()
 			return nil, fmt.Errorf("failed to scan alert: %w", err)
 		}
-		if aggregation != nil {
-			agg := models.Aggregation(*aggregation)
-			a.Aggregation = &agg
-		}
+		alerts = append(alerts, *a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query alerts: %w", err)
+	}
 
+	for i := range alerts {
 		// Get channel IDs
-		channelIDs, err := s.getAlertChannelIDs(ctx, a.ID)
+		channelIDs, err := s.getAlertChannelIDs(ctx, alerts[i].ID)
 		if err != nil {
 			return nil, err
 		}
-		a.ChannelIDs = channelIDs
+		alerts[i].ChannelIDs = channelIDs
 
-		alerts = append(alerts, a)
+		conditions, err := s.getAlertConditions(ctx, alerts[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		alerts[i].Conditions = conditions
 	}
 
 	return alerts, nil
@@ -78,28 +130,16 @@ func (s *AlertService) GetAlerts(ctx context.Context, userID uuid.UUID) ([]model
 // GetAlertByID returns an alert by ID
 func (s *AlertService) GetAlertByID(ctx context.Context, id uuid.UUID) (*models.QueryAlert, error) {
 	query := `
-		SELECT id, user_id, query_id, name, description, condition_column, condition_operator,
-		       condition_value, aggregation, check_interval_minutes, cooldown_minutes, is_active,
-		       last_checked_at, last_triggered_at, next_check_at, created_at, updated_at
+		SELECT ` + alertColumns + `
 		FROM query_alerts
 		WHERE id = $1
 	`
 
-	var a models.QueryAlert
-	var aggregation *string
-	err := s.pool.QueryRow(ctx, query, id).Scan(&a.ID, &a.UserID, &a.QueryID, &a.Name, &a.Description,
-		&a.ConditionColumn, &a.ConditionOperator, &a.ConditionValue, &aggregation,
-		&a.CheckIntervalMinutes, &a.CooldownMinutes, &a.IsActive, &a.LastCheckedAt,
-		&a.LastTriggeredAt, &a.NextCheckAt, &a.CreatedAt, &a.UpdatedAt)
+	a, err := scanAlert(s.pool.QueryRow(ctx, query, id))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get alert: %w", err)
 	}
 
-	if aggregation != nil {
-		agg := models.Aggregation(*aggregation)
-		a.Aggregation = &agg
-	}
-
 	// Get channel IDs
 	channelIDs, err := s.getAlertChannelIDs(ctx, a.ID)
 	if err != nil {
@@ -107,7 +147,60 @@ func (s *AlertService) GetAlertByID(ctx context.Context, id uuid.UUID) (*models.
 	}
 	a.ChannelIDs = channelIDs
 
-	return &a, nil
+	conditions, err := s.getAlertConditions(ctx, a.ID)
+	if err != nil {
+		return nil, err
+	}
+	a.Conditions = conditions
+
+	return a, nil
+}
+
+// normalizeAlertConditions validates the logic operator and conditions, and mirrors the
+// first condition into the legacy single-condition fields so they stay populated.
+func normalizeAlertConditions(alert *models.QueryAlert) error {
+	if alert.LogicOperator == "" {
+		alert.LogicOperator = models.LogicAnd
+	}
+	if alert.LogicOperator != models.LogicAnd && alert.LogicOperator != models.LogicOr {
+		return fmt.Errorf("invalid logic_operator: %s (must be 'and' or 'or')", alert.LogicOperator)
+	}
+
+	if len(alert.Conditions) > 0 {
+		first := alert.Conditions[0]
+		alert.ConditionColumn = first.Column
+		alert.ConditionOperator = first.Operator
+		alert.ConditionValue = first.Value
+		alert.Aggregation = first.Aggregation
+	}
+
+	if alert.ConditionColumn == "" || alert.ConditionOperator == "" || alert.ConditionValue == "" {
+		return fmt.Errorf("either condition_column/condition_operator/condition_value or conditions is required")
+	}
+	return nil
+}
+
+// checkAlertConditionFields rejects a request that sets both the legacy single-condition fields
+// and a conditions list, since only one of them would take effect
+func checkAlertConditionFields(column string, operator models.ConditionOperator, value string, aggregation *models.Aggregation, conditions []models.AlertCondition) error {
+	if len(conditions) > 0 && hasLegacyCondition(column, operator, value, aggregation) {
+		return fmt.Errorf("condition_column/condition_operator/condition_value/aggregation cannot be combined with conditions")
+	}
+	return nil
+}
+
+// hasLegacyCondition reports whether any of the legacy single-condition fields is set
+func hasLegacyCondition(column string, operator models.ConditionOperator, value string, aggregation *models.Aggregation) bool {
+	return column != "" || operator != "" || value != "" || aggregation != nil
+}
+
+// validateAlertSeverity rejects anything but the known severities
+func validateAlertSeverity(severity models.AlertSeverity) error {
+	switch severity {
+	case models.SeverityInfo, models.SeverityWarning, models.SeverityCritical:
+		return nil
+	}
+	return fmt.Errorf("invalid severity: %s (must be 'info', 'warning' or 'critical')", severity)
 }
 
 // CreateAlert creates a new alert
@@ -121,50 +214,102 @@ func (s *AlertService) CreateAlert(ctx context.Context, userID uuid.UUID, req *m
 	if cooldown <= 0 {
 		cooldown = 60
 	}
+	severity := req.Severity
+	if severity == "" {
+		severity = models.SeverityWarning
+	}
+	if err := validateAlertSeverity(severity); err != nil {
+		return nil, err
+	}
+	if err := checkAlertConditionFields(req.ConditionColumn, req.ConditionOperator, req.ConditionValue, req.Aggregation, req.Conditions); err != nil {
+		return nil, err
+	}
+
+	conds := models.QueryAlert{
+		ConditionColumn:   req.ConditionColumn,
+		ConditionOperator: req.ConditionOperator,
+		ConditionValue:    req.ConditionValue,
+		Aggregation:       req.Aggregation,
+		LogicOperator:     req.LogicOperator,
+		Conditions:        req.Conditions,
+	}
+	if err := normalizeAlertConditions(&conds); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkAlertQuery(ctx, userID, req.QueryID); err != nil {
+		return nil, err
+	}
+
+	// New alerts start active
+	if err := s.checkActiveLimit(ctx, userID); err != nil {
+		return nil, err
+	}
 
 	nextCheckAt := time.Now().Add(time.Duration(checkInterval) * time.Minute)
 
 	var aggregation *string
-	if req.Aggregation != nil {
-		agg := string(*req.Aggregation)
+	if conds.Aggregation != nil {
+		agg := string(*conds.Aggregation)
 		aggregation = &agg
 	}
 
 	query := `
 		INSERT INTO query_alerts (user_id, query_id, name, description, condition_column, condition_operator,
-		                          condition_value, aggregation, check_interval_minutes, cooldown_minutes, next_check_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		RETURNING id, user_id, query_id, name, description, condition_column, condition_operator,
-		          condition_value, aggregation, check_interval_minutes, cooldown_minutes, is_active,
-		          last_checked_at, last_triggered_at, next_check_at, created_at, updated_at
-	`
-
-	var a models.QueryAlert
-	var returnedAggregation *string
-	err := s.pool.QueryRow(ctx, query, userID, req.QueryID, req.Name, req.Description, req.ConditionColumn,
-		req.ConditionOperator, req.ConditionValue, aggregation, checkInterval, cooldown, nextCheckAt).Scan(
-		&a.ID, &a.UserID, &a.QueryID, &a.Name, &a.Description, &a.ConditionColumn, &a.ConditionOperator,
-		&a.ConditionValue, &returnedAggregation, &a.CheckIntervalMinutes, &a.CooldownMinutes, &a.IsActive,
-		&a.LastCheckedAt, &a.LastTriggeredAt, &a.NextCheckAt, &a.CreatedAt, &a.UpdatedAt)
+		                          condition_value, aggregation, logic_operator, severity, check_interval_minutes,
+		                          cooldown_minutes, next_check_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING ` + alertColumns
+
+	// The alert, its conditions and its channels are written together, so a failure leaves no
+	// alert that would be checked without its conditions or notify nobody
+	var a *models.QueryAlert
+	err := database.WithTxOn(ctx, s.pool, func(tx pgx.Tx) error {
+		var err error
+		a, err = scanAlert(tx.QueryRow(ctx, query, userID, req.QueryID, req.Name, req.Description, conds.ConditionColumn,
+			conds.ConditionOperator, conds.ConditionValue, aggregation, conds.LogicOperator, severity, checkInterval, cooldown, nextCheckAt))
+		if err != nil {
+			return fmt.Errorf("failed to create alert: %w", err)
+		}
+		if err := setAlertConditions(ctx, tx, a.ID, conds.Conditions); err != nil {
+			return err
+		}
+		return setAlertChannels(ctx, tx, a.ID, req.ChannelIDs)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create alert: %w", err)
-	}
-
-	if returnedAggregation != nil {
-		agg := models.Aggregation(*returnedAggregation)
-		a.Aggregation = &agg
-	}
-
-	// Add channel associations
-	if err := s.setAlertChannels(ctx, a.ID, req.ChannelIDs); err != nil {
 		return nil, err
 	}
+	a.Conditions = conds.Conditions
 	a.ChannelIDs = req.ChannelIDs
 
-	return &a, nil
+	return a, nil
 }
 
-// UpdateAlert updates an alert
+// checkAlertQuery checks the saved query an alert of userID runs: against read-only mode, as it
+// may have been saved before read-only mode was enabled, and against the user's row filters,
+// which must apply to it for the alert to ever run
+func (s *AlertService) checkAlertQuery(ctx context.Context, userID, queryID uuid.UUID) error {
+	readOnly := s.queryService != nil && s.queryService.readOnly
+	if !readOnly && s.rowFilters == nil {
+		return nil
+	}
+	savedQuery, err := s.queryService.GetSavedQueryByID(ctx, queryID)
+	if err != nil {
+		return fmt.Errorf("failed to load query: %w", err)
+	}
+	if readOnly {
+		if err := s.queryService.CheckStatement(savedQuery.QueryText); err != nil {
+			return err
+		}
+	}
+	_, err = s.filterAlertQuery(ctx, userID, savedQuery)
+	return err
+}
+
+// UpdateAlert updates an alert. The legacy condition_* fields and conditions cannot be combined.
+// Setting condition_* fields replaces the alert's conditions with the single condition they
+// describe, and is rejected for an alert with several conditions, which must be updated through
+// conditions.
 func (s *AlertService) UpdateAlert(ctx context.Context, id uuid.UUID, userID uuid.UUID, req *models.UpdateAlertRequest) (*models.QueryAlert, error) {
 	existing, err := s.GetAlertByID(ctx, id)
 	if err != nil {
@@ -175,6 +320,20 @@ func (s *AlertService) UpdateAlert(ctx context.Context, id uuid.UUID, userID uui
 		return nil, fmt.Errorf("not authorized to update this alert")
 	}
 
+	if err := checkAlertConditionFields(req.ConditionColumn, req.ConditionOperator, req.ConditionValue, req.Aggregation, req.Conditions); err != nil {
+		return nil, err
+	}
+	// Conditions are replaced when given, or dropped in favor of the legacy fields; the legacy
+	// fields already hold the first condition, so a partial update edits that condition
+	replaceConditions := req.Conditions != nil
+	if hasLegacyCondition(req.ConditionColumn, req.ConditionOperator, req.ConditionValue, req.Aggregation) {
+		if len(existing.Conditions) > 1 {
+			return nil, fmt.Errorf("alert has %d conditions; update them with conditions instead of condition_column/condition_operator/condition_value/aggregation", len(existing.Conditions))
+		}
+		existing.Conditions = nil
+		replaceConditions = true
+	}
+
 	// Apply updates
 	if req.Name != "" {
 		existing.Name = req.Name
@@ -194,6 +353,18 @@ func (s *AlertService) UpdateAlert(ctx context.Context, id uuid.UUID, userID uui
 	if req.Aggregation != nil {
 		existing.Aggregation = req.Aggregation
 	}
+	if req.LogicOperator != "" {
+		existing.LogicOperator = req.LogicOperator
+	}
+	if req.Conditions != nil {
+		existing.Conditions = req.Conditions
+	}
+	if req.Severity != "" {
+		if err := validateAlertSeverity(req.Severity); err != nil {
+			return nil, err
+		}
+		existing.Severity = req.Severity
+	}
 	if req.CheckIntervalMinutes > 0 {
 		existing.CheckIntervalMinutes = req.CheckIntervalMinutes
 	}
@@ -201,9 +372,21 @@ func (s *AlertService) UpdateAlert(ctx context.Context, id uuid.UUID, userID uui
 		existing.CooldownMinutes = req.CooldownMinutes
 	}
 	if req.IsActive != nil {
+		if *req.IsActive && !existing.IsActive {
+			if err := s.checkActiveLimit(ctx, userID); err != nil {
+				return nil, err
+			}
+		}
 		existing.IsActive = *req.IsActive
 	}
 
+	if err := normalizeAlertConditions(existing); err != nil {
+		return nil, err
+	}
+	if err := s.checkAlertQuery(ctx, existing.UserID, existing.QueryID); err != nil {
+		return nil, err
+	}
+
 	var aggregation *string
 	if existing.Aggregation != nil {
 		agg := string(*existing.Aggregation)
@@ -213,43 +396,44 @@ func (s *AlertService) UpdateAlert(ctx context.Context, id uuid.UUID, userID uui
 	query := `
 		UPDATE query_alerts
 		SET name = $1, description = $2, condition_column = $3, condition_operator = $4,
-		    condition_value = $5, aggregation = $6, check_interval_minutes = $7, cooldown_minutes = $8,
-		    is_active = $9, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $10
-		RETURNING id, user_id, query_id, name, description, condition_column, condition_operator,
-		          condition_value, aggregation, check_interval_minutes, cooldown_minutes, is_active,
-		          last_checked_at, last_triggered_at, next_check_at, created_at, updated_at
-	`
-
-	var a models.QueryAlert
-	var returnedAggregation *string
-	err = s.pool.QueryRow(ctx, query, existing.Name, existing.Description, existing.ConditionColumn,
-		existing.ConditionOperator, existing.ConditionValue, aggregation, existing.CheckIntervalMinutes,
-		existing.CooldownMinutes, existing.IsActive, id).Scan(
-		&a.ID, &a.UserID, &a.QueryID, &a.Name, &a.Description, &a.ConditionColumn, &a.ConditionOperator,
-		&a.ConditionValue, &returnedAggregation, &a.CheckIntervalMinutes, &a.CooldownMinutes, &a.IsActive,
-		&a.LastCheckedAt, &a.LastTriggeredAt, &a.NextCheckAt, &a.CreatedAt, &a.UpdatedAt)
+		    condition_value = $5, aggregation = $6, logic_operator = $7, check_interval_minutes = $8,
+		    cooldown_minutes = $9, is_active = $10, severity = $11, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $12
+		RETURNING ` + alertColumns
+
+	var a *models.QueryAlert
+	err = database.WithTxOn(ctx, s.pool, func(tx pgx.Tx) error {
+		var err error
+		a, err = scanAlert(tx.QueryRow(ctx, query, existing.Name, existing.Description, existing.ConditionColumn,
+			existing.ConditionOperator, existing.ConditionValue, aggregation, existing.LogicOperator,
+			existing.CheckIntervalMinutes, existing.CooldownMinutes, existing.IsActive, existing.Severity, id))
+		if err != nil {
+			return fmt.Errorf("failed to update alert: %w", err)
+		}
+		if replaceConditions {
+			if err := setAlertConditions(ctx, tx, a.ID, existing.Conditions); err != nil {
+				return err
+			}
+		}
+		// Channel associations are only replaced when provided
+		if len(req.ChannelIDs) > 0 {
+			return setAlertChannels(ctx, tx, a.ID, req.ChannelIDs)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to update alert: %w", err)
-	}
-
-	if returnedAggregation != nil {
-		agg := models.Aggregation(*returnedAggregation)
-		a.Aggregation = &agg
+		return nil, err
 	}
+	a.Conditions = existing.Conditions
 
-	// Update channel associations if provided
 	if len(req.ChannelIDs) > 0 {
-		if err := s.setAlertChannels(ctx, a.ID, req.ChannelIDs); err != nil {
-			return nil, err
-		}
 		a.ChannelIDs = req.ChannelIDs
 	} else {
 		channelIDs, _ := s.getAlertChannelIDs(ctx, a.ID)
 		a.ChannelIDs = channelIDs
 	}
 
-	return &a, nil
+	return a, nil
 }
 
 // DeleteAlert deletes an alert
@@ -268,6 +452,67 @@ func (s *AlertService) DeleteAlert(ctx context.Context, id uuid.UUID, userID uui
 	return nil
 }
 
+// SnoozeAlert suppresses notifications for an alert until the given time.
+// The alert keeps being checked (and its history recorded) while snoozed.
+func (s *AlertService) SnoozeAlert(ctx context.Context, id uuid.UUID, userID uuid.UUID, until time.Time) (*models.QueryAlert, error) {
+	return s.setSnoozedUntil(ctx, id, userID, &until)
+}
+
+// UnsnoozeAlert clears an alert's snooze so notifications resume immediately
+func (s *AlertService) UnsnoozeAlert(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*models.QueryAlert, error) {
+	return s.setSnoozedUntil(ctx, id, userID, nil)
+}
+
+func (s *AlertService) setSnoozedUntil(ctx context.Context, id uuid.UUID, userID uuid.UUID, until *time.Time) (*models.QueryAlert, error) {
+	query := `
+		UPDATE query_alerts SET snoozed_until = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2
+	`
+
+	result, err := s.pool.Exec(ctx, query, id, userID, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snooze alert: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return nil, fmt.Errorf("alert not found or not authorized")
+	}
+
+	return s.GetAlertByID(ctx, id)
+}
+
+// AcknowledgeAlert marks the most recent history entry of an alert as acknowledged by the user
+func (s *AlertService) AcknowledgeAlert(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*models.AlertHistory, error) {
+	query := `
+		UPDATE alert_history SET acknowledged_by = $2, acknowledged_at = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT h.id FROM alert_history h
+			JOIN query_alerts a ON a.id = h.alert_id
+			WHERE h.alert_id = $1 AND a.user_id = $2
+			ORDER BY h.triggered_at DESC
+			LIMIT 1
+		)
+		RETURNING id, alert_id, triggered_at, condition_met_value, notification_status, notification_details, error_message,
+		          acknowledged_by, acknowledged_at
+	`
+
+	var h models.AlertHistory
+	err := s.pool.QueryRow(ctx, query, id, userID).Scan(&h.ID, &h.AlertID, &h.TriggeredAt, &h.ConditionMetValue,
+		&h.NotificationStatus, &h.NotificationDetails, &h.ErrorMessage, &h.AcknowledgedBy, &h.AcknowledgedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to acknowledge alert: %w", err)
+	}
+
+	return &h, nil
+}
+
+// isAlertSnoozed reports whether notifications for the alert are currently suppressed
+func isAlertSnoozed(alert *models.QueryAlert, now time.Time) bool {
+	return alert.SnoozedUntil != nil && now.Before(*alert.SnoozedUntil)
+}
+
 // TestAlert runs the alert query and checks the condition
 func (s *AlertService) TestAlert(ctx context.Context, id uuid.UUID, userID uuid.UUID) (bool, string, error) {
 	alert, err := s.GetAlertByID(ctx, id)
@@ -282,7 +527,65 @@ func (s *AlertService) TestAlert(ctx context.Context, id uuid.UUID, userID uuid.
 	return s.EvaluateAlert(ctx, alert)
 }
 
-// EvaluateAlert runs the query and checks the condition
+// PreviewAlert evaluates an alert definition that has not been saved, so an alert can be tried
+// out while it is edited. Nothing is persisted and no notification is sent. The user must own
+// the saved query the alert runs (ErrPermissionDenied otherwise).
+func (s *AlertService) PreviewAlert(ctx context.Context, userID uuid.UUID, req *models.PreviewAlertRequest) (*models.AlertPreviewResponse, error) {
+	alert := &models.QueryAlert{
+		UserID:            userID,
+		QueryID:           req.QueryID,
+		ConditionColumn:   req.ConditionColumn,
+		ConditionOperator: req.ConditionOperator,
+		ConditionValue:    req.ConditionValue,
+		Aggregation:       req.Aggregation,
+		LogicOperator:     req.LogicOperator,
+		Conditions:        req.Conditions,
+	}
+	if err := checkAlertConditionFields(req.ConditionColumn, req.ConditionOperator, req.ConditionValue, req.Aggregation, req.Conditions); err != nil {
+		return nil, err
+	}
+	if err := normalizeAlertConditions(alert); err != nil {
+		return nil, err
+	}
+
+	savedQuery, err := s.queryService.GetSavedQueryByID(ctx, req.QueryID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get saved query: %w", err)
+	}
+	if savedQuery.UserID != userID {
+		return nil, ErrPermissionDenied
+	}
+	if s.queryService.readOnly {
+		if err := s.queryService.CheckStatement(savedQuery.QueryText); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := s.executeAlertQuery(ctx, alert, savedQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &models.AlertPreviewResponse{
+		RowCount:      len(result.Rows),
+		LogicOperator: alert.LogicOperator,
+		Conditions:    effectiveAlertConditions(alert),
+	}
+	if len(result.Rows) == 0 {
+		return resp, nil // No data, no alert
+	}
+	resp.Triggered, resp.ActualValue, err = s.evaluateConditions(result, resp.Conditions, alert.LogicOperator)
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// EvaluateAlert runs the query and checks every condition, combining them
+// according to the alert's logic operator
 func (s *AlertService) EvaluateAlert(ctx context.Context, alert *models.QueryAlert) (bool, string, error) {
 	// Get the saved query
 	savedQuery, err := s.queryService.GetSavedQueryByID(ctx, alert.QueryID)
@@ -290,86 +593,199 @@ func (s *AlertService) EvaluateAlert(ctx context.Context, alert *models.QueryAle
 		return false, "", fmt.Errorf("failed to get saved query: %w", err)
 	}
 
-	// Get catalog and schema from saved query
-	catalog := ""
-	schema := ""
+	result, err := s.executeAlertQuery(ctx, alert, savedQuery)
+	if err != nil {
+		return false, "", err
+	}
+
+	if len(result.Rows) == 0 {
+		return false, "", nil // No data, no alert
+	}
+
+	return s.evaluateConditions(result, effectiveAlertConditions(alert), alert.LogicOperator)
+}
+
+// savedQueryLocation returns the catalog and schema a saved query runs in ("" for the defaults)
+func savedQueryLocation(savedQuery *models.SavedQuery) (catalog, schema string) {
 	if savedQuery.Catalog != nil {
 		catalog = *savedQuery.Catalog
 	}
 	if savedQuery.SchemaName != nil {
 		schema = *savedQuery.SchemaName
 	}
+	return catalog, schema
+}
+
+// filterAlertQuery returns the saved query's text with the row filters of userID's roles applied
+func (s *AlertService) filterAlertQuery(ctx context.Context, userID uuid.UUID, savedQuery *models.SavedQuery) (string, error) {
+	if s.rowFilters == nil {
+		return savedQuery.QueryText, nil
+	}
+	filters, err := s.rowFilters.GetUserRowFilters(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to load row filters: %w", err)
+	}
+	catalog, schema := savedQueryLocation(savedQuery)
+	return ApplyRowFilters(savedQuery.QueryText, catalog, schema, filters)
+}
+
+// executeAlertQuery runs the alert's saved query with the saved query's catalog and schema and
+// the alert owner's row filters, recording it in the owner's query history. Unsaved alerts
+// (previews) have no ID and are recorded without a source.
+func (s *AlertService) executeAlertQuery(ctx context.Context, alert *models.QueryAlert, savedQuery *models.SavedQuery) (*models.QueryResult, error) {
+	catalog, schema := savedQueryLocation(savedQuery)
+	query, err := s.filterAlertQuery(ctx, alert.UserID, savedQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	var sourceID *uuid.UUID
+	if alert.ID != uuid.Nil {
+		sourceID = &alert.ID
+	}
 
 	// Execute the query with caching (HIGH priority for scheduled alerts)
-	result, err := s.trinoService.ExecuteQueryWithCache(ctx, savedQuery.QueryText, catalog, schema, int(CachePriorityHigh), &alert.QueryID)
+	result, err := s.trinoService.ExecuteQueryWithCache(ctx, query, catalog, schema, int(CachePriorityHigh), &alert.QueryID)
+	RecordQueryExecution(ctx, s.queryService, alert.UserID, savedQuery.QueryText, models.QueryHistorySourceAlert, sourceID, result, err)
 	if err != nil {
-		return false, "", fmt.Errorf("failed to execute query: %w", err)
+		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
+	return result, nil
+}
 
-	if len(result.Rows) == 0 {
-		return false, "", nil // No data, no alert
+// effectiveAlertConditions returns the alert's conditions, treating the legacy
+// single-condition fields as one implicit condition when no condition rows exist
+func effectiveAlertConditions(alert *models.QueryAlert) []models.AlertCondition {
+	if len(alert.Conditions) > 0 {
+		return alert.Conditions
 	}
+	return []models.AlertCondition{{
+		Column:      alert.ConditionColumn,
+		Operator:    alert.ConditionOperator,
+		Value:       alert.ConditionValue,
+		Aggregation: alert.Aggregation,
+	}}
+}
 
-	// Find column index
-	colIdx := -1
-	for i, col := range result.Columns {
-		if col == alert.ConditionColumn {
-			colIdx = i
+// evaluateConditions checks each condition against the result set and combines them
+// with logic (AND by default). Evaluation short-circuits: AND stops at the first false
+// condition, OR at the first true one. The returned value string is the checked value for
+// a single condition, or "column=value" pairs for multiple conditions.
+func (s *AlertService) evaluateConditions(result *models.QueryResult, conditions []models.AlertCondition, logic models.LogicOperator) (bool, string, error) {
+	isOr := logic == models.LogicOr
+	triggered := !isOr
+	values := make([]string, 0, len(conditions))
+
+	for _, cond := range conditions {
+		// Find column index
+		colIdx := -1
+		for i, col := range result.Columns {
+			if col == cond.Column {
+				colIdx = i
+				break
+			}
+		}
+		if colIdx == -1 {
+			return false, "", fmt.Errorf("column %s not found in query results", cond.Column)
+		}
+
+		// Get value to check (with optional aggregation)
+		value, err := s.aggregateValue(result.Rows, colIdx, cond.Aggregation)
+		if err != nil {
+			return false, "", fmt.Errorf("failed to aggregate value: %w", err)
+		}
+		values = append(values, fmt.Sprintf("%v", value))
+
+		met := s.checkCondition(value, cond.Operator, cond.Value)
+		if isOr && met {
+			triggered = true
+			break
+		}
+		if !isOr && !met {
+			triggered = false
 			break
 		}
-	}
-	if colIdx == -1 {
-		return false, "", fmt.Errorf("column %s not found in query results", alert.ConditionColumn)
 	}
 
-	// Get value to check (with optional aggregation)
-	value, err := s.aggregateValue(result.Rows, colIdx, alert.Aggregation)
-	if err != nil {
-		return false, "", fmt.Errorf("failed to aggregate value: %w", err)
+	if len(conditions) == 1 {
+		return triggered, values[0], nil
 	}
-
-	// Check condition
-	triggered := s.checkCondition(value, alert.ConditionOperator, alert.ConditionValue)
-	return triggered, fmt.Sprintf("%v", value), nil
+	pairs := make([]string, len(values))
+	for i, v := range values {
+		pairs[i] = conditions[i].Column + "=" + v
+	}
+	return triggered, strings.Join(pairs, ", "), nil
 }
 
-// GetDueAlerts returns alerts that are due for checking
+// GetDueAlerts claims and returns up to 100 alerts that are due for checking.
+// Rows are selected with FOR UPDATE SKIP LOCKED and their next_check_at is pushed
+// forward by dueClaimLease in the same transaction, so concurrent callers never
+// receive the same alert. The scheduler sets the real next check time afterwards.
 func (s *AlertService) GetDueAlerts(ctx context.Context) ([]models.QueryAlert, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
-		SELECT id, user_id, query_id, name, description, condition_column, condition_operator,
-		       condition_value, aggregation, check_interval_minutes, cooldown_minutes, is_active,
-		       last_checked_at, last_triggered_at, next_check_at, created_at, updated_at
+		SELECT ` + alertColumns + `
 		FROM query_alerts
 		WHERE is_active = TRUE AND (next_check_at IS NULL OR next_check_at <= CURRENT_TIMESTAMP)
 		ORDER BY next_check_at ASC
 		LIMIT 100
+		FOR UPDATE SKIP LOCKED
 	`
 
-	rows, err := s.pool.Query(ctx, query)
+	rows, err := tx.Query(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query due alerts: %w", err)
 	}
-	defer rows.Close()
 
 	var alerts []models.QueryAlert
 	for rows.Next() {
-		var a models.QueryAlert
-		var aggregation *string
-		if err := rows.Scan(&a.ID, &a.UserID, &a.QueryID, &a.Name, &a.Description, &a.ConditionColumn,
-			&a.ConditionOperator, &a.ConditionValue, &aggregation, &a.CheckIntervalMinutes, &a.CooldownMinutes,
-			&a.IsActive, &a.LastCheckedAt, &a.LastTriggeredAt, &a.NextCheckAt, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		a, err := scanAlert(rows)
+		if err != nil {
+			rows.Close()
 			return nil, fmt.Errorf("failed to scan alert: %w", err)
 		}
-		if aggregation != nil {
-			agg := models.Aggregation(*aggregation)
-			a.Aggregation = &agg
-		}
+		alerts = append(alerts, *a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query due alerts: %w", err)
+	}
+
+	if len(alerts) == 0 {
+		return nil, nil
+	}
 
+	ids := make([]uuid.UUID, len(alerts))
+	for i := range alerts {
+		ids[i] = alerts[i].ID
+	}
+	_, err = tx.Exec(ctx,
+		`UPDATE query_alerts SET next_check_at = CURRENT_TIMESTAMP + $2 * INTERVAL '1 second' WHERE id = ANY($1)`,
+		ids, dueClaimLease.Seconds(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due alerts: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to claim due alerts: %w", err)
+	}
+
+	for i := range alerts {
 		// Get channel IDs
-		channelIDs, _ := s.getAlertChannelIDs(ctx, a.ID)
-		a.ChannelIDs = channelIDs
+		channelIDs, _ := s.getAlertChannelIDs(ctx, alerts[i].ID)
+		alerts[i].ChannelIDs = channelIDs
 
-		alerts = append(alerts, a)
+		conditions, err := s.getAlertConditions(ctx, alerts[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		alerts[i].Conditions = conditions
 	}
 
 	return alerts, nil
@@ -416,7 +832,8 @@ func (s *AlertService) GetAlertHistory(ctx context.Context, alertID uuid.UUID, l
 	}
 
 	query := `
-		SELECT id, alert_id, triggered_at, condition_met_value, notification_status, notification_details, error_message
+		SELECT id, alert_id, triggered_at, condition_met_value, notification_status, notification_details, error_message,
+		       acknowledged_by, acknowledged_at
 		FROM alert_history
 		WHERE alert_id = $1
 		ORDER BY triggered_at DESC
@@ -433,7 +850,7 @@ func (s *AlertService) GetAlertHistory(ctx context.Context, alertID uuid.UUID, l
 	for rows.Next() {
 		var h models.AlertHistory
 		if err := rows.Scan(&h.ID, &h.AlertID, &h.TriggeredAt, &h.ConditionMetValue,
-			&h.NotificationStatus, &h.NotificationDetails, &h.ErrorMessage); err != nil {
+			&h.NotificationStatus, &h.NotificationDetails, &h.ErrorMessage, &h.AcknowledgedBy, &h.AcknowledgedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan alert history: %w", err)
 		}
 		history = append(history, h)
@@ -488,16 +905,16 @@ func (s *AlertService) getAlertChannelIDs(ctx context.Context, alertID uuid.UUID
 	return channelIDs, nil
 }
 
-func (s *AlertService) setAlertChannels(ctx context.Context, alertID uuid.UUID, channelIDs []uuid.UUID) error {
+func setAlertChannels(ctx context.Context, tx pgx.Tx, alertID uuid.UUID, channelIDs []uuid.UUID) error {
 	// Delete existing associations
-	_, err := s.pool.Exec(ctx, "DELETE FROM alert_channels WHERE alert_id = $1", alertID)
+	_, err := tx.Exec(ctx, "DELETE FROM alert_channels WHERE alert_id = $1", alertID)
 	if err != nil {
 		return fmt.Errorf("failed to clear alert channels: %w", err)
 	}
 
 	// Insert new associations
 	for _, channelID := range channelIDs {
-		_, err := s.pool.Exec(ctx, "INSERT INTO alert_channels (alert_id, channel_id) VALUES ($1, $2)", alertID, channelID)
+		_, err := tx.Exec(ctx, "INSERT INTO alert_channels (alert_id, channel_id) VALUES ($1, $2)", alertID, channelID)
 		if err != nil {
 			return fmt.Errorf("failed to add alert channel: %w", err)
 		}
@@ -506,6 +923,61 @@ func (s *AlertService) setAlertChannels(ctx context.Context, alertID uuid.UUID,
 	return nil
 }
 
+func (s *AlertService) getAlertConditions(ctx context.Context, alertID uuid.UUID) ([]models.AlertCondition, error) {
+	query := `
+		SELECT column_name, operator, value, aggregation
+		FROM alert_conditions
+		WHERE alert_id = $1
+		ORDER BY position ASC
+	`
+	rows, err := s.pool.Query(ctx, query, alertID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert conditions: %w", err)
+	}
+	defer rows.Close()
+
+	var conditions []models.AlertCondition
+	for rows.Next() {
+		var cond models.AlertCondition
+		var aggregation *string
+		if err := rows.Scan(&cond.Column, &cond.Operator, &cond.Value, &aggregation); err != nil {
+			return nil, err
+		}
+		if aggregation != nil {
+			agg := models.Aggregation(*aggregation)
+			cond.Aggregation = &agg
+		}
+		conditions = append(conditions, cond)
+	}
+
+	return conditions, nil
+}
+
+func setAlertConditions(ctx context.Context, tx pgx.Tx, alertID uuid.UUID, conditions []models.AlertCondition) error {
+	// Delete existing conditions
+	_, err := tx.Exec(ctx, "DELETE FROM alert_conditions WHERE alert_id = $1", alertID)
+	if err != nil {
+		return fmt.Errorf("failed to clear alert conditions: %w", err)
+	}
+
+	// Insert new conditions in order
+	for i, cond := range conditions {
+		var aggregation *string
+		if cond.Aggregation != nil {
+			agg := string(*cond.Aggregation)
+			aggregation = &agg
+		}
+		_, err := tx.Exec(ctx,
+			"INSERT INTO alert_conditions (alert_id, position, column_name, operator, value, aggregation) VALUES ($1, $2, $3, $4, $5, $6)",
+			alertID, i, cond.Column, cond.Operator, cond.Value, aggregation)
+		if err != nil {
+			return fmt.Errorf("failed to add alert condition: %w", err)
+		}
+	}
+
+	return nil
+}
+
 func (s *AlertService) aggregateValue(rows [][]interface{}, colIdx int, agg *models.Aggregation) (interface{}, error) {
 	if agg == nil || *agg == models.AggregationFirst {
 		return rows[0][colIdx], nil
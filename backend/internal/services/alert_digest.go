@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mitsume/backend/internal/models"
+)
+
+// Defaults for users who have never saved digest settings
+const (
+	defaultDigestSendTime = "09:00"
+	defaultDigestTimezone = "Asia/Tokyo"
+)
+
+const digestSettingsColumns = `user_id, enabled, send_time, timezone, last_sent_at, next_send_at`
+
+func scanDigestSettings(row pgx.Row) (*models.AlertDigestSettings, error) {
+	var d models.AlertDigestSettings
+	if err := row.Scan(&d.UserID, &d.Enabled, &d.SendTime, &d.Timezone, &d.LastSentAt, &d.NextSendAt); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// GetDigestSettings returns the user's digest settings, or the disabled defaults if none are saved
+func (s *AlertService) GetDigestSettings(ctx context.Context, userID uuid.UUID) (*models.AlertDigestSettings, error) {
+	d, err := scanDigestSettings(s.pool.QueryRow(ctx,
+		`SELECT `+digestSettingsColumns+` FROM alert_digest_settings WHERE user_id = $1`, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &models.AlertDigestSettings{
+			UserID:   userID,
+			SendTime: defaultDigestSendTime,
+			Timezone: defaultDigestTimezone,
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get digest settings: %w", err)
+	}
+	return d, nil
+}
+
+// UpdateDigestSettings saves the user's digest settings and schedules the next digest.
+// Disabling keeps the schedule so triggers already queued are still delivered once.
+func (s *AlertService) UpdateDigestSettings(ctx context.Context, userID uuid.UUID, req *models.UpdateAlertDigestSettingsRequest) (*models.AlertDigestSettings, error) {
+	sendTime := req.SendTime
+	if sendTime == "" {
+		sendTime = defaultDigestSendTime
+	}
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = defaultDigestTimezone
+	}
+
+	nextSendAt, err := nextDigestSend(time.Now(), sendTime, timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := scanDigestSettings(s.pool.QueryRow(ctx, `
+		INSERT INTO alert_digest_settings (user_id, enabled, send_time, timezone, next_send_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET enabled = EXCLUDED.enabled, send_time = EXCLUDED.send_time, timezone = EXCLUDED.timezone,
+		    next_send_at = EXCLUDED.next_send_at, updated_at = CURRENT_TIMESTAMP
+		RETURNING `+digestSettingsColumns,
+		userID, req.Enabled, sendTime, timezone, nextSendAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save digest settings: %w", err)
+	}
+	return d, nil
+}
+
+// DigestEnabled reports whether the user's non-critical triggers should be queued for the digest
+func (s *AlertService) DigestEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var enabled bool
+	err := s.pool.QueryRow(ctx,
+		`SELECT enabled FROM alert_digest_settings WHERE user_id = $1`, userID,
+	).Scan(&enabled)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return enabled, err
+}
+
+// QueueDigestEntries queues one trigger of alert for the next digest on each of the channels
+func (s *AlertService) QueueDigestEntries(ctx context.Context, alert *models.QueryAlert, value string, channels []models.NotificationChannel) error {
+	batch := &pgx.Batch{}
+	for _, ch := range channels {
+		batch.Queue(`
+			INSERT INTO alert_digest_queue (user_id, channel_id, alert_id, alert_name, severity, condition_met_value)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			alert.UserID, ch.ID, alert.ID, alert.Name, alert.Severity, value)
+	}
+	if err := s.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to queue digest entries: %w", err)
+	}
+	return nil
+}
+
+// GetDueDigests claims and returns up to 100 digest settings whose send time has passed,
+// pushing next_send_at forward by dueClaimLease in the same way as GetDueAlerts.
+func (s *AlertService) GetDueDigests(ctx context.Context) ([]models.AlertDigestSettings, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT `+digestSettingsColumns+`
+		FROM alert_digest_settings
+		WHERE next_send_at IS NOT NULL AND next_send_at <= CURRENT_TIMESTAMP
+		ORDER BY next_send_at ASC
+		LIMIT 100
+		FOR UPDATE SKIP LOCKED`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query due digests: %w", err)
+	}
+
+	var digests []models.AlertDigestSettings
+	for rows.Next() {
+		d, err := scanDigestSettings(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan digest settings: %w", err)
+		}
+		digests = append(digests, *d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query due digests: %w", err)
+	}
+
+	if len(digests) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uuid.UUID, len(digests))
+	for i := range digests {
+		ids[i] = digests[i].UserID
+	}
+	_, err = tx.Exec(ctx,
+		`UPDATE alert_digest_settings SET next_send_at = CURRENT_TIMESTAMP + $2 * INTERVAL '1 second' WHERE user_id = ANY($1)`,
+		ids, dueClaimLease.Seconds(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due digests: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to claim due digests: %w", err)
+	}
+	return digests, nil
+}
+
+// GetDigestEntries returns the user's queued triggers, oldest first
+func (s *AlertService) GetDigestEntries(ctx context.Context, userID uuid.UUID) ([]models.AlertDigestEntry, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, channel_id, alert_id, alert_name, severity, COALESCE(condition_met_value, ''), triggered_at
+		FROM alert_digest_queue
+		WHERE user_id = $1
+		ORDER BY triggered_at ASC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query digest entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []models.AlertDigestEntry
+	for rows.Next() {
+		var e models.AlertDigestEntry
+		if err := rows.Scan(&e.ID, &e.ChannelID, &e.AlertID, &e.AlertName, &e.Severity,
+			&e.ConditionMetValue, &e.TriggeredAt); err != nil {
+			return nil, fmt.Errorf("failed to scan digest entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// DeleteDigestEntries removes delivered entries from the queue
+func (s *AlertService) DeleteDigestEntries(ctx context.Context, ids []uuid.UUID) error {
+	_, err := s.pool.Exec(ctx, `DELETE FROM alert_digest_queue WHERE id = ANY($1)`, ids)
+	return err
+}
+
+// UpdateDigestAfterSend records the send and schedules the next digest; a disabled
+// digest is not rescheduled
+func (s *AlertService) UpdateDigestAfterSend(ctx context.Context, d *models.AlertDigestSettings) error {
+	var nextSendAt *time.Time
+	if d.Enabled {
+		next, err := nextDigestSend(time.Now(), d.SendTime, d.Timezone)
+		if err != nil {
+			return err
+		}
+		nextSendAt = &next
+	}
+
+	_, err := s.pool.Exec(ctx,
+		`UPDATE alert_digest_settings SET last_sent_at = CURRENT_TIMESTAMP, next_send_at = $2 WHERE user_id = $1`,
+		d.UserID, nextSendAt)
+	return err
+}
+
+// nextDigestSend returns the first occurrence of sendTime (HH:MM) in timezone strictly after after
+func nextDigestSend(after time.Time, sendTime, timezone string) (time.Time, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timezone: %s", timezone)
+	}
+	clock, err := time.Parse("15:04", sendTime)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid send_time: %s (must be HH:MM)", sendTime)
+	}
+
+	local := after.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+	if !next.After(after) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, clock.Hour(), clock.Minute(), 0, 0, loc)
+	}
+	return next, nil
+}
+
+// buildDigestMessage summarizes queued triggers for one channel, with times shown in loc
+func buildDigestMessage(entries []models.AlertDigestEntry, loc *time.Location) models.NotificationMessage {
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		lines[i] = fmt.Sprintf("- [%s] %s at %s: %s",
+			e.Severity, e.AlertName, e.TriggeredAt.In(loc).Format("2006-01-02 15:04"), e.ConditionMetValue)
+	}
+
+	title := "Alert Digest: 1 alert triggered"
+	if len(entries) != 1 {
+		title = fmt.Sprintf("Alert Digest: %d alerts triggered", len(entries))
+	}
+	return models.NotificationMessage{
+		Title: title,
+		Body:  strings.Join(lines, "\n"),
+	}
+}
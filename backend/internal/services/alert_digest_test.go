@@ -0,0 +1,77 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mitsume/backend/internal/models"
+)
+
+func TestNextDigestSend(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		after time.Time
+		want  time.Time
+	}{
+		{"later today", time.Date(2024, 3, 1, 8, 0, 0, 0, tokyo), time.Date(2024, 3, 1, 9, 0, 0, 0, tokyo)},
+		{"exactly at send time", time.Date(2024, 3, 1, 9, 0, 0, 0, tokyo), time.Date(2024, 3, 2, 9, 0, 0, 0, tokyo)},
+		{"already passed", time.Date(2024, 3, 1, 10, 0, 0, 0, tokyo), time.Date(2024, 3, 2, 9, 0, 0, 0, tokyo)},
+		{"other day in UTC", time.Date(2024, 2, 29, 23, 30, 0, 0, time.UTC), time.Date(2024, 3, 1, 9, 0, 0, 0, tokyo)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nextDigestSend(tt.after, "09:00", "Asia/Tokyo")
+			if err != nil {
+				t.Fatalf("nextDigestSend() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Fatalf("nextDigestSend() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := nextDigestSend(time.Now(), "25:00", "Asia/Tokyo"); err == nil {
+		t.Fatal("nextDigestSend() error = nil, want error for invalid send_time")
+	}
+	if _, err := nextDigestSend(time.Now(), "09:00", "Not/AZone"); err == nil {
+		t.Fatal("nextDigestSend() error = nil, want error for invalid timezone")
+	}
+}
+
+func TestBuildDigestMessage(t *testing.T) {
+	triggeredAt := time.Date(2024, 3, 1, 0, 30, 0, 0, time.UTC)
+	entries := []models.AlertDigestEntry{
+		{AlertName: "Orders drop", Severity: models.SeverityWarning, ConditionMetValue: "42", TriggeredAt: triggeredAt},
+		{AlertName: "Error rate", Severity: models.SeverityInfo, ConditionMetValue: "0.07", TriggeredAt: triggeredAt},
+	}
+
+	msg := buildDigestMessage(entries, time.FixedZone("JST", 9*60*60))
+
+	if msg.Title != "Alert Digest: 2 alerts triggered" {
+		t.Fatalf("Title = %q", msg.Title)
+	}
+	if !strings.Contains(msg.Body, "- [warning] Orders drop at 2024-03-01 09:30: 42") {
+		t.Fatalf("Body = %q, want the trigger in the user's timezone", msg.Body)
+	}
+	if !strings.Contains(msg.Body, "Error rate") {
+		t.Fatalf("Body = %q, want every trigger listed", msg.Body)
+	}
+}
+
+func TestValidateAlertSeverity(t *testing.T) {
+	for _, sev := range []models.AlertSeverity{models.SeverityInfo, models.SeverityWarning, models.SeverityCritical} {
+		if err := validateAlertSeverity(sev); err != nil {
+			t.Fatalf("validateAlertSeverity(%q) error = %v", sev, err)
+		}
+	}
+	if err := validateAlertSeverity("urgent"); err == nil {
+		t.Fatal("validateAlertSeverity() error = nil, want error for unknown severity")
+	}
+}
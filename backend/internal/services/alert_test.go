@@ -0,0 +1,386 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/config"
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/repository"
+)
+
+func newTestAlertResult() *models.QueryResult {
+	return &models.QueryResult{
+		Columns: []string{"orders", "error_rate"},
+		Rows: [][]interface{}{
+			{float64(80), float64(0.07)},
+		},
+		RowCount: 1,
+	}
+}
+
+func TestEvaluateConditionsAndAllTrue(t *testing.T) {
+	s := &AlertService{}
+	conditions := []models.AlertCondition{
+		{Column: "orders", Operator: models.OperatorLessThan, Value: "100"},
+		{Column: "error_rate", Operator: models.OperatorGreaterThan, Value: "0.05"},
+	}
+
+	triggered, value, err := s.evaluateConditions(newTestAlertResult(), conditions, models.LogicAnd)
+	if err != nil {
+		t.Fatalf("evaluateConditions() error = %v", err)
+	}
+	if !triggered {
+		t.Fatalf("evaluateConditions() triggered = false, want true")
+	}
+	if value != "orders=80, error_rate=0.07" {
+		t.Fatalf("evaluateConditions() value = %q, want %q", value, "orders=80, error_rate=0.07")
+	}
+}
+
+func TestEvaluateConditionsAndShortCircuitsOnFalse(t *testing.T) {
+	s := &AlertService{}
+	// The second condition references a missing column; evaluating it would error.
+	conditions := []models.AlertCondition{
+		{Column: "orders", Operator: models.OperatorGreaterThan, Value: "100"},
+		{Column: "missing", Operator: models.OperatorGreaterThan, Value: "0"},
+	}
+
+	triggered, _, err := s.evaluateConditions(newTestAlertResult(), conditions, models.LogicAnd)
+	if err != nil {
+		t.Fatalf("evaluateConditions() error = %v, want short-circuit before missing column", err)
+	}
+	if triggered {
+		t.Fatalf("evaluateConditions() triggered = true, want false")
+	}
+}
+
+func TestEvaluateConditionsOrTrue(t *testing.T) {
+	s := &AlertService{}
+	conditions := []models.AlertCondition{
+		{Column: "orders", Operator: models.OperatorGreaterThan, Value: "100"},
+		{Column: "error_rate", Operator: models.OperatorGreaterThan, Value: "0.05"},
+	}
+
+	triggered, _, err := s.evaluateConditions(newTestAlertResult(), conditions, models.LogicOr)
+	if err != nil {
+		t.Fatalf("evaluateConditions() error = %v", err)
+	}
+	if !triggered {
+		t.Fatalf("evaluateConditions() triggered = false, want true")
+	}
+}
+
+func TestEvaluateConditionsOrShortCircuitsOnTrue(t *testing.T) {
+	s := &AlertService{}
+	conditions := []models.AlertCondition{
+		{Column: "orders", Operator: models.OperatorLessThan, Value: "100"},
+		{Column: "missing", Operator: models.OperatorGreaterThan, Value: "0"},
+	}
+
+	triggered, _, err := s.evaluateConditions(newTestAlertResult(), conditions, models.LogicOr)
+	if err != nil {
+		t.Fatalf("evaluateConditions() error = %v, want short-circuit before missing column", err)
+	}
+	if !triggered {
+		t.Fatalf("evaluateConditions() triggered = false, want true")
+	}
+}
+
+func TestEffectiveAlertConditionsLegacyFallback(t *testing.T) {
+	alert := &models.QueryAlert{
+		ConditionColumn:   "orders",
+		ConditionOperator: models.OperatorLessThan,
+		ConditionValue:    "100",
+	}
+
+	conditions := effectiveAlertConditions(alert)
+	if len(conditions) != 1 {
+		t.Fatalf("effectiveAlertConditions() len = %d, want 1", len(conditions))
+	}
+	if conditions[0].Column != "orders" || conditions[0].Operator != models.OperatorLessThan || conditions[0].Value != "100" {
+		t.Fatalf("effectiveAlertConditions() = %+v, want legacy condition", conditions[0])
+	}
+
+	s := &AlertService{}
+	triggered, value, err := s.evaluateConditions(newTestAlertResult(), conditions, alert.LogicOperator)
+	if err != nil {
+		t.Fatalf("evaluateConditions() error = %v", err)
+	}
+	if !triggered || value != "80" {
+		t.Fatalf("evaluateConditions() = (%v, %q), want (true, \"80\")", triggered, value)
+	}
+}
+
+func TestNormalizeAlertConditions(t *testing.T) {
+	alert := &models.QueryAlert{
+		LogicOperator: "xor",
+		Conditions:    []models.AlertCondition{{Column: "orders", Operator: models.OperatorLessThan, Value: "100"}},
+	}
+	if err := normalizeAlertConditions(alert); err == nil {
+		t.Fatalf("normalizeAlertConditions() error = nil, want error for invalid logic operator")
+	}
+
+	alert.LogicOperator = ""
+	if err := normalizeAlertConditions(alert); err != nil {
+		t.Fatalf("normalizeAlertConditions() error = %v", err)
+	}
+	if alert.LogicOperator != models.LogicAnd {
+		t.Fatalf("LogicOperator = %q, want %q", alert.LogicOperator, models.LogicAnd)
+	}
+	if alert.ConditionColumn != "orders" {
+		t.Fatalf("ConditionColumn = %q, want mirrored first condition", alert.ConditionColumn)
+	}
+
+	if err := normalizeAlertConditions(&models.QueryAlert{}); err == nil {
+		t.Fatalf("normalizeAlertConditions() error = nil, want error when no condition given")
+	}
+}
+
+func TestIsAlertSnoozed(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	future := now.Add(30 * time.Minute)
+	past := now.Add(-time.Minute)
+
+	tests := []struct {
+		name         string
+		snoozedUntil *time.Time
+		want         bool
+	}{
+		{"not snoozed", nil, false},
+		{"snooze active", &future, true},
+		{"snooze expired", &past, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert := &models.QueryAlert{SnoozedUntil: tt.snoozedUntil}
+			if got := isAlertSnoozed(alert, now); got != tt.want {
+				t.Fatalf("isAlertSnoozed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviewAlert_RejectsInvalidConditions(t *testing.T) {
+	s := NewAlertService(nil, nil, nil, nil)
+	_, err := s.PreviewAlert(context.Background(), uuid.New(), &models.PreviewAlertRequest{
+		QueryID:       uuid.New(),
+		LogicOperator: "xor",
+		Conditions:    []models.AlertCondition{{Column: "orders", Operator: models.OperatorLessThan, Value: "100"}},
+	})
+	if err == nil {
+		t.Fatalf("PreviewAlert() error = nil, want error for invalid logic operator")
+	}
+}
+
+func TestPreviewAlert_RejectsOtherUsersQuery(t *testing.T) {
+	pool := setupHistoryDatabase(t)
+	ctx := context.Background()
+
+	createUser := func(t *testing.T) uuid.UUID {
+		var id uuid.UUID
+		err := pool.QueryRow(ctx,
+			`INSERT INTO users (email, name) VALUES ($1, 'alert preview test') RETURNING id`,
+			fmt.Sprintf("preview-%s@example.com", uuid.NewString()),
+		).Scan(&id)
+		if err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		t.Cleanup(func() { _, _ = pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, id) })
+		return id
+	}
+	owner := createUser(t)
+	other := createUser(t)
+
+	var queryID uuid.UUID
+	if err := pool.QueryRow(ctx,
+		`INSERT INTO saved_queries (user_id, name, query_text) VALUES ($1, 'q', 'SELECT 1') RETURNING id`, owner,
+	).Scan(&queryID); err != nil {
+		t.Fatalf("failed to create query: %v", err)
+	}
+
+	s := NewAlertService(pool, nil, nil, NewQueryService(nil))
+	req := &models.PreviewAlertRequest{
+		QueryID:           queryID,
+		ConditionColumn:   "orders",
+		ConditionOperator: models.OperatorGreaterThan,
+		ConditionValue:    "1",
+	}
+	if _, err := s.PreviewAlert(ctx, other, req); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("PreviewAlert() by another user error = %v, want %v", err, ErrPermissionDenied)
+	}
+
+	req.QueryID = uuid.New()
+	if _, err := s.PreviewAlert(ctx, owner, req); !errors.Is(err, ErrNotFound) {
+		t.Errorf("PreviewAlert() of a missing query error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+// fakeRowFilterSource returns fixed row filters per user
+type fakeRowFilterSource map[uuid.UUID][]models.RowFilter
+
+func (f fakeRowFilterSource) GetUserRowFilters(ctx context.Context, userID uuid.UUID) ([]models.RowFilter, error) {
+	return f[userID], nil
+}
+
+func TestPreviewAlert_AppliesOwnersRowFilters(t *testing.T) {
+	pool := setupHistoryDatabase(t)
+	ctx := context.Background()
+
+	var userID uuid.UUID
+	err := pool.QueryRow(ctx,
+		`INSERT INTO users (email, name) VALUES ($1, 'alert row filter test') RETURNING id`,
+		fmt.Sprintf("preview-filter-%s@example.com", uuid.NewString()),
+	).Scan(&userID)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() { _, _ = pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID) })
+
+	createQuery := func(text string) uuid.UUID {
+		var id uuid.UUID
+		if err := pool.QueryRow(ctx,
+			`INSERT INTO saved_queries (user_id, name, query_text, catalog, schema_name) VALUES ($1, 'q', $2, 'hive', 'sales') RETURNING id`,
+			userID, text,
+		).Scan(&id); err != nil {
+			t.Fatalf("failed to create query: %v", err)
+		}
+		return id
+	}
+
+	// Trino stand-in: the filtered subquery only reads the west region's rows
+	trino := repository.NewMockTrinoExecutor()
+	trino.ExecuteQueryFunc = func(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error) {
+		rows := [][]interface{}{{float64(10)}, {float64(100)}}
+		if strings.Contains(query, "region = 'west'") {
+			rows = rows[:1]
+		}
+		return &models.QueryResult{Columns: []string{"amount"}, Rows: rows, RowCount: len(rows)}, nil
+	}
+	s := NewAlertService(pool, &CachedTrinoService{trino: trino, cfg: &config.CacheConfig{}}, nil, NewQueryService(nil))
+	s.SetRowFilters(fakeRowFilterSource{userID: {
+		{Catalog: "hive", Schema: "sales", Table: "orders", Predicate: "region = 'west'"},
+	}})
+
+	sum := models.AggregationSum
+	req := &models.PreviewAlertRequest{
+		QueryID:           createQuery("SELECT amount FROM orders"),
+		ConditionColumn:   "amount",
+		ConditionOperator: models.OperatorGreaterThan,
+		ConditionValue:    "50",
+		Aggregation:       &sum,
+	}
+	preview, err := s.PreviewAlert(ctx, userID, req)
+	if err != nil {
+		t.Fatalf("PreviewAlert() error = %v", err)
+	}
+	if preview.RowCount != 1 || preview.Triggered || preview.ActualValue != "10" {
+		t.Fatalf("PreviewAlert() = %d rows, triggered %v, value %q; want only the filtered row (1, false, \"10\")",
+			preview.RowCount, preview.Triggered, preview.ActualValue)
+	}
+
+	req.QueryID = createQuery("DESCRIBE orders")
+	if _, err := s.PreviewAlert(ctx, userID, req); !errors.Is(err, ErrRowFilterUnsupported) {
+		t.Errorf("PreviewAlert() of a query the filters cannot apply to error = %v, want %v", err, ErrRowFilterUnsupported)
+	}
+}
+
+func TestPreviewAlert_RejectsMixedConditionFields(t *testing.T) {
+	s := NewAlertService(nil, nil, nil, nil)
+	_, err := s.PreviewAlert(context.Background(), uuid.New(), &models.PreviewAlertRequest{
+		QueryID:         uuid.New(),
+		ConditionColumn: "revenue",
+		Conditions:      []models.AlertCondition{{Column: "orders", Operator: models.OperatorLessThan, Value: "100"}},
+	})
+	if err == nil {
+		t.Fatalf("PreviewAlert() error = nil, want error for condition_column combined with conditions")
+	}
+}
+
+func TestAlertConditionUpdates(t *testing.T) {
+	pool := setupHistoryDatabase(t)
+	ctx := context.Background()
+
+	var userID uuid.UUID
+	if err := pool.QueryRow(ctx,
+		`INSERT INTO users (email, name) VALUES ($1, 'alert conditions test') RETURNING id`,
+		fmt.Sprintf("conditions-%s@example.com", uuid.NewString()),
+	).Scan(&userID); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() { _, _ = pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID) })
+	var queryID uuid.UUID
+	if err := pool.QueryRow(ctx,
+		`INSERT INTO saved_queries (user_id, name, query_text) VALUES ($1, 'q', 'SELECT 1') RETURNING id`, userID,
+	).Scan(&queryID); err != nil {
+		t.Fatalf("failed to create query: %v", err)
+	}
+
+	s := NewAlertService(pool, nil, nil, NewQueryService(nil))
+	orders := models.AlertCondition{Column: "orders", Operator: models.OperatorLessThan, Value: "100"}
+	revenue := models.AlertCondition{Column: "revenue", Operator: models.OperatorGreaterThan, Value: "5"}
+	create := func(t *testing.T, conditions ...models.AlertCondition) *models.QueryAlert {
+		t.Helper()
+		alert, err := s.CreateAlert(ctx, userID, &models.CreateAlertRequest{
+			QueryID: queryID, Name: "conditions", Conditions: conditions, ChannelIDs: []uuid.UUID{},
+		})
+		if err != nil {
+			t.Fatalf("CreateAlert() error = %v", err)
+		}
+		return alert
+	}
+
+	t.Run("mixed fields are rejected", func(t *testing.T) {
+		alert := create(t, orders)
+		_, err := s.UpdateAlert(ctx, alert.ID, userID, &models.UpdateAlertRequest{
+			ConditionValue: "50", Conditions: []models.AlertCondition{revenue},
+		})
+		if err == nil {
+			t.Fatal("UpdateAlert() error = nil, want error for condition_value combined with conditions")
+		}
+	})
+
+	t.Run("legacy fields edit a single condition", func(t *testing.T) {
+		alert := create(t, orders)
+		if _, err := s.UpdateAlert(ctx, alert.ID, userID, &models.UpdateAlertRequest{ConditionValue: "50"}); err != nil {
+			t.Fatalf("UpdateAlert() error = %v", err)
+		}
+		got, err := s.GetAlertByID(ctx, alert.ID)
+		if err != nil {
+			t.Fatalf("GetAlertByID() error = %v", err)
+		}
+		conditions := effectiveAlertConditions(got)
+		if len(conditions) != 1 || conditions[0].Column != "orders" || conditions[0].Value != "50" {
+			t.Errorf("conditions = %+v, want orders < 50", conditions)
+		}
+	})
+
+	t.Run("legacy fields cannot edit several conditions", func(t *testing.T) {
+		alert := create(t, orders, revenue)
+		if _, err := s.UpdateAlert(ctx, alert.ID, userID, &models.UpdateAlertRequest{ConditionValue: "50"}); err == nil {
+			t.Fatal("UpdateAlert() error = nil, want error for legacy fields on an alert with two conditions")
+		}
+	})
+
+	t.Run("failed channel write leaves no alert", func(t *testing.T) {
+		_, err := s.CreateAlert(ctx, userID, &models.CreateAlertRequest{
+			QueryID: queryID, Name: "dangling", Conditions: []models.AlertCondition{orders}, ChannelIDs: []uuid.UUID{uuid.New()},
+		})
+		if err == nil {
+			t.Fatal("CreateAlert() with an unknown channel error = nil, want error")
+		}
+		var count int
+		if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM query_alerts WHERE user_id = $1 AND name = 'dangling'`, userID).Scan(&count); err != nil {
+			t.Fatalf("failed to count alerts: %v", err)
+		}
+		if count != 0 {
+			t.Errorf("alerts after failed create = %d, want 0", count)
+		}
+	})
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mitsume/backend/internal/models"
+)
+
+const annotationColumns = `id, user_id, dashboard_id, occurred_at, label, color, created_at, updated_at`
+
+// maxWidgetAnnotations bounds the annotations attached to a single widget data response
+const maxWidgetAnnotations = 200
+
+// AnnotationService manages chart annotations
+type AnnotationService struct {
+	pool             *pgxpool.Pool
+	dashboardService *DashboardService
+}
+
+// NewAnnotationService creates a new annotation service
+func NewAnnotationService(pool *pgxpool.Pool, dashboardService *DashboardService) *AnnotationService {
+	return &AnnotationService{
+		pool:             pool,
+		dashboardService: dashboardService,
+	}
+}
+
+// ListAnnotations returns the user's personal annotations and, when dashboardID is set,
+// the dashboard's annotations (requires view permission), optionally bounded by from/to.
+func (s *AnnotationService) ListAnnotations(ctx context.Context, userID uuid.UUID, dashboardID *uuid.UUID, from, to *time.Time) ([]models.Annotation, error) {
+	if dashboardID != nil {
+		if err := s.requireDashboardPermission(ctx, *dashboardID, userID, false); err != nil {
+			return nil, err
+		}
+	}
+
+	query := `
+		SELECT ` + annotationColumns + `
+		FROM annotations
+		WHERE ((dashboard_id IS NULL AND user_id = $1) OR ($2::uuid IS NOT NULL AND dashboard_id = $2))
+		  AND ($3::timestamptz IS NULL OR occurred_at >= $3)
+		  AND ($4::timestamptz IS NULL OR occurred_at <= $4)
+		ORDER BY occurred_at ASC
+	`
+	return s.queryAnnotations(ctx, query, userID, dashboardID, from, to)
+}
+
+// GetAnnotationsForWidget returns the annotations shown on a dashboard widget for the given viewer
+// within [from, to]. The caller must already have checked view permission on the dashboard.
+func (s *AnnotationService) GetAnnotationsForWidget(ctx context.Context, dashboardID, userID uuid.UUID, from, to time.Time) ([]models.Annotation, error) {
+	query := `
+		SELECT ` + annotationColumns + `
+		FROM annotations
+		WHERE (dashboard_id = $1 OR (dashboard_id IS NULL AND user_id = $2))
+		  AND occurred_at BETWEEN $3 AND $4
+		ORDER BY occurred_at ASC
+		LIMIT ` + fmt.Sprint(maxWidgetAnnotations)
+	return s.queryAnnotations(ctx, query, dashboardID, userID, from, to)
+}
+
+// CreateAnnotation creates a personal annotation, or a dashboard annotation when DashboardID
+// is set (requires edit permission on the dashboard)
+func (s *AnnotationService) CreateAnnotation(ctx context.Context, userID uuid.UUID, req *models.CreateAnnotationRequest) (*models.Annotation, error) {
+	if req.DashboardID != nil {
+		if err := s.requireDashboardPermission(ctx, *req.DashboardID, userID, true); err != nil {
+			return nil, err
+		}
+	}
+
+	return scanAnnotation(s.pool.QueryRow(ctx,
+		`INSERT INTO annotations (user_id, dashboard_id, occurred_at, label, color)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING `+annotationColumns,
+		userID, req.DashboardID, req.OccurredAt, req.Label, req.Color,
+	))
+}
+
+// UpdateAnnotation updates an annotation the user may edit
+func (s *AnnotationService) UpdateAnnotation(ctx context.Context, id, userID uuid.UUID, req *models.UpdateAnnotationRequest) (*models.Annotation, error) {
+	if err := s.authorizeEdit(ctx, id, userID); err != nil {
+		return nil, err
+	}
+
+	annotation, err := scanAnnotation(s.pool.QueryRow(ctx,
+		`UPDATE annotations
+		 SET occurred_at = COALESCE($2, occurred_at),
+		     label = COALESCE(NULLIF($3, ''), label),
+		     color = COALESCE($4, color),
+		     updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $1
+		 RETURNING `+annotationColumns,
+		id, req.OccurredAt, req.Label, req.Color,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return annotation, err
+}
+
+// DeleteAnnotation deletes an annotation the user may edit
+func (s *AnnotationService) DeleteAnnotation(ctx context.Context, id, userID uuid.UUID) error {
+	if err := s.authorizeEdit(ctx, id, userID); err != nil {
+		return err
+	}
+
+	if _, err := s.pool.Exec(ctx, `DELETE FROM annotations WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete annotation: %w", err)
+	}
+	return nil
+}
+
+// authorizeEdit allows personal annotations to be edited by their creator only, and
+// dashboard annotations by anyone with edit permission on the dashboard
+func (s *AnnotationService) authorizeEdit(ctx context.Context, id, userID uuid.UUID) error {
+	var ownerID uuid.UUID
+	var dashboardID *uuid.UUID
+	err := s.pool.QueryRow(ctx, `SELECT user_id, dashboard_id FROM annotations WHERE id = $1`, id).Scan(&ownerID, &dashboardID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	if dashboardID == nil {
+		if ownerID != userID {
+			return ErrNotFound
+		}
+		return nil
+	}
+	return s.requireDashboardPermission(ctx, *dashboardID, userID, true)
+}
+
+func (s *AnnotationService) requireDashboardPermission(ctx context.Context, dashboardID, userID uuid.UUID, edit bool) error {
+	level, err := s.dashboardService.GetUserPermissionLevel(ctx, dashboardID, userID)
+	if err != nil {
+		return err
+	}
+	if !level.CanView() || (edit && !level.CanEdit()) {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+func (s *AnnotationService) queryAnnotations(ctx context.Context, query string, args ...interface{}) ([]models.Annotation, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query annotations: %w", err)
+	}
+	defer rows.Close()
+
+	annotations := []models.Annotation{}
+	for rows.Next() {
+		a, err := scanAnnotation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan annotation: %w", err)
+		}
+		annotations = append(annotations, *a)
+	}
+	return annotations, rows.Err()
+}
+
+func scanAnnotation(row pgx.Row) (*models.Annotation, error) {
+	var a models.Annotation
+	if err := row.Scan(&a.ID, &a.UserID, &a.DashboardID, &a.OccurredAt, &a.Label, &a.Color, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// timeSeriesChartTypes are the chart types whose x axis can be a time column
+var timeSeriesChartTypes = map[string]bool{
+	"line":  true,
+	"area":  true,
+	"bar":   true,
+	"combo": true,
+}
+
+// widgetTimeColumn returns the time column of a time-series widget: the configured
+// timeSeriesConfig.timeColumn, else the x axis. Returns "" for other widgets.
+func widgetTimeColumn(widget *models.Widget) string {
+	if !timeSeriesChartTypes[widget.ChartType] || len(widget.ChartConfig) == 0 {
+		return ""
+	}
+
+	var cfg struct {
+		XAxis            string `json:"xAxis"`
+		TimeSeriesConfig *struct {
+			Enabled    bool   `json:"enabled"`
+			TimeColumn string `json:"timeColumn"`
+		} `json:"timeSeriesConfig"`
+	}
+	if err := json.Unmarshal(widget.ChartConfig, &cfg); err != nil {
+		return ""
+	}
+	if cfg.TimeSeriesConfig != nil && cfg.TimeSeriesConfig.Enabled && cfg.TimeSeriesConfig.TimeColumn != "" {
+		return cfg.TimeSeriesConfig.TimeColumn
+	}
+	return cfg.XAxis
+}
+
+// resultTimeLayouts are the formats time values take in query results
+var resultTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+func parseResultTime(v interface{}) (time.Time, bool) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, true
+	case string:
+		for _, layout := range resultTimeLayouts {
+			if t, err := time.Parse(layout, val); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// WidgetTimeWindow returns the time range covered by a time-series widget's result.
+// ok is false when the widget is not a time-series chart or the column holds no times.
+func WidgetTimeWindow(widget *models.Widget, result *models.QueryResult) (from, to time.Time, ok bool) {
+	column := widgetTimeColumn(widget)
+	if column == "" || result == nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	idx := -1
+	for i, c := range result.Columns {
+		if c == column {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return time.Time{}, time.Time{}, false
+	}
+
+	for _, row := range result.Rows {
+		if idx >= len(row) {
+			continue
+		}
+		t, parsed := parseResultTime(row[idx])
+		if !parsed {
+			continue
+		}
+		if !ok || t.Before(from) {
+			from = t
+		}
+		if !ok || t.After(to) {
+			to = t
+		}
+		ok = true
+	}
+	return from, to, ok
+}
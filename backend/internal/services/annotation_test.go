@@ -0,0 +1,78 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mitsume/backend/internal/models"
+)
+
+func TestWidgetTimeWindow_XAxis(t *testing.T) {
+	widget := &models.Widget{ChartType: "line", ChartConfig: json.RawMessage(`{"xAxis":"day","yAxis":"count"}`)}
+	result := &models.QueryResult{
+		Columns: []string{"day", "count"},
+		Rows: [][]interface{}{
+			{"2024-01-03", 5},
+			{"2024-01-01", 3},
+			{nil, 1},
+			{"2024-01-02 12:30:00.000", 4},
+		},
+	}
+
+	from, to, ok := WidgetTimeWindow(widget, result)
+	if !ok {
+		t.Fatal("WidgetTimeWindow() ok = false, want true")
+	}
+	if want := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC); !from.Equal(want) {
+		t.Errorf("from = %v, want %v", from, want)
+	}
+	if want := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC); !to.Equal(want) {
+		t.Errorf("to = %v, want %v", to, want)
+	}
+}
+
+func TestWidgetTimeWindow_TimeSeriesConfigColumn(t *testing.T) {
+	widget := &models.Widget{
+		ChartType:   "area",
+		ChartConfig: json.RawMessage(`{"xAxis":"label","timeSeriesConfig":{"enabled":true,"timeColumn":"ts"}}`),
+	}
+	result := &models.QueryResult{
+		Columns: []string{"label", "ts"},
+		Rows: [][]interface{}{
+			{"a", "2024-05-01T10:00:00Z"},
+			{"b", "2024-05-01T12:00:00Z"},
+		},
+	}
+
+	from, to, ok := WidgetTimeWindow(widget, result)
+	if !ok {
+		t.Fatal("WidgetTimeWindow() ok = false, want true")
+	}
+	if to.Sub(from) != 2*time.Hour {
+		t.Errorf("window = %v..%v, want 2h", from, to)
+	}
+}
+
+func TestWidgetTimeWindow_NotTimeSeries(t *testing.T) {
+	tests := []struct {
+		name   string
+		widget *models.Widget
+	}{
+		{"pie chart", &models.Widget{ChartType: "pie", ChartConfig: json.RawMessage(`{"xAxis":"day"}`)}},
+		{"no x axis", &models.Widget{ChartType: "line", ChartConfig: json.RawMessage(`{}`)}},
+		{"non-time x axis", &models.Widget{ChartType: "bar", ChartConfig: json.RawMessage(`{"xAxis":"name"}`)}},
+	}
+	result := &models.QueryResult{
+		Columns: []string{"day", "name"},
+		Rows:    [][]interface{}{{"2024-01-01", "alice"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, ok := WidgetTimeWindow(tt.widget, result); ok {
+				t.Error("WidgetTimeWindow() ok = true, want false")
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mitsume/backend/internal/models"
+)
+
+const (
+	defaultAuditLogLimit = 50
+	maxAuditLogLimit     = 500
+)
+
+// AuditService records admin and permission-changing actions
+type AuditService struct {
+	pool *pgxpool.Pool
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(pool *pgxpool.Pool) *AuditService {
+	return &AuditService{pool: pool}
+}
+
+// Record appends an entry to the audit log. Failures are logged and never returned, so
+// auditing cannot fail the action being audited. A nil service records nothing.
+func (s *AuditService) Record(ctx context.Context, actorID uuid.UUID, action string, targetType string, targetID uuid.UUID, metadata map[string]interface{}) {
+	if s == nil || s.pool == nil {
+		return
+	}
+
+	var metadataJSON []byte
+	if len(metadata) > 0 {
+		var err error
+		metadataJSON, err = json.Marshal(metadata)
+		if err != nil {
+			log.Printf("[WARN] Failed to encode audit metadata for %s on %s %s: %v", action, targetType, targetID, err)
+		}
+	}
+
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO audit_log (actor_id, action, target_type, target_id, metadata)
+		 VALUES ($1, $2, $3, $4, $5)`,
+		actorID, action, targetType, targetID, metadataJSON,
+	)
+	if err != nil {
+		log.Printf("[WARN] Failed to record audit log %s by %s on %s %s: %v", action, actorID, targetType, targetID, err)
+	}
+}
+
+// List returns a page of audit log entries, newest first, with the total matching the filter
+func (s *AuditService) List(ctx context.Context, filter models.AuditLogFilter) (*models.AuditLogResponse, error) {
+	filter.Limit, filter.Offset = normalizeAuditPage(filter.Limit, filter.Offset)
+
+	where := `WHERE ($1::uuid IS NULL OR actor_id = $1) AND ($2::text = '' OR action = $2)
+		   AND ($3::timestamptz IS NULL OR created_at >= $3) AND ($4::timestamptz IS NULL OR created_at < $4)`
+
+	var total int
+	if err := s.pool.QueryRow(ctx, `SELECT COUNT(*) FROM audit_log `+where,
+		filter.ActorID, filter.Action, filter.From, filter.To).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count audit log: %w", err)
+	}
+
+	rows, err := s.pool.Query(ctx,
+		`SELECT id, actor_id, action, target_type, target_id, metadata, created_at
+		 FROM audit_log `+where+`
+		 ORDER BY created_at DESC
+		 LIMIT $5 OFFSET $6`,
+		filter.ActorID, filter.Action, filter.From, filter.To, filter.Limit, filter.Offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.AuditLogEntry{}
+	for rows.Next() {
+		var e models.AuditLogEntry
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.Action, &e.TargetType, &e.TargetID, &e.Metadata, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit log entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &models.AuditLogResponse{Entries: entries, Total: total}, nil
+}
+
+func normalizeAuditPage(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = defaultAuditLogLimit
+	}
+	if limit > maxAuditLogLimit {
+		limit = maxAuditLogLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
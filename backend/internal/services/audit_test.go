@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+)
+
+func TestAuditRecord_NilServiceIsNoop(t *testing.T) {
+	var s *AuditService
+	// Must not panic: handlers call Record unconditionally
+	s.Record(context.Background(), uuid.New(), models.AuditActionRoleCreate, models.AuditTargetRole, uuid.New(), nil)
+
+	NewAuditService(nil).Record(context.Background(), uuid.New(), models.AuditActionRoleDelete, models.AuditTargetRole, uuid.New(), map[string]interface{}{"name": "analyst"})
+}
+
+func TestNormalizeAuditPage(t *testing.T) {
+	tests := []struct {
+		limit, offset         int
+		wantLimit, wantOffset int
+	}{
+		{0, 0, defaultAuditLogLimit, 0},
+		{-1, -5, defaultAuditLogLimit, 0},
+		{20, 40, 20, 40},
+		{maxAuditLogLimit + 1, 0, maxAuditLogLimit, 0},
+	}
+
+	for _, tt := range tests {
+		limit, offset := normalizeAuditPage(tt.limit, tt.offset)
+		if limit != tt.wantLimit || offset != tt.wantOffset {
+			t.Errorf("normalizeAuditPage(%d, %d) = (%d, %d), want (%d, %d)",
+				tt.limit, tt.offset, limit, offset, tt.wantLimit, tt.wantOffset)
+		}
+	}
+}
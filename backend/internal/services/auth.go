@@ -2,9 +2,15 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"log"
 	"time"
+	"unicode/utf8"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -14,20 +20,62 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrInvalidRefreshToken is returned when a refresh token is unknown, expired or already used
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+// ErrUserNotActive is returned when a disabled or pending user tries to authenticate
+var ErrUserNotActive = errors.New("user account is not active")
+
+// ErrSessionExpired is returned when a session has outlived SESSION_MAX_LIFETIME_HOURS or
+// been idle longer than SESSION_IDLE_TIMEOUT_MINUTES
+var ErrSessionExpired = errors.New("session expired")
+
+// ErrNoLocalPassword is returned when a user without a local password (e.g. Google sign-in) tries to change it
+var ErrNoLocalPassword = errors.New("account has no local password")
+
+// ErrInvalidCurrentPassword is returned when the current password given to ChangePassword is wrong
+var ErrInvalidCurrentPassword = errors.New("current password is incorrect")
+
+// ErrPasswordTooShort is returned when a new password is shorter than the configured minimum
+var ErrPasswordTooShort = errors.New("password is too short")
+
+// minPasswordLength matches the min= binding on RegisterRequest.Password
+const minPasswordLength = 6
+
+// temporaryPasswordLength is the length of passwords generated by ResetPassword, unless the
+// configured minimum is longer
+const temporaryPasswordLength = 16
+
 type AuthService struct {
-	cfg      *config.Config
-	userRepo repository.UserRepository
-	roleRepo repository.RoleRepository
+	cfg              *config.Config
+	userRepo         repository.UserRepository
+	roleRepo         repository.RoleRepository
+	refreshTokenRepo repository.RefreshTokenRepository // nil disables refresh tokens
+	stateCache       *QueryCacheService                // nil disables auth state caching
+	sessions         SessionTracker                    // nil disables the idle timeout
+	mailer           AccountMailer                     // nil disables account status emails
 }
 
-func NewAuthService(cfg *config.Config, userRepo repository.UserRepository, roleRepo repository.RoleRepository) *AuthService {
+func NewAuthService(cfg *config.Config, userRepo repository.UserRepository, roleRepo repository.RoleRepository, refreshTokenRepo repository.RefreshTokenRepository) *AuthService {
 	return &AuthService{
-		cfg:      cfg,
-		userRepo: userRepo,
-		roleRepo: roleRepo,
+		cfg:              cfg,
+		userRepo:         userRepo,
+		roleRepo:         roleRepo,
+		refreshTokenRepo: refreshTokenRepo,
 	}
 }
 
+// SetStateCache enables caching of users' token versions and statuses in Redis for
+// JWT.StatusCacheSeconds, avoiding a database lookup on every authenticated request
+func (s *AuthService) SetStateCache(cache *QueryCacheService) {
+	s.stateCache = cache
+}
+
+// SetSessionTracker enables the idle timeout (JWT.SessionIdleTimeoutMinutes) using the tracker
+func (s *AuthService) SetSessionTracker(tracker SessionTracker) {
+	s.sessions = tracker
+}
+
 func (s *AuthService) Register(ctx context.Context, req *models.RegisterRequest) (*models.AuthResponse, error) {
 	// Check if user already exists
 	exists, err := s.userRepo.ExistsByEmail(ctx, req.Email)
@@ -52,21 +100,12 @@ func (s *AuthService) Register(ctx context.Context, req *models.RegisterRequest)
 
 	// Auto-assign admin role to the first registered user
 	if s.roleRepo != nil {
-		if err := s.autoAssignAdminToFirstUser(ctx, user.ID); err != nil {
+		if _, err := s.autoAssignAdminToFirstUser(ctx, user.ID); err != nil {
 			log.Printf("[WARN] Failed to auto-assign admin role to first user %s: %v", user.ID, err)
 		}
 	}
 
-	// Generate token
-	token, err := s.generateToken(user.ID)
-	if err != nil {
-		return nil, err
-	}
-
-	return &models.AuthResponse{
-		Token: token,
-		User:  *user,
-	}, nil
+	return s.issueTokens(ctx, user)
 }
 
 func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*models.AuthResponse, error) {
@@ -88,16 +127,7 @@ func (s *AuthService) Login(ctx context.Context, req *models.LoginRequest) (*mod
 		return nil, errors.New("invalid credentials")
 	}
 
-	// Generate token
-	token, err := s.generateToken(user.ID)
-	if err != nil {
-		return nil, err
-	}
-
-	return &models.AuthResponse{
-		Token: token,
-		User:  *user,
-	}, nil
+	return s.issueTokens(ctx, user)
 }
 
 func (s *AuthService) GetUserByID(ctx context.Context, userID uuid.UUID) (*models.User, error) {
@@ -121,36 +151,442 @@ func (s *AuthService) FindOrCreateGoogleUser(ctx context.Context, googleID, emai
 
 		// Auto-assign admin role to the first registered user
 		if s.roleRepo != nil {
-			if err := s.autoAssignAdminToFirstUser(ctx, user.ID); err != nil {
+			if _, err := s.autoAssignAdminToFirstUser(ctx, user.ID); err != nil {
 				log.Printf("[WARN] Failed to auto-assign admin role to first Google user %s: %v", user.ID, err)
 			}
 		}
 	}
 
-	// Generate token
-	token, err := s.generateToken(user.ID)
+	return s.issueTokens(ctx, user)
+}
+
+// FindOrCreateOIDCUser signs in the user identified by the OIDC provider's subject, creating it
+// on first sign-in. New users are pending and fail with ErrUserNotActive until an admin activates
+// them; the first user of the instance becomes an active admin so that someone can do so.
+func (s *AuthService) FindOrCreateOIDCUser(ctx context.Context, subject, email, name string) (*models.AuthResponse, error) {
+	user, err := s.userRepo.FindByOIDCSubject(ctx, subject)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	if user == nil {
+		user, err = s.userRepo.CreateOIDCUser(ctx, email, name, subject)
+		if err != nil {
+			return nil, err
+		}
+
+		if s.roleRepo != nil {
+			isAdmin, err := s.autoAssignAdminToFirstUser(ctx, user.ID)
+			if err != nil {
+				log.Printf("[WARN] Failed to auto-assign admin role to first OIDC user %s: %v", user.ID, err)
+			}
+			if isAdmin {
+				if err := s.userRepo.SetStatus(ctx, user.ID, models.UserStatusActive); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	return s.issueTokens(ctx, user)
+}
+
+// Refresh exchanges a refresh token for a new access token. The refresh token is rotated:
+// the presented one is consumed in a single statement, so a token replayed concurrently is
+// accepted at most once, and a new one is returned. Tokens issued before the user's
+// sessions were last revoked are rejected even if they escaped deletion (e.g. a refresh
+// racing with RevokeUserSessions).
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*models.AuthResponse, error) {
+	if s.refreshTokenRepo == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	stored, err := s.refreshTokenRepo.ConsumeByHash(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	if stored.TokenVersion != nil {
+		state, err := s.userRepo.GetAuthState(ctx, stored.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if *stored.TokenVersion != state.TokenVersion {
+			return nil, ErrInvalidRefreshToken
+		}
+	}
+
+	user, err := s.userRepo.FindByID(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	// Refreshing continues the session; tokens issued before sessions were tracked start a new one
+	if stored.Session == nil {
+		return s.issueTokens(ctx, user)
+	}
+	if err := s.checkSession(ctx, *stored.Session); err != nil {
+		return nil, err
+	}
+	return s.issueSessionTokens(ctx, user, *stored.Session)
+}
+
+// Logout deletes the given refresh token and, when the idle timeout is enabled, ends its
+// session so access tokens of the session are rejected too. Unknown tokens are ignored.
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	if s.refreshTokenRepo == nil {
+		return nil
+	}
+	stored, err := s.refreshTokenRepo.ConsumeByHash(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if s.idleTimeoutEnabled() && stored.Session != nil {
+		if err := s.sessions.End(ctx, stored.Session.ID, s.sessionRetention()); err != nil {
+			log.Printf("Failed to end session %s: %v", stored.Session.ID, err)
+		}
+	}
+	return nil
+}
+
+// RevokeUserSessions invalidates every access and refresh token issued to the user
+func (s *AuthService) RevokeUserSessions(ctx context.Context, userID uuid.UUID) error {
+	if err := s.userRepo.IncrementTokenVersion(ctx, userID); err != nil {
+		return err
+	}
+	s.invalidateAuthState(ctx, userID)
+	if s.refreshTokenRepo != nil {
+		return s.refreshTokenRepo.DeleteByUser(ctx, userID)
+	}
+	return nil
+}
+
+// SetUserStatus enables or disables a user. Disabling also revokes the user's refresh tokens;
+// access tokens already issued are rejected by ValidateToken once the status is read.
+// Admins cannot change their own status. With an account mailer set, the user is emailed
+// about the change.
+func (s *AuthService) SetUserStatus(ctx context.Context, adminUserID, userID uuid.UUID, status models.UserStatus) error {
+	if adminUserID == userID {
+		return ErrInvalidRequest
+	}
+
+	var previous models.UserStatus
+	if s.mailer != nil {
+		if state, err := s.userRepo.GetAuthState(ctx, userID); err == nil {
+			previous = state.Status
+		}
+	}
+
+	if err := s.userRepo.SetStatus(ctx, userID, status); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	s.invalidateAuthState(ctx, userID)
+
+	if status != models.UserStatusActive && s.refreshTokenRepo != nil {
+		if err := s.refreshTokenRepo.DeleteByUser(ctx, userID); err != nil {
+			return err
+		}
+	}
+	s.notifyStatusChange(ctx, userID, previous, status)
+	return nil
+}
+
+// ApproveUsers activates the pending users among userIDs in one transaction and, when roleID
+// is set, assigns them that role. With an account mailer set, approved users are emailed. Users that are not pending, do not exist or are the admin
+// themselves are reported in the results without failing the others. Results follow the order
+// of userIDs, with duplicates removed.
+func (s *AuthService) ApproveUsers(ctx context.Context, adminUserID uuid.UUID, userIDs []uuid.UUID, roleID *uuid.UUID) (*models.ApproveUsersResponse, error) {
+	if roleID != nil {
+		if s.roleRepo == nil {
+			return nil, ErrRoleNotFound
+		}
+		if _, err := s.roleRepo.GetByID(ctx, *roleID); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil, ErrRoleNotFound
+			}
+			return nil, err
+		}
+	}
+
+	seen := make(map[uuid.UUID]bool, len(userIDs))
+	var ordered, candidates []uuid.UUID
+	for _, id := range userIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		ordered = append(ordered, id)
+		if id != adminUserID {
+			candidates = append(candidates, id)
+		}
+	}
+
+	previous := map[uuid.UUID]models.UserStatus{}
+	if len(candidates) > 0 {
+		var err error
+		if previous, err = s.userRepo.ApprovePending(ctx, candidates, roleID, adminUserID); err != nil {
+			return nil, err
+		}
+	}
+
+	resp := &models.ApproveUsersResponse{Results: make([]models.UserApprovalResult, 0, len(ordered))}
+	for _, id := range ordered {
+		result := models.UserApprovalResult{UserID: id}
+		status, found := previous[id]
+		switch {
+		case id == adminUserID:
+			result.Outcome = models.UserApprovalSelf
+			result.Status = models.UserStatusActive
+		case !found:
+			result.Outcome = models.UserApprovalNotFound
+		case status == models.UserStatusPending:
+			result.Outcome = models.UserApprovalApproved
+			result.Status = models.UserStatusActive
+			resp.Approved++
+			s.invalidateAuthState(ctx, id)
+			s.notifyStatusChange(ctx, id, models.UserStatusPending, models.UserStatusActive)
+		default:
+			result.Outcome = models.UserApprovalNotPending
+			result.Status = status
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	return resp, nil
+}
+
+// ChangePassword replaces a local user's password after verifying the current one.
+// Every existing session is revoked; the returned tokens keep the caller signed in.
+// The new password must be at least minPasswordLength and MITSUME_ADMIN_PASSWORD_MIN_LENGTH characters long.
+func (s *AuthService) ChangePassword(ctx context.Context, userID uuid.UUID, oldPassword, newPassword string) (*models.AuthResponse, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	currentHash, err := s.userRepo.GetPasswordHash(ctx, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrNoLocalPassword
+		}
+		return nil, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(currentHash), []byte(oldPassword)); err != nil {
+		return nil, ErrInvalidCurrentPassword
+	}
+
+	minLength := s.passwordMinLength()
+	if utf8.RuneCountInString(newPassword) < minLength {
+		return nil, fmt.Errorf("%w: minimum is %d characters", ErrPasswordTooShort, minLength)
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.UpdatePasswordHash(ctx, userID, string(hashedPassword)); err != nil {
+		return nil, err
+	}
+
+	if err := s.RevokeUserSessions(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(ctx, user)
+}
+
+// ResetPassword replaces a local user's password with a generated temporary one, which is
+// returned so the admin can pass it on. Every session of the user is revoked.
+// Admins cannot reset their own password; they use ChangePassword instead.
+func (s *AuthService) ResetPassword(ctx context.Context, adminUserID, userID uuid.UUID) (string, error) {
+	if adminUserID == userID {
+		return "", ErrInvalidRequest
+	}
+
+	if _, err := s.userRepo.FindByID(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	if _, err := s.userRepo.GetPasswordHash(ctx, userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", ErrNoLocalPassword
+		}
+		return "", err
+	}
+
+	password, err := generateTemporaryPassword(max(temporaryPasswordLength, s.passwordMinLength()))
+	if err != nil {
+		return "", err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	if err := s.userRepo.UpdatePasswordHash(ctx, userID, string(hashedPassword)); err != nil {
+		return "", err
+	}
+
+	if err := s.RevokeUserSessions(ctx, userID); err != nil {
+		return "", err
+	}
+	return password, nil
+}
+
+// passwordMinLength returns the minimum length, in characters, of a new password: the larger of
+// minPasswordLength and MITSUME_ADMIN_PASSWORD_MIN_LENGTH
+func (s *AuthService) passwordMinLength() int {
+	return max(minPasswordLength, s.cfg.Admin.PasswordMinLength)
+}
+
+// getAuthState returns the user's token version and status, from the cache when enabled
+func (s *AuthService) getAuthState(ctx context.Context, userID uuid.UUID) (*models.UserAuthState, error) {
+	ttl := time.Duration(s.cfg.JWT.StatusCacheSeconds) * time.Second
+	useCache := s.stateCache != nil && ttl > 0
+	if useCache {
+		if state, ok := s.stateCache.GetUserAuthState(ctx, userID); ok {
+			return state, nil
+		}
+	}
+
+	state, err := s.userRepo.GetAuthState(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if useCache {
+		s.stateCache.SetUserAuthState(ctx, userID, state, ttl)
+	}
+	return state, nil
+}
+
+func (s *AuthService) invalidateAuthState(ctx context.Context, userID uuid.UUID) {
+	if s.stateCache == nil {
+		return
+	}
+	if err := s.stateCache.InvalidateUserAuthState(ctx, userID); err != nil {
+		log.Printf("Failed to invalidate cached auth state for user %s: %v", userID, err)
+	}
+}
+
+// issueTokens starts a new session for the user and issues its tokens
+func (s *AuthService) issueTokens(ctx context.Context, user *models.User) (*models.AuthResponse, error) {
+	session := models.Session{ID: uuid.New(), StartedAt: time.Now()}
+	resp, err := s.issueSessionTokens(ctx, user, session)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.idleTimeoutEnabled() {
+		if _, err := s.sessions.Touch(ctx, session.ID, s.idleTimeout(), s.sessionRetention()); err != nil {
+			log.Printf("Failed to record session activity for %s: %v", session.ID, err)
+		}
+	}
+	return resp, nil
+}
+
+// issueSessionTokens creates an access token and, when refresh tokens are enabled, a refresh
+// token for the user's session. Neither outlives the session's absolute lifetime.
+func (s *AuthService) issueSessionTokens(ctx context.Context, user *models.User, session models.Session) (*models.AuthResponse, error) {
+	state, err := s.userRepo.GetAuthState(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if state.Status != models.UserStatusActive {
+		return nil, ErrUserNotActive
+	}
+
+	token, err := s.generateSessionToken(user.ID, state.TokenVersion, &session)
 	if err != nil {
 		return nil, err
 	}
 
-	return &models.AuthResponse{
+	resp := &models.AuthResponse{
 		Token: token,
 		User:  *user,
-	}, nil
+	}
+
+	if s.refreshTokenRepo != nil {
+		refreshToken, err := generateRefreshToken()
+		if err != nil {
+			return nil, err
+		}
+		expiresAt := s.capToSession(time.Now().Add(time.Duration(s.cfg.JWT.RefreshExpireDays)*24*time.Hour), session)
+		if err := s.refreshTokenRepo.Create(ctx, user.ID, hashRefreshToken(refreshToken), state.TokenVersion, session, expiresAt); err != nil {
+			return nil, err
+		}
+		resp.RefreshToken = refreshToken
+	}
+
+	return resp, nil
 }
 
 func (s *AuthService) generateToken(userID uuid.UUID) (string, error) {
+	return s.generateSessionToken(userID, 0, nil)
+}
+
+// generateSessionToken signs an access token. Session tokens carry the session ID ("sid") and
+// start time ("sat") so ValidateToken can enforce the session lifetime and idle timeout.
+func (s *AuthService) generateSessionToken(userID uuid.UUID, version int, session *models.Session) (string, error) {
+	expiresAt := time.Now().Add(time.Duration(s.cfg.JWT.ExpireHour) * time.Hour)
 	claims := jwt.MapClaims{
 		"user_id": userID.String(),
-		"exp":     time.Now().Add(time.Duration(s.cfg.JWT.ExpireHour) * time.Hour).Unix(),
+		"ver":     version,
 		"iat":     time.Now().Unix(),
 	}
+	if session != nil {
+		claims["sid"] = session.ID.String()
+		claims["sat"] = session.StartedAt.Unix()
+		expiresAt = s.capToSession(expiresAt, *session)
+	}
+	claims["exp"] = expiresAt.Unix()
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(s.cfg.JWT.Secret))
 }
 
-func (s *AuthService) ValidateToken(tokenString string) (uuid.UUID, error) {
+// generateRefreshToken returns a random opaque refresh token
+func generateRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// generateTemporaryPassword returns a random URL-safe password of length characters
+func generateTemporaryPassword(length int) (string, error) {
+	b := make([]byte, length) // base64 yields 4 characters per 3 bytes, so this is enough
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b)[:length], nil
+}
+
+// hashRefreshToken returns the hex SHA-256 of a refresh token; only the hash is stored
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidateToken verifies the access token signature and expiry, and rejects tokens
+// whose version is older than the user's current token version (revoked sessions).
+// Tokens of disabled or pending users fail with ErrUserNotActive.
+func (s *AuthService) ValidateToken(ctx context.Context, tokenString string) (uuid.UUID, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("invalid signing method")
@@ -167,29 +603,131 @@ func (s *AuthService) ValidateToken(tokenString string) (uuid.UUID, error) {
 		if !ok {
 			return uuid.Nil, errors.New("invalid token claims")
 		}
-		return uuid.Parse(userIDStr)
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			return uuid.Nil, err
+		}
+
+		// Tokens issued before versioning carry no "ver" claim and count as version 0
+		var tokenVersion int
+		if v, ok := claims["ver"].(float64); ok {
+			tokenVersion = int(v)
+		}
+		state, err := s.getAuthState(ctx, userID)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if tokenVersion < state.TokenVersion {
+			return uuid.Nil, errors.New("token has been revoked")
+		}
+		if state.Status != models.UserStatusActive {
+			return uuid.Nil, ErrUserNotActive
+		}
+
+		// Tokens issued before sessions were tracked carry no "sid" and are bound by exp only
+		if session, ok := sessionFromClaims(claims); ok {
+			if err := s.checkSession(ctx, session); err != nil {
+				return uuid.Nil, err
+			}
+		}
+
+		return userID, nil
 	}
 
 	return uuid.Nil, errors.New("invalid token")
 }
 
-// autoAssignAdminToFirstUser assigns admin role to the first registered user
-func (s *AuthService) autoAssignAdminToFirstUser(ctx context.Context, userID uuid.UUID) error {
+// sessionFromClaims reads the session carried by an access token
+func sessionFromClaims(claims jwt.MapClaims) (models.Session, bool) {
+	sid, ok := claims["sid"].(string)
+	if !ok {
+		return models.Session{}, false
+	}
+	id, err := uuid.Parse(sid)
+	if err != nil {
+		return models.Session{}, false
+	}
+	sat, _ := claims["sat"].(float64)
+	return models.Session{ID: id, StartedAt: time.Unix(int64(sat), 0)}, true
+}
+
+// checkSession rejects sessions past their absolute lifetime and, when the idle timeout is
+// enabled, sessions without recent activity; otherwise it records the activity.
+// Tracker errors are logged and the session is let through.
+func (s *AuthService) checkSession(ctx context.Context, session models.Session) error {
+	if lifetime := s.maxSessionLifetime(); lifetime > 0 && time.Since(session.StartedAt) > lifetime {
+		return ErrSessionExpired
+	}
+	if !s.idleTimeoutEnabled() {
+		return nil
+	}
+
+	active, err := s.sessions.Touch(ctx, session.ID, s.idleTimeout(), s.sessionRetention())
+	if err != nil {
+		log.Printf("Failed to check session activity for %s: %v", session.ID, err)
+		return nil
+	}
+	if !active {
+		return ErrSessionExpired
+	}
+	return nil
+}
+
+func (s *AuthService) maxSessionLifetime() time.Duration {
+	return time.Duration(s.cfg.JWT.SessionMaxLifetimeHours) * time.Hour
+}
+
+func (s *AuthService) idleTimeout() time.Duration {
+	return time.Duration(s.cfg.JWT.SessionIdleTimeoutMinutes) * time.Minute
+}
+
+func (s *AuthService) idleTimeoutEnabled() bool {
+	return s.sessions != nil && s.idleTimeout() > 0
+}
+
+// sessionRetention is how long session activity is kept: as long as any token of the
+// session can still be presented
+func (s *AuthService) sessionRetention() time.Duration {
+	retention := time.Duration(s.cfg.JWT.RefreshExpireDays) * 24 * time.Hour
+	if access := time.Duration(s.cfg.JWT.ExpireHour) * time.Hour; access > retention {
+		retention = access
+	}
+	if lifetime := s.maxSessionLifetime(); lifetime > 0 && lifetime < retention {
+		retention = lifetime
+	}
+	return retention
+}
+
+// capToSession caps a token expiry at the end of the session's absolute lifetime
+func (s *AuthService) capToSession(expiresAt time.Time, session models.Session) time.Time {
+	if lifetime := s.maxSessionLifetime(); lifetime > 0 {
+		if end := session.StartedAt.Add(lifetime); end.Before(expiresAt) {
+			return end
+		}
+	}
+	return expiresAt
+}
+
+// autoAssignAdminToFirstUser assigns admin role to the first registered user and reports whether it did
+func (s *AuthService) autoAssignAdminToFirstUser(ctx context.Context, userID uuid.UUID) (bool, error) {
 	count, err := s.roleRepo.CountUsers(ctx)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	// Only assign admin to the very first user
 	if count == 1 {
 		adminRole, err := s.roleRepo.GetAdminRole(ctx)
 		if err != nil {
-			return err
+			return false, err
+		}
+		if err := s.roleRepo.AssignRole(ctx, userID, adminRole.ID, nil); err != nil {
+			return false, err
 		}
-		return s.roleRepo.AssignRole(ctx, userID, adminRole.ID, nil)
+		return true, nil
 	}
 
-	return nil
+	return false, nil
 }
 
 // GetUserRoles returns the roles for a specific user
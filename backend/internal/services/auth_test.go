@@ -2,7 +2,11 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/mitsume/backend/internal/config"
@@ -23,7 +27,7 @@ func newTestConfig() *config.Config {
 func TestGenerateAndValidateToken(t *testing.T) {
 	cfg := newTestConfig()
 	mockRepo := repository.NewMockUserRepository()
-	service := NewAuthService(cfg, mockRepo, nil)
+	service := NewAuthService(cfg, mockRepo, nil, nil)
 
 	userID := uuid.New()
 	token, err := service.generateToken(userID)
@@ -31,7 +35,7 @@ func TestGenerateAndValidateToken(t *testing.T) {
 		t.Fatalf("generateToken() error = %v", err)
 	}
 
-	got, err := service.ValidateToken(token)
+	got, err := service.ValidateToken(context.Background(), token)
 	if err != nil {
 		t.Fatalf("ValidateToken() error = %v", err)
 	}
@@ -43,9 +47,9 @@ func TestGenerateAndValidateToken(t *testing.T) {
 func TestValidateToken_Invalid(t *testing.T) {
 	cfg := newTestConfig()
 	mockRepo := repository.NewMockUserRepository()
-	service := NewAuthService(cfg, mockRepo, nil)
+	service := NewAuthService(cfg, mockRepo, nil, nil)
 
-	_, err := service.ValidateToken("not-a-jwt")
+	_, err := service.ValidateToken(context.Background(), "not-a-jwt")
 	if err == nil {
 		t.Fatal("ValidateToken() expected error, got nil")
 	}
@@ -57,14 +61,14 @@ func TestValidateToken_Invalid(t *testing.T) {
 			ExpireHour: 1,
 		},
 	}
-	otherService := NewAuthService(other, mockRepo, nil)
+	otherService := NewAuthService(other, mockRepo, nil, nil)
 	userID := uuid.New()
 	token, err := otherService.generateToken(userID)
 	if err != nil {
 		t.Fatalf("generateToken() error = %v", err)
 	}
 
-	if _, err := service.ValidateToken(token); err == nil {
+	if _, err := service.ValidateToken(context.Background(), token); err == nil {
 		t.Fatal("ValidateToken() expected error with mismatched secret, got nil")
 	}
 }
@@ -72,7 +76,7 @@ func TestValidateToken_Invalid(t *testing.T) {
 func TestRegister_Success(t *testing.T) {
 	cfg := newTestConfig()
 	mockRepo := repository.NewMockUserRepository()
-	service := NewAuthService(cfg, mockRepo, nil)
+	service := NewAuthService(cfg, mockRepo, nil, nil)
 
 	req := &models.RegisterRequest{
 		Email:    "test@example.com",
@@ -99,7 +103,7 @@ func TestRegister_Success(t *testing.T) {
 func TestRegister_DuplicateEmail(t *testing.T) {
 	cfg := newTestConfig()
 	mockRepo := repository.NewMockUserRepository()
-	service := NewAuthService(cfg, mockRepo, nil)
+	service := NewAuthService(cfg, mockRepo, nil, nil)
 
 	// Add existing user
 	existingEmail := "existing@example.com"
@@ -128,7 +132,7 @@ func TestRegister_DuplicateEmail(t *testing.T) {
 func TestLogin_Success(t *testing.T) {
 	cfg := newTestConfig()
 	mockRepo := repository.NewMockUserRepository()
-	service := NewAuthService(cfg, mockRepo, nil)
+	service := NewAuthService(cfg, mockRepo, nil, nil)
 
 	// Create hashed password
 	password := "password123"
@@ -166,7 +170,7 @@ func TestLogin_Success(t *testing.T) {
 func TestLogin_InvalidEmail(t *testing.T) {
 	cfg := newTestConfig()
 	mockRepo := repository.NewMockUserRepository()
-	service := NewAuthService(cfg, mockRepo, nil)
+	service := NewAuthService(cfg, mockRepo, nil, nil)
 
 	req := &models.LoginRequest{
 		Email:    "nonexistent@example.com",
@@ -185,7 +189,7 @@ func TestLogin_InvalidEmail(t *testing.T) {
 func TestLogin_InvalidPassword(t *testing.T) {
 	cfg := newTestConfig()
 	mockRepo := repository.NewMockUserRepository()
-	service := NewAuthService(cfg, mockRepo, nil)
+	service := NewAuthService(cfg, mockRepo, nil, nil)
 
 	// Create hashed password
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("correctpassword"), bcrypt.DefaultCost)
@@ -217,7 +221,7 @@ func TestLogin_InvalidPassword(t *testing.T) {
 func TestGetUserByID_Success(t *testing.T) {
 	cfg := newTestConfig()
 	mockRepo := repository.NewMockUserRepository()
-	service := NewAuthService(cfg, mockRepo, nil)
+	service := NewAuthService(cfg, mockRepo, nil, nil)
 
 	email := "test@example.com"
 	user := &models.User{
@@ -239,7 +243,7 @@ func TestGetUserByID_Success(t *testing.T) {
 func TestGetUserByID_NotFound(t *testing.T) {
 	cfg := newTestConfig()
 	mockRepo := repository.NewMockUserRepository()
-	service := NewAuthService(cfg, mockRepo, nil)
+	service := NewAuthService(cfg, mockRepo, nil, nil)
 
 	_, err := service.GetUserByID(context.Background(), uuid.New())
 	if err == nil {
@@ -250,7 +254,7 @@ func TestGetUserByID_NotFound(t *testing.T) {
 func TestFindOrCreateGoogleUser_ExistingUser(t *testing.T) {
 	cfg := newTestConfig()
 	mockRepo := repository.NewMockUserRepository()
-	service := NewAuthService(cfg, mockRepo, nil)
+	service := NewAuthService(cfg, mockRepo, nil, nil)
 
 	googleID := "google-123"
 	email := "test@example.com"
@@ -278,7 +282,7 @@ func TestFindOrCreateGoogleUser_ExistingUser(t *testing.T) {
 func TestFindOrCreateGoogleUser_NewUser(t *testing.T) {
 	cfg := newTestConfig()
 	mockRepo := repository.NewMockUserRepository()
-	service := NewAuthService(cfg, mockRepo, nil)
+	service := NewAuthService(cfg, mockRepo, nil, nil)
 
 	googleID := "google-new-123"
 	email := "newuser@example.com"
@@ -299,3 +303,545 @@ func TestFindOrCreateGoogleUser_NewUser(t *testing.T) {
 		t.Fatalf("FindOrCreateGoogleUser() name = %v, want %v", resp.User.Name, name)
 	}
 }
+
+func TestFindOrCreateOIDCUser_NewUserIsPending(t *testing.T) {
+	cfg := newTestConfig()
+	mockRepo := repository.NewMockUserRepository()
+	roleRepo := repository.NewMockRoleRepository()
+	roleRepo.Create(context.Background(), "admin", "")
+	roleRepo.UserCount = 2
+	service := NewAuthService(cfg, mockRepo, roleRepo, nil)
+
+	_, err := service.FindOrCreateOIDCUser(context.Background(), "oidc-sub-1", "new@example.com", "New User")
+	if !errors.Is(err, ErrUserNotActive) {
+		t.Fatalf("FindOrCreateOIDCUser() error = %v, want ErrUserNotActive", err)
+	}
+
+	user, ok := mockRepo.UsersByOIDC["oidc-sub-1"]
+	if !ok {
+		t.Fatal("FindOrCreateOIDCUser() did not create the user")
+	}
+	if mockRepo.Statuses[user.ID] != models.UserStatusPending {
+		t.Fatalf("status = %q, want pending", mockRepo.Statuses[user.ID])
+	}
+
+	// Once approved, the same subject signs in as the same user
+	mockRepo.Statuses[user.ID] = models.UserStatusActive
+	resp, err := service.FindOrCreateOIDCUser(context.Background(), "oidc-sub-1", "new@example.com", "New User")
+	if err != nil {
+		t.Fatalf("FindOrCreateOIDCUser() after approval error = %v", err)
+	}
+	if resp.User.ID != user.ID {
+		t.Fatal("FindOrCreateOIDCUser() should return the existing user")
+	}
+}
+
+func TestFindOrCreateOIDCUser_FirstUserBecomesActiveAdmin(t *testing.T) {
+	cfg := newTestConfig()
+	mockRepo := repository.NewMockUserRepository()
+	roleRepo := repository.NewMockRoleRepository()
+	adminRole, _ := roleRepo.Create(context.Background(), "admin", "")
+	roleRepo.UserCount = 1
+	service := NewAuthService(cfg, mockRepo, roleRepo, nil)
+
+	resp, err := service.FindOrCreateOIDCUser(context.Background(), "oidc-sub-1", "first@example.com", "First User")
+	if err != nil {
+		t.Fatalf("FindOrCreateOIDCUser() error = %v", err)
+	}
+	if resp.Token == "" {
+		t.Fatal("FindOrCreateOIDCUser() returned empty token")
+	}
+	if roles := roleRepo.UserRoles[resp.User.ID]; len(roles) != 1 || roles[0] != adminRole.ID {
+		t.Fatalf("roles = %v, want admin", roles)
+	}
+}
+
+func newTestAuthServiceWithRefresh(t *testing.T) (*AuthService, *repository.MockUserRepository, *repository.MockRefreshTokenRepository, *models.AuthResponse) {
+	t.Helper()
+	cfg := newTestConfig()
+	cfg.JWT.RefreshExpireDays = 30
+	mockRepo := repository.NewMockUserRepository()
+	refreshRepo := repository.NewMockRefreshTokenRepository()
+	service := NewAuthService(cfg, mockRepo, nil, refreshRepo)
+
+	resp, err := service.Register(context.Background(), &models.RegisterRequest{
+		Email:    "refresh@example.com",
+		Password: "password123",
+		Name:     "Refresh User",
+	})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if resp.RefreshToken == "" {
+		t.Fatal("Register() returned empty refresh token")
+	}
+	return service, mockRepo, refreshRepo, resp
+}
+
+func TestRefresh_RotatesToken(t *testing.T) {
+	service, _, refreshRepo, login := newTestAuthServiceWithRefresh(t)
+
+	if _, ok := refreshRepo.Tokens[login.RefreshToken]; ok {
+		t.Fatal("refresh token stored in plain text, want hash")
+	}
+
+	resp, err := service.Refresh(context.Background(), login.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if resp.Token == "" || resp.RefreshToken == "" {
+		t.Fatal("Refresh() returned empty tokens")
+	}
+	if resp.RefreshToken == login.RefreshToken {
+		t.Fatal("Refresh() did not rotate the refresh token")
+	}
+
+	// The old refresh token is single-use
+	if _, err := service.Refresh(context.Background(), login.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh() with used token error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+}
+
+func TestRefresh_Expired(t *testing.T) {
+	service, _, refreshRepo, login := newTestAuthServiceWithRefresh(t)
+
+	for _, token := range refreshRepo.Tokens {
+		token.ExpiresAt = time.Now().Add(-time.Minute)
+	}
+
+	if _, err := service.Refresh(context.Background(), login.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh() error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+}
+
+func TestConsumeRefreshToken_ConcurrentReuse(t *testing.T) {
+	pool := setupHistoryDatabase(t)
+	ctx := context.Background()
+
+	var userID uuid.UUID
+	err := pool.QueryRow(ctx,
+		`INSERT INTO users (email, name) VALUES ($1, 'refresh test') RETURNING id`,
+		fmt.Sprintf("refresh-%s@example.com", uuid.NewString()),
+	).Scan(&userID)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() { _, _ = pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID) })
+
+	repo := repository.NewPostgresRefreshTokenRepository(pool)
+	hash := hashRefreshToken(uuid.NewString())
+	session := models.Session{ID: uuid.New(), StartedAt: time.Now()}
+	if err := repo.Create(ctx, userID, hash, 0, session, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	const attempts = 8
+	var wg sync.WaitGroup
+	results := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := repo.ConsumeByHash(ctx, hash)
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	consumed := 0
+	for err := range results {
+		switch {
+		case err == nil:
+			consumed++
+		case !errors.Is(err, repository.ErrNotFound):
+			t.Fatalf("ConsumeByHash() error = %v", err)
+		}
+	}
+	if consumed != 1 {
+		t.Fatalf("ConsumeByHash() succeeded %d times, want 1", consumed)
+	}
+}
+
+func TestLogout_DeletesRefreshToken(t *testing.T) {
+	service, _, _, login := newTestAuthServiceWithRefresh(t)
+
+	if err := service.Logout(context.Background(), login.RefreshToken); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+	if _, err := service.Refresh(context.Background(), login.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh() after logout error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+}
+
+func TestRevokeUserSessions_InvalidatesTokens(t *testing.T) {
+	service, _, _, login := newTestAuthServiceWithRefresh(t)
+	ctx := context.Background()
+
+	if _, err := service.ValidateToken(ctx, login.Token); err != nil {
+		t.Fatalf("ValidateToken() before revocation error = %v", err)
+	}
+
+	if err := service.RevokeUserSessions(ctx, login.User.ID); err != nil {
+		t.Fatalf("RevokeUserSessions() error = %v", err)
+	}
+
+	if _, err := service.ValidateToken(ctx, login.Token); err == nil {
+		t.Fatal("ValidateToken() expected error for revoked token, got nil")
+	}
+	if _, err := service.Refresh(ctx, login.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh() after revocation error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+
+	// Tokens issued after revocation carry the new version and are accepted
+	resp, err := service.Login(ctx, &models.LoginRequest{Email: "refresh@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if _, err := service.ValidateToken(ctx, resp.Token); err != nil {
+		t.Fatalf("ValidateToken() for new token error = %v", err)
+	}
+}
+
+func TestRefresh_RejectsTokenFromBeforeRevocation(t *testing.T) {
+	service, userRepo, refreshRepo, login := newTestAuthServiceWithRefresh(t)
+
+	// A refresh token that survived revocation (e.g. inserted by a refresh racing with
+	// RevokeUserSessions) still carries the old version
+	userRepo.TokenVersions[login.User.ID]++
+	if len(refreshRepo.Tokens) != 1 {
+		t.Fatalf("stored refresh tokens = %d, want 1", len(refreshRepo.Tokens))
+	}
+
+	if _, err := service.Refresh(context.Background(), login.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh() error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+}
+
+func TestSetUserStatus_DisabledUserRejected(t *testing.T) {
+	service, _, _, login := newTestAuthServiceWithRefresh(t)
+	ctx := context.Background()
+	adminID := uuid.New()
+
+	if err := service.SetUserStatus(ctx, adminID, login.User.ID, models.UserStatusDisabled); err != nil {
+		t.Fatalf("SetUserStatus() error = %v", err)
+	}
+
+	if _, err := service.ValidateToken(ctx, login.Token); !errors.Is(err, ErrUserNotActive) {
+		t.Fatalf("ValidateToken() error = %v, want %v", err, ErrUserNotActive)
+	}
+	if _, err := service.Refresh(ctx, login.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh() error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+	if _, err := service.Login(ctx, &models.LoginRequest{Email: "refresh@example.com", Password: "password123"}); !errors.Is(err, ErrUserNotActive) {
+		t.Fatalf("Login() error = %v, want %v", err, ErrUserNotActive)
+	}
+
+	// Re-enabling restores access for the existing token
+	if err := service.SetUserStatus(ctx, adminID, login.User.ID, models.UserStatusActive); err != nil {
+		t.Fatalf("SetUserStatus() error = %v", err)
+	}
+	if _, err := service.ValidateToken(ctx, login.Token); err != nil {
+		t.Fatalf("ValidateToken() after re-enable error = %v", err)
+	}
+}
+
+func TestValidateToken_PendingUserRejected(t *testing.T) {
+	service, mockRepo, _, login := newTestAuthServiceWithRefresh(t)
+	mockRepo.Statuses[login.User.ID] = models.UserStatusPending
+
+	if _, err := service.ValidateToken(context.Background(), login.Token); !errors.Is(err, ErrUserNotActive) {
+		t.Fatalf("ValidateToken() error = %v, want %v", err, ErrUserNotActive)
+	}
+}
+
+func TestSetUserStatus_Self(t *testing.T) {
+	service, _, _, login := newTestAuthServiceWithRefresh(t)
+
+	err := service.SetUserStatus(context.Background(), login.User.ID, login.User.ID, models.UserStatusDisabled)
+	if !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("SetUserStatus() error = %v, want %v", err, ErrInvalidRequest)
+	}
+}
+
+func TestApproveUsers_ReportsEachUser(t *testing.T) {
+	ctx := context.Background()
+	userRepo := repository.NewMockUserRepository()
+	roleRepo := repository.NewMockRoleRepository()
+	userRepo.RoleRepo = roleRepo
+	service := NewAuthService(newTestConfig(), userRepo, roleRepo, nil)
+
+	role := &models.Role{ID: uuid.New(), Name: "analyst"}
+	roleRepo.Roles[role.ID] = role
+
+	adminID, pendingID, activeID, disabledID, missingID := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	for _, id := range []uuid.UUID{adminID, pendingID, activeID, disabledID} {
+		userRepo.AddUser(&models.User{ID: id, AuthProvider: "oidc"})
+	}
+	userRepo.Statuses[pendingID] = models.UserStatusPending
+	userRepo.Statuses[disabledID] = models.UserStatusDisabled
+
+	resp, err := service.ApproveUsers(ctx, adminID,
+		[]uuid.UUID{pendingID, activeID, disabledID, missingID, adminID, pendingID}, &role.ID)
+	if err != nil {
+		t.Fatalf("ApproveUsers() error = %v", err)
+	}
+
+	want := []models.UserApprovalResult{
+		{UserID: pendingID, Outcome: models.UserApprovalApproved, Status: models.UserStatusActive},
+		{UserID: activeID, Outcome: models.UserApprovalNotPending, Status: models.UserStatusActive},
+		{UserID: disabledID, Outcome: models.UserApprovalNotPending, Status: models.UserStatusDisabled},
+		{UserID: missingID, Outcome: models.UserApprovalNotFound},
+		{UserID: adminID, Outcome: models.UserApprovalSelf, Status: models.UserStatusActive},
+	}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("ApproveUsers() results = %+v, want %+v", resp.Results, want)
+	}
+	for i := range want {
+		if resp.Results[i] != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, resp.Results[i], want[i])
+		}
+	}
+	if resp.Approved != 1 {
+		t.Errorf("Approved = %d, want 1", resp.Approved)
+	}
+
+	if userRepo.Statuses[pendingID] != models.UserStatusActive || userRepo.Statuses[disabledID] != models.UserStatusDisabled {
+		t.Errorf("statuses after approval = %v", userRepo.Statuses)
+	}
+	if got := roleRepo.UserRoles[pendingID]; len(got) != 1 || got[0] != role.ID {
+		t.Errorf("roles of approved user = %v, want [%s]", got, role.ID)
+	}
+	if got := roleRepo.UserRoles[activeID]; len(got) != 0 {
+		t.Errorf("roles of skipped user = %v, want none", got)
+	}
+}
+
+func TestApproveUsers_UnknownRole(t *testing.T) {
+	userRepo := repository.NewMockUserRepository()
+	service := NewAuthService(newTestConfig(), userRepo, repository.NewMockRoleRepository(), nil)
+
+	pendingID := uuid.New()
+	userRepo.AddUser(&models.User{ID: pendingID, AuthProvider: "oidc"})
+	userRepo.Statuses[pendingID] = models.UserStatusPending
+
+	roleID := uuid.New()
+	if _, err := service.ApproveUsers(context.Background(), uuid.New(), []uuid.UUID{pendingID}, &roleID); !errors.Is(err, ErrRoleNotFound) {
+		t.Fatalf("ApproveUsers() error = %v, want %v", err, ErrRoleNotFound)
+	}
+	if userRepo.Statuses[pendingID] != models.UserStatusPending {
+		t.Errorf("status = %s, want the user left pending", userRepo.Statuses[pendingID])
+	}
+}
+
+func TestChangePassword_Success(t *testing.T) {
+	service, _, _, login := newTestAuthServiceWithRefresh(t)
+	ctx := context.Background()
+
+	resp, err := service.ChangePassword(ctx, login.User.ID, "password123", "new-password")
+	if err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+
+	// Existing sessions are revoked; the returned tokens are valid
+	if _, err := service.ValidateToken(ctx, login.Token); err == nil {
+		t.Fatal("ValidateToken() expected error for token issued before the change, got nil")
+	}
+	if _, err := service.Refresh(ctx, login.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh() error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+	if _, err := service.ValidateToken(ctx, resp.Token); err != nil {
+		t.Fatalf("ValidateToken() for new token error = %v", err)
+	}
+
+	if _, err := service.Login(ctx, &models.LoginRequest{Email: "refresh@example.com", Password: "password123"}); err == nil {
+		t.Fatal("Login() with old password succeeded")
+	}
+	if _, err := service.Login(ctx, &models.LoginRequest{Email: "refresh@example.com", Password: "new-password"}); err != nil {
+		t.Fatalf("Login() with new password error = %v", err)
+	}
+}
+
+func TestChangePassword_WrongCurrentPassword(t *testing.T) {
+	service, _, _, login := newTestAuthServiceWithRefresh(t)
+
+	_, err := service.ChangePassword(context.Background(), login.User.ID, "wrong", "new-password")
+	if !errors.Is(err, ErrInvalidCurrentPassword) {
+		t.Fatalf("ChangePassword() error = %v, want %v", err, ErrInvalidCurrentPassword)
+	}
+}
+
+func TestChangePassword_TooShort(t *testing.T) {
+	service, _, _, login := newTestAuthServiceWithRefresh(t)
+
+	if _, err := service.ChangePassword(context.Background(), login.User.ID, "password123", "short"); !errors.Is(err, ErrPasswordTooShort) {
+		t.Fatalf("ChangePassword() error = %v, want %v", err, ErrPasswordTooShort)
+	}
+
+	// MITSUME_ADMIN_PASSWORD_MIN_LENGTH raises the minimum
+	service.cfg.Admin.PasswordMinLength = 16
+	if _, err := service.ChangePassword(context.Background(), login.User.ID, "password123", "new-password"); !errors.Is(err, ErrPasswordTooShort) {
+		t.Fatalf("ChangePassword() error = %v, want %v", err, ErrPasswordTooShort)
+	}
+}
+
+func TestChangePassword_GoogleUser(t *testing.T) {
+	cfg := newTestConfig()
+	mockRepo := repository.NewMockUserRepository()
+	service := NewAuthService(cfg, mockRepo, nil, nil)
+
+	resp, err := service.FindOrCreateGoogleUser(context.Background(), "google-123", "google@example.com", "Google User")
+	if err != nil {
+		t.Fatalf("FindOrCreateGoogleUser() error = %v", err)
+	}
+
+	_, err = service.ChangePassword(context.Background(), resp.User.ID, "", "new-password")
+	if !errors.Is(err, ErrNoLocalPassword) {
+		t.Fatalf("ChangePassword() error = %v, want %v", err, ErrNoLocalPassword)
+	}
+}
+
+func TestResetPassword_Success(t *testing.T) {
+	service, _, _, login := newTestAuthServiceWithRefresh(t)
+	ctx := context.Background()
+	service.cfg.Admin.PasswordMinLength = 20
+
+	password, err := service.ResetPassword(ctx, uuid.New(), login.User.ID)
+	if err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+	if len(password) != 20 {
+		t.Fatalf("ResetPassword() password length = %d, want the configured minimum 20", len(password))
+	}
+
+	// Existing sessions are revoked
+	if _, err := service.ValidateToken(ctx, login.Token); err == nil {
+		t.Fatal("ValidateToken() expected error for token issued before the reset, got nil")
+	}
+	if _, err := service.Refresh(ctx, login.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("Refresh() error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+
+	if _, err := service.Login(ctx, &models.LoginRequest{Email: "refresh@example.com", Password: "password123"}); err == nil {
+		t.Fatal("Login() with old password succeeded")
+	}
+	if _, err := service.Login(ctx, &models.LoginRequest{Email: "refresh@example.com", Password: password}); err != nil {
+		t.Fatalf("Login() with temporary password error = %v", err)
+	}
+}
+
+func TestResetPassword_Rejected(t *testing.T) {
+	service, _, _, login := newTestAuthServiceWithRefresh(t)
+	ctx := context.Background()
+
+	if _, err := service.ResetPassword(ctx, login.User.ID, login.User.ID); !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("ResetPassword() of own account error = %v, want %v", err, ErrInvalidRequest)
+	}
+	if _, err := service.ResetPassword(ctx, uuid.New(), uuid.New()); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("ResetPassword() of unknown user error = %v, want %v", err, ErrNotFound)
+	}
+
+	google, err := service.FindOrCreateGoogleUser(ctx, "google-123", "google@example.com", "Google User")
+	if err != nil {
+		t.Fatalf("FindOrCreateGoogleUser() error = %v", err)
+	}
+	if _, err := service.ResetPassword(ctx, uuid.New(), google.User.ID); !errors.Is(err, ErrNoLocalPassword) {
+		t.Fatalf("ResetPassword() of Google user error = %v, want %v", err, ErrNoLocalPassword)
+	}
+}
+
+func TestRefresh_SessionLifetimeExceeded(t *testing.T) {
+	service, _, refreshRepo, login := newTestAuthServiceWithRefresh(t)
+	service.cfg.JWT.SessionMaxLifetimeHours = 12
+
+	for _, token := range refreshRepo.Tokens {
+		if token.Session == nil {
+			t.Fatal("refresh token has no session")
+		}
+		token.Session.StartedAt = time.Now().Add(-13 * time.Hour)
+	}
+
+	if _, err := service.Refresh(context.Background(), login.RefreshToken); !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("Refresh() error = %v, want %v", err, ErrSessionExpired)
+	}
+}
+
+func TestRefresh_KeepsSession(t *testing.T) {
+	service, _, refreshRepo, login := newTestAuthServiceWithRefresh(t)
+
+	var session models.Session
+	for _, token := range refreshRepo.Tokens {
+		session = *token.Session
+	}
+
+	if _, err := service.Refresh(context.Background(), login.RefreshToken); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	for _, token := range refreshRepo.Tokens {
+		if token.Session == nil || *token.Session != session {
+			t.Fatalf("refreshed token session = %v, want %v", token.Session, session)
+		}
+	}
+}
+
+func TestValidateToken_SessionLifetimeExceeded(t *testing.T) {
+	service, _, _, login := newTestAuthServiceWithRefresh(t)
+	service.cfg.JWT.SessionMaxLifetimeHours = 12
+
+	session := &models.Session{ID: uuid.New(), StartedAt: time.Now().Add(-13 * time.Hour)}
+	token, err := service.generateSessionToken(login.User.ID, 0, session)
+	if err != nil {
+		t.Fatalf("generateSessionToken() error = %v", err)
+	}
+
+	// The token expires with its session
+	if _, err := service.ValidateToken(context.Background(), token); err == nil {
+		t.Fatal("ValidateToken() expected error, got nil")
+	}
+}
+
+func TestValidateToken_IdleTimeout(t *testing.T) {
+	service, _, _, _ := newTestAuthServiceWithRefresh(t)
+	service.cfg.JWT.SessionIdleTimeoutMinutes = 30
+	now := time.Now()
+	tracker := NewMemorySessionTracker()
+	tracker.now = func() time.Time { return now }
+	service.SetSessionTracker(tracker)
+	ctx := context.Background()
+
+	login, err := service.Login(ctx, &models.LoginRequest{Email: "refresh@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	now = now.Add(20 * time.Minute)
+	if _, err := service.ValidateToken(ctx, login.Token); err != nil {
+		t.Fatalf("ValidateToken() within idle timeout error = %v", err)
+	}
+
+	now = now.Add(31 * time.Minute)
+	if _, err := service.ValidateToken(ctx, login.Token); !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("ValidateToken() after idle timeout error = %v, want %v", err, ErrSessionExpired)
+	}
+	if _, err := service.Refresh(ctx, login.RefreshToken); !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("Refresh() after idle timeout error = %v, want %v", err, ErrSessionExpired)
+	}
+}
+
+func TestLogout_EndsSession(t *testing.T) {
+	service, _, _, _ := newTestAuthServiceWithRefresh(t)
+	service.cfg.JWT.SessionIdleTimeoutMinutes = 30
+	service.SetSessionTracker(NewMemorySessionTracker())
+	ctx := context.Background()
+
+	login, err := service.Login(ctx, &models.LoginRequest{Email: "refresh@example.com", Password: "password123"})
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+	if err := service.Logout(ctx, login.RefreshToken); err != nil {
+		t.Fatalf("Logout() error = %v", err)
+	}
+	if _, err := service.ValidateToken(ctx, login.Token); !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("ValidateToken() after logout error = %v, want %v", err, ErrSessionExpired)
+	}
+}
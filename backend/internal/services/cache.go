@@ -7,7 +7,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -25,7 +24,8 @@ const (
 	CachePriorityHigh   CachePriority = 3 // Scheduled queries (long TTL)
 )
 
-// TTL returns the TTL duration based on the cache priority
+// TTL returns the TTL duration based on the cache priority. A TTL of 0 or less disables
+// caching for that priority: results are neither read from nor written to the cache.
 func (p CachePriority) TTL(cfg *config.CacheConfig) time.Duration {
 	switch p {
 	case CachePriorityHigh:
@@ -78,22 +78,12 @@ func NewQueryCacheService(cfg *config.CacheConfig) (*QueryCacheService, error) {
 	}, nil
 }
 
-// GenerateCacheKey creates a cache key from query parameters
-func GenerateCacheKey(prefix, queryText, catalog, schema string, params map[string]string) string {
-	// Sort parameters for consistent key generation
-	var paramStr string
-	if len(params) > 0 {
-		keys := make([]string, 0, len(params))
-		for k := range params {
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
-		for _, k := range keys {
-			paramStr += k + "=" + params[k] + "&"
-		}
-	}
-
-	data := queryText + "|" + catalog + "|" + schema + "|" + paramStr
+// GenerateCacheKey creates a cache key from the fully resolved query text and the session
+// catalog/schema. Dashboard parameters are already substituted into queryText, so each set of
+// parameter values gets its own key. Fields are length-prefixed so that separators inside the
+// query text cannot make two different (query, catalog, schema) triples collide.
+func GenerateCacheKey(prefix, queryText, catalog, schema string) string {
+	data := fmt.Sprintf("%d:%s|%d:%s|%d:%s", len(queryText), queryText, len(catalog), catalog, len(schema), schema)
 	hash := sha256.Sum256([]byte(data))
 	return prefix + "query:" + hex.EncodeToString(hash[:])
 }
@@ -105,6 +95,12 @@ func (s *QueryCacheService) savedQueryKeySetKey(savedQueryID uuid.UUID) string {
 
 // Get retrieves a cached query result
 func (s *QueryCacheService) Get(ctx context.Context, key string) (*models.QueryResult, bool) {
+	return s.GetFresh(ctx, key, 0)
+}
+
+// GetFresh retrieves a cached query result computed no longer than maxStaleness ago.
+// A maxStaleness of 0 accepts any cached result. Stale entries count as a miss.
+func (s *QueryCacheService) GetFresh(ctx context.Context, key string, maxStaleness time.Duration) (*models.QueryResult, bool) {
 	data, err := s.client.Get(ctx, key).Bytes()
 	if err != nil {
 		if err != redis.Nil {
@@ -119,9 +115,47 @@ func (s *QueryCacheService) Get(ctx context.Context, key string) (*models.QueryR
 		return nil, false
 	}
 
+	if !isCacheFresh(cached.CachedAt, maxStaleness, time.Now()) {
+		return nil, false
+	}
+
 	return cached.QueryResult, true
 }
 
+// GetCachedAt returns when the result under key was cached, without returning the result itself.
+// Like GetFresh, entries older than maxStaleness (0 means no limit) count as a miss.
+func (s *QueryCacheService) GetCachedAt(ctx context.Context, key string, maxStaleness time.Duration) (time.Time, bool) {
+	data, err := s.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("Cache get error for key %s: %v", key, err)
+		}
+		return time.Time{}, false
+	}
+
+	var cached struct {
+		CachedAt time.Time `json:"cached_at"`
+	}
+	if err := json.Unmarshal(data, &cached); err != nil {
+		log.Printf("Cache unmarshal error for key %s: %v", key, err)
+		return time.Time{}, false
+	}
+
+	if !isCacheFresh(cached.CachedAt, maxStaleness, time.Now()) {
+		return time.Time{}, false
+	}
+
+	return cached.CachedAt, true
+}
+
+// isCacheFresh reports whether a result cached at cachedAt satisfies maxStaleness (0 means no limit)
+func isCacheFresh(cachedAt time.Time, maxStaleness time.Duration, now time.Time) bool {
+	if maxStaleness <= 0 {
+		return true
+	}
+	return now.Sub(cachedAt) <= maxStaleness
+}
+
 // Set stores a query result in the cache with the specified priority
 func (s *QueryCacheService) Set(ctx context.Context, key string, result *models.QueryResult, priority CachePriority) {
 	cached := CachedQueryResult{
@@ -135,7 +169,11 @@ func (s *QueryCacheService) Set(ctx context.Context, key string, result *models.
 		return
 	}
 
+	// Redis treats a zero TTL as "never expire"; a zero TTL means "don't cache" here
 	ttl := priority.TTL(s.cfg)
+	if ttl <= 0 {
+		return
+	}
 	if err := s.client.Set(ctx, key, data, ttl).Err(); err != nil {
 		log.Printf("Cache set error for key %s: %v", key, err)
 	}
@@ -158,7 +196,7 @@ func (s *QueryCacheService) RegisterSavedQueryCache(ctx context.Context, savedQu
 
 	// Set expiration on the set (longer than any individual cache TTL)
 	// This ensures the set gets cleaned up eventually
-	maxTTL := time.Duration(s.cfg.TTLHighSeconds*2) * time.Second
+	maxTTL := 2 * max(CachePriorityHigh.TTL(s.cfg), CachePriorityNormal.TTL(s.cfg), CachePriorityLow.TTL(s.cfg))
 	if err := s.client.Expire(ctx, setKey, maxTTL).Err(); err != nil {
 		log.Printf("Failed to set expiration on saved query key set: %v", err)
 	}
@@ -197,6 +235,48 @@ func (s *QueryCacheService) InvalidateSavedQueryCaches(ctx context.Context, save
 	return nil
 }
 
+// userAuthStateKey returns the Redis key caching a user's token version and status
+func (s *QueryCacheService) userAuthStateKey(userID uuid.UUID) string {
+	return s.cfg.KeyPrefix + "user_auth:" + userID.String()
+}
+
+// GetUserAuthState retrieves a cached user auth state
+func (s *QueryCacheService) GetUserAuthState(ctx context.Context, userID uuid.UUID) (*models.UserAuthState, bool) {
+	key := s.userAuthStateKey(userID)
+	data, err := s.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("Cache get error for key %s: %v", key, err)
+		}
+		return nil, false
+	}
+
+	var state models.UserAuthState
+	if err := json.Unmarshal(data, &state); err != nil {
+		log.Printf("Cache unmarshal error for key %s: %v", key, err)
+		return nil, false
+	}
+	return &state, true
+}
+
+// SetUserAuthState caches a user auth state for the given TTL
+func (s *QueryCacheService) SetUserAuthState(ctx context.Context, userID uuid.UUID, state *models.UserAuthState, ttl time.Duration) {
+	key := s.userAuthStateKey(userID)
+	data, err := json.Marshal(state)
+	if err != nil {
+		log.Printf("Cache marshal error for key %s: %v", key, err)
+		return
+	}
+	if err := s.client.Set(ctx, key, data, ttl).Err(); err != nil {
+		log.Printf("Cache set error for key %s: %v", key, err)
+	}
+}
+
+// InvalidateUserAuthState removes a cached user auth state so the next request reads it from the database
+func (s *QueryCacheService) InvalidateUserAuthState(ctx context.Context, userID uuid.UUID) error {
+	return s.client.Del(ctx, s.userAuthStateKey(userID)).Err()
+}
+
 // Close closes the Redis connection
 func (s *QueryCacheService) Close() error {
 	if s.client != nil {
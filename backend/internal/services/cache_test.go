@@ -0,0 +1,54 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGenerateCacheKey(t *testing.T) {
+	base := GenerateCacheKey("mitsume:", "SELECT * FROM t WHERE region = 'emea'", "hive", "sales")
+
+	if got := GenerateCacheKey("mitsume:", "SELECT * FROM t WHERE region = 'emea'", "hive", "sales"); got != base {
+		t.Fatalf("GenerateCacheKey() is not stable: %q != %q", got, base)
+	}
+
+	distinct := map[string][3]string{
+		"other parameter value": {"SELECT * FROM t WHERE region = 'apac'", "hive", "sales"},
+		"other catalog":         {"SELECT * FROM t WHERE region = 'emea'", "iceberg", "sales"},
+		"other schema":          {"SELECT * FROM t WHERE region = 'emea'", "hive", "finance"},
+	}
+	for name, args := range distinct {
+		if GenerateCacheKey("mitsume:", args[0], args[1], args[2]) == base {
+			t.Errorf("GenerateCacheKey() for %s = base key, want a distinct key", name)
+		}
+	}
+
+	// A separator inside the query text must not shift into the catalog or schema
+	if GenerateCacheKey("p:", "SELECT 1|a", "b", "c") == GenerateCacheKey("p:", "SELECT 1", "a|b", "c") {
+		t.Fatal("GenerateCacheKey() collides when a field contains the separator")
+	}
+}
+
+func TestIsCacheFresh(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		cachedAt     time.Time
+		maxStaleness time.Duration
+		want         bool
+	}{
+		{"no limit", now.Add(-24 * time.Hour), 0, true},
+		{"within limit", now.Add(-30 * time.Second), time.Minute, true},
+		{"at limit", now.Add(-time.Minute), time.Minute, true},
+		{"too stale", now.Add(-61 * time.Second), time.Minute, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isCacheFresh(tt.cachedAt, tt.maxStaleness, now); got != tt.want {
+				t.Errorf("isCacheFresh() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
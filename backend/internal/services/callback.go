@@ -0,0 +1,235 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitsume/backend/internal/config"
+)
+
+const (
+	// CallbackSignatureHeader carries the HMAC-SHA256 signature of "<timestamp>.<body>"
+	CallbackSignatureHeader = "X-Mitsume-Signature"
+	// CallbackTimestampHeader carries the Unix time the callback was signed at, so receivers
+	// can reject replayed deliveries
+	CallbackTimestampHeader = "X-Mitsume-Timestamp"
+)
+
+var (
+	// ErrCallbacksDisabled is returned for callback URLs when WEBHOOK_SECRET is not set,
+	// since receivers could not verify unsigned callbacks
+	ErrCallbacksDisabled = errors.New("callbacks are disabled: WEBHOOK_SECRET is not set")
+	// ErrCallbackHostNotAllowed is returned for callback URLs that resolve to a loopback,
+	// private or link-local address, or whose host is not in WEBHOOK_ALLOWED_HOSTS
+	ErrCallbackHostNotAllowed = errors.New("callback host is not allowed")
+)
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which net.IP does not classify as private
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// CallbackService delivers signed webhook callbacks with retry and backoff
+type CallbackService struct {
+	client       *http.Client
+	secret       []byte
+	maxRetries   int
+	backoff      time.Duration
+	allowedHosts map[string]bool // when non-empty, the only hosts callbacks may go to
+	lookupIP     func(ctx context.Context, host string) ([]net.IP, error)
+	now          func() time.Time
+}
+
+// NewCallbackService creates a new callback service
+func NewCallbackService(cfg *config.WebhookConfig) *CallbackService {
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	maxRetries := cfg.MaxRetries
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	s := &CallbackService{
+		secret:       []byte(cfg.Secret),
+		maxRetries:   maxRetries,
+		backoff:      time.Duration(cfg.RetryBackoffMillis) * time.Millisecond,
+		allowedHosts: make(map[string]bool, len(cfg.AllowedHosts)),
+		lookupIP: func(ctx context.Context, host string) ([]net.IP, error) {
+			return net.DefaultResolver.LookupIP(ctx, "ip", host)
+		},
+		now: time.Now,
+	}
+	for _, host := range cfg.AllowedHosts {
+		s.allowedHosts[strings.ToLower(host)] = true
+	}
+	// Every connection re-checks the address it dials, so a host cannot pass CheckURL and
+	// then resolve to an internal address at delivery time
+	dialer := &net.Dialer{Timeout: timeout}
+	s.client = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				host, port, err := net.SplitHostPort(addr)
+				if err != nil {
+					return nil, err
+				}
+				ip, err := s.resolveHost(ctx, host)
+				if err != nil {
+					return nil, err
+				}
+				return dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			},
+			TLSHandshakeTimeout: timeout,
+		},
+		// Redirects could point a permitted host at an internal one
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return s
+}
+
+// ValidateCallbackURL checks that a callback URL is an absolute http(s) URL
+func ValidateCallbackURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid callback_url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid callback_url: scheme must be http or https")
+	}
+	if u.Host == "" || u.Hostname() == "" {
+		return fmt.Errorf("invalid callback_url: host is required")
+	}
+	return nil
+}
+
+// CheckURL validates a callback URL and checks that callbacks may be sent to it: a secret
+// must be configured, and the host must be in WEBHOOK_ALLOWED_HOSTS when that is set, or
+// otherwise resolve only to public addresses
+func (s *CallbackService) CheckURL(ctx context.Context, rawURL string) error {
+	if len(s.secret) == 0 {
+		return ErrCallbacksDisabled
+	}
+	if err := ValidateCallbackURL(rawURL); err != nil {
+		return err
+	}
+	u, _ := url.Parse(rawURL)
+	_, err := s.resolveHost(ctx, u.Hostname())
+	return err
+}
+
+// resolveHost returns the address to connect to for host. Allowlisted hosts resolve normally;
+// without an allowlist every address of host must be public.
+func (s *CallbackService) resolveHost(ctx context.Context, host string) (net.IP, error) {
+	host = strings.ToLower(host)
+	if len(s.allowedHosts) > 0 && !s.allowedHosts[host] {
+		return nil, fmt.Errorf("%w: %s is not in WEBHOOK_ALLOWED_HOSTS", ErrCallbackHostNotAllowed, host)
+	}
+
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		var err error
+		if ips, err = s.lookupIP(ctx, host); err != nil {
+			return nil, fmt.Errorf("failed to resolve callback host %s: %w", host, err)
+		}
+		if len(ips) == 0 {
+			return nil, fmt.Errorf("failed to resolve callback host %s: no addresses", host)
+		}
+	}
+	if len(s.allowedHosts) == 0 {
+		for _, ip := range ips {
+			if !isPublicIP(ip) {
+				return nil, fmt.Errorf("%w: %s resolves to internal address %s", ErrCallbackHostNotAllowed, host, ip)
+			}
+		}
+	}
+	return ips[0], nil
+}
+
+// isPublicIP reports whether ip is outside the loopback, private, link-local, shared,
+// unspecified and multicast ranges
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified() && !sharedAddressSpace.Contains(ip)
+}
+
+// SignPayload returns the hex-encoded HMAC-SHA256 of "<timestamp>.<body>" using the configured secret
+func (s *CallbackService) SignPayload(timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Deliver POSTs payload as JSON to callbackURL, retrying failed attempts with
+// exponential backoff. Any 2xx response is treated as success; redirects are not followed.
+func (s *CallbackService) Deliver(ctx context.Context, callbackURL string, payload interface{}) error {
+	if len(s.secret) == 0 {
+		return ErrCallbacksDisabled
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal callback payload: %w", err)
+	}
+
+	var lastErr error
+	delay := s.backoff
+	for attempt := 0; attempt <= s.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("callback delivery cancelled: %w", ctx.Err())
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+
+		lastErr = s.post(ctx, callbackURL, body)
+		if lastErr == nil {
+			return nil
+		}
+		if errors.Is(lastErr, ErrCallbackHostNotAllowed) {
+			break
+		}
+	}
+
+	return fmt.Errorf("callback delivery failed: %w", lastErr)
+}
+
+func (s *CallbackService) post(ctx context.Context, callbackURL string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	// Each attempt is signed afresh so its timestamp is current
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(CallbackTimestampHeader, timestamp)
+	req.Header.Set(CallbackSignatureHeader, s.SignPayload(timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send callback: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("callback returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mitsume/backend/internal/config"
+)
+
+func TestCallbackDeliverSignsPayload(t *testing.T) {
+	secret := "test-secret"
+	var gotSignature, gotTimestamp string
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSignature = r.Header.Get(CallbackSignatureHeader)
+		gotTimestamp = r.Header.Get(CallbackTimestampHeader)
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	svc := NewCallbackService(&config.WebhookConfig{Secret: secret, MaxRetries: 0, AllowedHosts: []string{"127.0.0.1"}})
+	if err := svc.Deliver(context.Background(), srv.URL, map[string]string{"status": "succeeded"}); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+
+	if gotTimestamp == "" {
+		t.Fatalf("%s header is missing", CallbackTimestampHeader)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(gotTimestamp + "."))
+	mac.Write(gotBody)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if gotSignature != want {
+		t.Fatalf("signature = %s, want %s", gotSignature, want)
+	}
+}
+
+func TestCallbackDeliverRetries(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	svc := NewCallbackService(&config.WebhookConfig{Secret: "s", MaxRetries: 3, RetryBackoffMillis: 1, AllowedHosts: []string{"127.0.0.1"}})
+	if err := svc.Deliver(context.Background(), srv.URL, map[string]string{}); err != nil {
+		t.Fatalf("Deliver() error = %v", err)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Fatalf("attempts = %d, want 3", got)
+	}
+}
+
+func TestCallbackDeliverGivesUp(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	svc := NewCallbackService(&config.WebhookConfig{Secret: "s", MaxRetries: 2, RetryBackoffMillis: 1, AllowedHosts: []string{"127.0.0.1"}})
+	if err := svc.Deliver(context.Background(), srv.URL, map[string]string{}); err == nil {
+		t.Fatalf("Deliver() error = nil, want error")
+	}
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Fatalf("attempts = %d, want 3", got)
+	}
+}
+
+func TestValidateCallbackURL(t *testing.T) {
+	cases := []struct {
+		url       string
+		wantError bool
+	}{
+		{"https://example.com/hook", false},
+		{"ftp://example.com/hook", true},
+		{"/relative/path", true},
+		{"https://", true},
+	}
+	for _, tc := range cases {
+		err := ValidateCallbackURL(tc.url)
+		if (err != nil) != tc.wantError {
+			t.Fatalf("ValidateCallbackURL(%q) error = %v, wantError %v", tc.url, err, tc.wantError)
+		}
+	}
+}
+
+func TestCallbackCheckURL(t *testing.T) {
+	svc := NewCallbackService(&config.WebhookConfig{Secret: "s"})
+	svc.lookupIP = func(ctx context.Context, host string) ([]net.IP, error) {
+		switch host {
+		case "hooks.example.com":
+			return []net.IP{net.ParseIP("93.184.216.34")}, nil
+		case "internal.example.com":
+			return []net.IP{net.ParseIP("93.184.216.34"), net.ParseIP("10.0.0.5")}, nil
+		case "localhost":
+			return []net.IP{net.ParseIP("127.0.0.1")}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	cases := []struct {
+		url        string
+		wantDenied bool
+	}{
+		{"https://hooks.example.com/cb", false},
+		{"https://93.184.216.34/cb", false},
+		{"http://localhost:9000/cb", true},
+		{"http://127.0.0.1:9000/cb", true},
+		{"http://[::1]/cb", true},
+		{"http://10.1.2.3/cb", true},
+		{"http://192.168.0.1/cb", true},
+		{"http://169.254.169.254/latest/meta-data", true},
+		{"http://100.64.0.1/cb", true},
+		{"http://0.0.0.0/cb", true},
+		{"https://internal.example.com/cb", true},
+	}
+	for _, tc := range cases {
+		err := svc.CheckURL(context.Background(), tc.url)
+		if got := errors.Is(err, ErrCallbackHostNotAllowed); got != tc.wantDenied {
+			t.Errorf("CheckURL(%q) error = %v, want denied %v", tc.url, err, tc.wantDenied)
+		}
+		if !tc.wantDenied && err != nil {
+			t.Errorf("CheckURL(%q) error = %v, want nil", tc.url, err)
+		}
+	}
+}
+
+func TestCallbackCheckURLAllowlist(t *testing.T) {
+	svc := NewCallbackService(&config.WebhookConfig{Secret: "s", AllowedHosts: []string{"Hooks.Internal"}})
+	svc.lookupIP = func(ctx context.Context, host string) ([]net.IP, error) {
+		return []net.IP{net.ParseIP("10.0.0.5")}, nil
+	}
+
+	if err := svc.CheckURL(context.Background(), "https://hooks.internal/cb"); err != nil {
+		t.Fatalf("CheckURL(allowlisted) error = %v, want nil", err)
+	}
+	if err := svc.CheckURL(context.Background(), "https://93.184.216.34/cb"); !errors.Is(err, ErrCallbackHostNotAllowed) {
+		t.Fatalf("CheckURL(not allowlisted) error = %v, want ErrCallbackHostNotAllowed", err)
+	}
+}
+
+func TestCallbackRequiresSecret(t *testing.T) {
+	svc := NewCallbackService(&config.WebhookConfig{AllowedHosts: []string{"127.0.0.1"}})
+	if err := svc.CheckURL(context.Background(), "http://127.0.0.1/cb"); !errors.Is(err, ErrCallbacksDisabled) {
+		t.Fatalf("CheckURL() error = %v, want ErrCallbacksDisabled", err)
+	}
+	if err := svc.Deliver(context.Background(), "http://127.0.0.1/cb", map[string]string{}); !errors.Is(err, ErrCallbacksDisabled) {
+		t.Fatalf("Deliver() error = %v, want ErrCallbacksDisabled", err)
+	}
+}
+
+func TestCallbackDeliverRefusesInternalAddress(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	// No allowlist: the loopback test server must be refused at dial time
+	svc := NewCallbackService(&config.WebhookConfig{Secret: "s", MaxRetries: 2, RetryBackoffMillis: 1})
+	err := svc.Deliver(context.Background(), srv.URL, map[string]string{})
+	if !errors.Is(err, ErrCallbackHostNotAllowed) {
+		t.Fatalf("Deliver() error = %v, want ErrCallbackHostNotAllowed", err)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 0 {
+		t.Fatalf("attempts = %d, want 0", got)
+	}
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// ConcurrencyLimiter bounds how many operations identified by the same key run at once
+type ConcurrencyLimiter interface {
+	// Acquire takes one of limit slots for key. When it succeeds, release must be called once
+	// the operation ends. A limit of 0 or less always succeeds.
+	Acquire(ctx context.Context, key string, limit int) (release func(), acquired bool, err error)
+}
+
+// concurrencySlotLease is how long a slot is held at most, so slots held by a crashed
+// instance (or a release that failed) are eventually given back
+const concurrencySlotLease = time.Hour
+
+// NewConcurrencyLimiter returns a Redis-backed limiter when the cache is enabled, so slots are
+// shared across instances, and an in-memory limiter otherwise
+func NewConcurrencyLimiter(cache *QueryCacheService) ConcurrencyLimiter {
+	if cache != nil {
+		return &RedisConcurrencyLimiter{client: cache.client, prefix: cache.cfg.KeyPrefix + "concurrency:", lease: concurrencySlotLease}
+	}
+	return NewMemoryConcurrencyLimiter()
+}
+
+// MemoryConcurrencyLimiter is a per-process ConcurrencyLimiter
+type MemoryConcurrencyLimiter struct {
+	mu     sync.Mutex
+	active map[string]int
+}
+
+// NewMemoryConcurrencyLimiter creates an in-memory concurrency limiter
+func NewMemoryConcurrencyLimiter() *MemoryConcurrencyLimiter {
+	return &MemoryConcurrencyLimiter{active: make(map[string]int)}
+}
+
+// Acquire implements ConcurrencyLimiter
+func (m *MemoryConcurrencyLimiter) Acquire(ctx context.Context, key string, limit int) (func(), bool, error) {
+	if limit <= 0 {
+		return func() {}, true, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.active[key] >= limit {
+		return nil, false, nil
+	}
+	m.active[key]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if m.active[key]--; m.active[key] <= 0 {
+				delete(m.active, key)
+			}
+		})
+	}, true, nil
+}
+
+// concurrencyAcquireScript holds slots as members of a sorted set scored by their lease
+// expiry (unix ms). Expired slots are dropped before counting. Returns 1 when a slot was taken.
+var concurrencyAcquireScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local lease = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+if redis.call('ZCARD', KEYS[1]) >= limit then
+	return 0
+end
+
+redis.call('ZADD', KEYS[1], now + lease, ARGV[4])
+redis.call('PEXPIRE', KEYS[1], lease)
+return 1
+`)
+
+// RedisConcurrencyLimiter is a ConcurrencyLimiter shared by every instance using the same Redis
+type RedisConcurrencyLimiter struct {
+	client *redis.Client
+	prefix string
+	lease  time.Duration
+}
+
+// Acquire implements ConcurrencyLimiter
+func (r *RedisConcurrencyLimiter) Acquire(ctx context.Context, key string, limit int) (func(), bool, error) {
+	if limit <= 0 {
+		return func() {}, true, nil
+	}
+
+	slot := uuid.New().String()
+	res, err := concurrencyAcquireScript.Run(ctx, r.client, []string{r.prefix + key},
+		time.Now().UnixMilli(), r.lease.Milliseconds(), limit, slot,
+	).Int()
+	if err != nil {
+		return nil, false, err
+	}
+	if res != 1 {
+		return nil, false, nil
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			// The request context may already be canceled when the operation ends
+			if err := r.client.ZRem(context.Background(), r.prefix+key, slot).Err(); err != nil {
+				log.Printf("Failed to release concurrency slot for %s: %v", key, err)
+			}
+		})
+	}, true, nil
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemoryConcurrencyLimiter_ReleasesSlots(t *testing.T) {
+	limiter := NewMemoryConcurrencyLimiter()
+	ctx := context.Background()
+
+	release1, ok, _ := limiter.Acquire(ctx, "export:alice", 2)
+	if !ok {
+		t.Fatal("first Acquire() = false, want true")
+	}
+	if _, ok, _ := limiter.Acquire(ctx, "export:alice", 2); !ok {
+		t.Fatal("second Acquire() = false, want true")
+	}
+	if _, ok, _ := limiter.Acquire(ctx, "export:alice", 2); ok {
+		t.Fatal("third Acquire() = true, want false at the limit")
+	}
+	if _, ok, _ := limiter.Acquire(ctx, "export:bob", 2); !ok {
+		t.Fatal("Acquire() for another key = false, want true")
+	}
+
+	// Releasing twice gives back a single slot
+	release1()
+	release1()
+	if _, ok, _ := limiter.Acquire(ctx, "export:alice", 2); !ok {
+		t.Fatal("Acquire() after release = false, want true")
+	}
+	if _, ok, _ := limiter.Acquire(ctx, "export:alice", 2); ok {
+		t.Fatal("Acquire() = true, want false: a double release must not free two slots")
+	}
+}
+
+func TestMemoryConcurrencyLimiter_ZeroLimitDisabled(t *testing.T) {
+	limiter := NewMemoryConcurrencyLimiter()
+	for i := 0; i < 10; i++ {
+		if _, ok, _ := limiter.Acquire(context.Background(), "export:alice", 0); !ok {
+			t.Fatalf("Acquire() %d with limit 0 = false, want true", i+1)
+		}
+	}
+}
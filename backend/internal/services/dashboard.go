@@ -4,6 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"reflect"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -16,10 +19,30 @@ import (
 var (
 	ErrPermissionDenied = errors.New("permission denied")
 	ErrInvalidRequest   = errors.New("invalid request")
+	// ErrChartThemeNotFound is returned when a widget references a theme that does not exist or
+	// is neither a system theme nor one of the user's own
+	ErrChartThemeNotFound = errors.New("chart theme not found")
+	// ErrDuplicateDashboardName is returned when unique names are enforced and the owner already
+	// has a dashboard with the same name
+	ErrDuplicateDashboardName = errors.New("a dashboard with this name already exists")
+	// ErrDraftExists is returned when restoring a version would replace an unpublished draft
+	ErrDraftExists = errors.New("the dashboard already has a draft; publish or discard it first")
 )
 
 type DashboardService struct {
-	permRepo *repository.PostgresDashboardPermissionRepository
+	permRepo        *repository.PostgresDashboardPermissionRepository
+	uniqueNames     bool
+	maxVersions     int // 0 keeps every version
+	revealForbidden bool
+	admins          AdminChecker
+	ownerParameters bool             // only owners may change parameter definitions
+	statements      StatementChecker // checks imported SQL; nil accepts any statement
+}
+
+// StatementChecker rejects statements that may not be saved, such as writes in read-only mode
+// (see QueryService.CheckStatement)
+type StatementChecker interface {
+	CheckStatement(query string) error
 }
 
 func NewDashboardService() *DashboardService {
@@ -29,17 +52,115 @@ func NewDashboardService() *DashboardService {
 	}
 }
 
+// SetUniqueNames makes CreateDashboard and UpdateDashboard reject a name the owner already uses
+// for another dashboard (case-insensitive). Drafts are neither checked nor counted.
+func (s *DashboardService) SetUniqueNames(enabled bool) {
+	s.uniqueNames = enabled
+}
+
+// SetRevealForbidden makes GetDashboard fail with ErrPermissionDenied instead of ErrNotFound
+// when an admin asks for a dashboard that exists but that they may not view. Other users always
+// get ErrNotFound, so they cannot probe which dashboard IDs exist.
+func (s *DashboardService) SetRevealForbidden(enabled bool, admins AdminChecker) {
+	s.revealForbidden = enabled
+	s.admins = admins
+}
+
+// hiddenDashboardError returns the error for a dashboard that exists but userID may not view
+func (s *DashboardService) hiddenDashboardError(ctx context.Context, userID uuid.UUID) error {
+	if !s.revealForbidden || s.admins == nil {
+		return ErrNotFound
+	}
+	isAdmin, err := s.admins.IsAdmin(ctx, userID)
+	if err != nil || !isAdmin {
+		return ErrNotFound
+	}
+	return ErrPermissionDenied
+}
+
+// SetStatementChecker makes ImportDashboard reject documents whose saved queries or inline
+// widget SQL the checker rejects
+func (s *DashboardService) SetStatementChecker(checker StatementChecker) {
+	s.statements = checker
+}
+
+// SetOwnerOnlyParameters restricts changing a dashboard's parameter definitions to its owner.
+// Editors can still change everything else; UpdateDashboard and PublishDraft fail with
+// ErrPermissionDenied when an editor's change would alter the parameters.
+func (s *DashboardService) SetOwnerOnlyParameters(enabled bool) {
+	s.ownerParameters = enabled
+}
+
+// checkParameterChange fails with ErrPermissionDenied when parameter changes are restricted to
+// owners, permLevel is not the owner's and changed reports that the parameters would change
+func (s *DashboardService) checkParameterChange(permLevel models.PermissionLevel, changed func() (bool, error)) error {
+	if !s.ownerParameters || permLevel.IsOwner() {
+		return nil
+	}
+	differs, err := changed()
+	if err != nil {
+		return fmt.Errorf("failed to compare dashboard parameters: %w", err)
+	}
+	if differs {
+		return fmt.Errorf("%w: only the owner can change dashboard parameters", ErrPermissionDenied)
+	}
+	return nil
+}
+
+// parametersDiffer reports whether two parameter definition documents differ, ignoring
+// formatting and key order
+func parametersDiffer(a, b json.RawMessage) (bool, error) {
+	var va, vb interface{}
+	if err := json.Unmarshal(orEmptyParameters(a), &va); err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(orEmptyParameters(b), &vb); err != nil {
+		return false, err
+	}
+	return !reflect.DeepEqual(va, vb), nil
+}
+
+func orEmptyParameters(params json.RawMessage) json.RawMessage {
+	if len(params) == 0 || string(params) == "null" {
+		return json.RawMessage("[]")
+	}
+	return params
+}
+
+// checkUniqueName fails with ErrDuplicateDashboardName when unique names are enforced and exists
+// reports another dashboard with the name
+func (s *DashboardService) checkUniqueName(exists func() (bool, error)) error {
+	if !s.uniqueNames {
+		return nil
+	}
+	found, err := exists()
+	if err != nil {
+		return fmt.Errorf("failed to check dashboard name: %w", err)
+	}
+	if found {
+		return ErrDuplicateDashboardName
+	}
+	return nil
+}
+
 // Dashboard CRUD operations with permission checks
 
-// GetDashboards returns all dashboards accessible to the user (owned + shared + public)
-func (s *DashboardService) GetDashboards(ctx context.Context, userID uuid.UUID) ([]models.Dashboard, error) {
-	return s.permRepo.GetAccessibleDashboards(ctx, userID)
+// GetDashboards returns all dashboards accessible to the user (owned + shared + public),
+// either the archived ones or the rest
+func (s *DashboardService) GetDashboards(ctx context.Context, userID uuid.UUID, archived bool) ([]models.Dashboard, error) {
+	return s.permRepo.GetAccessibleDashboards(ctx, userID, archived)
 }
 
 // GetDashboard returns a specific dashboard if user has view permission
 func (s *DashboardService) GetDashboard(ctx context.Context, id, userID uuid.UUID) (*models.Dashboard, error) {
 	dashboard, err := s.permRepo.GetDashboardByIDWithPermission(ctx, id, userID)
 	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrForbidden):
+			return nil, s.hiddenDashboardError(ctx, userID)
+		case errors.Is(err, repository.ErrNotFound):
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
 
@@ -49,6 +170,7 @@ func (s *DashboardService) GetDashboard(ctx context.Context, id, userID uuid.UUI
 		return nil, err
 	}
 	dashboard.Widgets = widgets
+	dashboard.WidgetErrorCount = countWidgetErrors(widgets)
 
 	// Get permissions if user is owner
 	if dashboard.MyPermission.IsOwner() {
@@ -62,25 +184,77 @@ func (s *DashboardService) GetDashboard(ctx context.Context, id, userID uuid.UUI
 	return dashboard, nil
 }
 
+// SetArchived archives or restores a dashboard (owner only). Archiving only hides the dashboard
+// from the default list; it stays viewable, editable and shared. Drafts cannot be archived.
+func (s *DashboardService) SetArchived(ctx context.Context, id, userID uuid.UUID, archived bool) error {
+	permLevel, err := s.permRepo.GetUserPermissionLevel(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if !permLevel.IsOwner() {
+		return ErrPermissionDenied
+	}
+
+	pool := database.GetPool()
+
+	var name string
+	err = pool.QueryRow(ctx,
+		`UPDATE dashboards SET is_archived = $2, updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $1 AND COALESCE(is_draft, false) = false
+		 RETURNING name`,
+		id, archived,
+	).Scan(&name)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w: drafts cannot be archived", ErrInvalidRequest)
+		}
+		return err
+	}
+
+	action := models.DashboardEventArchive
+	if !archived {
+		action = models.DashboardEventUnarchive
+	}
+	_ = recordDashboardEvent(ctx, pool, id, userID, action, models.DashboardEventTargetDashboard, id, name)
+	return nil
+}
+
 func (s *DashboardService) CreateDashboard(ctx context.Context, userID uuid.UUID, req *models.CreateDashboardRequest) (*models.Dashboard, error) {
 	pool := database.GetPool()
 
+	err := s.checkUniqueName(func() (bool, error) {
+		var exists bool
+		err := pool.QueryRow(ctx,
+			`SELECT EXISTS (
+			   SELECT 1 FROM dashboards
+			   WHERE user_id = $1 AND LOWER(name) = LOWER($2) AND NOT COALESCE(is_draft, false)
+			     AND deleted_at IS NULL)`,
+			userID, req.Name,
+		).Scan(&exists)
+		return exists, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	defaultLayout, _ := json.Marshal([]interface{}{})
 	defaultParams, _ := json.Marshal([]interface{}{})
 
 	var d models.Dashboard
-	err := pool.QueryRow(ctx,
-		`INSERT INTO dashboards (user_id, name, description, layout, is_public, parameters)
-		 VALUES ($1, $2, $3, $4, false, $5)
+	err = pool.QueryRow(ctx,
+		`INSERT INTO dashboards (user_id, name, description, layout, is_public, parameters, created_by, updated_by)
+		 VALUES ($1, $2, $3, $4, false, $5, $1, $1)
 		 RETURNING id, user_id, name, description, layout, COALESCE(is_public, false), COALESCE(parameters, '[]'),
-		           COALESCE(is_draft, false), draft_of, created_at, updated_at`,
+		           COALESCE(is_draft, false), draft_of, COALESCE(is_archived, false), created_by, updated_by, created_at, updated_at`,
 		userID, req.Name, req.Description, defaultLayout, defaultParams,
 	).Scan(&d.ID, &d.UserID, &d.Name, &d.Description, &d.Layout, &d.IsPublic, &d.Parameters,
-		&d.IsDraft, &d.DraftOf, &d.CreatedAt, &d.UpdatedAt)
+		&d.IsDraft, &d.DraftOf, &d.IsArchived, &d.CreatedBy, &d.UpdatedBy, &d.CreatedAt, &d.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
 
+	_ = recordDashboardEvent(ctx, pool, d.ID, userID, models.DashboardEventCreate, models.DashboardEventTargetDashboard, d.ID, d.Name)
+
 	d.MyPermission = models.PermissionOwner
 	return &d, nil
 }
@@ -98,6 +272,47 @@ func (s *DashboardService) UpdateDashboard(ctx context.Context, id, userID uuid.
 
 	pool := database.GetPool()
 
+	if req.Timezone != nil && *req.Timezone != "" {
+		if _, err := loadSubscriptionLocation(*req.Timezone); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Parameters != nil {
+		err = s.checkParameterChange(permLevel, func() (bool, error) {
+			var current json.RawMessage
+			if err := pool.QueryRow(ctx,
+				`SELECT COALESCE(parameters, '[]') FROM dashboards WHERE id = $1`, id,
+			).Scan(&current); err != nil {
+				return false, err
+			}
+			return parametersDiffer(current, req.Parameters)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Name != "" {
+		// Compare against the owner's dashboards, since editors may rename shared dashboards
+		err = s.checkUniqueName(func() (bool, error) {
+			var exists bool
+			err := pool.QueryRow(ctx,
+				`SELECT EXISTS (
+				   SELECT 1 FROM dashboards d
+				   JOIN dashboards target ON target.id = $1 AND NOT COALESCE(target.is_draft, false)
+				   WHERE d.user_id = target.user_id AND d.id <> target.id
+				     AND LOWER(d.name) = LOWER($2) AND NOT COALESCE(d.is_draft, false)
+				     AND d.deleted_at IS NULL)`,
+				id, req.Name,
+			).Scan(&exists)
+			return exists, err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var d models.Dashboard
 	err = pool.QueryRow(ctx,
 		`UPDATE dashboards
@@ -105,13 +320,19 @@ func (s *DashboardService) UpdateDashboard(ctx context.Context, id, userID uuid.
 		     description = COALESCE($3, description),
 		     layout = COALESCE($4, layout),
 		     parameters = COALESCE($5, parameters),
+		     default_catalog = CASE WHEN $7::varchar IS NULL THEN default_catalog ELSE NULLIF($7, '') END,
+		     default_schema = CASE WHEN $8::varchar IS NULL THEN default_schema ELSE NULLIF($8, '') END,
+		     timezone = CASE WHEN $9::varchar IS NULL THEN timezone ELSE NULLIF($9, '') END,
+		     updated_by = $6,
 		     updated_at = CURRENT_TIMESTAMP
 		 WHERE id = $1
 		 RETURNING id, user_id, name, description, layout, COALESCE(is_public, false), COALESCE(parameters, '[]'),
-		           COALESCE(is_draft, false), draft_of, created_at, updated_at`,
-		id, req.Name, req.Description, req.Layout, req.Parameters,
+		           COALESCE(is_draft, false), draft_of, COALESCE(is_archived, false), default_catalog, default_schema,
+		           timezone, created_by, updated_by, created_at, updated_at`,
+		id, req.Name, req.Description, req.Layout, req.Parameters, userID, req.DefaultCatalog, req.DefaultSchema, req.Timezone,
 	).Scan(&d.ID, &d.UserID, &d.Name, &d.Description, &d.Layout, &d.IsPublic, &d.Parameters,
-		&d.IsDraft, &d.DraftOf, &d.CreatedAt, &d.UpdatedAt)
+		&d.IsDraft, &d.DraftOf, &d.IsArchived, &d.DefaultCatalog, &d.DefaultSchema,
+		&d.Timezone, &d.CreatedBy, &d.UpdatedBy, &d.CreatedAt, &d.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -119,6 +340,8 @@ func (s *DashboardService) UpdateDashboard(ctx context.Context, id, userID uuid.
 		return nil, err
 	}
 
+	_ = recordDashboardEvent(ctx, pool, d.ID, userID, models.DashboardEventUpdate, models.DashboardEventTargetDashboard, d.ID, d.Name)
+
 	d.MyPermission = permLevel
 
 	// Populate widgets/permissions to match GetDashboard response shape.
@@ -138,6 +361,9 @@ func (s *DashboardService) UpdateDashboard(ctx context.Context, id, userID uuid.
 	return &d, nil
 }
 
+// DeleteDashboard moves a dashboard and its draft to the owner's trash. They disappear from
+// every listing and lookup until restored, and are removed for good by PurgeDeletedDashboards.
+// Deleting a draft directly discards it immediately, as DiscardDraft does.
 func (s *DashboardService) DeleteDashboard(ctx context.Context, id, userID uuid.UUID) error {
 	// Only owner can delete
 	permLevel, err := s.permRepo.GetUserPermissionLevel(ctx, id, userID)
@@ -151,18 +377,174 @@ func (s *DashboardService) DeleteDashboard(ctx context.Context, id, userID uuid.
 
 	pool := database.GetPool()
 
-	result, err := pool.Exec(ctx, `DELETE FROM dashboards WHERE id = $1 AND user_id = $2`, id, userID)
+	var name string
+	var isDraft bool
+	err = pool.QueryRow(ctx,
+		`SELECT name, COALESCE(is_draft, false) FROM dashboards WHERE id = $1 AND user_id = $2`,
+		id, userID,
+	).Scan(&name, &isDraft)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
 		return err
 	}
 
-	if result.RowsAffected() == 0 {
-		return ErrNotFound
+	if isDraft {
+		_, err = pool.Exec(ctx, `DELETE FROM dashboards WHERE id = $1`, id)
+		return err
 	}
 
+	if _, err := pool.Exec(ctx,
+		`UPDATE dashboards SET deleted_at = CURRENT_TIMESTAMP, purge_warned_at = NULL
+		 WHERE (id = $1 OR draft_of = $1) AND deleted_at IS NULL`,
+		id,
+	); err != nil {
+		return err
+	}
+
+	_ = recordDashboardEvent(ctx, pool, id, userID, models.DashboardEventDelete, models.DashboardEventTargetDashboard, id, name)
 	return nil
 }
 
+// RestoreDashboard takes a dashboard and its draft out of the owner's trash (owner only)
+func (s *DashboardService) RestoreDashboard(ctx context.Context, id, userID uuid.UUID) error {
+	pool := database.GetPool()
+
+	var ownerID uuid.UUID
+	var name string
+	err := pool.QueryRow(ctx,
+		`SELECT user_id, name FROM dashboards
+		 WHERE id = $1 AND deleted_at IS NOT NULL AND NOT COALESCE(is_draft, false)`,
+		id,
+	).Scan(&ownerID, &name)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if ownerID != userID {
+		return ErrPermissionDenied
+	}
+
+	err = s.checkUniqueName(func() (bool, error) {
+		var exists bool
+		err := pool.QueryRow(ctx,
+			`SELECT EXISTS (
+			   SELECT 1 FROM dashboards
+			   WHERE user_id = $1 AND id <> $2 AND LOWER(name) = LOWER($3) AND NOT COALESCE(is_draft, false)
+			     AND deleted_at IS NULL)`,
+			userID, id, name,
+		).Scan(&exists)
+		return exists, err
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := pool.Exec(ctx,
+		`UPDATE dashboards SET deleted_at = NULL, purge_warned_at = NULL WHERE id = $1 OR draft_of = $1`,
+		id,
+	); err != nil {
+		return err
+	}
+
+	_ = recordDashboardEvent(ctx, pool, id, userID, models.DashboardEventRestore, models.DashboardEventTargetDashboard, id, name)
+	return nil
+}
+
+// GetTrash returns the user's soft-deleted dashboards, most recently deleted first
+func (s *DashboardService) GetTrash(ctx context.Context, userID uuid.UUID) ([]models.Dashboard, error) {
+	pool := database.GetPool()
+
+	rows, err := pool.Query(ctx,
+		`SELECT id, user_id, name, description, layout, COALESCE(is_public, false), COALESCE(parameters, '[]'),
+		        COALESCE(is_draft, false), draft_of, COALESCE(is_archived, false), created_by, updated_by, created_at, updated_at,
+		        deleted_at
+		 FROM dashboards
+		 WHERE user_id = $1 AND deleted_at IS NOT NULL AND NOT COALESCE(is_draft, false)
+		 ORDER BY deleted_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dashboards []models.Dashboard
+	for rows.Next() {
+		var d models.Dashboard
+		if err := rows.Scan(&d.ID, &d.UserID, &d.Name, &d.Description, &d.Layout, &d.IsPublic, &d.Parameters,
+			&d.IsDraft, &d.DraftOf, &d.IsArchived, &d.CreatedBy, &d.UpdatedBy, &d.CreatedAt, &d.UpdatedAt,
+			&d.DeletedAt); err != nil {
+			return nil, err
+		}
+		d.MyPermission = models.PermissionOwner
+		dashboards = append(dashboards, d)
+	}
+
+	return dashboards, rows.Err()
+}
+
+// ClaimPurgeWarnings returns the trashed dashboards that will be purged within warning and whose
+// owners have not been warned yet, marking them as warned. A dashboard is purged no earlier than
+// warning after it is claimed here (see PurgeDeletedDashboards) unless ReleasePurgeWarning undoes
+// the claim.
+func (s *DashboardService) ClaimPurgeWarnings(ctx context.Context, retention, warning time.Duration) ([]models.Dashboard, error) {
+	pool := database.GetPool()
+
+	rows, err := pool.Query(ctx,
+		`UPDATE dashboards SET purge_warned_at = CURRENT_TIMESTAMP
+		 WHERE deleted_at < CURRENT_TIMESTAMP - $1 * INTERVAL '1 second'
+		   AND purge_warned_at IS NULL AND NOT COALESCE(is_draft, false)
+		 RETURNING id, user_id, name, deleted_at`,
+		int64(max(retention-warning, 0).Seconds()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dashboards []models.Dashboard
+	for rows.Next() {
+		var d models.Dashboard
+		if err := rows.Scan(&d.ID, &d.UserID, &d.Name, &d.DeletedAt); err != nil {
+			return nil, err
+		}
+		dashboards = append(dashboards, d)
+	}
+	return dashboards, rows.Err()
+}
+
+// ReleasePurgeWarning clears the warning ClaimPurgeWarnings recorded for a dashboard whose owner
+// could not be notified, so the next run warns them again instead of purging unannounced
+func (s *DashboardService) ReleasePurgeWarning(ctx context.Context, dashboardID uuid.UUID) error {
+	pool := database.GetPool()
+
+	_, err := pool.Exec(ctx, `UPDATE dashboards SET purge_warned_at = NULL WHERE id = $1`, dashboardID)
+	return err
+}
+
+// PurgeDeletedDashboards permanently deletes dashboards that have been in the trash longer than
+// retention, with their drafts, widgets and permissions, and returns how many were deleted.
+// When warning is positive, a dashboard is only purged once its owner was warned by
+// ClaimPurgeWarnings at least warning ago.
+func (s *DashboardService) PurgeDeletedDashboards(ctx context.Context, retention, warning time.Duration) (int64, error) {
+	pool := database.GetPool()
+
+	result, err := pool.Exec(ctx,
+		`DELETE FROM dashboards
+		 WHERE deleted_at < CURRENT_TIMESTAMP - $1 * INTERVAL '1 second'
+		   AND ($2 <= 0 OR COALESCE(is_draft, false) OR purge_warned_at < CURRENT_TIMESTAMP - $2 * INTERVAL '1 second')`,
+		int64(retention.Seconds()), int64(warning.Seconds()),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 // GetDashboardParameters returns the dashboard parameters JSON without loading widgets.
 // Permission checks must be performed by the caller.
 func (s *DashboardService) GetDashboardParameters(ctx context.Context, dashboardID uuid.UUID) (json.RawMessage, error) {
@@ -180,13 +562,89 @@ func (s *DashboardService) GetDashboardParameters(ctx context.Context, dashboard
 	return params, nil
 }
 
+// GetDashboardTitle returns a dashboard's name and description without loading widgets
+func (s *DashboardService) GetDashboardTitle(ctx context.Context, dashboardID uuid.UUID) (string, *string, error) {
+	pool := database.GetPool()
+
+	var name string
+	var description *string
+	err := pool.QueryRow(ctx, `SELECT name, description FROM dashboards WHERE id = $1`, dashboardID).Scan(&name, &description)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil, ErrNotFound
+		}
+		return "", nil, err
+	}
+
+	return name, description, nil
+}
+
+// defaultDashboardTimezone is used for dashboards that do not set a timezone
+const defaultDashboardTimezone = "Asia/Tokyo"
+
+// GetDashboardLocation returns the timezone relative date parameters of a dashboard are
+// resolved in. Dashboards without a valid timezone use Asia/Tokyo.
+func (s *DashboardService) GetDashboardLocation(ctx context.Context, dashboardID uuid.UUID) (*time.Location, error) {
+	pool := database.GetPool()
+
+	var timezone *string
+	err := pool.QueryRow(ctx, `SELECT timezone FROM dashboards WHERE id = $1`, dashboardID).Scan(&timezone)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if timezone != nil {
+		if loc, err := loadSubscriptionLocation(*timezone); err == nil {
+			return loc, nil
+		}
+	}
+	return time.LoadLocation(defaultDashboardTimezone)
+}
+
+// GetDashboardExecutionDefaults returns the catalog and schema a dashboard sets for widget
+// queries that do not name their own; either is nil when the dashboard leaves it to the server
+func (s *DashboardService) GetDashboardExecutionDefaults(ctx context.Context, dashboardID uuid.UUID) (*string, *string, error) {
+	pool := database.GetPool()
+
+	var catalog, schema *string
+	err := pool.QueryRow(ctx,
+		`SELECT default_catalog, default_schema FROM dashboards WHERE id = $1`, dashboardID,
+	).Scan(&catalog, &schema)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+
+	return catalog, schema, nil
+}
+
 // Widget CRUD operations
 
+// widgetColumns is the column list shared by every query that loads a Widget (see scanWidget)
+const widgetColumns = `id, dashboard_id, name, query_id, query_text, catalog, schema_name, chart_type, chart_config, position, responsive_positions,
+		 theme_id, (SELECT config FROM chart_themes WHERE chart_themes.id = dashboard_widgets.theme_id),
+		 max_staleness_seconds, last_error, last_error_at, created_by, updated_by, created_at, updated_at`
+
+// scanWidget scans a row selected with widgetColumns
+func scanWidget(row pgx.Row) (*models.Widget, error) {
+	var w models.Widget
+	if err := row.Scan(&w.ID, &w.DashboardID, &w.Name, &w.QueryID, &w.QueryText, &w.Catalog, &w.Schema, &w.ChartType, &w.ChartConfig, &w.Position, &w.ResponsivePositions,
+		&w.ThemeID, &w.ThemeConfig, &w.MaxStalenessSeconds, &w.LastError, &w.LastErrorAt, &w.CreatedBy, &w.UpdatedBy, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
 func (s *DashboardService) GetWidgets(ctx context.Context, dashboardID uuid.UUID) ([]models.Widget, error) {
 	pool := database.GetPool()
 
 	rows, err := pool.Query(ctx,
-		`SELECT id, dashboard_id, name, query_id, chart_type, chart_config, position, responsive_positions, created_at, updated_at
+		`SELECT `+widgetColumns+`
 		 FROM dashboard_widgets WHERE dashboard_id = $1`,
 		dashboardID,
 	)
@@ -197,11 +655,11 @@ func (s *DashboardService) GetWidgets(ctx context.Context, dashboardID uuid.UUID
 
 	var widgets []models.Widget
 	for rows.Next() {
-		var w models.Widget
-		if err := rows.Scan(&w.ID, &w.DashboardID, &w.Name, &w.QueryID, &w.ChartType, &w.ChartConfig, &w.Position, &w.ResponsivePositions, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		w, err := scanWidget(rows)
+		if err != nil {
 			return nil, err
 		}
-		widgets = append(widgets, w)
+		widgets = append(widgets, *w)
 	}
 
 	return widgets, nil
@@ -211,12 +669,11 @@ func (s *DashboardService) GetWidgets(ctx context.Context, dashboardID uuid.UUID
 func (s *DashboardService) GetWidget(ctx context.Context, dashboardID, widgetID uuid.UUID) (*models.Widget, error) {
 	pool := database.GetPool()
 
-	var w models.Widget
-	err := pool.QueryRow(ctx,
-		`SELECT id, dashboard_id, name, query_id, chart_type, chart_config, position, responsive_positions, created_at, updated_at
+	w, err := scanWidget(pool.QueryRow(ctx,
+		`SELECT `+widgetColumns+`
 		 FROM dashboard_widgets WHERE dashboard_id = $1 AND id = $2`,
 		dashboardID, widgetID,
-	).Scan(&w.ID, &w.DashboardID, &w.Name, &w.QueryID, &w.ChartType, &w.ChartConfig, &w.Position, &w.ResponsivePositions, &w.CreatedAt, &w.UpdatedAt)
+	))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -224,7 +681,7 @@ func (s *DashboardService) GetWidget(ctx context.Context, dashboardID, widgetID
 		return nil, err
 	}
 
-	return &w, nil
+	return w, nil
 }
 
 func (s *DashboardService) CreateWidget(ctx context.Context, dashboardID, userID uuid.UUID, req *models.CreateWidgetRequest) (*models.Widget, error) {
@@ -245,20 +702,28 @@ func (s *DashboardService) CreateWidget(ctx context.Context, dashboardID, userID
 		}
 	}
 
+	if req.ThemeID != nil {
+		if err := s.ensureChartThemeUsable(ctx, *req.ThemeID, userID); err != nil {
+			return nil, err
+		}
+	}
+
 	pool := database.GetPool()
 
-	var w models.Widget
-	err = pool.QueryRow(ctx,
-		`INSERT INTO dashboard_widgets (dashboard_id, name, query_id, chart_type, chart_config, position, responsive_positions)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7)
-		 RETURNING id, dashboard_id, name, query_id, chart_type, chart_config, position, responsive_positions, created_at, updated_at`,
-		dashboardID, req.Name, req.QueryID, req.ChartType, req.ChartConfig, req.Position, req.ResponsivePositions,
-	).Scan(&w.ID, &w.DashboardID, &w.Name, &w.QueryID, &w.ChartType, &w.ChartConfig, &w.Position, &w.ResponsivePositions, &w.CreatedAt, &w.UpdatedAt)
+	w, err := scanWidget(pool.QueryRow(ctx,
+		`INSERT INTO dashboard_widgets (dashboard_id, name, query_id, chart_type, chart_config, position, responsive_positions, theme_id, max_staleness_seconds, created_by, updated_by,
+		                                query_text, catalog, schema_name)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10, NULLIF($11, ''), NULLIF($12, ''), NULLIF($13, ''))
+		 RETURNING `+widgetColumns,
+		dashboardID, req.Name, req.QueryID, req.ChartType, req.ChartConfig, req.Position, req.ResponsivePositions, req.ThemeID, req.MaxStalenessSeconds, userID,
+		req.QueryText, req.Catalog, req.Schema,
+	))
 	if err != nil {
 		return nil, err
 	}
 
-	return &w, nil
+	_ = recordDashboardEvent(ctx, pool, dashboardID, userID, models.DashboardEventCreate, models.DashboardEventTargetWidget, w.ID, w.Name)
+	return w, nil
 }
 
 func (s *DashboardService) UpdateWidget(ctx context.Context, id, dashboardID, userID uuid.UUID, req *models.UpdateWidgetRequest) (*models.Widget, error) {
@@ -279,10 +744,15 @@ func (s *DashboardService) UpdateWidget(ctx context.Context, id, dashboardID, us
 		}
 	}
 
+	if req.ThemeID != nil {
+		if err := s.ensureChartThemeUsable(ctx, *req.ThemeID, userID); err != nil {
+			return nil, err
+		}
+	}
+
 	pool := database.GetPool()
 
-	var w models.Widget
-	err = pool.QueryRow(ctx,
+	w, err := scanWidget(pool.QueryRow(ctx,
 		`UPDATE dashboard_widgets
 		 SET name = COALESCE(NULLIF($3, ''), name),
 		     query_id = COALESCE($4, query_id),
@@ -290,11 +760,18 @@ func (s *DashboardService) UpdateWidget(ctx context.Context, id, dashboardID, us
 		     chart_config = COALESCE($6, chart_config),
 		     position = COALESCE($7, position),
 		     responsive_positions = COALESCE($8, responsive_positions),
+		     theme_id = COALESCE($9, theme_id),
+		     max_staleness_seconds = COALESCE($10, max_staleness_seconds),
+		     query_text = CASE WHEN $12::text IS NULL THEN query_text ELSE NULLIF($12, '') END,
+		     catalog = CASE WHEN $13::text IS NULL THEN catalog ELSE NULLIF($13, '') END,
+		     schema_name = CASE WHEN $14::text IS NULL THEN schema_name ELSE NULLIF($14, '') END,
+		     updated_by = $11,
 		     updated_at = CURRENT_TIMESTAMP
 		 WHERE id = $1 AND dashboard_id = $2
-		 RETURNING id, dashboard_id, name, query_id, chart_type, chart_config, position, responsive_positions, created_at, updated_at`,
-		id, dashboardID, req.Name, req.QueryID, req.ChartType, req.ChartConfig, req.Position, req.ResponsivePositions,
-	).Scan(&w.ID, &w.DashboardID, &w.Name, &w.QueryID, &w.ChartType, &w.ChartConfig, &w.Position, &w.ResponsivePositions, &w.CreatedAt, &w.UpdatedAt)
+		 RETURNING `+widgetColumns,
+		id, dashboardID, req.Name, req.QueryID, req.ChartType, req.ChartConfig, req.Position, req.ResponsivePositions, req.ThemeID, req.MaxStalenessSeconds, userID,
+		req.QueryText, req.Catalog, req.Schema,
+	))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -302,7 +779,8 @@ func (s *DashboardService) UpdateWidget(ctx context.Context, id, dashboardID, us
 		return nil, err
 	}
 
-	return &w, nil
+	_ = recordDashboardEvent(ctx, pool, dashboardID, userID, models.DashboardEventUpdate, models.DashboardEventTargetWidget, w.ID, w.Name)
+	return w, nil
 }
 
 func (s *DashboardService) DeleteWidget(ctx context.Context, id, dashboardID, userID uuid.UUID) error {
@@ -318,18 +796,19 @@ func (s *DashboardService) DeleteWidget(ctx context.Context, id, dashboardID, us
 
 	pool := database.GetPool()
 
-	result, err := pool.Exec(ctx,
-		`DELETE FROM dashboard_widgets WHERE id = $1 AND dashboard_id = $2`,
+	var name string
+	err = pool.QueryRow(ctx,
+		`DELETE FROM dashboard_widgets WHERE id = $1 AND dashboard_id = $2 RETURNING name`,
 		id, dashboardID,
-	)
+	).Scan(&name)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
 		return err
 	}
 
-	if result.RowsAffected() == 0 {
-		return ErrNotFound
-	}
-
+	_ = recordDashboardEvent(ctx, pool, dashboardID, userID, models.DashboardEventDelete, models.DashboardEventTargetWidget, id, name)
 	return nil
 }
 
@@ -345,14 +824,20 @@ func (s *DashboardService) BatchUpdateWidgets(ctx context.Context, dashboardID,
 		return nil, ErrPermissionDenied
 	}
 
-	pool := database.GetPool()
-
-	// Start transaction
-	tx, err := pool.Begin(ctx)
-	if err != nil {
-		return nil, err
+	for _, createReq := range req.Create {
+		if createReq.ThemeID != nil {
+			if err := s.ensureChartThemeUsable(ctx, *createReq.ThemeID, userID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, updateReq := range req.Update {
+		if updateReq.ThemeID != nil {
+			if err := s.ensureChartThemeUsable(ctx, *updateReq.ThemeID, userID); err != nil {
+				return nil, err
+			}
+		}
 	}
-	defer tx.Rollback(ctx)
 
 	response := &models.BatchWidgetUpdateResponse{
 		Created: []models.Widget{},
@@ -360,74 +845,94 @@ func (s *DashboardService) BatchUpdateWidgets(ctx context.Context, dashboardID,
 		Deleted: []string{},
 	}
 
-	// 1. Delete widgets first (within transaction)
-	for _, widgetID := range req.Delete {
-		id, err := uuid.Parse(widgetID)
-		if err != nil {
-			return nil, ErrInvalidRequest
-		}
+	// All changes are atomic
+	err = database.WithTx(ctx, func(tx pgx.Tx) error {
+		// 1. Delete widgets first (within transaction)
+		for _, widgetID := range req.Delete {
+			id, err := uuid.Parse(widgetID)
+			if err != nil {
+				return ErrInvalidRequest
+			}
 
-		result, err := tx.Exec(ctx,
-			`DELETE FROM dashboard_widgets WHERE id = $1 AND dashboard_id = $2`,
-			id, dashboardID,
-		)
-		if err != nil {
-			return nil, err
-		}
+			var name string
+			err = tx.QueryRow(ctx,
+				`DELETE FROM dashboard_widgets WHERE id = $1 AND dashboard_id = $2 RETURNING name`,
+				id, dashboardID,
+			).Scan(&name)
+			if err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					continue
+				}
+				return err
+			}
 
-		if result.RowsAffected() > 0 {
+			if err := recordDashboardEvent(ctx, tx, dashboardID, userID, models.DashboardEventDelete, models.DashboardEventTargetWidget, id, name); err != nil {
+				return err
+			}
 			response.Deleted = append(response.Deleted, widgetID)
 		}
-	}
 
-	// 2. Create new widgets (within transaction)
-	for _, createReq := range req.Create {
-		var w models.Widget
-		err := tx.QueryRow(ctx,
-			`INSERT INTO dashboard_widgets (dashboard_id, name, query_id, chart_type, chart_config, position, responsive_positions)
-			 VALUES ($1, $2, $3, $4, $5, $6, $7)
-			 RETURNING id, dashboard_id, name, query_id, chart_type, chart_config, position, responsive_positions, created_at, updated_at`,
-			dashboardID, createReq.Name, createReq.QueryID, createReq.ChartType, createReq.ChartConfig, createReq.Position, createReq.ResponsivePositions,
-		).Scan(&w.ID, &w.DashboardID, &w.Name, &w.QueryID, &w.ChartType, &w.ChartConfig, &w.Position, &w.ResponsivePositions, &w.CreatedAt, &w.UpdatedAt)
-		if err != nil {
-			return nil, err
+		// 2. Create new widgets (within transaction)
+		for _, createReq := range req.Create {
+			w, err := scanWidget(tx.QueryRow(ctx,
+				`INSERT INTO dashboard_widgets (dashboard_id, name, query_id, chart_type, chart_config, position, responsive_positions, theme_id, max_staleness_seconds, created_by, updated_by,
+				                                query_text, catalog, schema_name)
+				 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10, NULLIF($11, ''), NULLIF($12, ''), NULLIF($13, ''))
+				 RETURNING `+widgetColumns,
+				dashboardID, createReq.Name, createReq.QueryID, createReq.ChartType, createReq.ChartConfig, createReq.Position, createReq.ResponsivePositions, createReq.ThemeID, createReq.MaxStalenessSeconds, userID,
+				createReq.QueryText, createReq.Catalog, createReq.Schema,
+			))
+			if err != nil {
+				return err
+			}
+			if err := recordDashboardEvent(ctx, tx, dashboardID, userID, models.DashboardEventCreate, models.DashboardEventTargetWidget, w.ID, w.Name); err != nil {
+				return err
+			}
+			response.Created = append(response.Created, *w)
 		}
-		response.Created = append(response.Created, w)
-	}
 
-	// 3. Update existing widgets (within transaction)
-	for widgetID, updateReq := range req.Update {
-		id, err := uuid.Parse(widgetID)
-		if err != nil {
-			return nil, ErrInvalidRequest
-		}
-
-		var w models.Widget
-		err = tx.QueryRow(ctx,
-			`UPDATE dashboard_widgets
-			 SET name = COALESCE(NULLIF($3, ''), name),
-			     query_id = COALESCE($4, query_id),
-			     chart_type = COALESCE(NULLIF($5, ''), chart_type),
-			     chart_config = COALESCE($6, chart_config),
-			     position = COALESCE($7, position),
-			     responsive_positions = COALESCE($8, responsive_positions),
-			     updated_at = CURRENT_TIMESTAMP
-			 WHERE id = $1 AND dashboard_id = $2
-			 RETURNING id, dashboard_id, name, query_id, chart_type, chart_config, position, responsive_positions, created_at, updated_at`,
-			id, dashboardID, updateReq.Name, updateReq.QueryID, updateReq.ChartType, updateReq.ChartConfig, updateReq.Position, updateReq.ResponsivePositions,
-		).Scan(&w.ID, &w.DashboardID, &w.Name, &w.QueryID, &w.ChartType, &w.ChartConfig, &w.Position, &w.ResponsivePositions, &w.CreatedAt, &w.UpdatedAt)
-		if err != nil {
-			if errors.Is(err, pgx.ErrNoRows) {
-				// Widget not found - skip but don't fail the whole transaction
-				continue
+		// 3. Update existing widgets (within transaction)
+		for widgetID, updateReq := range req.Update {
+			id, err := uuid.Parse(widgetID)
+			if err != nil {
+				return ErrInvalidRequest
 			}
-			return nil, err
-		}
-		response.Updated = append(response.Updated, w)
-	}
 
-	// Commit transaction - all changes are atomic
-	if err := tx.Commit(ctx); err != nil {
+			w, err := scanWidget(tx.QueryRow(ctx,
+				`UPDATE dashboard_widgets
+				 SET name = COALESCE(NULLIF($3, ''), name),
+				     query_id = COALESCE($4, query_id),
+				     chart_type = COALESCE(NULLIF($5, ''), chart_type),
+				     chart_config = COALESCE($6, chart_config),
+				     position = COALESCE($7, position),
+				     responsive_positions = COALESCE($8, responsive_positions),
+				     theme_id = COALESCE($9, theme_id),
+				     max_staleness_seconds = COALESCE($10, max_staleness_seconds),
+				     query_text = CASE WHEN $12::text IS NULL THEN query_text ELSE NULLIF($12, '') END,
+				     catalog = CASE WHEN $13::text IS NULL THEN catalog ELSE NULLIF($13, '') END,
+				     schema_name = CASE WHEN $14::text IS NULL THEN schema_name ELSE NULLIF($14, '') END,
+				     updated_by = $11,
+				     updated_at = CURRENT_TIMESTAMP
+				 WHERE id = $1 AND dashboard_id = $2
+				 RETURNING `+widgetColumns,
+				id, dashboardID, updateReq.Name, updateReq.QueryID, updateReq.ChartType, updateReq.ChartConfig, updateReq.Position, updateReq.ResponsivePositions, updateReq.ThemeID, updateReq.MaxStalenessSeconds, userID,
+				updateReq.QueryText, updateReq.Catalog, updateReq.Schema,
+			))
+			if err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					// Widget not found - skip but don't fail the whole transaction
+					continue
+				}
+				return err
+			}
+			if err := recordDashboardEvent(ctx, tx, dashboardID, userID, models.DashboardEventUpdate, models.DashboardEventTargetWidget, w.ID, w.Name); err != nil {
+				return err
+			}
+			response.Updated = append(response.Updated, *w)
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -448,12 +953,11 @@ func (s *DashboardService) DuplicateWidget(ctx context.Context, id, dashboardID,
 	pool := database.GetPool()
 
 	// Get the original widget
-	var original models.Widget
-	err = pool.QueryRow(ctx,
-		`SELECT id, dashboard_id, name, query_id, chart_type, chart_config, position, responsive_positions, created_at, updated_at
+	original, err := scanWidget(pool.QueryRow(ctx,
+		`SELECT `+widgetColumns+`
 		 FROM dashboard_widgets WHERE id = $1 AND dashboard_id = $2`,
 		id, dashboardID,
-	).Scan(&original.ID, &original.DashboardID, &original.Name, &original.QueryID, &original.ChartType, &original.ChartConfig, &original.Position, &original.ResponsivePositions, &original.CreatedAt, &original.UpdatedAt)
+	))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -469,18 +973,20 @@ func (s *DashboardService) DuplicateWidget(ctx context.Context, id, dashboardID,
 	newPosition, _ := json.Marshal(pos)
 
 	// Create the duplicate with "(Copy)" appended to name
-	var w models.Widget
-	err = pool.QueryRow(ctx,
-		`INSERT INTO dashboard_widgets (dashboard_id, name, query_id, chart_type, chart_config, position, responsive_positions)
-		 VALUES ($1, $2, $3, $4, $5, $6, $7)
-		 RETURNING id, dashboard_id, name, query_id, chart_type, chart_config, position, responsive_positions, created_at, updated_at`,
-		dashboardID, original.Name+" (Copy)", original.QueryID, original.ChartType, original.ChartConfig, newPosition, original.ResponsivePositions,
-	).Scan(&w.ID, &w.DashboardID, &w.Name, &w.QueryID, &w.ChartType, &w.ChartConfig, &w.Position, &w.ResponsivePositions, &w.CreatedAt, &w.UpdatedAt)
+	w, err := scanWidget(pool.QueryRow(ctx,
+		`INSERT INTO dashboard_widgets (dashboard_id, name, query_id, chart_type, chart_config, position, responsive_positions, theme_id, max_staleness_seconds, created_by, updated_by,
+		                                query_text, catalog, schema_name)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10, $11, $12, $13)
+		 RETURNING `+widgetColumns,
+		dashboardID, original.Name+" (Copy)", original.QueryID, original.ChartType, original.ChartConfig, newPosition, original.ResponsivePositions, original.ThemeID, original.MaxStalenessSeconds, userID,
+		original.QueryText, original.Catalog, original.Schema,
+	))
 	if err != nil {
 		return nil, err
 	}
 
-	return &w, nil
+	_ = recordDashboardEvent(ctx, pool, dashboardID, userID, models.DashboardEventCreate, models.DashboardEventTargetWidget, w.ID, w.Name)
+	return w, nil
 }
 
 // Permission management (only owner can manage permissions)
@@ -578,13 +1084,13 @@ func (s *DashboardService) GetDraft(ctx context.Context, originalDashboardID, us
 	var d models.Dashboard
 	err = pool.QueryRow(ctx,
 		`SELECT id, user_id, name, description, layout, COALESCE(is_public, false), COALESCE(parameters, '[]'),
-		        COALESCE(is_draft, false), draft_of, created_at, updated_at
+		        COALESCE(is_draft, false), draft_of, COALESCE(is_archived, false), created_by, updated_by, created_at, updated_at
 		 FROM dashboards WHERE draft_of = $1 AND COALESCE(is_draft, false) = true
 		 ORDER BY updated_at DESC, created_at DESC
 		 LIMIT 1`,
 		originalDashboardID,
 	).Scan(&d.ID, &d.UserID, &d.Name, &d.Description, &d.Layout, &d.IsPublic, &d.Parameters,
-		&d.IsDraft, &d.DraftOf, &d.CreatedAt, &d.UpdatedAt)
+		&d.IsDraft, &d.DraftOf, &d.IsArchived, &d.CreatedBy, &d.UpdatedBy, &d.CreatedAt, &d.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil // No draft exists
@@ -632,11 +1138,11 @@ func (s *DashboardService) CreateDraft(ctx context.Context, originalDashboardID,
 	var original models.Dashboard
 	err = pool.QueryRow(ctx,
 		`SELECT id, user_id, name, description, layout, COALESCE(is_public, false), COALESCE(parameters, '[]'),
-		        created_at, updated_at
+		        default_catalog, default_schema, created_at, updated_at
 		 FROM dashboards WHERE id = $1`,
 		originalDashboardID,
 	).Scan(&original.ID, &original.UserID, &original.Name, &original.Description, &original.Layout, &original.IsPublic, &original.Parameters,
-		&original.CreatedAt, &original.UpdatedAt)
+		&original.DefaultCatalog, &original.DefaultSchema, &original.CreatedAt, &original.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -644,30 +1150,46 @@ func (s *DashboardService) CreateDraft(ctx context.Context, originalDashboardID,
 		return nil, err
 	}
 
-	// Start transaction
-	tx, err := pool.Begin(ctx)
-	if err != nil {
-		return nil, err
-	}
-	defer tx.Rollback(ctx)
-
-	// Create draft dashboard (Phase 1.2: is_public is always false for drafts)
+	// Create the draft and copy the widgets in one transaction
 	var draft models.Dashboard
-	err = tx.QueryRow(ctx,
-		`INSERT INTO dashboards (user_id, name, description, layout, is_public, parameters, is_draft, draft_of)
-		 VALUES ($1, $2, $3, $4, false, $5, true, $6)
-		 RETURNING id, user_id, name, description, layout, COALESCE(is_public, false), COALESCE(parameters, '[]'),
-		           COALESCE(is_draft, false), draft_of, created_at, updated_at`,
-		original.UserID, original.Name, original.Description, original.Layout, original.Parameters, originalDashboardID,
-	).Scan(&draft.ID, &draft.UserID, &draft.Name, &draft.Description, &draft.Layout, &draft.IsPublic, &draft.Parameters,
-		&draft.IsDraft, &draft.DraftOf, &draft.CreatedAt, &draft.UpdatedAt)
+	err = database.WithTx(ctx, func(tx pgx.Tx) error {
+		// Create draft dashboard (Phase 1.2: is_public is always false for drafts)
+		err := tx.QueryRow(ctx,
+			`INSERT INTO dashboards (user_id, name, description, layout, is_public, parameters, is_draft, draft_of,
+			                         default_catalog, default_schema, timezone, created_by, updated_by)
+			 VALUES ($1, $2, $3, $4, false, $5, true, $6, $8, $9, (SELECT timezone FROM dashboards WHERE id = $6), $7, $7)
+			 RETURNING id, user_id, name, description, layout, COALESCE(is_public, false), COALESCE(parameters, '[]'),
+			           COALESCE(is_draft, false), draft_of, COALESCE(is_archived, false), default_catalog, default_schema,
+			           created_by, updated_by, created_at, updated_at`,
+			original.UserID, original.Name, original.Description, original.Layout, original.Parameters, originalDashboardID, userID,
+			original.DefaultCatalog, original.DefaultSchema,
+		).Scan(&draft.ID, &draft.UserID, &draft.Name, &draft.Description, &draft.Layout, &draft.IsPublic, &draft.Parameters,
+			&draft.IsDraft, &draft.DraftOf, &draft.IsArchived, &draft.DefaultCatalog, &draft.DefaultSchema,
+			&draft.CreatedBy, &draft.UpdatedBy, &draft.CreatedAt, &draft.UpdatedAt)
+		if err != nil {
+			return err
+		}
+
+		// Copy all widgets from original to draft, keeping who created and last edited them
+		_, err = tx.Exec(ctx,
+			`INSERT INTO dashboard_widgets (dashboard_id, name, query_id, chart_type, chart_config, position, responsive_positions, theme_id, max_staleness_seconds, created_by, updated_by,
+			                                query_text, catalog, schema_name)
+			 SELECT $1, name, query_id, chart_type, chart_config, position, responsive_positions, theme_id, max_staleness_seconds, created_by, updated_by,
+			        query_text, catalog, schema_name
+			 FROM dashboard_widgets WHERE dashboard_id = $2`,
+			draft.ID, originalDashboardID,
+		)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
 	if err != nil {
 		// Phase 1.4: Handle unique constraint violation (concurrent CreateDraft)
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
-			// Unique violation - another draft was created concurrently
-			tx.Rollback(ctx)
-			// Return the existing draft
+			// Unique violation - another draft was created concurrently; return it
 			existingDraft, getDraftErr := s.GetDraft(ctx, originalDashboardID, userID)
 			if getDraftErr != nil {
 				return nil, getDraftErr
@@ -675,38 +1197,358 @@ func (s *DashboardService) CreateDraft(ctx context.Context, originalDashboardID,
 			if existingDraft != nil {
 				return existingDraft, nil
 			}
-			// Shouldn't happen, but fallback to error
-			return nil, err
 		}
+		// Shouldn't happen for a unique violation, but fallback to error
+		return nil, err
+	}
+
+	draft.MyPermission = permLevel
+
+	// Load widgets
+	widgets, err := s.GetWidgets(ctx, draft.ID)
+	if err != nil {
+		return nil, err
+	}
+	draft.Widgets = widgets
+
+	return &draft, nil
+}
+
+// CloneDashboard creates a private copy of a dashboard and its widgets owned by the user.
+// Any viewer may clone a published dashboard; cloning a draft requires edit permission.
+// Permissions and public visibility are not copied; widget queries are referenced as-is.
+func (s *DashboardService) CloneDashboard(ctx context.Context, sourceID, userID uuid.UUID) (*models.Dashboard, error) {
+	permLevel, err := s.permRepo.GetUserPermissionLevel(ctx, sourceID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := database.GetPool()
+
+	var source models.Dashboard
+	err = pool.QueryRow(ctx,
+		`SELECT id, name, description, layout, COALESCE(parameters, '[]'), COALESCE(is_draft, false),
+		        default_catalog, default_schema
+		 FROM dashboards WHERE id = $1`,
+		sourceID,
+	).Scan(&source.ID, &source.Name, &source.Description, &source.Layout, &source.Parameters, &source.IsDraft,
+		&source.DefaultCatalog, &source.DefaultSchema)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if !permLevel.CanView() || (source.IsDraft && !permLevel.CanEdit()) {
+		return nil, ErrPermissionDenied
+	}
+
+	// Start transaction
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// The clone always starts private and published, owned by the cloner
+	var clone models.Dashboard
+	err = tx.QueryRow(ctx,
+		`INSERT INTO dashboards (user_id, name, description, layout, is_public, parameters, default_catalog, default_schema,
+		                         timezone, created_by, updated_by)
+		 VALUES ($1, $2, $3, $4, false, $5, $6, $7, (SELECT timezone FROM dashboards WHERE id = $8), $1, $1)
+		 RETURNING id, user_id, name, description, layout, COALESCE(is_public, false), COALESCE(parameters, '[]'),
+		           COALESCE(is_draft, false), draft_of, COALESCE(is_archived, false), default_catalog, default_schema,
+		           created_by, updated_by, created_at, updated_at`,
+		userID, source.Name+" (Copy)", source.Description, source.Layout, source.Parameters, source.DefaultCatalog, source.DefaultSchema,
+		sourceID,
+	).Scan(&clone.ID, &clone.UserID, &clone.Name, &clone.Description, &clone.Layout, &clone.IsPublic, &clone.Parameters,
+		&clone.IsDraft, &clone.DraftOf, &clone.IsArchived, &clone.DefaultCatalog, &clone.DefaultSchema,
+		&clone.CreatedBy, &clone.UpdatedBy, &clone.CreatedAt, &clone.UpdatedAt)
+	if err != nil {
 		return nil, err
 	}
 
-	// Copy all widgets from original to draft
+	// Copy all widgets from the source; the copies are created by the cloner
 	_, err = tx.Exec(ctx,
-		`INSERT INTO dashboard_widgets (dashboard_id, name, query_id, chart_type, chart_config, position, responsive_positions)
-		 SELECT $1, name, query_id, chart_type, chart_config, position, responsive_positions
+		`INSERT INTO dashboard_widgets (dashboard_id, name, query_id, chart_type, chart_config, position, responsive_positions, theme_id, max_staleness_seconds, created_by, updated_by,
+		                                query_text, catalog, schema_name)
+		 SELECT $1, name, query_id, chart_type, chart_config, position, responsive_positions, theme_id, max_staleness_seconds, $3, $3,
+		        query_text, catalog, schema_name
 		 FROM dashboard_widgets WHERE dashboard_id = $2`,
-		draft.ID, originalDashboardID,
+		clone.ID, sourceID, userID,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := recordDashboardEvent(ctx, tx, clone.ID, userID, models.DashboardEventCreate, models.DashboardEventTargetDashboard, clone.ID, clone.Name); err != nil {
+		return nil, err
+	}
+
 	// Commit transaction
 	if err := tx.Commit(ctx); err != nil {
 		return nil, err
 	}
 
-	draft.MyPermission = permLevel
+	clone.MyPermission = models.PermissionOwner
 
 	// Load widgets
-	widgets, err := s.GetWidgets(ctx, draft.ID)
+	widgets, err := s.GetWidgets(ctx, clone.ID)
 	if err != nil {
 		return nil, err
 	}
-	draft.Widgets = widgets
+	clone.Widgets = widgets
 
-	return &draft, nil
+	return &clone, nil
+}
+
+// ExportDashboard returns a portable copy of a dashboard, its widgets and the saved queries
+// they reference. Only the owner may export.
+func (s *DashboardService) ExportDashboard(ctx context.Context, dashboardID, userID uuid.UUID) (*models.DashboardExport, error) {
+	permLevel, err := s.permRepo.GetUserPermissionLevel(ctx, dashboardID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !permLevel.IsOwner() {
+		return nil, ErrPermissionDenied
+	}
+
+	pool := database.GetPool()
+
+	export := &models.DashboardExport{
+		Version:    models.DashboardExportVersion,
+		ExportedAt: time.Now().UTC(),
+		Widgets:    []models.ExportedWidget{},
+		Queries:    []models.ExportedQuery{},
+	}
+	err = pool.QueryRow(ctx,
+		`SELECT name, description, layout, COALESCE(parameters, '[]'), default_catalog, default_schema
+		 FROM dashboards WHERE id = $1`,
+		dashboardID,
+	).Scan(&export.Dashboard.Name, &export.Dashboard.Description, &export.Dashboard.Layout, &export.Dashboard.Parameters,
+		&export.Dashboard.DefaultCatalog, &export.Dashboard.DefaultSchema)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	widgets, err := s.GetWidgets(ctx, dashboardID)
+	if err != nil {
+		return nil, err
+	}
+
+	var queryIDs []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, w := range widgets {
+		exported := models.ExportedWidget{
+			Name:                w.Name,
+			ChartType:           w.ChartType,
+			ChartConfig:         models.MergeChartTheme(w.ThemeConfig, w.ChartConfig), // theme IDs are not portable, so the theme is folded in
+			Position:            w.Position,
+			ResponsivePositions: w.ResponsivePositions,
+			MaxStalenessSeconds: w.MaxStalenessSeconds,
+		}
+		if w.HasInlineQuery() {
+			exported.QueryText, exported.Catalog, exported.Schema = w.QueryText, w.Catalog, w.Schema
+		} else if w.QueryID != nil {
+			ref := w.QueryID.String()
+			exported.QueryRef = &ref
+			if !seen[*w.QueryID] {
+				seen[*w.QueryID] = true
+				queryIDs = append(queryIDs, *w.QueryID)
+			}
+		}
+		export.Widgets = append(export.Widgets, exported)
+	}
+
+	if len(queryIDs) > 0 {
+		rows, err := pool.Query(ctx,
+			`SELECT id, name, description, query_text, catalog, schema_name
+			 FROM saved_queries WHERE id = ANY($1) ORDER BY name`,
+			queryIDs,
+		)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id uuid.UUID
+			var q models.ExportedQuery
+			if err := rows.Scan(&id, &q.Name, &q.Description, &q.QueryText, &q.Catalog, &q.SchemaName); err != nil {
+				return nil, err
+			}
+			q.Ref = id.String()
+			export.Queries = append(export.Queries, q)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return export, nil
+}
+
+// ValidateDashboardImport checks that an export document can be imported: the version must
+// match DashboardExportVersion and every widget query reference must resolve to a query.
+func ValidateDashboardImport(doc *models.DashboardExport) error {
+	if doc.Version != models.DashboardExportVersion {
+		return &models.ValidationError{
+			Field:   "version",
+			Message: fmt.Sprintf("unsupported export version %d (expected %d)", doc.Version, models.DashboardExportVersion),
+		}
+	}
+
+	refs := make(map[string]bool, len(doc.Queries))
+	for i, q := range doc.Queries {
+		if q.Ref == "" {
+			return &models.ValidationError{Field: fmt.Sprintf("queries[%d].ref", i), Message: "query ref is required"}
+		}
+		if refs[q.Ref] {
+			return &models.ValidationError{Field: fmt.Sprintf("queries[%d].ref", i), Message: fmt.Sprintf("duplicate query ref %q", q.Ref)}
+		}
+		if q.Name == "" || q.QueryText == "" {
+			return &models.ValidationError{Field: fmt.Sprintf("queries[%d]", i), Message: "query name and query_text are required"}
+		}
+		refs[q.Ref] = true
+	}
+
+	for i, w := range doc.Widgets {
+		if w.Name == "" {
+			return &models.ValidationError{Field: fmt.Sprintf("widgets[%d].name", i), Message: "widget name is required"}
+		}
+		if w.QueryRef != nil && !refs[*w.QueryRef] {
+			return &models.ValidationError{Field: fmt.Sprintf("widgets[%d].query_ref", i), Message: fmt.Sprintf("unknown query ref %q", *w.QueryRef)}
+		}
+	}
+
+	return nil
+}
+
+// CheckDashboardImportStatements applies checker to every saved query and inline widget SQL of an
+// export document, reporting the first rejected statement as a *models.ValidationError
+func CheckDashboardImportStatements(doc *models.DashboardExport, checker StatementChecker) error {
+	for i, q := range doc.Queries {
+		if err := checker.CheckStatement(q.QueryText); err != nil {
+			return &models.ValidationError{Field: fmt.Sprintf("queries[%d].query_text", i), Message: err.Error()}
+		}
+	}
+	for i, w := range doc.Widgets {
+		if w.QueryText == nil || *w.QueryText == "" {
+			continue
+		}
+		if err := checker.CheckStatement(*w.QueryText); err != nil {
+			return &models.ValidationError{Field: fmt.Sprintf("widgets[%d].query_text", i), Message: err.Error()}
+		}
+	}
+	return nil
+}
+
+// ImportDashboard recreates an exported dashboard as a new private dashboard owned by the user.
+// Referenced saved queries are created for the user and widget query IDs remapped to them,
+// all in one transaction. The document must pass ValidateDashboardImport and, when a statement
+// checker is set, CheckDashboardImportStatements.
+func (s *DashboardService) ImportDashboard(ctx context.Context, userID uuid.UUID, doc *models.DashboardExport) (*models.Dashboard, error) {
+	if err := ValidateDashboardImport(doc); err != nil {
+		return nil, err
+	}
+	if s.statements != nil {
+		if err := CheckDashboardImportStatements(doc, s.statements); err != nil {
+			return nil, err
+		}
+	}
+
+	layout := doc.Dashboard.Layout
+	if len(layout) == 0 {
+		layout = json.RawMessage(`[]`)
+	}
+	params := doc.Dashboard.Parameters
+	if len(params) == 0 {
+		params = json.RawMessage(`[]`)
+	}
+
+	pool := database.GetPool()
+
+	// Start transaction
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Recreate referenced saved queries for the importing user
+	queryIDs := make(map[string]uuid.UUID, len(doc.Queries))
+	for _, q := range doc.Queries {
+		var id uuid.UUID
+		err := tx.QueryRow(ctx,
+			`INSERT INTO saved_queries (user_id, name, description, query_text, catalog, schema_name)
+			 VALUES ($1, $2, $3, $4, $5, $6)
+			 RETURNING id`,
+			userID, q.Name, q.Description, q.QueryText, q.Catalog, q.SchemaName,
+		).Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		queryIDs[q.Ref] = id
+	}
+
+	var d models.Dashboard
+	err = tx.QueryRow(ctx,
+		`INSERT INTO dashboards (user_id, name, description, layout, is_public, parameters, default_catalog, default_schema,
+		                         created_by, updated_by)
+		 VALUES ($1, $2, $3, $4, false, $5, $6, $7, $1, $1)
+		 RETURNING id, user_id, name, description, layout, COALESCE(is_public, false), COALESCE(parameters, '[]'),
+		           COALESCE(is_draft, false), draft_of, COALESCE(is_archived, false), default_catalog, default_schema,
+		           created_by, updated_by, created_at, updated_at`,
+		userID, doc.Dashboard.Name, doc.Dashboard.Description, layout, params, doc.Dashboard.DefaultCatalog, doc.Dashboard.DefaultSchema,
+	).Scan(&d.ID, &d.UserID, &d.Name, &d.Description, &d.Layout, &d.IsPublic, &d.Parameters,
+		&d.IsDraft, &d.DraftOf, &d.IsArchived, &d.DefaultCatalog, &d.DefaultSchema,
+		&d.CreatedBy, &d.UpdatedBy, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, w := range doc.Widgets {
+		var queryID *uuid.UUID
+		if w.QueryRef != nil {
+			id := queryIDs[*w.QueryRef]
+			queryID = &id
+		}
+		_, err := tx.Exec(ctx,
+			`INSERT INTO dashboard_widgets (dashboard_id, name, query_id, chart_type, chart_config, position, responsive_positions, max_staleness_seconds, created_by, updated_by,
+			                                query_text, catalog, schema_name)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9, NULLIF($10, ''), NULLIF($11, ''), NULLIF($12, ''))`,
+			d.ID, w.Name, queryID, w.ChartType, w.ChartConfig, w.Position, w.ResponsivePositions, w.MaxStalenessSeconds, userID,
+			w.QueryText, w.Catalog, w.Schema,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := recordDashboardEvent(ctx, tx, d.ID, userID, models.DashboardEventCreate, models.DashboardEventTargetDashboard, d.ID, d.Name); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	d.MyPermission = models.PermissionOwner
+
+	// Load widgets
+	widgets, err := s.GetWidgets(ctx, d.ID)
+	if err != nil {
+		return nil, err
+	}
+	d.Widgets = widgets
+
+	return &d, nil
 }
 
 // SaveAsDraft saves changes to an existing draft dashboard
@@ -742,12 +1584,12 @@ func (s *DashboardService) SaveAsDraft(ctx context.Context, dashboardID, userID
 	// Update the draft's updated_at timestamp
 	var d models.Dashboard
 	err = pool.QueryRow(ctx,
-		`UPDATE dashboards SET updated_at = CURRENT_TIMESTAMP WHERE id = $1
+		`UPDATE dashboards SET updated_by = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1
 		 RETURNING id, user_id, name, description, layout, COALESCE(is_public, false), COALESCE(parameters, '[]'),
-		           COALESCE(is_draft, false), draft_of, created_at, updated_at`,
-		dashboardID,
+		           COALESCE(is_draft, false), draft_of, COALESCE(is_archived, false), created_by, updated_by, created_at, updated_at`,
+		dashboardID, userID,
 	).Scan(&d.ID, &d.UserID, &d.Name, &d.Description, &d.Layout, &d.IsPublic, &d.Parameters,
-		&d.IsDraft, &d.DraftOf, &d.CreatedAt, &d.UpdatedAt)
+		&d.IsDraft, &d.DraftOf, &d.IsArchived, &d.CreatedBy, &d.UpdatedBy, &d.CreatedAt, &d.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -764,7 +1606,8 @@ func (s *DashboardService) SaveAsDraft(ctx context.Context, dashboardID, userID
 	return &d, nil
 }
 
-// PublishDraft merges the draft back to the original dashboard and deletes the draft
+// PublishDraft merges the draft back to the original dashboard and deletes the draft. The
+// original's previous state is kept as a version (see GetDashboardVersions).
 func (s *DashboardService) PublishDraft(ctx context.Context, draftID, userID uuid.UUID) (*models.Dashboard, error) {
 	// Check edit permission on the draft
 	permLevel, err := s.permRepo.GetUserPermissionLevel(ctx, draftID, userID)
@@ -782,11 +1625,13 @@ func (s *DashboardService) PublishDraft(ctx context.Context, draftID, userID uui
 	var draft models.Dashboard
 	err = pool.QueryRow(ctx,
 		`SELECT id, user_id, name, description, layout, COALESCE(is_public, false), COALESCE(parameters, '[]'),
-		        COALESCE(is_draft, false), draft_of, created_at, updated_at
+		        COALESCE(is_draft, false), draft_of, COALESCE(is_archived, false), default_catalog, default_schema,
+		        created_by, updated_by, created_at, updated_at
 		 FROM dashboards WHERE id = $1`,
 		draftID,
 	).Scan(&draft.ID, &draft.UserID, &draft.Name, &draft.Description, &draft.Layout, &draft.IsPublic, &draft.Parameters,
-		&draft.IsDraft, &draft.DraftOf, &draft.CreatedAt, &draft.UpdatedAt)
+		&draft.IsDraft, &draft.DraftOf, &draft.IsArchived, &draft.DefaultCatalog, &draft.DefaultSchema,
+		&draft.CreatedBy, &draft.UpdatedBy, &draft.CreatedAt, &draft.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrNotFound
@@ -800,57 +1645,86 @@ func (s *DashboardService) PublishDraft(ctx context.Context, draftID, userID uui
 
 	originalID := *draft.DraftOf
 
-	// Start transaction
-	tx, err := pool.Begin(ctx)
+	// A draft restored from an old version may carry other parameters than the original
+	err = s.checkParameterChange(permLevel, func() (bool, error) {
+		var current json.RawMessage
+		if err := pool.QueryRow(ctx,
+			`SELECT COALESCE(parameters, '[]') FROM dashboards WHERE id = $1`, originalID,
+		).Scan(&current); err != nil {
+			return false, err
+		}
+		return parametersDiffer(current, draft.Parameters)
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer tx.Rollback(ctx)
 
-	// Update original dashboard with draft's data
+	// Overwrite the original with the draft and delete the draft in one transaction
 	var original models.Dashboard
-	err = tx.QueryRow(ctx,
-		`UPDATE dashboards SET
-		     name = $2,
-		     description = $3,
-		     layout = $4,
-		     parameters = $5,
-		     updated_at = CURRENT_TIMESTAMP
-		 WHERE id = $1
-		 RETURNING id, user_id, name, description, layout, COALESCE(is_public, false), COALESCE(parameters, '[]'),
-		           COALESCE(is_draft, false), draft_of, created_at, updated_at`,
-		originalID, draft.Name, draft.Description, draft.Layout, draft.Parameters,
-	).Scan(&original.ID, &original.UserID, &original.Name, &original.Description, &original.Layout, &original.IsPublic, &original.Parameters,
-		&original.IsDraft, &original.DraftOf, &original.CreatedAt, &original.UpdatedAt)
-	if err != nil {
-		return nil, err
-	}
+	err = database.WithTx(ctx, func(tx pgx.Tx) error {
+		// Keep the published state as a version before it is overwritten
+		if err := s.snapshotDashboard(ctx, tx, originalID, userID); err != nil {
+			return err
+		}
 
-	// Delete all widgets from original
-	_, err = tx.Exec(ctx, `DELETE FROM dashboard_widgets WHERE dashboard_id = $1`, originalID)
-	if err != nil {
-		return nil, err
-	}
+		// Update original dashboard with draft's data
+		err := tx.QueryRow(ctx,
+			`UPDATE dashboards SET
+			     name = $2,
+			     description = $3,
+			     layout = $4,
+			     parameters = $5,
+			     default_catalog = $7,
+			     default_schema = $8,
+			     timezone = (SELECT timezone FROM dashboards WHERE id = $9),
+			     updated_by = $6,
+			     updated_at = CURRENT_TIMESTAMP
+			 WHERE id = $1
+			 RETURNING id, user_id, name, description, layout, COALESCE(is_public, false), COALESCE(parameters, '[]'),
+			           COALESCE(is_draft, false), draft_of, COALESCE(is_archived, false), default_catalog, default_schema,
+			           created_by, updated_by, created_at, updated_at`,
+			originalID, draft.Name, draft.Description, draft.Layout, draft.Parameters, userID, draft.DefaultCatalog, draft.DefaultSchema,
+			draftID,
+		).Scan(&original.ID, &original.UserID, &original.Name, &original.Description, &original.Layout, &original.IsPublic, &original.Parameters,
+			&original.IsDraft, &original.DraftOf, &original.IsArchived, &original.DefaultCatalog, &original.DefaultSchema,
+			&original.CreatedBy, &original.UpdatedBy, &original.CreatedAt, &original.UpdatedAt)
+		if err != nil {
+			return err
+		}
 
-	// Copy all widgets from draft to original
-	_, err = tx.Exec(ctx,
-		`INSERT INTO dashboard_widgets (dashboard_id, name, query_id, chart_type, chart_config, position, responsive_positions)
-		 SELECT $1, name, query_id, chart_type, chart_config, position, responsive_positions
-		 FROM dashboard_widgets WHERE dashboard_id = $2`,
-		originalID, draftID,
-	)
-	if err != nil {
-		return nil, err
-	}
+		// Delete all widgets from original
+		_, err = tx.Exec(ctx, `DELETE FROM dashboard_widgets WHERE dashboard_id = $1`, originalID)
+		if err != nil {
+			return err
+		}
 
-	// Delete the draft dashboard (cascades to delete draft's widgets)
-	_, err = tx.Exec(ctx, `DELETE FROM dashboards WHERE id = $1`, draftID)
-	if err != nil {
-		return nil, err
-	}
+		// Copy all widgets from draft to original, keeping who created and last edited them
+		_, err = tx.Exec(ctx,
+			`INSERT INTO dashboard_widgets (dashboard_id, name, query_id, chart_type, chart_config, position, responsive_positions, theme_id, max_staleness_seconds, created_by, updated_by,
+			                                query_text, catalog, schema_name)
+			 SELECT $1, name, query_id, chart_type, chart_config, position, responsive_positions, theme_id, max_staleness_seconds, created_by, updated_by,
+			        query_text, catalog, schema_name
+			 FROM dashboard_widgets WHERE dashboard_id = $2`,
+			originalID, draftID,
+		)
+		if err != nil {
+			return err
+		}
 
-	// Commit transaction
-	if err := tx.Commit(ctx); err != nil {
+		// Delete the draft dashboard (cascades to delete draft's widgets)
+		_, err = tx.Exec(ctx, `DELETE FROM dashboards WHERE id = $1`, draftID)
+		if err != nil {
+			return err
+		}
+
+		// Edits made on the draft are recorded against it, so the original gets a single update
+		if err := recordDashboardEvent(ctx, tx, originalID, userID, models.DashboardEventUpdate, models.DashboardEventTargetDashboard, originalID, original.Name); err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -928,6 +1802,22 @@ func (s *DashboardService) ensureSavedQueryOwned(ctx context.Context, queryID, u
 	return nil
 }
 
+// ensureChartThemeUsable checks that a widget may reference the theme: it must be a system
+// theme or one of the user's own
+func (s *DashboardService) ensureChartThemeUsable(ctx context.Context, themeID, userID uuid.UUID) error {
+	pool := database.GetPool()
+	var exists bool
+	err := pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM chart_themes WHERE id = $1 AND (is_system = true OR user_id = $2))`, themeID, userID).
+		Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrChartThemeNotFound
+	}
+	return nil
+}
+
 // IsDraft checks if a dashboard is a draft
 func (s *DashboardService) IsDraft(ctx context.Context, dashboardID uuid.UUID) (bool, error) {
 	pool := database.GetPool()
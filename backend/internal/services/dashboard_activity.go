@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mitsume/backend/internal/database"
+	"github.com/mitsume/backend/internal/models"
+)
+
+const (
+	defaultActivityLimit = 50
+	maxActivityLimit     = 200
+)
+
+// dbExecer is satisfied by both the pool and a transaction, so events can be written
+// alongside the change they describe
+type dbExecer interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+}
+
+// recordDashboardEvent appends an entry to the dashboard's activity feed. Inside a
+// transaction the error must be returned so the change and its event commit together;
+// outside one, callers log and ignore it so the feed cannot fail the change itself.
+func recordDashboardEvent(ctx context.Context, db dbExecer, dashboardID, actorID uuid.UUID, action, targetType string, targetID uuid.UUID, targetName string) error {
+	_, err := db.Exec(ctx,
+		`INSERT INTO dashboard_events (dashboard_id, actor_id, action, target_type, target_id, target_name)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		dashboardID, actorID, action, targetType, targetID, targetName,
+	)
+	if err != nil {
+		log.Printf("[WARN] Failed to record dashboard event %s %s %s on %s: %v", action, targetType, targetID, dashboardID, err)
+	}
+	return err
+}
+
+// GetDashboardActivity returns a page of the dashboard's events, newest first, with the total.
+// Permission checks must be performed by the caller.
+func (s *DashboardService) GetDashboardActivity(ctx context.Context, dashboardID uuid.UUID, limit, offset int) (*models.DashboardActivityResponse, error) {
+	limit, offset = normalizeActivityPage(limit, offset)
+	pool := database.GetPool()
+
+	var total int
+	if err := pool.QueryRow(ctx, `SELECT COUNT(*) FROM dashboard_events WHERE dashboard_id = $1`, dashboardID).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count dashboard activity: %w", err)
+	}
+
+	rows, err := pool.Query(ctx,
+		`SELECT e.id, e.dashboard_id, e.actor_id, u.name, e.action, e.target_type, e.target_id, e.target_name, e.created_at
+		 FROM dashboard_events e
+		 LEFT JOIN users u ON u.id = e.actor_id
+		 WHERE e.dashboard_id = $1
+		 ORDER BY e.created_at DESC
+		 LIMIT $2 OFFSET $3`,
+		dashboardID, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dashboard activity: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.DashboardEvent{}
+	for rows.Next() {
+		var e models.DashboardEvent
+		if err := rows.Scan(&e.ID, &e.DashboardID, &e.ActorID, &e.ActorName, &e.Action, &e.TargetType,
+			&e.TargetID, &e.TargetName, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan dashboard event: %w", err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &models.DashboardActivityResponse{Events: events, Total: total}, nil
+}
+
+func normalizeActivityPage(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = defaultActivityLimit
+	}
+	if limit > maxActivityLimit {
+		limit = maxActivityLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
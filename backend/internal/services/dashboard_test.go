@@ -0,0 +1,480 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+)
+
+// fakeRow is a pgx.Row returning fixed values
+type fakeRow []interface{}
+
+func (r fakeRow) Scan(dest ...interface{}) error {
+	if len(dest) != len(r) {
+		return errors.New("column count mismatch")
+	}
+	for i, v := range r {
+		switch d := dest[i].(type) {
+		case *uuid.UUID:
+			*d = v.(uuid.UUID)
+		case **uuid.UUID:
+			*d = v.(*uuid.UUID)
+		case *string:
+			*d = v.(string)
+		case **string:
+			*d = v.(*string)
+		case *json.RawMessage:
+			*d = v.(json.RawMessage)
+		case **int:
+			*d = v.(*int)
+		case *time.Time:
+			*d = v.(time.Time)
+		case **time.Time:
+			*d = v.(*time.Time)
+		default:
+			return errors.New("unsupported scan destination")
+		}
+	}
+	return nil
+}
+
+func TestScanWidget_EditorAttribution(t *testing.T) {
+	owner := uuid.New()
+	editor := uuid.New() // non-owner with edit permission
+	now := time.Now()
+
+	w, err := scanWidget(fakeRow{
+		uuid.New(), uuid.New(), "Revenue", (*uuid.UUID)(nil), (*string)(nil), (*string)(nil), (*string)(nil), "line",
+		json.RawMessage(`{}`), json.RawMessage(`{}`), json.RawMessage(nil),
+		(*uuid.UUID)(nil), json.RawMessage(nil), (*int)(nil), (*string)(nil), (*time.Time)(nil),
+		&owner, &editor, now, now,
+	})
+	if err != nil {
+		t.Fatalf("scanWidget() error = %v", err)
+	}
+	if w.CreatedBy == nil || *w.CreatedBy != owner {
+		t.Fatalf("CreatedBy = %v, want %v", w.CreatedBy, owner)
+	}
+	if w.UpdatedBy == nil || *w.UpdatedBy != editor {
+		t.Fatalf("UpdatedBy = %v, want %v", w.UpdatedBy, editor)
+	}
+
+	body, err := json.Marshal(w)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var decoded models.Widget
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if decoded.UpdatedBy == nil || *decoded.UpdatedBy != editor {
+		t.Fatalf("updated_by in response = %v, want %v", decoded.UpdatedBy, editor)
+	}
+}
+
+func TestValidateDashboardImport(t *testing.T) {
+	ref := "q1"
+	unknown := "missing"
+	valid := func() *models.DashboardExport {
+		return &models.DashboardExport{
+			Version:   models.DashboardExportVersion,
+			Dashboard: models.ExportedDashboard{Name: "Sales"},
+			Widgets: []models.ExportedWidget{
+				{Name: "Revenue", QueryRef: &ref, ChartType: "line"},
+				{Name: "Notes", ChartType: "markdown"},
+			},
+			Queries: []models.ExportedQuery{{Ref: ref, Name: "revenue", QueryText: "SELECT 1"}},
+		}
+	}
+
+	if err := ValidateDashboardImport(valid()); err != nil {
+		t.Fatalf("ValidateDashboardImport() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(doc *models.DashboardExport)
+		field  string
+	}{
+		{"unsupported version", func(doc *models.DashboardExport) { doc.Version = models.DashboardExportVersion + 1 }, "version"},
+		{"unknown query ref", func(doc *models.DashboardExport) { doc.Widgets[0].QueryRef = &unknown }, "widgets[0].query_ref"},
+		{"duplicate query ref", func(doc *models.DashboardExport) { doc.Queries = append(doc.Queries, doc.Queries[0]) }, "queries[1].ref"},
+		{"query without text", func(doc *models.DashboardExport) { doc.Queries[0].QueryText = "" }, "queries[0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := valid()
+			tt.mutate(doc)
+			err := ValidateDashboardImport(doc)
+			var validationErr *models.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("ValidateDashboardImport() error = %v, want *models.ValidationError", err)
+			}
+			if validationErr.Field != tt.field {
+				t.Fatalf("field = %q, want %q", validationErr.Field, tt.field)
+			}
+		})
+	}
+}
+
+func TestCheckDashboardImportStatements(t *testing.T) {
+	readOnly := NewQueryService(nil)
+	readOnly.SetReadOnlyMode(true)
+	inline := "SELECT * FROM orders"
+	doc := func() *models.DashboardExport {
+		return &models.DashboardExport{
+			Version:   models.DashboardExportVersion,
+			Dashboard: models.ExportedDashboard{Name: "Sales"},
+			Widgets:   []models.ExportedWidget{{Name: "Notes", ChartType: "markdown"}, {Name: "Orders", QueryText: &inline, ChartType: "table"}},
+			Queries:   []models.ExportedQuery{{Ref: "q1", Name: "revenue", QueryText: "SELECT 1"}},
+		}
+	}
+
+	if err := CheckDashboardImportStatements(doc(), readOnly); err != nil {
+		t.Fatalf("CheckDashboardImportStatements() error = %v", err)
+	}
+
+	drop := "DROP TABLE hive.sales.orders"
+	tests := []struct {
+		name   string
+		mutate func(doc *models.DashboardExport)
+		field  string
+	}{
+		{"saved query", func(doc *models.DashboardExport) { doc.Queries[0].QueryText = "DELETE FROM hive.sales.orders" }, "queries[0].query_text"},
+		{"inline widget SQL", func(doc *models.DashboardExport) { doc.Widgets[1].QueryText = &drop }, "widgets[1].query_text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := doc()
+			tt.mutate(d)
+			if err := CheckDashboardImportStatements(d, NewQueryService(nil)); err != nil {
+				t.Fatalf("CheckDashboardImportStatements() error = %v, want nil outside read-only mode", err)
+			}
+			err := CheckDashboardImportStatements(d, readOnly)
+			var validationErr *models.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("CheckDashboardImportStatements() error = %v, want *models.ValidationError", err)
+			}
+			if validationErr.Field != tt.field {
+				t.Fatalf("field = %q, want %q", validationErr.Field, tt.field)
+			}
+		})
+	}
+}
+
+func TestNormalizeActivityPage(t *testing.T) {
+	tests := []struct {
+		limit, offset         int
+		wantLimit, wantOffset int
+	}{
+		{0, 0, defaultActivityLimit, 0},
+		{-1, -5, defaultActivityLimit, 0},
+		{20, 40, 20, 40},
+		{maxActivityLimit + 1, 0, maxActivityLimit, 0},
+	}
+
+	for _, tt := range tests {
+		limit, offset := normalizeActivityPage(tt.limit, tt.offset)
+		if limit != tt.wantLimit || offset != tt.wantOffset {
+			t.Errorf("normalizeActivityPage(%d, %d) = (%d, %d), want (%d, %d)",
+				tt.limit, tt.offset, limit, offset, tt.wantLimit, tt.wantOffset)
+		}
+	}
+}
+
+func TestCheckUniqueName(t *testing.T) {
+	existsIs := func(found bool, called *bool) func() (bool, error) {
+		return func() (bool, error) {
+			*called = true
+			return found, nil
+		}
+	}
+
+	var called bool
+	enforced := &DashboardService{uniqueNames: true}
+	if err := enforced.checkUniqueName(existsIs(true, &called)); !errors.Is(err, ErrDuplicateDashboardName) {
+		t.Fatalf("checkUniqueName() with duplicate error = %v, want ErrDuplicateDashboardName", err)
+	}
+	if err := enforced.checkUniqueName(existsIs(false, &called)); err != nil {
+		t.Fatalf("checkUniqueName() without duplicate error = %v, want nil", err)
+	}
+
+	called = false
+	allowed := &DashboardService{}
+	if err := allowed.checkUniqueName(existsIs(true, &called)); err != nil {
+		t.Fatalf("checkUniqueName() with flag off error = %v, want duplicates allowed", err)
+	}
+	if called {
+		t.Fatal("checkUniqueName() with flag off looked up the name")
+	}
+}
+
+func TestHiddenDashboardError(t *testing.T) {
+	ctx := context.Background()
+	admin, user := uuid.New(), uuid.New()
+	admins := fakeAdminChecker{admin: true}
+
+	hidden := &DashboardService{admins: admins}
+	for _, id := range []uuid.UUID{admin, user} {
+		if err := hidden.hiddenDashboardError(ctx, id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("hiddenDashboardError() by default = %v, want ErrNotFound", err)
+		}
+	}
+
+	revealed := &DashboardService{}
+	revealed.SetRevealForbidden(true, admins)
+	if err := revealed.hiddenDashboardError(ctx, admin); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("hiddenDashboardError() for an admin = %v, want ErrPermissionDenied", err)
+	}
+	if err := revealed.hiddenDashboardError(ctx, user); !errors.Is(err, ErrNotFound) {
+		t.Errorf("hiddenDashboardError() for a regular user = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCheckParameterChange(t *testing.T) {
+	current := json.RawMessage(`[{"name":"region","type":"text","sql_format":"string"}]`)
+	reordered := json.RawMessage(`[{"sql_format":"string", "type":"text", "name":"region"}]`)
+	changed := json.RawMessage(`[{"name":"region","type":"number","sql_format":"number"}]`)
+	differ := func(next json.RawMessage) func() (bool, error) {
+		return func() (bool, error) { return parametersDiffer(current, next) }
+	}
+
+	open := &DashboardService{}
+	if err := open.checkParameterChange(models.PermissionEdit, differ(changed)); err != nil {
+		t.Errorf("checkParameterChange() by an editor without the restriction = %v, want nil", err)
+	}
+
+	restricted := &DashboardService{}
+	restricted.SetOwnerOnlyParameters(true)
+	if err := restricted.checkParameterChange(models.PermissionEdit, differ(changed)); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("checkParameterChange() by an editor = %v, want ErrPermissionDenied", err)
+	}
+	if err := restricted.checkParameterChange(models.PermissionOwner, differ(changed)); err != nil {
+		t.Errorf("checkParameterChange() by the owner = %v, want nil", err)
+	}
+	if err := restricted.checkParameterChange(models.PermissionEdit, differ(reordered)); err != nil {
+		t.Errorf("checkParameterChange() by an editor resending the same parameters = %v, want nil", err)
+	}
+	if err := restricted.checkParameterChange(models.PermissionEdit, func() (bool, error) {
+		return parametersDiffer(nil, json.RawMessage(`[]`))
+	}); err != nil {
+		t.Errorf("checkParameterChange() with no parameters before and after = %v, want nil", err)
+	}
+}
+
+func TestPurgeDeletedDashboards_WarnsOwnersFirst(t *testing.T) {
+	pool := setupHistoryDatabase(t)
+	ctx := context.Background()
+	retention, warning := 30*24*time.Hour, 3*24*time.Hour
+
+	var userID uuid.UUID
+	err := pool.QueryRow(ctx,
+		`INSERT INTO users (email, name) VALUES ($1, 'trash test') RETURNING id`,
+		"trash-"+uuid.NewString()+"@example.com",
+	).Scan(&userID)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() { _, _ = pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID) })
+
+	trash := func(name string, daysAgo int) uuid.UUID {
+		var id uuid.UUID
+		err := pool.QueryRow(ctx,
+			`INSERT INTO dashboards (user_id, name, deleted_at)
+			 VALUES ($1, $2, CURRENT_TIMESTAMP - $3 * INTERVAL '1 day') RETURNING id`,
+			userID, name, daysAgo,
+		).Scan(&id)
+		if err != nil {
+			t.Fatalf("failed to create dashboard: %v", err)
+		}
+		return id
+	}
+	exists := func(id uuid.UUID) bool {
+		var ok bool
+		if err := pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM dashboards WHERE id = $1)`, id).Scan(&ok); err != nil {
+			t.Fatalf("failed to look up dashboard: %v", err)
+		}
+		return ok
+	}
+	due := trash("due", 28)
+	recent := trash("recent", 10)
+
+	s := &DashboardService{}
+	claimed, err := s.ClaimPurgeWarnings(ctx, retention, warning)
+	if err != nil {
+		t.Fatalf("ClaimPurgeWarnings() error = %v", err)
+	}
+	var claimedDue, claimedRecent bool
+	for _, d := range claimed {
+		claimedDue = claimedDue || d.ID == due
+		claimedRecent = claimedRecent || d.ID == recent
+	}
+	if !claimedDue || claimedRecent {
+		t.Fatalf("ClaimPurgeWarnings() = %v, want the dashboard due within the warning period only", claimed)
+	}
+	again, err := s.ClaimPurgeWarnings(ctx, retention, warning)
+	if err != nil {
+		t.Fatalf("ClaimPurgeWarnings() error = %v", err)
+	}
+	for _, d := range again {
+		if d.ID == due {
+			t.Fatalf("ClaimPurgeWarnings() warned about %s twice", due)
+		}
+	}
+
+	// Past the retention, but warned too recently
+	if _, err := pool.Exec(ctx, `UPDATE dashboards SET deleted_at = CURRENT_TIMESTAMP - INTERVAL '40 days' WHERE id = $1`, due); err != nil {
+		t.Fatalf("failed to backdate dashboard: %v", err)
+	}
+	if _, err := s.PurgeDeletedDashboards(ctx, retention, warning); err != nil {
+		t.Fatalf("PurgeDeletedDashboards() error = %v", err)
+	}
+	if !exists(due) {
+		t.Fatal("dashboard was purged before its owner had the full warning period")
+	}
+
+	// An undelivered warning is released and claimed again on the next run
+	if err := s.ReleasePurgeWarning(ctx, due); err != nil {
+		t.Fatalf("ReleasePurgeWarning() error = %v", err)
+	}
+	if _, err := s.PurgeDeletedDashboards(ctx, retention, warning); err != nil {
+		t.Fatalf("PurgeDeletedDashboards() error = %v", err)
+	}
+	if !exists(due) {
+		t.Fatal("dashboard was purged after its warning was released")
+	}
+	reclaimed, err := s.ClaimPurgeWarnings(ctx, retention, warning)
+	if err != nil {
+		t.Fatalf("ClaimPurgeWarnings() error = %v", err)
+	}
+	var reclaimedDue bool
+	for _, d := range reclaimed {
+		reclaimedDue = reclaimedDue || d.ID == due
+	}
+	if !reclaimedDue {
+		t.Fatal("ClaimPurgeWarnings() did not claim the dashboard again after its warning was released")
+	}
+
+	if _, err := pool.Exec(ctx, `UPDATE dashboards SET purge_warned_at = CURRENT_TIMESTAMP - INTERVAL '4 days' WHERE id = $1`, due); err != nil {
+		t.Fatalf("failed to backdate warning: %v", err)
+	}
+	if _, err := s.PurgeDeletedDashboards(ctx, retention, warning); err != nil {
+		t.Fatalf("PurgeDeletedDashboards() error = %v", err)
+	}
+	if exists(due) {
+		t.Fatal("dashboard was not purged after the warning period")
+	}
+	if !exists(recent) {
+		t.Fatal("dashboard within the retention was purged")
+	}
+}
+
+func TestCloneDashboard(t *testing.T) {
+	pool := setupHistoryDatabase(t)
+	ctx := context.Background()
+
+	createUser := func(t *testing.T) uuid.UUID {
+		var id uuid.UUID
+		err := pool.QueryRow(ctx,
+			`INSERT INTO users (email, name) VALUES ($1, 'clone test') RETURNING id`,
+			"clone-"+uuid.NewString()+"@example.com",
+		).Scan(&id)
+		if err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		t.Cleanup(func() { _, _ = pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, id) })
+		return id
+	}
+	owner := createUser(t)
+	viewer := createUser(t)
+	editor := createUser(t)
+
+	var published uuid.UUID
+	err := pool.QueryRow(ctx,
+		`INSERT INTO dashboards (user_id, name, is_public) VALUES ($1, 'Sales', true) RETURNING id`, owner,
+	).Scan(&published)
+	if err != nil {
+		t.Fatalf("failed to create dashboard: %v", err)
+	}
+	var draft uuid.UUID
+	err = pool.QueryRow(ctx,
+		`INSERT INTO dashboards (user_id, name, is_draft, draft_of) VALUES ($1, 'Sales', true, $2) RETURNING id`, owner, published,
+	).Scan(&draft)
+	if err != nil {
+		t.Fatalf("failed to create draft: %v", err)
+	}
+	if _, err := pool.Exec(ctx,
+		`INSERT INTO dashboard_widgets (dashboard_id, name, chart_type, position, query_text)
+		 VALUES ($1, 'Revenue', 'bar', '{"x":0,"y":0,"w":6,"h":4}', 'SELECT 1')`, published,
+	); err != nil {
+		t.Fatalf("failed to create widget: %v", err)
+	}
+	for user, level := range map[uuid.UUID]string{viewer: "view", editor: "edit"} {
+		if _, err := pool.Exec(ctx,
+			`INSERT INTO dashboard_permissions (dashboard_id, user_id, permission_level) VALUES ($1, $2, $3)`,
+			published, user, level,
+		); err != nil {
+			t.Fatalf("failed to grant permission: %v", err)
+		}
+	}
+
+	s := NewDashboardService()
+
+	t.Run("viewer clones a published dashboard", func(t *testing.T) {
+		clone, err := s.CloneDashboard(ctx, published, viewer)
+		if err != nil {
+			t.Fatalf("CloneDashboard() error = %v", err)
+		}
+		if clone.UserID != viewer || clone.Name != "Sales (Copy)" || clone.IsDraft {
+			t.Errorf("clone = %+v, want a published copy named %q owned by the viewer", clone, "Sales (Copy)")
+		}
+		if len(clone.Widgets) != 1 || clone.Widgets[0].Name != "Revenue" {
+			t.Errorf("clone widgets = %+v, want the source's widget", clone.Widgets)
+		}
+		if clone.Widgets[0].CreatedBy == nil || *clone.Widgets[0].CreatedBy != viewer {
+			t.Errorf("clone widget CreatedBy = %v, want the viewer", clone.Widgets[0].CreatedBy)
+		}
+	})
+
+	t.Run("permissions and visibility are not copied", func(t *testing.T) {
+		clone, err := s.CloneDashboard(ctx, published, viewer)
+		if err != nil {
+			t.Fatalf("CloneDashboard() error = %v", err)
+		}
+		var isPublic bool
+		var grants int
+		if err := pool.QueryRow(ctx,
+			`SELECT COALESCE(is_public, false), (SELECT COUNT(*) FROM dashboard_permissions WHERE dashboard_id = $1)
+			 FROM dashboards WHERE id = $1`, clone.ID,
+		).Scan(&isPublic, &grants); err != nil {
+			t.Fatalf("failed to load clone: %v", err)
+		}
+		if isPublic || clone.IsPublic {
+			t.Error("clone is public, want private")
+		}
+		if grants != 0 {
+			t.Errorf("clone has %d permission grants, want 0", grants)
+		}
+		level, err := s.permRepo.GetUserPermissionLevel(ctx, clone.ID, editor)
+		if err != nil {
+			t.Fatalf("GetUserPermissionLevel() error = %v", err)
+		}
+		if level.CanView() {
+			t.Errorf("editor of the source has %q on the clone, want no access", level)
+		}
+	})
+
+	t.Run("viewer cannot clone a draft", func(t *testing.T) {
+		if _, err := s.CloneDashboard(ctx, draft, viewer); !errors.Is(err, ErrPermissionDenied) {
+			t.Fatalf("CloneDashboard() of a draft by a viewer error = %v, want %v", err, ErrPermissionDenied)
+		}
+		if _, err := s.CloneDashboard(ctx, draft, editor); err != nil {
+			t.Fatalf("CloneDashboard() of a draft by an editor error = %v", err)
+		}
+	})
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mitsume/backend/internal/database"
+	"github.com/mitsume/backend/internal/models"
+)
+
+// SetMaxVersions caps the versions kept per dashboard; older ones are pruned as new versions
+// are taken. 0 keeps every version.
+func (s *DashboardService) SetMaxVersions(n int) {
+	s.maxVersions = n
+}
+
+// snapshotDashboard records the dashboard's current state and widgets as its next version and
+// prunes versions beyond the cap. It runs inside PublishDraft's transaction, before the
+// dashboard is overwritten.
+func (s *DashboardService) snapshotDashboard(ctx context.Context, tx pgx.Tx, dashboardID, userID uuid.UUID) error {
+	var version int
+	err := tx.QueryRow(ctx,
+		`INSERT INTO dashboard_versions (dashboard_id, version, name, description, layout, parameters,
+		                                 default_catalog, default_schema, widgets, created_by)
+		 SELECT d.id,
+		        COALESCE((SELECT MAX(version) FROM dashboard_versions WHERE dashboard_id = d.id), 0) + 1,
+		        d.name, d.description, d.layout, d.parameters, d.default_catalog, d.default_schema,
+		        (SELECT COALESCE(jsonb_agg(jsonb_build_object(
+		                    'name', w.name,
+		                    'query_id', w.query_id,
+		                    'query_text', w.query_text,
+		                    'catalog', w.catalog,
+		                    'schema_name', w.schema_name,
+		                    'chart_type', w.chart_type,
+		                    'chart_config', w.chart_config,
+		                    'position', w.position,
+		                    'responsive_positions', w.responsive_positions,
+		                    'theme_id', w.theme_id,
+		                    'max_staleness_seconds', w.max_staleness_seconds,
+		                    'created_by', w.created_by,
+		                    'updated_by', w.updated_by
+		                ) ORDER BY w.created_at), '[]'::jsonb)
+		         FROM dashboard_widgets w WHERE w.dashboard_id = d.id),
+		        $2
+		 FROM dashboards d WHERE d.id = $1
+		 RETURNING version`,
+		dashboardID, userID,
+	).Scan(&version)
+	if err != nil {
+		return fmt.Errorf("failed to snapshot dashboard: %w", err)
+	}
+
+	if s.maxVersions > 0 {
+		if _, err := tx.Exec(ctx,
+			`DELETE FROM dashboard_versions WHERE dashboard_id = $1 AND version <= $2`,
+			dashboardID, version-s.maxVersions,
+		); err != nil {
+			return fmt.Errorf("failed to prune dashboard versions: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetDashboardVersions lists the dashboard's versions, newest first (view permission)
+func (s *DashboardService) GetDashboardVersions(ctx context.Context, dashboardID, userID uuid.UUID) ([]models.DashboardVersion, error) {
+	permLevel, err := s.permRepo.GetUserPermissionLevel(ctx, dashboardID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !permLevel.CanView() {
+		return nil, ErrPermissionDenied
+	}
+
+	pool := database.GetPool()
+
+	rows, err := pool.Query(ctx,
+		`SELECT dashboard_id, version, name, description, jsonb_array_length(widgets), created_by, created_at
+		 FROM dashboard_versions
+		 WHERE dashboard_id = $1
+		 ORDER BY version DESC`,
+		dashboardID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dashboard versions: %w", err)
+	}
+	defer rows.Close()
+
+	versions := []models.DashboardVersion{}
+	for rows.Next() {
+		var v models.DashboardVersion
+		if err := rows.Scan(&v.DashboardID, &v.Version, &v.Name, &v.Description, &v.WidgetCount,
+			&v.CreatedBy, &v.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan dashboard version: %w", err)
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// RestoreDashboardVersion creates a draft of the dashboard from one of its versions (edit
+// permission). The published dashboard is unchanged until the draft is published. Widgets whose
+// query or theme has since been deleted are restored without it.
+func (s *DashboardService) RestoreDashboardVersion(ctx context.Context, dashboardID uuid.UUID, version int, userID uuid.UUID) (*models.Dashboard, error) {
+	permLevel, err := s.permRepo.GetUserPermissionLevel(ctx, dashboardID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !permLevel.CanEdit() {
+		return nil, ErrPermissionDenied
+	}
+
+	pool := database.GetPool()
+
+	existingDraft, err := s.GetDraft(ctx, dashboardID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if existingDraft != nil {
+		return nil, ErrDraftExists
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var draft models.Dashboard
+	err = tx.QueryRow(ctx,
+		`INSERT INTO dashboards (user_id, name, description, layout, is_public, parameters, is_draft, draft_of,
+		                         default_catalog, default_schema, created_by, updated_by)
+		 SELECT d.user_id, v.name, v.description, v.layout, false, COALESCE(v.parameters, '[]'), true, d.id,
+		        v.default_catalog, v.default_schema, $3, $3
+		 FROM dashboard_versions v
+		 JOIN dashboards d ON d.id = v.dashboard_id AND NOT COALESCE(d.is_draft, false)
+		 WHERE v.dashboard_id = $1 AND v.version = $2
+		 RETURNING id, user_id, name, description, layout, COALESCE(is_public, false), COALESCE(parameters, '[]'),
+		           COALESCE(is_draft, false), draft_of, COALESCE(is_archived, false), default_catalog, default_schema,
+		           created_by, updated_by, created_at, updated_at`,
+		dashboardID, version, userID,
+	).Scan(&draft.ID, &draft.UserID, &draft.Name, &draft.Description, &draft.Layout, &draft.IsPublic, &draft.Parameters,
+		&draft.IsDraft, &draft.DraftOf, &draft.IsArchived, &draft.DefaultCatalog, &draft.DefaultSchema,
+		&draft.CreatedBy, &draft.UpdatedBy, &draft.CreatedAt, &draft.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		// A draft was created concurrently
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrDraftExists
+		}
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO dashboard_widgets (dashboard_id, name, query_id, chart_type, chart_config, position, responsive_positions, theme_id, max_staleness_seconds, created_by, updated_by,
+		                                query_text, catalog, schema_name)
+		 SELECT $1, w.name, q.id, w.chart_type, w.chart_config, w.position, w.responsive_positions, t.id, w.max_staleness_seconds, cu.id, uu.id,
+		        w.query_text, w.catalog, w.schema_name
+		 FROM dashboard_versions v,
+		      jsonb_to_recordset(v.widgets) AS w(name text, query_id uuid, query_text text, catalog text, schema_name text,
+		                                         chart_type text, chart_config jsonb, position jsonb,
+		                                         responsive_positions jsonb, theme_id uuid, max_staleness_seconds int,
+		                                         created_by uuid, updated_by uuid)
+		 LEFT JOIN saved_queries q ON q.id = w.query_id
+		 LEFT JOIN chart_themes t ON t.id = w.theme_id
+		 LEFT JOIN users cu ON cu.id = w.created_by
+		 LEFT JOIN users uu ON uu.id = w.updated_by
+		 WHERE v.dashboard_id = $2 AND v.version = $3`,
+		draft.ID, dashboardID, version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	draft.MyPermission = permLevel
+
+	widgets, err := s.GetWidgets(ctx, draft.ID)
+	if err != nil {
+		return nil, err
+	}
+	draft.Widgets = widgets
+
+	return &draft, nil
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mitsume/backend/internal/models"
+)
+
+const (
+	maintenanceSettingKey = "maintenance"
+
+	// maintenanceRefresh bounds how long another instance keeps running queries after an admin
+	// turns maintenance on, while keeping the per-request check off the database
+	maintenanceRefresh = 5 * time.Second
+
+	DefaultMaintenanceMessage = "Query execution is paused for maintenance. Please try again later."
+)
+
+// MaintenanceChecker reports whether query execution is paused and the message to show
+type MaintenanceChecker interface {
+	InMaintenance(ctx context.Context) (bool, string)
+}
+
+// maintenanceSetting is the JSON stored in system_settings under maintenanceSettingKey
+type maintenanceSetting struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message,omitempty"`
+}
+
+// MaintenanceService stores the global maintenance switch in system_settings
+type MaintenanceService struct {
+	pool  *pgxpool.Pool
+	cache maintenanceCache
+}
+
+// NewMaintenanceService creates a new maintenance service
+func NewMaintenanceService(pool *pgxpool.Pool) *MaintenanceService {
+	return &MaintenanceService{pool: pool, cache: maintenanceCache{refresh: maintenanceRefresh}}
+}
+
+// Status reads the current maintenance status from the database
+func (s *MaintenanceService) Status(ctx context.Context) (*models.MaintenanceStatus, error) {
+	var value []byte
+	status := &models.MaintenanceStatus{}
+	err := s.pool.QueryRow(ctx,
+		`SELECT value, updated_by, updated_at FROM system_settings WHERE key = $1`,
+		maintenanceSettingKey,
+	).Scan(&value, &status.UpdatedBy, &status.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get maintenance status: %w", err)
+	}
+
+	var setting maintenanceSetting
+	if err := json.Unmarshal(value, &setting); err != nil {
+		return nil, fmt.Errorf("failed to decode maintenance status: %w", err)
+	}
+	status.Enabled = setting.Enabled
+	status.Message = setting.Message
+	return status, nil
+}
+
+// SetStatus turns maintenance mode on or off. This instance sees the change immediately;
+// other instances pick it up within maintenanceRefresh.
+func (s *MaintenanceService) SetStatus(ctx context.Context, userID uuid.UUID, req *models.UpdateMaintenanceRequest) (*models.MaintenanceStatus, error) {
+	value, err := json.Marshal(maintenanceSetting{Enabled: *req.Enabled, Message: req.Message})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode maintenance status: %w", err)
+	}
+
+	status := &models.MaintenanceStatus{Enabled: *req.Enabled, Message: req.Message}
+	err = s.pool.QueryRow(ctx,
+		`INSERT INTO system_settings (key, value, updated_by, updated_at)
+		 VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		 ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at
+		 RETURNING updated_by, updated_at`,
+		maintenanceSettingKey, value, userID,
+	).Scan(&status.UpdatedBy, &status.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update maintenance status: %w", err)
+	}
+
+	s.cache.store(*status, time.Now())
+	return status, nil
+}
+
+// InMaintenance reports whether maintenance mode is on, from a copy refreshed at most every
+// maintenanceRefresh. If the refresh fails the last known state is kept. A nil service is
+// never in maintenance.
+func (s *MaintenanceService) InMaintenance(ctx context.Context) (bool, string) {
+	if s == nil || s.pool == nil {
+		return false, ""
+	}
+	status := s.cache.get(time.Now(), func() (*models.MaintenanceStatus, error) {
+		return s.Status(ctx)
+	})
+	if !status.Enabled {
+		return false, ""
+	}
+	if status.Message == "" {
+		return true, DefaultMaintenanceMessage
+	}
+	return true, status.Message
+}
+
+// maintenanceCache holds the last loaded maintenance status
+type maintenanceCache struct {
+	mu       sync.Mutex
+	refresh  time.Duration
+	status   models.MaintenanceStatus
+	loadedAt time.Time
+}
+
+// get returns the cached status, calling load first when it is older than refresh.
+// A failed load is logged and retried on the next refresh.
+func (c *maintenanceCache) get(now time.Time, load func() (*models.MaintenanceStatus, error)) models.MaintenanceStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.loadedAt.IsZero() && now.Sub(c.loadedAt) < c.refresh {
+		return c.status
+	}
+
+	status, err := load()
+	c.loadedAt = now
+	if err != nil {
+		log.Printf("[WARN] Failed to refresh maintenance status, keeping enabled=%t: %v", c.status.Enabled, err)
+		return c.status
+	}
+	c.status = *status
+	return c.status
+}
+
+func (c *maintenanceCache) store(status models.MaintenanceStatus, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status = status
+	c.loadedAt = now
+}
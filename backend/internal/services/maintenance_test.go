@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mitsume/backend/internal/models"
+)
+
+func TestMaintenanceCache_RefreshesAfterInterval(t *testing.T) {
+	cache := maintenanceCache{refresh: 5 * time.Second}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	loads := 0
+	enabled := false
+	load := func() (*models.MaintenanceStatus, error) {
+		loads++
+		return &models.MaintenanceStatus{Enabled: enabled}, nil
+	}
+
+	if status := cache.get(now, load); status.Enabled || loads != 1 {
+		t.Fatalf("first get: enabled=%t loads=%d, want disabled after 1 load", status.Enabled, loads)
+	}
+
+	enabled = true
+	if status := cache.get(now.Add(4*time.Second), load); status.Enabled || loads != 1 {
+		t.Fatalf("within refresh: enabled=%t loads=%d, want the cached status", status.Enabled, loads)
+	}
+	if status := cache.get(now.Add(5*time.Second), load); !status.Enabled || loads != 2 {
+		t.Fatalf("after refresh: enabled=%t loads=%d, want the reloaded status", status.Enabled, loads)
+	}
+}
+
+func TestMaintenanceCache_KeepsStateOnLoadError(t *testing.T) {
+	cache := maintenanceCache{refresh: time.Second}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache.store(models.MaintenanceStatus{Enabled: true, Message: "upgrading"}, now)
+
+	status := cache.get(now.Add(2*time.Second), func() (*models.MaintenanceStatus, error) {
+		return nil, errors.New("connection refused")
+	})
+	if !status.Enabled || status.Message != "upgrading" {
+		t.Fatalf("status = %+v, want the last known status", status)
+	}
+}
+
+func TestMaintenanceService_NilIsNeverInMaintenance(t *testing.T) {
+	var s *MaintenanceService
+	if active, _ := s.InMaintenance(context.Background()); active {
+		t.Fatal("nil service reported maintenance")
+	}
+}
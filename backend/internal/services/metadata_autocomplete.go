@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/repository"
+)
+
+// Autocomplete suggestion limits; suggestions are capped small to keep keystroke requests fast
+const (
+	DefaultAutocompleteLimit = 10
+	MaxAutocompleteLimit     = 25
+)
+
+// autocompleteSearchLimit is how many hits are requested from SearchMetadata before prefix
+// filtering. SearchMetadata matches substrings, so it returns more hits than are kept.
+const autocompleteSearchLimit = 100
+
+// suggestionKindRank orders suggestion kinds from the broadest to the narrowest
+var suggestionKindRank = map[string]int{"catalog": 0, "schema": 1, "table": 2, "column": 3}
+
+// AutocompleteMetadata suggests catalogs, schemas, tables and columns whose names start with
+// prefix (ignoring case), narrowed to catalog and schema when given. catalogs are the catalogs
+// the user may see and allowedSchemas the schemas within them (nil means all), as returned by
+// RoleService for the user. Exact matches rank first, then broader kinds, then shorter names.
+func AutocompleteMetadata(ctx context.Context, executor repository.TrinoExecutor, prefix, catalog, schema string, catalogs []string, allowedSchemas []models.SchemaPermission, limit int) ([]models.MetadataSuggestion, error) {
+	if limit <= 0 {
+		limit = DefaultAutocompleteLimit
+	}
+	limit = min(limit, MaxAutocompleteLimit)
+	lowerPrefix := strings.ToLower(prefix)
+	matches := func(name string) bool {
+		return strings.HasPrefix(strings.ToLower(name), lowerPrefix)
+	}
+
+	suggestions := []models.MetadataSuggestion{}
+	scope := catalogs
+	if catalog == "" {
+		for _, c := range catalogs {
+			if matches(c) {
+				suggestions = append(suggestions, models.MetadataSuggestion{Kind: "catalog", Name: c, Catalog: c})
+			}
+		}
+	} else {
+		scope = []string{catalog}
+	}
+
+	switch {
+	case catalog != "" && schema == "":
+		schemas, err := executor.GetSchemas(ctx, catalog)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range FilterAllowedSchemas(allowedSchemas, catalog, schemas) {
+			if matches(s) {
+				suggestions = append(suggestions, models.MetadataSuggestion{Kind: "schema", Name: s, Catalog: catalog, Schema: s})
+			}
+		}
+	case catalog != "" && schema != "" && schemaVisible(allowedSchemas, catalog, schema):
+		// Within a schema every table is listed, even before anything is typed
+		tables, err := executor.GetTables(ctx, catalog, schema)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tables {
+			if matches(t) {
+				suggestions = append(suggestions, models.MetadataSuggestion{Kind: "table", Name: t, Catalog: catalog, Schema: schema, Table: t})
+			}
+		}
+	}
+
+	if prefix != "" {
+		searchType := "all"
+		if schema != "" {
+			searchType = "column" // tables of the schema are listed above
+		}
+		hits, err := executor.SearchMetadata(ctx, prefix, searchType, scope, autocompleteSearchLimit)
+		if err != nil {
+			return nil, err
+		}
+		for _, hit := range FilterAllowedMetadata(allowedSchemas, hits) {
+			if schema != "" && hit.Schema != schema {
+				continue
+			}
+			suggestion := models.MetadataSuggestion{Kind: hit.Type, Name: hit.Table, Catalog: hit.Catalog, Schema: hit.Schema, Table: hit.Table}
+			if hit.Type == "column" {
+				suggestion.Name = hit.Column
+			}
+			if matches(suggestion.Name) {
+				suggestions = append(suggestions, suggestion)
+			}
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if aExact, bExact := strings.EqualFold(a.Name, prefix), strings.EqualFold(b.Name, prefix); aExact != bExact {
+			return aExact
+		}
+		if suggestionKindRank[a.Kind] != suggestionKindRank[b.Kind] {
+			return suggestionKindRank[a.Kind] < suggestionKindRank[b.Kind]
+		}
+		if len(a.Name) != len(b.Name) {
+			return len(a.Name) < len(b.Name)
+		}
+		return a.Name < b.Name
+	})
+
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+// schemaVisible reports whether allowedSchemas (nil means all) grants catalog.schema
+func schemaVisible(allowedSchemas []models.SchemaPermission, catalog, schema string) bool {
+	return allowedSchemas == nil || SchemaAllowed(allowedSchemas, catalog, schema)
+}
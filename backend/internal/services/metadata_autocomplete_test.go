@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/repository"
+)
+
+func setupAutocompleteTrino() *repository.MockTrinoExecutor {
+	trino := repository.NewMockTrinoExecutor()
+	trino.SetupCatalog("hive", map[string][]string{
+		"sales": {"orders", "order_items", "customers"},
+		"hr":    {"employees"},
+	})
+	trino.SetupCatalog("hudi", map[string][]string{"events": {"clicks"}})
+	trino.SetColumns("hive", "sales", "orders", []models.ColumnInfo{{Name: "order_id"}, {Name: "customer_id"}})
+	trino.SetColumns("hive", "hr", "employees", []models.ColumnInfo{{Name: "hire_date"}})
+	return trino
+}
+
+func suggestionNames(suggestions []models.MetadataSuggestion) []string {
+	names := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		names[i] = s.Kind + ":" + s.Name
+	}
+	return names
+}
+
+func TestAutocompleteMetadata_PrefixMatchesRankedByKind(t *testing.T) {
+	trino := setupAutocompleteTrino()
+
+	got, err := AutocompleteMetadata(context.Background(), trino, "h", "", "", []string{"hive", "hudi"}, nil, 0)
+	if err != nil {
+		t.Fatalf("AutocompleteMetadata() error = %v", err)
+	}
+	want := []string{"catalog:hive", "catalog:hudi", "column:hire_date"}
+	if names := suggestionNames(got); len(names) != len(want) || names[0] != want[0] || names[1] != want[1] || names[2] != want[2] {
+		t.Fatalf("suggestions = %v, want %v", names, want)
+	}
+
+	// Matching ignores case, and substring hits such as customer_id are dropped
+	got, err = AutocompleteMetadata(context.Background(), trino, "ORDER", "hive", "", []string{"hive", "hudi"}, nil, 0)
+	if err != nil {
+		t.Fatalf("AutocompleteMetadata() error = %v", err)
+	}
+	want = []string{"table:orders", "table:order_items", "column:order_id"}
+	names := suggestionNames(got)
+	if len(names) != len(want) || names[0] != want[0] || names[1] != want[1] || names[2] != want[2] {
+		t.Fatalf("suggestions = %v, want %v", names, want)
+	}
+}
+
+func TestAutocompleteMetadata_ExactMatchFirst(t *testing.T) {
+	trino := setupAutocompleteTrino()
+	trino.SetColumns("hive", "sales", "customers", []models.ColumnInfo{{Name: "sales"}})
+
+	got, err := AutocompleteMetadata(context.Background(), trino, "sales", "hive", "", []string{"hive"}, nil, 0)
+	if err != nil {
+		t.Fatalf("AutocompleteMetadata() error = %v", err)
+	}
+	if len(got) < 2 || got[0].Kind != "schema" || got[1].Kind != "column" {
+		t.Fatalf("suggestions = %v, want the schema and the column named sales first", suggestionNames(got))
+	}
+}
+
+func TestAutocompleteMetadata_ListsTablesOfSchema(t *testing.T) {
+	trino := setupAutocompleteTrino()
+
+	got, err := AutocompleteMetadata(context.Background(), trino, "", "hive", "sales", []string{"hive"}, nil, 2)
+	if err != nil {
+		t.Fatalf("AutocompleteMetadata() error = %v", err)
+	}
+	names := suggestionNames(got)
+	if len(names) != 2 || names[0] != "table:orders" || names[1] != "table:customers" {
+		t.Fatalf("suggestions = %v, want the two shortest tables of hive.sales", names)
+	}
+}
+
+func TestAutocompleteMetadata_RespectsAllowedSchemas(t *testing.T) {
+	trino := setupAutocompleteTrino()
+	allowed := []models.SchemaPermission{{Catalog: "hive", Schema: "sales"}}
+
+	got, err := AutocompleteMetadata(context.Background(), trino, "", "hive", "", []string{"hive"}, allowed, 0)
+	if err != nil {
+		t.Fatalf("AutocompleteMetadata() error = %v", err)
+	}
+	if names := suggestionNames(got); len(names) != 1 || names[0] != "schema:sales" {
+		t.Fatalf("suggestions = %v, want only the allowed schema", names)
+	}
+
+	got, err = AutocompleteMetadata(context.Background(), trino, "hire", "", "", []string{"hive"}, allowed, 0)
+	if err != nil {
+		t.Fatalf("AutocompleteMetadata() error = %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("suggestions = %v, want none from the hr schema", suggestionNames(got))
+	}
+}
+
+func TestAutocompleteMetadata_CapsLimit(t *testing.T) {
+	trino := repository.NewMockTrinoExecutor()
+	tables := make([]string, 40)
+	for i := range tables {
+		tables[i] = "t" + string(rune('a'+i%26)) + string(rune('a'+i/26))
+	}
+	trino.SetupCatalog("hive", map[string][]string{"sales": tables})
+
+	got, err := AutocompleteMetadata(context.Background(), trino, "t", "hive", "sales", []string{"hive"}, nil, 1000)
+	if err != nil {
+		t.Fatalf("AutocompleteMetadata() error = %v", err)
+	}
+	if len(got) != MaxAutocompleteLimit {
+		t.Fatalf("got %d suggestions, want the cap of %d", len(got), MaxAutocompleteLimit)
+	}
+}
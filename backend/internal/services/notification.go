@@ -9,6 +9,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/mitsume/backend/internal/config"
+	"github.com/mitsume/backend/internal/crypto"
+	"github.com/mitsume/backend/internal/metrics"
 	"github.com/mitsume/backend/internal/models"
 )
 
@@ -24,16 +26,31 @@ type NotificationService struct {
 	slackNotifier      *SlackNotifier
 	emailNotifier      *EmailNotifier
 	googleChatNotifier *GoogleChatNotifier
+	inAppNotifier      *InAppNotifier
+	configCipher       *crypto.Cipher // nil stores channel configs in plaintext
 }
 
-// NewNotificationService creates a new notification service
+// NewNotificationService creates a new notification service. Channel configs are encrypted at
+// rest when cfg.EncryptionKey is set (config.Load validates the key).
 func NewNotificationService(pool *pgxpool.Pool, cfg *config.NotificationConfig) *NotificationService {
-	return &NotificationService{
+	s := &NotificationService{
 		pool:               pool,
 		slackNotifier:      NewSlackNotifier(),
 		emailNotifier:      NewEmailNotifier(&cfg.SMTP),
 		googleChatNotifier: NewGoogleChatNotifier(),
+		inAppNotifier:      NewInAppNotifier(pool),
 	}
+	if cfg.EncryptionKey != "" {
+		if c, err := crypto.NewCipherFromBase64(cfg.EncryptionKey); err == nil {
+			s.configCipher = c
+		}
+	}
+	return s
+}
+
+// EncryptsConfigs reports whether channel configs are encrypted at rest
+func (s *NotificationService) EncryptsConfigs() bool {
+	return s.configCipher != nil
 }
 
 // GetChannels returns all notification channels for a user
@@ -45,7 +62,31 @@ func (s *NotificationService) GetChannels(ctx context.Context, userID uuid.UUID)
 		ORDER BY created_at DESC
 	`
 
-	rows, err := s.pool.Query(ctx, query, userID)
+	return s.queryChannels(ctx, query, userID)
+}
+
+// GetUnusedChannels returns the user's channels that no alert, subscription or dashboard error
+// notification sends to, so they can be cleaned up
+func (s *NotificationService) GetUnusedChannels(ctx context.Context, userID uuid.UUID) ([]models.NotificationChannel, error) {
+	query := `
+		SELECT nc.id, nc.user_id, nc.name, nc.channel_type, nc.config, nc.is_verified, nc.created_at, nc.updated_at
+		FROM notification_channels nc
+		LEFT JOIN alert_channels ac ON ac.channel_id = nc.id
+		LEFT JOIN subscription_channels sc ON sc.channel_id = nc.id
+		LEFT JOIN dashboards d ON d.error_notification_channel_id = nc.id
+		WHERE nc.user_id = $1
+			AND ac.channel_id IS NULL
+			AND sc.channel_id IS NULL
+			AND d.id IS NULL
+		ORDER BY nc.created_at DESC
+	`
+
+	return s.queryChannels(ctx, query, userID)
+}
+
+// queryChannels runs a query selecting notification channel columns, decrypting each config
+func (s *NotificationService) queryChannels(ctx context.Context, query string, args ...interface{}) ([]models.NotificationChannel, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query notification channels: %w", err)
 	}
@@ -57,13 +98,16 @@ func (s *NotificationService) GetChannels(ctx context.Context, userID uuid.UUID)
 		if err := rows.Scan(&ch.ID, &ch.UserID, &ch.Name, &ch.ChannelType, &ch.Config, &ch.IsVerified, &ch.CreatedAt, &ch.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan notification channel: %w", err)
 		}
+		if ch.Config, err = s.openConfig(ch.ID, ch.Config); err != nil {
+			return nil, err
+		}
 		channels = append(channels, ch)
 	}
 
 	return channels, nil
 }
 
-// GetChannelByID returns a notification channel by ID
+// GetChannelByID returns a notification channel by ID, with its config decrypted
 func (s *NotificationService) GetChannelByID(ctx context.Context, id uuid.UUID) (*models.NotificationChannel, error) {
 	query := `
 		SELECT id, user_id, name, channel_type, config, is_verified, created_at, updated_at
@@ -76,26 +120,41 @@ func (s *NotificationService) GetChannelByID(ctx context.Context, id uuid.UUID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get notification channel: %w", err)
 	}
+	if ch.Config, err = s.openConfig(ch.ID, ch.Config); err != nil {
+		return nil, err
+	}
 
 	return &ch, nil
 }
 
 // CreateChannel creates a new notification channel
 func (s *NotificationService) CreateChannel(ctx context.Context, userID uuid.UUID, req *models.CreateNotificationChannelRequest) (*models.NotificationChannel, error) {
+	// In-app channels always deliver to their owner, whatever config was sent
+	if req.ChannelType == models.ChannelTypeInApp {
+		req.Config = inAppChannelConfig(userID)
+	}
+
 	// Validate config based on channel type
 	if err := s.validateChannelConfig(req.ChannelType, req.Config); err != nil {
 		return nil, fmt.Errorf("invalid channel config: %w", err)
 	}
 
+	// The ID is chosen up front because the encrypted config is bound to it
+	id := uuid.New()
+	stored, err := s.sealConfig(id, req.Config)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
-		INSERT INTO notification_channels (user_id, name, channel_type, config)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, user_id, name, channel_type, config, is_verified, created_at, updated_at
+		INSERT INTO notification_channels (id, user_id, name, channel_type, config)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, user_id, name, channel_type, is_verified, created_at, updated_at
 	`
 
-	var ch models.NotificationChannel
-	err := s.pool.QueryRow(ctx, query, userID, req.Name, req.ChannelType, req.Config).Scan(
-		&ch.ID, &ch.UserID, &ch.Name, &ch.ChannelType, &ch.Config, &ch.IsVerified, &ch.CreatedAt, &ch.UpdatedAt,
+	ch := models.NotificationChannel{Config: req.Config}
+	err = s.pool.QueryRow(ctx, query, id, userID, req.Name, req.ChannelType, stored).Scan(
+		&ch.ID, &ch.UserID, &ch.Name, &ch.ChannelType, &ch.IsVerified, &ch.CreatedAt, &ch.UpdatedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create notification channel: %w", err)
@@ -116,6 +175,11 @@ func (s *NotificationService) UpdateChannel(ctx context.Context, id uuid.UUID, u
 		return nil, fmt.Errorf("not authorized to update this channel")
 	}
 
+	// The recipient of an in-app channel cannot be changed
+	if existing.ChannelType == models.ChannelTypeInApp {
+		req.Config = nil
+	}
+
 	// Validate config if provided
 	if req.Config != nil {
 		if err := s.validateChannelConfig(existing.ChannelType, req.Config); err != nil {
@@ -132,17 +196,22 @@ func (s *NotificationService) UpdateChannel(ctx context.Context, id uuid.UUID, u
 	if req.Config != nil {
 		config = req.Config
 	}
+	// Re-sealing also encrypts a config still stored in plaintext
+	stored, err := s.sealConfig(id, config)
+	if err != nil {
+		return nil, err
+	}
 
 	query := `
 		UPDATE notification_channels
 		SET name = $1, config = $2, updated_at = CURRENT_TIMESTAMP
 		WHERE id = $3
-		RETURNING id, user_id, name, channel_type, config, is_verified, created_at, updated_at
+		RETURNING id, user_id, name, channel_type, is_verified, created_at, updated_at
 	`
 
-	var ch models.NotificationChannel
-	err = s.pool.QueryRow(ctx, query, name, config, id).Scan(
-		&ch.ID, &ch.UserID, &ch.Name, &ch.ChannelType, &ch.Config, &ch.IsVerified, &ch.CreatedAt, &ch.UpdatedAt,
+	ch := models.NotificationChannel{Config: config}
+	err = s.pool.QueryRow(ctx, query, name, stored, id).Scan(
+		&ch.ID, &ch.UserID, &ch.Name, &ch.ChannelType, &ch.IsVerified, &ch.CreatedAt, &ch.UpdatedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update notification channel: %w", err)
@@ -196,18 +265,35 @@ func (s *NotificationService) TestChannel(ctx context.Context, id uuid.UUID, use
 	return nil
 }
 
-// Send sends a notification to a channel
+// Send sends a notification to a channel. Channels loaded by other services may carry their
+// config as stored, so it is decrypted here if needed.
 func (s *NotificationService) Send(ctx context.Context, channel *models.NotificationChannel, msg models.NotificationMessage) error {
+	config, err := s.openConfig(channel.ID, channel.Config)
+	if err != nil {
+		return err
+	}
+
 	switch channel.ChannelType {
 	case models.ChannelTypeSlack:
-		return s.slackNotifier.Send(ctx, channel.Config, msg)
+		err = s.slackNotifier.Send(ctx, config, msg)
 	case models.ChannelTypeEmail:
-		return s.emailNotifier.Send(ctx, channel.Config, msg)
+		err = s.emailNotifier.Send(ctx, config, msg)
 	case models.ChannelTypeGoogleChat:
-		return s.googleChatNotifier.Send(ctx, channel.Config, msg)
+		err = s.googleChatNotifier.Send(ctx, config, msg)
+	case models.ChannelTypeInApp:
+		err = s.inAppNotifier.Send(ctx, config, msg)
 	default:
 		return fmt.Errorf("unsupported channel type: %s", channel.ChannelType)
 	}
+	metrics.ObserveNotification(string(channel.ChannelType), err)
+	return err
+}
+
+// NotifyInApp stores a notification in a user's in-app inbox, without going through a channel
+func (s *NotificationService) NotifyInApp(ctx context.Context, userID uuid.UUID, msg models.NotificationMessage) error {
+	err := s.inAppNotifier.Send(ctx, inAppChannelConfig(userID), msg)
+	metrics.ObserveNotification(string(models.ChannelTypeInApp), err)
+	return err
 }
 
 // SendToChannels sends a notification to multiple channels
@@ -239,7 +325,54 @@ func (s *NotificationService) validateChannelConfig(channelType models.ChannelTy
 		return s.emailNotifier.ValidateConfig(config)
 	case models.ChannelTypeGoogleChat:
 		return s.googleChatNotifier.ValidateConfig(config)
+	case models.ChannelTypeInApp:
+		return s.inAppNotifier.ValidateConfig(config)
 	default:
 		return fmt.Errorf("unsupported channel type: %s", channelType)
 	}
 }
+
+// GetInAppNotifications returns the newest notifications in a user's in-app inbox, only the
+// unread ones when unreadOnly is set
+func (s *NotificationService) GetInAppNotifications(ctx context.Context, userID uuid.UUID, unreadOnly bool, limit int) ([]models.InAppNotification, error) {
+	query := `
+		SELECT id, user_id, title, body, read_at, created_at
+		FROM in_app_notifications
+		WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)
+		ORDER BY created_at DESC
+		LIMIT $3
+	`
+
+	rows, err := s.pool.Query(ctx, query, userID, unreadOnly, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query in-app notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []models.InAppNotification
+	for rows.Next() {
+		var n models.InAppNotification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Title, &n.Body, &n.ReadAt, &n.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan in-app notification: %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+
+	return notifications, rows.Err()
+}
+
+// MarkInAppNotificationRead marks one of a user's in-app notifications as read, keeping the
+// original read time if it was already read. It returns ErrNotFound for other users' notifications.
+func (s *NotificationService) MarkInAppNotificationRead(ctx context.Context, id, userID uuid.UUID) error {
+	result, err := s.pool.Exec(ctx,
+		`UPDATE in_app_notifications SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP) WHERE id = $1 AND user_id = $2`,
+		id, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark in-app notification as read: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/crypto"
+)
+
+// ErrChannelConfigEncrypted is returned for an encrypted channel config when no encryption key
+// is configured
+var ErrChannelConfigEncrypted = errors.New("notification channel config is encrypted but NOTIFICATION_ENCRYPTION_KEY is not set")
+
+// sealedChannelConfig is how an encrypted config is stored in notification_channels.config
+type sealedChannelConfig struct {
+	Encrypted *crypto.Envelope `json:"encrypted"`
+}
+
+// channelConfigAAD binds a sealed config to its channel, so it cannot be copied to another row
+func channelConfigAAD(channelID uuid.UUID) []byte {
+	return []byte("notification_channels.config:" + channelID.String())
+}
+
+// parseSealedConfig returns the envelope of a stored config, or false for a plaintext config
+func parseSealedConfig(stored json.RawMessage) (*crypto.Envelope, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(stored, &fields); err != nil || len(fields) != 1 || fields["encrypted"] == nil {
+		return nil, false
+	}
+	var sealed sealedChannelConfig
+	if err := json.Unmarshal(stored, &sealed); err != nil || sealed.Encrypted == nil {
+		return nil, false
+	}
+	return sealed.Encrypted, true
+}
+
+// sealConfig returns a channel config as it is stored: encrypted when a cipher is set,
+// unchanged otherwise
+func (s *NotificationService) sealConfig(channelID uuid.UUID, config json.RawMessage) (json.RawMessage, error) {
+	if s.configCipher == nil {
+		return config, nil
+	}
+	envelope, err := s.configCipher.Seal(config, channelConfigAAD(channelID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt channel config: %w", err)
+	}
+	return json.Marshal(sealedChannelConfig{Encrypted: envelope})
+}
+
+// openConfig returns the plaintext of a stored channel config. Plaintext configs, saved before
+// encryption was enabled, are returned unchanged.
+func (s *NotificationService) openConfig(channelID uuid.UUID, stored json.RawMessage) (json.RawMessage, error) {
+	envelope, ok := parseSealedConfig(stored)
+	if !ok {
+		return stored, nil
+	}
+	if s.configCipher == nil {
+		return nil, ErrChannelConfigEncrypted
+	}
+	config, err := s.configCipher.Open(envelope, channelConfigAAD(channelID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt channel config: %w", err)
+	}
+	return config, nil
+}
+
+// EncryptPlaintextConfigs encrypts every channel config still stored in plaintext, returning
+// how many were encrypted. It does nothing without a cipher and can be run repeatedly.
+func (s *NotificationService) EncryptPlaintextConfigs(ctx context.Context) (int, error) {
+	if s.configCipher == nil {
+		return 0, nil
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `SELECT id, config FROM notification_channels FOR UPDATE`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query notification channels: %w", err)
+	}
+	plaintext := make(map[uuid.UUID]json.RawMessage)
+	for rows.Next() {
+		var id uuid.UUID
+		var config json.RawMessage
+		if err := rows.Scan(&id, &config); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan notification channel: %w", err)
+		}
+		if _, sealed := parseSealedConfig(config); !sealed {
+			plaintext[id] = config
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for id, config := range plaintext {
+		stored, err := s.sealConfig(id, config)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(ctx, `UPDATE notification_channels SET config = $1 WHERE id = $2`, stored, id); err != nil {
+			return 0, fmt.Errorf("failed to encrypt config of channel %s: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+	return len(plaintext), nil
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/config"
+	"github.com/mitsume/backend/internal/models"
+)
+
+func testEncryptionKey(fill byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{fill}, 32))
+}
+
+func TestNotificationService_SealAndOpenConfig(t *testing.T) {
+	s := NewNotificationService(nil, &config.NotificationConfig{EncryptionKey: testEncryptionKey(1)})
+	channelID := uuid.New()
+	channelConfig := json.RawMessage(`{"webhook_url":"https://hooks.slack.com/services/secret"}`)
+
+	stored, err := s.sealConfig(channelID, channelConfig)
+	if err != nil {
+		t.Fatalf("sealConfig() error = %v", err)
+	}
+	if bytes.Contains(stored, []byte("hooks.slack.com")) {
+		t.Fatalf("sealConfig() = %s, want the webhook encrypted", stored)
+	}
+
+	opened, err := s.openConfig(channelID, stored)
+	if err != nil {
+		t.Fatalf("openConfig() error = %v", err)
+	}
+	if string(opened) != string(channelConfig) {
+		t.Errorf("openConfig() = %s, want %s", opened, channelConfig)
+	}
+
+	// A sealed config copied to another channel does not decrypt
+	if _, err := s.openConfig(uuid.New(), stored); err == nil {
+		t.Error("openConfig() of another channel's config error = nil, want an error")
+	}
+}
+
+func TestNotificationService_OpenConfigPassesPlaintextThrough(t *testing.T) {
+	s := NewNotificationService(nil, &config.NotificationConfig{EncryptionKey: testEncryptionKey(1)})
+	channelConfig := json.RawMessage(`{"recipients":["a@example.com"]}`)
+
+	opened, err := s.openConfig(uuid.New(), channelConfig)
+	if err != nil || string(opened) != string(channelConfig) {
+		t.Fatalf("openConfig() = %s, %v, want the plaintext config unchanged", opened, err)
+	}
+}
+
+func TestNotificationService_WithoutKey(t *testing.T) {
+	s := NewNotificationService(nil, &config.NotificationConfig{})
+	channelConfig := json.RawMessage(`{"webhook_url":"https://example.com/hook"}`)
+
+	stored, err := s.sealConfig(uuid.New(), channelConfig)
+	if err != nil || string(stored) != string(channelConfig) {
+		t.Fatalf("sealConfig() without a key = %s, %v, want the config unchanged", stored, err)
+	}
+
+	channelID := uuid.New()
+	sealed, err := NewNotificationService(nil, &config.NotificationConfig{EncryptionKey: testEncryptionKey(1)}).sealConfig(channelID, channelConfig)
+	if err != nil {
+		t.Fatalf("sealConfig() error = %v", err)
+	}
+	if _, err := s.openConfig(channelID, sealed); !errors.Is(err, ErrChannelConfigEncrypted) {
+		t.Errorf("openConfig() without a key error = %v, want %v", err, ErrChannelConfigEncrypted)
+	}
+}
+
+func TestEncryptPlaintextConfigs(t *testing.T) {
+	pool := setupHistoryDatabase(t)
+	ctx := context.Background()
+
+	var userID uuid.UUID
+	err := pool.QueryRow(ctx,
+		`INSERT INTO users (email, name) VALUES ($1, 'channel test') RETURNING id`,
+		fmt.Sprintf("channel-%s@example.com", uuid.NewString()),
+	).Scan(&userID)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() { _, _ = pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID) })
+
+	// A channel saved before encryption was enabled
+	plain := NewNotificationService(pool, &config.NotificationConfig{})
+	channel, err := plain.CreateChannel(ctx, userID, &models.CreateNotificationChannelRequest{
+		Name:        "Slack",
+		ChannelType: models.ChannelTypeSlack,
+		Config:      json.RawMessage(`{"webhook_url":"https://hooks.slack.com/services/secret"}`),
+	})
+	if err != nil {
+		t.Fatalf("CreateChannel() error = %v", err)
+	}
+
+	s := NewNotificationService(pool, &config.NotificationConfig{EncryptionKey: testEncryptionKey(1)})
+	if _, err := s.EncryptPlaintextConfigs(ctx); err != nil {
+		t.Fatalf("EncryptPlaintextConfigs() error = %v", err)
+	}
+
+	var stored json.RawMessage
+	if err := pool.QueryRow(ctx, `SELECT config FROM notification_channels WHERE id = $1`, channel.ID).Scan(&stored); err != nil {
+		t.Fatalf("failed to read channel: %v", err)
+	}
+	if bytes.Contains(stored, []byte("hooks.slack.com")) {
+		t.Fatalf("stored config = %s, want it encrypted", stored)
+	}
+
+	got, err := s.GetChannelByID(ctx, channel.ID)
+	if err != nil {
+		t.Fatalf("GetChannelByID() error = %v", err)
+	}
+	var cfg models.SlackChannelConfig
+	if err := json.Unmarshal(got.Config, &cfg); err != nil || cfg.WebhookURL != "https://hooks.slack.com/services/secret" {
+		t.Errorf("GetChannelByID() config = %s, want the decrypted webhook", got.Config)
+	}
+}
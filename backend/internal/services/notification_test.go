@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/config"
+	"github.com/mitsume/backend/internal/models"
+)
+
+func TestGetUnusedChannels(t *testing.T) {
+	pool := setupHistoryDatabase(t)
+	ctx := context.Background()
+
+	var userID uuid.UUID
+	err := pool.QueryRow(ctx,
+		`INSERT INTO users (email, name) VALUES ($1, 'unused channel test') RETURNING id`,
+		fmt.Sprintf("unused-%s@example.com", uuid.NewString()),
+	).Scan(&userID)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() { _, _ = pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID) })
+
+	s := NewNotificationService(pool, &config.NotificationConfig{})
+	createChannel := func(t *testing.T, name string) uuid.UUID {
+		ch, err := s.CreateChannel(ctx, userID, &models.CreateNotificationChannelRequest{
+			Name:        name,
+			ChannelType: models.ChannelTypeSlack,
+			Config:      json.RawMessage(`{"webhook_url":"https://hooks.slack.com/services/test"}`),
+		})
+		if err != nil {
+			t.Fatalf("CreateChannel() error = %v", err)
+		}
+		return ch.ID
+	}
+	exec := func(t *testing.T, query string, args ...interface{}) {
+		if _, err := pool.Exec(ctx, query, args...); err != nil {
+			t.Fatalf("failed to run %q: %v", query, err)
+		}
+	}
+
+	alertChannel := createChannel(t, "alert")
+	subscriptionChannel := createChannel(t, "subscription")
+	dashboardChannel := createChannel(t, "dashboard")
+	unused := createChannel(t, "unused")
+
+	var queryID, alertID, dashboardID, subscriptionID uuid.UUID
+	if err := pool.QueryRow(ctx,
+		`INSERT INTO saved_queries (user_id, name, query_text) VALUES ($1, 'q', 'SELECT 1') RETURNING id`, userID,
+	).Scan(&queryID); err != nil {
+		t.Fatalf("failed to create query: %v", err)
+	}
+	if err := pool.QueryRow(ctx, `
+		INSERT INTO query_alerts (user_id, query_id, name, condition_column, condition_operator, condition_value)
+		VALUES ($1, $2, 'a', 'c', 'gt', '1') RETURNING id`, userID, queryID,
+	).Scan(&alertID); err != nil {
+		t.Fatalf("failed to create alert: %v", err)
+	}
+	if err := pool.QueryRow(ctx,
+		`INSERT INTO dashboards (user_id, name, error_notification_channel_id) VALUES ($1, 'd', $2) RETURNING id`, userID, dashboardChannel,
+	).Scan(&dashboardID); err != nil {
+		t.Fatalf("failed to create dashboard: %v", err)
+	}
+	if err := pool.QueryRow(ctx,
+		`INSERT INTO dashboard_subscriptions (user_id, dashboard_id, name, schedule_cron) VALUES ($1, $2, 's', '0 9 * * *') RETURNING id`, userID, dashboardID,
+	).Scan(&subscriptionID); err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+	exec(t, `INSERT INTO alert_channels (alert_id, channel_id) VALUES ($1, $2)`, alertID, alertChannel)
+	exec(t, `INSERT INTO subscription_channels (subscription_id, channel_id) VALUES ($1, $2)`, subscriptionID, subscriptionChannel)
+
+	channels, err := s.GetUnusedChannels(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetUnusedChannels() error = %v", err)
+	}
+	if len(channels) != 1 || channels[0].ID != unused {
+		t.Fatalf("GetUnusedChannels() = %+v, want only the unused channel", channels)
+	}
+	if channels[0].IsVerified {
+		t.Errorf("GetUnusedChannels() IsVerified = true, want false for a new channel")
+	}
+
+	// Removing the alert frees its channel
+	exec(t, `DELETE FROM query_alerts WHERE id = $1`, alertID)
+	channels, err = s.GetUnusedChannels(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetUnusedChannels() error = %v", err)
+	}
+	got := make(map[uuid.UUID]bool)
+	for _, ch := range channels {
+		got[ch.ID] = true
+	}
+	if len(channels) != 2 || !got[unused] || !got[alertChannel] {
+		t.Errorf("GetUnusedChannels() after deleting the alert = %+v, want the unused and alert channels", channels)
+	}
+}
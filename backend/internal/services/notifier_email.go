@@ -38,7 +38,15 @@ func (n *EmailNotifier) Send(ctx context.Context, configData json.RawMessage, ms
 		return fmt.Errorf("failed to parse email config: %w", err)
 	}
 
-	if len(channelConfig.Recipients) == 0 {
+	return n.SendTo(ctx, channelConfig.Recipients, msg)
+}
+
+// SendTo sends a message to the given addresses, independently of any notification channel
+func (n *EmailNotifier) SendTo(ctx context.Context, recipients []string, msg models.NotificationMessage) error {
+	if n.smtpConfig.Host == "" {
+		return fmt.Errorf("SMTP not configured")
+	}
+	if len(recipients) == 0 {
 		return fmt.Errorf("no recipients specified")
 	}
 
@@ -51,7 +59,7 @@ func (n *EmailNotifier) Send(ctx context.Context, configData json.RawMessage, ms
 	// Headers
 	headers := make(map[string]string)
 	headers["From"] = n.smtpConfig.From
-	headers["To"] = strings.Join(channelConfig.Recipients, ", ")
+	headers["To"] = strings.Join(recipients, ", ")
 	headers["Subject"] = msg.Title
 	headers["MIME-Version"] = "1.0"
 	headers["Content-Type"] = fmt.Sprintf("multipart/mixed; boundary=%s", writer.Boundary())
@@ -99,7 +107,7 @@ func (n *EmailNotifier) Send(ctx context.Context, configData json.RawMessage, ms
 	addr := fmt.Sprintf("%s:%s", n.smtpConfig.Host, n.smtpConfig.Port)
 	auth := smtp.PlainAuth("", n.smtpConfig.Username, n.smtpConfig.Password, n.smtpConfig.Host)
 
-	err = smtp.SendMail(addr, auth, n.smtpConfig.From, channelConfig.Recipients, fullEmail.Bytes())
+	err = smtp.SendMail(addr, auth, n.smtpConfig.From, recipients, fullEmail.Bytes())
 	if err != nil {
 		return fmt.Errorf("failed to send email: %w", err)
 	}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mitsume/backend/internal/models"
+)
+
+// InAppNotifier delivers notifications to a user's in-app inbox
+type InAppNotifier struct {
+	pool *pgxpool.Pool
+}
+
+// NewInAppNotifier creates a new in-app notifier
+func NewInAppNotifier(pool *pgxpool.Pool) *InAppNotifier {
+	return &InAppNotifier{
+		pool: pool,
+	}
+}
+
+// Send stores the notification in the recipient's inbox. Attachments are not kept.
+func (n *InAppNotifier) Send(ctx context.Context, configData json.RawMessage, msg models.NotificationMessage) error {
+	var config models.InAppChannelConfig
+	if err := json.Unmarshal(configData, &config); err != nil {
+		return fmt.Errorf("failed to parse in-app config: %w", err)
+	}
+	if config.UserID == uuid.Nil {
+		return fmt.Errorf("no recipient specified")
+	}
+
+	_, err := n.pool.Exec(ctx,
+		`INSERT INTO in_app_notifications (user_id, title, body) VALUES ($1, $2, $3)`,
+		config.UserID, msg.Title, msg.Body,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store in-app notification: %w", err)
+	}
+	return nil
+}
+
+// ValidateConfig validates the in-app channel configuration
+func (n *InAppNotifier) ValidateConfig(configData json.RawMessage) error {
+	var config models.InAppChannelConfig
+	if err := json.Unmarshal(configData, &config); err != nil {
+		return fmt.Errorf("failed to parse in-app config: %w", err)
+	}
+	if config.UserID == uuid.Nil {
+		return fmt.Errorf("user_id is required")
+	}
+	return nil
+}
+
+// inAppChannelConfig returns the config of an in-app channel owned by userID, which always
+// delivers to its owner
+func inAppChannelConfig(userID uuid.UUID) json.RawMessage {
+	config, _ := json.Marshal(models.InAppChannelConfig{UserID: userID})
+	return config
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/config"
+	"github.com/mitsume/backend/internal/models"
+)
+
+func TestInAppNotifier_ValidateConfig(t *testing.T) {
+	n := NewInAppNotifier(nil)
+
+	if err := n.ValidateConfig(inAppChannelConfig(uuid.New())); err != nil {
+		t.Errorf("ValidateConfig() with a user error = %v, want nil", err)
+	}
+	for _, cfg := range []string{`{}`, `{"user_id":"00000000-0000-0000-0000-000000000000"}`, `not json`} {
+		if err := n.ValidateConfig(json.RawMessage(cfg)); err == nil {
+			t.Errorf("ValidateConfig(%s) error = nil, want an error", cfg)
+		}
+	}
+}
+
+func TestInAppNotifications_DeliverAndMarkRead(t *testing.T) {
+	pool := setupHistoryDatabase(t)
+	ctx := context.Background()
+
+	var userID uuid.UUID
+	err := pool.QueryRow(ctx,
+		`INSERT INTO users (email, name) VALUES ($1, 'inbox test') RETURNING id`,
+		fmt.Sprintf("inbox-%s@example.com", uuid.NewString()),
+	).Scan(&userID)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() { _, _ = pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID) })
+
+	s := NewNotificationService(pool, &config.NotificationConfig{})
+	// The config sent by the client is replaced, so a channel cannot target another user
+	channel, err := s.CreateChannel(ctx, userID, &models.CreateNotificationChannelRequest{
+		Name:        "Inbox",
+		ChannelType: models.ChannelTypeInApp,
+		Config:      inAppChannelConfig(uuid.New()),
+	})
+	if err != nil {
+		t.Fatalf("CreateChannel() error = %v", err)
+	}
+	for _, title := range []string{"first", "second"} {
+		if err := s.Send(ctx, channel, models.NotificationMessage{Title: title, Body: "body"}); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	unread, err := s.GetInAppNotifications(ctx, userID, true, 50)
+	if err != nil {
+		t.Fatalf("GetInAppNotifications() error = %v", err)
+	}
+	if len(unread) != 2 || unread[0].Title != "second" {
+		t.Fatalf("unread = %+v, want both notifications, newest first", unread)
+	}
+
+	if err := s.MarkInAppNotificationRead(ctx, unread[0].ID, uuid.New()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("MarkInAppNotificationRead() by another user error = %v, want ErrNotFound", err)
+	}
+	if err := s.MarkInAppNotificationRead(ctx, unread[0].ID, userID); err != nil {
+		t.Fatalf("MarkInAppNotificationRead() error = %v", err)
+	}
+
+	unread, err = s.GetInAppNotifications(ctx, userID, true, 50)
+	if err != nil {
+		t.Fatalf("GetInAppNotifications() error = %v", err)
+	}
+	if len(unread) != 1 || unread[0].Title != "first" {
+		t.Errorf("unread after marking = %+v, want only the first notification", unread)
+	}
+	all, err := s.GetInAppNotifications(ctx, userID, false, 50)
+	if err != nil {
+		t.Fatalf("GetInAppNotifications() error = %v", err)
+	}
+	if len(all) != 2 || all[0].ReadAt == nil {
+		t.Errorf("all = %+v, want both notifications with the second marked read", all)
+	}
+}
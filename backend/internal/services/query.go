@@ -9,10 +9,13 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/mitsume/backend/internal/database"
 	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/repository"
 )
 
 type QueryService struct {
-	cache *QueryCacheService // nil if caching is disabled
+	cache           *QueryCacheService // nil if caching is disabled
+	readOnly        bool               // reject saved queries that are not read-only (see CheckReadOnlyStatement)
+	slowThresholdMs int                // executions at least this long are slow; 0 disables slow query reporting
 }
 
 func NewQueryService(cache *QueryCacheService) *QueryService {
@@ -21,6 +24,19 @@ func NewQueryService(cache *QueryCacheService) *QueryService {
 	}
 }
 
+// SetReadOnlyMode enables rejecting saved queries that could modify data
+func (s *QueryService) SetReadOnlyMode(enabled bool) {
+	s.readOnly = enabled
+}
+
+// CheckStatement applies CheckReadOnlyStatement to query when read-only mode is enabled
+func (s *QueryService) CheckStatement(query string) error {
+	if !s.readOnly {
+		return nil
+	}
+	return CheckReadOnlyStatement(query)
+}
+
 // SavedQuery CRUD operations
 
 func (s *QueryService) GetSavedQueries(ctx context.Context, userID uuid.UUID) ([]models.SavedQuery, error) {
@@ -82,6 +98,10 @@ func (s *QueryService) GetSavedQueryByID(ctx context.Context, id uuid.UUID) (*mo
 }
 
 func (s *QueryService) CreateSavedQuery(ctx context.Context, userID uuid.UUID, req *models.SaveQueryRequest) (*models.SavedQuery, error) {
+	if err := s.CheckStatement(req.QueryText); err != nil {
+		return nil, err
+	}
+
 	pool := database.GetPool()
 
 	var q models.SavedQuery
@@ -98,7 +118,49 @@ func (s *QueryService) CreateSavedQuery(ctx context.Context, userID uuid.UUID, r
 	return &q, nil
 }
 
+// ImportSavedQueries creates the queries for the user in one transaction, returning their IDs
+// in order. Either every query is created or none is; callers validate items beforehand.
+func (s *QueryService) ImportSavedQueries(ctx context.Context, userID uuid.UUID, reqs []models.SaveQueryRequest) ([]uuid.UUID, error) {
+	for i := range reqs {
+		if err := s.CheckStatement(reqs[i].QueryText); err != nil {
+			return nil, err
+		}
+	}
+
+	pool := database.GetPool()
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	ids := make([]uuid.UUID, len(reqs))
+	for i, req := range reqs {
+		err := tx.QueryRow(ctx,
+			`INSERT INTO saved_queries (user_id, name, description, query_text, catalog, schema_name)
+			 VALUES ($1, $2, $3, $4, $5, $6)
+			 RETURNING id`,
+			userID, req.Name, req.Description, req.QueryText, req.Catalog, req.SchemaName,
+		).Scan(&ids[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
 func (s *QueryService) UpdateSavedQuery(ctx context.Context, id, userID uuid.UUID, req *models.UpdateQueryRequest) (*models.SavedQuery, error) {
+	if req.QueryText != "" {
+		if err := s.CheckStatement(req.QueryText); err != nil {
+			return nil, err
+		}
+	}
+
 	pool := database.GetPool()
 
 	var q models.SavedQuery
@@ -160,19 +222,65 @@ func (s *QueryService) DeleteSavedQuery(ctx context.Context, id, userID uuid.UUI
 
 // Query History operations
 
-func (s *QueryService) SaveQueryHistory(ctx context.Context, userID uuid.UUID, queryText, status string, executionTimeMs int64, rowCount int, errorMsg *string) error {
+func (s *QueryService) SaveQueryHistory(ctx context.Context, userID uuid.UUID, queryText, status string, executionTimeMs int64, rowCount int, errorMsg *string, source models.QueryHistorySource, sourceID *uuid.UUID) error {
 	pool := database.GetPool()
 
 	execTime := int(executionTimeMs)
 	_, err := pool.Exec(ctx,
-		`INSERT INTO query_history (user_id, query_text, status, execution_time_ms, row_count, error_message)
-		 VALUES ($1, $2, $3, $4, $5, $6)`,
-		userID, queryText, status, execTime, rowCount, errorMsg,
+		`INSERT INTO query_history (user_id, query_text, status, execution_time_ms, row_count, error_message, source, source_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		userID, queryText, status, execTime, rowCount, errorMsg, source, sourceID,
 	)
 
 	return err
 }
 
+// RecordQueryExecution saves the history entry for one execution from its result or execErr.
+// Failures are logged only; recording must never affect the execution itself.
+func RecordQueryExecution(ctx context.Context, recorder repository.QueryHistoryRecorder, userID uuid.UUID, queryText string, source models.QueryHistorySource, sourceID *uuid.UUID, result *models.QueryResult, execErr error) {
+	if recorder == nil {
+		return
+	}
+
+	var err error
+	if execErr != nil {
+		errMsg := execErr.Error()
+		err = recorder.SaveQueryHistory(ctx, userID, queryText, "error", 0, 0, &errMsg, source, sourceID)
+	} else {
+		err = recorder.SaveQueryHistory(ctx, userID, queryText, "success", result.ExecutionTimeMs, result.RowCount, nil, source, sourceID)
+	}
+	if err != nil {
+		log.Printf("failed to record %s query history for user %s: %v", source, userID, err)
+	}
+}
+
+// TrimQueryHistory deletes each user's query history beyond their maxPerUser most recent
+// entries and returns how many were deleted. maxPerUser <= 0 keeps everything. Entries that
+// must outlive the cap (such as pinned entries, should they be added) need to be excluded
+// from the ranking here.
+func (s *QueryService) TrimQueryHistory(ctx context.Context, maxPerUser int) (int64, error) {
+	if maxPerUser <= 0 {
+		return 0, nil
+	}
+
+	pool := database.GetPool()
+
+	result, err := pool.Exec(ctx,
+		`DELETE FROM query_history h
+		 USING (
+		     SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY executed_at DESC, id DESC) AS position
+		     FROM query_history
+		 ) ranked
+		 WHERE h.id = ranked.id AND ranked.position > $1`,
+		maxPerUser,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected(), nil
+}
+
 func (s *QueryService) GetQueryHistory(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.QueryHistory, error) {
 	pool := database.GetPool()
 
@@ -183,7 +291,7 @@ func (s *QueryService) GetQueryHistory(ctx context.Context, userID uuid.UUID, li
 	limit = min(limit, 200)
 
 	rows, err := pool.Query(ctx,
-		`SELECT id, user_id, query_text, status, execution_time_ms, row_count, error_message, executed_at
+		`SELECT id, user_id, query_text, status, execution_time_ms, row_count, error_message, source, source_id, executed_at
 		 FROM query_history
 		 WHERE user_id = $1
 		 ORDER BY executed_at DESC
@@ -198,7 +306,7 @@ func (s *QueryService) GetQueryHistory(ctx context.Context, userID uuid.UUID, li
 	var history []models.QueryHistory
 	for rows.Next() {
 		var h models.QueryHistory
-		if err := rows.Scan(&h.ID, &h.UserID, &h.QueryText, &h.Status, &h.ExecutionTimeMs, &h.RowCount, &h.ErrorMessage, &h.ExecutedAt); err != nil {
+		if err := rows.Scan(&h.ID, &h.UserID, &h.QueryText, &h.Status, &h.ExecutionTimeMs, &h.RowCount, &h.ErrorMessage, &h.Source, &h.SourceID, &h.ExecutedAt); err != nil {
 			return nil, err
 		}
 		history = append(history, h)
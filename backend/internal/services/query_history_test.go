@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mitsume/backend/internal/database"
+)
+
+// setupHistoryDatabase connects to the database named by TEST_DATABASE_URL and runs the
+// migrations, skipping the test when no database is configured
+func setupHistoryDatabase(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	previous := database.GetPool()
+	database.SetPool(pool)
+	t.Cleanup(func() {
+		database.SetPool(previous)
+		pool.Close()
+	})
+
+	if err := database.RunMigrations(); err != nil {
+		t.Fatalf("RunMigrations() error = %v", err)
+	}
+	return pool
+}
+
+func TestTrimQueryHistory_KeepsMostRecentEntriesPerUser(t *testing.T) {
+	pool := setupHistoryDatabase(t)
+	ctx := context.Background()
+
+	createUser := func(t *testing.T) uuid.UUID {
+		var id uuid.UUID
+		err := pool.QueryRow(ctx,
+			`INSERT INTO users (email, name) VALUES ($1, 'history test') RETURNING id`,
+			fmt.Sprintf("history-%s@example.com", uuid.NewString()),
+		).Scan(&id)
+		if err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		t.Cleanup(func() { _, _ = pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, id) })
+		return id
+	}
+	addHistory := func(t *testing.T, userID uuid.UUID, n int) {
+		start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i := 0; i < n; i++ {
+			_, err := pool.Exec(ctx,
+				`INSERT INTO query_history (user_id, query_text, status, executed_at) VALUES ($1, $2, 'success', $3)`,
+				userID, fmt.Sprintf("SELECT %d", i), start.Add(time.Duration(i)*time.Minute),
+			)
+			if err != nil {
+				t.Fatalf("failed to insert history: %v", err)
+			}
+		}
+	}
+
+	heavy := createUser(t)
+	light := createUser(t)
+	addHistory(t, heavy, 8)
+	addHistory(t, light, 3)
+
+	s := NewQueryService(nil)
+	if _, err := s.TrimQueryHistory(ctx, 5); err != nil {
+		t.Fatalf("TrimQueryHistory() error = %v", err)
+	}
+
+	history, err := s.GetQueryHistory(ctx, heavy, 50, 0)
+	if err != nil {
+		t.Fatalf("GetQueryHistory() error = %v", err)
+	}
+	if len(history) != 5 {
+		t.Fatalf("user over the cap has %d entries, want 5", len(history))
+	}
+	if history[0].QueryText != "SELECT 7" || history[4].QueryText != "SELECT 3" {
+		t.Errorf("kept %q through %q, want the newest entries SELECT 7 through SELECT 3", history[0].QueryText, history[4].QueryText)
+	}
+
+	history, err = s.GetQueryHistory(ctx, light, 50, 0)
+	if err != nil {
+		t.Fatalf("GetQueryHistory() error = %v", err)
+	}
+	if len(history) != 3 {
+		t.Errorf("user under the cap has %d entries, want all 3", len(history))
+	}
+}
+
+func TestTrimQueryHistory_ZeroCapKeepsEverything(t *testing.T) {
+	// A disabled cap must not touch the database, so no connection is needed
+	n, err := NewQueryService(nil).TrimQueryHistory(context.Background(), 0)
+	if err != nil || n != 0 {
+		t.Fatalf("TrimQueryHistory(0) = %d, %v, want 0, nil", n, err)
+	}
+}
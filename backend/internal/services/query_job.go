@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/repository"
+)
+
+const (
+	// queryJobRetention is how long completed jobs remain available for status polling
+	queryJobRetention = 1 * time.Hour
+	// queryJobMaxRuntime bounds jobs submitted without a timeout
+	queryJobMaxRuntime = 1 * time.Hour
+	// queryJobGrace lets the executor report its own timeout before the job's deadline cancels it
+	queryJobGrace = 30 * time.Second
+	// queryJobCallbackTimeout bounds callback delivery, including retries
+	queryJobCallbackTimeout = 5 * time.Minute
+)
+
+// ErrTooManyQueryJobs is returned when a user already has the maximum number of unfinished jobs
+var ErrTooManyQueryJobs = errors.New("too many running query jobs")
+
+// QueryJobService runs queries in the background and tracks their status in memory
+type QueryJobService struct {
+	executor        repository.CachedTrinoExecutor
+	historyRecorder repository.QueryHistoryRecorder
+	callbacks       *CallbackService
+	maxJobsPerUser  int // 0 = unlimited
+	retention       time.Duration
+
+	mu   sync.RWMutex
+	jobs map[uuid.UUID]*models.QueryJob
+}
+
+// NewQueryJobService creates a new async query job service
+func NewQueryJobService(executor repository.CachedTrinoExecutor, historyRecorder repository.QueryHistoryRecorder, callbacks *CallbackService) *QueryJobService {
+	return &QueryJobService{
+		executor:        executor,
+		historyRecorder: historyRecorder,
+		callbacks:       callbacks,
+		retention:       queryJobRetention,
+		jobs:            make(map[uuid.UUID]*models.QueryJob),
+	}
+}
+
+// SetMaxJobsPerUser caps how many pending or running jobs a user may have (0 = unlimited)
+func (s *QueryJobService) SetMaxJobsPerUser(n int) {
+	s.maxJobsPerUser = n
+}
+
+// CheckCallbackURL reports whether job results may be POSTed to rawURL
+func (s *QueryJobService) CheckCallbackURL(ctx context.Context, rawURL string) error {
+	if s.callbacks == nil {
+		return ErrCallbacksDisabled
+	}
+	return s.callbacks.CheckURL(ctx, rawURL)
+}
+
+// Submit registers a new job and starts executing it in the background with the given
+// timeout (0 for QUERY_TIMEOUT_SECONDS). The returned job is a snapshot taken at submission time.
+// Fails with ErrTooManyQueryJobs when the user is at the per-user job limit.
+func (s *QueryJobService) Submit(userID uuid.UUID, query, catalog, schema string, callbackURL *string, timeout time.Duration) (*models.QueryJob, error) {
+	job := &models.QueryJob{
+		ID:          uuid.New(),
+		UserID:      userID,
+		QueryText:   query,
+		Catalog:     catalog,
+		Schema:      schema,
+		Status:      models.QueryJobStatusPending,
+		CallbackURL: callbackURL,
+		CreatedAt:   time.Now(),
+	}
+
+	s.mu.Lock()
+	if s.maxJobsPerUser > 0 {
+		if n := s.unfinishedLocked(userID); n >= s.maxJobsPerUser {
+			s.mu.Unlock()
+			return nil, fmt.Errorf("%w (%d/%d): wait for one to finish", ErrTooManyQueryJobs, n, s.maxJobsPerUser)
+		}
+	}
+	s.jobs[job.ID] = job
+	snapshot := *job
+	s.mu.Unlock()
+
+	go s.run(job.ID, timeout)
+
+	return &snapshot, nil
+}
+
+// GetJob returns a snapshot of a job owned by the given user
+func (s *QueryJobService) GetJob(jobID, userID uuid.UUID) (*models.QueryJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[jobID]
+	if !ok || job.UserID != userID {
+		return nil, ErrNotFound
+	}
+	snapshot := *job
+	return &snapshot, nil
+}
+
+func (s *QueryJobService) run(jobID uuid.UUID, timeout time.Duration) {
+	// Detached from the request context: the job outlives the submitting request, but not its timeout
+	deadline := queryJobMaxRuntime
+	if timeout > 0 {
+		deadline = timeout + queryJobGrace
+	}
+	ctx, cancel := context.WithTimeout(WithQueryTimeout(context.Background(), timeout), deadline)
+	defer cancel()
+
+	s.mu.Lock()
+	job := s.jobs[jobID]
+	job.Status = models.QueryJobStatusRunning
+	query, catalog, schema, userID := job.QueryText, job.Catalog, job.Schema, job.UserID
+	s.mu.Unlock()
+
+	result, err := s.executor.ExecuteQueryWithCache(ctx, query, catalog, schema, int(CachePriorityLow), nil)
+	completedAt := time.Now()
+
+	s.mu.Lock()
+	job.CompletedAt = &completedAt
+	if err != nil {
+		errMsg := err.Error()
+		job.Status = models.QueryJobStatusFailed
+		job.Error = &errMsg
+	} else {
+		job.Status = models.QueryJobStatusSucceeded
+		job.Result = result
+	}
+	snapshot := *job
+	s.mu.Unlock()
+
+	// Results are held in memory, so completed jobs are dropped after the retention window
+	time.AfterFunc(s.retention, func() {
+		s.mu.Lock()
+		delete(s.jobs, jobID)
+		s.mu.Unlock()
+	})
+
+	if s.historyRecorder != nil {
+		// Still record jobs that hit their deadline
+		ctx := context.WithoutCancel(ctx)
+		var recErr error
+		if snapshot.Status == models.QueryJobStatusFailed {
+			recErr = s.historyRecorder.SaveQueryHistory(ctx, userID, query, "error", 0, 0, snapshot.Error, models.QueryHistorySourceQueryJob, nil)
+		} else {
+			recErr = s.historyRecorder.SaveQueryHistory(ctx, userID, query, "success", result.ExecutionTimeMs, result.RowCount, nil, models.QueryHistorySourceQueryJob, nil)
+		}
+		if recErr != nil {
+			log.Printf("failed to record query history for job %s: %v", jobID, recErr)
+		}
+	}
+
+	if snapshot.CallbackURL == nil || s.callbacks == nil {
+		return
+	}
+
+	payload := models.QueryJobCallbackPayload{
+		JobID:       snapshot.ID,
+		Status:      snapshot.Status,
+		Error:       snapshot.Error,
+		CompletedAt: completedAt,
+	}
+	if snapshot.Result != nil {
+		payload.RowCount = snapshot.Result.RowCount
+	}
+	// The query's deadline may have passed; delivery gets its own
+	callbackCtx, cancelCallback := context.WithTimeout(context.Background(), queryJobCallbackTimeout)
+	defer cancelCallback()
+	if err := s.callbacks.Deliver(callbackCtx, *snapshot.CallbackURL, payload); err != nil {
+		log.Printf("Failed to deliver callback for job %s: %v", jobID, err)
+	}
+}
+
+// unfinishedLocked counts the user's pending and running jobs. Caller must hold s.mu.
+func (s *QueryJobService) unfinishedLocked(userID uuid.UUID) int {
+	n := 0
+	for _, job := range s.jobs {
+		if job.UserID == userID && job.CompletedAt == nil {
+			n++
+		}
+	}
+	return n
+}
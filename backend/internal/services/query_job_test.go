@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/config"
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/repository"
+)
+
+// waitForJob polls until the job has completed
+func waitForJob(t *testing.T, svc *QueryJobService, jobID, userID uuid.UUID) *models.QueryJob {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := svc.GetJob(jobID, userID)
+		if err != nil {
+			t.Fatalf("GetJob() error = %v", err)
+		}
+		if job.CompletedAt != nil {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not complete", jobID)
+	return nil
+}
+
+func TestQueryJobService_RunsJobAndRecordsHistory(t *testing.T) {
+	executor := repository.NewMockTrinoExecutor()
+	executor.QueryResults["SELECT 1"] = &models.QueryResult{Columns: []string{"x"}, Rows: [][]interface{}{{1}}, RowCount: 1}
+	recorded := make(chan string, 1)
+	history := repository.NewMockQueryHistoryRecorder()
+	history.SaveQueryHistoryFunc = func(ctx context.Context, userID uuid.UUID, queryText, status string, executionTimeMs int64, rowCount int, errorMsg *string, source models.QueryHistorySource, sourceID *uuid.UUID) error {
+		recorded <- status
+		return nil
+	}
+	svc := NewQueryJobService(executor, history, nil)
+	userID := uuid.New()
+
+	job, err := svc.Submit(userID, "SELECT 1", "memory", "default", nil, 0)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if job.Status != models.QueryJobStatusPending {
+		t.Fatalf("status = %s, want pending", job.Status)
+	}
+
+	done := waitForJob(t, svc, job.ID, userID)
+	if done.Status != models.QueryJobStatusSucceeded || done.Result == nil || done.Result.RowCount != 1 {
+		t.Fatalf("job = %+v, want succeeded with 1 row", done)
+	}
+	if status := <-recorded; status != "success" {
+		t.Fatalf("history status = %s, want success", status)
+	}
+}
+
+func TestQueryJobService_GetJobOfAnotherUser(t *testing.T) {
+	svc := NewQueryJobService(repository.NewMockTrinoExecutor(), nil, nil)
+	owner := uuid.New()
+	job, err := svc.Submit(owner, "SELECT 1", "memory", "default", nil, 0)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	if _, err := svc.GetJob(job.ID, uuid.New()); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetJob(other user) error = %v, want ErrNotFound", err)
+	}
+	if _, err := svc.GetJob(uuid.New(), owner); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetJob(unknown job) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestQueryJobService_PerUserLimit(t *testing.T) {
+	release := make(chan struct{})
+	executor := repository.NewMockTrinoExecutor()
+	executor.ExecuteQueryFunc = func(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error) {
+		<-release
+		return &models.QueryResult{}, nil
+	}
+	svc := NewQueryJobService(executor, nil, nil)
+	svc.SetMaxJobsPerUser(1)
+	userID := uuid.New()
+
+	first, err := svc.Submit(userID, "SELECT 1", "memory", "default", nil, 0)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if _, err := svc.Submit(userID, "SELECT 2", "memory", "default", nil, 0); !errors.Is(err, ErrTooManyQueryJobs) {
+		t.Fatalf("Submit(over limit) error = %v, want ErrTooManyQueryJobs", err)
+	}
+	other := uuid.New()
+	otherJob, err := svc.Submit(other, "SELECT 3", "memory", "default", nil, 0)
+	if err != nil {
+		t.Fatalf("Submit(other user) error = %v", err)
+	}
+
+	close(release)
+	waitForJob(t, svc, first.ID, userID)
+	waitForJob(t, svc, otherJob.ID, other)
+	if _, err := svc.Submit(userID, "SELECT 4", "memory", "default", nil, 0); err != nil {
+		t.Fatalf("Submit(after completion) error = %v", err)
+	}
+}
+
+func TestQueryJobService_JobHasDeadline(t *testing.T) {
+	deadlines := make(chan time.Duration, 1)
+	executor := repository.NewMockTrinoExecutor()
+	executor.ExecuteQueryFunc = func(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error) {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			deadlines <- 0
+		} else {
+			deadlines <- time.Until(deadline)
+		}
+		return &models.QueryResult{}, nil
+	}
+	svc := NewQueryJobService(executor, nil, nil)
+
+	if _, err := svc.Submit(uuid.New(), "SELECT 1", "memory", "default", nil, 10*time.Second); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	got := <-deadlines
+	if got <= 0 || got > 10*time.Second+queryJobGrace {
+		t.Fatalf("time to deadline = %s, want within timeout plus grace", got)
+	}
+}
+
+func TestQueryJobService_DropsJobsAfterRetention(t *testing.T) {
+	svc := NewQueryJobService(repository.NewMockTrinoExecutor(), nil, nil)
+	svc.retention = 20 * time.Millisecond
+	userID := uuid.New()
+
+	job, err := svc.Submit(userID, "SELECT 1", "memory", "default", nil, 0)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	waitForJob(t, svc, job.ID, userID)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := svc.GetJob(job.ID, userID); errors.Is(err, ErrNotFound) {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s was not dropped after the retention window", job.ID)
+}
+
+func TestQueryJobService_DeliversSignedCallback(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	callbacks := NewCallbackService(&config.WebhookConfig{Secret: "s", AllowedHosts: []string{"127.0.0.1"}})
+	svc := NewQueryJobService(repository.NewMockTrinoExecutor(), nil, callbacks)
+	if err := svc.CheckCallbackURL(context.Background(), srv.URL); err != nil {
+		t.Fatalf("CheckCallbackURL() error = %v", err)
+	}
+
+	callbackURL := srv.URL
+	if _, err := svc.Submit(uuid.New(), "SELECT 1", "memory", "default", &callbackURL, 0); err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	select {
+	case r := <-received:
+		if r.Header.Get(CallbackSignatureHeader) == "" || r.Header.Get(CallbackTimestampHeader) == "" {
+			t.Fatalf("callback headers = %v, want signature and timestamp", r.Header)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("callback was not delivered")
+	}
+}
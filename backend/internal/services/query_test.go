@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/repository"
+)
+
+func TestRecordQueryExecution(t *testing.T) {
+	ctx := context.Background()
+	recorder := repository.NewMockQueryHistoryRecorder()
+	userID := uuid.New()
+	alertID := uuid.New()
+
+	RecordQueryExecution(ctx, recorder, userID, "SELECT 1", models.QueryHistorySourceAlert, &alertID,
+		&models.QueryResult{RowCount: 3, ExecutionTimeMs: 12}, nil)
+	RecordQueryExecution(ctx, recorder, userID, "SELECT broken", models.QueryHistorySourceAlert, &alertID,
+		nil, errors.New("syntax error"))
+
+	if len(recorder.SavedHistories) != 2 {
+		t.Fatalf("SavedHistories = %d, want 2", len(recorder.SavedHistories))
+	}
+
+	success := recorder.SavedHistories[0]
+	if success.Status != "success" || success.RowCount != 3 || success.ExecutionTimeMs != 12 {
+		t.Fatalf("success entry = %+v", success)
+	}
+	if success.Source != models.QueryHistorySourceAlert || success.SourceID == nil || *success.SourceID != alertID {
+		t.Fatalf("success entry source = %s/%v, want alert/%s", success.Source, success.SourceID, alertID)
+	}
+
+	failure := recorder.SavedHistories[1]
+	if failure.Status != "error" || failure.ErrorMsg == nil || *failure.ErrorMsg != "syntax error" {
+		t.Fatalf("error entry = %+v", failure)
+	}
+
+	// Recording failures must not panic or propagate
+	recorder.SaveError = errors.New("db down")
+	RecordQueryExecution(ctx, recorder, userID, "SELECT 1", models.QueryHistorySourceWidget, nil, &models.QueryResult{}, nil)
+	RecordQueryExecution(ctx, nil, userID, "SELECT 1", models.QueryHistorySourceWidget, nil, &models.QueryResult{}, nil)
+}
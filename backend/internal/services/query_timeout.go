@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mitsume/backend/internal/config"
+)
+
+// defaultQueryTimeout applies when QUERY_TIMEOUT_SECONDS is not positive
+const defaultQueryTimeout = 60 * time.Second
+
+// QueryTimeoutError is returned when Trino does not finish a query within its timeout
+type QueryTimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *QueryTimeoutError) Error() string {
+	return fmt.Sprintf("query timed out after %s", e.Timeout)
+}
+
+// QueryTimeoutPolicy decides how long a query may run
+type QueryTimeoutPolicy struct {
+	Default time.Duration // timeout of queries that do not request one
+	Max     time.Duration // longest timeout a request can ask for without a role cap
+}
+
+// NewQueryTimeoutPolicy creates a policy from QUERY_TIMEOUT_SECONDS and QUERY_MAX_TIMEOUT_SECONDS
+func NewQueryTimeoutPolicy(cfg *config.TrinoConfig) QueryTimeoutPolicy {
+	p := QueryTimeoutPolicy{
+		Default: time.Duration(cfg.QueryTimeoutSeconds) * time.Second,
+		Max:     time.Duration(cfg.MaxQueryTimeoutSeconds) * time.Second,
+	}
+	if p.Default <= 0 {
+		p.Default = defaultQueryTimeout
+	}
+	if p.Max < p.Default {
+		p.Max = p.Default
+	}
+	return p
+}
+
+// Effective returns the timeout of a query that requested the given timeout (0 for the
+// default). It is clamped to roleCap, the cap of the user's roles, or to Max when the user's
+// roles set none (nil). A zero policy returns 0, leaving the executor's default in place.
+func (p QueryTimeoutPolicy) Effective(requested time.Duration, roleCap *time.Duration) time.Duration {
+	timeout := requested
+	if timeout <= 0 {
+		timeout = p.Default
+	}
+	limit := p.Max
+	if roleCap != nil {
+		limit = *roleCap
+	}
+	if limit > 0 && timeout > limit {
+		timeout = limit
+	}
+	return timeout
+}
+
+type queryTimeoutKey struct{}
+
+// WithQueryTimeout returns a context whose queries run with the given timeout instead of
+// QUERY_TIMEOUT_SECONDS. A timeout of 0 leaves ctx unchanged.
+func WithQueryTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	if timeout <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, queryTimeoutKey{}, timeout)
+}
+
+// QueryTimeoutFrom returns the timeout set on ctx with WithQueryTimeout
+func QueryTimeoutFrom(ctx context.Context) (time.Duration, bool) {
+	timeout, ok := ctx.Value(queryTimeoutKey{}).(time.Duration)
+	return timeout, ok
+}
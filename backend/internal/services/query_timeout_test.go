@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mitsume/backend/internal/config"
+)
+
+func TestNewQueryTimeoutPolicy(t *testing.T) {
+	p := NewQueryTimeoutPolicy(&config.TrinoConfig{QueryTimeoutSeconds: 30, MaxQueryTimeoutSeconds: 600})
+	if p.Default != 30*time.Second || p.Max != 10*time.Minute {
+		t.Errorf("NewQueryTimeoutPolicy() = %+v, want 30s default and 10m max", p)
+	}
+
+	// Unset values fall back to the built-in default, and the max never undercuts the default
+	p = NewQueryTimeoutPolicy(&config.TrinoConfig{})
+	if p.Default != defaultQueryTimeout || p.Max != defaultQueryTimeout {
+		t.Errorf("NewQueryTimeoutPolicy() of empty config = %+v, want %s for both", p, defaultQueryTimeout)
+	}
+}
+
+func TestQueryTimeoutPolicy_Effective(t *testing.T) {
+	p := QueryTimeoutPolicy{Default: time.Minute, Max: 10 * time.Minute}
+	cap5m := 5 * time.Minute
+	cap30m := 30 * time.Minute
+	cap10s := 10 * time.Second
+
+	tests := []struct {
+		name      string
+		requested time.Duration
+		roleCap   *time.Duration
+		want      time.Duration
+	}{
+		{"default", 0, nil, time.Minute},
+		{"requested within max", 2 * time.Minute, nil, 2 * time.Minute},
+		{"requested above max is clamped", time.Hour, nil, 10 * time.Minute},
+		{"role cap below max", time.Hour, &cap5m, 5 * time.Minute},
+		{"role cap above max", time.Hour, &cap30m, 30 * time.Minute},
+		{"role cap below default", 0, &cap10s, 10 * time.Second},
+		{"short request under role cap", 5 * time.Second, &cap5m, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Effective(tt.requested, tt.roleCap); got != tt.want {
+				t.Errorf("Effective(%s) = %s, want %s", tt.requested, got, tt.want)
+			}
+		})
+	}
+
+	if got := (QueryTimeoutPolicy{}).Effective(0, nil); got != 0 {
+		t.Errorf("zero policy Effective() = %s, want 0", got)
+	}
+}
+
+func TestWithQueryTimeout(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := QueryTimeoutFrom(WithQueryTimeout(ctx, 0)); ok {
+		t.Error("WithQueryTimeout(0) set a timeout")
+	}
+	if got, ok := QueryTimeoutFrom(WithQueryTimeout(ctx, time.Minute)); !ok || got != time.Minute {
+		t.Errorf("QueryTimeoutFrom() = %s, %v, want 1m0s, true", got, ok)
+	}
+}
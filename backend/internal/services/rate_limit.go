@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RateLimit describes a token bucket: Burst tokens at most, refilled at PerMinute tokens per minute
+type RateLimit struct {
+	PerMinute int
+	Burst     int
+}
+
+// rate returns the refill rate in tokens per second
+func (l RateLimit) rate() float64 {
+	return float64(l.PerMinute) / 60
+}
+
+// RateLimiter takes one token from the bucket identified by key
+type RateLimiter interface {
+	// Allow reports whether the request may proceed; when it may not, retryAfter is the
+	// time until the next token is available
+	Allow(ctx context.Context, key string, limit RateLimit) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// NewRateLimiter returns a Redis-backed limiter when the cache is enabled, so limits are
+// shared across instances, and an in-memory limiter otherwise
+func NewRateLimiter(cache *QueryCacheService) RateLimiter {
+	if cache != nil {
+		return &RedisRateLimiter{client: cache.client, prefix: cache.cfg.KeyPrefix + "ratelimit:"}
+	}
+	return NewMemoryRateLimiter()
+}
+
+// memoryBucketIdleSweep is how often idle buckets are dropped from the in-memory limiter
+const memoryBucketIdleSweep = time.Minute
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	limit   RateLimit
+}
+
+// MemoryRateLimiter is a per-process token bucket limiter
+type MemoryRateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryRateLimiter creates an in-memory rate limiter
+func NewMemoryRateLimiter() *MemoryRateLimiter {
+	return &MemoryRateLimiter{
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Allow implements RateLimiter
+func (m *MemoryRateLimiter) Allow(ctx context.Context, key string, limit RateLimit) (bool, time.Duration, error) {
+	if limit.PerMinute <= 0 || limit.Burst <= 0 {
+		return true, 0, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if now.Sub(m.lastSweep) >= memoryBucketIdleSweep {
+		m.sweepLocked(now)
+	}
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(limit.Burst), updated: now}
+		m.buckets[key] = b
+	}
+
+	b.tokens = refill(b.tokens, now.Sub(b.updated), limit)
+	b.updated = now
+	b.limit = limit
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	return false, timeUntilToken(b.tokens, limit), nil
+}
+
+// sweepLocked drops buckets that have refilled completely. Caller must hold m.mu.
+func (m *MemoryRateLimiter) sweepLocked(now time.Time) {
+	for key, b := range m.buckets {
+		if refill(b.tokens, now.Sub(b.updated), b.limit) >= float64(b.limit.Burst) {
+			delete(m.buckets, key)
+		}
+	}
+	m.lastSweep = now
+}
+
+func refill(tokens float64, elapsed time.Duration, limit RateLimit) float64 {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return math.Min(float64(limit.Burst), tokens+elapsed.Seconds()*limit.rate())
+}
+
+func timeUntilToken(tokens float64, limit RateLimit) time.Duration {
+	return time.Duration((1 - tokens) / limit.rate() * float64(time.Second))
+}
+
+// tokenBucketScript atomically refills and takes a token from a bucket stored as a hash.
+// Returns {allowed (0/1), milliseconds until the next token}.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) / rate * 1000)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 1000)
+return {allowed, wait}
+`)
+
+// RedisRateLimiter is a token bucket limiter shared by every instance using the same Redis
+type RedisRateLimiter struct {
+	client *redis.Client
+	prefix string
+}
+
+// Allow implements RateLimiter
+func (r *RedisRateLimiter) Allow(ctx context.Context, key string, limit RateLimit) (bool, time.Duration, error) {
+	if limit.PerMinute <= 0 || limit.Burst <= 0 {
+		return true, 0, nil
+	}
+
+	res, err := tokenBucketScript.Run(ctx, r.client, []string{r.prefix + key},
+		limit.rate(), limit.Burst, time.Now().UnixMilli(),
+	).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	if len(res) != 2 {
+		return false, 0, fmt.Errorf("unexpected rate limit script result: %v", res)
+	}
+	return res[0] == 1, time.Duration(res[1]) * time.Millisecond, nil
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryRateLimiter_RefillsOverTime(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewMemoryRateLimiter()
+	limiter.now = func() time.Time { return now }
+	limit := RateLimit{PerMinute: 6, Burst: 2} // one token every 10s
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if ok, _, _ := limiter.Allow(ctx, "k", limit); !ok {
+			t.Fatalf("Allow() #%d = false, want true", i+1)
+		}
+	}
+
+	ok, retryAfter, _ := limiter.Allow(ctx, "k", limit)
+	if ok {
+		t.Fatal("Allow() with empty bucket = true, want false")
+	}
+	if retryAfter != 10*time.Second {
+		t.Fatalf("retryAfter = %v, want %v", retryAfter, 10*time.Second)
+	}
+
+	now = now.Add(10 * time.Second)
+	if ok, _, _ := limiter.Allow(ctx, "k", limit); !ok {
+		t.Fatal("Allow() after refill = false, want true")
+	}
+	if ok, _, _ := limiter.Allow(ctx, "k", limit); ok {
+		t.Fatal("Allow() after using refilled token = true, want false")
+	}
+}
+
+func TestMemoryRateLimiter_SweepsIdleBuckets(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := NewMemoryRateLimiter()
+	limiter.now = func() time.Time { return now }
+	limiter.lastSweep = now
+	limit := RateLimit{PerMinute: 60, Burst: 1}
+	ctx := context.Background()
+
+	limiter.Allow(ctx, "idle", limit)
+	now = now.Add(2 * time.Minute)
+	limiter.Allow(ctx, "active", limit)
+
+	if _, ok := limiter.buckets["idle"]; ok {
+		t.Fatal("idle bucket was not swept")
+	}
+	if _, ok := limiter.buckets["active"]; !ok {
+		t.Fatal("active bucket missing")
+	}
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrStatementNotReadOnly = errors.New("only SELECT, SHOW, DESCRIBE, EXPLAIN and WITH queries are allowed in read-only mode")
+	ErrMultipleStatements   = errors.New("multiple statements are not allowed")
+)
+
+// readOnlyStatements are the leading keywords of statements that cannot modify data.
+// A query starting with WITH is always a query in Trino (INSERT ... WITH starts with INSERT).
+var readOnlyStatements = map[string]bool{
+	"SELECT":   true,
+	"SHOW":     true,
+	"DESCRIBE": true,
+	"EXPLAIN":  true,
+	"WITH":     true,
+}
+
+// CheckReadOnlyStatement fails with ErrStatementNotReadOnly unless query is a single statement
+// that only reads data, and with ErrMultipleStatements when it contains more than one.
+// Comments are ignored and a trailing semicolon is allowed.
+func CheckReadOnlyStatement(query string) error {
+	statements := splitStatements(query)
+	if len(statements) > 1 {
+		return ErrMultipleStatements
+	}
+	if len(statements) == 0 {
+		return nil
+	}
+	return checkReadOnly(statements[0])
+}
+
+func checkReadOnly(statement string) error {
+	keyword, rest := leadingKeyword(strings.TrimLeft(statement, " \t\r\n("))
+	if !readOnlyStatements[keyword] {
+		if keyword == "" {
+			return ErrStatementNotReadOnly
+		}
+		return fmt.Errorf("%w: %s", ErrStatementNotReadOnly, keyword)
+	}
+
+	// EXPLAIN ANALYZE runs the statement it explains, so that statement must be read-only too
+	if keyword == "EXPLAIN" {
+		next, afterNext := leadingKeyword(rest)
+		if next == "ANALYZE" {
+			rest = afterNext
+			if next, afterNext = leadingKeyword(rest); next == "VERBOSE" {
+				rest = afterNext
+			}
+			return checkReadOnly(rest)
+		}
+	}
+	return nil
+}
+
+// leadingKeyword returns the upper-cased word at the start of s (after whitespace) and the rest of s
+func leadingKeyword(s string) (string, string) {
+	s = strings.TrimLeft(s, " \t\r\n")
+	end := 0
+	for end < len(s) && (s[end] >= 'a' && s[end] <= 'z' || s[end] >= 'A' && s[end] <= 'Z') {
+		end++
+	}
+	return strings.ToUpper(s[:end]), s[end:]
+}
+
+// splitStatements splits query on semicolons outside string literals, quoted identifiers and
+// comments. Comments are replaced by a space and empty statements are dropped.
+func splitStatements(query string) []string {
+	var statements []string
+	var b strings.Builder
+
+	flush := func() {
+		if s := strings.TrimSpace(b.String()); s != "" {
+			statements = append(statements, s)
+		}
+		b.Reset()
+	}
+
+	for i := 0; i < len(query); {
+		switch {
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			b.WriteByte(' ')
+			i += end
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i - 4 // unterminated: drop the rest
+			}
+			b.WriteByte(' ')
+			i += end + 4
+		case query[i] == '\'' || query[i] == '"':
+			quote := query[i]
+			j := i + 1
+			for j < len(query) {
+				if query[j] == quote {
+					if j+1 < len(query) && query[j+1] == quote {
+						j += 2 // escaped quote
+						continue
+					}
+					break
+				}
+				j++
+			}
+			end := min(j+1, len(query))
+			b.WriteString(query[i:end])
+			i = end
+		case query[i] == ';':
+			flush()
+			i++
+		default:
+			b.WriteByte(query[i])
+			i++
+		}
+	}
+	flush()
+	return statements
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckReadOnlyStatement(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantErr error
+	}{
+		{"select", "SELECT * FROM hive.sales.orders", nil},
+		{"lower case with trailing semicolon", "select 1;", nil},
+		{"with", "WITH t AS (SELECT 1 AS x) SELECT x FROM t", nil},
+		{"parenthesized select", "(SELECT 1) UNION (SELECT 2)", nil},
+		{"show", "SHOW TABLES FROM hive.sales", nil},
+		{"describe", "DESCRIBE hive.sales.orders", nil},
+		{"explain", "EXPLAIN SELECT 1", nil},
+		{"explain of a write is not executed", "EXPLAIN DELETE FROM t", nil},
+		{"leading comments", "-- daily report\n/* owner: sales */ SELECT 1", nil},
+		{"semicolon in literal", "SELECT ';DROP TABLE t' AS s", nil},
+		{"semicolon in quoted identifier", `SELECT 1 AS "a;DROP TABLE t"`, nil},
+		{"empty", "  -- nothing\n", nil},
+		{"delete", "DELETE FROM hive.sales.orders", ErrStatementNotReadOnly},
+		{"drop", "drop table hive.sales.orders", ErrStatementNotReadOnly},
+		{"insert with select", "INSERT INTO t WITH s AS (SELECT 1) SELECT * FROM s", ErrStatementNotReadOnly},
+		{"create table as", "CREATE TABLE t AS SELECT 1", ErrStatementNotReadOnly},
+		{"comment hides keyword", "/* SELECT */ DELETE FROM t", ErrStatementNotReadOnly},
+		{"explain analyze runs the statement", "EXPLAIN ANALYZE VERBOSE INSERT INTO t VALUES (1)", ErrStatementNotReadOnly},
+		{"explain analyze select", "EXPLAIN ANALYZE SELECT 1", nil},
+		{"multiple statements", "SELECT 1; SELECT 2", ErrMultipleStatements},
+		{"write after select", "SELECT 1;\nDROP TABLE t", ErrMultipleStatements},
+		{"statement after line comment", "SELECT 1 -- ;\n; DELETE FROM t", ErrMultipleStatements},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckReadOnlyStatement(tt.query)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("CheckReadOnlyStatement(%q) error = %v, want %v", tt.query, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQueryService_CheckStatement_OnlyInReadOnlyMode(t *testing.T) {
+	s := NewQueryService(nil)
+	if err := s.CheckStatement("DELETE FROM t"); err != nil {
+		t.Fatalf("CheckStatement() error = %v, want nil when read-only mode is off", err)
+	}
+
+	s.SetReadOnlyMode(true)
+	if err := s.CheckStatement("DELETE FROM t"); !errors.Is(err, ErrStatementNotReadOnly) {
+		t.Fatalf("CheckStatement() error = %v, want %v", err, ErrStatementNotReadOnly)
+	}
+}
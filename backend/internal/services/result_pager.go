@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultResultPageSize is the page size used when no page size is given
+	DefaultResultPageSize = 1000
+	// MaxResultPageSize bounds the rows returned by a single page
+	MaxResultPageSize = 10000
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrCursorExpired = errors.New("result is no longer available; run the query again")
+)
+
+// ResultStore holds complete query results for cursor pagination. Entries expire once they
+// have not been read for the idle duration.
+type ResultStore interface {
+	Put(ctx context.Context, key string, result *models.QueryResult, idle time.Duration) error
+
+	// Get returns the result stored under key and extends its expiry by idle, or
+	// ErrNotFound if there is none
+	Get(ctx context.Context, key string, idle time.Duration) (*models.QueryResult, error)
+}
+
+// NewResultStore returns a Redis-backed store when the cache is enabled, so any instance can
+// serve the next page, and an in-memory store otherwise
+func NewResultStore(cache *QueryCacheService) ResultStore {
+	if cache != nil {
+		return &RedisResultStore{client: cache.client, prefix: cache.cfg.KeyPrefix + "result:"}
+	}
+	return NewMemoryResultStore()
+}
+
+// memoryResultSweep is how often expired results are dropped from the in-memory store
+const memoryResultSweep = time.Minute
+
+type storedResult struct {
+	result    *models.QueryResult
+	expiresAt time.Time
+}
+
+// MemoryResultStore is a per-process ResultStore
+type MemoryResultStore struct {
+	mu        sync.Mutex
+	results   map[string]*storedResult
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryResultStore creates an in-memory result store
+func NewMemoryResultStore() *MemoryResultStore {
+	return &MemoryResultStore{
+		results:   make(map[string]*storedResult),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Put implements ResultStore
+func (m *MemoryResultStore) Put(ctx context.Context, key string, result *models.QueryResult, idle time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweepLocked(now)
+	m.results[key] = &storedResult{result: result, expiresAt: now.Add(idle)}
+	return nil
+}
+
+// Get implements ResultStore
+func (m *MemoryResultStore) Get(ctx context.Context, key string, idle time.Duration) (*models.QueryResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweepLocked(now)
+	stored, ok := m.results[key]
+	if !ok || now.After(stored.expiresAt) {
+		return nil, ErrNotFound
+	}
+	stored.expiresAt = now.Add(idle)
+	return stored.result, nil
+}
+
+// sweepLocked drops expired results. Caller must hold m.mu.
+func (m *MemoryResultStore) sweepLocked(now time.Time) {
+	if now.Sub(m.lastSweep) < memoryResultSweep {
+		return
+	}
+	for key, stored := range m.results {
+		if now.After(stored.expiresAt) {
+			delete(m.results, key)
+		}
+	}
+	m.lastSweep = now
+}
+
+// RedisResultStore is a ResultStore shared by every instance using the same Redis
+type RedisResultStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// Put implements ResultStore
+func (r *RedisResultStore) Put(ctx context.Context, key string, result *models.QueryResult, idle time.Duration) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, r.prefix+key, data, idle).Err()
+}
+
+// Get implements ResultStore
+func (r *RedisResultStore) Get(ctx context.Context, key string, idle time.Duration) (*models.QueryResult, error) {
+	data, err := r.client.GetEx(ctx, r.prefix+key, idle).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	var result models.QueryResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ResultPager serves large results page by page. The complete result is executed once, held
+// in a ResultStore per user and query, and later pages are read from the store by cursor.
+type ResultPager struct {
+	store ResultStore
+	idle  time.Duration
+}
+
+// NewResultPager creates a pager whose results expire after idle without a page being read
+func NewResultPager(store ResultStore, idle time.Duration) *ResultPager {
+	return &ResultPager{store: store, idle: idle}
+}
+
+// resultCursor is the decoded form of the opaque cursor handed to clients
+type resultCursor struct {
+	Key      string `json:"k"`
+	Offset   int    `json:"o"`
+	PageSize int    `json:"n"`
+}
+
+// resultKey identifies the user's result for a query; it starts with the user ID so a cursor
+// can only be used by the user it was issued to
+func resultKey(userID uuid.UUID, query, catalog, schema string) string {
+	return GenerateCacheKey(userID.String()+":", query, catalog, schema)
+}
+
+// Open returns the first page of the user's result for the query. A result still held from an
+// earlier Open is reused; otherwise execute runs the query and its result is stored.
+func (p *ResultPager) Open(
+	ctx context.Context,
+	userID uuid.UUID,
+	query, catalog, schema string,
+	pageSize int,
+	execute func() (*models.QueryResult, error),
+) (*models.QueryResultPage, error) {
+	key := resultKey(userID, query, catalog, schema)
+
+	result, err := p.store.Get(ctx, key, p.idle)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		if result, err = execute(); err != nil {
+			return nil, err
+		}
+		if err := p.store.Put(ctx, key, result, p.idle); err != nil {
+			return nil, err
+		}
+	}
+
+	return resultPage(result, resultCursor{Key: key, PageSize: clampPageSize(pageSize)}), nil
+}
+
+// Next returns the page a cursor from Open or Next points to. It fails with ErrInvalidCursor
+// for malformed cursors or cursors issued to another user, and with ErrCursorExpired once the
+// result has expired.
+func (p *ResultPager) Next(ctx context.Context, userID uuid.UUID, cursor string) (*models.QueryResultPage, error) {
+	c, err := decodeResultCursor(cursor)
+	if err != nil || !strings.HasPrefix(c.Key, userID.String()+":") {
+		return nil, ErrInvalidCursor
+	}
+
+	result, err := p.store.Get(ctx, c.Key, p.idle)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrCursorExpired
+		}
+		return nil, err
+	}
+	return resultPage(result, c), nil
+}
+
+func resultPage(result *models.QueryResult, c resultCursor) *models.QueryResultPage {
+	total := len(result.Rows)
+	start := min(c.Offset, total)
+	end := min(start+c.PageSize, total)
+
+	page := &models.QueryResultPage{
+		Columns:         result.Columns,
+		Rows:            result.Rows[start:end],
+		RowCount:        end - start,
+		TotalRows:       total,
+		ExecutionTimeMs: result.ExecutionTimeMs,
+	}
+	if page.Rows == nil {
+		page.Rows = [][]interface{}{}
+	}
+	if end < total {
+		next := encodeResultCursor(resultCursor{Key: c.Key, Offset: end, PageSize: c.PageSize})
+		page.NextCursor = &next
+	}
+	return page
+}
+
+func clampPageSize(pageSize int) int {
+	if pageSize <= 0 {
+		return DefaultResultPageSize
+	}
+	return min(pageSize, MaxResultPageSize)
+}
+
+func encodeResultCursor(c resultCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeResultCursor(cursor string) (resultCursor, error) {
+	var c resultCursor
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, err
+	}
+	if c.Key == "" || c.Offset < 0 || c.PageSize <= 0 || c.PageSize > MaxResultPageSize {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+)
+
+func numberedResult(n int) *models.QueryResult {
+	rows := make([][]interface{}, n)
+	for i := range rows {
+		rows[i] = []interface{}{i}
+	}
+	return &models.QueryResult{Columns: []string{"n"}, Rows: rows, RowCount: n}
+}
+
+func TestResultPager_PagesThroughStoredResult(t *testing.T) {
+	ctx := context.Background()
+	pager := NewResultPager(NewMemoryResultStore(), time.Minute)
+	userID := uuid.New()
+
+	executions := 0
+	execute := func() (*models.QueryResult, error) {
+		executions++
+		return numberedResult(5), nil
+	}
+
+	page, err := pager.Open(ctx, userID, "SELECT n FROM t", "hive", "default", 2, execute)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	var seen []interface{}
+	for {
+		if page.TotalRows != 5 || page.RowCount != len(page.Rows) {
+			t.Fatalf("page = %+v, want total 5 and row_count matching rows", page)
+		}
+		for _, row := range page.Rows {
+			seen = append(seen, row[0])
+		}
+		if page.NextCursor == nil {
+			break
+		}
+		if page, err = pager.Next(ctx, userID, *page.NextCursor); err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+	}
+	if len(seen) != 5 || seen[0] != 0 || seen[4] != 4 {
+		t.Fatalf("rows = %v, want 0..4", seen)
+	}
+
+	// Opening the same query again serves the held result
+	if _, err := pager.Open(ctx, userID, "SELECT n FROM t", "hive", "default", 2, execute); err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if executions != 1 {
+		t.Fatalf("query executed %d times, want 1", executions)
+	}
+}
+
+func TestResultPager_RejectsForeignAndMalformedCursors(t *testing.T) {
+	ctx := context.Background()
+	pager := NewResultPager(NewMemoryResultStore(), time.Minute)
+	owner := uuid.New()
+
+	page, err := pager.Open(ctx, owner, "SELECT n FROM t", "hive", "default", 1, func() (*models.QueryResult, error) {
+		return numberedResult(3), nil
+	})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	if _, err := pager.Next(ctx, uuid.New(), *page.NextCursor); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("Next() by another user error = %v, want ErrInvalidCursor", err)
+	}
+	if _, err := pager.Next(ctx, owner, "not-a-cursor"); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("Next() with malformed cursor error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestResultPager_ExpiresAfterInactivity(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryResultStore()
+	store.now = func() time.Time { return now }
+	pager := NewResultPager(store, 10*time.Minute)
+	userID := uuid.New()
+
+	page, _ := pager.Open(ctx, userID, "SELECT n FROM t", "hive", "default", 1, func() (*models.QueryResult, error) {
+		return numberedResult(3), nil
+	})
+
+	// Reading a page keeps the result alive
+	now = now.Add(9 * time.Minute)
+	page, err := pager.Next(ctx, userID, *page.NextCursor)
+	if err != nil {
+		t.Fatalf("Next() within idle time error = %v", err)
+	}
+
+	now = now.Add(11 * time.Minute)
+	if _, err := pager.Next(ctx, userID, *page.NextCursor); !errors.Is(err, ErrCursorExpired) {
+		t.Fatalf("Next() after idle time error = %v, want ErrCursorExpired", err)
+	}
+}
+
+func TestResultPager_ClampsPageSize(t *testing.T) {
+	pager := NewResultPager(NewMemoryResultStore(), time.Minute)
+	page, err := pager.Open(context.Background(), uuid.New(), "SELECT n FROM t", "", "", 0, func() (*models.QueryResult, error) {
+		return numberedResult(DefaultResultPageSize + 1), nil
+	})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if page.RowCount != DefaultResultPageSize || page.NextCursor == nil {
+		t.Fatalf("page has %d rows, want the default page size %d and a next cursor", page.RowCount, DefaultResultPageSize)
+	}
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/mitsume/backend/internal/models"
@@ -16,6 +17,8 @@ var (
 	ErrDuplicateRoleName  = errors.New("role with this name already exists")
 	ErrUnauthorized       = errors.New("unauthorized: admin access required")
 	ErrCannotSelfDemote   = errors.New("cannot remove your own admin role")
+	ErrParentRoleNotFound = errors.New("parent role not found")
+	ErrRoleCycle          = errors.New("parent role would create an inheritance cycle")
 )
 
 type RoleService struct {
@@ -42,9 +45,24 @@ func (s *RoleService) GetAllRoles(ctx context.Context) ([]models.RoleWithCatalog
 		if err != nil {
 			return nil, err
 		}
+		schemas, err := s.roleRepo.GetRoleSchemas(ctx, role.ID)
+		if err != nil {
+			return nil, err
+		}
+		rowFilters, err := s.roleRepo.GetRoleRowFilters(ctx, role.ID)
+		if err != nil {
+			return nil, err
+		}
+		timeout, err := s.roleRepo.GetRoleQueryTimeout(ctx, role.ID)
+		if err != nil {
+			return nil, err
+		}
 		result[i] = models.RoleWithCatalogs{
-			Role:     role,
-			Catalogs: catalogs,
+			Role:                   role,
+			Catalogs:               catalogs,
+			Schemas:                schemas,
+			RowFilters:             rowFilters,
+			MaxQueryTimeoutSeconds: timeout,
 		}
 	}
 	return result, nil
@@ -63,10 +81,25 @@ func (s *RoleService) GetRole(ctx context.Context, roleID uuid.UUID) (*models.Ro
 	if err != nil {
 		return nil, err
 	}
+	schemas, err := s.roleRepo.GetRoleSchemas(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	rowFilters, err := s.roleRepo.GetRoleRowFilters(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := s.roleRepo.GetRoleQueryTimeout(ctx, roleID)
+	if err != nil {
+		return nil, err
+	}
 
 	return &models.RoleWithCatalogs{
-		Role:     *role,
-		Catalogs: catalogs,
+		Role:                   *role,
+		Catalogs:               catalogs,
+		Schemas:                schemas,
+		RowFilters:             rowFilters,
+		MaxQueryTimeoutSeconds: timeout,
 	}, nil
 }
 
@@ -126,12 +159,43 @@ func (s *RoleService) UpdateRole(ctx context.Context, adminUserID, roleID uuid.U
 		}
 	}
 
+	if req.ParentRoleID != nil {
+		if err := s.checkParentRole(ctx, roleID, *req.ParentRoleID); err != nil {
+			return nil, err
+		}
+	}
+
 	name := req.Name
 	if name == "" {
 		name = role.Name
 	}
 
-	return s.roleRepo.Update(ctx, roleID, name, req.Description)
+	return s.roleRepo.Update(ctx, roleID, name, req.Description, req.ParentRoleID)
+}
+
+// checkParentRole verifies that parentID exists and that making it the parent
+// of roleID would not create a cycle, by walking up the parent's ancestors.
+func (s *RoleService) checkParentRole(ctx context.Context, roleID, parentID uuid.UUID) error {
+	visited := make(map[uuid.UUID]bool)
+	for id := &parentID; id != nil; {
+		if *id == roleID || visited[*id] {
+			return ErrRoleCycle
+		}
+		visited[*id] = true
+
+		ancestor, err := s.roleRepo.GetByID(ctx, *id)
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				if *id == parentID {
+					return ErrParentRoleNotFound
+				}
+				return nil
+			}
+			return err
+		}
+		id = ancestor.ParentRoleID
+	}
+	return nil
 }
 
 func (s *RoleService) DeleteRole(ctx context.Context, adminUserID, roleID uuid.UUID) error {
@@ -184,6 +248,84 @@ func (s *RoleService) SetRoleCatalogs(ctx context.Context, adminUserID, roleID u
 	return s.roleRepo.SetRoleCatalogs(ctx, roleID, catalogs)
 }
 
+// SetRoleSchemas replaces the role's schema permissions. Catalog permissions are left
+// untouched and keep granting every schema of their catalogs.
+func (s *RoleService) SetRoleSchemas(ctx context.Context, adminUserID, roleID uuid.UUID, schemas []models.SchemaPermission) error {
+	// Check if admin
+	isAdmin, err := s.roleRepo.IsUserAdmin(ctx, adminUserID)
+	if err != nil {
+		return err
+	}
+	if !isAdmin {
+		return ErrUnauthorized
+	}
+
+	// Check if role exists
+	_, err = s.roleRepo.GetByID(ctx, roleID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrRoleNotFound
+		}
+		return err
+	}
+
+	return s.roleRepo.SetRoleSchemas(ctx, roleID, schemas)
+}
+
+// SetRoleRowFilters replaces the role's row filters, rejecting predicates that cannot be
+// embedded in a WHERE clause with a *models.ValidationError
+func (s *RoleService) SetRoleRowFilters(ctx context.Context, adminUserID, roleID uuid.UUID, filters []models.RowFilter) error {
+	// Check if admin
+	isAdmin, err := s.roleRepo.IsUserAdmin(ctx, adminUserID)
+	if err != nil {
+		return err
+	}
+	if !isAdmin {
+		return ErrUnauthorized
+	}
+
+	// Check if role exists
+	_, err = s.roleRepo.GetByID(ctx, roleID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrRoleNotFound
+		}
+		return err
+	}
+
+	for _, f := range filters {
+		if err := ValidateRowFilterPredicate(f.Predicate); err != nil {
+			return err
+		}
+	}
+
+	return s.roleRepo.SetRoleRowFilters(ctx, roleID, filters)
+}
+
+// SetRoleQueryTimeout sets the longest timeout, in seconds, that the role's users' queries may
+// run with; nil removes the cap so QUERY_MAX_TIMEOUT_SECONDS applies
+func (s *RoleService) SetRoleQueryTimeout(ctx context.Context, adminUserID, roleID uuid.UUID, seconds *int) error {
+	// Check if admin
+	isAdmin, err := s.roleRepo.IsUserAdmin(ctx, adminUserID)
+	if err != nil {
+		return err
+	}
+	if !isAdmin {
+		return ErrUnauthorized
+	}
+
+	// Check if role exists
+	_, err = s.roleRepo.GetByID(ctx, roleID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrRoleNotFound
+		}
+		return err
+	}
+
+	return s.roleRepo.SetRoleQueryTimeout(ctx, roleID, seconds)
+}
+
 // User-Role assignments
 
 func (s *RoleService) AssignRoleToUser(ctx context.Context, adminUserID, targetUserID, roleID uuid.UUID) error {
@@ -280,6 +422,89 @@ func (s *RoleService) CanUserAccessCatalog(ctx context.Context, userID uuid.UUID
 	return false, nil
 }
 
+// GetUserAllowedSchemas returns the schemas a user can access, with catalog-level grants
+// expressed as models.AllSchemas (nil means all schemas for admin)
+func (s *RoleService) GetUserAllowedSchemas(ctx context.Context, userID uuid.UUID) ([]models.SchemaPermission, error) {
+	return s.roleRepo.GetUserAllowedSchemas(ctx, userID)
+}
+
+// GetUserRowFilters returns the row filters that apply to a user's queries (nil for admin)
+func (s *RoleService) GetUserRowFilters(ctx context.Context, userID uuid.UUID) ([]models.RowFilter, error) {
+	return s.roleRepo.GetUserRowFilters(ctx, userID)
+}
+
+// GetUserQueryTimeoutCap returns the longest timeout the user's roles allow their queries
+// (nil for admin and when no role sets a cap)
+func (s *RoleService) GetUserQueryTimeoutCap(ctx context.Context, userID uuid.UUID) (*time.Duration, error) {
+	seconds, err := s.roleRepo.GetUserMaxQueryTimeout(ctx, userID)
+	if err != nil || seconds == nil {
+		return nil, err
+	}
+	limit := time.Duration(*seconds) * time.Second
+	return &limit, nil
+}
+
+func (s *RoleService) CanUserAccessSchema(ctx context.Context, userID uuid.UUID, catalog, schema string) (bool, error) {
+	// Check if admin (admin has access to all schemas)
+	isAdmin, err := s.roleRepo.IsUserAdmin(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if isAdmin {
+		return true, nil
+	}
+
+	allowedSchemas, err := s.roleRepo.GetUserAllowedSchemas(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return SchemaAllowed(allowedSchemas, catalog, schema), nil
+}
+
+// SchemaAllowed reports whether allowed (as returned by GetUserAllowedSchemas for a non-admin)
+// grants catalog.schema. An empty schema asks for access to any schema of the catalog, which
+// is enough to list the catalog's schemas.
+func SchemaAllowed(allowed []models.SchemaPermission, catalog, schema string) bool {
+	for _, sp := range allowed {
+		if sp.Catalog != catalog {
+			continue
+		}
+		if schema == "" || sp.Schema == models.AllSchemas || sp.Schema == schema {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterAllowedSchemas returns the schemas of catalog that allowed grants (nil means all)
+func FilterAllowedSchemas(allowed []models.SchemaPermission, catalog string, schemas []string) []string {
+	if allowed == nil {
+		return schemas
+	}
+	filtered := make([]string, 0, len(schemas))
+	for _, schema := range schemas {
+		if SchemaAllowed(allowed, catalog, schema) {
+			filtered = append(filtered, schema)
+		}
+	}
+	return filtered
+}
+
+// FilterAllowedMetadata drops metadata search hits in schemas that allowed does not grant
+// (nil means all)
+func FilterAllowedMetadata(allowed []models.SchemaPermission, hits []models.MetadataSearchResult) []models.MetadataSearchResult {
+	if allowed == nil {
+		return hits
+	}
+	filtered := make([]models.MetadataSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		if SchemaAllowed(allowed, hit.Catalog, hit.Schema) {
+			filtered = append(filtered, hit)
+		}
+	}
+	return filtered
+}
+
 // GetUserRoles returns the roles for a specific user
 func (s *RoleService) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]models.Role, error) {
 	return s.roleRepo.GetUserRoles(ctx, userID)
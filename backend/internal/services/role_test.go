@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/repository"
+)
+
+func TestRoleService_UpdateRole_ParentValidation(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMockRoleRepository()
+	admin := uuid.New()
+	repo.AdminUsers[admin] = true
+
+	grandparent, _ := repo.Create(ctx, "grandparent", "")
+	parent, _ := repo.Create(ctx, "parent", "")
+	child, _ := repo.Create(ctx, "child", "")
+	parent.ParentRoleID = &grandparent.ID
+
+	svc := NewRoleService(repo)
+
+	updated, err := svc.UpdateRole(ctx, admin, child.ID, &models.UpdateRoleRequest{ParentRoleID: &parent.ID})
+	if err != nil {
+		t.Fatalf("UpdateRole() error = %v", err)
+	}
+	if updated.ParentRoleID == nil || *updated.ParentRoleID != parent.ID {
+		t.Fatalf("ParentRoleID = %v, want %v", updated.ParentRoleID, parent.ID)
+	}
+
+	tests := []struct {
+		name   string
+		roleID uuid.UUID
+		parent uuid.UUID
+		want   error
+	}{
+		{"self", child.ID, child.ID, ErrRoleCycle},
+		{"descendant", grandparent.ID, child.ID, ErrRoleCycle},
+		{"unknown", child.ID, uuid.New(), ErrParentRoleNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.UpdateRole(ctx, admin, tt.roleID, &models.UpdateRoleRequest{ParentRoleID: &tt.parent})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("UpdateRole() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := svc.UpdateRole(ctx, admin, child.ID, &models.UpdateRoleRequest{}); err != nil {
+		t.Fatalf("UpdateRole() clearing parent error = %v", err)
+	}
+	if child.ParentRoleID != nil {
+		t.Fatalf("ParentRoleID = %v, want nil after clearing", child.ParentRoleID)
+	}
+}
+
+func TestRoleService_CanUserAccessSchema(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMockRoleRepository()
+	parent, _ := repo.Create(ctx, "analyst", "")
+	child, _ := repo.Create(ctx, "sales", "")
+	child.ParentRoleID = &parent.ID
+	repo.RoleCatalogs[parent.ID] = []string{"memory"}
+	repo.RoleSchemas[child.ID] = []models.SchemaPermission{{Catalog: "hive", Schema: "sales"}}
+
+	user := uuid.New()
+	repo.UserRoles[user] = []uuid.UUID{child.ID}
+
+	svc := NewRoleService(repo)
+
+	tests := []struct {
+		catalog, schema string
+		want            bool
+	}{
+		{"hive", "sales", true},
+		{"hive", "hr", false},
+		{"memory", "default", true}, // inherited catalog grant covers every schema
+		{"secret", "default", false},
+	}
+	for _, tt := range tests {
+		got, err := svc.CanUserAccessSchema(ctx, user, tt.catalog, tt.schema)
+		if err != nil {
+			t.Fatalf("CanUserAccessSchema() error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("CanUserAccessSchema(%s, %s) = %v, want %v", tt.catalog, tt.schema, got, tt.want)
+		}
+	}
+
+	ok, err := svc.CanUserAccessCatalog(ctx, user, "hive")
+	if err != nil || !ok {
+		t.Fatalf("CanUserAccessCatalog(hive) = %v, %v; want a schema grant to expose its catalog", ok, err)
+	}
+}
@@ -0,0 +1,393 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+)
+
+// Row filters restrict the rows of a table that a role's users see. Queries are rewritten before
+// they reach Trino: each reference to a filtered table in a FROM clause, JOIN or FROM list is
+// replaced with a subquery applying the filter's predicate, keeping the reference's alias:
+//
+//	FROM hive.sales.orders o  ->  FROM (SELECT * FROM hive.sales.orders WHERE (region = 'west')) o
+//
+// Only SELECT queries (optionally starting with WITH) that name a filtered table are rewritten.
+// Rather than run a query unfiltered, ApplyRowFilters rejects it when a filtered table is named
+// anywhere that is not such a table reference: in other statements (DESCRIBE, SHOW STATS, ...),
+// as a CTE or column name, after a JOIN ... ON list or in a reference it cannot parse. Table
+// functions are rejected for every user with row filters, as they may read any table.
+// Views over a filtered table are not filtered; filter the view itself as well.
+
+// ErrRowFilterUnsupported is returned for queries that read a filtered table in a way the
+// rewriter cannot filter safely
+var ErrRowFilterUnsupported = errors.New("query cannot be rewritten to apply row filters")
+
+// RowFilterSource looks up the row filters that apply to a user's queries (nil for admins)
+type RowFilterSource interface {
+	GetUserRowFilters(ctx context.Context, userID uuid.UUID) ([]models.RowFilter, error)
+}
+
+type sqlTokenKind int
+
+const (
+	sqlSpace   sqlTokenKind = iota // whitespace
+	sqlComment                     // -- or /* */ comment
+	sqlWord                        // unquoted identifier or keyword
+	sqlQuoted                      // "quoted identifier"
+	sqlString                      // 'string literal'
+	sqlNumber
+	sqlPunct // any other single character
+)
+
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+}
+
+// tokenizeSQL splits a query into tokens whose texts concatenate back to the query. It reports
+// false for an unterminated string, quoted identifier or block comment.
+func tokenizeSQL(query string) ([]sqlToken, bool) {
+	var tokens []sqlToken
+	isWordChar := func(c byte) bool {
+		return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+	}
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		start := i
+		var kind sqlTokenKind
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			for i < len(query) && strings.IndexByte(" \t\n\r\f", query[i]) >= 0 {
+				i++
+			}
+			kind = sqlSpace
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			i += end
+			kind = sqlComment
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return nil, false
+			}
+			i += end + 4
+			kind = sqlComment
+		case c == '\'' || c == '"':
+			j := i + 1
+			for {
+				if j >= len(query) {
+					return nil, false
+				}
+				if query[j] == c {
+					if j+1 < len(query) && query[j+1] == c {
+						j += 2 // escaped quote
+						continue
+					}
+					break
+				}
+				j++
+			}
+			i = j + 1
+			kind = sqlString
+			if c == '"' {
+				kind = sqlQuoted
+			}
+		case c >= '0' && c <= '9':
+			for i < len(query) && (isWordChar(query[i]) || query[i] == '.') {
+				i++
+			}
+			kind = sqlNumber
+		case isWordChar(c):
+			for i < len(query) && isWordChar(query[i]) {
+				i++
+			}
+			kind = sqlWord
+		default:
+			i++
+			kind = sqlPunct
+		}
+		tokens = append(tokens, sqlToken{kind: kind, text: query[start:i]})
+	}
+	return tokens, true
+}
+
+// ValidateRowFilterPredicate checks that a predicate can be embedded in a WHERE clause: a
+// non-empty expression with balanced parentheses, without comments or statement separators
+func ValidateRowFilterPredicate(predicate string) error {
+	tokens, ok := tokenizeSQL(predicate)
+	if !ok {
+		return &models.ValidationError{Field: "predicate", Message: "predicate has an unterminated string or comment"}
+	}
+	depth := 0
+	empty := true
+	for _, t := range tokens {
+		switch {
+		case t.kind == sqlComment:
+			return &models.ValidationError{Field: "predicate", Message: "predicate must not contain comments"}
+		case t.kind == sqlPunct && t.text == ";":
+			return &models.ValidationError{Field: "predicate", Message: "predicate must be a single expression"}
+		case t.kind == sqlPunct && t.text == "(":
+			depth++
+		case t.kind == sqlPunct && t.text == ")":
+			depth--
+			if depth < 0 {
+				return &models.ValidationError{Field: "predicate", Message: "predicate has unbalanced parentheses"}
+			}
+		}
+		if t.kind != sqlSpace {
+			empty = false
+		}
+	}
+	if empty {
+		return &models.ValidationError{Field: "predicate", Message: "predicate is required"}
+	}
+	if depth != 0 {
+		return &models.ValidationError{Field: "predicate", Message: "predicate has unbalanced parentheses"}
+	}
+	return nil
+}
+
+// rowFilterTable identifies a table in lower case, as Trino resolves unquoted names
+type rowFilterTable struct {
+	catalog, schema, table string
+}
+
+// rowFilterAliasStop lists keywords that may follow a table reference and so are never its alias
+var rowFilterAliasStop = map[string]bool{
+	"where": true, "join": true, "left": true, "right": true, "full": true, "inner": true, "cross": true,
+	"natural": true, "on": true, "using": true, "group": true, "having": true, "order": true, "limit": true,
+	"offset": true, "fetch": true, "union": true, "intersect": true, "except": true, "window": true,
+	"tablesample": true, "for": true, "match_recognize": true, "with": true, "select": true,
+}
+
+// rowFilterFromListEnd lists keywords that end a comma-separated FROM list
+var rowFilterFromListEnd = map[string]bool{
+	"where": true, "group": true, "having": true, "order": true, "limit": true, "offset": true,
+	"fetch": true, "union": true, "intersect": true, "except": true, "window": true, "on": true,
+	"using": true, "select": true,
+}
+
+// ApplyRowFilters rewrites query so that every filtered table it reads is filtered by the
+// predicates that apply to it. Predicates of different roles on the same table are combined
+// with OR, as each describes rows one of the user's roles may see. Unqualified names resolve
+// against catalog and schema. The query is returned unchanged when it names no filtered table;
+// queries that cannot be rewritten safely fail with ErrRowFilterUnsupported.
+func ApplyRowFilters(query, catalog, schema string, filters []models.RowFilter) (string, error) {
+	if len(filters) == 0 {
+		return query, nil
+	}
+
+	predicates := make(map[rowFilterTable][]string)
+	for _, f := range filters {
+		key := rowFilterTable{strings.ToLower(f.Catalog), strings.ToLower(f.Schema), strings.ToLower(f.Table)}
+		predicates[key] = append(predicates[key], f.Predicate)
+	}
+
+	tokens, ok := tokenizeSQL(query)
+	if !ok {
+		return "", fmt.Errorf("%w: the query could not be parsed", ErrRowFilterUnsupported)
+	}
+	// Indexes of the significant (non-space, non-comment) tokens
+	var sig []int
+	for i, t := range tokens {
+		if t.kind != sqlSpace && t.kind != sqlComment {
+			sig = append(sig, i)
+		}
+	}
+	tok := func(k int) sqlToken {
+		if k < 0 || k >= len(sig) {
+			return sqlToken{}
+		}
+		return tokens[sig[k]]
+	}
+	isPunct := func(k int, p string) bool { return tok(k).kind == sqlPunct && tok(k).text == p }
+	isKeyword := func(k int, kw string) bool { return tok(k).kind == sqlWord && strings.EqualFold(tok(k).text, kw) }
+	isIdent := func(k int) bool { return tok(k).kind == sqlWord || tok(k).kind == sqlQuoted }
+
+	// chainAt reads a dotted name starting at significant token k, returning its parts and the
+	// index of its last token, or no parts when k does not start a name
+	chainAt := func(k int) ([]string, int) {
+		if !isIdent(k) {
+			return nil, k
+		}
+		parts := []string{normalizeIdentifier(tok(k).text)}
+		for isPunct(k+1, ".") && isIdent(k+2) {
+			k += 2
+			parts = append(parts, normalizeIdentifier(tok(k).text))
+		}
+		return parts, k
+	}
+	resolve := func(parts []string) (rowFilterTable, bool) {
+		switch len(parts) {
+		case 1:
+			return rowFilterTable{strings.ToLower(catalog), strings.ToLower(schema), parts[0]}, true
+		case 2:
+			return rowFilterTable{strings.ToLower(catalog), parts[0], parts[1]}, true
+		case 3:
+			return rowFilterTable{parts[0], parts[1], parts[2]}, true
+		}
+		return rowFilterTable{}, false
+	}
+
+	type replacement struct {
+		first, last int // significant token range replaced
+		text        string
+	}
+	var replacements []replacement
+
+	// tableRefAt rewrites the table reference starting at significant token k if it names a
+	// filtered table
+	tableRefAt := func(k int) {
+		parts, last := chainAt(k)
+		if parts == nil || isPunct(last+1, ".") || isPunct(last+1, "(") {
+			return // not a plain table name, e.g. a subquery, UNNEST(...) or a table function
+		}
+		table, ok := resolve(parts)
+		if !ok {
+			return
+		}
+		preds, filtered := predicates[table]
+		if !filtered {
+			return
+		}
+
+		var ref strings.Builder
+		for i := sig[k]; i <= sig[last]; i++ {
+			ref.WriteString(tokens[i].text)
+		}
+		alias := tok(last).text // keeps qualified column references such as orders.id working
+		end := last
+		a := last + 1
+		if isKeyword(a, "as") {
+			a++
+		}
+		if isIdent(a) && !rowFilterAliasStop[strings.ToLower(tok(a).text)] {
+			end = a
+			alias = ""
+			if isPunct(a+1, "(") {
+				// column aliases: AS t (a, b)
+				depth := 0
+				for end = a + 1; end < len(sig); end++ {
+					if isPunct(end, "(") {
+						depth++
+					} else if isPunct(end, ")") {
+						depth--
+						if depth == 0 {
+							break
+						}
+					}
+				}
+			}
+			var b strings.Builder
+			for i := sig[a]; i <= sig[min(end, len(sig)-1)]; i++ {
+				b.WriteString(tokens[i].text)
+			}
+			alias = b.String()
+		}
+
+		conditions := make([]string, len(preds))
+		for i, p := range preds {
+			conditions[i] = "(" + p + ")"
+		}
+		replacements = append(replacements, replacement{
+			first: k,
+			last:  end,
+			text:  "(SELECT * FROM " + ref.String() + " WHERE " + strings.Join(conditions, " OR ") + ") " + alias,
+		})
+	}
+
+	// Find table references: after FROM and JOIN, and after commas of a FROM list
+	fromList := map[int]bool{}
+	depth := 0
+	for k := 0; k < len(sig); k++ {
+		switch {
+		case isPunct(k, "("):
+			depth++
+			fromList[depth] = false
+		case isPunct(k, ")"):
+			fromList[depth] = false
+			depth--
+		case isKeyword(k, "from"):
+			fromList[depth] = true
+			tableRefAt(k + 1)
+		case isKeyword(k, "join"):
+			tableRefAt(k + 1)
+		case isPunct(k, ",") && fromList[depth]:
+			tableRefAt(k + 1)
+		case tok(k).kind == sqlWord && rowFilterFromListEnd[strings.ToLower(tok(k).text)]:
+			fromList[depth] = false
+		}
+	}
+
+	// Table functions such as query passthrough read tables the rewriter cannot see
+	for k := 0; k < len(sig); k++ {
+		if isKeyword(k, "table") && isPunct(k+1, "(") {
+			return "", fmt.Errorf("%w: table functions cannot be used with row filters", ErrRowFilterUnsupported)
+		}
+	}
+
+	// Every other name that resolves to a filtered table would read it unfiltered
+	replaced := make([]bool, len(sig))
+	for _, r := range replacements {
+		for k := r.first; k <= r.last && k < len(sig); k++ {
+			replaced[k] = true
+		}
+	}
+	for k := 0; k < len(sig); k++ {
+		if replaced[k] || !isIdent(k) || isPunct(k-1, ".") {
+			continue
+		}
+		parts, last := chainAt(k)
+		if table, ok := resolve(parts); ok {
+			if _, filtered := predicates[table]; filtered {
+				return "", fmt.Errorf("%w: %s.%s.%s is named outside a FROM or JOIN table reference", ErrRowFilterUnsupported, table.catalog, table.schema, table.table)
+			}
+		}
+		k = last
+	}
+	if len(replacements) == 0 {
+		return query, nil
+	}
+
+	if !isKeyword(0, "select") && !isKeyword(0, "with") && !isPunct(0, "(") {
+		return "", fmt.Errorf("%w: only SELECT queries can read tables with row filters", ErrRowFilterUnsupported)
+	}
+	for k := 0; k < len(sig)-1; k++ {
+		if isPunct(k, ";") {
+			return "", fmt.Errorf("%w: multiple statements are not supported", ErrRowFilterUnsupported)
+		}
+	}
+
+	var b strings.Builder
+	next := 0 // next token to copy
+	for _, r := range replacements {
+		for i := next; i < sig[r.first]; i++ {
+			b.WriteString(tokens[i].text)
+		}
+		b.WriteString(r.text)
+		next = sig[min(r.last, len(sig)-1)] + 1
+	}
+	for i := next; i < len(tokens); i++ {
+		b.WriteString(tokens[i].text)
+	}
+	return b.String(), nil
+}
+
+// HasRowFilter reports whether any of the filters applies to the table
+func HasRowFilter(filters []models.RowFilter, catalog, schema, table string) bool {
+	for _, f := range filters {
+		if strings.EqualFold(f.Catalog, catalog) && strings.EqualFold(f.Schema, schema) && strings.EqualFold(f.Table, table) {
+			return true
+		}
+	}
+	return false
+}
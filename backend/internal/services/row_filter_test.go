@@ -0,0 +1,169 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mitsume/backend/internal/models"
+)
+
+func TestApplyRowFilters(t *testing.T) {
+	filters := []models.RowFilter{
+		{Catalog: "hive", Schema: "sales", Table: "orders", Predicate: "region = 'west'"},
+		{Catalog: "hive", Schema: "hr", Table: "Employees", Predicate: "dept_id = 10"},
+		{Catalog: "hive", Schema: "hr", Table: "employees", Predicate: "dept_id = 20"},
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"unfiltered table", "SELECT * FROM hive.sales.customers", "SELECT * FROM hive.sales.customers"},
+		{"no table", "SELECT 1", "SELECT 1"},
+		{
+			"qualified name keeps its name as alias",
+			"SELECT orders.id FROM hive.sales.orders",
+			"SELECT orders.id FROM (SELECT * FROM hive.sales.orders WHERE (region = 'west')) orders",
+		},
+		{
+			"unqualified name resolves against the session",
+			"SELECT * FROM orders o WHERE o.total > 10",
+			"SELECT * FROM (SELECT * FROM orders WHERE (region = 'west')) o WHERE o.total > 10",
+		},
+		{
+			"schema-qualified name with AS alias",
+			"select * from Sales.ORDERS as o",
+			"select * from (SELECT * FROM Sales.ORDERS WHERE (region = 'west')) o",
+		},
+		{
+			"quoted identifiers",
+			`SELECT * FROM "hive"."sales"."orders" "o"`,
+			`SELECT * FROM (SELECT * FROM "hive"."sales"."orders" WHERE (region = 'west')) "o"`,
+		},
+		{
+			"column aliases",
+			"SELECT a FROM orders t (a, b)",
+			"SELECT a FROM (SELECT * FROM orders WHERE (region = 'west')) t (a, b)",
+		},
+		{
+			"join",
+			"SELECT * FROM hive.sales.customers c JOIN orders o ON o.customer_id = c.id",
+			"SELECT * FROM hive.sales.customers c JOIN (SELECT * FROM orders WHERE (region = 'west')) o ON o.customer_id = c.id",
+		},
+		{
+			"joined table of a parenthesized join",
+			"SELECT * FROM (hive.sales.customers c JOIN orders o ON o.customer_id = c.id)",
+			"SELECT * FROM (hive.sales.customers c JOIN (SELECT * FROM orders WHERE (region = 'west')) o ON o.customer_id = c.id)",
+		},
+		{
+			"from list",
+			"SELECT * FROM hive.sales.customers c, orders o WHERE o.customer_id = c.id",
+			"SELECT * FROM hive.sales.customers c, (SELECT * FROM orders WHERE (region = 'west')) o WHERE o.customer_id = c.id",
+		},
+		{
+			"subquery and CTE",
+			"WITH recent AS (SELECT * FROM orders WHERE day > 1) SELECT * FROM recent WHERE id IN (SELECT id FROM orders)",
+			"WITH recent AS (SELECT * FROM (SELECT * FROM orders WHERE (region = 'west')) orders WHERE day > 1) SELECT * FROM recent WHERE id IN (SELECT id FROM (SELECT * FROM orders WHERE (region = 'west')) orders)",
+		},
+		{
+			"filters of several roles are combined",
+			"SELECT * FROM hive.hr.employees",
+			"SELECT * FROM (SELECT * FROM hive.hr.employees WHERE (dept_id = 10) OR (dept_id = 20)) employees",
+		},
+		{
+			"alias followed by clause keyword",
+			"SELECT * FROM orders GROUP BY 1",
+			"SELECT * FROM (SELECT * FROM orders WHERE (region = 'west')) orders GROUP BY 1",
+		},
+		{
+			"filtered name in literal and comment",
+			"SELECT 'orders' -- orders\nFROM orders",
+			"SELECT 'orders' -- orders\nFROM (SELECT * FROM orders WHERE (region = 'west')) orders",
+		},
+		{"same table in another schema", "SELECT * FROM hive.archive.orders", "SELECT * FROM hive.archive.orders"},
+		{"extract from is not a table", "SELECT EXTRACT(YEAR FROM created_at) FROM hive.sales.customers", "SELECT EXTRACT(YEAR FROM created_at) FROM hive.sales.customers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyRowFilters(tt.query, "hive", "sales", filters)
+			if err != nil {
+				t.Fatalf("ApplyRowFilters(%q) error = %v", tt.query, err)
+			}
+			if got != tt.want {
+				t.Errorf("ApplyRowFilters(%q) =\n  %q\nwant\n  %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyRowFilters_RejectsUnsafeQueries(t *testing.T) {
+	filters := []models.RowFilter{{Catalog: "hive", Schema: "sales", Table: "orders", Predicate: "region = 'west'"}}
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"describe", "DESCRIBE hive.sales.orders"},
+		{"show stats", "SHOW STATS FOR orders"},
+		{"explain", "EXPLAIN SELECT * FROM orders"},
+		{"insert", "INSERT INTO hive.sales.copy SELECT * FROM orders"},
+		{"CTE shadowing the table", "WITH orders AS (SELECT 1 AS id) SELECT * FROM orders"},
+		{"first table of a parenthesized join", "SELECT * FROM (orders o JOIN hive.sales.customers c ON o.customer_id = c.id)"},
+		{"from list after join", "SELECT * FROM a JOIN b ON a.id = b.id, orders"},
+		{"table function", "SELECT * FROM TABLE(hive.system.query(query => 'SELECT * FROM customers'))"},
+		{"multiple statements", "SELECT * FROM orders; SELECT 1"},
+		{"unterminated string", "SELECT * FROM orders WHERE a = 'x"},
+		{"column named like the table", "SELECT orders FROM hive.sales.customers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyRowFilters(tt.query, "hive", "sales", filters)
+			if !errors.Is(err, ErrRowFilterUnsupported) {
+				t.Fatalf("ApplyRowFilters(%q) = %q, %v, want %v", tt.query, got, err, ErrRowFilterUnsupported)
+			}
+		})
+	}
+}
+
+func TestApplyRowFilters_NoFilters(t *testing.T) {
+	query := "DESCRIBE hive.sales.orders"
+	got, err := ApplyRowFilters(query, "hive", "sales", nil)
+	if err != nil || got != query {
+		t.Fatalf("ApplyRowFilters() = %q, %v, want the query unchanged", got, err)
+	}
+}
+
+func TestValidateRowFilterPredicate(t *testing.T) {
+	tests := []struct {
+		predicate string
+		valid     bool
+	}{
+		{"region = 'west'", true},
+		{"dept_id IN (1, 2) AND (active OR manager)", true},
+		{"name = 'it''s; fine'", true},
+		{"", false},
+		{"   ", false},
+		{"1 = 1; DROP TABLE t", false},
+		{"1 = 1 -- ", false},
+		{"1 = 1 /* */", false},
+		{"a = 1) OR (1 = 1", false},
+		{"(a = 1", false},
+		{"a = 'x", false},
+	}
+
+	for _, tt := range tests {
+		err := ValidateRowFilterPredicate(tt.predicate)
+		if tt.valid && err != nil {
+			t.Errorf("ValidateRowFilterPredicate(%q) error = %v, want nil", tt.predicate, err)
+		}
+		if !tt.valid {
+			var validationErr *models.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Errorf("ValidateRowFilterPredicate(%q) error = %v, want a validation error", tt.predicate, err)
+			}
+		}
+	}
+}
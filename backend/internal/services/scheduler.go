@@ -2,23 +2,51 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/go-co-op/gocron/v2"
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/metrics"
 	"github.com/mitsume/backend/internal/models"
 )
 
+// Lock names for scheduler batches; every replica must use the same names
+const (
+	alertBatchLockKey        = "mitsume:scheduler:process-alerts"
+	alertDigestBatchLockKey  = "mitsume:scheduler:process-alert-digests"
+	subscriptionBatchLockKey = "mitsume:scheduler:process-subscriptions"
+	trashPurgeLockKey        = "mitsume:scheduler:purge-dashboard-trash"
+	historyTrimLockKey       = "mitsume:scheduler:trim-query-history"
+)
+
+// dueClaimLease is how far GetDueAlerts and GetDueSubscriptions push the next check/run time
+// of the rows they claim. It matches the batch timeout, so rows claimed by a replica that
+// dies mid-batch become due again once the batch could no longer be running.
+const dueClaimLease = 5 * time.Minute
+
 // Scheduler manages background jobs for alerts and subscriptions
 type Scheduler struct {
 	scheduler           gocron.Scheduler
 	alertService        *AlertService
 	subscriptionService *SubscriptionService
 	notificationService *NotificationService
+	locker              BatchLocker // nil runs every batch without locking (single replica)
+	dashboardService    *DashboardService
+	trashRetention      time.Duration // 0 disables purging the dashboard trash
+	trashWarning        time.Duration // how long before a purge owners are warned; 0 disables warnings
+	queryService        *QueryService
+	historyCap          int // query history entries kept per user; 0 disables trimming
+	maintenance         MaintenanceChecker
 }
 
-// NewScheduler creates a new scheduler instance
-func NewScheduler(alertService *AlertService, subscriptionService *SubscriptionService, notificationService *NotificationService) (*Scheduler, error) {
+// NewScheduler creates a new scheduler instance.
+// When locker is non-nil, each batch runs only on the replica that holds its lock.
+func NewScheduler(alertService *AlertService, subscriptionService *SubscriptionService, notificationService *NotificationService, locker BatchLocker) (*Scheduler, error) {
 	scheduler, err := gocron.NewScheduler()
 	if err != nil {
 		return nil, err
@@ -29,9 +57,49 @@ func NewScheduler(alertService *AlertService, subscriptionService *SubscriptionS
 		alertService:        alertService,
 		subscriptionService: subscriptionService,
 		notificationService: notificationService,
+		locker:              locker,
 	}, nil
 }
 
+// SetTrashPurge makes the scheduler permanently delete dashboards that have been in the trash
+// longer than retention; retention <= 0 keeps them until restored
+func (s *Scheduler) SetTrashPurge(dashboardService *DashboardService, retention time.Duration) {
+	s.dashboardService = dashboardService
+	s.trashRetention = retention
+}
+
+// SetTrashPurgeWarning makes the scheduler notify owners in-app that a trashed dashboard will be
+// purged warning before it is. Dashboards are kept until their owner has had the full warning
+// period, even past the retention; warning <= 0 purges without notice.
+func (s *Scheduler) SetTrashPurgeWarning(warning time.Duration) {
+	s.trashWarning = warning
+}
+
+// SetQueryHistoryCap makes the scheduler trim each user's query history to their maxPerUser most
+// recent entries; maxPerUser <= 0 keeps the whole history
+func (s *Scheduler) SetQueryHistoryCap(queryService *QueryService, maxPerUser int) {
+	s.queryService = queryService
+	s.historyCap = maxPerUser
+}
+
+// SetMaintenance makes the scheduler leave alerts and subscriptions due while maintenance mode
+// is on. Nothing is claimed, so they run on the first tick after maintenance ends.
+func (s *Scheduler) SetMaintenance(maintenance MaintenanceChecker) {
+	s.maintenance = maintenance
+}
+
+// inMaintenance reports whether the batch named what should be skipped for maintenance
+func (s *Scheduler) inMaintenance(ctx context.Context, what string) bool {
+	if s.maintenance == nil {
+		return false
+	}
+	active, _ := s.maintenance.InMaintenance(ctx)
+	if active {
+		log.Printf("Maintenance mode is on; leaving due %s for after maintenance", what)
+	}
+	return active
+}
+
 // Start begins the scheduler
 func (s *Scheduler) Start() error {
 	// Process alerts every minute
@@ -44,6 +112,16 @@ func (s *Scheduler) Start() error {
 		return err
 	}
 
+	// Send daily alert digests every minute (each user's digest is due at their own time)
+	_, err = s.scheduler.NewJob(
+		gocron.DurationJob(1*time.Minute),
+		gocron.NewTask(s.processAlertDigests),
+		gocron.WithName("process-alert-digests"),
+	)
+	if err != nil {
+		return err
+	}
+
 	// Process subscriptions every minute
 	_, err = s.scheduler.NewJob(
 		gocron.DurationJob(1*time.Minute),
@@ -54,6 +132,30 @@ func (s *Scheduler) Start() error {
 		return err
 	}
 
+	// Purge the dashboard trash hourly
+	if s.dashboardService != nil && s.trashRetention > 0 {
+		_, err = s.scheduler.NewJob(
+			gocron.DurationJob(1*time.Hour),
+			gocron.NewTask(s.purgeDashboardTrash),
+			gocron.WithName("purge-dashboard-trash"),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Trim query history to the per-user cap hourly
+	if s.queryService != nil && s.historyCap > 0 {
+		_, err = s.scheduler.NewJob(
+			gocron.DurationJob(1*time.Hour),
+			gocron.NewTask(s.trimQueryHistory),
+			gocron.WithName("trim-query-history"),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
 	s.scheduler.Start()
 	log.Println("Scheduler started")
 	return nil
@@ -65,9 +167,17 @@ func (s *Scheduler) Stop() error {
 }
 
 func (s *Scheduler) processAlerts() {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), dueClaimLease)
 	defer cancel()
 
+	s.runExclusive(ctx, alertBatchLockKey, s.processDueAlerts)
+}
+
+func (s *Scheduler) processDueAlerts(ctx context.Context) {
+	if s.inMaintenance(ctx, "alerts") {
+		return
+	}
+
 	alerts, err := s.alertService.GetDueAlerts(ctx)
 	if err != nil {
 		log.Printf("Failed to get due alerts: %v", err)
@@ -79,16 +189,48 @@ func (s *Scheduler) processAlerts() {
 	}
 }
 
+// runExclusive runs batch while holding the named lock. If the lock is held by another
+// replica or cannot be acquired, the batch is skipped and retried on the next tick.
+// A panicking batch is recovered so the lock is always released and the job keeps running.
+func (s *Scheduler) runExclusive(ctx context.Context, key string, batch func(ctx context.Context)) {
+	if s.locker != nil {
+		release, ok, err := s.locker.TryLock(ctx, key)
+		if err != nil {
+			log.Printf("Failed to acquire scheduler lock %q: %v", key, err)
+			return
+		}
+		if !ok {
+			return
+		}
+		defer release()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Scheduler batch %q panicked: %v", key, r)
+		}
+	}()
+
+	batch(ctx)
+}
+
 func (s *Scheduler) processAlert(ctx context.Context, alert *models.QueryAlert) {
 	// Evaluate the alert
 	triggered, value, err := s.alertService.EvaluateAlert(ctx, alert)
 	if err != nil {
 		log.Printf("Failed to evaluate alert %s: %v", alert.ID, err)
+		metrics.AlertEvaluationsTotal.WithLabelValues("error").Inc()
 		errMsg := err.Error()
 		_ = s.alertService.RecordAlertHistory(ctx, alert.ID, "", "error", nil, &errMsg)
 		return
 	}
 
+	if triggered {
+		metrics.AlertEvaluationsTotal.WithLabelValues("triggered").Inc()
+	} else {
+		metrics.AlertEvaluationsTotal.WithLabelValues("not_triggered").Inc()
+	}
+
 	// Calculate next check time
 	nextCheckAt := time.Now().Add(time.Duration(alert.CheckIntervalMinutes) * time.Minute)
 
@@ -103,6 +245,14 @@ func (s *Scheduler) processAlert(ctx context.Context, alert *models.QueryAlert)
 			}
 		}
 
+		// Snoozed alerts keep their history but do not notify. The trigger is not recorded
+		// on the alert so the cooldown does not delay the first notification after the snooze ends.
+		if isAlertSnoozed(alert, time.Now()) {
+			_ = s.alertService.RecordAlertHistory(ctx, alert.ID, value, "snoozed", nil, nil)
+			_ = s.alertService.UpdateAlertAfterCheck(ctx, alert.ID, false, nextCheckAt)
+			return
+		}
+
 		// Get channels and send notifications
 		channels, err := s.alertService.GetAlertChannels(ctx, alert.ID)
 		if err != nil {
@@ -112,6 +262,16 @@ func (s *Scheduler) processAlert(ctx context.Context, alert *models.QueryAlert)
 			return
 		}
 
+		metrics.AlertTriggersTotal.Inc()
+
+		// In digest mode, non-critical triggers wait for the user's daily digest.
+		// If the trigger cannot be queued it falls through to an immediate notification.
+		if s.queueForDigest(ctx, alert, value, channels) {
+			_ = s.alertService.RecordAlertHistory(ctx, alert.ID, value, "queued", nil, nil)
+			_ = s.alertService.UpdateAlertAfterCheck(ctx, alert.ID, true, nextCheckAt)
+			return
+		}
+
 		// Send notification to all channels
 		notificationDetails := make(map[string]interface{})
 		var notificationErr error
@@ -147,10 +307,196 @@ func (s *Scheduler) processAlert(ctx context.Context, alert *models.QueryAlert)
 	_ = s.alertService.UpdateAlertAfterCheck(ctx, alert.ID, triggered, nextCheckAt)
 }
 
+// queueForDigest queues a non-critical trigger for the owner's digest and reports whether it did
+func (s *Scheduler) queueForDigest(ctx context.Context, alert *models.QueryAlert, value string, channels []models.NotificationChannel) bool {
+	if alert.Severity == models.SeverityCritical || len(channels) == 0 {
+		return false
+	}
+	enabled, err := s.alertService.DigestEnabled(ctx, alert.UserID)
+	if err != nil {
+		log.Printf("Failed to get digest settings for alert %s: %v", alert.ID, err)
+		return false
+	}
+	if !enabled {
+		return false
+	}
+	if err := s.alertService.QueueDigestEntries(ctx, alert, value, channels); err != nil {
+		log.Printf("Failed to queue alert %s for digest: %v", alert.ID, err)
+		return false
+	}
+	return true
+}
+
+func (s *Scheduler) processAlertDigests() {
+	ctx, cancel := context.WithTimeout(context.Background(), dueClaimLease)
+	defer cancel()
+
+	s.runExclusive(ctx, alertDigestBatchLockKey, s.processDueDigests)
+}
+
+func (s *Scheduler) processDueDigests(ctx context.Context) {
+	digests, err := s.alertService.GetDueDigests(ctx)
+	if err != nil {
+		log.Printf("Failed to get due alert digests: %v", err)
+		return
+	}
+
+	for i := range digests {
+		s.processDigest(ctx, &digests[i])
+	}
+}
+
+// processDigest sends one message per channel listing the user's queued triggers.
+// Entries for a channel that fails to send stay queued for the next digest.
+func (s *Scheduler) processDigest(ctx context.Context, digest *models.AlertDigestSettings) {
+	entries, err := s.alertService.GetDigestEntries(ctx, digest.UserID)
+	if err != nil {
+		log.Printf("Failed to get alert digest entries for user %s: %v", digest.UserID, err)
+		return
+	}
+
+	loc, err := time.LoadLocation(digest.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	byChannel := make(map[uuid.UUID][]models.AlertDigestEntry)
+	var channelOrder []uuid.UUID
+	for _, e := range entries {
+		if _, ok := byChannel[e.ChannelID]; !ok {
+			channelOrder = append(channelOrder, e.ChannelID)
+		}
+		byChannel[e.ChannelID] = append(byChannel[e.ChannelID], e)
+	}
+
+	for _, channelID := range channelOrder {
+		channelEntries := byChannel[channelID]
+		channel, err := s.notificationService.GetChannelByID(ctx, channelID)
+		if err != nil {
+			log.Printf("Failed to get channel %s for alert digest: %v", channelID, err)
+			continue
+		}
+		if err := s.notificationService.Send(ctx, channel, buildDigestMessage(channelEntries, loc)); err != nil {
+			log.Printf("Failed to send alert digest to channel %s: %v", channelID, err)
+			continue
+		}
+
+		ids := make([]uuid.UUID, len(channelEntries))
+		for i, e := range channelEntries {
+			ids[i] = e.ID
+		}
+		if err := s.alertService.DeleteDigestEntries(ctx, ids); err != nil {
+			log.Printf("Failed to clear sent alert digest entries for user %s: %v", digest.UserID, err)
+		}
+	}
+
+	if err := s.alertService.UpdateDigestAfterSend(ctx, digest); err != nil {
+		log.Printf("Failed to reschedule alert digest for user %s: %v", digest.UserID, err)
+	}
+}
+
+func (s *Scheduler) purgeDashboardTrash() {
+	ctx, cancel := context.WithTimeout(context.Background(), dueClaimLease)
+	defer cancel()
+
+	s.runExclusive(ctx, trashPurgeLockKey, func(ctx context.Context) {
+		if s.trashWarning > 0 {
+			s.warnBeforeTrashPurge(ctx, time.Now())
+		}
+		n, err := s.dashboardService.PurgeDeletedDashboards(ctx, s.trashRetention, s.trashWarning)
+		if err != nil {
+			log.Printf("Failed to purge dashboard trash: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("Purged %d dashboards from the trash", n)
+		}
+	})
+}
+
+// purgeWarningClaimer records which trashed dashboards' owners were warned (DashboardService)
+type purgeWarningClaimer interface {
+	ClaimPurgeWarnings(ctx context.Context, retention, warning time.Duration) ([]models.Dashboard, error)
+	ReleasePurgeWarning(ctx context.Context, dashboardID uuid.UUID) error
+}
+
+// inAppSender delivers a notification to a user's in-app inbox (NotificationService)
+type inAppSender interface {
+	NotifyInApp(ctx context.Context, userID uuid.UUID, msg models.NotificationMessage) error
+}
+
+// warnBeforeTrashPurge notifies the owners of trashed dashboards that are about to be purged
+func (s *Scheduler) warnBeforeTrashPurge(ctx context.Context, now time.Time) {
+	warnOwnersBeforePurge(ctx, s.dashboardService, s.notificationService, s.trashRetention, s.trashWarning, now)
+}
+
+// warnOwnersBeforePurge claims the dashboards due for a purge warning and notifies their owners.
+// A claim whose notification fails is released, so the dashboard is not purged before its owner
+// has actually been warned.
+func warnOwnersBeforePurge(ctx context.Context, claimer purgeWarningClaimer, notifier inAppSender, retention, warning time.Duration, now time.Time) {
+	dashboards, err := claimer.ClaimPurgeWarnings(ctx, retention, warning)
+	if err != nil {
+		log.Printf("Failed to find dashboards due for a purge warning: %v", err)
+		return
+	}
+	for _, d := range dashboards {
+		msg := trashPurgeWarningMessage(d, retention, warning, now)
+		if err := notifier.NotifyInApp(ctx, d.UserID, msg); err != nil {
+			log.Printf("Failed to warn owner of dashboard %s before purge: %v", d.ID, err)
+			if err := claimer.ReleasePurgeWarning(ctx, d.ID); err != nil {
+				log.Printf("Failed to release purge warning of dashboard %s: %v", d.ID, err)
+			}
+		}
+	}
+}
+
+// trashPurgeWarningMessage tells the owner of a trashed dashboard when it will be purged: after
+// the retention, but no sooner than the warning period from now
+func trashPurgeWarningMessage(d models.Dashboard, retention, warning time.Duration, now time.Time) models.NotificationMessage {
+	purgeAt := now.Add(warning)
+	if d.DeletedAt != nil && d.DeletedAt.Add(retention).After(purgeAt) {
+		purgeAt = d.DeletedAt.Add(retention)
+	}
+	days := int(math.Ceil(purgeAt.Sub(now).Hours() / 24))
+	unit := "days"
+	if days == 1 {
+		unit = "day"
+	}
+	return models.NotificationMessage{
+		Title: fmt.Sprintf("Dashboard %q will be permanently deleted in %d %s", d.Name, days, unit),
+		Body: fmt.Sprintf("Your dashboard %q is in the trash and will be permanently deleted on %s. Restore it from the trash to keep it.",
+			d.Name, purgeAt.UTC().Format("2006-01-02 15:04 MST")),
+	}
+}
+
+func (s *Scheduler) trimQueryHistory() {
+	ctx, cancel := context.WithTimeout(context.Background(), dueClaimLease)
+	defer cancel()
+
+	s.runExclusive(ctx, historyTrimLockKey, func(ctx context.Context) {
+		n, err := s.queryService.TrimQueryHistory(ctx, s.historyCap)
+		if err != nil {
+			log.Printf("Failed to trim query history: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("Trimmed %d query history entries beyond %d per user", n, s.historyCap)
+		}
+	})
+}
+
 func (s *Scheduler) processSubscriptions() {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), dueClaimLease)
 	defer cancel()
 
+	s.runExclusive(ctx, subscriptionBatchLockKey, s.processDueSubscriptions)
+}
+
+func (s *Scheduler) processDueSubscriptions(ctx context.Context) {
+	if s.inMaintenance(ctx, "subscriptions") {
+		return
+	}
+
 	subscriptions, err := s.subscriptionService.GetDueSubscriptions(ctx)
 	if err != nil {
 		log.Printf("Failed to get due subscriptions: %v", err)
@@ -163,13 +509,20 @@ func (s *Scheduler) processSubscriptions() {
 }
 
 func (s *Scheduler) processSubscription(ctx context.Context, sub *models.DashboardSubscription) {
+	// ExecuteSubscription records the run and each channel's result in the subscription history
 	err := s.subscriptionService.ExecuteSubscription(ctx, sub)
-	if err != nil {
+	metrics.ObserveSubscriptionRun(err)
+	var deliveryErr *ChannelDeliveryError
+	if errors.As(err, &deliveryErr) {
+		for channelID, chErr := range deliveryErr.Failures {
+			log.Printf("Failed to send subscription %s to channel %s (%s): %v", sub.ID, deliveryErr.Names[channelID], channelID, chErr)
+		}
+	} else if err != nil {
 		log.Printf("Failed to execute subscription %s: %v", sub.ID, err)
 	}
 
 	// Update next run time
-	_ = s.subscriptionService.UpdateSubscriptionAfterRun(ctx, sub.ID, sub.ScheduleCron, sub.Timezone)
+	_ = s.subscriptionService.UpdateSubscriptionAfterRun(ctx, sub)
 }
 
 func buildAlertMessage(alert *models.QueryAlert, value string) models.NotificationMessage {
@@ -184,5 +537,16 @@ func buildAlertBody(alert *models.QueryAlert, value string) string {
 	if alert.Description != nil {
 		description = *alert.Description + "\n\n"
 	}
-	return description + "Condition: " + alert.ConditionColumn + " " + string(alert.ConditionOperator) + " " + alert.ConditionValue + "\nActual Value: " + value
+
+	conditions := effectiveAlertConditions(alert)
+	parts := make([]string, len(conditions))
+	for i, cond := range conditions {
+		parts[i] = cond.Column + " " + string(cond.Operator) + " " + cond.Value
+	}
+	joiner := " AND "
+	if alert.LogicOperator == models.LogicOr {
+		joiner = " OR "
+	}
+
+	return description + "Condition: " + strings.Join(parts, joiner) + "\nActual Value: " + value
 }
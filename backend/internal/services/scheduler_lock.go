@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// BatchLocker serializes scheduler batches across backend replicas.
+// TryLock never blocks: ok is false when another replica holds the lock.
+// release must be called exactly once when ok is true.
+type BatchLocker interface {
+	TryLock(ctx context.Context, key string) (release func(), ok bool, err error)
+}
+
+// PostgresAdvisoryLocker implements BatchLocker with session-level Postgres advisory locks.
+// Each lock pins a pooled connection until released, since advisory locks belong to the session.
+type PostgresAdvisoryLocker struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresAdvisoryLocker creates a new advisory-lock based batch locker
+func NewPostgresAdvisoryLocker(pool *pgxpool.Pool) *PostgresAdvisoryLocker {
+	return &PostgresAdvisoryLocker{pool: pool}
+}
+
+// TryLock attempts pg_try_advisory_lock on a key derived from the lock name
+func (l *PostgresAdvisoryLocker) TryLock(ctx context.Context, key string) (func(), bool, error) {
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	lockID := advisoryLockID(key)
+	var acquired bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", lockID).Scan(&acquired); err != nil {
+		conn.Release()
+		return nil, false, err
+	}
+	if !acquired {
+		conn.Release()
+		return nil, false, nil
+	}
+
+	release := func() {
+		// Use a fresh context: the batch context may already be cancelled
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", lockID); err != nil {
+			// The session is unusable; closing it drops the lock on the server side
+			log.Printf("Failed to release advisory lock %q: %v", key, err)
+			_ = conn.Conn().Close(ctx)
+		}
+		conn.Release()
+	}
+	return release, true, nil
+}
+
+// advisoryLockID maps a lock name to the bigint key space of pg advisory locks
+func advisoryLockID(key string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return int64(h.Sum64())
+}
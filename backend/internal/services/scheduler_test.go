@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+)
+
+type fakeBatchLocker struct {
+	held     map[string]bool
+	err      error
+	releases int
+}
+
+func newFakeBatchLocker() *fakeBatchLocker {
+	return &fakeBatchLocker{held: make(map[string]bool)}
+}
+
+func (l *fakeBatchLocker) TryLock(ctx context.Context, key string) (func(), bool, error) {
+	if l.err != nil {
+		return nil, false, l.err
+	}
+	if l.held[key] {
+		return nil, false, nil
+	}
+	l.held[key] = true
+	return func() {
+		l.held[key] = false
+		l.releases++
+	}, true, nil
+}
+
+func TestRunExclusive_RunsAndReleases(t *testing.T) {
+	locker := newFakeBatchLocker()
+	s := &Scheduler{locker: locker}
+
+	ran := false
+	s.runExclusive(context.Background(), alertBatchLockKey, func(ctx context.Context) {
+		ran = true
+		if !locker.held[alertBatchLockKey] {
+			t.Fatal("batch ran without holding the lock")
+		}
+	})
+
+	if !ran {
+		t.Fatal("batch did not run")
+	}
+	if locker.held[alertBatchLockKey] || locker.releases != 1 {
+		t.Fatalf("lock not released: held=%v releases=%d", locker.held[alertBatchLockKey], locker.releases)
+	}
+}
+
+func TestRunExclusive_SkipsWhenHeldElsewhere(t *testing.T) {
+	locker := newFakeBatchLocker()
+	locker.held[alertBatchLockKey] = true // another replica
+	s := &Scheduler{locker: locker}
+
+	ran := false
+	s.runExclusive(context.Background(), alertBatchLockKey, func(ctx context.Context) { ran = true })
+
+	if ran {
+		t.Fatal("batch ran while another replica held the lock")
+	}
+
+	// Once the other replica releases, the next tick proceeds
+	locker.held[alertBatchLockKey] = false
+	s.runExclusive(context.Background(), alertBatchLockKey, func(ctx context.Context) { ran = true })
+	if !ran {
+		t.Fatal("batch did not resume after the lock was released")
+	}
+}
+
+func TestRunExclusive_LockErrorSkipsBatch(t *testing.T) {
+	locker := newFakeBatchLocker()
+	locker.err = errors.New("connection refused")
+	s := &Scheduler{locker: locker}
+
+	ran := false
+	s.runExclusive(context.Background(), subscriptionBatchLockKey, func(ctx context.Context) { ran = true })
+
+	if ran {
+		t.Fatal("batch ran without a lock")
+	}
+}
+
+func TestRunExclusive_PanicReleasesLock(t *testing.T) {
+	locker := newFakeBatchLocker()
+	s := &Scheduler{locker: locker}
+
+	s.runExclusive(context.Background(), alertBatchLockKey, func(ctx context.Context) {
+		panic("boom")
+	})
+
+	if locker.held[alertBatchLockKey] {
+		t.Fatal("lock still held after batch panicked")
+	}
+}
+
+func TestRunExclusive_NoLocker(t *testing.T) {
+	s := &Scheduler{}
+
+	ran := false
+	s.runExclusive(context.Background(), alertBatchLockKey, func(ctx context.Context) { ran = true })
+
+	if !ran {
+		t.Fatal("batch did not run without a locker")
+	}
+}
+
+func TestAdvisoryLockIDStable(t *testing.T) {
+	if advisoryLockID(alertBatchLockKey) != advisoryLockID(alertBatchLockKey) {
+		t.Fatal("advisoryLockID() is not deterministic")
+	}
+	if advisoryLockID(alertBatchLockKey) == advisoryLockID(subscriptionBatchLockKey) {
+		t.Fatal("advisoryLockID() collides for alert and subscription batches")
+	}
+}
+
+type fakeMaintenanceChecker bool
+
+func (f fakeMaintenanceChecker) InMaintenance(ctx context.Context) (bool, string) {
+	return bool(f), DefaultMaintenanceMessage
+}
+
+func TestProcessDue_SkipsDuringMaintenance(t *testing.T) {
+	// With no alert or subscription service, reaching GetDue* would panic
+	s := &Scheduler{}
+	s.SetMaintenance(fakeMaintenanceChecker(true))
+
+	s.processDueAlerts(context.Background())
+	s.processDueSubscriptions(context.Background())
+}
+
+func TestTrashPurgeWarningMessage(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	retention := 30 * 24 * time.Hour
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name      string
+		deletedAt time.Time
+		warning   time.Duration
+		wantTitle string
+		wantDate  string
+	}{
+		{"purged at the end of the retention", now.Add(-27 * day), 3 * day, "in 3 days", "2024-03-13"},
+		{"warned late, purged after the full warning", now.Add(-40 * day), 3 * day, "in 3 days", "2024-03-13"},
+		{"one day left", now.Add(-29*day - time.Hour), day, "in 1 day", "2024-03-11"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := models.Dashboard{Name: "Sales", DeletedAt: &tt.deletedAt}
+			msg := trashPurgeWarningMessage(d, retention, tt.warning, now)
+			if !strings.Contains(msg.Title, `"Sales"`) || !strings.HasSuffix(msg.Title, tt.wantTitle) {
+				t.Fatalf("title = %q, want it to name the dashboard and end with %q", msg.Title, tt.wantTitle)
+			}
+			if !strings.Contains(msg.Body, tt.wantDate) {
+				t.Fatalf("body = %q, want the purge date %s", msg.Body, tt.wantDate)
+			}
+		})
+	}
+}
+
+type fakePurgeWarningClaimer struct {
+	claimed  []models.Dashboard
+	released []uuid.UUID
+}
+
+func (f *fakePurgeWarningClaimer) ClaimPurgeWarnings(ctx context.Context, retention, warning time.Duration) ([]models.Dashboard, error) {
+	return f.claimed, nil
+}
+
+func (f *fakePurgeWarningClaimer) ReleasePurgeWarning(ctx context.Context, dashboardID uuid.UUID) error {
+	f.released = append(f.released, dashboardID)
+	return nil
+}
+
+// fakeInAppSender fails delivery to the users in failFor
+type fakeInAppSender struct {
+	failFor map[uuid.UUID]bool
+	sent    []uuid.UUID
+}
+
+func (f *fakeInAppSender) NotifyInApp(ctx context.Context, userID uuid.UUID, msg models.NotificationMessage) error {
+	if f.failFor[userID] {
+		return errors.New("inbox unavailable")
+	}
+	f.sent = append(f.sent, userID)
+	return nil
+}
+
+func TestWarnOwnersBeforePurge_ReleasesUndeliveredWarnings(t *testing.T) {
+	now := time.Now()
+	deletedAt := now.Add(-28 * 24 * time.Hour)
+	delivered := models.Dashboard{ID: uuid.New(), UserID: uuid.New(), Name: "delivered", DeletedAt: &deletedAt}
+	failed := models.Dashboard{ID: uuid.New(), UserID: uuid.New(), Name: "failed", DeletedAt: &deletedAt}
+
+	claimer := &fakePurgeWarningClaimer{claimed: []models.Dashboard{delivered, failed}}
+	sender := &fakeInAppSender{failFor: map[uuid.UUID]bool{failed.UserID: true}}
+	warnOwnersBeforePurge(context.Background(), claimer, sender, 30*24*time.Hour, 3*24*time.Hour, now)
+
+	if len(sender.sent) != 1 || sender.sent[0] != delivered.UserID {
+		t.Fatalf("warnings sent to %v, want only %s", sender.sent, delivered.UserID)
+	}
+	if len(claimer.released) != 1 || claimer.released[0] != failed.ID {
+		t.Fatalf("released %v, want only the undelivered dashboard %s", claimer.released, failed.ID)
+	}
+}
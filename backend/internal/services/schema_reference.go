@@ -0,0 +1,130 @@
+package services
+
+import "strings"
+
+// SchemaReference is a schema a query reads from. An empty Catalog stands for the session
+// catalog; an empty Schema only requires access to some schema of the catalog, as for
+// SHOW SCHEMAS FROM catalog.
+type SchemaReference struct {
+	Catalog string
+	Schema  string
+}
+
+// normalizeIdentifier resolves an identifier token the way Trino does: quoted or not, names are
+// compared in lower case, and quoted ones are unquoted as written ("" escapes a quote)
+func normalizeIdentifier(identifier string) string {
+	if len(identifier) >= 2 && identifier[0] == '"' && identifier[len(identifier)-1] == '"' {
+		identifier = strings.ReplaceAll(identifier[1:len(identifier)-1], `""`, `"`)
+	}
+	return strings.ToLower(identifier)
+}
+
+// SchemaReferences returns the schemas query reads from, with normalized names, in this order:
+// the catalog and schema of every dotted name of three or more parts anywhere in the query (a
+// column reference may also name a catalog), those of the qualified names TableReferences
+// returns, the catalog of SHOW SCHEMAS FROM, and the [catalog.]schema of SHOW TABLES FROM and
+// USE. Names in comments and string literals are ignored. Queries TableReferences cannot
+// classify fail with ErrUnclassifiedTableReference.
+func SchemaReferences(query string) ([]SchemaReference, error) {
+	tables, err := TableReferences(query)
+	if err != nil {
+		return nil, err
+	}
+	// TableReferences tokenized the query already, so this cannot fail
+	tokens, _ := tokenizeSQL(query)
+	var sig []sqlToken
+	for _, t := range tokens {
+		if t.kind != sqlSpace && t.kind != sqlComment {
+			sig = append(sig, t)
+		}
+	}
+	tok := func(k int) sqlToken {
+		if k < 0 || k >= len(sig) {
+			return sqlToken{}
+		}
+		return sig[k]
+	}
+	isPunct := func(k int, p string) bool { return tok(k).kind == sqlPunct && tok(k).text == p }
+	isKeyword := func(k int, kw string) bool { return tok(k).kind == sqlWord && strings.EqualFold(tok(k).text, kw) }
+	isIdent := func(k int) bool { return tok(k).kind == sqlWord || tok(k).kind == sqlQuoted }
+
+	// chainAt reads the dotted name starting at significant token k as normalized parts
+	chainAt := func(k int) []string {
+		if !isIdent(k) {
+			return nil
+		}
+		parts := []string{normalizeIdentifier(tok(k).text)}
+		for isPunct(k+1, ".") && isIdent(k+2) {
+			k += 2
+			parts = append(parts, normalizeIdentifier(tok(k).text))
+		}
+		return parts
+	}
+	// schemaAt reads the [catalog.]schema name starting at significant token k
+	schemaAt := func(k int) (SchemaReference, bool) {
+		parts := chainAt(k)
+		switch len(parts) {
+		case 1:
+			return SchemaReference{Schema: parts[0]}, true
+		case 0:
+			return SchemaReference{}, false
+		}
+		return SchemaReference{Catalog: parts[0], Schema: parts[1]}, true
+	}
+
+	var qualified, showSchemas, showTables, use []SchemaReference
+	for k := range sig {
+		if isIdent(k) && !isPunct(k-1, ".") {
+			if parts := chainAt(k); len(parts) >= 3 {
+				qualified = append(qualified, SchemaReference{Catalog: parts[0], Schema: parts[1]})
+			}
+		}
+		switch {
+		case isKeyword(k, "show") && isKeyword(k+1, "schemas") && (isKeyword(k+2, "from") || isKeyword(k+2, "in")):
+			if isIdent(k + 3) {
+				showSchemas = append(showSchemas, SchemaReference{Catalog: normalizeIdentifier(tok(k + 3).text)})
+			}
+		case isKeyword(k, "show") && isKeyword(k+1, "tables") && (isKeyword(k+2, "from") || isKeyword(k+2, "in")):
+			if ref, ok := schemaAt(k + 3); ok {
+				showTables = append(showTables, ref)
+			}
+		case isKeyword(k, "use"):
+			if ref, ok := schemaAt(k + 1); ok {
+				use = append(use, ref)
+			}
+		}
+	}
+
+	refs := qualified
+	for _, parts := range tables {
+		switch len(parts) {
+		case 2:
+			refs = append(refs, SchemaReference{Schema: normalizeIdentifier(parts[0])})
+		case 3:
+			refs = append(refs, SchemaReference{Catalog: normalizeIdentifier(parts[0]), Schema: normalizeIdentifier(parts[1])})
+		}
+	}
+	refs = append(refs, showSchemas...)
+	refs = append(refs, showTables...)
+	return append(refs, use...), nil
+}
+
+// ShowsCatalogs reports whether query contains SHOW CATALOGS outside comments and string
+// literals. Queries that cannot be tokenized report false; SchemaReferences rejects them.
+func ShowsCatalogs(query string) bool {
+	tokens, ok := tokenizeSQL(query)
+	if !ok {
+		return false
+	}
+	var prev sqlToken
+	for _, t := range tokens {
+		if t.kind == sqlSpace || t.kind == sqlComment {
+			continue
+		}
+		if prev.kind == sqlWord && strings.EqualFold(prev.text, "show") && t.kind == sqlWord && strings.EqualFold(t.text, "catalogs") {
+			return true
+		}
+		prev = t
+	}
+	return false
+}
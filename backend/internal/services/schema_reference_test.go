@@ -0,0 +1,58 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSchemaReferences(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []SchemaReference
+	}{
+		{"qualified names", "SELECT * FROM hive.sales.orders o JOIN hr.people p ON o.id = p.id",
+			[]SchemaReference{{"hive", "sales"}, {"hive", "sales"}, {"", "hr"}}},
+		{"three-part column reference", "SELECT postgres.public.users.id FROM t",
+			[]SchemaReference{{"postgres", "public"}}},
+		{"quoted names are lower-cased like row filters", `SELECT * FROM "Hive"."SALES".t`,
+			[]SchemaReference{{"hive", "sales"}, {"hive", "sales"}}},
+		{"comments between parts", "SELECT * FROM hive /* x */ . sales -- y\n.t",
+			[]SchemaReference{{"hive", "sales"}, {"hive", "sales"}}},
+		{"string literals", "SELECT 'postgres.public.users' FROM t", nil},
+		{"show schemas", "SHOW SCHEMAS IN hive", []SchemaReference{{"hive", ""}}},
+		{"show tables and use", "SHOW TABLES FROM hr; USE hive.sales",
+			[]SchemaReference{{"", "hr"}, {"hive", "sales"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SchemaReferences(tt.query)
+			if err != nil {
+				t.Fatalf("SchemaReferences() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("SchemaReferences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := SchemaReferences("SELECT * FROM hive.sales.orders /* unterminated"); !errors.Is(err, ErrUnclassifiedTableReference) {
+		t.Fatalf("SchemaReferences() of an unterminated comment error = %v, want ErrUnclassifiedTableReference", err)
+	}
+}
+
+func TestShowsCatalogs(t *testing.T) {
+	for query, want := range map[string]bool{
+		"SHOW CATALOGS":                   true,
+		"show /* x */ catalogs like 'h%'": true,
+		"SELECT 'SHOW CATALOGS'":          false,
+		"-- SHOW CATALOGS\nSELECT 1":      false,
+		"SHOW SCHEMAS FROM hive":          false,
+	} {
+		if got := ShowsCatalogs(query); got != want {
+			t.Errorf("ShowsCatalogs(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/repository"
+)
+
+const (
+	// DefaultSearchLimit is the page size used when no limit is given
+	DefaultSearchLimit = 20
+	// MaxSearchLimit bounds the page size of a single search request
+	MaxSearchLimit = 100
+	// maxMetadataSearchResults bounds how many table/column hits are fetched from Trino per search
+	maxMetadataSearchResults = 50
+)
+
+// Relevance scores, highest first. Name matches outrank description matches, which outrank SQL body matches.
+const (
+	scoreExactName    = 100
+	scorePrefixName   = 75
+	scoreContainsName = 50
+	scoreDescription  = 25
+	scoreQueryText    = 10
+)
+
+// SearchService searches dashboards, saved queries and Trino metadata in one pass
+type SearchService struct {
+	dashboardService *DashboardService
+	queryService     *QueryService
+	trinoExecutor    repository.TrinoExecutor
+	roleService      *RoleService
+}
+
+// NewSearchService creates a new unified search service
+func NewSearchService(dashboardService *DashboardService, queryService *QueryService, trinoExecutor repository.TrinoExecutor, roleService *RoleService) *SearchService {
+	return &SearchService{
+		dashboardService: dashboardService,
+		queryService:     queryService,
+		trinoExecutor:    trinoExecutor,
+		roleService:      roleService,
+	}
+}
+
+// Search returns a ranked page of results visible to the user.
+// Dashboards are limited to those the user can view, saved queries to the user's own,
+// and metadata to catalogs the user is allowed to access.
+func (s *SearchService) Search(ctx context.Context, userID uuid.UUID, q string, limit, offset int) (*models.SearchResponse, error) {
+	q = strings.TrimSpace(q)
+	var results []models.SearchResult
+
+	dashboards, err := s.dashboardService.GetDashboards(ctx, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	for i := range dashboards {
+		d := &dashboards[i]
+		if d.IsDraft {
+			continue
+		}
+		score := scoreMatch(q, d.Name, d.Description, "")
+		if score == 0 {
+			continue
+		}
+		id := d.ID
+		results = append(results, models.SearchResult{
+			Type:        models.SearchResultDashboard,
+			ID:          &id,
+			Name:        d.Name,
+			Description: d.Description,
+			Score:       score,
+		})
+	}
+
+	queries, err := s.queryService.GetSavedQueries(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range queries {
+		sq := &queries[i]
+		score := scoreMatch(q, sq.Name, sq.Description, sq.QueryText)
+		if score == 0 {
+			continue
+		}
+		id := sq.ID
+		results = append(results, models.SearchResult{
+			Type:        models.SearchResultSavedQuery,
+			ID:          &id,
+			Name:        sq.Name,
+			Description: sq.Description,
+			Score:       score,
+		})
+	}
+
+	metadata, err := s.searchMetadata(ctx, userID, q)
+	if err != nil {
+		// Trino being unavailable should not hide dashboard and query hits
+		log.Printf("unified search: metadata search failed: %v", err)
+	}
+	results = append(results, metadata...)
+
+	return paginateSearchResults(rankSearchResults(results), limit, offset), nil
+}
+
+func (s *SearchService) searchMetadata(ctx context.Context, userID uuid.UUID, q string) ([]models.SearchResult, error) {
+	if s.trinoExecutor == nil {
+		return nil, nil
+	}
+
+	var catalogs []string
+	var allowedSchemas []models.SchemaPermission
+	if s.roleService != nil {
+		allowed, err := s.roleService.GetUserAllowedCatalogs(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		catalogs = allowed
+		allowedSchemas, err = s.roleService.GetUserAllowedSchemas(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if catalogs == nil {
+		// Admin (or no role service) - search every catalog
+		all, err := s.trinoExecutor.GetCatalogs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		catalogs = all
+	}
+	if len(catalogs) == 0 {
+		return nil, nil
+	}
+
+	hits, err := s.trinoExecutor.SearchMetadata(ctx, q, "all", catalogs, maxMetadataSearchResults)
+	if err != nil {
+		return nil, err
+	}
+	hits = FilterAllowedMetadata(allowedSchemas, hits)
+
+	results := make([]models.SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		result := models.SearchResult{
+			Catalog: hit.Catalog,
+			Schema:  hit.Schema,
+			Table:   hit.Table,
+		}
+		if hit.Type == "column" {
+			result.Type = models.SearchResultColumn
+			result.Column = hit.Column
+			result.Name = hit.Column
+		} else {
+			result.Type = models.SearchResultTable
+			result.Name = hit.Table
+		}
+		result.Score = scoreMatch(q, result.Name, nil, "")
+		if result.Score == 0 {
+			result.Score = scoreQueryText
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// scoreMatch returns the relevance of an object for the search term, or 0 if it does not match.
+// Matching is case-insensitive.
+func scoreMatch(q, name string, description *string, queryText string) int {
+	term := strings.ToLower(q)
+	if term == "" {
+		return 0
+	}
+
+	lowerName := strings.ToLower(name)
+	switch {
+	case lowerName == term:
+		return scoreExactName
+	case strings.HasPrefix(lowerName, term):
+		return scorePrefixName
+	case strings.Contains(lowerName, term):
+		return scoreContainsName
+	case description != nil && strings.Contains(strings.ToLower(*description), term):
+		return scoreDescription
+	case queryText != "" && strings.Contains(strings.ToLower(queryText), term):
+		return scoreQueryText
+	}
+	return 0
+}
+
+// searchTypeOrder breaks score ties so that user-curated content comes before raw metadata
+var searchTypeOrder = map[models.SearchResultType]int{
+	models.SearchResultDashboard:  0,
+	models.SearchResultSavedQuery: 1,
+	models.SearchResultTable:      2,
+	models.SearchResultColumn:     3,
+}
+
+func rankSearchResults(results []models.SearchResult) []models.SearchResult {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Type != b.Type {
+			return searchTypeOrder[a.Type] < searchTypeOrder[b.Type]
+		}
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	})
+	return results
+}
+
+func paginateSearchResults(results []models.SearchResult, limit, offset int) *models.SearchResponse {
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	resp := &models.SearchResponse{
+		Results: []models.SearchResult{},
+		Total:   len(results),
+		Limit:   limit,
+		Offset:  offset,
+	}
+	if offset >= len(results) {
+		return resp
+	}
+	end := offset + limit
+	if end > len(results) {
+		end = len(results)
+	}
+	resp.Results = results[offset:end]
+	return resp
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/mitsume/backend/internal/models"
+)
+
+func TestScoreMatch(t *testing.T) {
+	desc := "Monthly revenue by region"
+
+	tests := []struct {
+		name        string
+		q           string
+		itemName    string
+		description *string
+		queryText   string
+		want        int
+	}{
+		{"exact name", "Revenue", "revenue", nil, "", scoreExactName},
+		{"name prefix", "rev", "Revenue Overview", nil, "", scorePrefixName},
+		{"name contains", "revenue", "Daily Revenue", nil, "", scoreContainsName},
+		{"description", "region", "Sales", &desc, "", scoreDescription},
+		{"query text", "orders", "Sales", &desc, "SELECT * FROM orders", scoreQueryText},
+		{"no match", "churn", "Sales", &desc, "SELECT 1", 0},
+		{"empty term", "", "Sales", nil, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scoreMatch(tt.q, tt.itemName, tt.description, tt.queryText); got != tt.want {
+				t.Fatalf("scoreMatch(%q, %q) = %d, want %d", tt.q, tt.itemName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankSearchResults(t *testing.T) {
+	results := []models.SearchResult{
+		{Type: models.SearchResultColumn, Name: "revenue", Score: scoreExactName},
+		{Type: models.SearchResultSavedQuery, Name: "b query", Score: scoreContainsName},
+		{Type: models.SearchResultDashboard, Name: "revenue", Score: scoreExactName},
+		{Type: models.SearchResultSavedQuery, Name: "A query", Score: scoreContainsName},
+	}
+
+	ranked := rankSearchResults(results)
+
+	want := []struct {
+		typ  models.SearchResultType
+		name string
+	}{
+		{models.SearchResultDashboard, "revenue"},
+		{models.SearchResultColumn, "revenue"},
+		{models.SearchResultSavedQuery, "A query"},
+		{models.SearchResultSavedQuery, "b query"},
+	}
+	for i, w := range want {
+		if ranked[i].Type != w.typ || ranked[i].Name != w.name {
+			t.Fatalf("rank[%d] = %s %q, want %s %q", i, ranked[i].Type, ranked[i].Name, w.typ, w.name)
+		}
+	}
+}
+
+func TestPaginateSearchResults(t *testing.T) {
+	results := make([]models.SearchResult, 250)
+
+	resp := paginateSearchResults(results, 0, 0)
+	if len(resp.Results) != DefaultSearchLimit || resp.Limit != DefaultSearchLimit {
+		t.Fatalf("default page size = %d, want %d", len(resp.Results), DefaultSearchLimit)
+	}
+	if resp.Total != 250 {
+		t.Fatalf("Total = %d, want 250", resp.Total)
+	}
+
+	resp = paginateSearchResults(results, 1000, 0)
+	if len(resp.Results) != MaxSearchLimit {
+		t.Fatalf("capped page size = %d, want %d", len(resp.Results), MaxSearchLimit)
+	}
+
+	resp = paginateSearchResults(results, 100, 200)
+	if len(resp.Results) != 50 {
+		t.Fatalf("last page size = %d, want 50", len(resp.Results))
+	}
+
+	resp = paginateSearchResults(results, 10, 500)
+	if resp.Results == nil || len(resp.Results) != 0 {
+		t.Fatalf("out of range page = %v, want empty slice", resp.Results)
+	}
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// SessionTracker records when each session was last active, for the idle timeout
+type SessionTracker interface {
+	// Touch records activity on the session and reports whether it is still active. A session
+	// idle longer than idleTimeout, or ended with End, stays expired and reports false.
+	// Sessions without a record (e.g. tracked by another instance before a restart) count as
+	// active. Records are kept for retention.
+	Touch(ctx context.Context, sessionID uuid.UUID, idleTimeout, retention time.Duration) (active bool, err error)
+
+	// End expires the session so later Touch calls report false
+	End(ctx context.Context, sessionID uuid.UUID, retention time.Duration) error
+}
+
+// NewSessionTracker returns a Redis-backed tracker when the cache is enabled, so activity is
+// shared across instances, and an in-memory tracker otherwise
+func NewSessionTracker(cache *QueryCacheService) SessionTracker {
+	if cache != nil {
+		return &RedisSessionTracker{client: cache.client, prefix: cache.cfg.KeyPrefix + "session:"}
+	}
+	return NewMemorySessionTracker()
+}
+
+// memorySessionSweep is how often expired records are dropped from the in-memory tracker
+const memorySessionSweep = time.Minute
+
+type sessionRecord struct {
+	lastActive time.Time // zero once the session has ended
+	expiresAt  time.Time
+}
+
+// MemorySessionTracker is a per-process SessionTracker
+type MemorySessionTracker struct {
+	mu        sync.Mutex
+	sessions  map[uuid.UUID]*sessionRecord
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemorySessionTracker creates an in-memory session tracker
+func NewMemorySessionTracker() *MemorySessionTracker {
+	return &MemorySessionTracker{
+		sessions:  make(map[uuid.UUID]*sessionRecord),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Touch implements SessionTracker
+func (m *MemorySessionTracker) Touch(ctx context.Context, sessionID uuid.UUID, idleTimeout, retention time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweepLocked(now)
+
+	if r, ok := m.sessions[sessionID]; ok {
+		if r.lastActive.IsZero() || now.Sub(r.lastActive) > idleTimeout {
+			r.lastActive = time.Time{}
+			return false, nil
+		}
+	}
+	m.sessions[sessionID] = &sessionRecord{lastActive: now, expiresAt: now.Add(retention)}
+	return true, nil
+}
+
+// End implements SessionTracker
+func (m *MemorySessionTracker) End(ctx context.Context, sessionID uuid.UUID, retention time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sessions[sessionID] = &sessionRecord{expiresAt: m.now().Add(retention)}
+	return nil
+}
+
+// sweepLocked drops records past their retention. Caller must hold m.mu.
+func (m *MemorySessionTracker) sweepLocked(now time.Time) {
+	if now.Sub(m.lastSweep) < memorySessionSweep {
+		return
+	}
+	for id, r := range m.sessions {
+		if now.After(r.expiresAt) {
+			delete(m.sessions, id)
+		}
+	}
+	m.lastSweep = now
+}
+
+// sessionTouchScript stores the last activity time (unix ms, 0 once ended) of a session.
+// Returns 1 when the session is active, 0 when it is idle or ended (the record is left as is).
+var sessionTouchScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local idle = tonumber(ARGV[2])
+local retention = tonumber(ARGV[3])
+
+local last = redis.call('GET', KEYS[1])
+if last then
+	last = tonumber(last)
+	if last == 0 or now - last > idle then
+		return 0
+	end
+end
+
+redis.call('SET', KEYS[1], now, 'PX', retention)
+return 1
+`)
+
+// RedisSessionTracker is a SessionTracker shared by every instance using the same Redis
+type RedisSessionTracker struct {
+	client *redis.Client
+	prefix string
+}
+
+// Touch implements SessionTracker
+func (r *RedisSessionTracker) Touch(ctx context.Context, sessionID uuid.UUID, idleTimeout, retention time.Duration) (bool, error) {
+	res, err := sessionTouchScript.Run(ctx, r.client, []string{r.prefix + sessionID.String()},
+		time.Now().UnixMilli(), idleTimeout.Milliseconds(), retention.Milliseconds(),
+	).Int()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// End implements SessionTracker
+func (r *RedisSessionTracker) End(ctx context.Context, sessionID uuid.UUID, retention time.Duration) error {
+	return r.client.Set(ctx, r.prefix+sessionID.String(), 0, retention).Err()
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestMemorySessionTracker_IdleTimeout(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := NewMemorySessionTracker()
+	tracker.now = func() time.Time { return now }
+	ctx := context.Background()
+	id := uuid.New()
+
+	if active, _ := tracker.Touch(ctx, id, 10*time.Minute, time.Hour); !active {
+		t.Fatal("Touch() of unknown session = false, want true")
+	}
+
+	now = now.Add(9 * time.Minute)
+	if active, _ := tracker.Touch(ctx, id, 10*time.Minute, time.Hour); !active {
+		t.Fatal("Touch() within idle timeout = false, want true")
+	}
+
+	now = now.Add(11 * time.Minute)
+	if active, _ := tracker.Touch(ctx, id, 10*time.Minute, time.Hour); active {
+		t.Fatal("Touch() after idle timeout = true, want false")
+	}
+
+	// An idle session stays expired
+	if active, _ := tracker.Touch(ctx, id, 10*time.Minute, time.Hour); active {
+		t.Fatal("Touch() of expired session = true, want false")
+	}
+}
+
+func TestMemorySessionTracker_End(t *testing.T) {
+	tracker := NewMemorySessionTracker()
+	ctx := context.Background()
+	id := uuid.New()
+
+	tracker.Touch(ctx, id, time.Hour, time.Hour)
+	if err := tracker.End(ctx, id, time.Hour); err != nil {
+		t.Fatalf("End() error = %v", err)
+	}
+	if active, _ := tracker.Touch(ctx, id, time.Hour, time.Hour); active {
+		t.Fatal("Touch() of ended session = true, want false")
+	}
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/mitsume/backend/internal/database"
+	"github.com/mitsume/backend/internal/models"
+)
+
+const (
+	defaultSlowQueryDays   = 7
+	defaultPerformanceDays = 30
+	maxSlowQueryDays       = 90
+	maxSlowQueryLimit      = 200
+	slowestRunsShown       = 5
+	maxPerformanceRuns     = 5000 // newest executions considered for a saved query's statistics
+)
+
+// ErrSlowQueryReportingDisabled is returned when no slow query threshold is configured
+var ErrSlowQueryReportingDisabled = errors.New("slow query reporting is disabled")
+
+// SetSlowQueryThreshold sets the execution time, in milliseconds, from which a query is reported
+// as slow. 0 disables slow query reporting.
+func (s *QueryService) SetSlowQueryThreshold(ms int) {
+	s.slowThresholdMs = ms
+}
+
+// IsSlowQuery reports whether an execution time reaches the threshold; a threshold of 0 or less
+// never matches
+func IsSlowQuery(executionTimeMs int64, thresholdMs int) bool {
+	return thresholdMs > 0 && executionTimeMs >= int64(thresholdMs)
+}
+
+// clampSlowQueryDays bounds a reporting window, using def when days is not positive
+func clampSlowQueryDays(days, def int) int {
+	if days <= 0 {
+		return def
+	}
+	return min(days, maxSlowQueryDays)
+}
+
+// GetSlowQueries returns successful executions of the last days that reached the slow query
+// threshold, slowest first. thresholdMs overrides the configured threshold when positive.
+// Permission checks (admin only) must be performed by the caller.
+func (s *QueryService) GetSlowQueries(ctx context.Context, thresholdMs, days, limit, offset int) ([]models.SlowQuery, error) {
+	if thresholdMs <= 0 {
+		thresholdMs = s.slowThresholdMs
+	}
+	if thresholdMs <= 0 {
+		return nil, ErrSlowQueryReportingDisabled
+	}
+	days = clampSlowQueryDays(days, defaultSlowQueryDays)
+	if limit <= 0 {
+		limit = 50
+	}
+	limit = min(limit, maxSlowQueryLimit)
+	offset = max(offset, 0)
+
+	pool := database.GetPool()
+
+	rows, err := pool.Query(ctx,
+		`SELECT h.id, h.user_id, h.query_text, h.status, h.execution_time_ms, h.row_count, h.error_message,
+		        h.source, h.source_id, h.executed_at, COALESCE(u.email, '')
+		 FROM query_history h
+		 LEFT JOIN users u ON u.id = h.user_id
+		 WHERE h.status = 'success' AND h.execution_time_ms >= $1
+		   AND h.executed_at >= CURRENT_TIMESTAMP - $2 * INTERVAL '1 day'
+		 ORDER BY h.execution_time_ms DESC, h.executed_at DESC
+		 LIMIT $3 OFFSET $4`,
+		thresholdMs, days, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query slow queries: %w", err)
+	}
+	defer rows.Close()
+
+	slow := []models.SlowQuery{}
+	for rows.Next() {
+		var q models.SlowQuery
+		if err := rows.Scan(&q.ID, &q.UserID, &q.QueryText, &q.Status, &q.ExecutionTimeMs, &q.RowCount, &q.ErrorMessage,
+			&q.Source, &q.SourceID, &q.ExecutedAt, &q.UserEmail); err != nil {
+			return nil, fmt.Errorf("failed to scan slow query: %w", err)
+		}
+		slow = append(slow, q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return slow, nil
+}
+
+// GetSavedQueryPerformance summarizes the executions of the user's saved query over the last days
+// (see models.SavedQueryPerformance)
+func (s *QueryService) GetSavedQueryPerformance(ctx context.Context, queryID, userID uuid.UUID, days int) (*models.SavedQueryPerformance, error) {
+	saved, err := s.GetSavedQuery(ctx, queryID, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	days = clampSlowQueryDays(days, defaultPerformanceDays)
+
+	pool := database.GetPool()
+
+	rows, err := pool.Query(ctx,
+		`SELECT h.id, h.user_id, h.query_text, h.status, h.execution_time_ms, h.row_count, h.error_message,
+		        h.source, h.source_id, h.executed_at
+		 FROM query_history h
+		 WHERE h.executed_at >= CURRENT_TIMESTAMP - $4 * INTERVAL '1 day'
+		   AND ((h.source = 'widget' AND h.source_id IN (SELECT id FROM dashboard_widgets WHERE query_id = $1))
+		     OR (h.source = 'alert' AND h.source_id IN (SELECT id FROM query_alerts WHERE query_id = $1))
+		     OR (h.source IN ('query', 'query_job') AND h.user_id = $2 AND h.query_text = $3))
+		 ORDER BY h.executed_at DESC
+		 LIMIT $5`,
+		queryID, userID, saved.QueryText, days, maxPerformanceRuns,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saved query runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []models.QueryHistory
+	for rows.Next() {
+		var h models.QueryHistory
+		if err := rows.Scan(&h.ID, &h.UserID, &h.QueryText, &h.Status, &h.ExecutionTimeMs, &h.RowCount, &h.ErrorMessage,
+			&h.Source, &h.SourceID, &h.ExecutedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan saved query run: %w", err)
+		}
+		runs = append(runs, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	perf := summarizeQueryRuns(runs, s.slowThresholdMs)
+	perf.QueryID = queryID
+	perf.Days = days
+	return perf, nil
+}
+
+// summarizeQueryRuns computes run statistics from executions ordered newest first. Failed runs
+// are counted but left out of the timings, since their execution time is not recorded.
+func summarizeQueryRuns(runs []models.QueryHistory, thresholdMs int) *models.SavedQueryPerformance {
+	perf := &models.SavedQueryPerformance{
+		ThresholdMs: max(thresholdMs, 0),
+		RunCount:    len(runs),
+		SlowestRuns: []models.QueryHistory{},
+	}
+	if len(runs) > 0 {
+		perf.LastRunAt = &runs[0].ExecutedAt
+	}
+
+	var timings []int64
+	var total int64
+	for _, run := range runs {
+		if run.Status != "success" || run.ExecutionTimeMs == nil {
+			perf.ErrorCount++
+			continue
+		}
+		ms := int64(*run.ExecutionTimeMs)
+		timings = append(timings, ms)
+		total += ms
+		if IsSlowQuery(ms, thresholdMs) {
+			perf.SlowCount++
+			perf.SlowestRuns = append(perf.SlowestRuns, run)
+		}
+	}
+	if len(timings) == 0 {
+		return perf
+	}
+
+	sort.Slice(timings, func(i, j int) bool { return timings[i] < timings[j] })
+	perf.AvgMs = total / int64(len(timings))
+	perf.MaxMs = timings[len(timings)-1]
+	// Nearest-rank percentile
+	perf.P95Ms = timings[(len(timings)*95+99)/100-1]
+
+	sort.SliceStable(perf.SlowestRuns, func(i, j int) bool {
+		return *perf.SlowestRuns[i].ExecutionTimeMs > *perf.SlowestRuns[j].ExecutionTimeMs
+	})
+	if len(perf.SlowestRuns) > slowestRunsShown {
+		perf.SlowestRuns = perf.SlowestRuns[:slowestRunsShown]
+	}
+	return perf
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mitsume/backend/internal/models"
+)
+
+func TestIsSlowQuery(t *testing.T) {
+	tests := []struct {
+		name        string
+		ms          int64
+		thresholdMs int
+		want        bool
+	}{
+		{"below threshold", 9999, 10000, false},
+		{"at threshold", 10000, 10000, true},
+		{"above threshold", 25000, 10000, true},
+		{"reporting disabled", 25000, 0, false},
+		{"negative threshold", 25000, -1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsSlowQuery(tt.ms, tt.thresholdMs); got != tt.want {
+				t.Errorf("IsSlowQuery(%d, %d) = %v, want %v", tt.ms, tt.thresholdMs, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummarizeQueryRuns(t *testing.T) {
+	now := time.Now()
+	run := func(status string, ms int, ago time.Duration) models.QueryHistory {
+		h := models.QueryHistory{Status: status, ExecutedAt: now.Add(-ago)}
+		h.ExecutionTimeMs = &ms
+		return h
+	}
+	// Newest first, as loaded from query_history
+	runs := []models.QueryHistory{
+		run("success", 1200, time.Minute),
+		run("error", 0, 2*time.Minute),
+		run("success", 15000, 3*time.Minute),
+		run("success", 800, 4*time.Minute),
+		run("success", 30000, 5*time.Minute),
+		run("success", 1000, 6*time.Minute),
+	}
+
+	perf := summarizeQueryRuns(runs, 10000)
+
+	if perf.RunCount != 6 || perf.ErrorCount != 1 {
+		t.Errorf("RunCount, ErrorCount = %d, %d, want 6, 1", perf.RunCount, perf.ErrorCount)
+	}
+	if perf.SlowCount != 2 || len(perf.SlowestRuns) != 2 {
+		t.Fatalf("SlowCount = %d with %d runs, want 2", perf.SlowCount, len(perf.SlowestRuns))
+	}
+	if *perf.SlowestRuns[0].ExecutionTimeMs != 30000 || *perf.SlowestRuns[1].ExecutionTimeMs != 15000 {
+		t.Errorf("SlowestRuns = %d, %d, want slowest first", *perf.SlowestRuns[0].ExecutionTimeMs, *perf.SlowestRuns[1].ExecutionTimeMs)
+	}
+	if perf.AvgMs != 9600 || perf.MaxMs != 30000 || perf.P95Ms != 30000 {
+		t.Errorf("AvgMs, MaxMs, P95Ms = %d, %d, %d, want 9600, 30000, 30000", perf.AvgMs, perf.MaxMs, perf.P95Ms)
+	}
+	if perf.LastRunAt == nil || !perf.LastRunAt.Equal(runs[0].ExecutedAt) {
+		t.Errorf("LastRunAt = %v, want the newest run", perf.LastRunAt)
+	}
+
+	disabled := summarizeQueryRuns(runs, 0)
+	if disabled.SlowCount != 0 || len(disabled.SlowestRuns) != 0 || disabled.ThresholdMs != 0 {
+		t.Errorf("with reporting disabled SlowCount = %d, want 0", disabled.SlowCount)
+	}
+
+	empty := summarizeQueryRuns(nil, 10000)
+	if empty.RunCount != 0 || empty.LastRunAt != nil || empty.SlowestRuns == nil {
+		t.Errorf("summarizeQueryRuns(nil) = %+v", empty)
+	}
+}
@@ -2,13 +2,16 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/mitsume/backend/internal/models"
-	"github.com/robfig/cron/v3"
 )
 
 // SubscriptionService manages dashboard subscriptions
@@ -16,6 +19,8 @@ type SubscriptionService struct {
 	pool                *pgxpool.Pool
 	notificationService *NotificationService
 	dashboardService    *DashboardService
+	activeLimit         activeLimit
+	failureLimit        int // consecutive failed runs that pause a subscription; 0 never pauses
 }
 
 // NewSubscriptionService creates a new subscription service
@@ -27,10 +32,31 @@ func NewSubscriptionService(pool *pgxpool.Pool, notificationService *Notificatio
 	}
 }
 
+// SetActiveLimit caps how many active subscriptions a non-admin user may have; max <= 0 is unlimited
+func (s *SubscriptionService) SetActiveLimit(max int, admins AdminChecker) {
+	s.activeLimit = activeLimit{Max: max, admins: admins}
+}
+
+// SetFailureLimit pauses a subscription once its last n runs all failed; n <= 0 never pauses
+func (s *SubscriptionService) SetFailureLimit(n int) {
+	s.failureLimit = n
+}
+
+// checkActiveLimit fails with *ActiveLimitError when the user may not activate another subscription
+func (s *SubscriptionService) checkActiveLimit(ctx context.Context, userID uuid.UUID) error {
+	return s.activeLimit.check(ctx, userID, "subscriptions", func() (int, error) {
+		var n int
+		err := s.pool.QueryRow(ctx,
+			`SELECT COUNT(*) FROM dashboard_subscriptions WHERE user_id = $1 AND is_active = TRUE`, userID,
+		).Scan(&n)
+		return n, err
+	})
+}
+
 // GetSubscriptions returns all subscriptions for a user
 func (s *SubscriptionService) GetSubscriptions(ctx context.Context, userID uuid.UUID) ([]models.DashboardSubscription, error) {
 	query := `
-		SELECT id, user_id, dashboard_id, name, schedule_cron, timezone, format, is_active,
+		SELECT id, user_id, dashboard_id, name, schedule_cron, schedule_interval_minutes, timezone, format, is_active,
 		       last_sent_at, next_run_at, created_at, updated_at
 		FROM dashboard_subscriptions
 		WHERE user_id = $1
@@ -47,10 +73,11 @@ func (s *SubscriptionService) GetSubscriptions(ctx context.Context, userID uuid.
 	for rows.Next() {
 		var sub models.DashboardSubscription
 		if err := rows.Scan(&sub.ID, &sub.UserID, &sub.DashboardID, &sub.Name, &sub.ScheduleCron,
-			&sub.Timezone, &sub.Format, &sub.IsActive, &sub.LastSentAt, &sub.NextRunAt,
+			&sub.ScheduleIntervalMinutes, &sub.Timezone, &sub.Format, &sub.IsActive, &sub.LastSentAt, &sub.NextRunAt,
 			&sub.CreatedAt, &sub.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan subscription: %w", err)
 		}
+		flagInvalidTimezone(&sub)
 
 		// Get channel IDs
 		channelIDs, err := s.getSubscriptionChannelIDs(ctx, sub.ID)
@@ -68,7 +95,7 @@ func (s *SubscriptionService) GetSubscriptions(ctx context.Context, userID uuid.
 // GetSubscriptionByID returns a subscription by ID
 func (s *SubscriptionService) GetSubscriptionByID(ctx context.Context, id uuid.UUID) (*models.DashboardSubscription, error) {
 	query := `
-		SELECT id, user_id, dashboard_id, name, schedule_cron, timezone, format, is_active,
+		SELECT id, user_id, dashboard_id, name, schedule_cron, schedule_interval_minutes, timezone, format, is_active,
 		       last_sent_at, next_run_at, created_at, updated_at
 		FROM dashboard_subscriptions
 		WHERE id = $1
@@ -76,11 +103,12 @@ func (s *SubscriptionService) GetSubscriptionByID(ctx context.Context, id uuid.U
 
 	var sub models.DashboardSubscription
 	err := s.pool.QueryRow(ctx, query, id).Scan(&sub.ID, &sub.UserID, &sub.DashboardID,
-		&sub.Name, &sub.ScheduleCron, &sub.Timezone, &sub.Format, &sub.IsActive,
+		&sub.Name, &sub.ScheduleCron, &sub.ScheduleIntervalMinutes, &sub.Timezone, &sub.Format, &sub.IsActive,
 		&sub.LastSentAt, &sub.NextRunAt, &sub.CreatedAt, &sub.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get subscription: %w", err)
 	}
+	flagInvalidTimezone(&sub)
 
 	// Get channel IDs
 	channelIDs, err := s.getSubscriptionChannelIDs(ctx, sub.ID)
@@ -94,40 +122,48 @@ func (s *SubscriptionService) GetSubscriptionByID(ctx context.Context, id uuid.U
 
 // CreateSubscription creates a new subscription
 func (s *SubscriptionService) CreateSubscription(ctx context.Context, userID uuid.UUID, req *models.CreateSubscriptionRequest) (*models.DashboardSubscription, error) {
-	// Validate cron expression
-	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	_, err := parser.Parse(req.ScheduleCron)
-	if err != nil {
-		return nil, fmt.Errorf("invalid cron expression: %w", err)
-	}
-
 	// Set defaults
 	timezone := req.Timezone
 	if timezone == "" {
-		timezone = "Asia/Tokyo"
+		timezone = defaultSubscriptionTimezone
+	}
+
+	// Validate the cron expression or interval, and the timezone
+	if err := validateSubscriptionSchedule(req.ScheduleCron, req.ScheduleIntervalMinutes, timezone); err != nil {
+		return nil, err
 	}
+
 	format := req.Format
 	if format == "" {
 		format = "pdf"
 	}
 
+	// New subscriptions start active
+	if err := s.checkActiveLimit(ctx, userID); err != nil {
+		return nil, err
+	}
+
 	// Calculate next run time
-	nextRunAt, err := s.calculateNextRun(req.ScheduleCron, timezone)
+	nextRunAt, err := s.nextRunOf(&models.DashboardSubscription{
+		ScheduleCron:            req.ScheduleCron,
+		ScheduleIntervalMinutes: req.ScheduleIntervalMinutes,
+		Timezone:                timezone,
+	}, time.Now())
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate next run: %w", err)
 	}
 
 	query := `
-		INSERT INTO dashboard_subscriptions (user_id, dashboard_id, name, schedule_cron, timezone, format, next_run_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, user_id, dashboard_id, name, schedule_cron, timezone, format, is_active,
+		INSERT INTO dashboard_subscriptions (user_id, dashboard_id, name, schedule_cron, schedule_interval_minutes, timezone, format, next_run_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, user_id, dashboard_id, name, schedule_cron, schedule_interval_minutes, timezone, format, is_active,
 		          last_sent_at, next_run_at, created_at, updated_at
 	`
 
 	var sub models.DashboardSubscription
 	err = s.pool.QueryRow(ctx, query, userID, req.DashboardID, req.Name, req.ScheduleCron,
-		timezone, format, nextRunAt).Scan(&sub.ID, &sub.UserID, &sub.DashboardID, &sub.Name,
-		&sub.ScheduleCron, &sub.Timezone, &sub.Format, &sub.IsActive, &sub.LastSentAt,
+		req.ScheduleIntervalMinutes, timezone, format, nextRunAt).Scan(&sub.ID, &sub.UserID, &sub.DashboardID, &sub.Name,
+		&sub.ScheduleCron, &sub.ScheduleIntervalMinutes, &sub.Timezone, &sub.Format, &sub.IsActive, &sub.LastSentAt,
 		&sub.NextRunAt, &sub.CreatedAt, &sub.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create subscription: %w", err)
@@ -157,47 +193,63 @@ func (s *SubscriptionService) UpdateSubscription(ctx context.Context, id uuid.UU
 	if req.Name != "" {
 		existing.Name = req.Name
 	}
+	if req.ScheduleCron != "" && req.ScheduleIntervalMinutes != nil {
+		return nil, &models.ValidationError{Field: "schedule_interval_minutes", Message: "schedule_cron and schedule_interval_minutes cannot both be set"}
+	}
+	// Setting either schedule replaces the other
 	if req.ScheduleCron != "" {
-		// Validate cron expression
-		parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-		if _, err := parser.Parse(req.ScheduleCron); err != nil {
-			return nil, fmt.Errorf("invalid cron expression: %w", err)
-		}
 		existing.ScheduleCron = req.ScheduleCron
+		existing.ScheduleIntervalMinutes = nil
+	}
+	if req.ScheduleIntervalMinutes != nil {
+		existing.ScheduleIntervalMinutes = req.ScheduleIntervalMinutes
+		existing.ScheduleCron = ""
 	}
 	if req.Timezone != "" {
 		existing.Timezone = req.Timezone
 	}
+	if req.ScheduleCron != "" || req.ScheduleIntervalMinutes != nil || req.Timezone != "" {
+		// Validate the cron expression or interval, and the timezone
+		if err := validateSubscriptionSchedule(existing.ScheduleCron, existing.ScheduleIntervalMinutes, existing.Timezone); err != nil {
+			return nil, err
+		}
+	}
 	if req.Format != "" {
 		existing.Format = req.Format
 	}
 	if req.IsActive != nil {
+		if *req.IsActive && !existing.IsActive {
+			if err := s.checkActiveLimit(ctx, userID); err != nil {
+				return nil, err
+			}
+		}
 		existing.IsActive = *req.IsActive
 	}
 
 	// Recalculate next run time
-	nextRunAt, err := s.calculateNextRun(existing.ScheduleCron, existing.Timezone)
+	nextRunAt, err := s.nextRunOf(existing, time.Now())
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate next run: %w", err)
 	}
 
 	query := `
 		UPDATE dashboard_subscriptions
-		SET name = $1, schedule_cron = $2, timezone = $3, format = $4, is_active = $5,
-		    next_run_at = $6, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $7
-		RETURNING id, user_id, dashboard_id, name, schedule_cron, timezone, format, is_active,
+		SET name = $1, schedule_cron = $2, schedule_interval_minutes = $3, timezone = $4, format = $5,
+		    is_active = $6, next_run_at = $7, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $8
+		RETURNING id, user_id, dashboard_id, name, schedule_cron, schedule_interval_minutes, timezone, format, is_active,
 		          last_sent_at, next_run_at, created_at, updated_at
 	`
 
 	var sub models.DashboardSubscription
-	err = s.pool.QueryRow(ctx, query, existing.Name, existing.ScheduleCron, existing.Timezone,
-		existing.Format, existing.IsActive, nextRunAt, id).Scan(&sub.ID, &sub.UserID, &sub.DashboardID,
-		&sub.Name, &sub.ScheduleCron, &sub.Timezone, &sub.Format, &sub.IsActive,
+	err = s.pool.QueryRow(ctx, query, existing.Name, existing.ScheduleCron, existing.ScheduleIntervalMinutes,
+		existing.Timezone, existing.Format, existing.IsActive, nextRunAt, id).Scan(&sub.ID, &sub.UserID, &sub.DashboardID,
+		&sub.Name, &sub.ScheduleCron, &sub.ScheduleIntervalMinutes, &sub.Timezone, &sub.Format, &sub.IsActive,
 		&sub.LastSentAt, &sub.NextRunAt, &sub.CreatedAt, &sub.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update subscription: %w", err)
 	}
+	flagInvalidTimezone(&sub)
 
 	// Update channel associations if provided
 	if len(req.ChannelIDs) > 0 {
@@ -243,82 +295,409 @@ func (s *SubscriptionService) TriggerSubscription(ctx context.Context, id uuid.U
 	return s.ExecuteSubscription(ctx, sub)
 }
 
-// ExecuteSubscription sends the dashboard snapshot to subscribed channels
+// PauseSubscription stops a subscription from running until it is resumed, keeping its settings
+func (s *SubscriptionService) PauseSubscription(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*models.DashboardSubscription, error) {
+	return s.setSubscriptionActive(ctx, id, userID, false)
+}
+
+// ResumeSubscription reactivates a paused subscription. Its next run is recalculated from now, so
+// runs missed while paused are not sent.
+func (s *SubscriptionService) ResumeSubscription(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*models.DashboardSubscription, error) {
+	return s.setSubscriptionActive(ctx, id, userID, true)
+}
+
+func (s *SubscriptionService) setSubscriptionActive(ctx context.Context, id uuid.UUID, userID uuid.UUID, active bool) (*models.DashboardSubscription, error) {
+	sub, err := s.GetSubscriptionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if sub.UserID != userID {
+		return nil, fmt.Errorf("not authorized to update this subscription")
+	}
+
+	if sub.IsActive == active {
+		return sub, nil
+	}
+
+	if !active {
+		if _, err := s.pool.Exec(ctx,
+			`UPDATE dashboard_subscriptions SET is_active = FALSE, updated_at = CURRENT_TIMESTAMP WHERE id = $1`, id,
+		); err != nil {
+			return nil, fmt.Errorf("failed to pause subscription: %w", err)
+		}
+		return s.GetSubscriptionByID(ctx, id)
+	}
+
+	if err := s.checkActiveLimit(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	nextRunAt, err := s.nextRunOf(sub, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate next run: %w", err)
+	}
+
+	if _, err := s.pool.Exec(ctx,
+		`UPDATE dashboard_subscriptions SET is_active = TRUE, next_run_at = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`,
+		id, nextRunAt,
+	); err != nil {
+		return nil, fmt.Errorf("failed to resume subscription: %w", err)
+	}
+	return s.GetSubscriptionByID(ctx, id)
+}
+
+// SkipNextRun moves an active subscription's next run to the following scheduled time without
+// sending anything
+func (s *SubscriptionService) SkipNextRun(ctx context.Context, id uuid.UUID, userID uuid.UUID) (*models.DashboardSubscription, error) {
+	sub, err := s.GetSubscriptionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if sub.UserID != userID {
+		return nil, fmt.Errorf("not authorized to update this subscription")
+	}
+
+	if !sub.IsActive {
+		return nil, fmt.Errorf("subscription is paused; resume it instead of skipping a run")
+	}
+
+	nextRunAt, err := s.runAfterNextOf(sub, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate next run: %w", err)
+	}
+
+	// Compare against the run being skipped, so a concurrent skip or scheduler run is not skipped over
+	result, err := s.pool.Exec(ctx,
+		`UPDATE dashboard_subscriptions SET next_run_at = $2, updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $1 AND next_run_at IS NOT DISTINCT FROM $3`,
+		id, nextRunAt, sub.NextRunAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to skip next run: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return nil, fmt.Errorf("subscription changed while skipping its next run; try again")
+	}
+	return s.GetSubscriptionByID(ctx, id)
+}
+
+// ExecuteSubscription sends the dashboard snapshot to subscribed channels and records the run,
+// with each channel's outcome, in subscription_history. If only some deliveries failed the error
+// is a *ChannelDeliveryError naming each failed channel.
 func (s *SubscriptionService) ExecuteSubscription(ctx context.Context, sub *models.DashboardSubscription) error {
+	results, err := s.deliverSubscription(ctx, sub)
+	status, errMsg := subscriptionRunStatus(results, err)
+	if recErr := s.recordSubscriptionRun(ctx, sub.ID, status, results, errMsg); recErr != nil {
+		// The report has already gone out; a missing history row must not fail the run
+		log.Printf("Failed to record history for subscription %s: %v", sub.ID, recErr)
+	} else if status == models.SubscriptionRunFailed {
+		if pauseErr := s.pauseAfterFailures(ctx, sub); pauseErr != nil {
+			log.Printf("Failed to check consecutive failures of subscription %s: %v", sub.ID, pauseErr)
+		}
+	}
+
+	return err
+}
+
+// pauseAfterFailures pauses the subscription when its last failureLimit runs, as recorded in
+// subscription_history, all failed. Partial runs reached some channels and break the streak, and
+// only runs since the subscription was last changed (resumed, edited) count, so a resumed
+// subscription gets failureLimit new attempts.
+func (s *SubscriptionService) pauseAfterFailures(ctx context.Context, sub *models.DashboardSubscription) error {
+	if s.failureLimit <= 0 || !sub.IsActive {
+		return nil
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT status FROM subscription_history
+		WHERE subscription_id = $1
+		  AND run_at >= COALESCE((SELECT updated_at FROM dashboard_subscriptions WHERE id = $1), '-infinity')
+		ORDER BY run_at DESC
+		LIMIT $2
+	`, sub.ID, s.failureLimit)
+	if err != nil {
+		return err
+	}
+	var statuses []string
+	for rows.Next() {
+		var status string
+		if err := rows.Scan(&status); err != nil {
+			rows.Close()
+			return err
+		}
+		statuses = append(statuses, status)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if !reachedFailureLimit(statuses, s.failureLimit) {
+		return nil
+	}
+
+	if _, err := s.pool.Exec(ctx,
+		`UPDATE dashboard_subscriptions SET is_active = FALSE, updated_at = CURRENT_TIMESTAMP WHERE id = $1`, sub.ID,
+	); err != nil {
+		return err
+	}
+	sub.IsActive = false
+	log.Printf("Paused subscription %s after %d consecutive failed runs", sub.ID, s.failureLimit)
+	return nil
+}
+
+// reachedFailureLimit reports whether the most recent run statuses, newest first, start with
+// limit failed runs
+func reachedFailureLimit(statuses []string, limit int) bool {
+	if limit <= 0 || len(statuses) < limit {
+		return false
+	}
+	for _, status := range statuses[:limit] {
+		if status != models.SubscriptionRunFailed {
+			return false
+		}
+	}
+	return true
+}
+
+// deliverSubscription sends the report to every channel of the subscription, returning each
+// channel's result. The error is a *ChannelDeliveryError when some channels failed, or reports
+// a failure before any channel was tried.
+func (s *SubscriptionService) deliverSubscription(ctx context.Context, sub *models.DashboardSubscription) ([]models.SubscriptionChannelResult, error) {
 	// Get channels
 	channels, err := s.GetSubscriptionChannels(ctx, sub.ID)
 	if err != nil {
-		return fmt.Errorf("failed to get subscription channels: %w", err)
+		return nil, fmt.Errorf("failed to get subscription channels: %w", err)
 	}
 
 	if len(channels) == 0 {
-		return fmt.Errorf("no notification channels configured")
+		return nil, fmt.Errorf("no notification channels configured")
 	}
 
 	// Get dashboard info
 	dashboard, err := s.dashboardService.GetDashboard(ctx, sub.DashboardID, sub.UserID)
 	if err != nil {
-		return fmt.Errorf("failed to get dashboard: %w", err)
+		return nil, fmt.Errorf("failed to get dashboard: %w", err)
+	}
+
+	// Scheduled reports have no viewer input, so {{param}} placeholders take the default values
+	var paramDefs []models.ParameterDefinition
+	if len(dashboard.Parameters) > 0 {
+		_ = json.Unmarshal(dashboard.Parameters, &paramDefs)
 	}
+	title := models.RenderParameterText(dashboard.Name, nil, paramDefs)
 
 	// Create notification message
 	msg := models.NotificationMessage{
-		Title: fmt.Sprintf("Scheduled Report: %s", dashboard.Name),
-		Body:  fmt.Sprintf("Dashboard report for '%s' is ready.\nFormat: %s\nSchedule: %s", dashboard.Name, sub.Format, sub.ScheduleCron),
+		Title: fmt.Sprintf("Scheduled Report: %s", title),
+		Body:  fmt.Sprintf("Dashboard report for '%s' is ready.\nFormat: %s\nSchedule: %s", title, sub.Format, subscriptionScheduleLabel(sub)),
 	}
 
-	// Send to all channels
-	var lastErr error
+	// Send to all channels, keeping every channel's outcome
+	results := make([]models.SubscriptionChannelResult, 0, len(channels))
+	failures := make(map[uuid.UUID]error)
+	names := make(map[uuid.UUID]string, len(channels))
 	for _, ch := range channels {
+		names[ch.ID] = ch.Name
+		result := models.SubscriptionChannelResult{
+			ChannelID:   ch.ID,
+			ChannelName: ch.Name,
+			ChannelType: ch.ChannelType,
+			Status:      models.SubscriptionRunSent,
+		}
 		if err := s.notificationService.Send(ctx, &ch, msg); err != nil {
-			lastErr = err
+			failures[ch.ID] = err
+			errMsg := err.Error()
+			result.Status = models.SubscriptionRunFailed
+			result.Error = &errMsg
+		}
+		results = append(results, result)
+	}
+
+	if len(failures) > 0 {
+		return results, &ChannelDeliveryError{Failures: failures, Names: names, Total: len(channels)}
+	}
+	return results, nil
+}
+
+// ChannelDeliveryError reports the channels a notification could not be delivered to, like the
+// result of NotificationService.SendToChannels but only with the failures
+type ChannelDeliveryError struct {
+	Failures map[uuid.UUID]error  // error per failed channel
+	Names    map[uuid.UUID]string // channel names, used in the message
+	Total    int                  // number of channels the notification was sent to
+}
+
+// Error names each failed channel with its error, ordered by channel name
+func (e *ChannelDeliveryError) Error() string {
+	ids := make([]uuid.UUID, 0, len(e.Failures))
+	for id := range e.Failures {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if e.Names[ids[i]] != e.Names[ids[j]] {
+			return e.Names[ids[i]] < e.Names[ids[j]]
 		}
+		return ids[i].String() < ids[j].String()
+	})
+
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprintf("%s (%s): %v", e.Names[id], id, e.Failures[id])
 	}
+	return fmt.Sprintf("failed to send to %d of %d channels: %s", len(ids), e.Total, strings.Join(parts, "; "))
+}
 
-	return lastErr
+// subscriptionRunStatus derives a run's status and error message from its channel results and
+// the run error: "failed" when nothing was delivered, "partial" when only some channels failed
+func subscriptionRunStatus(results []models.SubscriptionChannelResult, runErr error) (string, *string) {
+	if runErr == nil {
+		return models.SubscriptionRunSent, nil
+	}
+	errMsg := runErr.Error()
+	for _, r := range results {
+		if r.Status == models.SubscriptionRunSent {
+			return models.SubscriptionRunPartial, &errMsg
+		}
+	}
+	return models.SubscriptionRunFailed, &errMsg
 }
 
-// GetDueSubscriptions returns subscriptions that are due for execution
+// recordSubscriptionRun stores a run of the subscription in subscription_history
+func (s *SubscriptionService) recordSubscriptionRun(ctx context.Context, subID uuid.UUID, status string, results []models.SubscriptionChannelResult, errMsg *string) error {
+	if results == nil {
+		results = []models.SubscriptionChannelResult{}
+	}
+	resultsJSON, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.pool.Exec(ctx, `
+		INSERT INTO subscription_history (subscription_id, status, channel_results, error_message)
+		VALUES ($1, $2, $3, $4)
+	`, subID, status, resultsJSON, errMsg)
+	return err
+}
+
+// GetSubscriptionHistory returns the most recent runs of a subscription, newest first
+func (s *SubscriptionService) GetSubscriptionHistory(ctx context.Context, subID uuid.UUID, limit int) ([]models.SubscriptionHistory, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	query := `
+		SELECT id, subscription_id, run_at, status, channel_results, error_message
+		FROM subscription_history
+		WHERE subscription_id = $1
+		ORDER BY run_at DESC
+		LIMIT $2
+	`
+
+	rows, err := s.pool.Query(ctx, query, subID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subscription history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []models.SubscriptionHistory
+	for rows.Next() {
+		var h models.SubscriptionHistory
+		var resultsJSON []byte
+		if err := rows.Scan(&h.ID, &h.SubscriptionID, &h.RunAt, &h.Status, &resultsJSON, &h.ErrorMessage); err != nil {
+			return nil, fmt.Errorf("failed to scan subscription history: %w", err)
+		}
+		if err := json.Unmarshal(resultsJSON, &h.ChannelResults); err != nil {
+			return nil, fmt.Errorf("failed to parse channel results: %w", err)
+		}
+		history = append(history, h)
+	}
+
+	return history, rows.Err()
+}
+
+// GetDueSubscriptions claims and returns up to 100 subscriptions that are due for execution.
+// Claiming works like GetDueAlerts: rows are locked with FOR UPDATE SKIP LOCKED and their
+// next_run_at is pushed forward by dueClaimLease before the transaction commits.
 func (s *SubscriptionService) GetDueSubscriptions(ctx context.Context) ([]models.DashboardSubscription, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
-		SELECT id, user_id, dashboard_id, name, schedule_cron, timezone, format, is_active,
+		SELECT id, user_id, dashboard_id, name, schedule_cron, schedule_interval_minutes, timezone, format, is_active,
 		       last_sent_at, next_run_at, created_at, updated_at
 		FROM dashboard_subscriptions
 		WHERE is_active = TRUE AND (next_run_at IS NULL OR next_run_at <= CURRENT_TIMESTAMP)
 		ORDER BY next_run_at ASC
 		LIMIT 100
+		FOR UPDATE SKIP LOCKED
 	`
 
-	rows, err := s.pool.Query(ctx, query)
+	rows, err := tx.Query(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query due subscriptions: %w", err)
 	}
-	defer rows.Close()
 
 	var subscriptions []models.DashboardSubscription
 	for rows.Next() {
 		var sub models.DashboardSubscription
 		if err := rows.Scan(&sub.ID, &sub.UserID, &sub.DashboardID, &sub.Name, &sub.ScheduleCron,
-			&sub.Timezone, &sub.Format, &sub.IsActive, &sub.LastSentAt, &sub.NextRunAt,
+			&sub.ScheduleIntervalMinutes, &sub.Timezone, &sub.Format, &sub.IsActive, &sub.LastSentAt, &sub.NextRunAt,
 			&sub.CreatedAt, &sub.UpdatedAt); err != nil {
+			rows.Close()
 			return nil, fmt.Errorf("failed to scan subscription: %w", err)
 		}
+		subscriptions = append(subscriptions, sub)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query due subscriptions: %w", err)
+	}
 
-		// Get channel IDs
-		channelIDs, _ := s.getSubscriptionChannelIDs(ctx, sub.ID)
-		sub.ChannelIDs = channelIDs
+	if len(subscriptions) == 0 {
+		return nil, nil
+	}
 
-		subscriptions = append(subscriptions, sub)
+	ids := make([]uuid.UUID, len(subscriptions))
+	for i := range subscriptions {
+		ids[i] = subscriptions[i].ID
+	}
+	_, err = tx.Exec(ctx,
+		`UPDATE dashboard_subscriptions SET next_run_at = CURRENT_TIMESTAMP + $2 * INTERVAL '1 second' WHERE id = ANY($1)`,
+		ids, dueClaimLease.Seconds(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due subscriptions: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to claim due subscriptions: %w", err)
+	}
+
+	for i := range subscriptions {
+		// Get channel IDs
+		channelIDs, _ := s.getSubscriptionChannelIDs(ctx, subscriptions[i].ID)
+		subscriptions[i].ChannelIDs = channelIDs
 	}
 
 	return subscriptions, nil
 }
 
-// UpdateSubscriptionAfterRun updates subscription timestamps after execution
-func (s *SubscriptionService) UpdateSubscriptionAfterRun(ctx context.Context, subID uuid.UUID, cronExpr, timezone string) error {
-	nextRunAt, err := s.calculateNextRun(cronExpr, timezone)
-	if err != nil {
-		return fmt.Errorf("failed to calculate next run: %w", err)
+// UpdateSubscriptionAfterRun updates subscription timestamps after execution. sub.NextRunAt is
+// the run that was due, from which interval schedules count their next run.
+func (s *SubscriptionService) UpdateSubscriptionAfterRun(ctx context.Context, sub *models.DashboardSubscription) error {
+	var nextRunAt time.Time
+	if interval, ok := subscriptionInterval(sub); ok {
+		nextRunAt = nextIntervalRun(interval, sub.NextRunAt, time.Now())
+	} else {
+		var err error
+		if nextRunAt, err = s.calculateNextRun(sub.ScheduleCron, sub.Timezone); err != nil {
+			return fmt.Errorf("failed to calculate next run: %w", err)
+		}
 	}
 
 	query := `
@@ -327,7 +706,7 @@ func (s *SubscriptionService) UpdateSubscriptionAfterRun(ctx context.Context, su
 		WHERE id = $1
 	`
 
-	_, err = s.pool.Exec(ctx, query, subID, nextRunAt)
+	_, err := s.pool.Exec(ctx, query, sub.ID, nextRunAt)
 	return err
 }
 
@@ -395,18 +774,84 @@ func (s *SubscriptionService) setSubscriptionChannels(ctx context.Context, subID
 	return nil
 }
 
+// flagInvalidTimezone marks a subscription whose stored timezone is no longer accepted, so that
+// subscriptions saved before timezones were validated can be found and fixed
+func flagInvalidTimezone(sub *models.DashboardSubscription) {
+	if _, err := loadSubscriptionLocation(sub.Timezone); err != nil {
+		sub.TimezoneInvalid = true
+	}
+}
+
+// subscriptionInterval returns the interval of a subscription with an interval schedule
+func subscriptionInterval(sub *models.DashboardSubscription) (time.Duration, bool) {
+	if sub.ScheduleIntervalMinutes == nil {
+		return 0, false
+	}
+	return time.Duration(*sub.ScheduleIntervalMinutes) * time.Minute, true
+}
+
+// subscriptionScheduleLabel returns how a subscription's schedule is shown in its reports
+func subscriptionScheduleLabel(sub *models.DashboardSubscription) string {
+	if sub.ScheduleIntervalMinutes != nil {
+		return DescribeInterval(*sub.ScheduleIntervalMinutes)
+	}
+	return sub.ScheduleCron
+}
+
+// nextRunOf returns the first run of sub's schedule after now, from its cron expression or
+// one interval after now
+func (s *SubscriptionService) nextRunOf(sub *models.DashboardSubscription, now time.Time) (time.Time, error) {
+	if interval, ok := subscriptionInterval(sub); ok {
+		return nextIntervalRun(interval, nil, now), nil
+	}
+	return s.calculateNextRunAfter(sub.ScheduleCron, sub.Timezone, now)
+}
+
+// runAfterNextOf is calculateRunAfterNext for sub's schedule, cron or interval
+func (s *SubscriptionService) runAfterNextOf(sub *models.DashboardSubscription, now time.Time) (time.Time, error) {
+	interval, ok := subscriptionInterval(sub)
+	if !ok {
+		return s.calculateRunAfterNext(sub.ScheduleCron, sub.Timezone, sub.NextRunAt, now)
+	}
+	upcoming := now.Add(interval)
+	if sub.NextRunAt != nil && sub.NextRunAt.After(now) {
+		upcoming = *sub.NextRunAt
+	}
+	return upcoming.Add(interval), nil
+}
+
+// calculateNextRun returns the next run after now. Create and update reject unknown timezones;
+// rows saved before that check fall back to UTC so the scheduler keeps advancing them, are
+// logged here and are reported to their owner through TimezoneInvalid.
 func (s *SubscriptionService) calculateNextRun(cronExpr, timezone string) (time.Time, error) {
-	loc, err := time.LoadLocation(timezone)
+	return s.calculateNextRunAfter(cronExpr, timezone, time.Now())
+}
+
+// calculateRunAfterNext returns the scheduled run following the upcoming one: nextRunAt when it
+// is still ahead of now, otherwise the next run after now
+func (s *SubscriptionService) calculateRunAfterNext(cronExpr, timezone string, nextRunAt *time.Time, now time.Time) (time.Time, error) {
+	upcoming, err := s.calculateNextRunAfter(cronExpr, timezone, now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if nextRunAt != nil && nextRunAt.After(now) {
+		upcoming = *nextRunAt
+	}
+	return s.calculateNextRunAfter(cronExpr, timezone, upcoming)
+}
+
+// calculateNextRunAfter returns the first scheduled run after t, as calculateNextRun
+func (s *SubscriptionService) calculateNextRunAfter(cronExpr, timezone string, t time.Time) (time.Time, error) {
+	loc, err := loadSubscriptionLocation(timezone)
 	if err != nil {
+		log.Printf("Subscription timezone %q is invalid, scheduling in UTC", timezone)
 		loc = time.UTC
 	}
 
-	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	schedule, err := parser.Parse(cronExpr)
+	schedule, err := subscriptionCronParser.Parse(cronExpr)
 	if err != nil {
 		return time.Time{}, err
 	}
 
-	now := time.Now().In(loc)
-	return schedule.Next(now), nil
+	return schedule.Next(t.In(loc)), nil
 }
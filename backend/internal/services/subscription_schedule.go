@@ -0,0 +1,338 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mitsume/backend/internal/models"
+	"github.com/robfig/cron/v3"
+)
+
+// defaultSubscriptionTimezone is used when a subscription does not name a timezone
+const defaultSubscriptionTimezone = "Asia/Tokyo"
+
+// schedulePreviewRuns is how many upcoming runs PreviewSchedule returns
+const schedulePreviewRuns = 5
+
+// cronParser parses 5-field expressions and descriptors such as @daily
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// cronSecondsParser parses 6-field expressions whose first field is the second, and descriptors
+var cronSecondsParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// subscriptionCronParser parses subscription schedules: 5-field expressions, 6-field expressions
+// whose first field is the second, and descriptors such as @hourly or @every 90m. Validation and
+// scheduling both go through it, so every accepted expression can be scheduled.
+var subscriptionCronParser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// parseSchedule parses a subscription's cron expression and an IANA timezone name, reporting a
+// *models.ValidationError for either
+func parseSchedule(cronExpr, timezone string) (cron.Schedule, *time.Location, error) {
+	return parseScheduleWith(subscriptionCronParser, cronExpr, timezone)
+}
+
+// parseScheduleWith is parseSchedule with the given cron parser
+func parseScheduleWith(parser cron.Parser, cronExpr, timezone string) (cron.Schedule, *time.Location, error) {
+	schedule, err := parser.Parse(cronExpr)
+	if err != nil {
+		return nil, nil, &models.ValidationError{Field: "schedule_cron", Message: "invalid cron expression: " + err.Error()}
+	}
+	loc, err := loadSubscriptionLocation(timezone)
+	if err != nil {
+		return nil, nil, err
+	}
+	return schedule, loc, nil
+}
+
+// maxScheduleIntervalMinutes is the longest interval schedule (one week); longer periods are
+// expressed with cron
+const maxScheduleIntervalMinutes = 7 * 24 * 60
+
+// validateSubscriptionSchedule checks that exactly one of a cron expression and an interval is
+// given, that it is valid, and that timezone is an IANA name, reporting a *models.ValidationError
+func validateSubscriptionSchedule(cronExpr string, intervalMinutes *int, timezone string) error {
+	switch {
+	case cronExpr == "" && intervalMinutes == nil:
+		return &models.ValidationError{Field: "schedule_cron", Message: "either schedule_cron or schedule_interval_minutes is required"}
+	case cronExpr != "" && intervalMinutes != nil:
+		return &models.ValidationError{Field: "schedule_interval_minutes", Message: "schedule_cron and schedule_interval_minutes cannot both be set"}
+	case intervalMinutes != nil:
+		if *intervalMinutes < 1 || *intervalMinutes > maxScheduleIntervalMinutes {
+			return &models.ValidationError{
+				Field:   "schedule_interval_minutes",
+				Message: fmt.Sprintf("schedule_interval_minutes must be between 1 and %d", maxScheduleIntervalMinutes),
+			}
+		}
+		_, err := loadSubscriptionLocation(timezone)
+		return err
+	default:
+		_, _, err := parseSchedule(cronExpr, timezone)
+		return err
+	}
+}
+
+// nextIntervalRun returns the next run of an interval schedule after now. Intervals are counted
+// from due, the run that was last due, so runs do not drift by how long each one took; without
+// a past due run the next run is one interval after now.
+func nextIntervalRun(interval time.Duration, due *time.Time, now time.Time) time.Time {
+	if due == nil || due.After(now) {
+		return now.Add(interval)
+	}
+	elapsed := now.Sub(*due)/interval + 1
+	return due.Add(elapsed * interval)
+}
+
+// DescribeInterval returns an English description of an interval schedule, such as
+// "Every 6 hours"
+func DescribeInterval(minutes int) string {
+	unit, n := "minute", minutes
+	switch {
+	case minutes%(24*60) == 0:
+		unit, n = "day", minutes/(24*60)
+	case minutes%60 == 0:
+		unit, n = "hour", minutes/60
+	}
+	if n == 1 {
+		return "Every " + unit
+	}
+	return fmt.Sprintf("Every %d %ss", n, unit)
+}
+
+// loadSubscriptionLocation loads an IANA timezone name, reporting a *models.ValidationError for
+// names that are empty, "Local" or unknown
+func loadSubscriptionLocation(timezone string) (*time.Location, error) {
+	if timezone == "" || timezone == "Local" {
+		return nil, &models.ValidationError{Field: "timezone", Message: "timezone must be an IANA name such as Asia/Tokyo"}
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, &models.ValidationError{Field: "timezone", Message: fmt.Sprintf("invalid timezone: %s", timezone)}
+	}
+	return loc, nil
+}
+
+// PreviewSchedule validates a cron expression and timezone and returns the next runs after now
+// with a description of the schedule
+func (s *SubscriptionService) PreviewSchedule(cronExpr, timezone string, now time.Time) (*models.SchedulePreview, error) {
+	return s.previewSchedule(cronExpr, timezone, false, now)
+}
+
+// PreviewScheduleWithSeconds is PreviewSchedule for a 6-field expression whose first field is
+// the second
+func (s *SubscriptionService) PreviewScheduleWithSeconds(cronExpr, timezone string, now time.Time) (*models.SchedulePreview, error) {
+	return s.previewSchedule(cronExpr, timezone, true, now)
+}
+
+func (s *SubscriptionService) previewSchedule(cronExpr, timezone string, seconds bool, now time.Time) (*models.SchedulePreview, error) {
+	if timezone == "" {
+		timezone = defaultSubscriptionTimezone
+	}
+	parser, describe := cronParser, DescribeCron
+	if seconds {
+		parser, describe = cronSecondsParser, describeCronWithSeconds
+	}
+	schedule, loc, err := parseScheduleWith(parser, cronExpr, timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]time.Time, 0, schedulePreviewRuns)
+	next := now.In(loc)
+	for i := 0; i < schedulePreviewRuns; i++ {
+		next = schedule.Next(next)
+		if next.IsZero() {
+			break // the expression never matches, e.g. February 30th
+		}
+		runs = append(runs, next)
+	}
+
+	return &models.SchedulePreview{
+		Valid:       true,
+		Cron:        cronExpr,
+		Timezone:    timezone,
+		Seconds:     seconds,
+		Description: describe(cronExpr),
+		NextRuns:    runs,
+	}, nil
+}
+
+// PreviewSubscriptionSchedule validates a subscription's schedule, a cron expression or an
+// interval, and returns its next runs after now as the scheduler would compute them, with a
+// description of the schedule
+func (s *SubscriptionService) PreviewSubscriptionSchedule(cronExpr string, intervalMinutes *int, timezone string, now time.Time) (*models.SchedulePreview, error) {
+	if timezone == "" {
+		timezone = defaultSubscriptionTimezone
+	}
+	if err := validateSubscriptionSchedule(cronExpr, intervalMinutes, timezone); err != nil {
+		return nil, err
+	}
+	sub := &models.DashboardSubscription{ScheduleCron: cronExpr, ScheduleIntervalMinutes: intervalMinutes, Timezone: timezone}
+
+	loc, _ := loadSubscriptionLocation(timezone)
+	runs := make([]time.Time, 0, schedulePreviewRuns)
+	next := now
+	for i := 0; i < schedulePreviewRuns; i++ {
+		var err error
+		if next, err = s.nextRunOf(sub, next); err != nil {
+			return nil, err
+		}
+		if next.IsZero() {
+			break // the expression never matches, e.g. February 30th
+		}
+		runs = append(runs, next.In(loc))
+	}
+
+	description := DescribeCron(cronExpr)
+	if intervalMinutes != nil {
+		description = DescribeInterval(*intervalMinutes)
+	}
+	return &models.SchedulePreview{
+		Valid:           true,
+		Cron:            cronExpr,
+		IntervalMinutes: intervalMinutes,
+		Timezone:        timezone,
+		Description:     description,
+		NextRuns:        runs,
+	}, nil
+}
+
+var (
+	cronMonthNames = []string{"", "January", "February", "March", "April", "May", "June", "July",
+		"August", "September", "October", "November", "December"}
+	cronDayNames = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+)
+
+// cronDescriptors are the 5-field equivalents of the cron descriptors, for DescribeCron
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// DescribeCron returns an English description of a cron expression, such as
+// "At 09:00, Monday through Friday". 6-field expressions are read with a leading seconds field
+// and descriptors such as @daily are described as their equivalent expressions. It assumes the
+// expression has already been validated.
+func DescribeCron(cronExpr string) string {
+	if strings.HasPrefix(cronExpr, "@every ") {
+		return "Every " + strings.TrimSpace(strings.TrimPrefix(cronExpr, "@every "))
+	}
+	if expr, ok := cronDescriptors[strings.ToLower(strings.TrimSpace(cronExpr))]; ok {
+		return DescribeCron(expr)
+	}
+	fields := strings.Fields(cronExpr)
+	if len(fields) == 6 {
+		return describeCronWithSeconds(cronExpr)
+	}
+	if len(fields) != 5 {
+		return cronExpr
+	}
+	minute, hour, dom, month, dow := fields[0], fields[1], fields[2], fields[3], fields[4]
+
+	var parts []string
+	m, minuteErr := strconv.Atoi(minute)
+	h, hourErr := strconv.Atoi(hour)
+	switch {
+	case minuteErr == nil && hourErr == nil:
+		parts = append(parts, fmt.Sprintf("At %02d:%02d", h, m))
+	case minute == "*" && hour == "*":
+		parts = append(parts, "Every minute")
+	case strings.HasPrefix(minute, "*/") && hour == "*":
+		parts = append(parts, "Every "+minute[2:]+" minutes")
+	case minute == "*":
+		parts = append(parts, "Every minute", "during hour "+describeCronField(hour, nil))
+	case hour == "*":
+		parts = append(parts, "At minute "+describeCronField(minute, nil)+" of every hour")
+	default:
+		parts = append(parts, "At minute "+describeCronField(minute, nil), "hour "+describeCronField(hour, nil))
+	}
+
+	if dom != "*" && dom != "?" {
+		parts = append(parts, "on day "+describeCronField(dom, nil)+" of the month")
+	}
+	if dow != "*" && dow != "?" {
+		if dom != "*" && dom != "?" {
+			// cron runs when either the day of month or the day of week matches
+			parts = append(parts, "or on "+describeCronField(dow, cronDayNames))
+		} else {
+			parts = append(parts, describeCronField(dow, cronDayNames))
+		}
+	}
+	if month != "*" && month != "?" {
+		parts = append(parts, "in "+describeCronField(month, cronMonthNames))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// describeCronWithSeconds is DescribeCron for a 6-field expression whose first field is the
+// second, such as "At second 30, at 09:00"
+func describeCronWithSeconds(cronExpr string) string {
+	fields := strings.Fields(cronExpr)
+	if len(fields) != 6 {
+		return DescribeCron(cronExpr) // a descriptor
+	}
+	second, rest := fields[0], DescribeCron(strings.Join(fields[1:], " "))
+
+	var prefix string
+	switch {
+	case second == "0":
+		return rest
+	case second == "*":
+		prefix = "Every second"
+	case strings.HasPrefix(second, "*/"):
+		prefix = "Every " + second[2:] + " seconds"
+	default:
+		prefix = "At second " + describeCronField(second, nil)
+	}
+	if rest == "Every minute" {
+		return prefix
+	}
+	return prefix + ", " + strings.ToLower(rest[:1]) + rest[1:]
+}
+
+// describeCronField describes a comma-separated cron field of values, ranges and steps,
+// naming values through names when given
+func describeCronField(field string, names []string) string {
+	name := func(v string) string {
+		if i, err := strconv.Atoi(v); err == nil && names != nil && i >= 0 && i < len(names) {
+			return names[i]
+		}
+		if names != nil {
+			// Named values such as MON or JAN
+			for _, n := range names {
+				if len(n) >= 3 && strings.EqualFold(n[:3], v) {
+					return n
+				}
+			}
+		}
+		return v
+	}
+
+	var items []string
+	for _, item := range strings.Split(field, ",") {
+		base, step, hasStep := strings.Cut(item, "/")
+		from, to, isRange := strings.Cut(base, "-")
+		switch {
+		case hasStep && base == "*":
+			items = append(items, "every "+step)
+		case hasStep && isRange:
+			items = append(items, "every "+step+" from "+name(from)+" through "+name(to))
+		case hasStep:
+			items = append(items, "every "+step+" starting at "+name(base))
+		case isRange:
+			items = append(items, name(from)+" through "+name(to))
+		default:
+			items = append(items, name(base))
+		}
+	}
+	return strings.Join(items, " and ")
+}
@@ -0,0 +1,346 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mitsume/backend/internal/models"
+)
+
+func TestDescribeCron(t *testing.T) {
+	tests := []struct {
+		cron string
+		want string
+	}{
+		{"0 9 * * *", "At 09:00"},
+		{"30 8 * * 1-5", "At 08:30, Monday through Friday"},
+		{"0 9 * * MON,FRI", "At 09:00, Monday and Friday"},
+		{"0 0 1 * *", "At 00:00, on day 1 of the month"},
+		{"0 6 1 1,7 *", "At 06:00, on day 1 of the month, in January and July"},
+		{"*/15 * * * *", "Every 15 minutes"},
+		{"5 * * * *", "At minute 5 of every hour"},
+		{"0 9-17/2 * * *", "At minute 0, hour every 2 from 9 through 17"},
+		{"0 9 15 * 0", "At 09:00, on day 15 of the month, or on Sunday"},
+		{"@daily", "At 00:00"},
+		{"@hourly", "At minute 0 of every hour"},
+		{"@weekly", "At 00:00, Sunday"},
+		{"@yearly", "At 00:00, on day 1 of the month, in January"},
+		{"@every 90m", "Every 90m"},
+		{"30 0 9 * * 1-5", "At second 30, at 09:00, Monday through Friday"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cron, func(t *testing.T) {
+			if got := DescribeCron(tt.cron); got != tt.want {
+				t.Fatalf("DescribeCron(%q) = %q, want %q", tt.cron, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviewSchedule(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	s := &SubscriptionService{}
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, tokyo) // a Friday
+
+	preview, err := s.PreviewSchedule("0 9 * * 1-5", "", now)
+	if err != nil {
+		t.Fatalf("PreviewSchedule() error = %v", err)
+	}
+	if preview.Timezone != "Asia/Tokyo" {
+		t.Errorf("PreviewSchedule() timezone = %q, want the Asia/Tokyo default", preview.Timezone)
+	}
+	want := []time.Time{
+		time.Date(2024, 3, 4, 9, 0, 0, 0, tokyo),
+		time.Date(2024, 3, 5, 9, 0, 0, 0, tokyo),
+		time.Date(2024, 3, 6, 9, 0, 0, 0, tokyo),
+		time.Date(2024, 3, 7, 9, 0, 0, 0, tokyo),
+		time.Date(2024, 3, 8, 9, 0, 0, 0, tokyo),
+	}
+	if len(preview.NextRuns) != len(want) {
+		t.Fatalf("PreviewSchedule() runs = %v, want %v", preview.NextRuns, want)
+	}
+	for i := range want {
+		if !preview.NextRuns[i].Equal(want[i]) {
+			t.Errorf("run %d = %v, want %v", i, preview.NextRuns[i], want[i])
+		}
+	}
+
+	// An expression that never matches is valid but has no runs
+	preview, err = s.PreviewSchedule("0 9 30 2 *", "UTC", now)
+	if err != nil || len(preview.NextRuns) != 0 {
+		t.Errorf("PreviewSchedule(Feb 30) = %v, %v, want no runs", preview, err)
+	}
+}
+
+func TestPreviewSchedule_RejectsInvalidInput(t *testing.T) {
+	s := &SubscriptionService{}
+
+	tests := []struct {
+		name      string
+		cron      string
+		timezone  string
+		wantField string
+	}{
+		{"bad cron", "0 25 * * *", "UTC", "schedule_cron"},
+		{"six fields", "0 0 9 * * *", "UTC", "schedule_cron"},
+		{"unknown timezone", "0 9 * * *", "Mars/Olympus_Mons", "timezone"},
+		{"server local time", "0 9 * * *", "Local", "timezone"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.PreviewSchedule(tt.cron, tt.timezone, time.Now())
+			var validationErr *models.ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Field != tt.wantField {
+				t.Fatalf("PreviewSchedule() error = %v, want a validation error on %s", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestPreviewScheduleWithSeconds(t *testing.T) {
+	s := &SubscriptionService{}
+	now := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+
+	preview, err := s.PreviewScheduleWithSeconds("30 0 9 * * *", "UTC", now)
+	if err != nil {
+		t.Fatalf("PreviewScheduleWithSeconds() error = %v", err)
+	}
+	if !preview.Valid || !preview.Seconds || preview.Description != "At second 30, at 09:00" {
+		t.Errorf("PreviewScheduleWithSeconds() = %+v, want a valid seconds schedule described at second 30", preview)
+	}
+	if len(preview.NextRuns) == 0 || !preview.NextRuns[0].Equal(time.Date(2024, 3, 4, 9, 0, 30, 0, time.UTC)) {
+		t.Errorf("PreviewScheduleWithSeconds() runs = %v, want 09:00:30 first", preview.NextRuns)
+	}
+
+	// A 5-field expression lacks the seconds field
+	_, err = s.PreviewScheduleWithSeconds("0 9 * * *", "UTC", now)
+	var validationErr *models.ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Field != "schedule_cron" {
+		t.Fatalf("PreviewScheduleWithSeconds(5 fields) error = %v, want a validation error on schedule_cron", err)
+	}
+}
+
+func TestDescribeCronWithSeconds(t *testing.T) {
+	tests := []struct {
+		cron string
+		want string
+	}{
+		{"0 0 9 * * *", "At 09:00"},
+		{"30 0 9 * * 1-5", "At second 30, at 09:00, Monday through Friday"},
+		{"*/10 * * * * *", "Every 10 seconds"},
+		{"* 0 9 * * *", "Every second, at 09:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cron, func(t *testing.T) {
+			if got := describeCronWithSeconds(tt.cron); got != tt.want {
+				t.Fatalf("describeCronWithSeconds(%q) = %q, want %q", tt.cron, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlagInvalidTimezone(t *testing.T) {
+	tests := []struct {
+		timezone string
+		want     bool
+	}{
+		{"Asia/Tokyo", false},
+		{"America/New_York", false},
+		{"UTC", false},
+		{"Asia/Tokio", true},
+		{"Local", true},
+		{"", true},
+	}
+
+	for _, tt := range tests {
+		sub := &models.DashboardSubscription{Timezone: tt.timezone}
+		flagInvalidTimezone(sub)
+		if sub.TimezoneInvalid != tt.want {
+			t.Errorf("flagInvalidTimezone(%q) TimezoneInvalid = %v, want %v", tt.timezone, sub.TimezoneInvalid, tt.want)
+		}
+	}
+}
+
+func TestCalculateRunAfterNext(t *testing.T) {
+	s := &SubscriptionService{}
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC) // Monday
+	at := func(day, hour int) *time.Time {
+		ts := time.Date(2024, 3, day, hour, 0, 0, 0, time.UTC)
+		return &ts
+	}
+
+	tests := []struct {
+		name      string
+		nextRunAt *time.Time
+		want      time.Time
+	}{
+		{"skips the upcoming run", at(5, 9), *at(6, 9)},
+		{"no next run yet", nil, *at(6, 9)},
+		{"overdue next run", at(4, 9), *at(6, 9)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.calculateRunAfterNext("0 9 * * *", "UTC", tt.nextRunAt, now)
+			if err != nil {
+				t.Fatalf("calculateRunAfterNext() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("calculateRunAfterNext() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSubscriptionSchedule(t *testing.T) {
+	minutes := func(n int) *int { return &n }
+
+	tests := []struct {
+		name      string
+		cron      string
+		interval  *int
+		timezone  string
+		wantField string // empty when the schedule is valid
+	}{
+		{"cron", "0 9 * * *", nil, "UTC", ""},
+		{"interval", "", minutes(360), "UTC", ""},
+		{"neither", "", nil, "UTC", "schedule_cron"},
+		{"both", "0 9 * * *", minutes(60), "UTC", "schedule_interval_minutes"},
+		{"zero interval", "", minutes(0), "UTC", "schedule_interval_minutes"},
+		{"interval over a week", "", minutes(maxScheduleIntervalMinutes + 1), "UTC", "schedule_interval_minutes"},
+		{"interval with unknown timezone", "", minutes(60), "Mars/Olympus_Mons", "timezone"},
+		{"bad cron", "0 25 * * *", nil, "UTC", "schedule_cron"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSubscriptionSchedule(tt.cron, tt.interval, tt.timezone)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("validateSubscriptionSchedule() error = %v", err)
+				}
+				return
+			}
+			var validationErr *models.ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Field != tt.wantField {
+				t.Fatalf("validateSubscriptionSchedule() error = %v, want a validation error on %s", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestNextIntervalRun(t *testing.T) {
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) *time.Time {
+		ts := time.Date(2024, 3, 4, hour, minute, 0, 0, time.UTC)
+		return &ts
+	}
+
+	tests := []struct {
+		name string
+		due  *time.Time
+		want time.Time
+	}{
+		{"no due run", nil, *at(16, 0)},
+		{"counts from the due run", at(9, 58), *at(15, 58)},
+		{"skips missed runs", at(0, 30), *at(12, 30)},
+		{"due run still ahead", at(11, 0), *at(16, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextIntervalRun(6*time.Hour, tt.due, now); !got.Equal(tt.want) {
+				t.Errorf("nextIntervalRun() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntervalSubscriptionRuns(t *testing.T) {
+	s := &SubscriptionService{}
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	interval := 90
+	next := now.Add(30 * time.Minute)
+	sub := &models.DashboardSubscription{ScheduleIntervalMinutes: &interval, Timezone: "UTC", NextRunAt: &next}
+
+	got, err := s.nextRunOf(sub, now)
+	if err != nil {
+		t.Fatalf("nextRunOf() error = %v", err)
+	}
+	if want := now.Add(90 * time.Minute); !got.Equal(want) {
+		t.Errorf("nextRunOf() = %v, want %v", got, want)
+	}
+
+	got, err = s.runAfterNextOf(sub, now)
+	if err != nil {
+		t.Fatalf("runAfterNextOf() error = %v", err)
+	}
+	if want := next.Add(90 * time.Minute); !got.Equal(want) {
+		t.Errorf("runAfterNextOf() = %v, want %v", got, want)
+	}
+}
+
+func TestDescribeInterval(t *testing.T) {
+	tests := map[int]string{
+		1:    "Every minute",
+		15:   "Every 15 minutes",
+		60:   "Every hour",
+		360:  "Every 6 hours",
+		90:   "Every 90 minutes",
+		1440: "Every day",
+		4320: "Every 3 days",
+	}
+	for minutes, want := range tests {
+		if got := DescribeInterval(minutes); got != want {
+			t.Errorf("DescribeInterval(%d) = %q, want %q", minutes, got, want)
+		}
+	}
+}
+
+func TestSubscriptionCronExpressions(t *testing.T) {
+	s := &SubscriptionService{}
+	now := time.Date(2024, 3, 4, 9, 10, 0, 0, time.UTC) // a Monday
+
+	tests := []struct {
+		cron string
+		want time.Time
+	}{
+		{"0 9 * * 1-5", time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)},
+		{"30 0 9 * * 1-5", time.Date(2024, 3, 5, 9, 0, 30, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"@every 90m", time.Date(2024, 3, 4, 10, 40, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cron, func(t *testing.T) {
+			// Whatever create and update accept, the scheduler can schedule
+			if err := validateSubscriptionSchedule(tt.cron, nil, "UTC"); err != nil {
+				t.Fatalf("validateSubscriptionSchedule(%q) error = %v", tt.cron, err)
+			}
+			got, err := s.calculateNextRunAfter(tt.cron, "UTC", now)
+			if err != nil {
+				t.Fatalf("calculateNextRunAfter(%q) error = %v", tt.cron, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("calculateNextRunAfter(%q) = %v, want %v", tt.cron, got, tt.want)
+			}
+		})
+	}
+
+	for _, cron := range []string{"@fortnightly", "0 0 0 9 * * *", "61 0 9 * * *"} {
+		err := validateSubscriptionSchedule(cron, nil, "UTC")
+		var validationErr *models.ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Field != "schedule_cron" {
+			t.Errorf("validateSubscriptionSchedule(%q) error = %v, want a validation error on schedule_cron", cron, err)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/models"
+)
+
+func TestSubscriptionRunStatus(t *testing.T) {
+	sent := models.SubscriptionChannelResult{ChannelID: uuid.New(), ChannelName: "ops", Status: models.SubscriptionRunSent}
+	failed := models.SubscriptionChannelResult{ChannelID: uuid.New(), ChannelName: "sales", Status: models.SubscriptionRunFailed}
+	deliveryErr := &ChannelDeliveryError{
+		Failures: map[uuid.UUID]error{failed.ChannelID: errors.New("webhook returned 500")},
+		Names:    map[uuid.UUID]string{failed.ChannelID: "sales"},
+		Total:    2,
+	}
+
+	tests := []struct {
+		name       string
+		results    []models.SubscriptionChannelResult
+		runErr     error
+		wantStatus string
+	}{
+		{"all channels sent", []models.SubscriptionChannelResult{sent, sent}, nil, models.SubscriptionRunSent},
+		{"some channels failed", []models.SubscriptionChannelResult{sent, failed}, deliveryErr, models.SubscriptionRunPartial},
+		{"every channel failed", []models.SubscriptionChannelResult{failed}, deliveryErr, models.SubscriptionRunFailed},
+		{"failed before sending", nil, errors.New("no notification channels configured"), models.SubscriptionRunFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, errMsg := subscriptionRunStatus(tt.results, tt.runErr)
+			if status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", status, tt.wantStatus)
+			}
+			switch {
+			case tt.runErr == nil && errMsg != nil:
+				t.Errorf("error message = %q, want none", *errMsg)
+			case tt.runErr != nil && (errMsg == nil || *errMsg != tt.runErr.Error()):
+				t.Errorf("error message = %v, want %q", errMsg, tt.runErr.Error())
+			}
+		})
+	}
+}
+
+func TestChannelDeliveryError_NamesEachFailedChannel(t *testing.T) {
+	slack, email := uuid.New(), uuid.New()
+	err := &ChannelDeliveryError{
+		Failures: map[uuid.UUID]error{
+			slack: errors.New("webhook returned 500"),
+			email: errors.New("smtp timeout"),
+		},
+		Names: map[uuid.UUID]string{slack: "team-slack", email: "ops-mail"},
+		Total: 3,
+	}
+
+	want := "failed to send to 2 of 3 channels: ops-mail (" + email.String() + "): smtp timeout; team-slack (" + slack.String() + "): webhook returned 500"
+	if got := err.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	var deliveryErr *ChannelDeliveryError
+	if !errors.As(fmt.Errorf("subscription run: %w", err), &deliveryErr) || len(deliveryErr.Failures) != 2 {
+		t.Error("wrapped ChannelDeliveryError is not reachable with errors.As")
+	}
+}
+
+func TestReachedFailureLimit(t *testing.T) {
+	failed, partial, sent := models.SubscriptionRunFailed, models.SubscriptionRunPartial, models.SubscriptionRunSent
+
+	tests := []struct {
+		name     string
+		statuses []string
+		limit    int
+		want     bool
+	}{
+		{"limit reached", []string{failed, failed, failed}, 3, true},
+		{"older runs are ignored", []string{failed, failed, sent}, 2, true},
+		{"too few runs", []string{failed, failed}, 3, false},
+		{"a sent run breaks the streak", []string{failed, sent, failed}, 3, false},
+		{"a partial run breaks the streak", []string{failed, partial, failed}, 3, false},
+		{"disabled", []string{failed, failed, failed}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reachedFailureLimit(tt.statuses, tt.limit); got != tt.want {
+				t.Errorf("reachedFailureLimit(%v, %d) = %v, want %v", tt.statuses, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecuteSubscription_PausesAfterConsecutiveFailures(t *testing.T) {
+	pool := setupHistoryDatabase(t)
+	ctx := context.Background()
+
+	var userID, dashboardID uuid.UUID
+	err := pool.QueryRow(ctx,
+		`INSERT INTO users (email, name) VALUES ($1, 'failing subscription test') RETURNING id`,
+		fmt.Sprintf("failing-sub-%s@example.com", uuid.NewString()),
+	).Scan(&userID)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() { _, _ = pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID) })
+	if err := pool.QueryRow(ctx,
+		`INSERT INTO dashboards (user_id, name) VALUES ($1, 'd') RETURNING id`, userID,
+	).Scan(&dashboardID); err != nil {
+		t.Fatalf("failed to create dashboard: %v", err)
+	}
+
+	// Without channels every run fails before anything is sent
+	sub := &models.DashboardSubscription{UserID: userID, DashboardID: dashboardID, IsActive: true}
+	if err := pool.QueryRow(ctx,
+		`INSERT INTO dashboard_subscriptions (user_id, dashboard_id, name, schedule_cron) VALUES ($1, $2, 's', '0 9 * * *') RETURNING id`,
+		userID, dashboardID,
+	).Scan(&sub.ID); err != nil {
+		t.Fatalf("failed to create subscription: %v", err)
+	}
+
+	s := NewSubscriptionService(pool, nil, nil)
+	s.SetFailureLimit(2)
+	isActive := func() bool {
+		var active bool
+		if err := pool.QueryRow(ctx, `SELECT is_active FROM dashboard_subscriptions WHERE id = $1`, sub.ID).Scan(&active); err != nil {
+			t.Fatalf("failed to read subscription: %v", err)
+		}
+		return active
+	}
+
+	if err := s.ExecuteSubscription(ctx, sub); err == nil {
+		t.Fatal("ExecuteSubscription() error = nil, want a failure without channels")
+	}
+	if !isActive() {
+		t.Fatal("subscription paused after one failure, want it active until the limit")
+	}
+
+	_ = s.ExecuteSubscription(ctx, sub)
+	if isActive() || sub.IsActive {
+		t.Fatal("subscription still active after 2 consecutive failures, want it paused")
+	}
+
+	history, err := s.GetSubscriptionHistory(ctx, sub.ID, 10)
+	if err != nil {
+		t.Fatalf("GetSubscriptionHistory() error = %v", err)
+	}
+	if len(history) != 2 || history[0].Status != models.SubscriptionRunFailed || history[0].ErrorMessage == nil {
+		t.Fatalf("GetSubscriptionHistory() = %+v, want 2 failed runs with their error", history)
+	}
+}
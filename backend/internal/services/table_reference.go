@@ -0,0 +1,172 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnclassifiedTableReference is returned by TableReferences when a table position of a query
+// holds something it cannot read as a table name or subquery
+var ErrUnclassifiedTableReference = errors.New("query names a table that cannot be classified")
+
+// fromArgumentFunctions lists functions whose arguments use FROM, e.g. EXTRACT(YEAR FROM o.created_at)
+var fromArgumentFunctions = map[string]bool{
+	"extract": true, "substring": true, "trim": true, "overlay": true, "position": true,
+}
+
+// tableReferenceFromListEnd lists keywords that end a comma-separated FROM list. Unlike
+// rowFilterFromListEnd, ON and USING do not: "FROM a JOIN b ON a.id = b.id, c" still lists c.
+var tableReferenceFromListEnd = map[string]bool{
+	"where": true, "group": true, "having": true, "order": true, "limit": true, "offset": true,
+	"fetch": true, "union": true, "intersect": true, "except": true, "window": true, "select": true,
+	"values": true,
+}
+
+// tableReferenceKeywords lists keywords that may be followed by a table name outside a FROM
+// clause: DESCRIBE t, TABLE t, CREATE/ALTER/DROP TABLE t, SHOW CREATE VIEW t, INSERT INTO t,
+// UPDATE t and MERGE INTO t USING s
+var tableReferenceKeywords = map[string]bool{
+	"table": true, "view": true, "into": true, "update": true, "using": true,
+}
+
+// TableReferences returns the names of the tables and views query reads or writes, each as the
+// dotted parts of the name as written (quoted identifiers keep their quotes). Names are taken from
+// FROM and JOIN clauses, every entry of a comma-separated FROM list, parenthesized FROM items, and
+// statements naming a table directly: DESCRIBE, TABLE, SHOW COLUMNS, SHOW STATS FOR, SHOW GRANTS
+// ON, DDL and DML. SHOW TABLES/SCHEMAS FROM name a schema or catalog rather than a table and are
+// not returned.
+// Queries with a FROM list entry that is neither a name nor a subquery, or that cannot be
+// tokenized, fail with ErrUnclassifiedTableReference rather than being let through unchecked.
+func TableReferences(query string) ([][]string, error) {
+	tokens, ok := tokenizeSQL(query)
+	if !ok {
+		return nil, fmt.Errorf("%w: the query could not be parsed", ErrUnclassifiedTableReference)
+	}
+	var sig []int
+	for i, t := range tokens {
+		if t.kind != sqlSpace && t.kind != sqlComment {
+			sig = append(sig, i)
+		}
+	}
+	tok := func(k int) sqlToken {
+		if k < 0 || k >= len(sig) {
+			return sqlToken{}
+		}
+		return tokens[sig[k]]
+	}
+	isPunct := func(k int, p string) bool { return tok(k).kind == sqlPunct && tok(k).text == p }
+	isKeyword := func(k int, kw string) bool { return tok(k).kind == sqlWord && strings.EqualFold(tok(k).text, kw) }
+	isIdent := func(k int) bool { return tok(k).kind == sqlWord || tok(k).kind == sqlQuoted }
+	statementStart := func(k int) bool { return k == 0 || isPunct(k-1, ";") }
+
+	var refs [][]string
+
+	// nameAt reads the table name at significant token k, skipping opening parentheses and
+	// IF [NOT] EXISTS. It reports false when k holds no name.
+	nameAt := func(k int) ([]string, int, bool) {
+		for isPunct(k, "(") {
+			k++
+		}
+		if isKeyword(k, "if") {
+			k++
+			if isKeyword(k, "not") {
+				k++
+			}
+			if isKeyword(k, "exists") {
+				k++
+			}
+		}
+		if !isIdent(k) {
+			return nil, k, false
+		}
+		parts := []string{tok(k).text}
+		for isPunct(k+1, ".") && isIdent(k+2) {
+			k += 2
+			parts = append(parts, tok(k).text)
+		}
+		return parts, k, true
+	}
+
+	// tableRefAt records the table reference in a FROM or JOIN position at significant token k.
+	// Subqueries, UNNEST, LATERAL and table functions are left to the main loop, which sees
+	// their FROM clauses and arguments.
+	tableRefAt := func(k int) error {
+		for isPunct(k, "(") {
+			k++
+		}
+		for _, kw := range []string{"select", "with", "values", "lateral", "unnest", "table"} {
+			if isKeyword(k, kw) {
+				return nil
+			}
+		}
+		parts, last, ok := nameAt(k)
+		if !ok {
+			return fmt.Errorf("%w: unexpected %q after FROM or JOIN", ErrUnclassifiedTableReference, tok(k).text)
+		}
+		if isPunct(last+1, ".") || len(parts) > 3 {
+			return fmt.Errorf("%w: %s is not a table name", ErrUnclassifiedTableReference, strings.Join(parts, "."))
+		}
+		refs = append(refs, parts)
+		return nil
+	}
+
+	// namedRefAt records the name following a keyword such as DESCRIBE or TABLE; those keywords
+	// may also be column names, so anything else is not an error
+	namedRefAt := func(k int) {
+		if parts, last, ok := nameAt(k); ok && !isPunct(last+1, ".") {
+			refs = append(refs, parts)
+		}
+	}
+
+	fromList := map[int]bool{} // whether commas at a depth separate FROM list entries
+	funcArgs := map[int]bool{} // whether parentheses at a depth hold arguments of a fromArgumentFunctions call
+	depth := 0
+	for k := 0; k < len(sig); k++ {
+		var err error
+		switch {
+		case isPunct(k, "("):
+			depth++
+			// A parenthesized FROM item may hold a join or another FROM list: FROM (a JOIN b)
+			fromList[depth] = isKeyword(k-1, "from") && !funcArgs[depth-1] || isKeyword(k-1, "join") ||
+				(isPunct(k-1, ",") || isPunct(k-1, "(")) && fromList[depth-1]
+			funcArgs[depth] = tok(k-1).kind == sqlWord && fromArgumentFunctions[strings.ToLower(tok(k-1).text)]
+		case isPunct(k, ")"):
+			fromList[depth] = false
+			funcArgs[depth] = false
+			depth--
+		case isPunct(k, ";"):
+			fromList[depth] = false
+		case isKeyword(k, "from"):
+			switch {
+			case funcArgs[depth] || isKeyword(k-1, "distinct"):
+				// EXTRACT(YEAR FROM ts), a IS DISTINCT FROM b
+			case isKeyword(k-1, "tables") || isKeyword(k-1, "schemas") || isKeyword(k-1, "functions"):
+				// SHOW TABLES FROM schema names no table
+			default:
+				fromList[depth] = true
+				err = tableRefAt(k + 1)
+			}
+		case isKeyword(k, "in") && isKeyword(k-1, "columns"):
+			err = tableRefAt(k + 1)
+		case isKeyword(k, "join"):
+			err = tableRefAt(k + 1)
+		case isPunct(k, ",") && fromList[depth]:
+			err = tableRefAt(k + 1)
+		case (isKeyword(k, "describe") || isKeyword(k, "desc")) && statementStart(k):
+			if !isKeyword(k+1, "input") && !isKeyword(k+1, "output") {
+				namedRefAt(k + 1)
+			}
+		case isKeyword(k, "for") && isKeyword(k-1, "stats") || isKeyword(k, "on") && isKeyword(k-1, "grants"):
+			namedRefAt(k + 1)
+		case tok(k).kind == sqlWord && tableReferenceKeywords[strings.ToLower(tok(k).text)]:
+			namedRefAt(k + 1)
+		case tok(k).kind == sqlWord && tableReferenceFromListEnd[strings.ToLower(tok(k).text)]:
+			fromList[depth] = false
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return refs, nil
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTableReferences(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  [][]string
+	}{
+		{"from and join", "SELECT * FROM a.b x JOIN c d ON x.id = d.id", [][]string{{"a", "b"}, {"c"}}},
+		{"from list", "SELECT * FROM a, b.c, (d)", [][]string{{"a"}, {"b", "c"}, {"d"}}},
+		{"subquery", "SELECT * FROM (SELECT x.a, x.b FROM s.t x) y", [][]string{{"s", "t"}}},
+		{"quoted identifiers keep their quotes", `SELECT * FROM "Hr"."Salaries"`, [][]string{{`"Hr"`, `"Salaries"`}}},
+		{"describe", "DESCRIBE s.t", [][]string{{"s", "t"}}},
+		{"table", "TABLE s.t", [][]string{{"s", "t"}}},
+		{"show stats", "SHOW STATS FOR s.t", [][]string{{"s", "t"}}},
+		{"insert", "INSERT INTO s.t SELECT * FROM u", [][]string{{"s", "t"}, {"u"}}},
+		{"drop if exists", "DROP TABLE IF EXISTS s.t", [][]string{{"s", "t"}}},
+		{"function arguments", "SELECT EXTRACT(YEAR FROM x.d), SUBSTRING(x.s FROM 2 FOR 3) FROM t x", [][]string{{"t"}}},
+		{"show tables names no table", "SHOW TABLES FROM hive.sales", nil},
+		{"order by desc is not describe", "SELECT a FROM t ORDER BY a DESC, b", [][]string{{"t"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TableReferences(tt.query)
+			if err != nil {
+				t.Fatalf("TableReferences() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("TableReferences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTableReferences_Unclassified(t *testing.T) {
+	for _, query := range []string{
+		"SELECT * FROM a.b.c.d",
+		"SELECT * FROM 'a.b'",
+		"SELECT * FROM t WHERE s = 'unterminated",
+	} {
+		if _, err := TableReferences(query); !errors.Is(err, ErrUnclassifiedTableReference) {
+			t.Errorf("TableReferences(%q) error = %v, want ErrUnclassifiedTableReference", query, err)
+		}
+	}
+}
@@ -5,13 +5,16 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"net/url"
 	"regexp"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/mitsume/backend/internal/config"
+	"github.com/mitsume/backend/internal/metrics"
 	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/repository"
 
 	_ "github.com/trinodb/trino-go-client/trino"
 )
@@ -37,8 +40,28 @@ func (s *TrinoService) getConnectionString(catalog, schema string) string {
 }
 
 func (s *TrinoService) ExecuteQuery(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error) {
-	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	startTime := time.Now()
+	result, err := s.executeQuery(ctx, query, catalog, schema)
+
+	metricsCatalog := catalog
+	if metricsCatalog == "" {
+		metricsCatalog = s.cfg.Catalog
+	}
+	metrics.ObserveQuery(metricsCatalog, time.Since(startTime), err)
+
+	return result, err
+}
+
+// executeQuery runs a query with the timeout set by WithQueryTimeout, or QUERY_TIMEOUT_SECONDS.
+// A query that runs out of time fails with *QueryTimeoutError.
+func (s *TrinoService) executeQuery(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error) {
+	timeout, ok := QueryTimeoutFrom(ctx)
+	if !ok {
+		timeout = NewQueryTimeoutPolicy(s.cfg).Default
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
+	timedOut := func() bool { return errors.Is(ctx.Err(), context.DeadlineExceeded) }
 
 	startTime := time.Now()
 
@@ -50,6 +73,9 @@ func (s *TrinoService) ExecuteQuery(ctx context.Context, query, catalog, schema
 
 	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
+		if timedOut() {
+			return nil, &QueryTimeoutError{Timeout: timeout}
+		}
 		return nil, fmt.Errorf("query execution failed: %w", err)
 	}
 	defer rows.Close()
@@ -59,7 +85,7 @@ func (s *TrinoService) ExecuteQuery(ctx context.Context, query, catalog, schema
 		return nil, fmt.Errorf("failed to get columns: %w", err)
 	}
 
-	var result [][]interface{}
+	result := [][]interface{}{} // an empty result serializes as "rows": [] rather than null
 
 	for rows.Next() {
 		values := make([]interface{}, len(columns))
@@ -69,6 +95,9 @@ func (s *TrinoService) ExecuteQuery(ctx context.Context, query, catalog, schema
 		}
 
 		if err := rows.Scan(valuePtrs...); err != nil {
+			if timedOut() {
+				return nil, &QueryTimeoutError{Timeout: timeout}
+			}
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 
@@ -80,6 +109,9 @@ func (s *TrinoService) ExecuteQuery(ctx context.Context, query, catalog, schema
 	}
 
 	if err := rows.Err(); err != nil {
+		if timedOut() {
+			return nil, &QueryTimeoutError{Timeout: timeout}
+		}
 		return nil, fmt.Errorf("row iteration error: %w", err)
 	}
 
@@ -249,6 +281,89 @@ func (s *TrinoService) GetColumns(ctx context.Context, catalog, schema, table st
 	return columns, nil
 }
 
+// GetTableSample returns up to limit rows from the specified table
+func (s *TrinoService) GetTableSample(ctx context.Context, catalog, schema, table string, limit int) (*models.QueryResult, error) {
+	if err := validateIdentifier(catalog); err != nil {
+		return nil, err
+	}
+	if err := validateIdentifier(schema); err != nil {
+		return nil, err
+	}
+	if err := validateIdentifier(table); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		return nil, errors.New("limit must be positive")
+	}
+
+	query := fmt.Sprintf("SELECT * FROM %s.%s.%s LIMIT %d",
+		quoteIdentifier(catalog), quoteIdentifier(schema), quoteIdentifier(table), limit)
+
+	return s.ExecuteQuery(ctx, query, catalog, schema)
+}
+
+// ErrDDLNotSupported is returned when neither SHOW CREATE TABLE nor SHOW CREATE VIEW succeeds,
+// typically because the connector does not support it
+var ErrDDLNotSupported = errors.New("DDL preview is not supported for this object or connector")
+
+// GetTableDDL returns the CREATE statement for a table, falling back to SHOW CREATE VIEW
+// when the object is a view
+func GetTableDDL(ctx context.Context, executor repository.TrinoExecutor, catalog, schema, table string) (*models.TableDDL, error) {
+	if err := validateIdentifier(catalog); err != nil {
+		return nil, err
+	}
+	if err := validateIdentifier(schema); err != nil {
+		return nil, err
+	}
+	if err := validateIdentifier(table); err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("%s.%s.%s", quoteIdentifier(catalog), quoteIdentifier(schema), quoteIdentifier(table))
+	ddl := &models.TableDDL{Catalog: catalog, Schema: schema, Table: table}
+
+	text, tableErr := showCreate(ctx, executor, "SHOW CREATE TABLE "+name, catalog, schema)
+	if tableErr == nil {
+		ddl.Type = "table"
+		ddl.DDL = text
+		return ddl, nil
+	}
+
+	text, err := showCreate(ctx, executor, "SHOW CREATE VIEW "+name, catalog, schema)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDDLNotSupported, tableErr)
+	}
+	ddl.Type = "view"
+	ddl.DDL = text
+	return ddl, nil
+}
+
+func showCreate(ctx context.Context, executor repository.TrinoExecutor, query, catalog, schema string) (string, error) {
+	result, err := executor.ExecuteQuery(ctx, query, catalog, schema)
+	if err != nil {
+		return "", err
+	}
+	if len(result.Rows) == 0 || len(result.Rows[0]) == 0 {
+		return "", errors.New("empty result")
+	}
+	text, ok := result.Rows[0][0].(string)
+	if !ok {
+		return "", errors.New("unexpected result type")
+	}
+	return text, nil
+}
+
+// ValidateQuery checks a query with EXPLAIN (TYPE VALIDATE), which parses and analyzes it
+// against the catalog without running it. The returned error is Trino's validation error.
+func ValidateQuery(ctx context.Context, executor repository.TrinoExecutor, query, catalog, schema string) error {
+	query = strings.TrimRight(strings.TrimSpace(query), "; \t\r\n")
+	if query == "" {
+		return errors.New("query is empty")
+	}
+	_, err := executor.ExecuteQuery(ctx, "EXPLAIN (TYPE VALIDATE) "+query, catalog, schema)
+	return err
+}
+
 func (s *TrinoService) SearchMetadata(ctx context.Context, query, searchType string, catalogs []string, limit int) ([]models.MetadataSearchResult, error) {
 	if query == "" {
 		return []models.MetadataSearchResult{}, nil
@@ -397,6 +512,34 @@ func (s *TrinoService) getDB(dsn string) (*sql.DB, error) {
 	return db, nil
 }
 
+// Stats returns the connection pool statistics of each Trino connection opened so far, keyed by
+// its DSN with the user and host masked
+func (s *TrinoService) Stats() map[string]sql.DBStats {
+	stats := make(map[string]sql.DBStats)
+	s.dbs.Range(func(dsn, db any) bool {
+		stats[maskDSN(dsn.(string))] = db.(*sql.DB).Stats()
+		return true
+	})
+	return stats
+}
+
+// maskDSN hides the user and host of a Trino DSN, keeping the port, catalog and schema
+func maskDSN(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "***"
+	}
+	host := "***"
+	if port := u.Port(); port != "" {
+		host += ":" + port
+	}
+	masked := u.Scheme + "://***@" + host
+	if u.RawQuery != "" {
+		masked += "?" + u.RawQuery
+	}
+	return masked
+}
+
 // identifierPattern allows common characters in Trino identifiers
 // Includes letters, digits, underscores, hyphens, and dollar signs
 // More exotic characters will be handled by quoting in SQL
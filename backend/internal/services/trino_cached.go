@@ -2,51 +2,130 @@ package services
 
 import (
 	"context"
+	"log"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/mitsume/backend/internal/config"
+	"github.com/mitsume/backend/internal/metrics"
 	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/repository"
 )
 
+// resultCache is the part of QueryCacheService used to cache query results
+type resultCache interface {
+	GetFresh(ctx context.Context, key string, maxStaleness time.Duration) (*models.QueryResult, bool)
+	GetCachedAt(ctx context.Context, key string, maxStaleness time.Duration) (time.Time, bool)
+	Set(ctx context.Context, key string, result *models.QueryResult, priority CachePriority)
+	RegisterSavedQueryCache(ctx context.Context, savedQueryID uuid.UUID, cacheKey string) error
+}
+
 // CachedTrinoService wraps TrinoService with caching capability
 type CachedTrinoService struct {
-	trino *TrinoService
-	cache *QueryCacheService // nil if caching is disabled
+	trino repository.TrinoExecutor
+	cache resultCache // nil if caching is disabled
 	cfg   *config.CacheConfig
 }
 
 // NewCachedTrinoService creates a new cached Trino service
 func NewCachedTrinoService(trino *TrinoService, cache *QueryCacheService, cfg *config.CacheConfig) *CachedTrinoService {
-	return &CachedTrinoService{
+	s := &CachedTrinoService{
 		trino: trino,
-		cache: cache,
 		cfg:   cfg,
 	}
+	if cache != nil {
+		s.cache = cache
+	}
+	return s
 }
 
 // ExecuteQueryWithCache executes a query with caching support
-// If caching is disabled or cache is nil, it falls back to direct execution
-// priority: 1=Low (ad-hoc), 2=Normal (widget), 3=High (scheduled)
+// If caching is disabled, cache is nil or the priority's TTL is 0, it falls back to direct execution
+// priority: 1=Low (ad-hoc), 2=Normal (widget), 3=High (scheduled); see CACHE_TTL_*_SECONDS
 func (s *CachedTrinoService) ExecuteQueryWithCache(
 	ctx context.Context,
 	query, catalog, schema string,
 	priority int,
 	savedQueryID *uuid.UUID,
+) (*models.QueryResult, error) {
+	return s.ExecuteQueryWithMaxStaleness(ctx, query, catalog, schema, priority, savedQueryID, 0)
+}
+
+// ExecuteQueryWithMaxStaleness is ExecuteQueryWithCache, but a cached result older than
+// maxStaleness is re-computed even if it is still within its TTL (0 means no limit)
+func (s *CachedTrinoService) ExecuteQueryWithMaxStaleness(
+	ctx context.Context,
+	query, catalog, schema string,
+	priority int,
+	savedQueryID *uuid.UUID,
+	maxStaleness time.Duration,
 ) (*models.QueryResult, error) {
 	// If caching is disabled, execute directly
-	if s.cache == nil {
+	if s.cache == nil || CachePriority(priority).TTL(s.cfg) <= 0 {
 		return s.trino.ExecuteQuery(ctx, query, catalog, schema)
 	}
 
-	// Generate cache key
-	key := GenerateCacheKey(s.cfg.KeyPrefix, query, catalog, schema, nil)
+	// The key covers the resolved query text, so each set of parameter values is cached separately
+	key := GenerateCacheKey(s.cfg.KeyPrefix, query, catalog, schema)
 
 	// Check cache
-	if result, ok := s.cache.Get(ctx, key); ok {
+	if result, ok := s.cache.GetFresh(ctx, key, maxStaleness); ok {
+		metrics.ObserveCacheLookup(true)
 		return result, nil // Cache hit
 	}
+	metrics.ObserveCacheLookup(false)
 
 	// Cache miss - execute query
+	return s.executeAndCache(ctx, key, query, catalog, schema, priority, savedQueryID)
+}
+
+// RefreshQuery executes a query without consulting the cache and stores the result in its place,
+// so later cached reads see the fresh result. Without a cache it is a direct execution.
+func (s *CachedTrinoService) RefreshQuery(
+	ctx context.Context,
+	query, catalog, schema string,
+	priority int,
+	savedQueryID *uuid.UUID,
+) (*models.QueryResult, error) {
+	if s.cache == nil || CachePriority(priority).TTL(s.cfg) <= 0 {
+		return s.trino.ExecuteQuery(ctx, query, catalog, schema)
+	}
+	return s.executeAndCache(ctx, GenerateCacheKey(s.cfg.KeyPrefix, query, catalog, schema), query, catalog, schema, priority, savedQueryID)
+}
+
+// CachedResultTime reports when the cached result of a query was computed and when it stops being
+// served (its TTL or maxStaleness, whichever comes first), without executing the query. ok is
+// false if caching is disabled for the priority or no fresh result is cached.
+func (s *CachedTrinoService) CachedResultTime(
+	ctx context.Context,
+	query, catalog, schema string,
+	priority int,
+	maxStaleness time.Duration,
+) (cachedAt, expiresAt time.Time, ok bool) {
+	ttl := CachePriority(priority).TTL(s.cfg)
+	if s.cache == nil || ttl <= 0 {
+		return time.Time{}, time.Time{}, false
+	}
+
+	cachedAt, ok = s.cache.GetCachedAt(ctx, GenerateCacheKey(s.cfg.KeyPrefix, query, catalog, schema), maxStaleness)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+
+	lifetime := ttl
+	if maxStaleness > 0 && maxStaleness < lifetime {
+		lifetime = maxStaleness
+	}
+	return cachedAt, cachedAt.Add(lifetime), true
+}
+
+// executeAndCache executes a query and stores its result under key
+func (s *CachedTrinoService) executeAndCache(
+	ctx context.Context,
+	key, query, catalog, schema string,
+	priority int,
+	savedQueryID *uuid.UUID,
+) (*models.QueryResult, error) {
 	result, err := s.trino.ExecuteQuery(ctx, query, catalog, schema)
 	if err != nil {
 		return nil, err
@@ -55,11 +134,13 @@ func (s *CachedTrinoService) ExecuteQueryWithCache(
 	// Store in cache (convert int to CachePriority)
 	s.cache.Set(ctx, key, result, CachePriority(priority))
 
-	// Register association with saved query (for invalidation)
+	// Register association with saved query (for invalidation). Every parameter variant of
+	// the query is registered, so InvalidateSavedQueryCaches clears them all.
 	if savedQueryID != nil {
 		if regErr := s.cache.RegisterSavedQueryCache(ctx, *savedQueryID, key); regErr != nil {
 			// Log but don't fail the request
 			// The cache will still work, just won't be invalidated on query update
+			log.Printf("Failed to register cache key for saved query %s: %v", *savedQueryID, regErr)
 		}
 	}
 
@@ -91,6 +172,11 @@ func (s *CachedTrinoService) GetColumns(ctx context.Context, catalog, schema, ta
 	return s.trino.GetColumns(ctx, catalog, schema, table)
 }
 
+// GetTableSample delegates to the underlying Trino service
+func (s *CachedTrinoService) GetTableSample(ctx context.Context, catalog, schema, table string, limit int) (*models.QueryResult, error) {
+	return s.trino.GetTableSample(ctx, catalog, schema, table, limit)
+}
+
 // SearchMetadata delegates to the underlying Trino service
 func (s *CachedTrinoService) SearchMetadata(ctx context.Context, query, searchType string, catalogs []string, limit int) ([]models.MetadataSearchResult, error) {
 	return s.trino.SearchMetadata(ctx, query, searchType, catalogs, limit)
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mitsume/backend/internal/config"
+	"github.com/mitsume/backend/internal/models"
+	"github.com/mitsume/backend/internal/repository"
+)
+
+// fakeResultCache keeps results and saved query key sets in memory, like QueryCacheService does in Redis
+type fakeResultCache struct {
+	results   map[string]*models.QueryResult
+	cachedAt  map[string]time.Time
+	savedKeys map[uuid.UUID]map[string]bool
+}
+
+func newFakeResultCache() *fakeResultCache {
+	return &fakeResultCache{
+		results:   make(map[string]*models.QueryResult),
+		cachedAt:  make(map[string]time.Time),
+		savedKeys: make(map[uuid.UUID]map[string]bool),
+	}
+}
+
+func (f *fakeResultCache) GetFresh(ctx context.Context, key string, maxStaleness time.Duration) (*models.QueryResult, bool) {
+	result, ok := f.results[key]
+	return result, ok
+}
+
+func (f *fakeResultCache) GetCachedAt(ctx context.Context, key string, maxStaleness time.Duration) (time.Time, bool) {
+	cachedAt, ok := f.cachedAt[key]
+	return cachedAt, ok
+}
+
+func (f *fakeResultCache) Set(ctx context.Context, key string, result *models.QueryResult, priority CachePriority) {
+	f.results[key] = result
+	f.cachedAt[key] = time.Now()
+}
+
+func (f *fakeResultCache) RegisterSavedQueryCache(ctx context.Context, savedQueryID uuid.UUID, cacheKey string) error {
+	if f.savedKeys[savedQueryID] == nil {
+		f.savedKeys[savedQueryID] = make(map[string]bool)
+	}
+	f.savedKeys[savedQueryID][cacheKey] = true
+	return nil
+}
+
+// invalidate mirrors QueryCacheService.InvalidateSavedQueryCaches: it deletes every registered key
+func (f *fakeResultCache) invalidate(savedQueryID uuid.UUID) {
+	for key := range f.savedKeys[savedQueryID] {
+		delete(f.results, key)
+		delete(f.cachedAt, key)
+	}
+	delete(f.savedKeys, savedQueryID)
+}
+
+func TestCachedTrinoService_CachesParameterVariantsSeparately(t *testing.T) {
+	ctx := context.Background()
+	trino := repository.NewMockTrinoExecutor()
+	trino.ExecuteQueryFunc = func(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error) {
+		return &models.QueryResult{Columns: []string{"query"}, Rows: [][]interface{}{{query}}, RowCount: 1}, nil
+	}
+	cache := newFakeResultCache()
+	s := &CachedTrinoService{trino: trino, cache: cache, cfg: &config.CacheConfig{KeyPrefix: "test:", TTLNormalSeconds: 600}}
+
+	savedQueryID := uuid.New()
+	emea := "SELECT * FROM orders WHERE region = 'emea'"
+	apac := "SELECT * FROM orders WHERE region = 'apac'"
+
+	run := func(query string) *models.QueryResult {
+		t.Helper()
+		result, err := s.ExecuteQueryWithCache(ctx, query, "hive", "sales", int(CachePriorityNormal), &savedQueryID)
+		if err != nil {
+			t.Fatalf("ExecuteQueryWithCache() error = %v", err)
+		}
+		return result
+	}
+
+	if got := run(emea); got.Rows[0][0] != emea {
+		t.Fatalf("ExecuteQueryWithCache(emea) = %v, want the emea result", got.Rows)
+	}
+	if got := run(apac); got.Rows[0][0] != apac {
+		t.Fatalf("ExecuteQueryWithCache(apac) = %v, want the apac result, not the cached emea one", got.Rows)
+	}
+	run(emea)
+	run(apac)
+	if len(trino.ExecuteQueryCalls) != 2 {
+		t.Fatalf("Trino ran %d queries, want 2 (one per parameter set, then cache hits)", len(trino.ExecuteQueryCalls))
+	}
+
+	if n := len(cache.savedKeys[savedQueryID]); n != 2 {
+		t.Fatalf("saved query has %d registered cache keys, want 2", n)
+	}
+	cache.invalidate(savedQueryID)
+	run(emea)
+	run(apac)
+	if len(trino.ExecuteQueryCalls) != 4 {
+		t.Fatalf("Trino ran %d queries after invalidation, want 4 (both variants re-executed)", len(trino.ExecuteQueryCalls))
+	}
+}
+
+func TestCachedTrinoService_ZeroTTLDisablesCachingForThatPriority(t *testing.T) {
+	ctx := context.Background()
+	trino := repository.NewMockTrinoExecutor()
+	cache := newFakeResultCache()
+	s := &CachedTrinoService{trino: trino, cache: cache, cfg: &config.CacheConfig{KeyPrefix: "test:", TTLHighSeconds: 30, TTLLowSeconds: 0}}
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.ExecuteQueryWithCache(ctx, "SELECT 1", "hive", "sales", int(CachePriorityLow), nil); err != nil {
+			t.Fatalf("ExecuteQueryWithCache() error = %v", err)
+		}
+	}
+	if len(trino.ExecuteQueryCalls) != 2 || len(cache.results) != 0 {
+		t.Fatalf("low priority: Trino ran %d queries and %d results were cached, want 2 and 0", len(trino.ExecuteQueryCalls), len(cache.results))
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.ExecuteQueryWithCache(ctx, "SELECT 1", "hive", "sales", int(CachePriorityHigh), nil); err != nil {
+			t.Fatalf("ExecuteQueryWithCache() error = %v", err)
+		}
+	}
+	if len(trino.ExecuteQueryCalls) != 3 {
+		t.Fatalf("high priority: Trino ran %d queries in total, want 3 (second run is a cache hit)", len(trino.ExecuteQueryCalls))
+	}
+}
+
+func TestCachedTrinoService_RefreshQueryRepopulatesCache(t *testing.T) {
+	ctx := context.Background()
+	trino := repository.NewMockTrinoExecutor()
+	runs := 0
+	trino.ExecuteQueryFunc = func(ctx context.Context, query, catalog, schema string) (*models.QueryResult, error) {
+		runs++
+		return &models.QueryResult{Columns: []string{"run"}, Rows: [][]interface{}{{runs}}, RowCount: 1}, nil
+	}
+	cache := newFakeResultCache()
+	s := &CachedTrinoService{trino: trino, cache: cache, cfg: &config.CacheConfig{KeyPrefix: "test:", TTLNormalSeconds: 600}}
+	savedQueryID := uuid.New()
+
+	if _, err := s.ExecuteQueryWithCache(ctx, "SELECT 1", "hive", "sales", int(CachePriorityNormal), &savedQueryID); err != nil {
+		t.Fatalf("ExecuteQueryWithCache() error = %v", err)
+	}
+	refreshed, err := s.RefreshQuery(ctx, "SELECT 1", "hive", "sales", int(CachePriorityNormal), &savedQueryID)
+	if err != nil {
+		t.Fatalf("RefreshQuery() error = %v", err)
+	}
+	if refreshed.Rows[0][0] != 2 {
+		t.Fatalf("RefreshQuery() = %v, want a second execution despite the cached result", refreshed.Rows)
+	}
+
+	cached, err := s.ExecuteQueryWithCache(ctx, "SELECT 1", "hive", "sales", int(CachePriorityNormal), &savedQueryID)
+	if err != nil {
+		t.Fatalf("ExecuteQueryWithCache() error = %v", err)
+	}
+	if cached.Rows[0][0] != 2 || runs != 2 {
+		t.Fatalf("after refresh: cached = %v with %d runs, want the refreshed result served from cache", cached.Rows, runs)
+	}
+	if len(cache.savedKeys[savedQueryID]) != 1 {
+		t.Fatalf("saved query has %d registered cache keys, want 1", len(cache.savedKeys[savedQueryID]))
+	}
+}
+
+func TestCachedTrinoService_CachedResultTime(t *testing.T) {
+	ctx := context.Background()
+	trino := repository.NewMockTrinoExecutor()
+	cache := newFakeResultCache()
+	s := &CachedTrinoService{trino: trino, cache: cache, cfg: &config.CacheConfig{KeyPrefix: "test:", TTLNormalSeconds: 600}}
+
+	if _, _, ok := s.CachedResultTime(ctx, "SELECT 1", "hive", "sales", int(CachePriorityNormal), 0); ok {
+		t.Fatal("CachedResultTime() reported a result before anything was cached")
+	}
+
+	if _, err := s.ExecuteQueryWithCache(ctx, "SELECT 1", "hive", "sales", int(CachePriorityNormal), nil); err != nil {
+		t.Fatalf("ExecuteQueryWithCache() error = %v", err)
+	}
+	cachedAt, expiresAt, ok := s.CachedResultTime(ctx, "SELECT 1", "hive", "sales", int(CachePriorityNormal), 0)
+	if !ok {
+		t.Fatal("CachedResultTime() reported no result after caching one")
+	}
+	if got := expiresAt.Sub(cachedAt); got != 600*time.Second {
+		t.Errorf("lifetime = %v, want the normal TTL of 10m", got)
+	}
+	if _, expiresAt, _ := s.CachedResultTime(ctx, "SELECT 1", "hive", "sales", int(CachePriorityNormal), time.Minute); expiresAt.Sub(cachedAt) != time.Minute {
+		t.Errorf("lifetime with max staleness = %v, want 1m", expiresAt.Sub(cachedAt))
+	}
+	if _, _, ok := s.CachedResultTime(ctx, "SELECT 2", "hive", "sales", int(CachePriorityNormal), 0); ok {
+		t.Error("CachedResultTime() reported a result for a query that was never run")
+	}
+	if len(trino.ExecuteQueryCalls) != 1 {
+		t.Errorf("Trino ran %d queries, want 1 (CachedResultTime must not execute)", len(trino.ExecuteQueryCalls))
+	}
+}
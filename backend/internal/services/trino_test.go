@@ -1,6 +1,7 @@
 package services
 
 import (
+	"database/sql"
 	"testing"
 	"time"
 
@@ -37,6 +38,28 @@ func TestGetConnectionStringOverrides(t *testing.T) {
 	}
 }
 
+func TestStats_MasksUserAndHost(t *testing.T) {
+	service := newTestTrinoService()
+	dsn := service.getConnectionString("hive", "sales")
+	db, err := sql.Open("trino", dsn) // does not connect
+	if err != nil {
+		t.Fatalf("sql.Open() error = %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(10)
+	service.dbs.Store(dsn, db)
+
+	stats := service.Stats()
+	want := "http://***@***:8080?catalog=hive&schema=sales"
+	got, ok := stats[want]
+	if len(stats) != 1 || !ok {
+		t.Fatalf("Stats() keys = %v, want only %q", stats, want)
+	}
+	if got.MaxOpenConnections != 10 {
+		t.Fatalf("MaxOpenConnections = %d, want 10", got.MaxOpenConnections)
+	}
+}
+
 func TestValidateIdentifier(t *testing.T) {
 	cases := []struct {
 		name      string
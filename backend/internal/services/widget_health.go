@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/mitsume/backend/internal/models"
+)
+
+// maxWidgetErrorLength bounds the stored error message
+const maxWidgetErrorLength = 2000
+
+// WidgetHealthService persists the last-known error state of widgets and notifies
+// dashboard owners when a widget starts failing
+type WidgetHealthService struct {
+	pool                *pgxpool.Pool
+	notificationService *NotificationService
+}
+
+// NewWidgetHealthService creates a new widget health service
+func NewWidgetHealthService(pool *pgxpool.Pool, notificationService *NotificationService) *WidgetHealthService {
+	return &WidgetHealthService{
+		pool:                pool,
+		notificationService: notificationService,
+	}
+}
+
+// RecordFailure stores the error on the widget. When the widget was previously healthy and the
+// dashboard has an error notification channel, the owner is notified in the background.
+func (s *WidgetHealthService) RecordFailure(ctx context.Context, widget *models.Widget, errMsg string) error {
+	errMsg = truncateWidgetError(errMsg)
+
+	var (
+		wasHealthy    bool
+		dashboardName string
+		channelID     *uuid.UUID
+	)
+	err := s.pool.QueryRow(ctx,
+		`UPDATE dashboard_widgets w
+		 SET last_error = $2, last_error_at = CURRENT_TIMESTAMP
+		 FROM (SELECT id, last_error FROM dashboard_widgets WHERE id = $1 FOR UPDATE) prev, dashboards d
+		 WHERE w.id = prev.id AND d.id = w.dashboard_id
+		 RETURNING prev.last_error IS NULL, d.name, d.error_notification_channel_id`,
+		widget.ID, errMsg,
+	).Scan(&wasHealthy, &dashboardName, &channelID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to record widget error: %w", err)
+	}
+
+	if wasHealthy && channelID != nil && s.notificationService != nil {
+		msg := buildWidgetErrorMessage(dashboardName, widget.Name, errMsg)
+		go s.notify(*channelID, msg)
+	}
+
+	return nil
+}
+
+// RecordSuccess clears the widget's error state, if any
+func (s *WidgetHealthService) RecordSuccess(ctx context.Context, widgetID uuid.UUID) error {
+	_, err := s.pool.Exec(ctx,
+		`UPDATE dashboard_widgets SET last_error = NULL, last_error_at = NULL
+		 WHERE id = $1 AND last_error IS NOT NULL`,
+		widgetID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to clear widget error: %w", err)
+	}
+	return nil
+}
+
+// SetErrorNotificationChannel sets the channel notified when a widget on the dashboard starts failing.
+// Only the dashboard owner may change it, and the channel must belong to the owner. A nil channel disables notifications.
+func (s *WidgetHealthService) SetErrorNotificationChannel(ctx context.Context, dashboardID, userID uuid.UUID, channelID *uuid.UUID) error {
+	if channelID != nil {
+		channel, err := s.notificationService.GetChannelByID(ctx, *channelID)
+		if err != nil || channel.UserID != userID {
+			return fmt.Errorf("%w: notification channel not found", ErrInvalidRequest)
+		}
+	}
+
+	result, err := s.pool.Exec(ctx,
+		`UPDATE dashboards SET error_notification_channel_id = $3, updated_at = CURRENT_TIMESTAMP
+		 WHERE id = $1 AND user_id = $2`,
+		dashboardID, userID, channelID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update error notification channel: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+func (s *WidgetHealthService) notify(channelID uuid.UUID, msg models.NotificationMessage) {
+	// Detached from the request: notification delivery must not slow down or fail the widget fetch
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	channel, err := s.notificationService.GetChannelByID(ctx, channelID)
+	if err != nil {
+		log.Printf("Failed to load widget error notification channel %s: %v", channelID, err)
+		return
+	}
+	if err := s.notificationService.Send(ctx, channel, msg); err != nil {
+		log.Printf("Failed to send widget error notification to channel %s: %v", channelID, err)
+	}
+}
+
+func buildWidgetErrorMessage(dashboardName, widgetName, errMsg string) models.NotificationMessage {
+	return models.NotificationMessage{
+		Title: "Widget Error: " + dashboardName + " / " + widgetName,
+		Body:  "The widget \"" + widgetName + "\" on dashboard \"" + dashboardName + "\" failed to load data.\n\nError: " + errMsg,
+	}
+}
+
+// truncateWidgetError cuts errMsg to at most maxWidgetErrorLength bytes, backing up to a rune
+// boundary so a multi-byte character is not split into invalid UTF-8, which PostgreSQL rejects
+func truncateWidgetError(errMsg string) string {
+	if len(errMsg) <= maxWidgetErrorLength {
+		return errMsg
+	}
+	cut := maxWidgetErrorLength
+	for cut > 0 && !utf8.RuneStart(errMsg[cut]) {
+		cut--
+	}
+	return errMsg[:cut]
+}
+
+// countWidgetErrors returns how many widgets are currently in an error state
+func countWidgetErrors(widgets []models.Widget) int {
+	count := 0
+	for i := range widgets {
+		if widgets[i].LastError != nil {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mitsume/backend/internal/models"
+)
+
+func TestCountWidgetErrors(t *testing.T) {
+	errMsg := "Column 'revenue' cannot be resolved"
+	widgets := []models.Widget{
+		{Name: "ok"},
+		{Name: "broken", LastError: &errMsg},
+		{Name: "also broken", LastError: &errMsg},
+	}
+
+	if got := countWidgetErrors(widgets); got != 2 {
+		t.Fatalf("countWidgetErrors() = %d, want 2", got)
+	}
+	if got := countWidgetErrors(nil); got != 0 {
+		t.Fatalf("countWidgetErrors(nil) = %d, want 0", got)
+	}
+}
+
+func TestBuildWidgetErrorMessage(t *testing.T) {
+	msg := buildWidgetErrorMessage("Sales", "Revenue by region", "Column 'revenue' cannot be resolved")
+
+	if !strings.Contains(msg.Title, "Sales") || !strings.Contains(msg.Title, "Revenue by region") {
+		t.Fatalf("Title = %q, want dashboard and widget names", msg.Title)
+	}
+	if !strings.Contains(msg.Body, "Column 'revenue' cannot be resolved") {
+		t.Fatalf("Body = %q, want error message", msg.Body)
+	}
+}
+
+func TestTruncateWidgetError(t *testing.T) {
+	short := "Column 'revenue' cannot be resolved"
+	if got := truncateWidgetError(short); got != short {
+		t.Fatalf("truncateWidgetError(%q) = %q, want unchanged", short, got)
+	}
+
+	// "列" is 3 bytes, so the limit falls inside a character
+	long := strings.Repeat("列", maxWidgetErrorLength)
+	got := truncateWidgetError(long)
+	if !utf8.ValidString(got) {
+		t.Fatal("truncateWidgetError() returned invalid UTF-8")
+	}
+	if len(got) > maxWidgetErrorLength || len(got) < maxWidgetErrorLength-2 {
+		t.Fatalf("truncateWidgetError() length = %d bytes, want just under %d", len(got), maxWidgetErrorLength)
+	}
+}
@@ -2,7 +2,7 @@ import React, { useEffect, useState, useCallback, useRef, useMemo } from 'react'
 import { useNavigate } from 'react-router-dom'
 import { Link } from 'react-router-dom'
 import ReactECharts from 'echarts-for-react'
-import type { Widget, QueryResult, ChartConfig, WidgetDataResponse } from '@/types'
+import type { Widget, QueryResult, WidgetDataResponse, Annotation } from '@/types'
 import { dashboardApi } from '@/services/api'
 import {
   getColumnLinkConfig,
@@ -13,6 +13,8 @@ import {
   type ChartClickData,
 } from '@/lib/drilldown'
 import { buildChartOptions } from '@/lib/chart-options'
+import { applyAnnotations } from '@/lib/chart-options/annotations'
+import { applyChartTheme, resolveWidgetConfig } from '@/lib/chart-options/theme'
 import { MarkdownWidget } from './MarkdownWidget'
 import { CounterWidget } from './CounterWidget'
 import { PivotWidget } from './PivotWidget'
@@ -40,6 +42,7 @@ export const ChartWidget: React.FC<ChartWidgetProps> = ({
 }) => {
   const navigate = useNavigate()
   const [data, setData] = useState<QueryResult | null>(null)
+  const [annotations, setAnnotations] = useState<Annotation[]>([])
   const [loading, setLoading] = useState(false)
   const [error, setError] = useState<unknown>(null)
   const [isRetrying, setIsRetrying] = useState(false)
@@ -48,8 +51,8 @@ export const ChartWidget: React.FC<ChartWidgetProps> = ({
   const abortControllerRef = useRef<AbortController | null>(null)
 
   const isMarkdown = widget.chart_type === 'markdown'
-  const hasQuery = !!widget.query_id
-  const config = widget.chart_config as ChartConfig
+  const hasQuery = !!(widget.query_text || widget.query_id)
+  const config = useMemo(() => resolveWidgetConfig(widget), [widget])
 
   const loadData = useCallback(async (params: Record<string, string>, signal?: AbortSignal) => {
     lastParamsRef.current = params
@@ -82,6 +85,7 @@ export const ChartWidget: React.FC<ChartWidgetProps> = ({
         setData(null)
       } else {
         setData(response.query_result || null)
+        setAnnotations(response.annotations || [])
       }
     } catch (err) {
       // Ignore abort errors - the request was intentionally cancelled
@@ -225,8 +229,8 @@ export const ChartWidget: React.FC<ChartWidgetProps> = ({
 
   // Build chart options using the centralized builder
   const chartOptions = useMemo(
-    () => buildChartOptions(widget.chart_type, data, config),
-    [widget.chart_type, data, config]
+    () => applyAnnotations(applyChartTheme(buildChartOptions(widget.chart_type, data, config), config), annotations),
+    [widget.chart_type, data, config, annotations]
   )
 
   if (loading) {
@@ -266,6 +270,20 @@ export const ChartWidget: React.FC<ChartWidgetProps> = ({
     )
   }
 
+  // The query succeeded but matched nothing; tables still show their column headers
+  if (data.rows.length === 0 && widget.chart_type !== 'table' && widget.chart_type !== 'markdown') {
+    return (
+      <div className="flex items-center justify-center h-full">
+        <EmptyState
+          title="No Rows"
+          description="The query ran successfully but returned no rows"
+          icon={BarChart3}
+          className="py-6"
+        />
+      </div>
+    )
+  }
+
   if (widget.chart_type === 'markdown') {
     return <MarkdownWidget content={widget.chart_config.content || ''} />
   }
@@ -298,11 +316,11 @@ export const ChartWidget: React.FC<ChartWidgetProps> = ({
   }
 
   if (widget.chart_type === 'counter') {
-    return <CounterWidget data={data} config={widget.chart_config} />
+    return <CounterWidget data={data} config={config} />
   }
 
   if (widget.chart_type === 'pivot') {
-    return <PivotWidget data={data} config={widget.chart_config} />
+    return <PivotWidget data={data} config={config} />
   }
 
   // Add cursor pointer style if drilldown or cross-filter is configured
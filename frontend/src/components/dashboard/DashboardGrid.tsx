@@ -361,7 +361,7 @@ export const DashboardGrid: React.FC<DashboardGridProps> = ({
                   </CardTitle>
                   <div className="flex gap-1">
                     {/* Refresh button - only for data widgets, not markdown */}
-                    {!editable && widget.chart_type !== 'markdown' && widget.query_id && (
+                    {!editable && widget.chart_type !== 'markdown' && (widget.query_text || widget.query_id) && (
                       <Button
                         variant="ghost"
                         size="icon"
@@ -270,6 +270,17 @@ export const ParameterSettingsDialog: React.FC<ParameterSettingsDialogProps> = (
                         {t('dashboard.parameters.required', 'Required')}
                       </label>
 
+                      {param.sql_format === 'identifier' && (
+                        <label className="flex items-center gap-2 text-sm">
+                          <input
+                            type="checkbox"
+                            checked={param.allow_qualified || false}
+                            onChange={(e) => handleUpdateParameter(index, { allow_qualified: e.target.checked || undefined })}
+                          />
+                          {t('dashboard.parameters.allowQualified', 'Allow schema.table')}
+                        </label>
+                      )}
+
                       {/* Advanced options toggle for select types */}
                       {isSelectType && (
                         <button
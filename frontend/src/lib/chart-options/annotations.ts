@@ -0,0 +1,57 @@
+import type { EChartsOption } from 'echarts'
+import type { Annotation } from '@/types'
+
+const DEFAULT_ANNOTATION_COLOR = '#ef4444'
+
+type AxisLike = { type?: string; data?: unknown[] }
+type SeriesLike = Record<string, unknown>
+
+/**
+ * Resolve the x-axis position of an annotation.
+ * Time axes take the timestamp directly; category axes snap to the first category at or after it.
+ */
+function resolveXPosition(axis: AxisLike, occurredAt: number): string | number | undefined {
+  if (axis.type === 'time') return occurredAt
+
+  const categories = axis.data || []
+  for (const category of categories) {
+    const t = new Date(String(category)).getTime()
+    if (!Number.isNaN(t) && t >= occurredAt) return String(category)
+  }
+  return undefined
+}
+
+/**
+ * Overlay annotations as vertical markers on the first series of a time-series chart.
+ * Options without a single x axis or series are returned unchanged.
+ */
+export function applyAnnotations(options: EChartsOption, annotations?: Annotation[]): EChartsOption {
+  if (!annotations || annotations.length === 0) return options
+
+  const xAxis = options.xAxis as AxisLike | AxisLike[] | undefined
+  const axis = Array.isArray(xAxis) ? xAxis[0] : xAxis
+  const series = options.series as SeriesLike[] | undefined
+  if (!axis || !Array.isArray(series) || series.length === 0) return options
+
+  const data = annotations.flatMap(a => {
+    const xAxisValue = resolveXPosition(axis, new Date(a.occurred_at).getTime())
+    if (xAxisValue === undefined) return []
+    const color = a.color || DEFAULT_ANNOTATION_COLOR
+    return [{
+      name: a.label,
+      xAxis: xAxisValue,
+      lineStyle: { color, type: 'dashed' as const },
+      label: { formatter: a.label, color },
+    }]
+  })
+  if (data.length === 0) return options
+
+  const [first, ...rest] = series
+  return {
+    ...options,
+    series: [
+      { ...first, markLine: { symbol: 'none', silent: false, data } },
+      ...rest,
+    ] as EChartsOption['series'],
+  }
+}
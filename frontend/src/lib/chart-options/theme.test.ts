@@ -0,0 +1,40 @@
+import { describe, expect, it } from 'vitest'
+import { applyChartTheme, resolveWidgetConfig } from './theme'
+import { COLOR_SCHEMES } from './color-schemes'
+
+describe('resolveWidgetConfig', () => {
+  it('returns chart_config when the widget has no theme', () => {
+    const chartConfig = { xAxis: 'day' }
+    expect(resolveWidgetConfig({ chart_config: chartConfig })).toBe(chartConfig)
+  })
+
+  it('lets widget keys win over the theme', () => {
+    const config = resolveWidgetConfig({
+      chart_config: { xAxis: 'day', colorScheme: 'vivid' },
+      theme_config: { colorScheme: 'pastel', fontSize: 14 },
+    })
+    expect(config).toEqual({ xAxis: 'day', colorScheme: 'vivid', fontSize: 14 })
+  })
+})
+
+describe('applyChartTheme', () => {
+  it('leaves options unchanged without theme keys', () => {
+    expect(applyChartTheme({ series: [] }, { xAxis: 'day' })).toEqual({ series: [] })
+  })
+
+  it('applies custom colors before the named scheme', () => {
+    const options = applyChartTheme({}, { colorScheme: 'pastel', customColors: ['#000000'] })
+    expect(options.color).toEqual(['#000000'])
+  })
+
+  it('applies the named scheme', () => {
+    const options = applyChartTheme({}, { colorScheme: 'pastel' })
+    expect(options.color).toEqual(COLOR_SCHEMES.pastel.colors)
+  })
+
+  it('applies background and text style', () => {
+    const options = applyChartTheme({}, { backgroundColor: '#111111', fontFamily: 'Inter', fontSize: 12, textColor: '#eeeeee' })
+    expect(options.backgroundColor).toBe('#111111')
+    expect(options.textStyle).toEqual({ fontFamily: 'Inter', fontSize: 12, color: '#eeeeee' })
+  })
+})
@@ -0,0 +1,36 @@
+import type { EChartsOption } from 'echarts'
+import type { ChartConfig, Widget } from '@/types'
+import { resolveColors } from './color-schemes'
+
+/**
+ * Merge a widget's theme under its chart_config; keys set on the widget win over the theme.
+ */
+export function resolveWidgetConfig(widget: Pick<Widget, 'chart_config' | 'theme_config'>): ChartConfig {
+  if (!widget.theme_config) return widget.chart_config
+  return { ...widget.theme_config, ...widget.chart_config }
+}
+
+/**
+ * Apply the palette, background and text style of a chart config to built options.
+ * Options are returned unchanged when the config sets none of them.
+ */
+export function applyChartTheme(options: EChartsOption, config: ChartConfig): EChartsOption {
+  const themed: EChartsOption = { ...options }
+
+  if (config.colorScheme || (config.customColors && config.customColors.length > 0)) {
+    themed.color = resolveColors(config.colorScheme, config.customColors)
+  }
+  if (config.backgroundColor) {
+    themed.backgroundColor = config.backgroundColor
+  }
+  if (config.fontFamily || config.fontSize || config.textColor) {
+    themed.textStyle = {
+      ...(options.textStyle as object | undefined),
+      ...(config.fontFamily && { fontFamily: config.fontFamily }),
+      ...(config.fontSize && { fontSize: config.fontSize }),
+      ...(config.textColor && { color: config.textColor }),
+    }
+  }
+
+  return themed
+}
@@ -907,7 +907,7 @@ export const DashboardDetail: React.FC = () => {
     if (!dashboard?.widgets) return
     const newKeys: Record<string, number> = {}
     for (const widget of dashboard.widgets) {
-      if (widget.chart_type !== 'markdown' && widget.query_id) {
+      if (widget.chart_type !== 'markdown' && (widget.query_text || widget.query_id)) {
         newKeys[widget.id] = (refreshKeys[widget.id] || 0) + 1
       }
     }
@@ -19,6 +19,7 @@ vi.mock('react-router-dom', async () => {
 vi.mock('@/services/api', () => ({
   authApi: {
     getGoogleLoginUrl: vi.fn(),
+    getOIDCLoginUrl: vi.fn(),
   },
 }))
 
@@ -252,4 +253,30 @@ describe('Login Component', () => {
       })
     })
   })
+
+  describe('SSO login', () => {
+    it('should request the SSO login URL on button click', async () => {
+      vi.mocked(authApi.getOIDCLoginUrl).mockResolvedValue('https://sso.example.com/authorize')
+
+      renderLogin()
+
+      fireEvent.click(screen.getByRole('button', { name: /sso/i }))
+
+      await waitFor(() => {
+        expect(authApi.getOIDCLoginUrl).toHaveBeenCalled()
+      })
+    })
+
+    it('should show error if SSO login not configured', async () => {
+      vi.mocked(authApi.getOIDCLoginUrl).mockRejectedValue(new Error('Not configured'))
+
+      renderLogin()
+
+      fireEvent.click(screen.getByRole('button', { name: /sso/i }))
+
+      await waitFor(() => {
+        expect(screen.getByText('SSO login is not configured')).toBeInTheDocument()
+      })
+    })
+  })
 })
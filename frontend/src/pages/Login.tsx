@@ -1,5 +1,5 @@
 import React, { useState } from 'react'
-import { useNavigate } from 'react-router-dom'
+import { useNavigate, useSearchParams } from 'react-router-dom'
 import { useTranslation } from 'react-i18next'
 import { useAuthStore } from '@/stores/authStore'
 import { authApi } from '@/services/api'
@@ -10,13 +10,16 @@ import { Tabs, TabsList, TabsTrigger, TabsContent } from '@/components/ui/tabs'
 
 export const Login: React.FC = () => {
   const navigate = useNavigate()
+  const [searchParams] = useSearchParams()
   const { t } = useTranslation()
   const { login, register } = useAuthStore()
   const [tab, setTab] = useState('login')
   const [email, setEmail] = useState('')
   const [password, setPassword] = useState('')
   const [name, setName] = useState('')
-  const [error, setError] = useState('')
+  const [error, setError] = useState(
+    searchParams.get('error') === 'account_not_active' ? t('errors.accountNotActive') : ''
+  )
   const [loading, setLoading] = useState(false)
 
   const handleLogin = async (e: React.FormEvent) => {
@@ -56,6 +59,15 @@ export const Login: React.FC = () => {
     }
   }
 
+  const handleOIDCLogin = async () => {
+    try {
+      const url = await authApi.getOIDCLoginUrl()
+      window.location.href = url
+    } catch (err) {
+      setError(t('errors.ssoLoginNotConfigured'))
+    }
+  }
+
   return (
     <div className="min-h-screen flex items-center justify-center bg-muted/50">
       <Card className="w-full max-w-md">
@@ -165,6 +177,10 @@ export const Login: React.FC = () => {
             </svg>
             Google
           </Button>
+
+          <Button variant="outline" className="w-full mt-2" onClick={handleOIDCLogin}>
+            {t('auth.loginWithSSO')}
+          </Button>
         </CardContent>
       </Card>
     </div>
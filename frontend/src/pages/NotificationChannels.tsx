@@ -18,6 +18,7 @@ import type {
   SlackChannelConfig,
   EmailChannelConfig,
   GoogleChatChannelConfig,
+  InAppChannelConfig,
 } from '@/types'
 
 export default function NotificationChannels() {
@@ -69,12 +70,8 @@ export default function NotificationChannels() {
       setFormData({
         name: channel.name,
         channel_type: channel.channel_type,
-        webhook_url:
-          channel.channel_type === 'slack'
-            ? (channel.config as SlackChannelConfig).webhook_url
-            : channel.channel_type === 'google_chat'
-            ? (channel.config as GoogleChatChannelConfig).webhook_url
-            : '',
+        // Webhook URLs are secrets and are not returned by the API
+        webhook_url: '',
         recipients:
           channel.channel_type === 'email'
             ? (channel.config as EmailChannelConfig).recipients.join(', ')
@@ -100,9 +97,11 @@ export default function NotificationChannels() {
   const handleSave = async () => {
     try {
       setSaving(true)
-      let config: SlackChannelConfig | EmailChannelConfig | GoogleChatChannelConfig
+      let config: SlackChannelConfig | EmailChannelConfig | GoogleChatChannelConfig | InAppChannelConfig
 
-      if (formData.channel_type === 'slack') {
+      if (formData.channel_type === 'in_app') {
+        config = {}
+      } else if (formData.channel_type === 'slack') {
         config = { webhook_url: formData.webhook_url }
       } else if (formData.channel_type === 'google_chat') {
         config = { webhook_url: formData.webhook_url }
@@ -113,9 +112,12 @@ export default function NotificationChannels() {
       }
 
       if (editingChannel) {
+        // A blank webhook URL keeps the stored one
+        const keepWebhook =
+          (formData.channel_type === 'slack' || formData.channel_type === 'google_chat') && !formData.webhook_url
         await notificationApi.updateChannel(editingChannel.id, {
           name: formData.name,
-          config,
+          config: keepWebhook ? undefined : config,
         })
         toast.success(t('notifications.toast.updated'), t('notifications.toast.updatedDesc', { name: formData.name }))
       } else {
@@ -166,6 +168,7 @@ export default function NotificationChannels() {
       case 'slack': return t('notifications.types.slack')
       case 'email': return t('notifications.types.email')
       case 'google_chat': return t('notifications.types.googleChat')
+      case 'in_app': return t('notifications.types.inApp')
       default: return type
     }
   }
@@ -237,6 +240,8 @@ export default function NotificationChannels() {
                 <p className="text-sm text-muted-foreground mb-4">
                   {channel.channel_type === 'email'
                     ? (channel.config as EmailChannelConfig).recipients.join(', ')
+                    : channel.channel_type === 'in_app'
+                    ? t('notifications.inApp.description')
                     : t('common.webhookConfigured')}
                 </p>
                 <div className="flex items-center justify-between">
@@ -297,6 +302,7 @@ export default function NotificationChannels() {
                   <option value="slack">{t('notifications.types.slack')}</option>
                   <option value="email">{t('notifications.types.email')}</option>
                   <option value="google_chat">{t('notifications.types.googleChat')}</option>
+                  <option value="in_app">{t('notifications.types.inApp')}</option>
                 </Select>
               </div>
             )}
@@ -310,6 +316,11 @@ export default function NotificationChannels() {
                   onChange={(e: React.ChangeEvent<HTMLInputElement>) => setFormData({ ...formData, webhook_url: e.target.value })}
                   placeholder={formData.channel_type === 'slack' ? t('notifications.slack.webhookPlaceholder') : t('notifications.googleChat.webhookPlaceholder')}
                 />
+                {editingChannel && (
+                  <p className="text-xs text-muted-foreground mt-1">
+                    {t('notifications.webhookKeepHint')}
+                  </p>
+                )}
               </div>
             )}
 
@@ -104,6 +104,7 @@ export default function RoleManagement() {
         await adminApi.updateRole(editingRole.id, {
           name: formData.name,
           description: formData.description,
+          parent_role_id: editingRole.parent_role_id,
         })
         toast.success(t('admin.roles.toast.updated'), t('admin.roles.toast.updatedDesc', { name: formData.name }))
       } else {
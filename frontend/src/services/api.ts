@@ -2,14 +2,21 @@ import axios, { AxiosError } from 'axios'
 import type {
   AuthResponse,
   SavedQuery,
+  ImportSavedQueryItem,
+  ImportSavedQueriesResponse,
   QueryHistory,
+  SavedQueryPerformance,
+  SlowQuery,
   QueryResult,
+  QueryResultPage,
   ColumnInfo,
   Dashboard,
+  DashboardVersion,
   Widget,
   CreateDashboardRequest,
   CreateWidgetRequest,
   NotificationChannel,
+  InAppNotification,
   CreateNotificationChannelRequest,
   UpdateNotificationChannelRequest,
   QueryAlert,
@@ -17,24 +24,41 @@ import type {
   UpdateAlertRequest,
   AlertHistory,
   AlertTestResult,
+  PreviewAlertRequest,
+  AlertPreviewResponse,
+  AlertDigestSettings,
+  UpdateAlertDigestSettingsRequest,
   DashboardSubscription,
   CreateSubscriptionRequest,
   UpdateSubscriptionRequest,
+  SubscriptionHistory,
+  SchedulePreview,
+  PreviewScheduleRequest,
   Role,
   RoleWithCatalogs,
+  SchemaPermission,
+  RowFilter,
   UserWithRoles,
+  ApproveUsersResponse,
   CreateRoleRequest,
   UpdateRoleRequest,
   DashboardPermission,
   GrantPermissionRequest,
   UpdateVisibilityRequest,
   LayoutTemplate,
+  ChartTheme,
+  ChartThemeConfig,
   Position,
   WidgetDataRequest,
   WidgetDataResponse,
+  WidgetQueryDebug,
+  MaintenanceStatus,
+  DashboardRenderResponse,
+  DashboardDataResponse,
   BatchWidgetUpdateRequest,
   BatchWidgetUpdateResponse,
   MetadataSearchResult,
+  MetadataSuggestion,
 } from '@/types'
 
 const api = axios.create({
@@ -80,6 +104,11 @@ export const authApi = {
     return data.url
   },
 
+  getOIDCLoginUrl: async (): Promise<string> => {
+    const { data } = await api.get<{ url: string }>('/auth/oidc')
+    return data.url
+  },
+
   me: async (): Promise<AuthResponse['user']> => {
     const { data } = await api.get<AuthResponse['user']>('/auth/me')
     return data
@@ -93,6 +122,21 @@ export const queryApi = {
     return data
   },
 
+  executePaged: async (query: string, catalog?: string, schema?: string, pageSize?: number): Promise<QueryResultPage> => {
+    const { data } = await api.post<QueryResultPage>('/queries/execute-paged', {
+      query,
+      catalog,
+      schema,
+      page_size: pageSize,
+    })
+    return data
+  },
+
+  getResultPage: async (cursor: string): Promise<QueryResultPage> => {
+    const { data } = await api.get<QueryResultPage>('/queries/results', { params: { cursor } })
+    return data
+  },
+
   getSaved: async (): Promise<SavedQuery[]> => {
     const { data } = await api.get<SavedQuery[]>('/queries/saved')
     return data
@@ -112,6 +156,11 @@ export const queryApi = {
     return data
   },
 
+  importSaved: async (items: ImportSavedQueryItem[]): Promise<ImportSavedQueriesResponse> => {
+    const { data } = await api.post<ImportSavedQueriesResponse>('/queries/saved/import', items)
+    return data
+  },
+
   update: async (id: string, updates: Partial<SavedQuery>): Promise<SavedQuery> => {
     const { data } = await api.put<SavedQuery>(`/queries/saved/${id}`, updates)
     return data
@@ -127,6 +176,19 @@ export const queryApi = {
     })
     return data
   },
+
+  getPerformance: async (id: string, days?: number): Promise<SavedQueryPerformance> => {
+    const { data } = await api.get<SavedQueryPerformance>(`/queries/saved/${id}/performance`, {
+      params: days ? { days } : undefined,
+    })
+    return data
+  },
+
+  // Admin only
+  getSlowQueries: async (params?: { threshold_ms?: number; days?: number; limit?: number; offset?: number }): Promise<SlowQuery[]> => {
+    const { data } = await api.get<SlowQuery[]>('/queries/slow', { params })
+    return data
+  },
 }
 
 // Export
@@ -188,15 +250,42 @@ export const catalogApi = {
     })
     return data.results || []
   },
+
+  autocomplete: async (
+    q: string,
+    options: { catalog?: string; schema?: string; limit?: number } = {}
+  ): Promise<MetadataSuggestion[]> => {
+    const { data } = await api.get<{ suggestions: MetadataSuggestion[] }>('/metadata/autocomplete', {
+      params: { q, ...options },
+    })
+    return data.suggestions || []
+  },
 }
 
 // Dashboards
 export const dashboardApi = {
-  getAll: async (): Promise<Dashboard[]> => {
-    const { data } = await api.get<Dashboard[]>('/dashboards')
+  getAll: async (filter?: 'archived'): Promise<Dashboard[]> => {
+    const { data } = await api.get<Dashboard[]>('/dashboards', { params: filter ? { filter } : undefined })
     return data
   },
 
+  archive: async (id: string): Promise<void> => {
+    await api.post(`/dashboards/${id}/archive`)
+  },
+
+  unarchive: async (id: string): Promise<void> => {
+    await api.post(`/dashboards/${id}/unarchive`)
+  },
+
+  getTrash: async (): Promise<Dashboard[]> => {
+    const { data } = await api.get<Dashboard[]>('/dashboards/trash')
+    return data
+  },
+
+  restore: async (id: string): Promise<void> => {
+    await api.post(`/dashboards/${id}/restore`)
+  },
+
   getById: async (id: string): Promise<Dashboard> => {
     const { data } = await api.get<Dashboard>(`/dashboards/${id}`)
     return data
@@ -293,6 +382,18 @@ export const dashboardApi = {
     await api.delete(`/dashboards/${draftId}/discard-draft`)
   },
 
+  // Versions kept each time a draft was published, newest first
+  getVersions: async (dashboardId: string): Promise<DashboardVersion[]> => {
+    const { data } = await api.get<DashboardVersion[]>(`/dashboards/${dashboardId}/versions`)
+    return data
+  },
+
+  // Restore a version as a new draft (fails with 409 while a draft exists)
+  restoreVersion: async (dashboardId: string, version: number): Promise<Dashboard> => {
+    const { data } = await api.post<Dashboard>(`/dashboards/${dashboardId}/versions/${version}/restore`)
+    return data
+  },
+
   // Permissions
   getPermissions: async (dashboardId: string): Promise<DashboardPermission[]> => {
     const { data } = await api.get<DashboardPermission[]>(`/dashboards/${dashboardId}/permissions`)
@@ -317,13 +418,14 @@ export const dashboardApi = {
     dashboardId: string,
     widgetId: string,
     parameters?: Record<string, unknown>,
-    signal?: AbortSignal
+    signal?: AbortSignal,
+    bypassCache = false
   ): Promise<WidgetDataResponse> => {
     // Always POST when parameters are provided (even if empty) so the server can return required/missing.
-    if (parameters !== undefined) {
+    if (parameters !== undefined || bypassCache) {
       const { data } = await api.post<WidgetDataResponse>(
         `/dashboards/${dashboardId}/widgets/${widgetId}/data`,
-        { parameters } as WidgetDataRequest,
+        { parameters: parameters ?? {}, bypass_cache: bypassCache || undefined } as WidgetDataRequest,
         { signal }
       )
       return data
@@ -337,6 +439,34 @@ export const dashboardApi = {
     }
   },
 
+  renderDashboard: async (
+    dashboardId: string,
+    parameters: Record<string, unknown>,
+    signal?: AbortSignal,
+    bypassCache = false
+  ): Promise<DashboardRenderResponse> => {
+    const { data } = await api.post<DashboardRenderResponse>(
+      `/dashboards/${dashboardId}/render`,
+      { parameters, bypass_cache: bypassCache || undefined } as WidgetDataRequest,
+      { signal }
+    )
+    return data
+  },
+
+  getDashboardData: async (
+    dashboardId: string,
+    parameters: Record<string, unknown>,
+    signal?: AbortSignal,
+    bypassCache = false
+  ): Promise<DashboardDataResponse> => {
+    const { data } = await api.post<DashboardDataResponse>(
+      `/dashboards/${dashboardId}/data`,
+      { parameters, bypass_cache: bypassCache || undefined } as WidgetDataRequest,
+      { signal }
+    )
+    return data
+  },
+
   // Parameter Options (for dynamic select/multiselect)
   getParameterOptions: async (
     dashboardId: string,
@@ -353,8 +483,10 @@ export const dashboardApi = {
 
 // Notification Channels
 export const notificationApi = {
-  getChannels: async (): Promise<NotificationChannel[]> => {
-    const { data } = await api.get<NotificationChannel[]>('/notification-channels')
+  getChannels: async (unused?: boolean): Promise<NotificationChannel[]> => {
+    const { data } = await api.get<NotificationChannel[]>('/notification-channels', {
+      params: unused ? { unused: true } : undefined,
+    })
     return data
   },
 
@@ -381,6 +513,17 @@ export const notificationApi = {
     const { data } = await api.post<{ message: string }>(`/notification-channels/${id}/test`)
     return data
   },
+
+  getInbox: async (unreadOnly = false, limit = 50): Promise<InAppNotification[]> => {
+    const { data } = await api.get<InAppNotification[]>('/notifications', {
+      params: { unread: unreadOnly || undefined, limit },
+    })
+    return data
+  },
+
+  markRead: async (id: string): Promise<void> => {
+    await api.post(`/notifications/${id}/read`)
+  },
 }
 
 // Alerts
@@ -414,12 +557,27 @@ export const alertApi = {
     return data
   },
 
+  preview: async (req: PreviewAlertRequest): Promise<AlertPreviewResponse> => {
+    const { data } = await api.post<AlertPreviewResponse>('/alerts/preview', req)
+    return data
+  },
+
   getHistory: async (id: string, limit = 50): Promise<AlertHistory[]> => {
     const { data } = await api.get<AlertHistory[]>(`/alerts/${id}/history`, {
       params: { limit },
     })
     return data
   },
+
+  getDigestSettings: async (): Promise<AlertDigestSettings> => {
+    const { data } = await api.get<AlertDigestSettings>('/alerts/digest-settings')
+    return data
+  },
+
+  updateDigestSettings: async (req: UpdateAlertDigestSettingsRequest): Promise<AlertDigestSettings> => {
+    const { data } = await api.put<AlertDigestSettings>('/alerts/digest-settings', req)
+    return data
+  },
 }
 
 // Subscriptions
@@ -452,6 +610,38 @@ export const subscriptionApi = {
     const { data } = await api.post<{ message: string }>(`/subscriptions/${id}/trigger`)
     return data
   },
+
+  getHistory: async (id: string, limit = 50): Promise<SubscriptionHistory[]> => {
+    const { data } = await api.get<SubscriptionHistory[]>(`/subscriptions/${id}/history`, {
+      params: { limit },
+    })
+    return data
+  },
+
+  pause: async (id: string): Promise<DashboardSubscription> => {
+    const { data } = await api.post<DashboardSubscription>(`/subscriptions/${id}/pause`)
+    return data
+  },
+
+  resume: async (id: string): Promise<DashboardSubscription> => {
+    const { data } = await api.post<DashboardSubscription>(`/subscriptions/${id}/resume`)
+    return data
+  },
+
+  skipNext: async (id: string): Promise<DashboardSubscription> => {
+    const { data } = await api.post<DashboardSubscription>(`/subscriptions/${id}/skip-next`)
+    return data
+  },
+
+  validateCron: async (cron: string, timezone?: string, seconds?: boolean): Promise<SchedulePreview> => {
+    const { data } = await api.post<SchedulePreview>('/subscriptions/validate-cron', { cron, timezone, seconds })
+    return data
+  },
+
+  previewSchedule: async (req: PreviewScheduleRequest): Promise<SchedulePreview> => {
+    const { data } = await api.post<SchedulePreview>('/subscriptions/preview-schedule', req)
+    return data
+  },
 }
 
 // Layout Templates
@@ -475,6 +665,27 @@ export const layoutTemplateApi = {
   },
 }
 
+// Chart Themes
+export const chartThemeApi = {
+  getAll: async (): Promise<ChartTheme[]> => {
+    const { data } = await api.get<ChartTheme[]>('/chart-themes')
+    return data
+  },
+
+  create: async (name: string, config: ChartThemeConfig, isSystem = false): Promise<ChartTheme> => {
+    const { data } = await api.post<ChartTheme>('/chart-themes', {
+      name,
+      config,
+      is_system: isSystem,
+    })
+    return data
+  },
+
+  delete: async (id: string): Promise<void> => {
+    await api.delete(`/chart-themes/${id}`)
+  },
+}
+
 // Admin API
 export const adminApi = {
   // Roles
@@ -506,6 +717,18 @@ export const adminApi = {
     await api.put(`/admin/roles/${id}/catalogs`, { catalogs })
   },
 
+  setRoleSchemas: async (id: string, schemas: SchemaPermission[]): Promise<void> => {
+    await api.put(`/admin/roles/${id}/schemas`, { schemas })
+  },
+
+  setRoleRowFilters: async (id: string, filters: RowFilter[]): Promise<void> => {
+    await api.put(`/admin/roles/${id}/row-filters`, { filters })
+  },
+
+  setRoleQueryTimeout: async (id: string, maxQueryTimeoutSeconds: number | null): Promise<void> => {
+    await api.put(`/admin/roles/${id}/query-timeout`, { max_query_timeout_seconds: maxQueryTimeoutSeconds })
+  },
+
   getAvailableCatalogs: async (): Promise<string[]> => {
     const { data } = await api.get<{ catalogs: string[] }>('/admin/catalogs/available')
     return data.catalogs
@@ -524,6 +747,34 @@ export const adminApi = {
   unassignRole: async (userId: string, roleId: string): Promise<void> => {
     await api.delete(`/admin/users/${userId}/roles/${roleId}`)
   },
+
+  approveUsers: async (userIds: string[], roleId?: string): Promise<ApproveUsersResponse> => {
+    const { data } = await api.post<ApproveUsersResponse>('/admin/users/approve-bulk', { user_ids: userIds, role_id: roleId })
+    return data
+  },
+
+  resetPassword: async (userId: string): Promise<string> => {
+    const { data } = await api.post<{ temporary_password: string }>(`/admin/users/${userId}/reset-password`)
+    return data.temporary_password
+  },
+
+  debugWidgetQuery: async (dashboardId: string, widgetId: string, params?: Record<string, unknown>): Promise<WidgetQueryDebug> => {
+    const { data } = await api.get<WidgetQueryDebug>(`/admin/dashboards/${dashboardId}/widgets/${widgetId}/debug`, {
+      params: params ? { params: JSON.stringify(params) } : undefined,
+    })
+    return data
+  },
+
+  // Maintenance mode
+  getMaintenance: async (): Promise<MaintenanceStatus> => {
+    const { data } = await api.get<MaintenanceStatus>('/admin/maintenance')
+    return data
+  },
+
+  setMaintenance: async (enabled: boolean, message?: string): Promise<MaintenanceStatus> => {
+    const { data } = await api.put<MaintenanceStatus>('/admin/maintenance', { enabled, message })
+    return data
+  },
 }
 
 export default api
@@ -22,6 +22,20 @@ export interface SavedQuery {
   updated_at: string
 }
 
+export interface ImportSavedQueryItem {
+  name: string
+  description?: string
+  query_text: string
+  catalog?: string
+  schema?: string
+}
+
+export interface ImportSavedQueriesResponse {
+  results: { index: number; id?: string; error?: string }[]
+  created_count: number
+  failed_count: number
+}
+
 export interface QueryHistory {
   id: string
   user_id: string
@@ -30,9 +44,31 @@ export interface QueryHistory {
   execution_time_ms: number | null
   row_count: number | null
   error_message: string | null
+  source: QueryHistorySource
+  source_id?: string
   executed_at: string
 }
 
+export type QueryHistorySource = 'query' | 'query_job' | 'export' | 'widget' | 'parameter_options' | 'alert'
+
+export interface SlowQuery extends QueryHistory {
+  user_email: string
+}
+
+export interface SavedQueryPerformance {
+  query_id: string
+  days: number
+  threshold_ms: number  // 0 when slow query reporting is disabled
+  run_count: number
+  error_count: number
+  slow_count: number
+  avg_ms: number
+  p95_ms: number
+  max_ms: number
+  last_run_at: string | null
+  slowest_runs: QueryHistory[]
+}
+
 export interface QueryResult {
   columns: string[]
   rows: unknown[][]
@@ -40,6 +76,12 @@ export interface QueryResult {
   execution_time_ms: number
 }
 
+// One page of a result held on the server; next_cursor is absent on the last page
+export interface QueryResultPage extends QueryResult {
+  total_rows: number
+  next_cursor?: string
+}
+
 export interface ColumnInfo {
   name: string
   type: string
@@ -51,7 +93,7 @@ export interface ColumnInfo {
 export type PermissionLevel = 'view' | 'edit' | 'owner' | ''
 
 // Parameter Definition Types for Dashboard Filters
-export type ParameterType = 'text' | 'number' | 'date' | 'daterange' | 'select' | 'multiselect'
+export type ParameterType = 'text' | 'number' | 'date' | 'daterange' | 'relativedate' | 'select' | 'multiselect'
 export type SqlFormat = 'raw' | 'string' | 'number' | 'date' | 'identifier' | 'string_list' | 'number_list'
 export type EmptyBehavior = 'missing' | 'null' | 'match_none'
 
@@ -77,6 +119,11 @@ export interface ParameterDefinition {
   options_query_id?: string
   depends_on?: string[]
   empty_behavior?: EmptyBehavior
+  allow_qualified?: boolean
+  min?: number        // number formats: smallest accepted value
+  max?: number        // number formats: largest accepted value
+  min_date?: string   // date format: earliest accepted date (YYYY-MM-DD)
+  max_date?: string   // date format: latest accepted date (YYYY-MM-DD)
 }
 
 export interface DashboardPermission {
@@ -102,6 +149,13 @@ export interface Dashboard {
   parameters?: ParameterDefinition[]
   is_draft?: boolean
   draft_of?: string  // Original dashboard ID if this is a draft
+  is_archived?: boolean  // Hidden from the default list
+  deleted_at?: string | null  // Set for dashboards in the trash
+  default_catalog?: string | null  // Catalog for widget queries without one; '' on update clears it
+  default_schema?: string | null  // Schema for widget queries without one; '' on update clears it
+  timezone?: string | null  // IANA timezone relative date parameters resolve in (default Asia/Tokyo); '' on update clears it
+  created_by?: string | null  // User who created the dashboard
+  updated_by?: string | null  // User who last edited the dashboard
   created_at: string
   updated_at: string
   widgets?: Widget[]
@@ -109,6 +163,33 @@ export interface Dashboard {
   permissions?: DashboardPermission[]
 }
 
+export interface DashboardEvent {
+  id: string
+  dashboard_id: string
+  actor_id: string | null
+  actor_name: string | null
+  action: 'create' | 'update' | 'delete' | 'archive' | 'unarchive' | 'restore'
+  target_type: 'dashboard' | 'widget'
+  target_id: string
+  target_name: string
+  created_at: string
+}
+
+export interface DashboardActivityResponse {
+  events: DashboardEvent[]
+  total: number
+}
+
+export interface DashboardVersion {
+  dashboard_id: string
+  version: number
+  name: string
+  description: string | null
+  widget_count: number
+  created_by: string | null  // User whose publish replaced this version
+  created_at: string
+}
+
 export interface GrantPermissionRequest {
   user_id?: string
   role_id?: string
@@ -124,10 +205,17 @@ export interface Widget {
   dashboard_id: string
   name: string
   query_id: string | null
+  query_text?: string  // Ad-hoc SQL run instead of query_id when set
+  catalog?: string
+  schema?: string
   chart_type: ChartType
   chart_config: ChartConfig
   position: Position
   responsive_positions?: ResponsivePositions
+  theme_id?: string
+  theme_config?: ChartThemeConfig  // Config of the referenced theme, merged under chart_config when rendering
+  created_by?: string | null  // User who created the widget
+  updated_by?: string | null  // User who last edited the widget
   created_at: string
   updated_at: string
 }
@@ -366,6 +454,7 @@ export interface ChartConfig {
   title?: string
   legend?: boolean
   content?: string  // Markdown content for markdown widget
+  columnAliases?: Record<string, string>  // Result column name -> label, applied server-side to widget data
 
   // Counter widget config
   valueColumn?: string  // Column to display as counter value
@@ -454,6 +543,27 @@ export interface ChartConfig {
   // Color scheme
   colorScheme?: string
   customColors?: string[]
+
+  // Text and background (usually set by a chart theme)
+  fontFamily?: string
+  fontSize?: number
+  backgroundColor?: string
+  textColor?: string
+}
+
+// Chart config keys a chart theme may set
+export type ChartThemeConfig = Pick<
+  ChartConfig,
+  'colorScheme' | 'customColors' | 'fontFamily' | 'fontSize' | 'backgroundColor' | 'textColor'
+>
+
+export interface ChartTheme {
+  id: string
+  user_id?: string
+  name: string
+  config: ChartThemeConfig
+  is_system: boolean
+  created_at: string
 }
 
 export interface CreateDashboardRequest {
@@ -464,19 +574,27 @@ export interface CreateDashboardRequest {
 export interface CreateWidgetRequest {
   name: string
   query_id?: string
+  query_text?: string
+  catalog?: string
+  schema?: string
   chart_type: ChartType
   chart_config: ChartConfig
   position: Position
   responsive_positions?: ResponsivePositions
+  theme_id?: string
 }
 
 export interface UpdateWidgetRequest {
   name?: string
   query_id?: string | null
+  query_text?: string  // Empty string clears it
+  catalog?: string
+  schema?: string
   chart_type?: ChartType
   chart_config?: ChartConfig
   position?: Position
   responsive_positions?: ResponsivePositions
+  theme_id?: string
 }
 
 export interface BatchWidgetUpdateRequest {
@@ -492,19 +610,20 @@ export interface BatchWidgetUpdateResponse {
 }
 
 // Notification Types
-export type ChannelType = 'slack' | 'email' | 'google_chat'
+export type ChannelType = 'slack' | 'email' | 'google_chat' | 'in_app'
 
 export interface NotificationChannel {
   id: string
   user_id: string
   name: string
   channel_type: ChannelType
-  config: SlackChannelConfig | EmailChannelConfig | GoogleChatChannelConfig
+  config: SlackChannelConfig | EmailChannelConfig | GoogleChatChannelConfig | InAppChannelConfig
   is_verified: boolean
   created_at: string
   updated_at: string
 }
 
+// webhook_url is omitted from API responses
 export interface SlackChannelConfig {
   webhook_url: string
 }
@@ -517,20 +636,35 @@ export interface GoogleChatChannelConfig {
   webhook_url: string
 }
 
+// Filled in by the server: in-app channels always deliver to their owner
+export interface InAppChannelConfig {
+  user_id?: string
+}
+
+export interface InAppNotification {
+  id: string
+  user_id: string
+  title: string
+  body: string
+  read_at?: string
+  created_at: string
+}
+
 export interface CreateNotificationChannelRequest {
   name: string
   channel_type: ChannelType
-  config: SlackChannelConfig | EmailChannelConfig | GoogleChatChannelConfig
+  config: SlackChannelConfig | EmailChannelConfig | GoogleChatChannelConfig | InAppChannelConfig
 }
 
 export interface UpdateNotificationChannelRequest {
   name?: string
-  config?: SlackChannelConfig | EmailChannelConfig | GoogleChatChannelConfig
+  config?: SlackChannelConfig | EmailChannelConfig | GoogleChatChannelConfig | InAppChannelConfig
 }
 
 // Alert Types
 export type ConditionOperator = 'gt' | 'lt' | 'eq' | 'gte' | 'lte' | 'neq' | 'contains'
 export type Aggregation = 'sum' | 'avg' | 'count' | 'min' | 'max' | 'first'
+export type AlertSeverity = 'info' | 'warning' | 'critical'
 
 export interface QueryAlert {
   id: string
@@ -542,6 +676,7 @@ export interface QueryAlert {
   condition_operator: ConditionOperator
   condition_value: string
   aggregation: Aggregation | null
+  severity: AlertSeverity
   check_interval_minutes: number
   cooldown_minutes: number
   is_active: boolean
@@ -561,6 +696,7 @@ export interface CreateAlertRequest {
   condition_operator: ConditionOperator
   condition_value: string
   aggregation?: Aggregation
+  severity?: AlertSeverity
   check_interval_minutes?: number
   cooldown_minutes?: number
   channel_ids: string[]
@@ -573,6 +709,7 @@ export interface UpdateAlertRequest {
   condition_operator?: ConditionOperator
   condition_value?: string
   aggregation?: Aggregation
+  severity?: AlertSeverity
   check_interval_minutes?: number
   cooldown_minutes?: number
   is_active?: boolean
@@ -589,6 +726,21 @@ export interface AlertHistory {
   error_message: string | null
 }
 
+export interface AlertDigestSettings {
+  user_id: string
+  enabled: boolean
+  send_time: string
+  timezone: string
+  last_sent_at: string | null
+  next_send_at: string | null
+}
+
+export interface UpdateAlertDigestSettingsRequest {
+  enabled: boolean
+  send_time?: string
+  timezone?: string
+}
+
 export interface AlertTestResult {
   triggered: boolean
   actual_value: string
@@ -600,19 +752,66 @@ export interface AlertTestResult {
   notification_sent?: boolean
 }
 
+export interface PreviewAlertRequest {
+  query_id: string
+  condition_column?: string
+  condition_operator?: ConditionOperator
+  condition_value?: string
+  aggregation?: Aggregation
+  logic_operator?: 'and' | 'or'
+  conditions?: {
+    column: string
+    operator: ConditionOperator
+    value: string
+    aggregation?: Aggregation
+  }[]
+}
+
+export interface AlertPreviewResponse {
+  triggered: boolean
+  actual_value: string
+  row_count: number
+  logic_operator: 'and' | 'or'
+  conditions: {
+    column: string
+    operator: ConditionOperator
+    value: string
+    aggregation?: Aggregation | null
+  }[]
+}
+
 // Subscription Types
+export interface SubscriptionChannelResult {
+  channel_id: string
+  channel_name: string
+  channel_type: string
+  status: 'sent' | 'failed'
+  error?: string
+}
+
+export interface SubscriptionHistory {
+  id: string
+  subscription_id: string
+  run_at: string
+  status: 'sent' | 'partial' | 'failed'
+  channel_results: SubscriptionChannelResult[]
+  error_message: string | null
+}
+
 export interface DashboardSubscription {
   id: string
   user_id: string
   dashboard_id: string
   name: string
-  schedule_cron: string
+  schedule_cron: string  // Empty for interval schedules
+  schedule_interval_minutes?: number
   timezone: string
   format: 'pdf' | 'png'
   is_active: boolean
   last_sent_at: string | null
   next_run_at: string | null
   channel_ids: string[]
+  timezone_invalid?: boolean  // Stored timezone is not a valid IANA name; runs on UTC
   created_at: string
   updated_at: string
 }
@@ -620,7 +819,8 @@ export interface DashboardSubscription {
 export interface CreateSubscriptionRequest {
   dashboard_id: string
   name: string
-  schedule_cron: string
+  schedule_cron?: string  // Exactly one of schedule_cron and schedule_interval_minutes
+  schedule_interval_minutes?: number
   timezone?: string
   format?: 'pdf' | 'png'
   channel_ids: string[]
@@ -629,30 +829,76 @@ export interface CreateSubscriptionRequest {
 export interface UpdateSubscriptionRequest {
   name?: string
   schedule_cron?: string
+  schedule_interval_minutes?: number
   timezone?: string
   format?: 'pdf' | 'png'
   is_active?: boolean
   channel_ids?: string[]
 }
 
+export interface PreviewScheduleRequest {
+  schedule_cron?: string
+  schedule_interval_minutes?: number
+  timezone?: string
+}
+
+export interface SchedulePreview {
+  valid: boolean
+  cron: string
+  interval_minutes?: number // set when previewing an interval schedule
+  timezone: string
+  seconds?: boolean // the expression has a leading seconds field
+  description: string
+  next_runs: string[]
+}
+
 // Role Types
 export interface Role {
   id: string
   name: string
   description: string | null
+  parent_role_id: string | null
   is_system: boolean
   created_at: string
   updated_at: string
 }
 
+export interface SchemaPermission {
+  catalog: string
+  schema: string // '*' grants every schema in the catalog
+}
+
+export interface RowFilter {
+  catalog: string
+  schema: string
+  table: string
+  predicate: string // SQL boolean expression over the table's columns
+}
+
 export interface RoleWithCatalogs extends Role {
   catalogs: string[]
+  schemas: SchemaPermission[]
+  row_filters: RowFilter[]
+  max_query_timeout_seconds: number | null // null: QUERY_MAX_TIMEOUT_SECONDS applies
 }
 
 export interface UserWithRoles extends User {
   roles: Role[]
 }
 
+export type UserApprovalOutcome = 'approved' | 'not_pending' | 'not_found' | 'self'
+
+export interface UserApprovalResult {
+  user_id: string
+  outcome: UserApprovalOutcome
+  status?: 'active' | 'disabled' | 'pending' // omitted for unknown users
+}
+
+export interface ApproveUsersResponse {
+  results: UserApprovalResult[]
+  approved: number
+}
+
 export interface CreateRoleRequest {
   name: string
   description?: string
@@ -661,12 +907,25 @@ export interface CreateRoleRequest {
 export interface UpdateRoleRequest {
   name?: string
   description?: string
+  parent_role_id?: string | null
 }
 
 export interface SetCatalogPermissionsRequest {
   catalogs: string[]
 }
 
+export interface SetSchemaPermissionsRequest {
+  schemas: SchemaPermission[]
+}
+
+export interface SetRowFiltersRequest {
+  filters: RowFilter[]
+}
+
+export interface SetQueryTimeoutRequest {
+  max_query_timeout_seconds: number | null
+}
+
 export interface AssignRoleRequest {
   role_id: string
 }
@@ -686,14 +945,65 @@ export interface LayoutTemplate {
 // Widget Data API Types
 export interface WidgetDataRequest {
   parameters?: Record<string, unknown>
+  bypass_cache?: boolean  // Re-run instead of serving the cache (editors and owners only)
 }
 
 export interface WidgetDataResponse {
   widget_id: string
   query_result?: QueryResult
+  chart_config?: ChartConfig  // The widget's chart_config with its theme merged in
   error?: string
   required_parameters?: string[]
   missing_parameters?: string[]
+  annotations?: Annotation[]
+}
+
+// How a widget's query would run, for admins debugging widgets (nothing is executed)
+export interface WidgetQueryDebug {
+  widget_id: string
+  query_id?: string  // Absent when the widget runs inline SQL
+  query_text: string
+  resolved_query: string  // Empty while parameters are missing
+  required_parameters?: string[]
+  missing_parameters?: string[]
+  catalog: string
+  schema: string
+  owner_id: string  // The dashboard owner whose catalog permissions are enforced
+  access_allowed: boolean
+  access_error?: string
+}
+
+// Global maintenance switch; while enabled, query endpoints answer 503 with { error, maintenance: true }
+export interface MaintenanceStatus {
+  enabled: boolean
+  message?: string
+  updated_by?: string
+  updated_at?: string
+}
+
+export interface DashboardRenderResponse {
+  dashboard_id: string
+  title: string
+  description?: string
+  widgets: WidgetDataResponse[]
+}
+
+export interface DashboardDataResponse {
+  dashboard_id: string
+  widgets: Record<string, WidgetDataResponse> // keyed by widget ID
+  missing_parameters: string[] // missing for any widget, sorted
+}
+
+// Annotation Types
+export interface Annotation {
+  id: string
+  user_id: string
+  dashboard_id?: string
+  occurred_at: string
+  label: string
+  color?: string
+  created_at: string
+  updated_at: string
 }
 
 // Metadata Search Types
@@ -705,6 +1015,14 @@ export interface MetadataSearchResult {
   type: 'table' | 'column'
 }
 
+export interface MetadataSuggestion {
+  kind: 'catalog' | 'schema' | 'table' | 'column'
+  name: string
+  catalog: string
+  schema?: string
+  table?: string
+}
+
 export interface MetadataSearchRequest {
   query: string
   search_type?: 'table' | 'column' | 'all'